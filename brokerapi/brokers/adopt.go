@@ -0,0 +1,121 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// AdoptInstance records existing cloud resources as a provisioned instance
+// without provisioning them. Only services whose provider implements
+// broker.InstanceAdopter can be adopted; the provider checks the resources
+// exist and if they don't, nothing is recorded.
+func (broker *ServiceBroker) AdoptInstance(ctx context.Context, instanceID string, request broker.AdoptRequest) error {
+	logger := broker.Logger.Session("adopt-instance", lager.Data{
+		"instance_id": instanceID,
+		"service_id":  request.ServiceID,
+		"plan_id":     request.PlanID,
+	})
+
+	if err := request.Validate(); err != nil {
+		return brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-adopt-request")
+	}
+
+	exists, err := db_service.ExistsServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("Database error checking for existing instance: %s", err)
+	}
+	if exists {
+		return brokerapi.ErrInstanceAlreadyExists
+	}
+
+	account := selectProviderAccount(logger, instanceID, request.OrganizationGUID, request.SpaceGUID)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(request.ServiceID, account)
+	if err != nil {
+		return brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-adopt-request")
+	}
+
+	if _, err := serviceDefinition.GetPlanById(request.PlanID); err != nil {
+		return brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-adopt-request")
+	}
+
+	adopter, err := instanceAdopter(serviceDefinition, serviceProvider)
+	if err != nil {
+		return err
+	}
+
+	instance := models.ServiceInstanceDetails{
+		ID:               instanceID,
+		Name:             request.Name,
+		ServiceId:        request.ServiceID,
+		PlanId:           request.PlanID,
+		SpaceGuid:        request.SpaceGUID,
+		OrganizationGuid: request.OrganizationGUID,
+		ProviderAccount:  account,
+	}
+	if err := instance.SetOtherDetails(request.Resources); err != nil {
+		return err
+	}
+
+	startedAt := time.Now()
+	if err := adopter.AdoptInstance(ctx, instance, request.Resources); err != nil {
+		return brokerapi.NewFailureResponse(
+			fmt.Errorf("the resources of instance %q couldn't be found: %s", instanceID, err),
+			http.StatusUnprocessableEntity,
+			"resources-not-found",
+		)
+	}
+
+	if err := db_service.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+		return fmt.Errorf("Error saving instance details to database: %s", err)
+	}
+
+	pr := models.ProvisionRequestDetails{ServiceInstanceId: instanceID}
+	if err := pr.SetRequestDetails(request.Parameters); err != nil {
+		return fmt.Errorf("Error encrypting provision request details: %s", err)
+	}
+	if err := db_service.CreateProvisionRequestDetails(ctx, &pr); err != nil {
+		return fmt.Errorf("Error saving provision request details to database: %s", err)
+	}
+
+	broker.recordOperation(ctx, instanceID, models.ProvisionOperationType, "", startedAt, false, nil)
+	logger.Info("adopted")
+
+	return nil
+}
+
+// instanceAdopter returns the provider as a broker.InstanceAdopter, or a 422
+// Unprocessable Entity if the service can't adopt instances.
+func instanceAdopter(service *broker.ServiceDefinition, provider broker.ServiceProvider) (broker.InstanceAdopter, error) {
+	adopter, ok := provider.(broker.InstanceAdopter)
+	if !ok {
+		return nil, brokerapi.NewFailureResponse(
+			fmt.Errorf("service %q doesn't support adopting instances", service.Name),
+			http.StatusUnprocessableEntity,
+			"adoption-not-supported",
+		)
+	}
+
+	return adopter, nil
+}
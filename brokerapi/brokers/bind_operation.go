@@ -0,0 +1,38 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// checkNoOperationInProgress rejects binding an instance while an operation,
+// most likely its asynchronous provision, is still running on it. The
+// provider's resources may not exist yet, so the bind would fail confusingly.
+func checkNoOperationInProgress(instance models.ServiceInstanceDetails) error {
+	if instance.OperationType == models.ClearOperationType {
+		return nil
+	}
+
+	return brokerapi.NewFailureResponseBuilder(
+		fmt.Errorf("the %s operation of instance %q is still in progress, wait for it to complete before binding", instance.OperationType, instance.ID),
+		http.StatusUnprocessableEntity,
+		"operation-in-progress",
+	).WithErrorKey("ConcurrencyError").Build()
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// saveBindRequest records the parameters the binding was created with so
+// GetBinding can return them.
+func saveBindRequest(ctx context.Context, instanceID, bindingID string, params json.RawMessage) error {
+	br := models.BindRequestDetails{ServiceInstanceId: instanceID, BindingId: bindingID}
+	if err := br.SetRequestDetails(params); err != nil {
+		return fmt.Errorf("Error encrypting bind request parameters: %s", err)
+	}
+
+	if err := db_service.CreateBindRequestDetails(ctx, &br); err != nil {
+		return fmt.Errorf("Error saving bind request details to database: %s", err)
+	}
+
+	return nil
+}
+
+// deleteBindRequest removes the recorded parameters of the binding. Failures
+// are only logged, the binding is already gone.
+func deleteBindRequest(ctx context.Context, logger lager.Logger, bindingID string) {
+	if err := db_service.DeleteBindRequestDetailsByBindingId(ctx, bindingID); err != nil {
+		logger.Error("deleting-bind-request", err, lager.Data{"binding_id": bindingID})
+	}
+}
+
+// bindingParameters returns the parameters the binding was created with, with
+// the values of sensitive parameters masked, see redactParameters. Bindings
+// created before the parameters were recorded have none.
+func (broker *ServiceBroker) bindingParameters(ctx context.Context, serviceID, bindingID string) (interface{}, error) {
+	br, err := db_service.GetBindRequestDetailsByBindingId(ctx, bindingID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("Error retrieving bind request details: %s", err)
+	}
+
+	params, err := br.GetRequestDetails()
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting bind request parameters: %s", err)
+	}
+
+	redacted := broker.redactParameters(serviceID, params)
+	if len(redacted) == 0 {
+		return nil, nil
+	}
+
+	var out interface{}
+	if err := json.Unmarshal(redacted, &out); err != nil {
+		return nil, fmt.Errorf("Error decoding bind request parameters: %s", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+	"github.com/spf13/viper"
+)
+
+const (
+	bindRetriesProp       = "request.bind_retries"
+	bindRetryIntervalProp = "request.bind_retry_interval"
+
+	defaultBindRetries       = 3
+	defaultBindRetryInterval = 2 * time.Second
+)
+
+func init() {
+	viper.BindEnv(bindRetriesProp, "BIND_RETRIES")
+	viper.SetDefault(bindRetriesProp, defaultBindRetries)
+
+	viper.BindEnv(bindRetryIntervalProp, "BIND_RETRY_INTERVAL")
+	viper.SetDefault(bindRetryIntervalProp, defaultBindRetryInterval)
+}
+
+// bindWithRetries calls the provider's Bind, calling it again if it fails
+// with a broker.RetryableBindError until it succeeds, fails otherwise or the
+// configured retries are used up. The interval between attempts doubles each
+// time. The last error is returned if the broker gives up.
+func bindWithRetries(ctx context.Context, logger lager.Logger, provider broker.ServiceProvider, vars *varcontext.VarContext, bindingID string) (map[string]interface{}, error) {
+	retries := viper.GetInt(bindRetriesProp)
+	interval := viper.GetDuration(bindRetryIntervalProp)
+
+	for attempt := 1; ; attempt++ {
+		credentials, err := provider.Bind(ctx, vars)
+		if err == nil || !broker.IsRetryableBind(err) || attempt > retries {
+			return credentials, err
+		}
+
+		logger.Info("bind-retrying", lager.Data{
+			"binding_id": bindingID,
+			"attempt":    attempt,
+			"interval":   interval.String(),
+			"error":      err.Error(),
+		})
+
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return nil, err
+		}
+		interval *= 2
+	}
+}
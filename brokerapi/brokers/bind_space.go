@@ -0,0 +1,100 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/toggles"
+	"github.com/spf13/viper"
+)
+
+const (
+	enforceBindSpaceProp       = "request.enforce_bind_space"
+	enforceBindSpaceSharedProp = "request.enforce_bind_space_allow_shared"
+)
+
+// CfSharingToggle makes every service shareable, so its instances can be
+// shared with and bound from other spaces.
+var CfSharingToggle = toggles.Features.Toggle("enable-cf-sharing", false, `Set all services to have the Sharable flag so they can be shared
+	across spaces in PCF.`)
+
+func init() {
+	viper.BindEnv(enforceBindSpaceProp, "ENFORCE_BIND_SPACE")
+	viper.SetDefault(enforceBindSpaceProp, false)
+	viper.BindEnv(enforceBindSpaceSharedProp, "ENFORCE_BIND_SPACE_ALLOW_SHARED")
+	viper.SetDefault(enforceBindSpaceSharedProp, false)
+}
+
+// checkBindSpace rejects binding an app from another space than the one the
+// instance was provisioned in, if enabled. The broker isn't told which spaces
+// an instance is shared with, so instances of shareable services are only
+// exempt if the operator opted in to trusting the platform's sharing checks.
+// Requests that don't say which space they come from, like service keys, are
+// allowed.
+func checkBindSpace(details brokerapi.BindDetails, instance models.ServiceInstanceDetails, service *broker.ServiceDefinition) error {
+	if !viper.GetBool(enforceBindSpaceProp) {
+		return nil
+	}
+
+	if viper.GetBool(enforceBindSpaceSharedProp) && serviceShareable(service) {
+		return nil
+	}
+
+	spaceGUID, _ := bindingOrigin(details)
+	if spaceGUID == "" || instance.SpaceGuid == "" || spaceGUID == instance.SpaceGuid {
+		return nil
+	}
+
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("apps in space %q can't bind to instance %q of space %q", spaceGUID, instance.ID, instance.SpaceGuid),
+		http.StatusForbidden,
+		"space-mismatch",
+	)
+}
+
+// serviceShareable returns whether instances of the service can be shared
+// across spaces.
+func serviceShareable(service *broker.ServiceDefinition) bool {
+	if CfSharingToggle.IsActive() {
+		return true
+	}
+
+	entry, err := service.CatalogEntry()
+	if err != nil || entry.Metadata == nil || entry.Metadata.Shareable == nil {
+		return false
+	}
+	return *entry.Metadata.Shareable
+}
+
+// bindingOrigin returns the GUIDs of the space and organization the binding
+// is requested from.
+func bindingOrigin(details brokerapi.BindDetails) (spaceGUID, organizationGUID string) {
+	var bindContext struct {
+		SpaceGUID        string `json:"space_guid"`
+		OrganizationGUID string `json:"organization_guid"`
+	}
+	json.Unmarshal(details.GetRawContext(), &bindContext) // explicitly ignore parse errors
+
+	if bindContext.SpaceGUID == "" && details.BindResource != nil {
+		return details.BindResource.SpaceGuid, bindContext.OrganizationGUID
+	}
+	return bindContext.SpaceGUID, bindContext.OrganizationGUID
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// errPlanNotBindable is returned when binding an instance of a plan that
+// doesn't support bindings, e.g. one that only sets up networking.
+func errPlanNotBindable(serviceName, planName string) error {
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("plan %q of service %q doesn't support bindings", planName, serviceName),
+		http.StatusBadRequest,
+		"plan-not-bindable",
+	)
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const bindingCacheTTLProp = "request.binding_cache_ttl"
+
+func init() {
+	viper.BindEnv(bindingCacheTTLProp, "BINDING_CACHE_TTL")
+	viper.SetDefault(bindingCacheTTLProp, "0s")
+}
+
+// bindingCache keeps the credentials providers built for bindings so
+// repeated GetBinding requests don't call the provider, which may call the
+// cloud, every time. Entries expire after the configured TTL and are tied to
+// the versions of the binding and instance records they were built from, so
+// any write to either, e.g. an update refreshing the credentials, makes the
+// next request build them again. Writes the broker makes also invalidate the
+// entries explicitly. Credentials are only kept in memory.
+type bindingCache struct {
+	mu      sync.Mutex
+	entries map[string]bindingCacheEntry
+}
+
+type bindingCacheEntry struct {
+	binding brokerapi.Binding
+	version string
+	expires time.Time
+}
+
+func newBindingCache() *bindingCache {
+	return &bindingCache{entries: make(map[string]bindingCacheEntry)}
+}
+
+// bindingCacheKey identifies a binding of an instance.
+func bindingCacheKey(instanceID, bindingID string) string {
+	return instanceID + "/" + bindingID
+}
+
+// bindingCacheVersion identifies the versions of the records the
+// credentials of a binding are built from.
+func bindingCacheVersion(binding models.ServiceBindingCredentials, instance models.ServiceInstanceDetails) string {
+	return fmt.Sprintf("%d/%d", binding.UpdatedAt.UnixNano(), instance.UpdatedAt.UnixNano())
+}
+
+// Get returns the cached credentials of the binding if they were built from
+// the current versions of its records and haven't expired.
+func (c *bindingCache) Get(binding models.ServiceBindingCredentials, instance models.ServiceInstanceDetails) (*brokerapi.Binding, bool) {
+	key := bindingCacheKey(instance.ID, binding.BindingId)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if time.Now().After(entry.expires) || entry.version != bindingCacheVersion(binding, instance) {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	cached := entry.binding
+	return &cached, true
+}
+
+// Put caches the credentials built for the binding, if caching is enabled.
+// Expired entries are dropped at the same time.
+func (c *bindingCache) Put(binding models.ServiceBindingCredentials, instance models.ServiceInstanceDetails, credentials brokerapi.Binding) {
+	ttl := viper.GetDuration(bindingCacheTTLProp)
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	c.entries[bindingCacheKey(instance.ID, binding.BindingId)] = bindingCacheEntry{
+		binding: credentials,
+		version: bindingCacheVersion(binding, instance),
+		expires: now.Add(ttl),
+	}
+}
+
+// Invalidate drops the cached credentials of the binding.
+func (c *bindingCache) Invalidate(instanceID, bindingID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, bindingCacheKey(instanceID, bindingID))
+}
+
+// InvalidateInstance drops the cached credentials of all bindings of the
+// instance, e.g. when its outputs change.
+func (c *bindingCache) InvalidateInstance(instanceID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	prefix := bindingCacheKey(instanceID, "")
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+func TestBindingCache(t *testing.T) {
+	defer viper.Set(bindingCacheTTLProp, "0s")
+
+	now := time.Now()
+	instance := models.ServiceInstanceDetails{ID: "instance"}
+	instance.UpdatedAt = now
+	binding := models.ServiceBindingCredentials{ServiceInstanceId: "instance", BindingId: "binding"}
+	binding.UpdatedAt = now
+	credentials := brokerapi.Binding{Credentials: map[string]interface{}{"password": "secret"}}
+
+	t.Run("disabled without a TTL", func(t *testing.T) {
+		viper.Set(bindingCacheTTLProp, "0s")
+		cache := newBindingCache()
+		cache.Put(binding, instance, credentials)
+
+		if _, ok := cache.Get(binding, instance); ok {
+			t.Errorf("expected nothing to be cached without a TTL")
+		}
+	})
+
+	t.Run("cached until a record changes", func(t *testing.T) {
+		viper.Set(bindingCacheTTLProp, "1h")
+		cache := newBindingCache()
+		cache.Put(binding, instance, credentials)
+
+		cached, ok := cache.Get(binding, instance)
+		if !ok {
+			t.Fatalf("expected the credentials to be cached")
+		}
+		if cached.Credentials.(map[string]interface{})["password"] != "secret" {
+			t.Errorf("expected the cached credentials, got %v", cached.Credentials)
+		}
+
+		updated := instance
+		updated.UpdatedAt = now.Add(time.Second)
+		if _, ok := cache.Get(binding, updated); ok {
+			t.Errorf("expected an updated instance not to use the cached credentials")
+		}
+		if _, ok := cache.Get(binding, instance); ok {
+			t.Errorf("expected the stale entry to be dropped")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		viper.Set(bindingCacheTTLProp, "1ns")
+		cache := newBindingCache()
+		cache.Put(binding, instance, credentials)
+		time.Sleep(time.Millisecond)
+
+		if _, ok := cache.Get(binding, instance); ok {
+			t.Errorf("expected expired credentials not to be returned")
+		}
+	})
+
+	t.Run("invalidated", func(t *testing.T) {
+		viper.Set(bindingCacheTTLProp, "1h")
+		cache := newBindingCache()
+		other := binding
+		other.BindingId = "other"
+
+		cache.Put(binding, instance, credentials)
+		cache.Put(other, instance, credentials)
+		cache.Invalidate("instance", "binding")
+		if _, ok := cache.Get(binding, instance); ok {
+			t.Errorf("expected the invalidated binding not to be cached")
+		}
+		if _, ok := cache.Get(other, instance); !ok {
+			t.Errorf("expected other bindings to stay cached")
+		}
+
+		cache.InvalidateInstance("instance")
+		if _, ok := cache.Get(other, instance); ok {
+			t.Errorf("expected the bindings of the instance not to be cached")
+		}
+	})
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const cleanupBindingsOnDeprovisionProp = "request.cleanup_bindings_on_deprovision"
+
+func init() {
+	viper.BindEnv(cleanupBindingsOnDeprovisionProp, "CLEANUP_BINDINGS_ON_DEPROVISION")
+	viper.SetDefault(cleanupBindingsOnDeprovisionProp, false)
+}
+
+// cleanupBindings removes the bindings still left once an instance was
+// deleted, e.g. because an operator forced the deprovision, so their Credstore
+// entries don't leak. It's disabled by default: the bindings are kept for the
+// operator to unbind explicitly.
+//
+// The instance is already gone, so failures are logged rather than returned.
+// A binding whose Credstore entry couldn't be deleted is kept so the operator
+// can find it.
+func (broker *ServiceBroker) cleanupBindings(ctx context.Context, instanceID string) {
+	if !viper.GetBool(cleanupBindingsOnDeprovisionProp) {
+		return
+	}
+
+	logger := broker.Logger.Session("cleanup-bindings", lager.Data{"instance_id": instanceID})
+	bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instanceID)
+	if err != nil {
+		logger.Error("listing-bindings", err)
+		return
+	}
+
+	for i := range bindings {
+		binding := &bindings[i]
+		bindingLogger := logger.WithData(lager.Data{"binding_id": binding.BindingId})
+
+		if broker.Credstore != nil {
+			service, err := broker.registry.GetServiceById(binding.ServiceId)
+			if err != nil {
+				bindingLogger.Error("finding-service", err)
+				continue
+			}
+
+			credentialName := getCredentialName(broker.getServiceName(service), binding.BindingId)
+			if !broker.credstoreRetries.cancel(credentialName) {
+				broker.deleteCredstorePermission(bindingLogger, credentialName, *binding)
+
+				if err := broker.Credstore.Delete(credentialName); err != nil {
+					bindingLogger.Error("deleting-credstore-entry", err)
+					continue
+				}
+			}
+		}
+
+		if err := db_service.DeleteServiceBindingCredentials(ctx, binding); err != nil {
+			bindingLogger.Error("deleting-binding", err)
+			continue
+		}
+		deleteBindRequest(ctx, bindingLogger, binding.BindingId)
+		broker.bindingCache.Invalidate(binding.ServiceInstanceId, binding.BindingId)
+
+		bindingLogger.Info("removed")
+	}
+}
+
+// deleteCredstorePermission removes the app's permission to read the
+// binding's credentials. Bindings created before app GUIDs were recorded look
+// like service keys, so it's attempted for every binding; failures for
+// bindings without an app GUID are expected when they are service keys and
+// only logged as info.
+func (broker *ServiceBroker) deleteCredstorePermission(logger lager.Logger, credentialName string, binding models.ServiceBindingCredentials) {
+	err := broker.Credstore.DeletePermission(credentialName)
+	switch {
+	case err == nil:
+	case binding.AppGuid == "":
+		logger.Info("delete-permission-failed", lager.Data{"credential_name": credentialName, "error": err.Error()})
+	default:
+		logger.Error("deleting-credstore-permission", err, lager.Data{"credential_name": credentialName})
+	}
+}
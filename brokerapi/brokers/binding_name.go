@@ -0,0 +1,50 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+)
+
+// checkBindingName rejects a name another binding of the instance already
+// uses, since database services create a user with it. Unnamed bindings are
+// always allowed.
+func checkBindingName(ctx context.Context, instanceID, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("Error retrieving the bindings of the instance: %s", err)
+	}
+
+	for _, binding := range bindings {
+		if binding.Name == name {
+			return brokerapi.NewFailureResponse(
+				fmt.Errorf("binding %s of the instance is already named %q", binding.BindingId, name),
+				http.StatusBadRequest,
+				"binding-name-in-use",
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	bindingPurgeAfterProp    = "bindings.purge_after"
+	bindingPurgeIntervalProp = "bindings.purge_interval"
+
+	defaultBindingPurgeInterval = time.Hour
+)
+
+func init() {
+	viper.BindEnv(bindingPurgeAfterProp, "BINDINGS_PURGE_AFTER")
+	viper.SetDefault(bindingPurgeAfterProp, 0)
+
+	viper.BindEnv(bindingPurgeIntervalProp, "BINDINGS_PURGE_INTERVAL")
+	viper.SetDefault(bindingPurgeIntervalProp, defaultBindingPurgeInterval)
+}
+
+// StartBindingPurger purges the unbound bindings older than
+// bindings.purge_after once right away and then every
+// bindings.purge_interval until the context is done. Unbound bindings are
+// kept forever if bindings.purge_after is 0.
+// The returned channel is closed once the purger stopped.
+func (broker *ServiceBroker) StartBindingPurger(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if viper.GetDuration(bindingPurgeAfterProp) <= 0 {
+		close(done)
+		return done
+	}
+
+	interval := viper.GetDuration(bindingPurgeIntervalProp)
+	go func() {
+		defer close(done)
+
+		broker.PurgeUnboundBindings(ctx)
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				broker.PurgeUnboundBindings(ctx)
+			}
+		}
+	}()
+
+	return done
+}
+
+// PurgeUnboundBindings permanently removes the bindings unbound longer than
+// bindings.purge_after ago. Until then they stay queryable for auditing, see
+// UnboundBindings.
+func (broker *ServiceBroker) PurgeUnboundBindings(ctx context.Context) {
+	retention := viper.GetDuration(bindingPurgeAfterProp)
+	if retention <= 0 {
+		return
+	}
+
+	before := time.Now().Add(-retention)
+	if err := db_service.PurgeDeletedServiceBindingCredentials(ctx, before); err != nil {
+		broker.Logger.Error("purge-unbound-bindings", err, lager.Data{"before": before})
+	}
+}
+
+// UnboundBindings returns the bindings unbound at or after the given time that
+// weren't purged yet, most recently unbound first. Binding the same binding ID
+// again while the unbound one is kept creates a new binding.
+func (broker *ServiceBroker) UnboundBindings(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error) {
+	return db_service.GetDeletedServiceBindingCredentials(ctx, since)
+}
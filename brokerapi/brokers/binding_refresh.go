@@ -0,0 +1,91 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+)
+
+// refreshBindings rebuilds the credentials of the instance's bindings from
+// its current details and puts them in the Credstore again, so apps see a
+// changed endpoint on their next restart. The secrets stored with each binding
+// are kept. Services opt in with RefreshBindingsOnUpdate, the cached
+// credentials of the bindings are dropped regardless. Failures are logged
+// rather than returned because the update itself already succeeded.
+func (broker *ServiceBroker) refreshBindings(ctx context.Context, instanceID string) {
+	broker.bindingCache.InvalidateInstance(instanceID)
+
+	if broker.Credstore == nil {
+		return
+	}
+
+	logger := broker.Logger.Session("refresh-bindings", lager.Data{"instance_id": instanceID})
+
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		logger.Error("getting-instance", err)
+		return
+	}
+
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId, instance.ProviderAccount)
+	if err != nil {
+		logger.Error("getting-service", err)
+		return
+	}
+
+	if !serviceDefinition.RefreshBindingsOnUpdate() {
+		return
+	}
+
+	credentialKeys, err := serviceDefinition.CredentialKeyMapping()
+	if err != nil {
+		logger.Error("getting-credential-keys", err)
+		return
+	}
+
+	bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instanceID)
+	if err != nil {
+		logger.Error("getting-bindings", err)
+		return
+	}
+
+	refreshed := []string{}
+	for _, binding := range bindings {
+		credentials, err := serviceProvider.BuildInstanceCredentials(ctx, binding, *instance)
+		if err != nil {
+			logger.Error("building-credentials", err, lager.Data{"binding_id": binding.BindingId})
+			continue
+		}
+
+		formatted, err := bindingCredentials(credentials.Credentials, credentialKeys, binding.CredentialFormat)
+		if err != nil {
+			logger.Error("formatting-credentials", err, lager.Data{"binding_id": binding.BindingId})
+			continue
+		}
+
+		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), binding.BindingId)
+		if _, err := broker.Credstore.Put(credentialName, formatted); err != nil {
+			logger.Error("putting-credentials", err, lager.Data{"binding_id": binding.BindingId})
+			continue
+		}
+
+		refreshed = append(refreshed, binding.BindingId)
+	}
+
+	logger.Info("refreshed", lager.Data{"binding_ids": refreshed})
+}
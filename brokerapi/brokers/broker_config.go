@@ -19,15 +19,25 @@ import (
 
 	"code.cloudfoundry.org/lager"
 	
+	"github.com/pivotal/cloud-service-broker/db_service"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
 	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
 	"github.com/pivotal/cloud-service-broker/pkg/config"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
 )
 
 type BrokerConfig struct {
 	Registry   broker.BrokerRegistry
 	Credstore  credstore.CredStore
+
+	// Store persists the broker's state, the database opened by
+	// db_service.New is used if it's nil.
+	Store db_service.Store
+
+	// EventSink receives the lifecycle events of instances and bindings, the
+	// sink configured in the environment is used if it's nil.
+	EventSink events.Sink
 }
 
 func NewBrokerConfigFromEnv(logger lager.Logger) (*BrokerConfig, error) {
@@ -41,11 +51,19 @@ func NewBrokerConfigFromEnv(logger lager.Logger) (*BrokerConfig, error) {
 		return nil, fmt.Errorf("Failed loading config: %v", err)
 	}
 
+	if err := broker.ValidateProviderAccounts(); err != nil {
+		return nil, fmt.Errorf("Invalid provider accounts: %v", err)
+	}
+
+	if err := ValidateOrgRateLimits(); err != nil {
+		return nil, fmt.Errorf("Invalid organization rate limits: %v", err)
+	}
+
 	var cs credstore.CredStore
 
 	if config.CredStoreConfig.HasCredHubConfig() {
 		var err error
-		cs, err = credstore.NewCredhubStore( &config.CredStoreConfig, logger )
+		cs, err = credstore.NewReloadableCredStore(newCredStoreFactory(logger))
 		if err != nil {
 			return nil, fmt.Errorf("Failed creating credstore: %v", err)
 		}
@@ -56,3 +74,16 @@ func NewBrokerConfigFromEnv(logger lager.Logger) (*BrokerConfig, error) {
 		Credstore:  cs,
 	}, nil
 }
+
+// newCredStoreFactory returns a function creating a credstore from the current
+// config, so reloads pick up rotated certificates and secrets.
+func newCredStoreFactory(logger lager.Logger) func() (credstore.CredStore, error) {
+	return func() (credstore.CredStore, error) {
+		cfg, err := config.Parse()
+		if err != nil {
+			return nil, err
+		}
+
+		return credstore.NewCredhubStore(&cfg.CredStoreConfig, logger)
+	}
+}
@@ -18,9 +18,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/pivotal-cf/brokerapi"
 	. "github.com/pivotal/cloud-service-broker/brokerapi/brokers"
@@ -30,16 +36,21 @@ import (
 	"github.com/pivotal/cloud-service-broker/pkg/broker/brokerfakes"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore/credstorefakes"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/base"
 	"github.com/pivotal/cloud-service-broker/pkg/providers/builtin/storage"
+	"github.com/pivotal/cloud-service-broker/pkg/providers/tf"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"google.golang.org/api/googleapi"
 
 	"code.cloudfoundry.org/lager"
+	"code.cloudfoundry.org/lager/lagertest"
 
 	"github.com/jinzhu/gorm"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/spf13/viper"
 )
 
 // InstanceState holds the lifecycle state of a provisioned service instance.
@@ -57,6 +68,7 @@ const (
 const (
 	fakeInstanceId = "newid"
 	fakeBindingId  = "newbinding"
+	fakeAppGuid    = "fake-app-guid"
 )
 
 // serviceStub holds a stubbed out ServiceDefinition with easy access to
@@ -90,11 +102,20 @@ func (s *serviceStub) DeprovisionDetails() brokerapi.DeprovisionDetails {
 // the given service.
 func (s *serviceStub) BindDetails() brokerapi.BindDetails {
 	return brokerapi.BindDetails{
+		AppGUID:   fakeAppGuid,
 		ServiceID: s.ServiceId,
 		PlanID:    s.PlanId,
 	}
 }
 
+// ServiceKeyBindDetails creates a brokerapi.BindDetails object for a service
+// key, which isn't bound to an app, valid for the given service.
+func (s *serviceStub) ServiceKeyBindDetails() brokerapi.BindDetails {
+	details := s.BindDetails()
+	details.AppGUID = ""
+	return details
+}
+
 // UnbindDetails creates a brokerapi.UnbindDetails object valid for
 // the given service.
 func (s *serviceStub) UnbindDetails() brokerapi.UnbindDetails {
@@ -283,7 +304,164 @@ func TestGCPServiceBroker_Services(t *testing.T) {
 	assertEqual(t, "service count should be the same", len(registry), len(services))
 }
 
+func TestGCPServiceBroker_Services_Order(t *testing.T) {
+	defer viper.Reset()
+	viper.Set("api.catalog_order", "display_name")
+
+	broker, closer := newStubbedBroker(t, builtin.BuiltinBrokerRegistry(), nil)
+	defer closer()
+
+	services, err := broker.Services(context.Background())
+	failIfErr(t, "getting services", err)
+
+	for i := 1; i < len(services); i++ {
+		previous, current := strings.ToLower(services[i-1].Metadata.DisplayName), strings.ToLower(services[i].Metadata.DisplayName)
+		assertTrue(t, fmt.Sprintf("%q should be listed before %q", previous, current), previous <= current)
+	}
+
+	catalog, err := broker.Catalog(context.Background())
+	failIfErr(t, "getting catalog", err)
+	for i, service := range catalog {
+		assertEqual(t, "the catalog should use the same order", services[i].ID, service.ID)
+	}
+}
+
+func TestGCPServiceBroker_Catalog(t *testing.T) {
+	stub := fakeService(t, false)
+	stub.ServiceDefinition.IsBuiltin = false
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+	broker, closer := newStubbedBroker(t, registry, nil)
+	defer closer()
+
+	catalog, err := broker.Catalog(context.Background())
+	failIfErr(t, "getting catalog", err)
+
+	services, err := broker.Services(context.Background())
+	failIfErr(t, "getting services", err)
+
+	assertEqual(t, "service count should be the same", 1, len(catalog))
+	assertEqual(t, "service count should be the same", len(services), len(catalog))
+	for i, service := range catalog {
+		assertEqual(t, "typed services should convert to the OSB services", services[i], service.ToPlain())
+	}
+
+	catalog[0].Plans[0].Name = "changed"
+	catalog[0].Tags = append(catalog[0].Tags, "changed")
+
+	again, err := broker.Catalog(context.Background())
+	failIfErr(t, "getting catalog", err)
+	assertEqual(t, "modifying the catalog shouldn't change the broker", services[0], again[0].ToPlain())
+}
+
+// enrichingProvider is a ServiceProvider adding live data to its catalog entry.
+type enrichingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	err      error
+	enriched int
+}
+
+func (p *enrichingProvider) EnrichCatalog(ctx context.Context, entry *broker.Service) error {
+	p.enriched++
+	if p.err != nil {
+		return p.err
+	}
+	entry.Plans[0].Description += " Available in us-east-1, eu-west-1."
+	return nil
+}
+
+func TestGCPServiceBroker_CatalogEnrichment(t *testing.T) {
+	planDescription := func(t *testing.T, serviceBroker *ServiceBroker) string {
+		services, err := serviceBroker.Services(context.Background())
+		failIfErr(t, "getting services", err)
+		return services[0].Plans[0].Description
+	}
+
+	cases := map[string]struct {
+		Err    error
+		Suffix string
+	}{
+		"enriched": {Suffix: " Available in us-east-1, eu-west-1."},
+		"failure":  {Err: errors.New("cloud unavailable"), Suffix: ""},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set("api.catalog_enrichment_ttl", "1h")
+
+			stub := fakeService(t, false)
+			stub.ServiceDefinition.IsBuiltin = false
+			provider := &enrichingProvider{FakeServiceProvider: stub.Provider, err: tc.Err}
+			stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+				return provider
+			}
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			entry, err := stub.ServiceDefinition.CatalogEntry()
+			failIfErr(t, "getting static entry", err)
+			static := entry.Plans[0].Description
+
+			assertEqual(t, "description should match", static+tc.Suffix, planDescription(t, serviceBroker))
+			assertEqual(t, "description should match", static+tc.Suffix, planDescription(t, serviceBroker))
+
+			entry, err = stub.ServiceDefinition.CatalogEntry()
+			failIfErr(t, "getting static entry", err)
+			assertEqual(t, "static catalog should be unchanged", static, entry.Plans[0].Description)
+
+			calls := 1
+			if tc.Err != nil {
+				calls = 2
+			}
+			assertEqual(t, "only successful enrichments should be cached", calls, provider.enriched)
+		})
+	}
+}
+
 func TestGCPServiceBroker_Provision(t *testing.T) {
+	planNetwork := &broker.PlanNetwork{DefaultSubnet: "subnet-default"}
+	planZones := &broker.PlanAvailabilityZones{Allowed: []string{"zone-a", "zone-b", "zone-c"}, MinZones: 2}
+	resourceNaming := &broker.ResourceNaming{Prefix: "csb-", Hash: true}
+	generatedPassword := broker.BrokerVariable{
+		FieldName: "admin_password",
+		Type:      broker.JsonTypeString,
+		Details:   "The admin password.",
+		Generate:  &broker.GenerateDirective{Length: 16},
+	}
+	legacySize := broker.BrokerVariable{
+		FieldName:  "legacy_size",
+		Type:       broker.JsonTypeString,
+		Details:    "Replaced by the plan.",
+		Deprecated: true,
+	}
+	region := broker.BrokerVariable{
+		FieldName: "region",
+		Type:      broker.JsonTypeString,
+		Details:   "The region of the bucket.",
+		Enum:      map[interface{}]string{"us-east-1": "US East", "eu-west-1": "EU West"},
+		Normalize: &broker.NormalizeDirective{Trim: true, Lowercase: true},
+	}
+	encrypted := broker.BrokerVariable{
+		FieldName: "encrypted",
+		Type:      broker.JsonTypeBoolean,
+		Details:   "Encrypt the bucket.",
+	}
+	pendingErr := &broker.PendingProvisionError{Reason: "configuring replication"}
+
+	// provisionWithHeaders provisions and returns the headers of the response.
+	provisionWithHeaders := func(t *testing.T, serviceBroker *ServiceBroker, details brokerapi.ProvisionDetails) http.Header {
+		headers := broker.NewResponseHeaders()
+		_, err := serviceBroker.Provision(broker.WithResponseHeaders(context.Background(), headers), fakeInstanceId, details, true)
+		failIfErr(t, "provisioning", err)
+
+		response := http.Header{}
+		headers.ApplyTo(response)
+		return response
+	}
+
 	cases := BrokerEndpointTestSuite{
 		"good-request": {
 			ServiceState: StateProvisioned,
@@ -308,280 +486,521 @@ func TestGCPServiceBroker_Provision(t *testing.T) {
 				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
 			},
 		},
-		"unknown-service-id": {
+		"unique-instance-names": {
 			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("request.unique_instance_names", true)
+
 				req := stub.ProvisionDetails()
-				req.ServiceID = "bad-service-id"
+				req.SpaceGUID = "space-1"
+				req.RawContext = json.RawMessage(`{"instance_name":"my-db"}`)
 				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
-				assertEqual(t, "errors should match", errors.New("Unknown service ID: \"bad-service-id\""), err)
+				failIfErr(t, "provisioning", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "instance name should be stored", "my-db", instance.InstanceName)
+
+				_, err = broker.Provision(context.Background(), "otherid", req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be conflict", http.StatusConflict, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "error should match", `an instance named "my-db" already exists in space "space-1"`, err.Error())
+
+				req.SpaceGUID = "space-2"
+				_, err = broker.Provision(context.Background(), "otherid", req, true)
+				failIfErr(t, "provisioning in another space", err)
+				assertEqual(t, "provision calls should match", 2, stub.Provider.ProvisionCallCount())
 			},
 		},
-		"unknown-plan-id": {
+		"unique-instance-names-after-rename": {
 			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("request.unique_instance_names", true)
+
 				req := stub.ProvisionDetails()
-				req.PlanID = "bad-plan-id"
+				req.SpaceGUID = "space-1"
+				req.RawContext = json.RawMessage(`{"instance_name":"my-db"}`)
 				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
-				assertEqual(t, "errors should match", errors.New("Plan ID \"bad-plan-id\" could not be found"), err)
+				failIfErr(t, "provisioning", err)
+
+				rename := stub.UpdateDetails()
+				rename.RawContext = json.RawMessage(`{"instance_name":"renamed-db"}`)
+				_, err = broker.Update(context.Background(), fakeInstanceId, rename, true)
+				failIfErr(t, "renaming", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "the new name should be stored", "renamed-db", instance.InstanceName)
+
+				_, err = broker.Provision(context.Background(), "otherid", req, true)
+				failIfErr(t, "provisioning with the old name", err)
+
+				req.RawContext = json.RawMessage(`{"instance_name":"renamed-db"}`)
+				_, err = broker.Provision(context.Background(), "thirdid", req, true)
+				assertEqual(t, "error should match", `an instance named "renamed-db" already exists in space "space-1"`, err.Error())
 			},
 		},
-		"bad-request-json": {
+		"plan-prerequisite": {
 			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
 				req := stub.ProvisionDetails()
-				req.RawParameters = json.RawMessage("{invalid json")
+				req.SpaceGUID = "space-1"
 				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
-				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+				failIfErr(t, "provisioning the prerequisite", err)
+
+				stub.ServiceDefinition.Plans[0].RequiresInstanceOf = []string{stub.ServiceDefinition.Name}
+				_, err = broker.Provision(context.Background(), "otherid", req, true)
+				failIfErr(t, "provisioning with the prerequisite met", err)
+				assertEqual(t, "provision calls should match", 2, stub.Provider.ProvisionCallCount())
 			},
 		},
-	}
+		"pending-provision": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationId: "setup-1"}, pendingErr)
 
-	cases.Run(t)
-}
+				spec, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertTrue(t, "the provision should finish asynchronously", spec.IsAsync)
+				assertEqual(t, "operation data should match", "setup-1", spec.OperationData)
 
-func TestGCPServiceBroker_Deprovision(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"good-request": {
-			ServiceState: StateProvisioned,
+				op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "the provision should be in progress", brokerapi.InProgress, op.State)
+
+				stub.Provider.PollInstanceReturns(true, nil)
+				op, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "the provision should succeed", brokerapi.Succeeded, op.State)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "the operation should be cleared", models.ClearOperationType, instance.OperationType)
+
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				assertEqual(t, "finished instances of sync services can't be polled", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+		"pending-provision-without-async": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
-				failIfErr(t, "deprovisioning", err)
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{}, pendingErr)
 
-				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), false)
+				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+				assertEqual(t, "the instance should be rolled back", 1, stub.Provider.DeprovisionCallCount())
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "the instance shouldn't be saved", !exists)
 			},
 		},
-		"duplicate-deprovision": {
-			ServiceState: StateDeprovisioned,
+		"missing-plan-prerequisite": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
-				assertEqual(t, "duplicate deprovision should lead to DNE", brokerapi.ErrInstanceDoesNotExist, err)
+				stub.ServiceDefinition.Plans[0].RequiresInstanceOf = []string{stub.ServiceDefinition.Name}
+
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "space-1"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "error should match", fmt.Sprintf("plan %q requires an instance of service %q in space %q, create one first", stub.ServiceDefinition.Plans[0].Name, stub.ServiceDefinition.Name, "space-1"), err.Error())
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.ProvisionCallCount())
 			},
 		},
-		"instance-does-not-exist": {
+		"plan-prerequisite-without-space": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
-				assertEqual(t, "instance does not exist should be set", brokerapi.ErrInstanceDoesNotExist, err)
+				stub.ServiceDefinition.Plans[0].RequiresInstanceOf = []string{stub.ServiceDefinition.Name}
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
 			},
 		},
-		"async-required": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"duplicate-instance-names-allowed-by-default": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), false)
-				assertEqual(t, "async required should be returned if not supported", brokerapi.ErrAsyncRequired, err)
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "space-1"
+				req.RawContext = json.RawMessage(`{"instance_name":"my-db"}`)
+				for _, id := range []string{fakeInstanceId, "otherid"} {
+					_, err := broker.Provision(context.Background(), id, req, true)
+					failIfErr(t, "provisioning", err)
+				}
 			},
 		},
-		"async-deprovision-returns-operation": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"network": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				operationId := "my-operation-id"
-				stub.Provider.DeprovisionReturns(&operationId, nil)
-				resp, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
-				failIfErr(t, "deprovisioning", err)
+				stub.ServiceDefinition.Plans[0].Network = planNetwork
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"network":"vpc-0a1b2c"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
 
-				assertEqual(t, "operationid should be set as the data", operationId, resp.OperationData)
-				assertEqual(t, "IsAsync should be set", true, resp.IsAsync)
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "network should be stored", "vpc-0a1b2c", instance.Network)
+				assertEqual(t, "plan default subnet should be stored", "subnet-default", instance.Subnet)
 			},
 		},
+		"availability-zones": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].AvailabilityZones = planZones
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"availability_zones":["zone-a","zone-c"]}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
 
-		"async-deprovision-updates-db": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				zones, err := instance.GetAvailabilityZones()
+				failIfErr(t, "getting zones", err)
+				assertEqual(t, "zones should be stored", []string{"zone-a", "zone-c"}, zones)
+			},
+		},
+		"too-few-availability-zones": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				operationId := "my-operation-id"
-				stub.Provider.DeprovisionReturns(&operationId, nil)
-				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
-				failIfErr(t, "deprovisioning", err)
+				stub.ServiceDefinition.Plans[0].AvailabilityZones = planZones
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"availability_zones":["zone-a"]}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
 
-				details, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
-				failIfErr(t, "looking up details", err)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be bad request", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"generated-parameters": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, generatedPassword)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
 
-				assertEqual(t, "OperationId should be set as the data", operationId, details.OperationId)
-				assertEqual(t, "OperationType should be set as Deprovision", models.DeprovisionOperationType, details.OperationType)
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				password := vars.GetString("admin_password")
+				assertEqual(t, "password should be generated", 16, len(password))
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				generated, err := instance.GetGeneratedParameters()
+				failIfErr(t, "getting generated parameters", err)
+				assertEqual(t, "password should be stored with the instance", map[string]string{"admin_password": password}, generated)
+
+				request, err := db_service.GetProvisionRequestDetailsById(context.Background(), 1)
+				failIfErr(t, "getting request details", err)
+				assertTrue(t, "password should not be stored with the request", !strings.Contains(request.RequestDetails, password))
 			},
 		},
-	}
+		"encrypted-parameters": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ring, err := models.NewKeyRing(map[string]string{"old": "old-secret", "new": "new-secret"}, "new")
+				failIfErr(t, "creating key ring", err)
+				models.SetKeyRing(ring)
+				defer models.SetKeyRing(nil)
 
-	cases.Run(t)
-}
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, generatedPassword)
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"secret-bucket"}`)
+				_, err = broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
 
-func TestGCPServiceBroker_Bind(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"good-request": {
-			ServiceState: StateBound,
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertTrue(t, "generated parameters should use the active key", strings.HasPrefix(instance.GeneratedParameters, "encrypted:new:"))
+
+				request, err := db_service.GetProvisionRequestDetailsById(context.Background(), 1)
+				failIfErr(t, "getting request details", err)
+				assertTrue(t, "request details should use the active key", strings.HasPrefix(request.RequestDetails, "encrypted:new:"))
+				details, err := request.GetRequestDetails()
+				failIfErr(t, "decrypting request details", err)
+				assertEqual(t, "request details should decrypt", `{"name":"secret-bucket"}`, string(details))
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding with encrypted parameters", err)
+			},
+		},
+		"deprecated-parameters": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				assertEqual(t, "BindCallCount should match", 1, stub.Provider.BindCallCount())
-				assertEqual(t, "BuildInstanceCredentialsCallCount should match", 1, stub.Provider.BuildInstanceCredentialsCallCount())
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, legacySize)
+				uses := DeprecatedParameterUses.WithLabelValues(stub.ServiceDefinition.Name, "legacy_size")
+				before := testutil.ToFloat64(uses)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"legacy_size":"large"}`)
+				headers := provisionWithHeaders(t, broker, req)
+
+				assertTrue(t, "the response should warn about the parameter", strings.Contains(headers.Get("Warning"), "legacy_size"))
+				assertEqual(t, "the use should be counted", before+1, testutil.ToFloat64(uses))
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
 			},
 		},
-		"good-request-with-credstore": {
-			ServiceState: StateBound,
+		"no-deprecated-parameters": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				assertEqual(t, "BindCallCount should match", 1, stub.Provider.BindCallCount())
-				assertEqual(t, "BuildInstanceCredentialsCallCount should match", 1, stub.Provider.BuildInstanceCredentialsCallCount())
-				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
-				assertEqual(t, "Credstore Put call count should match", 1, fcs.PutCallCount())
-				assertEqual(t, "Credstore AddPermission call count should match", 1, fcs.AddPermissionCallCount())
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, legacySize)
+				headers := provisionWithHeaders(t, broker, stub.ProvisionDetails())
+				assertEqual(t, "the response should not warn", "", headers.Get("Warning"))
 			},
-			Credstore: &credstorefakes.FakeCredStore{},
 		},
-		"duplicate-request": {
-			ServiceState: StateBound,
+		"normalized-parameters": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
-				assertEqual(t, "errors should match", brokerapi.ErrBindingAlreadyExists, err)
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, region)
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"region":" US-EAST-1 "}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "the provider should get the canonical value", "us-east-1", vars.GetString("region"))
+
+				request, err := db_service.GetProvisionRequestDetailsById(context.Background(), 1)
+				failIfErr(t, "getting request details", err)
+				details, err := request.GetRequestDetails()
+				failIfErr(t, "reading request details", err)
+				assertEqual(t, "the canonical value should be stored", `{"region":"us-east-1"}`, string(details))
 			},
 		},
-		"bad-bind-call": {
-			ServiceState: StateProvisioned,
+		"forced-parameters": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, serviceBroker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				stub.ServiceDefinition.ProvisionInputVariables = append(stub.ServiceDefinition.ProvisionInputVariables, encrypted)
+				viper.Set(stub.ServiceDefinition.ProvisionForcedParametersProperty(), map[string]interface{}{
+					stub.ServiceDefinition.Plans[0].Name: map[string]interface{}{"encrypted": true},
+				})
+				logger := lagertest.NewTestLogger("forced-parameters")
+				serviceBroker.Logger = logger
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"encrypted":false}`)
+				_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				assertTrue(t, "the provider should get the forced value", vars.GetBool("encrypted"))
+
+				request, err := db_service.GetProvisionRequestDetailsById(context.Background(), 1)
+				failIfErr(t, "getting request details", err)
+				details, err := request.GetRequestDetails()
+				failIfErr(t, "reading request details", err)
+				assertEqual(t, "the forced value should be stored", `{"encrypted":true}`, string(details))
+
+				overrides := logger.LogMessages()
+				assertTrue(t, "the override should be logged", len(overrides) > 0 && strings.Contains(strings.Join(overrides, ","), "forced-parameters-override-user-values"))
+			},
+		},
+		"plan-instance-quota": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.BindDetails()
-				req.RawParameters = json.RawMessage(`{"role":"project.admin"}`)
+				defer viper.Reset()
+				plan := stub.ServiceDefinition.Plans[0]
+				viper.Set(stub.ServiceDefinition.PlanInstanceQuotaProperty(), map[string]interface{}{plan.Name: 1})
 
-				expectedErr := "1 error(s) occurred: role: role must be one of the following: \"storage.objectAdmin\", \"storage.objectCreator\", \"storage.objectViewer\""
-				_, err := broker.Bind(context.Background(), fakeInstanceId, "bad-bind-call", req, true)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				// the quota applies across organizations
+				req := stub.ProvisionDetails()
+				req.OrganizationGUID = "other-org"
+				_, err = broker.Provision(context.Background(), "other-instance", req, true)
+				expectedErr := fmt.Sprintf(`plan %q reached its quota of 1 instances across the foundation, delete an instance or choose another plan`, plan.Name)
 				assertEqual(t, "errors should match", expectedErr, err.Error())
+				assertEqual(t, "status should be 400", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+				assertEqual(t, "provider should be called once", 1, stub.Provider.ProvisionCallCount())
 			},
 		},
-		"bad-request-json": {
-			ServiceState: StateProvisioned,
+		"resource-name": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.BindDetails()
-				req.RawParameters = json.RawMessage("{invalid json")
-				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
-				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+				stub.ServiceDefinition.InstanceNamer = resourceNaming
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				expected := stub.ServiceDefinition.ResourceName(fakeInstanceId)
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "resource name should be stored", expected, instance.ResourceName)
+				assertTrue(t, "resource name should be derived", strings.HasPrefix(expected, "csb-") && expected != "csb-"+fakeInstanceId)
 			},
 		},
-		"bind-variables-override-instance-variables": {
-			ServiceState: StateProvisioned,
+		"instance-metadata": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.BindDetails()
-				bindResult, err := broker.Bind(context.Background(), fakeInstanceId, "override-params", req, true)
-				failIfErr(t, "binding", err)
-				credMap, ok := bindResult.Credentials.(map[string]interface{})
-				assertTrue(t, "bind result credentials should be a map", ok)
-				assertEqual(t, "credential overridden", "bar", credMap["foo"].(string))
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"my-db","instance_metadata":{"owner":"team-a"}}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				_, passed := vars.ToMap()["instance_metadata"]
+				assertEqual(t, "metadata should not be passed to the provider", false, passed)
+
+				metadata, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting metadata", err)
+				assertEqual(t, "metadata should be stored", map[string]string{"owner": "team-a"}, metadata)
 			},
 		},
-		"bind-returns-credhub-ref": {
-			ServiceState: StateProvisioned,
+		"invalid-instance-metadata": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.BindDetails()
-				bindResult, err := broker.Bind(context.Background(), fakeInstanceId, "override-params", req, true)
-				failIfErr(t, "binding", err)
-				credMap, ok := bindResult.Credentials.(map[string]interface{})
-				assertTrue(t, "bind result credentials should be a map", ok)
-				assertTrue(t, "value foo missing", credMap["foo"] == nil)	
-				assertTrue(t, "cred-hub ref exists", credMap["credhub-ref"] != nil)	
-				assertEqual(t, "cred-hub ref has correct value", "/c/csb/google-storage/override-params/secrets-and-services", credMap["credhub-ref"].(string))		
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"instance_metadata":{"cost_center":42}}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "instance_metadata.cost_center must be a string", err.Error())
 			},
-			Credstore: &credstorefakes.FakeCredStore{},
-		},	
-	}
+		},
+		"deletion-protection": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"my-db","deletion_protection":true}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
 
-	cases.Run(t)
-}
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				_, passed := vars.ToMap()["deletion_protection"]
+				assertEqual(t, "deletion protection should not be passed to the provider", false, passed)
 
-func TestGCPServiceBroker_Unbind(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"good-request": {
-			ServiceState: StateBound,
+				protected, err := broker.DeletionProtection(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting deletion protection", err)
+				assertTrue(t, "deletion protection should be stored", protected)
+			},
+		},
+		"invalid-deletion-protection": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
-				failIfErr(t, "unbinding", err)
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"deletion_protection":"yes"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "deletion_protection must be a boolean", err.Error())
 			},
 		},
-		"good-request-with-credhub": {
-			ServiceState: StateBound,
+		"secret-reference": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
-				failIfErr(t, "unbinding", err)
+				viper.Set("credhub.secret_reference_prefix", "/shared")
+				defer viper.Reset()
+
 				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
-				assertEqual(t, "Credstore DeletePermission call count should match", 1, fcs.DeletePermissionCallCount())
-				assertEqual(t, "Credstore Delete call count should match", 1, fcs.DeleteCallCount())
+				fcs.GetValueReturns("secret-bucket", nil)
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"{{credhub-ref:/shared/bucket}}"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				assertEqual(t, "secret path", "/shared/bucket", fcs.GetValueArgsForCall(0))
+				_, vars := stub.Provider.ProvisionArgsForCall(0)
+				assertEqual(t, "provider should get the secret", "secret-bucket", vars.GetString("name"))
+
+				stored := models.ProvisionRequestDetails{}
+				failIfErr(t, "getting request details", db_service.DbConnection.Where("service_instance_id = ?", fakeInstanceId).First(&stored).Error)
+				assertEqual(t, "only the reference should be stored", `{"name":"{{credhub-ref:/shared/bucket}}"}`, stored.RequestDetails)
 			},
 			Credstore: &credstorefakes.FakeCredStore{},
 		},
-		"multiple-unbinds": {
-			ServiceState: StateUnbound,
+		"org-rate-limited": {
+			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
-				assertEqual(t, "errors should match", brokerapi.ErrBindingDoesNotExist, err)
-			},
-		},
-	}
+				defer viper.Reset()
+				viper.Set("request.org_rate_limit.per_second", 0.001)
+				viper.Set("request.org_rate_limit.burst", 1)
 
-	cases.Run(t)
-}
+				_, err := broker.Provision(context.Background(), "other-instance", stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning within the limit", err)
 
-func TestGCPServiceBroker_LastOperation(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"missing-instance": {
-			ServiceState: StateProvisioned,
-			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), "invalid-instance-id", brokerapi.PollDetails{OperationData: "operationtoken"})
-				assertEqual(t, "errors should match", brokerapi.ErrInstanceDoesNotExist, err)
+				_, err = broker.Provision(context.Background(), "third-instance", stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", `too many requests for organization "", retry after 16m40s`, err.Error())
+
+				// deprovisions are exempt so tenants can always clean up
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
 			},
 		},
-		"called-on-synchronous-service": {
+		"deprovision-plan-mismatch": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+				defer viper.Reset()
+				viper.Set("request.reject_deprovision_plan_mismatch", true)
+
+				req := stub.DeprovisionDetails()
+				req.PlanID = "stale-plan-id"
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", fmt.Sprintf(`plan ID "stale-plan-id" doesn't match the plan %q of instance %q`, stub.PlanId, fakeInstanceId), err.Error())
+
+				viper.Set("request.reject_deprovision_plan_mismatch", false)
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "deprovisioning with a mismatched plan when lenient", err)
 			},
 		},
-		"called-on-async-service": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"unknown-service-id": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "shouldn't be called on async service", err)
-
-				assertEqual(t, "PollInstanceCallCount should match", 1, stub.Provider.PollInstanceCallCount())
+				req := stub.ProvisionDetails()
+				req.ServiceID = "bad-service-id"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "Unknown service ID: \"bad-service-id\"", err.Error())
+				assertEqual(t, "status should be bad request", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
 			},
 		},
-		"poll-returns-retryable-error": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"unknown-plan-id": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, &googleapi.Error{Code: 503})
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "retryable errors should result in in-progress state", brokerapi.InProgress, status.State)
-				assertEqual(t, "description should be error string", "googleapi: got HTTP response code 503 with body: ", status.Description)
+				req := stub.ProvisionDetails()
+				req.PlanID = "bad-plan-id"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "Plan ID \"bad-plan-id\" could not be found", err.Error())
+				assertEqual(t, "status should be bad request", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
 			},
 		},
-		"poll-returns-failure": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"bad-request-json": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, errors.New("not-retryable"))
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "non-retryable errors should result in a failure state", brokerapi.Failed, status.State)
-				assertEqual(t, "description should be error string", "not-retryable", status.Description)
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage("{invalid json")
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
 			},
 		},
-		"poll-returns-not-done": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"non-object-parameters": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(false, nil)
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "polls that return no error should result in an in-progress state", brokerapi.InProgress, status.State)
+				for _, raw := range []string{"null", "[]", "123", " \n "} {
+					req := stub.ProvisionDetails()
+					req.RawParameters = json.RawMessage(raw)
+					_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+					failure, ok := err.(*brokerapi.FailureResponse)
+					assertTrue(t, fmt.Sprintf("%q should be rejected", raw), ok)
+					assertEqual(t, "status should be bad request", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				}
+				assertEqual(t, "provider should not be called", 0, stub.Provider.ProvisionCallCount())
 			},
 		},
-		"poll-returns-success": {
-			AsyncService: true,
-			ServiceState: StateProvisioned,
+		"parameters-too-large": {
+			ServiceState: StateNone,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				stub.Provider.PollInstanceReturns(true, nil)
-				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
-				failIfErr(t, "checking last operation", err)
-				assertEqual(t, "polls that return finished should result in a succeeded state", brokerapi.Succeeded, status.State)
+				viper.Set("request.max_parameter_bytes.provision", 10)
+				defer viper.Reset()
+
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage(`{"name":"too-long"}`)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "parameters are 19 bytes, the maximum allowed size is 10 bytes", err.Error())
 			},
 		},
 	}
@@ -589,119 +1008,3587 @@ func TestGCPServiceBroker_LastOperation(t *testing.T) {
 	cases.Run(t)
 }
 
-func TestGCPServiceBroker_GetBinding(t *testing.T) {
+func TestGCPServiceBroker_Deprovision(t *testing.T) {
+	remainingBindings := func(t *testing.T) int {
+		bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(context.Background(), fakeInstanceId)
+		failIfErr(t, "listing bindings", err)
+		return len(bindings)
+	}
+
+	skipSnapshot := []broker.BrokerVariable{
+		{FieldName: "skip_final_snapshot", Type: broker.JsonTypeBoolean, Details: "Skip the final snapshot.", Default: false},
+	}
+	deprovisionParameters := func(stub *serviceStub) map[string]interface{} {
+		ctx, _, _ := stub.Provider.DeprovisionArgsForCall(0)
+		return broker.GetDeprovisionParameters(ctx)
+	}
+	failedProvision := func(stub *serviceStub) (string, bool) {
+		ctx, _, _ := stub.Provider.DeprovisionArgsForCall(0)
+		return broker.GetFailedProvision(ctx)
+	}
+	failProvision := func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+		stub.Provider.PollInstanceReturns(false, errors.New("quota exceeded"))
+		op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+		failIfErr(t, "polling", err)
+		assertEqual(t, "provision should fail", brokerapi.Failed, op.State)
+	}
+
 	cases := BrokerEndpointTestSuite{
-		"called-on-bound": {
-			ServiceState: StateBound,
+		"deprovision-parameters": {
+			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				stub.ServiceDefinition.Plans[0].DeprovisionInputs = skipSnapshot
+				ctx := WithDeprovisionParameters(context.Background(), json.RawMessage(`{"skip_final_snapshot":true}`))
 
-				assertEqual(t, "expect get binding not supported err", ErrGetBindingsUnsupported, err)
+				_, err := broker.Deprovision(ctx, fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "parameters should be passed to the provider", map[string]interface{}{"skip_final_snapshot": true}, deprovisionParameters(stub))
 			},
 		},
-	}
+		"deprovision-parameters-defaults": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].DeprovisionInputs = skipSnapshot
 
-	cases.Run(t)
-}
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "defaults should be passed to the provider", map[string]interface{}{"skip_final_snapshot": false}, deprovisionParameters(stub))
+			},
+		},
+		"no-deprovision-parameters": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "parameters should be empty", map[string]interface{}{}, deprovisionParameters(stub))
+			},
+		},
+		"invalid-deprovision-parameters": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].DeprovisionInputs = skipSnapshot
+				ctx := WithDeprovisionParameters(context.Background(), json.RawMessage(`{"skip_final_snapshot":"yes"}`))
 
-func TestGCPServiceBroker_GetInstance(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"called-while-provisioned": {
+				_, err := broker.Deprovision(ctx, fakeInstanceId, stub.DeprovisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "expected a failure response", ok)
+				assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"undeclared-deprovision-parameters": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.GetInstance(context.Background(), fakeInstanceId)
+				ctx := WithDeprovisionParameters(context.Background(), json.RawMessage(`{"skip_final_snapshot":true}`))
 
-				assertEqual(t, "expect get instances not supported err", ErrGetInstancesUnsupported, err)
+				_, err := broker.Deprovision(ctx, fakeInstanceId, stub.DeprovisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "expected a failure response", ok)
+				assertEqual(t, "status should match", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.DeprovisionCallCount())
 			},
 		},
-	}
+		"bindings-kept-by-default": {
+			ServiceState: StateBound,
+			Credstore:    &credstorefakes.FakeCredStore{},
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
 
-	cases.Run(t)
-}
+				assertEqual(t, "bindings should be kept", 1, remainingBindings(t))
+				assertEqual(t, "credentials should be kept", 0, broker.Credstore.(*credstorefakes.FakeCredStore).DeleteCallCount())
+			},
+		},
+		"cleanup-bindings": {
+			ServiceState: StateBound,
+			Credstore:    &credstorefakes.FakeCredStore{},
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("request.cleanup_bindings_on_deprovision", true)
 
-func TestGCPServiceBroker_LastBindingOperation(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
-		"called-while-bound": {
-			ServiceState: StateProvisioned,
-			AsyncService: true,
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "bindings should be removed", 0, remainingBindings(t))
+				assertEqual(t, "permissions should be deleted", 1, fcs.DeletePermissionCallCount())
+				assertEqual(t, "credentials should be deleted", 1, fcs.DeleteCallCount())
+				assertEqual(t, "credential name should match", fmt.Sprintf("/c/csb/%s/%s/secrets-and-services", stub.ServiceDefinition.Name, fakeBindingId), fcs.DeleteArgsForCall(0))
+			},
+		},
+		"cleanup-bindings-credstore-failure": {
+			ServiceState: StateBound,
+			Credstore:    &credstorefakes.FakeCredStore{},
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.LastBindingOperation(context.Background(), fakeInstanceId, fakeBindingId, brokerapi.PollDetails{})
+				defer viper.Reset()
+				viper.Set("request.cleanup_bindings_on_deprovision", true)
+				broker.Credstore.(*credstorefakes.FakeCredStore).DeleteReturns(errors.New("credhub is down"))
 
-				assertEqual(t, "expect last binding to return async required", brokerapi.ErrAsyncRequired, err)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "the binding should be kept", 1, remainingBindings(t))
 			},
 		},
-	}
+		"cleanup-bindings-async": {
+			AsyncService: true,
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("request.cleanup_bindings_on_deprovision", true)
+				operationID := "deprovision-op"
+				stub.Provider.DeprovisionReturns(&operationID, nil)
 
-	cases.Run(t)
-}
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "bindings should be kept until the deletion completes", 1, remainingBindings(t))
 
-func TestGCPServiceBroker_Update(t *testing.T) {
-	cases := BrokerEndpointTestSuite{
+				stub.Provider.PollInstanceReturns(true, nil)
+				op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationID})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "deprovision should succeed", brokerapi.Succeeded, op.State)
+				assertEqual(t, "bindings should be removed", 0, remainingBindings(t))
+			},
+		},
 		"good-request": {
 			ServiceState: StateProvisioned,
-			AsyncService: true,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
 
-				failIfErr(t, "update", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+
+				_, failed := failedProvision(stub)
+				assertTrue(t, "provisioned instances shouldn't be marked as failed", !failed)
 			},
 		},
-		"missing-instance": {
-			ServiceState: StateProvisioned,
+		"failed-provision": {
 			AsyncService: true,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Update(context.Background(), "bogus-id", stub.UpdateDetails(), true)
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "provision-op"}, nil)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				failProvision(t, broker, stub)
 
-				assertEqual(t, "expect update error to be instance does not exist", brokerapi.ErrInstanceDoesNotExist, err)
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				reason, failed := failedProvision(stub)
+				assertTrue(t, "the provider should be told the provision failed", failed)
+				assertEqual(t, "the provider should get the provision's error", "quota exceeded", reason)
+
+				_, err = db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				assertEqual(t, "the instance should be deleted", gorm.ErrRecordNotFound, err)
 			},
-		},		
-		"requires-async": {
+		},
+		"failed-provision-async-deprovision": {
 			AsyncService: true,
-			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				// false for async support
-				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), false)
-				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "provision-op"}, nil)
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				failProvision(t, broker, stub)
+
+				operationID := "deprovision-op"
+				stub.Provider.DeprovisionReturns(&operationID, nil)
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				stub.Provider.PollInstanceReturns(true, nil)
+				op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationID})
+				failIfErr(t, "polling", err)
+				assertEqual(t, "deprovision should succeed", brokerapi.Succeeded, op.State)
+
+				_, err = db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				assertEqual(t, "the instance should be deleted", gorm.ErrRecordNotFound, err)
 			},
 		},
-		"instance-does-not-exist": {
+		"service-left-catalog": {
+			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
-				assertEqual(t, "instance does not exist should be set", brokerapi.ErrInstanceDoesNotExist, err)
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.ServiceId = "removed-service-id"
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				_, err = broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be not found", http.StatusNotFound, failure.ValidatedStatusCode(nil))
 			},
 		},
-		"bad-request-json": {
-			AsyncService: true,
+		"deletion-protected": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.UpdateDetails()
-				req.RawParameters = json.RawMessage("{invalid json")
-				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
-				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+				failIfErr(t, "protecting instance", broker.SetDeletionProtection(context.Background(), fakeInstanceId, true))
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable entity", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.DeprovisionCallCount())
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "instance should still exist", exists)
 			},
-		},		
-		"attempt-to-update-non-updatable-parameter": {
-			AsyncService: true,
+		},
+		"deletion-protection-overridden": {
 			ServiceState: StateProvisioned,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.UpdateDetails()
-				req.RawParameters = json.RawMessage(`{"location":"new-location"}`)
-				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
-				assertEqual(t, "errors should match", ErrNonUpdatableParameter, err)
+				failIfErr(t, "protecting instance", broker.SetDeletionProtection(context.Background(), fakeInstanceId, true))
+
+				handler := AddDeletionProtectionOverrideToContext(brokerapi.New(broker, utils.NewLogger("brokers-test"), brokerapi.BrokerCredentials{Username: "user", Password: "pass"}))
+
+				url := fmt.Sprintf("/v2/service_instances/%s?service_id=%s&plan_id=%s&accepts_incomplete=true&override_deletion_protection=true", fakeInstanceId, stub.ServiceId, stub.PlanId)
+				req := httptest.NewRequest(http.MethodDelete, url, nil)
+				req.SetBasicAuth("user", "pass")
+				req.Header.Set("X-Broker-API-Version", "2.14")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				assertEqual(t, "status code should be 200 OK", http.StatusOK, w.Code)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
 			},
 		},
-		"good-request-valid-parameter": {
+		"deletion-protection-disabled": {
 			ServiceState: StateProvisioned,
-			AsyncService: true,
 			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
-				req := stub.UpdateDetails()
-				req.RawParameters = json.RawMessage(`{"force_delete":"false"}`)
-				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "protecting instance", broker.SetDeletionProtection(context.Background(), fakeInstanceId, true))
+				failIfErr(t, "unprotecting instance", broker.SetDeletionProtection(context.Background(), fakeInstanceId, false))
 
-				failIfErr(t, "update", err)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
 			},
 		},
-	}
-
-	cases.Run(t)
-}
\ No newline at end of file
+		"bindings-check-off": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"bindings-check-warn": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.deprovision_bindings_check", "warn")
+				defer viper.Reset()
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"bindings-check-reject": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.deprovision_bindings_check", "reject")
+				defer viper.Reset()
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable entity", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				assertTrue(t, "error should contain the binding count", strings.Contains(err.Error(), "1 binding(s)"))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"bindings-check-reject-unbound": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.deprovision_bindings_check", "reject")
+				defer viper.Reset()
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"bindings-check-forced": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.deprovision_bindings_check", "reject")
+				defer viper.Reset()
+
+				handler := AddForceDeprovisionToContext(brokerapi.New(broker, utils.NewLogger("brokers-test"), brokerapi.BrokerCredentials{Username: "user", Password: "pass"}))
+
+				url := fmt.Sprintf("/v2/service_instances/%s?service_id=%s&plan_id=%s&accepts_incomplete=true&force=true", fakeInstanceId, stub.ServiceId, stub.PlanId)
+				req := httptest.NewRequest(http.MethodDelete, url, nil)
+				req.SetBasicAuth("user", "pass")
+				req.Header.Set("X-Broker-API-Version", "2.14")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				assertEqual(t, "status code should be 200 OK", http.StatusOK, w.Code)
+				assertEqual(t, "deprovision calls should match", 1, stub.Provider.DeprovisionCallCount())
+			},
+		},
+		"duplicate-deprovision": {
+			ServiceState: StateDeprovisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				assertEqual(t, "duplicate deprovision should lead to DNE", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"instance-does-not-exist": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				assertEqual(t, "instance does not exist should be set", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"instance-does-not-exist-is-gone": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				handler := brokerapi.New(broker, utils.NewLogger("brokers-test"), brokerapi.BrokerCredentials{Username: "user", Password: "pass"})
+
+				url := fmt.Sprintf("/v2/service_instances/%s?service_id=%s&plan_id=%s&accepts_incomplete=true", fakeInstanceId, stub.ServiceId, stub.PlanId)
+				req := httptest.NewRequest(http.MethodDelete, url, nil)
+				req.SetBasicAuth("user", "pass")
+				req.Header.Set("X-Broker-API-Version", "2.14")
+
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+
+				assertEqual(t, "status code should be 410 Gone", http.StatusGone, w.Code)
+			},
+		},
+		"async-required": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), false)
+				assertEqual(t, "async required should be returned if not supported", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+		"async-deprovision-returns-operation": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "my-operation-id"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				resp, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				assertEqual(t, "operationid should be set as the data", operationId, resp.OperationData)
+				assertEqual(t, "IsAsync should be set", true, resp.IsAsync)
+			},
+		},
+
+		"async-deprovision-updates-db": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "my-operation-id"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				details, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "looking up details", err)
+
+				assertEqual(t, "OperationId should be set as the data", operationId, details.OperationId)
+				assertEqual(t, "OperationType should be set as Deprovision", models.DeprovisionOperationType, details.OperationType)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Bind(t *testing.T) {
+	credstoreDown := errors.New("credhub is down")
+	iamLag := &broker.RetryableBindError{Err: errors.New("service account not found yet")}
+
+	// bindWithHeaders binds and returns the binding and the headers of the
+	// response.
+	bindWithHeaders := func(t *testing.T, serviceBroker *ServiceBroker, stub *serviceStub) (brokerapi.Binding, http.Header) {
+		headers := broker.NewResponseHeaders()
+		binding, err := serviceBroker.Bind(broker.WithResponseHeaders(context.Background(), headers), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+		failIfErr(t, "binding", err)
+
+		response := http.Header{}
+		headers.ApplyTo(response)
+		return binding, response
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"good-request": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				assertEqual(t, "BindCallCount should match", 1, stub.Provider.BindCallCount())
+				assertEqual(t, "BuildInstanceCredentialsCallCount should match", 1, stub.Provider.BuildInstanceCredentialsCallCount())
+			},
+		},
+		"good-request-with-credstore": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				assertEqual(t, "BindCallCount should match", 1, stub.Provider.BindCallCount())
+				assertEqual(t, "BuildInstanceCredentialsCallCount should match", 1, stub.Provider.BuildInstanceCredentialsCallCount())
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "Credstore Put call count should match", 1, fcs.PutCallCount())
+				assertEqual(t, "Credstore AddPermission call count should match", 1, fcs.AddPermissionCallCount())
+				_, actor, _ := fcs.AddPermissionArgsForCall(0)
+				assertEqual(t, "the app should be allowed to read the credentials", "mtls-app:"+fakeAppGuid, actor)
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"service-key-with-credstore": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				binding, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.ServiceKeyBindDetails(), true)
+				failIfErr(t, "creating service key", err)
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "credentials should be stored", 1, fcs.PutCallCount())
+				assertEqual(t, "no app permission should be added", 0, fcs.AddPermissionCallCount())
+				assertEqual(t, "the reference should be returned", map[string]interface{}{"credhub-ref": fmt.Sprintf("/c/csb/%s/%s/secrets-and-services", stub.ServiceDefinition.Name, fakeBindingId)}, binding.Credentials)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "deleting service key", err)
+				assertEqual(t, "permissions should be cleaned up", 1, fcs.DeletePermissionCallCount())
+				assertEqual(t, "credentials should be deleted", 1, fcs.DeleteCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"credstore-unavailable": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				fcs.PutReturns(nil, credstoreDown)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", "Bind failure: unable to put credentials in Credstore: credhub is down", err.Error())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"credstore-unavailable-returns-raw-credentials": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("credhub.put_failure_mode", "raw")
+				defer viper.Reset()
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				fcs.PutReturns(nil, credstoreDown)
+
+				binding, headers := bindWithHeaders(t, broker, stub)
+				_, isReference := binding.Credentials.(map[string]interface{})["credhub-ref"]
+				assertEqual(t, "credentials should be returned directly", false, isReference)
+				assertTrue(t, "a warning should be returned", headers.Get("Warning") != "")
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"credstore-unavailable-retries-write": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("credhub.put_failure_mode", "retry")
+				viper.Set("credhub.put_retry_interval", "10ms")
+				defer viper.Reset()
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				fcs.PutReturnsOnCall(0, nil, credstoreDown)
+
+				binding, headers := bindWithHeaders(t, broker, stub)
+				_, isReference := binding.Credentials.(map[string]interface{})["credhub-ref"]
+				assertTrue(t, "the reference should be returned", isReference)
+				assertEqual(t, "no warning should be returned", "", headers.Get("Warning"))
+
+				deadline := time.Now().Add(5 * time.Second)
+				for fcs.AddPermissionCallCount() == 0 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+				}
+				assertEqual(t, "Credstore Put should be retried", 2, fcs.PutCallCount())
+				assertEqual(t, "Credstore AddPermission call count should match", 1, fcs.AddPermissionCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"credstore-retry-cancelled-by-unbind": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("credhub.put_failure_mode", "retry")
+				viper.Set("credhub.put_retry_interval", "1h")
+				defer viper.Reset()
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				fcs.PutReturns(nil, credstoreDown)
+
+				bindWithHeaders(t, broker, stub)
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "credentials that were never written shouldn't be deleted", 0, fcs.DeleteCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"credential-key-mapping": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set(stub.ServiceDefinition.CredentialKeysProperty(), `{"foo":"hostname","mynameis":"name"}`)
+
+				binding, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				// the credentials as the app sees them in VCAP_SERVICES
+				vcap, err := json.Marshal(map[string]interface{}{
+					stub.ServiceDefinition.Name: []interface{}{map[string]interface{}{"credentials": binding.Credentials}},
+				})
+				failIfErr(t, "serializing VCAP_SERVICES", err)
+				assertEqual(t, "VCAP_SERVICES should use the mapped keys", `{"google-storage":[{"credentials":{"hostname":"bar","name":"instancename"}}]}`, string(vcap))
+
+				retrieved, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "retrieved credentials should use the mapped keys", binding.Credentials, retrieved.Credentials)
+			},
+		},
+		"invalid-credential-key-mapping": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set(stub.ServiceDefinition.CredentialKeysProperty(), `{"foo":"host","mynameis":"host"}`)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", `service.google-storage.bind.credential_keys: credentials "foo" and "mynameis" are both renamed to "host"`, err.Error())
+				assertEqual(t, "the provider shouldn't bind", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"syslog-drain-url": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Requires = []brokerapi.RequiredPermission{brokerapi.PermissionSyslogDrain}
+				stub.Provider.BindReturns(map[string]interface{}{"syslog_drain_url": "syslog-tls://logs.example.com:6514"}, nil)
+
+				binding, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "syslog drain URL should be returned", "syslog-tls://logs.example.com:6514", binding.SyslogDrainURL)
+
+				_, hasDrainCred := binding.Credentials.(map[string]interface{})["syslog_drain_url"]
+				assertTrue(t, "syslog drain URL should not be part of the credentials", !hasDrainCred)
+
+				record, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding record", err)
+				assertEqual(t, "syslog drain URL should be persisted", "syslog-tls://logs.example.com:6514", record.SyslogDrainURL)
+			},
+		},
+		"syslog-drain-url-not-required": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.BindReturns(map[string]interface{}{"syslog_drain_url": "syslog-tls://logs.example.com:6514"}, nil)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				expectedErr := `Bind failure: the binding returned a syslog_drain_url but service "google-storage" does not require "syslog_drain"`
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+			},
+		},
+		"route-service-url": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Requires = []brokerapi.RequiredPermission{brokerapi.PermissionRouteForwarding}
+				stub.Provider.BindReturns(map[string]interface{}{"route_service_url": "https://proxy.example.com"}, nil)
+
+				binding, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "route service URL should be returned", "https://proxy.example.com", binding.RouteServiceURL)
+
+				_, hasRouteCred := binding.Credentials.(map[string]interface{})["route_service_url"]
+				assertTrue(t, "route service URL should not be part of the credentials", !hasRouteCred)
+
+				fetched, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "route service URL should be persisted", "https://proxy.example.com", fetched.RouteServiceURL)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+
+				exists, err := db_service.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "checking binding", err)
+				assertTrue(t, "binding should be removed", !exists)
+			},
+		},
+		"route-service-url-not-required": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.BindReturns(map[string]interface{}{"route_service_url": "https://proxy.example.com"}, nil)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				expectedErr := `Bind failure: the binding returned a route_service_url but service "google-storage" does not require "route_forwarding"`
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+			},
+		},
+		"plan-role": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].Roles = []string{"storage.objectViewer"}
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"role":"storage.objectViewer"}`)
+
+				binding, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "role should be part of the credentials", "storage.objectViewer", binding.Credentials.(map[string]interface{})["role"])
+
+				record, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding record", err)
+				assertEqual(t, "role should be persisted", "storage.objectViewer", record.Role)
+			},
+		},
+		"plan-role-not-allowed": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].Roles = []string{"storage.objectViewer", "storage.objectCreator"}
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"role":"storage.objectAdmin"}`)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				expectedErr := `role "storage.objectAdmin" is not valid for plan "standard", valid roles are: storage.objectViewer, storage.objectCreator`
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+				assertEqual(t, "status should be 400", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+			},
+		},
+		"binding-name": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"name":"reporting"}`)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding", err)
+
+				record, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding record", err)
+				assertEqual(t, "name should be persisted", "reporting", record.Name)
+			},
+		},
+		"binding-name-in-use": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"name":"reporting"}`)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding", err)
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "other-binding", req, true)
+				expectedErr := fmt.Sprintf(`binding %s of the instance is already named "reporting"`, fakeBindingId)
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+				assertEqual(t, "status should be 400", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+				assertEqual(t, "provider should be called once", 1, stub.Provider.BindCallCount())
+			},
+		},
+		"binding-name-invalid": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"name":"root"}`)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				assertEqual(t, "status should be 400", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"duplicate-request": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", brokerapi.ErrBindingAlreadyExists, err)
+			},
+		},
+		"retryable-failure-retried": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.bind_retries", 3)
+				viper.Set("request.bind_retry_interval", "1ms")
+				defer viper.Reset()
+				stub.Provider.BindReturnsOnCall(0, nil, iamLag)
+				stub.Provider.BindReturnsOnCall(1, nil, iamLag)
+				stub.Provider.BindReturnsOnCall(2, map[string]interface{}{"foo": "bar"}, nil)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "BindCallCount should match", 3, stub.Provider.BindCallCount())
+			},
+		},
+		"retryable-failure-gives-up": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.bind_retries", 2)
+				viper.Set("request.bind_retry_interval", "1ms")
+				defer viper.Reset()
+				stub.Provider.BindReturns(nil, iamLag)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", "service account not found yet", fmt.Sprint(err))
+				assertEqual(t, "BindCallCount should match", 3, stub.Provider.BindCallCount())
+
+				exists, err := db_service.ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "checking binding", err)
+				assertTrue(t, "the binding should not be recorded", !exists)
+			},
+		},
+		"non-retryable-failure-not-retried": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.BindReturns(nil, errors.New("permission denied"))
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				assertEqual(t, "errors should match", "permission denied", fmt.Sprint(err))
+				assertEqual(t, "BindCallCount should match", 1, stub.Provider.BindCallCount())
+			},
+		},
+		"bad-bind-call": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"role":"project.admin"}`)
+
+				expectedErr := "1 error(s) occurred: role: role must be one of the following: \"storage.objectAdmin\", \"storage.objectCreator\", \"storage.objectViewer\""
+				_, err := broker.Bind(context.Background(), fakeInstanceId, "bad-bind-call", req, true)
+				assertEqual(t, "errors should match", expectedErr, err.Error())
+			},
+		},
+		"bad-request-json": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage("{invalid json")
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+			},
+		},
+		"non-object-parameters": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage("null")
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				assertEqual(t, "errors should match", "User supplied parameters must be a JSON object, got null.", err.Error())
+				assertEqual(t, "provider should not be called", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"parameters-too-large": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.max_parameter_bytes.bind", 10)
+				defer viper.Reset()
+
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"role":"too-long"}`)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				assertEqual(t, "errors should match", "parameters are 19 bytes, the maximum allowed size is 10 bytes", err.Error())
+			},
+		},
+		"bind-variables-override-instance-variables": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				bindResult, err := broker.Bind(context.Background(), fakeInstanceId, "override-params", req, true)
+				failIfErr(t, "binding", err)
+				credMap, ok := bindResult.Credentials.(map[string]interface{})
+				assertTrue(t, "bind result credentials should be a map", ok)
+				assertEqual(t, "credential overridden", "bar", credMap["foo"].(string))
+			},
+		},
+		"bind-records-app": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.BindResource = &brokerapi.BindResource{AppGuid: "app-guid"}
+				req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","app_name":"my-app"}`)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, "app-binding", req, true)
+				failIfErr(t, "binding", err)
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "service-key", stub.ServiceKeyBindDetails(), true)
+				failIfErr(t, "creating service key", err)
+
+				bindings, err := broker.InstanceBindings(context.Background(), fakeInstanceId)
+				failIfErr(t, "listing bindings", err)
+				assertEqual(t, "binding count", 2, len(bindings))
+				assertEqual(t, "app guid", "app-guid", bindings[0].AppGuid)
+				assertEqual(t, "app name", "my-app", bindings[0].AppName)
+				assertEqual(t, "service key app guid", "", bindings[1].AppGuid)
+				assertEqual(t, "service key app name", "", bindings[1].AppName)
+
+				_, err = broker.InstanceBindings(context.Background(), "missing-instance")
+				assertEqual(t, "unknown instances should not be found", ErrInstanceNotFound, err)
+			},
+		},
+		"credential-format": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"credential_format":"env"}`)
+				bindResult, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding", err)
+				credMap, ok := bindResult.Credentials.(map[string]interface{})
+				assertTrue(t, "bind result credentials should be a map", ok)
+				assertEqual(t, "credentials should be formatted", "bar", credMap["FOO"])
+
+				_, vc := stub.Provider.BindArgsForCall(0)
+				_, passed := vc.ToMap()["credential_format"]
+				assertTrue(t, "format shouldn't be passed to the provider", !passed)
+
+				record, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "raw credentials should be stored", `{"foo":"bar"}`, record.OtherDetails)
+
+				fetched, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "fetched credentials should be formatted", "bar", fetched.Credentials.(map[string]interface{})["FOO"])
+			},
+		},
+		"files-credential-format": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"credential_format":"files"}`)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding", err)
+
+				fetched, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				files, ok := fetched.Credentials.(map[string]interface{})["files"].(map[string]interface{})
+				assertTrue(t, "fetched credentials should be files", ok)
+				assertEqual(t, "files should hold the credentials", "bar", files["foo"])
+			},
+		},
+		"plan-not-bindable": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].Bindable = brokerapi.BindableValue(false)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be 400", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "provider shouldn't be called", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"invalid-credential-format": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.RawParameters = json.RawMessage(`{"credential_format":"yaml"}`)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				assertEqual(t, "status should be 400", http.StatusBadRequest, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+				assertEqual(t, "provider shouldn't be called", 0, stub.Provider.BindCallCount())
+			},
+		},
+		"bind-from-other-space-allowed-by-default": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provision := stub.ProvisionDetails()
+				provision.SpaceGUID = "instance-space"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, provision, true)
+				failIfErr(t, "provisioning", err)
+
+				req := stub.BindDetails()
+				req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","space_guid":"other-space"}`)
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding from another space", err)
+			},
+		},
+		"bind-space-enforced": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.enforce_bind_space", true)
+				defer viper.Reset()
+
+				provision := stub.ProvisionDetails()
+				provision.SpaceGUID = "instance-space"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, provision, true)
+				failIfErr(t, "provisioning", err)
+
+				req := stub.BindDetails()
+				req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","space_guid":"other-space"}`)
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "other-space-binding", req, true)
+				assertEqual(t, "status should be 403", http.StatusForbidden, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+
+				req.RawContext = nil
+				req.BindResource = &brokerapi.BindResource{AppGuid: "app-guid", SpaceGuid: "instance-space"}
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "same-space-binding", req, true)
+				failIfErr(t, "binding from the same space", err)
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "service-key", stub.ServiceKeyBindDetails(), true)
+				failIfErr(t, "creating a service key", err)
+			},
+		},
+		"bind-space-enforced-shared": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.enforce_bind_space", true)
+				viper.Set("compatibility.enable-cf-sharing", true)
+				defer viper.Reset()
+
+				provision := stub.ProvisionDetails()
+				provision.SpaceGUID = "instance-space"
+				provision.OrganizationGUID = "instance-org"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, provision, true)
+				failIfErr(t, "provisioning", err)
+
+				req := stub.BindDetails()
+				req.RawContext = json.RawMessage(`{"platform":"cloudfoundry","organization_guid":"other-org","space_guid":"other-space"}`)
+				_, err = broker.Bind(context.Background(), fakeInstanceId, "not-allowed-binding", req, true)
+				assertEqual(t, "shareable services should be enforced by default", http.StatusForbidden, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+
+				viper.Set("request.enforce_bind_space_allow_shared", true)
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failIfErr(t, "binding from a space the instance is shared with", err)
+
+				binding, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "binding space should be recorded", "other-space", binding.SpaceGuid)
+				assertEqual(t, "binding organization should be recorded", "other-org", binding.OrganizationGuid)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding from the shared space", err)
+				assertEqual(t, "unbind calls should match", 1, stub.Provider.UnbindCallCount())
+			},
+		},
+		"unknown-plan-id": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				req.PlanID = "bad-plan-id"
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, req, true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be bad request", http.StatusBadRequest, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "error key should match", "plan-not-found", failure.LoggerAction())
+			},
+		},
+		"bind-during-pending-provision": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.OperationType = models.ProvisionOperationType
+				instance.OperationId = "provision-op"
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				assertTrue(t, "error should tell to wait", strings.Contains(err.Error(), "wait for it to complete"))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.BindCallCount())
+
+				stub.Provider.PollInstanceReturns(true, nil)
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding after the provision completed", err)
+			},
+		},
+		"bind-returns-credhub-ref": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.BindDetails()
+				bindResult, err := broker.Bind(context.Background(), fakeInstanceId, "override-params", req, true)
+				failIfErr(t, "binding", err)
+				credMap, ok := bindResult.Credentials.(map[string]interface{})
+				assertTrue(t, "bind result credentials should be a map", ok)
+				assertTrue(t, "value foo missing", credMap["foo"] == nil)	
+				assertTrue(t, "cred-hub ref exists", credMap["credhub-ref"] != nil)	
+				assertEqual(t, "cred-hub ref has correct value", "/c/csb/google-storage/override-params/secrets-and-services", credMap["credhub-ref"].(string))		
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},	
+	}
+
+	cases.Run(t)
+}
+
+// networkPolicyProvider is a ServiceProvider creating network policies for
+// bound apps.
+type networkPolicyProvider struct {
+	*brokerfakes.FakeServiceProvider
+	created []string
+	deleted []string
+}
+
+func (p *networkPolicyProvider) CreateNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, appGUID string) (string, error) {
+	p.created = append(p.created, appGUID)
+	return "policy-" + appGUID, nil
+}
+
+func (p *networkPolicyProvider) DeleteNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, policyID string) error {
+	p.deleted = append(p.deleted, policyID)
+	return nil
+}
+
+func TestGCPServiceBroker_NetworkPolicy(t *testing.T) {
+	withPolicies := func(stub *serviceStub) *networkPolicyProvider {
+		stub.ServiceDefinition.Plans[0].NetworkPolicy = true
+		provider := &networkPolicyProvider{FakeServiceProvider: stub.Provider}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+		return provider
+	}
+	appBinding := func(stub *serviceStub, appGUID string) brokerapi.BindDetails {
+		details := stub.BindDetails()
+		details.AppGUID = appGUID
+		return details
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"shared-by-bindings-of-an-app": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := withPolicies(stub)
+
+				for _, bindingID := range []string{"binding-1", "binding-2"} {
+					_, err := broker.Bind(context.Background(), fakeInstanceId, bindingID, appBinding(stub, "app-a"), true)
+					failIfErr(t, "binding", err)
+				}
+				_, err := broker.Bind(context.Background(), fakeInstanceId, "binding-3", appBinding(stub, "app-b"), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "one policy per app should be created", []string{"app-a", "app-b"}, provider.created)
+
+				binding, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, "binding-2")
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "policy should be recorded", "policy-app-a", binding.NetworkPolicyId)
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, "binding-1", stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "shared policy should be kept", 0, len(provider.deleted))
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, "binding-2", stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				assertEqual(t, "policy should be deleted with the last binding", []string{"policy-app-a"}, provider.deleted)
+			},
+		},
+		"service-keys-get-none": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := withPolicies(stub)
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.ServiceKeyBindDetails(), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "no policy should be created", 0, len(provider.created))
+			},
+		},
+		"opt-in": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := withPolicies(stub)
+				stub.ServiceDefinition.Plans[0].NetworkPolicy = false
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, appBinding(stub, "app-a"), true)
+				failIfErr(t, "binding", err)
+				assertEqual(t, "no policy should be created", 0, len(provider.created))
+			},
+		},
+		"unsupported": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].NetworkPolicy = true
+
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, appBinding(stub, "app-a"), true)
+				assertTrue(t, "bind should fail", err != nil && strings.Contains(err.Error(), "doesn't support them"))
+				assertEqual(t, "provider should not be called", 0, stub.Provider.BindCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Unbind(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"good-request": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+			},
+		},
+		"good-request-with-credhub": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "Credstore DeletePermission call count should match", 1, fcs.DeletePermissionCallCount())
+				assertEqual(t, "Credstore Delete call count should match", 1, fcs.DeleteCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"binding-without-app-guid-with-credhub": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				// bindings created before app GUIDs were recorded
+				binding, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				binding.AppGuid = ""
+				failIfErr(t, "saving binding", db_service.SaveServiceBindingCredentials(context.Background(), binding))
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding", err)
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				assertEqual(t, "the app permission should be deleted", 1, fcs.DeletePermissionCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"multiple-unbinds": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				assertEqual(t, "errors should match", brokerapi.ErrBindingDoesNotExist, err)
+			},
+		},
+		"idempotent-unbind": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.idempotent_unbind", true)
+				defer viper.Reset()
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				fcs.DeleteReturns(errors.New("credential not found"))
+				deletesBefore := fcs.DeleteCallCount()
+
+				_, err := broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding a missing binding", err)
+				assertEqual(t, "Credstore cleanup should be attempted", deletesBefore+1, fcs.DeleteCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_IdempotentProvision(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"retry-replays-response": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				first, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				retry, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "retrying", err)
+				assertEqual(t, "retry should get the same response", first, retry)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+
+				_, err = broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertEqual(t, "requests without identity aren't replayed", brokerapi.ErrInstanceAlreadyExists, err)
+			},
+		},
+		"failed-request-can-be-retried": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				req := stub.ProvisionDetails()
+				req.RawParameters = json.RawMessage("{invalid json")
+				_, err := broker.Provision(ctx, fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+
+				_, err = broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "retrying", err)
+			},
+		},
+		"request-in-progress": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				running := models.IdempotencyKey{RequestIdentity: "request-1", ServiceInstanceId: fakeInstanceId, State: string(brokerapi.InProgress)}
+				failIfErr(t, "recording request", db_service.CreateIdempotencyKey(context.Background(), &running))
+
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", ErrDuplicateRequestInProgress, err)
+				assertEqual(t, "provision calls should match", 0, stub.Provider.ProvisionCallCount())
+
+				_, err = db_service.GetIdempotencyKeyByRequestIdentity(context.Background(), "request-1")
+				failIfErr(t, "the running request's key should be kept", err)
+			},
+		},
+		"finished-request-expires": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				expired := time.Now().Add(-time.Minute)
+				finished := models.IdempotencyKey{RequestIdentity: "request-1", ServiceInstanceId: fakeInstanceId, State: string(brokerapi.Succeeded), Response: `{}`, ExpiresAt: &expired}
+				failIfErr(t, "recording request", db_service.CreateIdempotencyKey(context.Background(), &finished))
+
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "retrying", err)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"running-request-outlives-ttl": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("request.idempotency_key_ttl", time.Millisecond)
+
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				retried := false
+				stub.Provider.ProvisionStub = func(context.Context, *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+					if retried {
+						return models.ServiceInstanceDetails{}, nil
+					}
+					retried = true
+
+					// the provision takes longer than the TTL
+					time.Sleep(10 * time.Millisecond)
+
+					_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+					assertEqual(t, "the retry should be rejected", ErrDuplicateRequestInProgress, err)
+					return models.ServiceInstanceDetails{}, nil
+				}
+
+				_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertEqual(t, "provision calls should match", 1, stub.Provider.ProvisionCallCount())
+			},
+		},
+		"identity-reused-for-other-instance": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				ctx := WithRequestIdentity(context.Background(), "request-1")
+				_, err := broker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				_, err = broker.Provision(ctx, "other-instance", stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", "the request identity was already used for another instance", err.Error())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// asyncOnlyProvider is a ServiceProvider that can't be forced to operate
+// synchronously.
+type asyncOnlyProvider struct {
+	*brokerfakes.FakeServiceProvider
+}
+
+func (p *asyncOnlyProvider) AsyncOnly() bool {
+	return true
+}
+
+func TestGCPServiceBroker_ForceSync(t *testing.T) {
+	forceSync := func(timeout string) func() {
+		viper.Set("request.force_sync", true)
+		viper.Set("request.force_sync_timeout", timeout)
+		return viper.Reset
+	}
+
+	asyncOnly := func(stub *serviceStub) {
+		provider := &asyncOnlyProvider{FakeServiceProvider: stub.Provider}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"provision-waits-for-completion": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer forceSync("1m")()
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				spec, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), false)
+				failIfErr(t, "provisioning", err)
+				assertTrue(t, "provision should be synchronous", !spec.IsAsync)
+				assertEqual(t, "provider should be polled", 1, stub.Provider.PollInstanceCallCount())
+				assertEqual(t, "instance details should be updated", 1, stub.Provider.UpdateInstanceDetailsCallCount())
+
+				history, err := broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertEqual(t, "operation should be finished", string(brokerapi.Succeeded), history[0].State)
+			},
+		},
+		"provision-failure": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer forceSync("1m")()
+				stub.Provider.PollInstanceReturns(false, errors.New("quota exceeded"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), false)
+				assertEqual(t, "errors should match", "quota exceeded", err.Error())
+			},
+		},
+		"provision-timeout": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer forceSync("1ms")()
+				stub.Provider.PollInstanceReturns(false, nil)
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), false)
+				assertEqual(t, "errors should match", "the operation didn't complete within 1ms, it continues in the background", err.Error())
+				assertEqual(t, "status should be 504", http.StatusGatewayTimeout, err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil))
+
+				spec, err := broker.Provision(context.Background(), "other-instance", stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertTrue(t, "clients accepting async operations should poll", spec.IsAsync)
+			},
+		},
+		"deprovision-waits-for-completion": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer forceSync("1m")()
+				operationID := "operationtoken"
+				stub.Provider.DeprovisionReturns(&operationID, nil)
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				spec, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), false)
+				failIfErr(t, "deprovisioning", err)
+				assertTrue(t, "deprovision should be synchronous", !spec.IsAsync)
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "instance should be deleted", !exists)
+			},
+		},
+		"async-only-provider": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer forceSync("1m")()
+				asyncOnly(stub)
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", `service "google-storage" only supports asynchronous operations, but the broker is configured to force synchronous operations with request.force_sync`, err.Error())
+				assertEqual(t, "provider shouldn't be called", 0, stub.Provider.ProvisionCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_ReadinessProbe(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	probe := &broker.ReadinessProbe{Type: broker.ReadinessProbeHTTP, Output: "uri", Timeout: "1h"}
+	provisioning := func(t *testing.T, stub *serviceStub, age time.Duration) {
+		stub.ServiceDefinition.Plans[0].ReadinessProbe = probe
+		stub.Provider.PollInstanceReturns(true, nil)
+		stub.Provider.UpdateInstanceDetailsStub = func(ctx context.Context, instance *models.ServiceInstanceDetails) error {
+			return instance.SetOtherDetails(map[string]string{"uri": server.URL})
+		}
+
+		instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+		failIfErr(t, "getting instance", err)
+		instance.OperationType = models.ProvisionOperationType
+		instance.CreatedAt = time.Now().Add(-age)
+		failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"waits-until-ready": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisioning(t, stub, time.Minute)
+
+				status = http.StatusServiceUnavailable
+				op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "provision should be in progress", brokerapi.InProgress, op.State)
+				assertTrue(t, "description should show the probe status", strings.Contains(op.Description, "503 Service Unavailable"))
+
+				status = http.StatusOK
+				op, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "provision should succeed", brokerapi.Succeeded, op.State)
+			},
+		},
+		"probe-timeout": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisioning(t, stub, 2*time.Hour)
+
+				status = http.StatusServiceUnavailable
+				op, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "provision should fail", brokerapi.Failed, op.State)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// verifyingProvider is a ServiceProvider verifying provisioned instances.
+type verifyingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	err      error
+	verified int
+}
+
+func (p *verifyingProvider) VerifyProvision(ctx context.Context, instance models.ServiceInstanceDetails) error {
+	p.verified++
+	return p.err
+}
+
+func TestGCPServiceBroker_VerifyProvision(t *testing.T) {
+	verifyWith := func(stub *serviceStub, err error) *verifyingProvider {
+		provider := &verifyingProvider{FakeServiceProvider: stub.Provider, err: err}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+		return provider
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"verified": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := verifyWith(stub, nil)
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertEqual(t, "instance should be verified", 1, provider.verified)
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "instance should be recorded", exists)
+			},
+		},
+		"verification-failure-rolls-back": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				verifyWith(stub, errors.New("connection refused"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", "provisioned instance failed verification and was rolled back: connection refused", err.Error())
+				assertEqual(t, "instance should be deprovisioned", 1, stub.Provider.DeprovisionCallCount())
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertTrue(t, "instance should not be recorded", !exists)
+			},
+		},
+		"rollback-failure": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				verifyWith(stub, errors.New("connection refused"))
+				stub.Provider.DeprovisionReturns(nil, errors.New("permission denied"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertTrue(t, "error should mention the cleanup", strings.Contains(err.Error(), "contact your operator for cleanup"))
+			},
+		},
+		"async-provisions-are-not-verified": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := verifyWith(stub, errors.New("connection refused"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertEqual(t, "instance should not be verified", 0, provider.verified)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// describingProvider is a ServiceProvider describing its running operations.
+type describingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	description string
+}
+
+func (p *describingProvider) DescribeOperation(ctx context.Context, instance models.ServiceInstanceDetails) (string, error) {
+	return p.description, nil
+}
+
+// suggestingProvider is a ServiceProvider suggesting how long to wait between
+// polls.
+type suggestingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	retryAfter time.Duration
+}
+
+func (p *suggestingProvider) SuggestRetryAfter(ctx context.Context, instance models.ServiceInstanceDetails) (time.Duration, error) {
+	return p.retryAfter, nil
+}
+
+func TestGCPServiceBroker_LastOperation(t *testing.T) {
+	describeOperations := func(stub *serviceStub, description string) {
+		provider := &describingProvider{FakeServiceProvider: stub.Provider, description: description}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+	}
+
+	suggestRetryAfter := func(stub *serviceStub, retryAfter time.Duration) {
+		provider := &suggestingProvider{FakeServiceProvider: stub.Provider, retryAfter: retryAfter}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+	}
+
+	phases := []broker.OperationProgress{
+		{Phase: 1, Phases: 3, Name: "network", Percent: 10},
+		{Phase: 2, Phases: 3, Name: "configuring", Percent: 60},
+	}
+
+	// reportPhases makes each poll of the provider report the next phase, and
+	// the poll after the last phase complete.
+	reportPhases := func(stub *serviceStub, phases ...broker.OperationProgress) {
+		polls := 0
+		stub.Provider.PollInstanceStub = func(ctx context.Context, instance models.ServiceInstanceDetails) (bool, error) {
+			if polls == len(phases) {
+				return true, nil
+			}
+
+			broker.ReportProgress(ctx, phases[polls])
+			polls++
+			return false, nil
+		}
+	}
+
+	// pollRetryAfter polls the instance and returns the Retry-After header of
+	// the response.
+	pollRetryAfter := func(t *testing.T, serviceBroker *ServiceBroker) string {
+		headers := broker.NewResponseHeaders()
+		_, err := serviceBroker.LastOperation(broker.WithResponseHeaders(context.Background(), headers), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+		failIfErr(t, "checking last operation", err)
+
+		response := http.Header{}
+		headers.ApplyTo(response)
+		return response.Get("Retry-After")
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"poll-interrupted": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "operationtoken"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				stub.Provider.PollInstanceReturns(false, context.DeadlineExceeded)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationId})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "deprovision should still be in progress", brokerapi.InProgress, status.State)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "the operation should still be recorded", models.DeprovisionOperationType, instance.OperationType)
+			},
+		},
+		"deprovision-in-progress-description": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "operationtoken"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				describeOperations(stub, "removing firewall rules")
+
+				stub.Provider.PollInstanceReturns(false, nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "deprovision should be in progress", brokerapi.InProgress, status.State)
+				assertEqual(t, "description should be the provider's", "removing firewall rules", status.Description)
+
+				stub.Provider.PollInstanceReturns(true, nil)
+				status, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "deprovision should succeed", brokerapi.Succeeded, status.State)
+				assertEqual(t, "completed operations shouldn't be described", "", status.Description)
+			},
+		},
+		"deprovision-phases": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "operationtoken"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				describeOperations(stub, "waiting for the API")
+				reportPhases(stub, phases...)
+
+				for _, expected := range []string{
+					"phase 1/3: network (10%), waiting for the API",
+					"phase 2/3: configuring (60%), waiting for the API",
+				} {
+					status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationId})
+					failIfErr(t, "checking last operation", err)
+					assertEqual(t, "deprovision should be in progress", brokerapi.InProgress, status.State)
+					assertEqual(t, "description should show the phase", expected, status.Description)
+				}
+
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: operationId})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "deprovision should succeed", brokerapi.Succeeded, status.State)
+				assertEqual(t, "completed operations shouldn't be described", "", status.Description)
+			},
+		},
+		"provision-timeout": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].ProvisionTimeout = "1h"
+				stub.Provider.PollInstanceReturns(false, nil)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.OperationType = models.ProvisionOperationType
+				instance.CreatedAt = time.Now().Add(-30 * time.Minute)
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "provision within the timeout should be in progress", brokerapi.InProgress, status.State)
+
+				instance.CreatedAt = time.Now().Add(-2 * time.Hour)
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				status, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "provision past the timeout should fail", brokerapi.Failed, status.State)
+				assertEqual(t, "description should name the timeout", `provisioning didn't complete within the provision timeout of plan "standard" (1h0m0s)`, status.Description)
+			},
+		},
+		"missing-instance": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), "invalid-instance-id", brokerapi.PollDetails{OperationData: "operationtoken"})
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"deprovision-completed": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "operationtoken"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				stub.Provider.PollInstanceReturns(true, nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "deprovision should succeed", brokerapi.Succeeded, status.State)
+
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				assertEqual(t, "polling a completed deprovision should be gone", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"called-on-synchronous-service": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+		"called-on-async-service": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "shouldn't be called on async service", err)
+
+				assertEqual(t, "PollInstanceCallCount should match", 1, stub.Provider.PollInstanceCallCount())
+			},
+		},
+		"poll-returns-retryable-error": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, &googleapi.Error{Code: 503})
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "retryable errors should result in in-progress state", brokerapi.InProgress, status.State)
+				assertEqual(t, "description should be error string", "googleapi: got HTTP response code 503 with body: ", status.Description)
+			},
+		},
+		"poll-returns-failure": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, errors.New("not-retryable"))
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "non-retryable errors should result in a failure state", brokerapi.Failed, status.State)
+				assertEqual(t, "description should be error string", "not-retryable", status.Description)
+			},
+		},
+		"poll-returns-not-done": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "polls that return no error should result in an in-progress state", brokerapi.InProgress, status.State)
+			},
+		},
+		"poll-returns-success": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(true, nil)
+				status, err := broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{OperationData: "operationtoken"})
+				failIfErr(t, "checking last operation", err)
+				assertEqual(t, "polls that return finished should result in a succeeded state", brokerapi.Succeeded, status.State)
+			},
+		},
+		"retry-after-plan-default": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].PollInterval = "30s"
+				stub.Provider.PollInstanceReturns(false, nil)
+				assertEqual(t, "retry after should be the plan's poll interval", "30", pollRetryAfter(t, broker))
+			},
+		},
+		"retry-after-provider-suggestion": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Plans[0].PollInterval = "30s"
+				suggestRetryAfter(stub, 90*time.Second)
+				stub.Provider.PollInstanceReturns(false, nil)
+				assertEqual(t, "retry after should be the provider's suggestion", "90", pollRetryAfter(t, broker))
+			},
+		},
+		"retry-after-clamped": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.retry_after_max", "1m")
+				defer viper.Reset()
+
+				suggestRetryAfter(stub, time.Hour)
+				stub.Provider.PollInstanceReturns(false, nil)
+				assertEqual(t, "retry after should be clamped to the maximum", "60", pollRetryAfter(t, broker))
+
+				suggestRetryAfter(stub, time.Second)
+				assertEqual(t, "retry after should be clamped to the minimum", "5", pollRetryAfter(t, broker))
+			},
+		},
+		"retry-after-unset": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.PollInstanceReturns(false, nil)
+				assertEqual(t, "retry after should not be set without a suggestion", "", pollRetryAfter(t, broker))
+
+				stub.ServiceDefinition.Plans[0].PollInterval = "30s"
+				stub.Provider.PollInstanceReturns(true, nil)
+				assertEqual(t, "retry after should not be set on completion", "", pollRetryAfter(t, broker))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Reconcile(t *testing.T) {
+	// setPendingOperation simulates an operation whose finalization failed.
+	setPendingOperation := func(t *testing.T) {
+		instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+		failIfErr(t, "getting instance", err)
+		instance.OperationType = models.UpdateOperationType
+		instance.OperationId = "operationtoken"
+		failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"missing-instance": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Reconcile(context.Background(), "invalid-instance-id")
+				assertEqual(t, "errors should match", ErrInstanceNotFound, err)
+			},
+		},
+		"no-pending-operation": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				status, err := broker.Reconcile(context.Background(), fakeInstanceId)
+				failIfErr(t, "reconciling", err)
+				assertEqual(t, "state should be succeeded", brokerapi.Succeeded, status.State)
+				assertEqual(t, "the cloud shouldn't be polled", 0, stub.Provider.PollInstanceCallCount())
+			},
+		},
+		"operation-in-progress": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setPendingOperation(t)
+				stub.Provider.PollInstanceReturns(false, nil)
+
+				_, err := broker.Reconcile(context.Background(), fakeInstanceId)
+				assertEqual(t, "errors should match", ErrOperationInProgress, err)
+				assertEqual(t, "details shouldn't be updated", 0, stub.Provider.UpdateInstanceDetailsCallCount())
+			},
+		},
+		"operation-complete": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				setPendingOperation(t)
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				status, err := broker.Reconcile(context.Background(), fakeInstanceId)
+				failIfErr(t, "reconciling", err)
+				assertEqual(t, "state should be succeeded", brokerapi.Succeeded, status.State)
+				assertEqual(t, "details should be updated", 1, stub.Provider.UpdateInstanceDetailsCallCount())
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "operation type should be cleared", models.ClearOperationType, instance.OperationType)
+				assertEqual(t, "operation id should be cleared", "", instance.OperationId)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_ReconcileOperations(t *testing.T) {
+	provisionAsync := func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+		stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "provision-op"}, nil)
+		_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+		failIfErr(t, "provisioning", err)
+	}
+	lastOperationState := func(t *testing.T) string {
+		history, err := db_service.GetOperationHistoryByServiceInstanceId(context.Background(), fakeInstanceId)
+		failIfErr(t, "getting history", err)
+		return history[len(history)-1].State
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"operation-complete": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisionAsync(t, broker, stub)
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				broker.ReconcileOperations(context.Background())
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "operation id should be cleared", "", instance.OperationId)
+				assertEqual(t, "the operation should be recorded as succeeded", string(brokerapi.Succeeded), lastOperationState(t))
+			},
+		},
+		"operation-in-progress": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisionAsync(t, broker, stub)
+				stub.Provider.PollInstanceReturns(false, nil)
+
+				broker.ReconcileOperations(context.Background())
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "the operation should be polled", 1, stub.Provider.PollInstanceCallCount())
+				assertEqual(t, "operation id should be kept", "provision-op", instance.OperationId)
+				assertEqual(t, "the operation should still be running", string(brokerapi.InProgress), lastOperationState(t))
+			},
+		},
+		"operation-failed": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisionAsync(t, broker, stub)
+				stub.Provider.PollInstanceReturns(false, errors.New("quota exceeded"))
+
+				broker.ReconcileOperations(context.Background())
+				assertEqual(t, "the operation should be recorded as failed", string(brokerapi.Failed), lastOperationState(t))
+
+				broker.ReconcileOperations(context.Background())
+				assertEqual(t, "finished operations shouldn't be polled again", 1, stub.Provider.PollInstanceCallCount())
+			},
+		},
+		"deprovision-complete": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationID := "deprovision-op"
+				stub.Provider.DeprovisionReturns(&operationID, nil)
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				broker.ReconcileOperations(context.Background())
+
+				_, err = db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				assertEqual(t, "the instance should be deleted", gorm.ErrRecordNotFound, err)
+			},
+		},
+		"started-on-startup": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("operations.reconcile", true)
+				viper.Set("operations.reconcile_interval", 0)
+				provisionAsync(t, broker, stub)
+				stub.Provider.PollInstanceReturns(true, nil)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				done := broker.StartOperationReconciler(ctx)
+
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("the reconciler should stop after its startup pass")
+				}
+				assertEqual(t, "the operation should be finalized without polling", string(brokerapi.Succeeded), lastOperationState(t))
+			},
+		},
+		"stopped-by-context": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("operations.reconcile", true)
+				viper.Set("operations.reconcile_interval", time.Hour)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				done := broker.StartOperationReconciler(ctx)
+				cancel()
+
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("the reconciler should stop once the context is done")
+				}
+			},
+		},
+		"instance-without-history": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provisionAsync(t, broker, stub)
+				failIfErr(t, "deleting history", db_service.DbConnection.Delete(&models.OperationHistory{}, "service_instance_id = ?", fakeInstanceId).Error)
+
+				broker.ReconcileOperations(context.Background())
+				assertEqual(t, "the operation shouldn't be polled", 0, stub.Provider.PollInstanceCallCount())
+			},
+		},
+		"disabled": {
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("operations.reconcile", false)
+				provisionAsync(t, broker, stub)
+
+				<-broker.StartOperationReconciler(context.Background())
+				assertEqual(t, "the operation shouldn't be polled", 0, stub.Provider.PollInstanceCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_ProviderAccounts(t *testing.T) {
+	configureAccounts := func() {
+		viper.Set("provider.accounts", `{"prod":{"GOOGLE_PROJECT":"prod-project"}}`)
+		viper.Set("provider.space_accounts", map[string]string{"prod-space": "prod"})
+	}
+	accountEnvs := func(stub *serviceStub) *[]map[string]string {
+		envs := []map[string]string{}
+		stub.ServiceDefinition.AccountProviderBuilder = func(logger lager.Logger, env map[string]string) broker.ServiceProvider {
+			envs = append(envs, env)
+			return stub.Provider
+		}
+		return &envs
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"mapped-space": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				configureAccounts()
+				defer viper.Reset()
+				envs := accountEnvs(stub)
+
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "prod-space"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "the account should be stored", "prod", instance.ProviderAccount)
+
+				// later operations use the stored account, even once the
+				// mapping changed
+				viper.Set("provider.space_accounts", map[string]string{})
+				_, err = broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				expected := map[string]string{"GOOGLE_PROJECT": "prod-project"}
+				assertEqual(t, "provision and bind should use the account", []map[string]string{expected, expected}, *envs)
+			},
+		},
+		"unmapped-space": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				configureAccounts()
+				defer viper.Reset()
+				envs := accountEnvs(stub)
+
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "dev-space"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "provisioning", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "no account should be stored", "", instance.ProviderAccount)
+				assertEqual(t, "the broker's credentials should be used", 0, len(*envs))
+			},
+		},
+		"unsupported-service": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				configureAccounts()
+				defer viper.Reset()
+
+				req := stub.ProvisionDetails()
+				req.SpaceGUID = "prod-space"
+				_, err := broker.Provision(context.Background(), fakeInstanceId, req, true)
+				if err == nil {
+					t.Fatal("expected an error provisioning")
+				}
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.ProvisionCallCount())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_PurgeUnboundBindings(t *testing.T) {
+	unboundIDs := func(t *testing.T, broker *ServiceBroker) []string {
+		bindings, err := broker.UnboundBindings(context.Background(), time.Time{})
+		failIfErr(t, "listing unbound bindings", err)
+
+		ids := []string{}
+		for _, binding := range bindings {
+			ids = append(ids, binding.BindingId)
+		}
+		return ids
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"kept-within-grace-period": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("bindings.purge_after", time.Hour)
+				defer viper.Reset()
+
+				broker.PurgeUnboundBindings(context.Background())
+				assertEqual(t, "the unbound binding should be listed", []string{fakeBindingId}, unboundIDs(t, broker))
+			},
+		},
+		"kept-without-purge": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("bindings.purge_after", 0)
+				defer viper.Reset()
+
+				broker.PurgeUnboundBindings(context.Background())
+				assertEqual(t, "the unbound binding should be listed", []string{fakeBindingId}, unboundIDs(t, broker))
+			},
+		},
+		"purged-after-grace-period": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("bindings.purge_after", time.Nanosecond)
+				defer viper.Reset()
+
+				time.Sleep(time.Millisecond)
+				broker.PurgeUnboundBindings(context.Background())
+				assertEqual(t, "the unbound binding should be purged", []string{}, unboundIDs(t, broker))
+			},
+		},
+		"purger-stopped-by-context": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("bindings.purge_after", time.Nanosecond)
+				viper.Set("bindings.purge_interval", time.Hour)
+				defer viper.Reset()
+
+				time.Sleep(time.Millisecond)
+				ctx, cancel := context.WithCancel(context.Background())
+				done := broker.StartBindingPurger(ctx)
+				cancel()
+
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Fatal("the purger should stop once the context is done")
+				}
+				assertEqual(t, "the startup pass should finish before stopping", []string{}, unboundIDs(t, broker))
+			},
+		},
+		"rebind-within-grace-period": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding again", err)
+
+				bindings, err := broker.InstanceBindings(context.Background(), fakeInstanceId)
+				failIfErr(t, "listing bindings", err)
+				assertEqual(t, "the binding should be live again", 1, len(bindings))
+				assertEqual(t, "the unbound binding should still be listed", []string{fakeBindingId}, unboundIDs(t, broker))
+
+				_, err = broker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+				failIfErr(t, "unbinding again", err)
+				assertEqual(t, "both unbound bindings should be listed", []string{fakeBindingId, fakeBindingId}, unboundIDs(t, broker))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_OperationHistory(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"synchronous": {
+			ServiceState: StateDeprovisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				history, err := broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertEqual(t, "operations should be recorded", 2, len(history))
+
+				for i, opType := range []string{models.ProvisionOperationType, models.DeprovisionOperationType} {
+					assertEqual(t, "operation type should match", opType, history[i].OperationType)
+					assertEqual(t, "operation should have succeeded", string(brokerapi.Succeeded), history[i].State)
+					if history[i].FinishedAt == nil {
+						t.Errorf("expected operation %d to be finished", i)
+					}
+				}
+			},
+		},
+		"asynchronous": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				operationId := "operation-1"
+				stub.Provider.DeprovisionReturns(&operationId, nil)
+				stub.Provider.PollInstanceReturns(false, errors.New("quota exceeded"))
+
+				_, err := broker.Deprovision(context.Background(), fakeInstanceId, stub.DeprovisionDetails(), true)
+				failIfErr(t, "deprovisioning", err)
+
+				history, err := broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertEqual(t, "deprovision should be in progress", string(brokerapi.InProgress), history[1].State)
+				assertEqual(t, "operation id should match", "operation-1", history[1].OperationId)
+
+				_, err = broker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+				failIfErr(t, "polling", err)
+
+				history, err = broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertEqual(t, "deprovision should have failed", string(brokerapi.Failed), history[1].State)
+				assertEqual(t, "error should be recorded", "quota exceeded", history[1].Error)
+			},
+		},
+		"failed": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.ProvisionStub = nil
+				stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{}, errors.New("out of capacity"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				assertEqual(t, "errors should match", "out of capacity", err.Error())
+
+				history, err := broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertEqual(t, "provision should have failed", string(brokerapi.Failed), history[0].State)
+				assertEqual(t, "error should be recorded", "out of capacity", history[0].Error)
+			},
+		},
+		"duration": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provision := stub.Provider.ProvisionStub
+				stub.Provider.ProvisionStub = func(ctx context.Context, vars *varcontext.VarContext) (models.ServiceInstanceDetails, error) {
+					time.Sleep(50 * time.Millisecond)
+					return provision(ctx, vars)
+				}
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				history, err := broker.OperationHistory(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting history", err)
+				assertTrue(t, "start should be recorded before calling the provider", history[0].FinishedAt.Sub(history[0].StartedAt) >= 50*time.Millisecond)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_GetBinding(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"called-on-bound": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+
+				expected := map[string]interface{}{"foo": "bar", "mynameis": "instancename"}
+				assertEqual(t, "credentials should match bind", expected, binding.Credentials)
+			},
+		},
+		"called-on-unbound": {
+			ServiceState: StateUnbound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				assertEqual(t, "expect binding does not exist err", brokerapi.ErrBindingDoesNotExist, err)
+			},
+		},
+		"stored-syslog-drain-url": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.ServiceDefinition.Requires = []brokerapi.RequiredPermission{brokerapi.PermissionSyslogDrain}
+				stub.Provider.BindReturns(map[string]interface{}{"syslog_drain_url": "syslog-tls://logs.example.com:6514"}, nil)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				stub.Provider.BuildInstanceCredentialsStub = nil
+				stub.Provider.BuildInstanceCredentialsReturns(&brokerapi.Binding{
+					Credentials:    map[string]interface{}{"foo": "bar"},
+					SyslogDrainURL: "syslog-tls://rebuilt.example.com:6514",
+				}, nil)
+
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "the stored syslog drain URL should be returned", "syslog-tls://logs.example.com:6514", binding.SyslogDrainURL)
+			},
+		},
+		"credential-set": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				stub.Provider.BindReturns(map[string]interface{}{
+					"primary":  map[string]interface{}{"host": "primary.example.com", "foo": "rw"},
+					"readonly": map[string]interface{}{"host": "replica.example.com"},
+				}, nil)
+				stub.Provider.BindStub = nil
+
+				bound, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+				failIfErr(t, "binding", err)
+
+				expected := map[string]interface{}{
+					"primary":  map[string]interface{}{"host": "primary.example.com", "foo": "rw", "mynameis": "instancename"},
+					"readonly": map[string]interface{}{"host": "replica.example.com", "foo": "baz", "mynameis": "instancename"},
+				}
+				assertEqual(t, "bind credentials should hold both endpoints", expected, bound.Credentials)
+
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "credentials should match bind", expected, binding.Credentials)
+			},
+		},
+		"credstore": {
+			ServiceState: StateBound,
+			Credstore:    &credstorefakes.FakeCredStore{},
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+
+				expected := map[string]interface{}{"credhub-ref": "/c/csb/google-storage/" + fakeBindingId + "/secrets-and-services"}
+				assertEqual(t, "credentials should be a reference", expected, binding.Credentials)
+			},
+		},
+		"parameters": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				details := stub.BindDetails()
+				details.RawParameters = json.RawMessage(`{"role":"storage.objectViewer","password":"hunter2"}`)
+				_, err := broker.Bind(context.Background(), fakeInstanceId, fakeBindingId, details, true)
+				failIfErr(t, "binding", err)
+
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+
+				expected := map[string]interface{}{"role": "storage.objectViewer", "password": "[REDACTED]"}
+				assertEqual(t, "parameters should match bind with sensitive values masked", expected, binding.Parameters)
+			},
+		},
+		"legacy-binding-without-parameters": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				failIfErr(t, "deleting bind request", db_service.DeleteBindRequestDetailsByBindingId(context.Background(), fakeBindingId))
+
+				binding, err := broker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "getting binding", err)
+				assertEqual(t, "parameters should be empty", nil, binding.Parameters)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// adoptingProvider is a ServiceProvider adopting existing resources.
+type adoptingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	err       error
+	adopted   models.ServiceInstanceDetails
+	resources map[string]interface{}
+}
+
+func (p *adoptingProvider) AdoptInstance(ctx context.Context, instance models.ServiceInstanceDetails, resources map[string]interface{}) error {
+	p.adopted = instance
+	p.resources = resources
+	return p.err
+}
+
+func TestGCPServiceBroker_AdoptInstance(t *testing.T) {
+	adoptWith := func(stub *serviceStub, err error) *adoptingProvider {
+		provider := &adoptingProvider{FakeServiceProvider: stub.Provider, err: err}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+		return provider
+	}
+
+	adoptRequest := func(stub *serviceStub) broker.AdoptRequest {
+		return broker.AdoptRequest{
+			ServiceID:  stub.ServiceId,
+			PlanID:     stub.PlanId,
+			Name:       "adopted",
+			Resources:  map[string]interface{}{"bucket": "existing-bucket"},
+			Parameters: json.RawMessage(`{"name":"existing-bucket"}`),
+		}
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"adopted": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				provider := adoptWith(stub, nil)
+
+				err := broker.AdoptInstance(context.Background(), fakeInstanceId, adoptRequest(stub))
+				failIfErr(t, "adopting", err)
+
+				assertEqual(t, "instance should be passed to the provider", fakeInstanceId, provider.adopted.ID)
+				assertEqual(t, "resources should be passed to the provider", map[string]interface{}{"bucket": "existing-bucket"}, provider.resources)
+				assertEqual(t, "instance shouldn't be refreshed", 0, stub.Provider.UpdateInstanceDetailsCallCount())
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "plan should be stored", stub.PlanId, instance.PlanId)
+				assertEqual(t, "resources should be stored", `{"bucket":"existing-bucket"}`, instance.OtherDetails)
+
+				stored := models.ProvisionRequestDetails{}
+				failIfErr(t, "getting request details", db_service.DbConnection.Where("service_instance_id = ?", fakeInstanceId).First(&stored).Error)
+				assertEqual(t, "parameters should be stored", `{"name":"existing-bucket"}`, stored.RequestDetails)
+			},
+		},
+		"resources-not-found": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				adoptWith(stub, errors.New("bucket not found"))
+
+				err := broker.AdoptInstance(context.Background(), fakeInstanceId, adoptRequest(stub))
+				assertEqual(t, "errors should match", fmt.Sprintf(`the resources of instance %q couldn't be found: bucket not found`, fakeInstanceId), err.Error())
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertEqual(t, "instance shouldn't be recorded", false, exists)
+			},
+		},
+		"adoption-not-supported": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.AdoptInstance(context.Background(), fakeInstanceId, adoptRequest(stub))
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "errors should match", fmt.Sprintf(`service %q doesn't support adopting instances`, stub.ServiceDefinition.Name), err.Error())
+			},
+		},
+		"terraform-service": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				definition := tf.NewExampleTfServiceDefinition()
+				tfService, err := definition.ToService(nil)
+				failIfErr(t, "creating terraform service", err)
+				stub.ServiceDefinition.ProviderBuilder = tfService.ProviderBuilder
+
+				err = broker.AdoptInstance(context.Background(), fakeInstanceId, adoptRequest(stub))
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unprocessable", http.StatusUnprocessableEntity, failure.ValidatedStatusCode(nil))
+
+				exists, err := db_service.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "checking instance", err)
+				assertEqual(t, "instance shouldn't be recorded", false, exists)
+			},
+		},
+		"already-exists": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.AdoptInstance(context.Background(), fakeInstanceId, adoptRequest(stub))
+				assertEqual(t, "errors should match", brokerapi.ErrInstanceAlreadyExists, err)
+			},
+		},
+		"missing-resources": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := adoptRequest(stub)
+				req.Resources = nil
+				err := broker.AdoptInstance(context.Background(), fakeInstanceId, req)
+				assertEqual(t, "errors should match", "missing field(s): resources", err.Error())
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_ServiceCapabilities(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"async-service": {
+			AsyncService: true,
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				capabilities, err := broker.ServiceCapabilities(context.Background(), stub.ServiceId)
+				failIfErr(t, "getting capabilities", err)
+				assertEqual(t, "service name should match", stub.ServiceDefinition.Name, capabilities.ServiceName)
+				assertTrue(t, "provisions should be async", capabilities.ProvisionsAsync)
+				assertTrue(t, "deprovisions should be async", capabilities.DeprovisionsAsync)
+			},
+		},
+		"unknown-service": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.ServiceCapabilities(context.Background(), "bad-service-id")
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be not found", http.StatusNotFound, failure.ValidatedStatusCode(nil))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_InstanceMetadata(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"no-metadata": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				metadata, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting metadata", err)
+				assertEqual(t, "metadata should be empty", map[string]string{}, metadata)
+			},
+		},
+		"set-metadata": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.SetInstanceMetadata(context.Background(), fakeInstanceId, map[string]string{"cost_center": "42"})
+				failIfErr(t, "setting metadata", err)
+
+				metadata, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting metadata", err)
+				assertEqual(t, "metadata should be stored", map[string]string{"cost_center": "42"}, metadata)
+			},
+		},
+		"invalid-metadata": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.SetInstanceMetadata(context.Background(), fakeInstanceId, map[string]string{"": "42"})
+				assertEqual(t, "errors should match", "instance_metadata keys must not be empty", err.Error())
+			},
+		},
+		"missing-instance": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				assertEqual(t, "errors should match", ErrInstanceNotFound, err)
+
+				err = broker.SetInstanceMetadata(context.Background(), fakeInstanceId, map[string]string{"owner": "team-a"})
+				assertEqual(t, "errors should match", ErrInstanceNotFound, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_GetInstance(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"called-while-provisioned": {
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.GetInstance(context.Background(), fakeInstanceId)
+
+				assertEqual(t, "expect get instances not supported err", ErrGetInstancesUnsupported, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_LastBindingOperation(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"called-while-bound": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.LastBindingOperation(context.Background(), fakeInstanceId, fakeBindingId, brokerapi.PollDetails{})
+
+				assertEqual(t, "expect last binding to return async required", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+func TestGCPServiceBroker_Update(t *testing.T) {
+	isUpgrade := broker.IsUpgrade
+	withMaintenanceInfo := func(stub *serviceStub, version string) brokerapi.MaintenanceInfo {
+		info := brokerapi.MaintenanceInfo{Public: map[string]string{"version": version}}
+		stub.ServiceDefinition.Plans[0].MaintenanceInfo = &info
+		return info
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"good-request": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+
+				failIfErr(t, "update", err)
+			},
+		},
+		"missing-instance": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Update(context.Background(), "bogus-id", stub.UpdateDetails(), true)
+
+				assertEqual(t, "expect update error to be instance does not exist", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},		
+		"requires-async": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				// false for async support
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), false)
+				assertEqual(t, "errors should match", brokerapi.ErrAsyncRequired, err)
+			},
+		},
+		"instance-does-not-exist": {
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				assertEqual(t, "instance does not exist should be set", brokerapi.ErrInstanceDoesNotExist, err)
+			},
+		},
+		"bad-request-json": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage("{invalid json")
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", ErrInvalidUserInput, err)
+			},
+		},
+		"non-object-parameters": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage("[]")
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "User supplied parameters must be a JSON object, got an array.", err.Error())
+				assertEqual(t, "provider should not be called", 0, stub.Provider.UpdateCallCount())
+			},
+		},		
+		"parameters-too-large": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set("request.max_parameter_bytes.update", 10)
+				defer viper.Reset()
+
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"name":"too-long"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", "parameters are 19 bytes, the maximum allowed size is 10 bytes", err.Error())
+			},
+		},
+		"attempt-to-update-non-updatable-parameter": {
+			AsyncService: true,
+			ServiceState: StateProvisioned,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"location":"new-location"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", ErrNonUpdatableParameter, err)
+			},
+		},
+		"refresh-bindings": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				viper.Set(stub.ServiceDefinition.RefreshBindingsOnUpdateProperty(), true)
+				defer viper.Reset()
+
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				putsBefore := fcs.PutCallCount()
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "update", err)
+
+				assertEqual(t, "binding credentials should be put again", putsBefore+1, fcs.PutCallCount())
+				name, _ := fcs.PutArgsForCall(putsBefore)
+				assertEqual(t, "credential name should be the binding's", fmt.Sprintf("/c/csb/%s/%s/secrets-and-services", stub.ServiceDefinition.Name, fakeBindingId), name)
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"no-refresh-by-default": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				putsBefore := fcs.PutCallCount()
+
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "update", err)
+
+				assertEqual(t, "binding credentials shouldn't be put again", putsBefore, fcs.PutCallCount())
+			},
+			Credstore: &credstorefakes.FakeCredStore{},
+		},
+		"instance-metadata": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"instance_metadata":{"owner":"team-b"}}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				metadata, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting metadata", err)
+				assertEqual(t, "metadata should be replaced", map[string]string{"owner": "team-b"}, metadata)
+			},
+		},
+		"instance-metadata-removal": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.SetInstanceMetadata(context.Background(), fakeInstanceId, map[string]string{"owner": "team-a", "cost_center": "42"})
+				failIfErr(t, "setting metadata", err)
+
+				// only the tags change
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"instance_metadata":{"owner":"team-a"}}`)
+				_, err = broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				assertEqual(t, "the provider should be called", 1, stub.Provider.UpdateCallCount())
+
+				metadata, err := broker.InstanceMetadata(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting metadata", err)
+				assertEqual(t, "the removed tag should be dropped", map[string]string{"owner": "team-a"}, metadata)
+			},
+		},
+		"stores-plan": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				_, err := broker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+				failIfErr(t, "update", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "plan should be stored", stub.PlanId, instance.PlanId)
+			},
+		},
+		"plan-omitted": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.PlanID = ""
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "plan should be kept", stub.PlanId, instance.PlanId)
+			},
+		},
+		"previous-values-recover-lost-plan": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				instance.PlanId = ""
+				failIfErr(t, "saving instance", db_service.SaveServiceInstanceDetails(context.Background(), instance))
+
+				req := stub.UpdateDetails()
+				req.PlanID = ""
+				req.PreviousValues = brokerapi.PreviousValues{PlanID: stub.PlanId}
+				_, err = broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				instance, err = db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "plan should be the previous one", stub.PlanId, instance.PlanId)
+			},
+		},
+		"previous-values-mismatch": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.PlanID = ""
+				req.PreviousValues = brokerapi.PreviousValues{PlanID: "some-other-plan"}
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "stored plan should win", stub.PlanId, instance.PlanId)
+			},
+		},
+		"good-request-valid-parameter": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.RawParameters = json.RawMessage(`{"force_delete":"false"}`)
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+
+				failIfErr(t, "update", err)
+			},
+		},
+		"upgrade": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.MaintenanceInfo = withMaintenanceInfo(stub, "2")
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "upgrade", err)
+
+				ctx, _ := stub.Provider.UpdateArgsForCall(0)
+				assertTrue(t, "the provider should be told it's an upgrade", isUpgrade(ctx))
+
+				instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+				failIfErr(t, "getting instance", err)
+				assertEqual(t, "maintenance info should be recorded", `{"public":{"version":"2"}}`, instance.MaintenanceInfo)
+			},
+		},
+		"update-with-current-maintenance-info": {
+			ServiceState: StateNone,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				info := withMaintenanceInfo(stub, "2")
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+
+				req := stub.UpdateDetails()
+				req.MaintenanceInfo = info
+				_, err = broker.Update(context.Background(), fakeInstanceId, req, true)
+				failIfErr(t, "update", err)
+
+				ctx, _ := stub.Provider.UpdateArgsForCall(0)
+				assertTrue(t, "the update shouldn't be an upgrade", !isUpgrade(ctx))
+			},
+		},
+		"maintenance-info-conflict": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				withMaintenanceInfo(stub, "2")
+				req := stub.UpdateDetails()
+				req.MaintenanceInfo = brokerapi.MaintenanceInfo{Public: map[string]string{"version": "1"}}
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", brokerapi.ErrMaintenanceInfoConflict, err)
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.UpdateCallCount())
+			},
+		},
+		"maintenance-info-without-plan-maintenance-info": {
+			ServiceState: StateProvisioned,
+			AsyncService: true,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				req := stub.UpdateDetails()
+				req.MaintenanceInfo = brokerapi.MaintenanceInfo{Public: map[string]string{"version": "1"}}
+				_, err := broker.Update(context.Background(), fakeInstanceId, req, true)
+				assertEqual(t, "errors should match", brokerapi.ErrMaintenanceInfoNilConflict, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+// healthCheckingProvider is a ServiceProvider checking the health of its
+// backend.
+type healthCheckingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	err     error
+	checked int
+}
+
+func (p *healthCheckingProvider) HealthCheck(ctx context.Context) error {
+	p.checked++
+	return p.err
+}
+
+func TestGCPServiceBroker_ServiceHealth(t *testing.T) {
+	withHealthCheck := func(stub *serviceStub, err error) *healthCheckingProvider {
+		stub.ServiceDefinition.IsBuiltin = false
+		provider := &healthCheckingProvider{FakeServiceProvider: stub.Provider, err: err}
+		stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+			return provider
+		}
+		return provider
+	}
+
+	cases := BrokerEndpointTestSuite{
+		"healthy": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				withHealthCheck(stub, nil)
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failIfErr(t, "provisioning", err)
+				assertEqual(t, "health should be reported", map[string]error{stub.ServiceDefinition.Name: nil}, broker.ServiceHealth(context.Background()))
+			},
+		},
+		"unhealthy": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				withHealthCheck(stub, errors.New("api down"))
+
+				_, err := broker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+				failure, ok := err.(*brokerapi.FailureResponse)
+				assertTrue(t, "error should be a failure response", ok)
+				assertEqual(t, "status should be unavailable", http.StatusServiceUnavailable, failure.ValidatedStatusCode(nil))
+				assertEqual(t, "the provider shouldn't be called", 0, stub.Provider.ProvisionCallCount())
+
+				health := broker.ServiceHealth(context.Background())
+				assertEqual(t, "error should be reported", "api down", health[stub.ServiceDefinition.Name].Error())
+			},
+		},
+		"cached-within-interval": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				defer viper.Reset()
+				viper.Set("api.service_health_interval", "1h")
+				provider := withHealthCheck(stub, nil)
+
+				broker.ServiceHealth(context.Background())
+				broker.ServiceHealth(context.Background())
+				assertEqual(t, "backend should be checked once", 1, provider.checked)
+
+				viper.Set("api.service_health_interval", "0s")
+				broker.ServiceHealth(context.Background())
+				assertEqual(t, "backend should be checked again", 2, provider.checked)
+			},
+		},
+		"without-health-check": {
+			ServiceState: StateNone,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				assertEqual(t, "no health should be reported", map[string]error{}, broker.ServiceHealth(context.Background()))
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// finalizingProvider is a ServiceProvider only refreshing some fields of an
+// instance's details once its operations complete.
+type finalizingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	fields  []string
+	fetched [][]string
+}
+
+func (p *finalizingProvider) FinalizationFields() []string {
+	return p.fields
+}
+
+func (p *finalizingProvider) FetchInstanceFields(ctx context.Context, instance models.ServiceInstanceDetails, fields []string) (map[string]interface{}, error) {
+	p.fetched = append(p.fetched, fields)
+	return map[string]interface{}{"ip": "10.0.0.2", "name": "changed"}, nil
+}
+
+func TestGCPServiceBroker_FinalizationFields(t *testing.T) {
+	cases := map[string]struct {
+		Fields          []string
+		ExpectedDetails string
+		ExpectedUpdates int
+	}{
+		"declared fields": {
+			Fields:          []string{"ip"},
+			ExpectedDetails: `{"ip":"10.0.0.2","name":"db"}`,
+			ExpectedUpdates: 0,
+		},
+		"no fields": {
+			Fields:          nil,
+			ExpectedDetails: `{"ip":"","name":"db"}`,
+			ExpectedUpdates: 1,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			stub := fakeService(t, true)
+			stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OtherDetails: `{"ip":"","name":"db"}`, OperationId: "op-1"}, nil)
+			stub.Provider.PollInstanceReturns(true, nil)
+			provider := &finalizingProvider{FakeServiceProvider: stub.Provider, fields: tc.Fields}
+			stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+				return provider
+			}
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+			failIfErr(t, "provisioning", err)
+			op, err := serviceBroker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+			failIfErr(t, "polling", err)
+			assertEqual(t, "the provision should succeed", brokerapi.Succeeded, op.State)
+
+			instance, err := db_service.GetServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+			failIfErr(t, "getting instance", err)
+			assertEqual(t, "details should match", tc.ExpectedDetails, instance.OtherDetails)
+			assertEqual(t, "full refreshes should match", tc.ExpectedUpdates, stub.Provider.UpdateInstanceDetailsCallCount())
+		})
+	}
+}
+
+func TestGCPServiceBroker_Store(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+
+	store := db_service.NewMemoryStore()
+	defer db_service.SetStore(nil)
+	serviceBroker, err := New(&BrokerConfig{Registry: registry, Store: store}, utils.NewLogger("brokers-test"))
+	failIfErr(t, "creating broker", err)
+
+	_, err = serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+	failIfErr(t, "provisioning", err)
+	_, err = serviceBroker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), false)
+	failIfErr(t, "binding", err)
+
+	exists, err := store.ExistsServiceInstanceDetailsById(context.Background(), fakeInstanceId)
+	failIfErr(t, "checking instance", err)
+	assertTrue(t, "the instance should be in the store", exists)
+	exists, err = store.ExistsServiceBindingCredentialsByBindingId(context.Background(), fakeBindingId)
+	failIfErr(t, "checking binding", err)
+	assertTrue(t, "the binding should be in the store", exists)
+}
+
+// unavailableBindingStore fails to look up bindings, like a database that is
+// down.
+type unavailableBindingStore struct {
+	*db_service.MemoryStore
+}
+
+func (s *unavailableBindingStore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestGCPServiceBroker_GetBinding_DatabaseError(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+
+	defer db_service.SetStore(nil)
+	serviceBroker, err := New(&BrokerConfig{Registry: registry, Store: &unavailableBindingStore{MemoryStore: db_service.NewMemoryStore()}}, utils.NewLogger("brokers-test"))
+	failIfErr(t, "creating broker", err)
+
+	_, err = serviceBroker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+	assertTrue(t, "a database error shouldn't report the binding missing", err != nil && err != brokerapi.ErrBindingDoesNotExist)
+	assertEqual(t, "errors should match", "Database error retrieving the binding: connection refused", err.Error())
+}
+
+// unavailableInstanceStore fails to look up instances, like a database that
+// is down.
+type unavailableInstanceStore struct {
+	*db_service.MemoryStore
+}
+
+func (s *unavailableInstanceStore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestGCPServiceBroker_InstanceSettings_DatabaseError(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+
+	defer db_service.SetStore(nil)
+	serviceBroker, err := New(&BrokerConfig{Registry: registry, Store: &unavailableInstanceStore{MemoryStore: db_service.NewMemoryStore()}}, utils.NewLogger("brokers-test"))
+	failIfErr(t, "creating broker", err)
+
+	cases := map[string]func() error{
+		"get-metadata": func() error {
+			_, err := serviceBroker.InstanceMetadata(context.Background(), fakeInstanceId)
+			return err
+		},
+		"set-metadata": func() error {
+			return serviceBroker.SetInstanceMetadata(context.Background(), fakeInstanceId, map[string]string{"cost_center": "42"})
+		},
+		"get-deletion-protection": func() error {
+			_, err := serviceBroker.DeletionProtection(context.Background(), fakeInstanceId)
+			return err
+		},
+		"set-deletion-protection": func() error {
+			return serviceBroker.SetDeletionProtection(context.Background(), fakeInstanceId, true)
+		},
+	}
+
+	for tn, call := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := call()
+			assertTrue(t, "a database error shouldn't report the instance missing", err != nil && err != ErrInstanceNotFound)
+			assertEqual(t, "errors should match", "connection refused", err.Error())
+		})
+	}
+}
+
+func TestGCPServiceBroker_InstanceSettings_MissingInstance(t *testing.T) {
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+
+	defer db_service.SetStore(nil)
+	serviceBroker, err := New(&BrokerConfig{Registry: registry, Store: db_service.NewMemoryStore()}, utils.NewLogger("brokers-test"))
+	failIfErr(t, "creating broker", err)
+
+	_, err = serviceBroker.InstanceMetadata(context.Background(), fakeInstanceId)
+	assertEqual(t, "metadata errors should match", ErrInstanceNotFound, err)
+	_, err = serviceBroker.DeletionProtection(context.Background(), fakeInstanceId)
+	assertEqual(t, "deletion protection errors should match", ErrInstanceNotFound, err)
+}
+
+// costEstimatingProvider prices instances by their requested name.
+type costEstimatingProvider struct {
+	*brokerfakes.FakeServiceProvider
+}
+
+func (p *costEstimatingProvider) EstimateCost(ctx context.Context, vars *varcontext.VarContext) (*broker.CostEstimate, error) {
+	amount := float64(len(vars.GetString("name")))
+	return &broker.CostEstimate{Costs: []broker.PlanCost{{Amount: amount, Currency: "USD", Unit: "MONTHLY"}}}, vars.Error()
+}
+
+func TestGCPServiceBroker_EstimateCost(t *testing.T) {
+	planCosts := []brokerapi.ServicePlanCost{{Amount: map[string]float64{"USD": 5, "EUR": 4.5}, Unit: "MONTHLY"}}
+
+	cases := map[string]struct {
+		Estimator      bool
+		Parameters     string
+		ExpectedCosts  []broker.PlanCost
+		ExpectedSource string
+		ExpectedError  bool
+	}{
+		"plan costs": {
+			ExpectedCosts: []broker.PlanCost{
+				{Amount: 4.5, Currency: "EUR", Unit: "MONTHLY"},
+				{Amount: 5, Currency: "USD", Unit: "MONTHLY"},
+			},
+			ExpectedSource: broker.CostEstimateSourcePlan,
+		},
+		"provider estimate": {
+			Estimator:      true,
+			Parameters:     `{"name":"my-bucket"}`,
+			ExpectedCosts:  []broker.PlanCost{{Amount: 9, Currency: "USD", Unit: "MONTHLY"}},
+			ExpectedSource: broker.CostEstimateSourceProvider,
+		},
+		"invalid parameters": {
+			Estimator:     true,
+			Parameters:    `{"name":1}`,
+			ExpectedError: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			stub := fakeService(t, false)
+			stub.ServiceDefinition.Plans[0].Metadata = &brokerapi.ServicePlanMetadata{Costs: planCosts}
+			if tc.Estimator {
+				provider := &costEstimatingProvider{FakeServiceProvider: stub.Provider}
+				stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+					return provider
+				}
+			}
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			request := broker.CostEstimateRequest{ServiceID: stub.ServiceId, PlanID: stub.PlanId, Parameters: json.RawMessage(tc.Parameters)}
+			estimate, err := serviceBroker.EstimateCost(context.Background(), fakeInstanceId, request)
+			if tc.ExpectedError {
+				assertTrue(t, "an error should be returned", err != nil)
+				return
+			}
+			failIfErr(t, "estimating cost", err)
+
+			assertEqual(t, "costs should match", tc.ExpectedCosts, estimate.Costs)
+			assertEqual(t, "source should match", tc.ExpectedSource, estimate.Source)
+			assertEqual(t, "nothing should be provisioned", 0, stub.Provider.ProvisionCallCount())
+		})
+	}
+}
+
+func TestGCPServiceBroker_ReissueBindingCredentials(t *testing.T) {
+	cases := BrokerEndpointTestSuite{
+		"reissued": {
+			ServiceState: StateBound,
+			Credstore:    &credstorefakes.FakeCredStore{},
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				fcs := broker.Credstore.(*credstorefakes.FakeCredStore)
+				putsAfterBind := fcs.PutCallCount()
+				bindsAfterBind := stub.Provider.BindCallCount()
+
+				err := broker.ReissueBindingCredentials(context.Background(), fakeInstanceId, fakeBindingId)
+				failIfErr(t, "reissuing credentials", err)
+
+				assertEqual(t, "credentials should be put again", putsAfterBind+1, fcs.PutCallCount())
+				name, credentials := fcs.PutArgsForCall(putsAfterBind)
+				assertEqual(t, "the original name should be used", "/c/csb/google-storage/"+fakeBindingId+"/secrets-and-services", name)
+				assertEqual(t, "the stored credentials should be put", map[string]interface{}{"foo": "bar", "mynameis": "instancename"}, credentials)
+
+				permissionName, actor, ops := fcs.AddPermissionArgsForCall(fcs.AddPermissionCallCount() - 1)
+				assertEqual(t, "the permission should be on the credentials", name, permissionName)
+				assertEqual(t, "the app should be granted access", "mtls-app:"+fakeAppGuid, actor)
+				assertEqual(t, "the app should get read access", []string{"read"}, ops)
+
+				assertEqual(t, "the provider shouldn't bind again", bindsAfterBind, stub.Provider.BindCallCount())
+			},
+		},
+		"unknown-binding": {
+			ServiceState: StateProvisioned,
+			Credstore:    &credstorefakes.FakeCredStore{},
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.ReissueBindingCredentials(context.Background(), fakeInstanceId, fakeBindingId)
+				assertEqual(t, "errors should match", ErrBindingNotFound, err)
+			},
+		},
+		"no-credstore": {
+			ServiceState: StateBound,
+			Check: func(t *testing.T, broker *ServiceBroker, stub *serviceStub) {
+				err := broker.ReissueBindingCredentials(context.Background(), fakeInstanceId, fakeBindingId)
+				assertEqual(t, "errors should match", ErrCredstoreNotConfigured, err)
+			},
+		},
+	}
+
+	cases.Run(t)
+}
+
+// updateValidatingProvider rejects every update with its error.
+type updateValidatingProvider struct {
+	*brokerfakes.FakeServiceProvider
+	err error
+
+	validated models.ServiceInstanceDetails
+}
+
+func (p *updateValidatingProvider) ValidateUpdate(ctx context.Context, instance models.ServiceInstanceDetails, vars *varcontext.VarContext) error {
+	p.validated = instance
+	return p.err
+}
+
+func TestGCPServiceBroker_ValidateUpdate(t *testing.T) {
+	conflict := brokerapi.NewFailureResponse(errors.New("busy"), http.StatusConflict, "busy")
+
+	cases := map[string]struct {
+		ValidationError error
+		ExpectedError   error
+	}{
+		"compatible": {},
+		"incompatible": {
+			ValidationError: errors.New("storage can't shrink below its usage of 20GB"),
+			ExpectedError:   brokerapi.NewFailureResponse(errors.New("storage can't shrink below its usage of 20GB"), http.StatusUnprocessableEntity, "update-incompatible"),
+		},
+		"provider-response": {
+			ValidationError: conflict,
+			ExpectedError:   conflict,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			stub := fakeService(t, true)
+			provider := &updateValidatingProvider{FakeServiceProvider: stub.Provider, err: tc.ValidationError}
+			stub.ServiceDefinition.ProviderBuilder = func(logger lager.Logger) broker.ServiceProvider {
+				return provider
+			}
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+			failIfErr(t, "provisioning", err)
+
+			_, err = serviceBroker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+			assertEqual(t, "errors should match", tc.ExpectedError, err)
+			assertEqual(t, "the instance should be validated", fakeInstanceId, provider.validated.ID)
+
+			expectedUpdates := 1
+			if tc.ExpectedError != nil {
+				expectedUpdates = 0
+			}
+			assertEqual(t, "provider updates should match", expectedUpdates, stub.Provider.UpdateCallCount())
+		})
+	}
+}
+
+// recordingSink keeps the events sent to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (s *recordingSink) Send(ctx context.Context, event events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// waitFor waits for the broker to deliver n events in the background and
+// returns the delivered events.
+func (s *recordingSink) waitFor(t *testing.T, n int) []events.Event {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(5 * time.Millisecond) {
+		s.mu.Lock()
+		delivered := append([]events.Event(nil), s.events...)
+		s.mu.Unlock()
+
+		if len(delivered) >= n || time.Now().After(deadline) {
+			return delivered
+		}
+	}
+}
+
+func eventTypes(delivered []events.Event) []string {
+	var types []string
+	for _, event := range delivered {
+		types = append(types, event.Type+":"+event.Data.Outcome)
+	}
+	return types
+}
+
+// blockingSink doesn't return until it's released.
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Send(ctx context.Context, event events.Event) error {
+	<-s.release
+	return nil
+}
+
+func TestGCPServiceBroker_LifecycleEvents(t *testing.T) {
+	newBroker := func(t *testing.T, stub *serviceStub, sink events.Sink) *ServiceBroker {
+		registry := broker.BrokerRegistry{}
+		registry.Register(stub.ServiceDefinition)
+
+		serviceBroker, err := New(&BrokerConfig{Registry: registry, Store: db_service.NewMemoryStore(), EventSink: sink}, utils.NewLogger("brokers-test"))
+		failIfErr(t, "creating broker", err)
+		return serviceBroker
+	}
+	defer db_service.SetStore(nil)
+
+	t.Run("sync", func(t *testing.T) {
+		stub := fakeService(t, false)
+		sink := &recordingSink{}
+		serviceBroker := newBroker(t, stub, sink)
+		ctx := context.Background()
+
+		_, err := serviceBroker.Provision(ctx, fakeInstanceId, stub.ProvisionDetails(), true)
+		failIfErr(t, "provisioning", err)
+		_, err = serviceBroker.Update(ctx, fakeInstanceId, stub.UpdateDetails(), true)
+		failIfErr(t, "updating", err)
+		_, err = serviceBroker.Bind(ctx, fakeInstanceId, fakeBindingId, stub.BindDetails(), true)
+		failIfErr(t, "binding", err)
+		_, err = serviceBroker.Unbind(ctx, fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+		failIfErr(t, "unbinding", err)
+		_, err = serviceBroker.Deprovision(ctx, fakeInstanceId, stub.DeprovisionDetails(), true)
+		failIfErr(t, "deprovisioning", err)
+
+		expected := []string{
+			events.InstanceProvisioned + ":succeeded",
+			events.InstanceUpdated + ":succeeded",
+			events.InstanceBound + ":succeeded",
+			events.InstanceUnbound + ":succeeded",
+			events.InstanceDeprovisioned + ":succeeded",
+		}
+		delivered := sink.waitFor(t, len(expected))
+		assertEqual(t, "events should match", expected, eventTypes(delivered))
+		assertEqual(t, "bind events should be about the binding", fakeBindingId, delivered[2].Subject)
+		assertEqual(t, "instance events should be about the instance", fakeInstanceId, delivered[0].Data.InstanceID)
+	})
+
+	t.Run("slow-sink", func(t *testing.T) {
+		stub := fakeService(t, false)
+		sink := &blockingSink{release: make(chan struct{})}
+		defer close(sink.release)
+		serviceBroker := newBroker(t, stub, sink)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+			done <- err
+		}()
+
+		select {
+		case err := <-done:
+			failIfErr(t, "provisioning", err)
+		case <-time.After(time.Second):
+			t.Fatal("expected the request not to wait for the event to be delivered")
+		}
+	})
+
+	t.Run("async", func(t *testing.T) {
+		stub := fakeService(t, true)
+		stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationId: "op-1"}, nil)
+		stub.Provider.PollInstanceReturns(true, errors.New("quota exceeded"))
+		sink := &recordingSink{}
+		serviceBroker := newBroker(t, stub, sink)
+
+		_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+		failIfErr(t, "provisioning", err)
+
+		_, err = serviceBroker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+		failIfErr(t, "polling", err)
+		delivered := sink.waitFor(t, 1)
+		assertEqual(t, "events should match", []string{events.InstanceProvisioned + ":failed"}, eventTypes(delivered))
+		assertEqual(t, "the operation should be identified", "op-1", delivered[0].Data.OperationID)
+		assertEqual(t, "the error should be reported", "quota exceeded", delivered[0].Data.Error)
+	})
+}
+
+func TestGCPServiceBroker_DashboardUrl(t *testing.T) {
+	cases := map[string]struct {
+		ServiceTemplate string
+		PlanTemplate    string
+		Expected        string
+	}{
+		"no template": {
+			Expected: "",
+		},
+		"service template": {
+			ServiceTemplate: "https://console.example.com/instances/{{instanceID}}",
+			Expected:        "https://console.example.com/instances/" + fakeInstanceId,
+		},
+		"plan template takes precedence": {
+			ServiceTemplate: "https://console.example.com/instances/{{instanceID}}",
+			PlanTemplate:    "https://{{plan.storage_class}}.example.com/{{output.mynameis}}?plan={{planName}}",
+			Expected:        "https://STANDARD.example.com/instancename?plan=standard",
+		},
+		"unknown output": {
+			PlanTemplate: "https://console.example.com/{{output.missing}}",
+			Expected:     "",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			stub := fakeService(t, false)
+			stub.ServiceDefinition.DashboardUrlTemplate = tc.ServiceTemplate
+			stub.ServiceDefinition.Plans[0].DashboardUrlTemplate = tc.PlanTemplate
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			provisioned, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+			failIfErr(t, "provisioning", err)
+			assertEqual(t, "provision dashboard URL should match", tc.Expected, provisioned.DashboardURL)
+
+			updated, err := serviceBroker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+			failIfErr(t, "updating", err)
+			assertEqual(t, "update dashboard URL should match", tc.Expected, updated.DashboardURL)
+		})
+	}
+}
+
+func TestGCPServiceBroker_BindingCache(t *testing.T) {
+	viper.Set("request.binding_cache_ttl", "1h")
+	defer viper.Reset()
+
+	stub := fakeService(t, false)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+	serviceBroker, closer := newStubbedBroker(t, registry, nil)
+	defer closer()
+
+	_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+	failIfErr(t, "provisioning", err)
+	_, err = serviceBroker.Bind(context.Background(), fakeInstanceId, fakeBindingId, stub.BindDetails(), false)
+	failIfErr(t, "binding", err)
+	assertEqual(t, "bind should build the credentials", 1, stub.Provider.BuildInstanceCredentialsCallCount())
+
+	for i := 0; i < 3; i++ {
+		binding, err := serviceBroker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+		failIfErr(t, "getting binding", err)
+		assertTrue(t, "credentials should be returned", binding.Credentials != nil)
+	}
+	assertEqual(t, "fetches should build the credentials once", 2, stub.Provider.BuildInstanceCredentialsCallCount())
+
+	_, err = serviceBroker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+	failIfErr(t, "updating", err)
+	_, err = serviceBroker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+	failIfErr(t, "getting binding", err)
+	assertEqual(t, "updates should invalidate the cached credentials", 3, stub.Provider.BuildInstanceCredentialsCallCount())
+
+	_, err = serviceBroker.Unbind(context.Background(), fakeInstanceId, fakeBindingId, stub.UnbindDetails(), true)
+	failIfErr(t, "unbinding", err)
+	_, err = serviceBroker.GetBinding(context.Background(), fakeInstanceId, fakeBindingId)
+	assertEqual(t, "unbound bindings should not be returned", brokerapi.ErrBindingDoesNotExist, err)
+}
+
+func TestGCPServiceBroker_CompletionCallback(t *testing.T) {
+	received := make(chan CompletionCallbackPayload, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload CompletionCallbackPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding callback: %v", err)
+		}
+		received <- payload
+	}))
+	defer callback.Close()
+
+	viper.Set("request.completion_callback.allowed_hosts", "ci.example.com, 127.0.0.1")
+	defer viper.Reset()
+
+	provisionDetails := func(stub *serviceStub, url string) brokerapi.ProvisionDetails {
+		req := stub.ProvisionDetails()
+		req.RawParameters = json.RawMessage(fmt.Sprintf(`{"completion_callback":%q}`, url))
+		return req
+	}
+
+	cases := map[string]struct {
+		PollErr  error
+		Expected CompletionCallbackPayload
+	}{
+		"succeeded": {
+			Expected: CompletionCallbackPayload{InstanceID: fakeInstanceId, OperationType: models.ProvisionOperationType, State: string(brokerapi.Succeeded)},
+		},
+		"failed": {
+			PollErr:  errors.New("quota exceeded"),
+			Expected: CompletionCallbackPayload{InstanceID: fakeInstanceId, OperationType: models.ProvisionOperationType, State: string(brokerapi.Failed), Description: "quota exceeded"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			stub := fakeService(t, true)
+			stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{OperationType: models.ProvisionOperationType, OperationId: "operation"}, nil)
+			stub.Provider.PollInstanceReturns(tc.PollErr == nil, tc.PollErr)
+			registry := broker.BrokerRegistry{}
+			registry.Register(stub.ServiceDefinition)
+			serviceBroker, closer := newStubbedBroker(t, registry, nil)
+			defer closer()
+
+			_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, provisionDetails(stub, callback.URL+"/hooks/42"), true)
+			failIfErr(t, "provisioning", err)
+			_, err = serviceBroker.LastOperation(context.Background(), fakeInstanceId, brokerapi.PollDetails{})
+			failIfErr(t, "polling", err)
+
+			select {
+			case payload := <-received:
+				assertEqual(t, "the final state should be posted", tc.Expected, payload)
+			case <-time.After(5 * time.Second):
+				t.Fatal("the callback wasn't called")
+			}
+		})
+	}
+
+	t.Run("host-not-allowed", func(t *testing.T) {
+		stub := fakeService(t, true)
+		registry := broker.BrokerRegistry{}
+		registry.Register(stub.ServiceDefinition)
+		serviceBroker, closer := newStubbedBroker(t, registry, nil)
+		defer closer()
+
+		_, err := serviceBroker.Provision(context.Background(), fakeInstanceId, provisionDetails(stub, "https://attacker.example.com/hooks"), true)
+		assertEqual(t, "errors should match", `completion_callback host "attacker.example.com" is not allowed`, fmt.Sprint(err))
+		assertEqual(t, "the provider should not be called", 0, stub.Provider.ProvisionCallCount())
+	})
+}
+
+func TestGCPServiceBroker_SensitiveOutputs(t *testing.T) {
+	const secret = "s3cret-admin-password"
+
+	stub := fakeService(t, false)
+	stub.ServiceDefinition.BindOutputVariables = append(stub.ServiceDefinition.BindOutputVariables,
+		broker.BrokerVariable{FieldName: "admin_password", Type: broker.JsonTypeString, Details: "The admin password.", Sensitive: true},
+		broker.BrokerVariable{FieldName: "host", Type: broker.JsonTypeString, Details: "The host."},
+	)
+	stub.ServiceDefinition.DashboardUrlTemplate = "https://{{output.host}}/console"
+	stub.Provider.ProvisionReturns(models.ServiceInstanceDetails{
+		OtherDetails: fmt.Sprintf(`{"admin_password":%q,"host":"db.example.com"}`, secret),
+	}, nil)
+	registry := broker.BrokerRegistry{}
+	registry.Register(stub.ServiceDefinition)
+	serviceBroker, closer := newStubbedBroker(t, registry, nil)
+	defer closer()
+	logger := lagertest.NewTestLogger("sensitive-outputs")
+	serviceBroker.Logger = logger
+
+	provisioned, err := serviceBroker.Provision(context.Background(), fakeInstanceId, stub.ProvisionDetails(), true)
+	failIfErr(t, "provisioning", err)
+	assertEqual(t, "non-sensitive outputs should render", "https://db.example.com/console", provisioned.DashboardURL)
+
+	outputs, err := serviceBroker.InstanceOutputs(context.Background(), fakeInstanceId)
+	failIfErr(t, "getting outputs", err)
+	assertEqual(t, "sensitive outputs should be masked", map[string]interface{}{
+		"admin_password": broker.MaskedOutputValue,
+		"host":           "db.example.com",
+	}, outputs)
+
+	_, err = serviceBroker.GetInstance(context.Background(), fakeInstanceId)
+	assertEqual(t, "GetInstance should not return the instance", ErrGetInstancesUnsupported, err)
+
+	_, err = serviceBroker.Update(context.Background(), fakeInstanceId, stub.UpdateDetails(), true)
+	failIfErr(t, "updating", err)
+
+	encoded, err := json.Marshal(outputs)
+	failIfErr(t, "encoding outputs", err)
+	for surface, value := range map[string]string{
+		"outputs":       string(encoded),
+		"dashboard URL": provisioned.DashboardURL,
+		"logs":          string(logger.Buffer().Contents()),
+	} {
+		assertTrue(t, fmt.Sprintf("the secret should not leak through the %s", surface), !strings.Contains(value, secret))
+	}
+}
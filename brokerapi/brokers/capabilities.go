@@ -0,0 +1,34 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// ServiceCapabilities reports how the service with the given ID and its
+// provider behave.
+func (broker *ServiceBroker) ServiceCapabilities(ctx context.Context, serviceID string) (capabilities broker.ServiceCapabilities, err error) {
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(serviceID, "")
+	if err != nil {
+		return capabilities, brokerapi.NewFailureResponse(err, http.StatusNotFound, "service-not-found")
+	}
+
+	return serviceDefinition.Capabilities(serviceProvider), nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const (
+	catalogEnrichmentTTLProp = "api.catalog_enrichment_ttl"
+
+	// catalogEnrichmentTimeout bounds how long a provider may take to enrich
+	// its entry, so a slow cloud API doesn't stall the catalog.
+	catalogEnrichmentTimeout = 10 * time.Second
+)
+
+func init() {
+	viper.BindEnv(catalogEnrichmentTTLProp, "CATALOG_ENRICHMENT_TTL")
+	viper.SetDefault(catalogEnrichmentTTLProp, "5m")
+}
+
+type enrichedEntry struct {
+	entry   *broker.Service
+	expires time.Time
+}
+
+// catalogEnrichment caches the catalog entries enriched by providers
+// implementing broker.CatalogEnricher for the configured TTL. Failed
+// enrichments aren't cached and fall back to the static entry, so a cloud
+// outage never fails the whole catalog.
+type catalogEnrichment struct {
+	mu      sync.Mutex
+	entries map[string]enrichedEntry
+}
+
+func newCatalogEnrichment() *catalogEnrichment {
+	return &catalogEnrichment{entries: make(map[string]enrichedEntry)}
+}
+
+// Enrich returns the entry of the service enriched by its provider, or the
+// static entry if the provider doesn't enrich its catalog or failed to.
+func (c *catalogEnrichment) Enrich(ctx context.Context, logger lager.Logger, service *broker.ServiceDefinition, entry *broker.Service) *broker.Service {
+	enricher, ok := service.ProviderBuilder(logger).(broker.CatalogEnricher)
+	if !ok {
+		return entry
+	}
+
+	c.mu.Lock()
+	cached, ok := c.entries[service.Id]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.entry
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, catalogEnrichmentTimeout)
+	defer cancel()
+
+	enriched := entry.DeepCopy()
+	if err := enricher.EnrichCatalog(ctx, &enriched); err != nil {
+		logger.Error("enriching-catalog", err, lager.Data{"service": service.Name})
+		return entry
+	}
+
+	c.mu.Lock()
+	c.entries[service.Id] = enrichedEntry{entry: &enriched, expires: time.Now().Add(viper.GetDuration(catalogEnrichmentTTLProp))}
+	c.mu.Unlock()
+
+	return &enriched
+}
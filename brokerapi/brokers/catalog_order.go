@@ -0,0 +1,37 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const catalogOrderProp = "api.catalog_order"
+
+func init() {
+	viper.BindEnv(catalogOrderProp, "CATALOG_ORDER")
+	viper.SetDefault(catalogOrderProp, string(broker.CatalogOrderDefinition))
+}
+
+// sortCatalog sorts the services and plans in the configured order and logs
+// the lists that fell back to name order because of duplicate display orders.
+func sortCatalog(logger lager.Logger, services []broker.Service) {
+	order := broker.CatalogOrder(viper.GetString(catalogOrderProp))
+	if fallbacks := broker.SortCatalog(services, order); len(fallbacks) > 0 {
+		logger.Info("duplicate-display-order", lager.Data{"sorted_by_name": fallbacks})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const (
+	completionCallbackAllowedHostsProp  = "request.completion_callback.allowed_hosts"
+	completionCallbackAttemptsProp      = "request.completion_callback.attempts"
+	completionCallbackRetryIntervalProp = "request.completion_callback.retry_interval"
+
+	defaultCompletionCallbackAttempts      = 3
+	defaultCompletionCallbackRetryInterval = 10 * time.Second
+)
+
+func init() {
+	viper.BindEnv(completionCallbackAllowedHostsProp, "COMPLETION_CALLBACK_ALLOWED_HOSTS")
+	viper.SetDefault(completionCallbackAllowedHostsProp, "")
+
+	viper.BindEnv(completionCallbackAttemptsProp, "COMPLETION_CALLBACK_ATTEMPTS")
+	viper.SetDefault(completionCallbackAttemptsProp, defaultCompletionCallbackAttempts)
+
+	viper.BindEnv(completionCallbackRetryIntervalProp, "COMPLETION_CALLBACK_RETRY_INTERVAL")
+	viper.SetDefault(completionCallbackRetryIntervalProp, defaultCompletionCallbackRetryInterval)
+}
+
+// completionCallbackClient posts the final states of operations. Redirects
+// aren't followed: only the callback's host was checked against the allowed
+// hosts, so an allowed host could otherwise forward the post anywhere.
+var completionCallbackClient = &http.Client{
+	Timeout: 5 * time.Second,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// CompletionCallbackPayload is posted to an instance's completion callback
+// once one of its asynchronous operations finishes.
+type CompletionCallbackPayload struct {
+	InstanceID    string `json:"instance_id"`
+	OperationType string `json:"operation_type"`
+	State         string `json:"state"`
+	Description   string `json:"description,omitempty"`
+}
+
+// completionCallback returns the completion callback of already validated
+// request parameters, an empty string if the request doesn't set it. Callbacks
+// to hosts the operator didn't allow are rejected.
+func completionCallback(rawParameters json.RawMessage) (string, error) {
+	callback, err := broker.CompletionCallback(rawParameters)
+	if err != nil || callback == nil {
+		return "", err
+	}
+
+	if !completionCallbackHostAllowed(callback.Hostname()) {
+		return "", brokerapi.NewFailureResponse(
+			fmt.Errorf("%s host %q is not allowed", broker.CompletionCallbackParameter, callback.Hostname()),
+			http.StatusBadRequest,
+			"completion-callback-not-allowed",
+		)
+	}
+
+	return callback.String(), nil
+}
+
+// completionCallbackHostAllowed checks the host against the comma separated
+// allowed hosts. Entries starting with "*." allow any subdomain.
+func completionCallbackHostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range strings.Split(viper.GetString(completionCallbackAllowedHostsProp), ",") {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		switch {
+		case allowed == "":
+			continue
+		case strings.HasPrefix(allowed, "*."):
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		case host == allowed:
+			return true
+		}
+	}
+
+	return false
+}
+
+// notifyCompletionCallback posts the final state of the instance's
+// asynchronous operation to its completion callback, if it has one. Delivery
+// is retried in the background; failures are logged but don't affect the
+// operation.
+func notifyCompletionCallback(logger lager.Logger, instance models.ServiceInstanceDetails, operationType string, state brokerapi.LastOperationState, description string) {
+	if instance.CompletionCallback == "" {
+		return
+	}
+
+	body, err := json.Marshal(CompletionCallbackPayload{
+		InstanceID:    instance.ID,
+		OperationType: operationType,
+		State:         string(state),
+		Description:   description,
+	})
+	if err != nil {
+		logger.Error("completion-callback", err, lager.Data{"instance_id": instance.ID})
+		return
+	}
+
+	attempts := viper.GetInt(completionCallbackAttemptsProp)
+	interval := viper.GetDuration(completionCallbackRetryIntervalProp)
+	go func() {
+		for attempt := 1; ; attempt++ {
+			err := postCompletionCallback(instance.CompletionCallback, body)
+			if err == nil {
+				return
+			}
+
+			logger.Error("completion-callback", err, lager.Data{"instance_id": instance.ID, "attempt": attempt})
+			if attempt >= attempts {
+				return
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func postCompletionCallback(url string, body []byte) error {
+	resp, err := completionCallbackClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("the callback responded %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager/lagertest"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+func TestCompletionCallbackHostAllowed(t *testing.T) {
+	viper.Set(completionCallbackAllowedHostsProp, "ci.example.com, *.pipelines.example.com")
+	defer viper.Set(completionCallbackAllowedHostsProp, "")
+
+	cases := map[string]bool{
+		"ci.example.com":              true,
+		"CI.example.com":              true,
+		"a.pipelines.example.com":     true,
+		"a.b.pipelines.example.com":   true,
+		"pipelines.example.com":       false,
+		"evilpipelines.example.com":   false,
+		"example.com":                 false,
+		"ci.example.com.attacker.com": false,
+	}
+
+	for host, expected := range cases {
+		t.Run(host, func(t *testing.T) {
+			if actual := completionCallbackHostAllowed(host); actual != expected {
+				t.Errorf("Expected %v, got %v", expected, actual)
+			}
+		})
+	}
+
+	viper.Set(completionCallbackAllowedHostsProp, "")
+	if completionCallbackHostAllowed("ci.example.com") {
+		t.Error("Expected no host to be allowed by default")
+	}
+}
+
+func TestPostCompletionCallback_redirects(t *testing.T) {
+	var redirected int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+	}))
+	defer internal.Close()
+
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusTemporaryRedirect)
+	}))
+	defer callback.Close()
+
+	err := postCompletionCallback(callback.URL, []byte(`{}`))
+	if err == nil || err.Error() != "the callback responded 307 Temporary Redirect" {
+		t.Errorf("Expected the redirect to fail the delivery, got %v", err)
+	}
+	if redirected != 0 {
+		t.Error("Expected the redirect not to be followed")
+	}
+}
+
+func TestNotifyCompletionCallback_retries(t *testing.T) {
+	viper.Set(completionCallbackRetryIntervalProp, "1ms")
+	defer viper.Set(completionCallbackRetryIntervalProp, defaultCompletionCallbackRetryInterval)
+
+	var calls int32
+	delivered := make(chan struct{})
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < defaultCompletionCallbackAttempts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		close(delivered)
+	}))
+	defer callback.Close()
+
+	instance := models.ServiceInstanceDetails{ID: "instance", CompletionCallback: callback.URL}
+	notifyCompletionCallback(lagertest.NewTestLogger("completion-callback"), instance, models.ProvisionOperationType, brokerapi.Succeeded, "")
+
+	select {
+	case <-delivered:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the callback to be delivered, got %d calls", atomic.LoadInt32(&calls))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+// EstimateCost prices an instance of the requested plan with the requested
+// parameters without provisioning it. The parameters are validated the same
+// way Provision validates them. Providers implementing broker.CostEstimator
+// price the instance from its variables, others get the static costs of the
+// plan.
+func (broker *ServiceBroker) EstimateCost(ctx context.Context, instanceID string, request broker.CostEstimateRequest) (*broker.CostEstimate, error) {
+	logger := broker.Logger.Session("estimate-cost", lager.Data{
+		"instance_id": instanceID,
+		"service_id":  request.ServiceID,
+		"plan_id":     request.PlanID,
+	})
+
+	if err := request.Validate(); err != nil {
+		return nil, brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-cost-estimate-request")
+	}
+
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(request.ServiceID, "")
+	if err != nil {
+		return nil, lookupFailure(err, http.StatusBadRequest)
+	}
+
+	plan, err := serviceDefinition.GetPlanById(request.PlanID)
+	if err != nil {
+		return nil, lookupFailure(err, http.StatusBadRequest)
+	}
+
+	details := brokerapi.ProvisionDetails{
+		ServiceID:        request.ServiceID,
+		PlanID:           request.PlanID,
+		OrganizationGUID: request.OrganizationGUID,
+		SpaceGUID:        request.SpaceGUID,
+		RawParameters:    request.Parameters,
+	}
+
+	if err := checkParametersSize(details.GetRawParameters(), provisionParamsMaxBytesProp); err != nil {
+		return nil, err
+	}
+
+	if err := validateUserParameters(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
+	vars, err := serviceDefinition.ProvisionVariables(instanceID, details, *plan, broker.Credstore)
+	if err != nil {
+		return nil, err
+	}
+
+	estimate, err := estimateCost(ctx, serviceProvider, vars, *plan)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("estimated", lager.Data{"source": estimate.Source})
+	return estimate, nil
+}
+
+// estimateCost asks the provider to price the instance if it can, falling
+// back to the static costs of the plan.
+func estimateCost(ctx context.Context, provider broker.ServiceProvider, vars *varcontext.VarContext, plan broker.ServicePlan) (*broker.CostEstimate, error) {
+	estimator, ok := provider.(broker.CostEstimator)
+	if !ok {
+		return broker.PlanCostEstimate(plan), nil
+	}
+
+	estimate, err := estimator.EstimateCost(ctx, vars)
+	if err != nil {
+		return nil, fmt.Errorf("Error estimating cost: %s", err)
+	}
+	if estimate == nil {
+		return broker.PlanCostEstimate(plan), nil
+	}
+
+	estimate.Source = broker.CostEstimateSourceProvider
+	if estimate.Costs == nil {
+		estimate.Costs = []broker.PlanCost{}
+	}
+	return estimate, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+)
+
+var (
+	// ErrBindingNotFound is returned by the operator endpoints for bindings
+	// that don't exist.
+	ErrBindingNotFound = brokerapi.NewFailureResponse(errors.New("binding does not exist"), http.StatusNotFound, "binding-not-found")
+
+	// ErrCredstoreNotConfigured is returned when reissuing credentials of a
+	// broker without a Credstore.
+	ErrCredstoreNotConfigured = brokerapi.NewFailureResponse(errors.New("the broker has no credstore configured"), http.StatusConflict, "credstore-not-configured")
+)
+
+// ReissueBindingCredentials puts the credentials of the binding back in the
+// Credstore under their original name and grants the bound app read access
+// again, e.g. after CredHub was restored from a backup missing the entry. The
+// credentials are rebuilt from the stored records the same way GetBinding
+// builds them; the service's resources aren't touched and no secret is
+// regenerated.
+func (broker *ServiceBroker) ReissueBindingCredentials(ctx context.Context, instanceID, bindingID string) error {
+	logger := broker.Logger.Session("reissue-binding-credentials", lager.Data{
+		"instance_id": instanceID,
+		"binding_id":  bindingID,
+	})
+
+	if broker.Credstore == nil {
+		return ErrCredstoreNotConfigured
+	}
+
+	bindRecord, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
+	if err != nil {
+		return ErrBindingNotFound
+	}
+
+	instanceRecord, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return ErrInstanceNotFound
+	}
+
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instanceRecord.ServiceId, instanceRecord.ProviderAccount)
+	if err != nil {
+		return lookupFailure(err, http.StatusNotFound)
+	}
+
+	credentialKeys, err := serviceDefinition.CredentialKeyMapping()
+	if err != nil {
+		return err
+	}
+
+	binding, err := serviceProvider.BuildInstanceCredentials(ctx, *bindRecord, *instanceRecord)
+	if err != nil {
+		return fmt.Errorf("Error building credentials: %s", err)
+	}
+	broker.bindingCache.Invalidate(instanceID, bindingID)
+	credentials, err := bindingCredentials(binding.Credentials, credentialKeys, bindRecord.CredentialFormat)
+	if err != nil {
+		return err
+	}
+
+	write := credstoreWrite{
+		name:        getCredentialName(broker.getServiceName(serviceDefinition), bindingID),
+		credentials: credentials,
+	}
+	if bindRecord.AppGuid != "" {
+		write.actor = "mtls-app:" + bindRecord.AppGuid
+	}
+	if err := write.apply(broker.Credstore); err != nil {
+		return err
+	}
+
+	logger.Info("reissued", lager.Data{"credential_name": write.name, "app_guid": bindRecord.AppGuid})
+	return nil
+}
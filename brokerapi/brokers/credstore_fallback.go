@@ -0,0 +1,205 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/spf13/viper"
+)
+
+const (
+	credstorePutFailureModeProp   = "credhub.put_failure_mode"
+	credstorePutRetryIntervalProp = "credhub.put_retry_interval"
+
+	// CredstoreFailBind fails binds whose credentials can't be written to the
+	// Credstore.
+	CredstoreFailBind = "fail"
+	// CredstoreReturnRaw returns the credentials themselves instead of a
+	// reference if they can't be written to the Credstore.
+	CredstoreReturnRaw = "raw"
+	// CredstoreRetryWrite returns the reference anyway and retries writing
+	// the credentials in the background.
+	CredstoreRetryWrite = "retry"
+
+	defaultCredstorePutRetryInterval = 30 * time.Second
+)
+
+func init() {
+	viper.BindEnv(credstorePutFailureModeProp, "CH_PUT_FAILURE_MODE")
+	viper.SetDefault(credstorePutFailureModeProp, CredstoreFailBind)
+
+	viper.BindEnv(credstorePutRetryIntervalProp, "CH_PUT_RETRY_INTERVAL")
+	viper.SetDefault(credstorePutRetryIntervalProp, defaultCredstorePutRetryInterval)
+}
+
+// putBindingCredentials writes the credentials of a binding to the Credstore
+// and allows the app to read them, returning the credentials to give the
+// platform. Service keys have no app GUID, so no permission is granted for
+// them. If the write fails, the configured failure mode decides whether
+// the bind fails, returns the raw credentials with a warning, or returns the
+// reference while the write is retried in the background.
+func (broker *ServiceBroker) putBindingCredentials(ctx context.Context, credentialName, appGUID string, credentials interface{}) (interface{}, error) {
+	reference := map[string]interface{}{"credhub-ref": credentialName}
+
+	write := credstoreWrite{name: credentialName, credentials: credentials}
+	if appGUID != "" {
+		write.actor = "mtls-app:" + appGUID
+	}
+	err := write.apply(broker.Credstore)
+	if err == nil {
+		return reference, nil
+	}
+
+	logger := broker.Logger.Session("credstore-unavailable", lager.Data{"credential_name": credentialName})
+	switch mode := viper.GetString(credstorePutFailureModeProp); mode {
+	case CredstoreReturnRaw:
+		logger.Error("returning-raw-credentials", err)
+		setWarning(ctx, "credentials could not be stored in CredHub and are returned directly")
+		return credentials, nil
+	case CredstoreRetryWrite:
+		logger.Error("queueing-credentials", err)
+		broker.credstoreRetries.enqueue(write)
+		return reference, nil
+	default:
+		return nil, err
+	}
+}
+
+// setWarning adds a Warning header to the response.
+func setWarning(ctx context.Context, message string) {
+	broker.SetResponseHeader(ctx, "Warning", fmt.Sprintf("299 - %q", message))
+}
+
+// credstoreWrite is a pending write of binding credentials to the Credstore.
+// The actor is granted read access to them, unless it's empty.
+type credstoreWrite struct {
+	name        string
+	actor       string
+	credentials interface{}
+}
+
+func (w credstoreWrite) apply(cs credstore.CredStore) error {
+	if _, err := cs.Put(w.name, w.credentials); err != nil {
+		return fmt.Errorf("Bind failure: unable to put credentials in Credstore: %v", err)
+	}
+
+	if w.actor == "" {
+		return nil
+	}
+
+	if _, err := cs.AddPermission(w.name, w.actor, []string{"read"}); err != nil {
+		return fmt.Errorf("Bind failure: Unable to add Credstore permissions to app: %v", err)
+	}
+
+	return nil
+}
+
+// credstoreRetryQueue retries failed Credstore writes in the background until
+// they succeed. Writes are keyed by the credential name, so unbinding can
+// cancel a pending write. The queue is kept in memory, writes still pending
+// when the broker stops are lost.
+type credstoreRetryQueue struct {
+	mu      sync.Mutex
+	pending map[string]credstoreWrite
+	running bool
+
+	credstore credstore.CredStore
+	logger    lager.Logger
+}
+
+func newCredstoreRetryQueue(cs credstore.CredStore, logger lager.Logger) *credstoreRetryQueue {
+	return &credstoreRetryQueue{
+		pending:   make(map[string]credstoreWrite),
+		credstore: cs,
+		logger:    logger.Session("credstore-retry"),
+	}
+}
+
+// enqueue adds the write to the queue and starts the worker if it isn't
+// running.
+func (q *credstoreRetryQueue) enqueue(write credstoreWrite) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[write.name] = write
+	if !q.running {
+		interval := viper.GetDuration(credstorePutRetryIntervalProp)
+		if interval <= 0 {
+			interval = defaultCredstorePutRetryInterval
+		}
+
+		q.running = true
+		go q.run(interval)
+	}
+}
+
+// cancel drops the pending write of the credential, returning whether there
+// was one.
+func (q *credstoreRetryQueue) cancel(name string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, ok := q.pending[name]
+	delete(q.pending, name)
+	return ok
+}
+
+// run retries the pending writes every interval until none are left.
+func (q *credstoreRetryQueue) run(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+
+		q.mu.Lock()
+		writes := make([]credstoreWrite, 0, len(q.pending))
+		for _, write := range q.pending {
+			writes = append(writes, write)
+		}
+		q.mu.Unlock()
+
+		for _, write := range writes {
+			if err := write.apply(q.credstore); err != nil {
+				q.logger.Error("retrying-write", err, lager.Data{"credential_name": write.name})
+				continue
+			}
+
+			q.logger.Info("wrote-credentials", lager.Data{"credential_name": write.name})
+			q.mu.Lock()
+			_, stillPending := q.pending[write.name]
+			delete(q.pending, write.name)
+			q.mu.Unlock()
+
+			// the binding was deleted while the write was retried
+			if !stillPending {
+				q.credstore.DeletePermission(write.name)
+				q.credstore.Delete(write.name)
+			}
+		}
+
+		q.mu.Lock()
+		if len(q.pending) == 0 {
+			q.running = false
+			q.mu.Unlock()
+			return
+		}
+		q.mu.Unlock()
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// dashboardURL renders the dashboard URL template of the instance's plan, or
+// of its service, with the outputs the provider returned. Failures are only
+// logged since the instance exists regardless, e.g. a template referencing
+// outputs can't be rendered before an asynchronous provision completes.
+func dashboardURL(logger lager.Logger, serviceDefinition *broker.ServiceDefinition, plan broker.ServicePlan, details brokerapi.ProvisionDetails, instance models.ServiceInstanceDetails) string {
+	template := serviceDefinition.GetDashboardUrlTemplate(plan)
+	if template == "" {
+		return ""
+	}
+
+	outputs := map[string]interface{}{}
+	if err := instance.GetOtherDetails(&outputs); err != nil {
+		logger.Error("dashboard-url-outputs", err, lager.Data{"instance_id": instance.ID})
+	}
+	for _, name := range serviceDefinition.SensitiveOutputs() {
+		delete(outputs, name)
+	}
+
+	url, err := broker.RenderDashboardUrl(template, broker.DashboardTemplateVariables(instance.ID, details, plan, outputs))
+	if err != nil {
+		logger.Info("dashboard-url-not-rendered", lager.Data{
+			"instance_id": instance.ID,
+			"plan_id":     plan.ID,
+			"error":       err.Error(),
+		})
+		return ""
+	}
+
+	return url
+}
+
+// updateDashboardDetails returns the provision details dashboard URL
+// templates of updated instances are rendered with.
+func updateDashboardDetails(instance models.ServiceInstanceDetails, details brokerapi.UpdateDetails) brokerapi.ProvisionDetails {
+	return brokerapi.ProvisionDetails{
+		ServiceID:        instance.ServiceId,
+		PlanID:           instance.PlanId,
+		OrganizationGUID: instance.OrganizationGuid,
+		SpaceGUID:        instance.SpaceGuid,
+		RawContext:       details.RawContext,
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// ErrDeletionProtected is returned when deprovisioning an instance with
+// deletion protection without overriding it.
+var ErrDeletionProtected = brokerapi.NewFailureResponse(
+	errors.New("the instance has deletion protection enabled, disable it or pass override_deletion_protection=true to deprovision it"),
+	http.StatusUnprocessableEntity,
+	"deletion-protected",
+)
+
+type deletionProtectionOverrideKey struct{}
+
+// AddDeletionProtectionOverrideToContext is a middleware storing the
+// override_deletion_protection query parameter of deprovision requests in the
+// request context, so protected instances can be deprovisioned deliberately.
+func AddDeletionProtectionOverrideToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			if override, _ := strconv.ParseBool(req.URL.Query().Get("override_deletion_protection")); override {
+				req = req.WithContext(WithDeletionProtectionOverride(req.Context()))
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithDeletionProtectionOverride returns a copy of the context that allows
+// deprovisioning instances with deletion protection.
+func WithDeletionProtectionOverride(ctx context.Context) context.Context {
+	return context.WithValue(ctx, deletionProtectionOverrideKey{}, true)
+}
+
+func deletionProtectionOverridden(ctx context.Context) bool {
+	override, _ := ctx.Value(deletionProtectionOverrideKey{}).(bool)
+	return override
+}
+
+// checkDeletionProtection rejects deprovisioning a protected instance unless
+// the request overrides the protection.
+func checkDeletionProtection(ctx context.Context, instance *models.ServiceInstanceDetails) error {
+	if !instance.DeletionProtection || deletionProtectionOverridden(ctx) {
+		return nil
+	}
+
+	return ErrDeletionProtected
+}
+
+// deletionProtection returns the deletion protection of already validated
+// request parameters, nil if the request doesn't set it.
+func deletionProtection(rawParameters json.RawMessage) *bool {
+	enabled, _ := broker.DeletionProtection(rawParameters)
+	return enabled
+}
+
+// DeletionProtection reports whether the instance has deletion protection.
+func (broker *ServiceBroker) DeletionProtection(ctx context.Context, instanceID string) (bool, error) {
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return false, ErrInstanceNotFound
+	case err != nil:
+		return false, err
+	}
+
+	return instance.DeletionProtection, nil
+}
+
+// SetDeletionProtection enables or disables the deletion protection of the
+// instance.
+func (broker *ServiceBroker) SetDeletionProtection(ctx context.Context, instanceID string, enabled bool) error {
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return ErrInstanceNotFound
+	case err != nil:
+		return err
+	}
+
+	instance.DeletionProtection = enabled
+	return db_service.SaveServiceInstanceDetails(ctx, instance)
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DeprecatedParameterUses counts the provision and update requests setting
+// deprecated parameters, so operators know when it's safe to remove them.
+var DeprecatedParameterUses = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "csb",
+	Name:      "deprecated_parameter_uses_total",
+	Help:      "The number of provision and update requests that set a deprecated parameter.",
+}, []string{"service", "parameter"})
+
+// warnDeprecatedParameters logs and counts the deprecated parameters of an
+// accepted request and warns the user about them in the response.
+func warnDeprecatedParameters(ctx context.Context, logger lager.Logger, service *broker.ServiceDefinition, instanceID string, rawParameters json.RawMessage) {
+	deprecated := service.DeprecatedParameters(rawParameters)
+	if len(deprecated) == 0 {
+		return
+	}
+
+	logger.Info("deprecated-parameters", lager.Data{
+		"instance_id": instanceID,
+		"service":     service.Name,
+		"parameters":  deprecated,
+	})
+
+	for _, parameter := range deprecated {
+		DeprecatedParameterUses.WithLabelValues(service.Name, parameter).Inc()
+	}
+
+	setWarning(ctx, fmt.Sprintf("deprecated parameters may be removed in a future version: %s", strings.Join(deprecated, ", ")))
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const deprovisionBindingsCheckProp = "request.deprovision_bindings_check"
+
+// The modes of the check for bindings of instances being deprovisioned.
+const (
+	deprovisionBindingsCheckOff    = "off"
+	deprovisionBindingsCheckWarn   = "warn"
+	deprovisionBindingsCheckReject = "reject"
+)
+
+func init() {
+	viper.BindEnv(deprovisionBindingsCheckProp, "DEPROVISION_BINDINGS_CHECK")
+	viper.SetDefault(deprovisionBindingsCheckProp, deprovisionBindingsCheckOff)
+}
+
+type forceDeprovisionKey struct{}
+
+// AddForceDeprovisionToContext is a middleware storing the force query
+// parameter of deprovision requests in the request context, so instances that
+// still have bindings can be deprovisioned deliberately.
+func AddForceDeprovisionToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			if force, _ := strconv.ParseBool(req.URL.Query().Get("force")); force {
+				req = req.WithContext(WithForceDeprovision(req.Context()))
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithForceDeprovision returns a copy of the context that allows
+// deprovisioning instances that still have bindings.
+func WithForceDeprovision(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceDeprovisionKey{}, true)
+}
+
+func deprovisionForced(ctx context.Context) bool {
+	force, _ := ctx.Value(forceDeprovisionKey{}).(bool)
+	return force
+}
+
+// errInstanceHasBindings is returned when deprovisioning an instance that
+// still has bindings without forcing it.
+func errInstanceHasBindings(instanceID string, count int) error {
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("instance %q still has %d binding(s), unbind them or pass force=true to deprovision it", instanceID, count),
+		http.StatusUnprocessableEntity,
+		"bindings-exist",
+	)
+}
+
+// checkDeprovisionBindings counts the bindings of an instance being
+// deprovisioned, whose credentials would be orphaned. Depending on the
+// configured mode, instances with bindings are logged or rejected with a 422
+// unless the request forces the deprovision.
+func (broker *ServiceBroker) checkDeprovisionBindings(ctx context.Context, instance *models.ServiceInstanceDetails) error {
+	mode := viper.GetString(deprovisionBindingsCheckProp)
+	if mode != deprovisionBindingsCheckWarn && mode != deprovisionBindingsCheckReject {
+		return nil
+	}
+
+	count, err := db_service.CountServiceBindingCredentialsByServiceInstanceId(ctx, instance.ID)
+	if err != nil {
+		return fmt.Errorf("Database error counting the bindings of the instance: %s", err)
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	forced := deprovisionForced(ctx)
+	broker.Logger.Info("deprovision-with-bindings", lager.Data{
+		"instance_id": instance.ID,
+		"bindings":    count,
+		"forced":      forced,
+	})
+
+	if mode == deprovisionBindingsCheckWarn || forced {
+		return nil
+	}
+
+	return errInstanceHasBindings(instance.ID, count)
+}
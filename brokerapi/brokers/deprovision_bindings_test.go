@@ -0,0 +1,50 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddForceDeprovisionToContext(t *testing.T) {
+	cases := map[string]struct {
+		Method   string
+		Query    string
+		Expected bool
+	}{
+		"force":             {Method: http.MethodDelete, Query: "?force=true", Expected: true},
+		"without force":     {Method: http.MethodDelete, Query: "", Expected: false},
+		"false force":       {Method: http.MethodDelete, Query: "?force=false", Expected: false},
+		"not a deprovision": {Method: http.MethodPut, Query: "?force=true", Expected: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var actual bool
+			handler := AddForceDeprovisionToContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actual = deprovisionForced(r.Context())
+			}))
+
+			req := httptest.NewRequest(tc.Method, "/v2/service_instances/instance"+tc.Query, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if actual != tc.Expected {
+				t.Errorf("expected force %t, got %t", tc.Expected, actual)
+			}
+		})
+	}
+}
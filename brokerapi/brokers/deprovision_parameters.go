@@ -0,0 +1,75 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+type deprovisionParametersKey struct{}
+
+// AddDeprovisionParametersToContext is a middleware storing the parameters
+// query parameter of deprovision requests, a JSON object, in the request
+// context. The OSB deprovision request has no body to carry them.
+func AddDeprovisionParametersToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete {
+			if params := req.URL.Query().Get("parameters"); params != "" {
+				req = req.WithContext(WithDeprovisionParameters(req.Context(), json.RawMessage(params)))
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithDeprovisionParameters returns a copy of the context carrying the raw
+// parameters of a deprovision request.
+func WithDeprovisionParameters(ctx context.Context, rawParameters json.RawMessage) context.Context {
+	return context.WithValue(ctx, deprovisionParametersKey{}, rawParameters)
+}
+
+func rawDeprovisionParameters(ctx context.Context) json.RawMessage {
+	raw, _ := ctx.Value(deprovisionParametersKey{}).(json.RawMessage)
+	return raw
+}
+
+// deprovisionContext validates the deprovision parameters of the request
+// against the instance's plan and returns a context passing them to the
+// provider.
+func deprovisionContext(ctx context.Context, serviceDefinition *broker.ServiceDefinition, instance *models.ServiceInstanceDetails) (context.Context, error) {
+	raw := rawDeprovisionParameters(ctx)
+	if err := validateUserParameters(raw); err != nil {
+		return ctx, err
+	}
+
+	// instances of removed plans can still be deprovisioned without parameters
+	plan, err := serviceDefinition.GetPlanById(instance.PlanId)
+	if err != nil {
+		plan = &broker.ServicePlan{}
+	}
+
+	params, err := plan.DeprovisionParameters(raw)
+	if err != nil {
+		return ctx, brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-deprovision-parameters")
+	}
+
+	return broker.WithDeprovisionParameters(ctx, params), nil
+}
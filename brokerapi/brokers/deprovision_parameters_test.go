@@ -0,0 +1,51 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAddDeprovisionParametersToContext(t *testing.T) {
+	params := url.QueryEscape(`{"skip_final_snapshot":true}`)
+	cases := map[string]struct {
+		Method   string
+		Query    string
+		Expected string
+	}{
+		"parameters":        {Method: http.MethodDelete, Query: "?parameters=" + params, Expected: `{"skip_final_snapshot":true}`},
+		"without":           {Method: http.MethodDelete, Query: "", Expected: ""},
+		"not a deprovision": {Method: http.MethodPut, Query: "?parameters=" + params, Expected: ""},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var actual string
+			handler := AddDeprovisionParametersToContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actual = string(rawDeprovisionParameters(r.Context()))
+			}))
+
+			req := httptest.NewRequest(tc.Method, "/v2/service_instances/instance"+tc.Query, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if actual != tc.Expected {
+				t.Errorf("expected parameters %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
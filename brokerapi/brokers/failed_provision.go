@@ -0,0 +1,59 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// failedProvision returns the error of the instance's provision if it was the
+// instance's last operation and failed. Such instances keep the provision's
+// operation type and ID so they can be told apart from provisioned ones.
+func failedProvision(ctx context.Context, instance models.ServiceInstanceDetails) (string, bool) {
+	if instance.OperationType != models.ProvisionOperationType {
+		return "", false
+	}
+
+	history, err := db_service.GetOperationHistoryByServiceInstanceId(ctx, instance.ID)
+	if err != nil || len(history) == 0 {
+		return "", false
+	}
+
+	last := history[len(history)-1]
+	if last.OperationType != models.ProvisionOperationType || last.State != string(brokerapi.Failed) {
+		return "", false
+	}
+
+	return last.Error, true
+}
+
+// failedProvisionContext marks the context of a deprovision if the instance's
+// provision failed so the provider knows it may only find part of the
+// instance's resources.
+func failedProvisionContext(ctx context.Context, logger lager.Logger, instance models.ServiceInstanceDetails) context.Context {
+	reason, failed := failedProvision(ctx, instance)
+	if !failed {
+		return ctx
+	}
+
+	logger.Info("deprovisioning-failed-provision", lager.Data{"instance_id": instance.ID, "reason": reason})
+	return broker.WithFailedProvision(ctx, reason)
+}
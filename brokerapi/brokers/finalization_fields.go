@@ -0,0 +1,51 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// finalizeInstanceDetails refreshes the instance's details once an operation
+// completed. Providers declaring finalization fields only have those fetched
+// and merged into the stored details, the others get a full refresh.
+func finalizeInstanceDetails(ctx context.Context, provider broker.ServiceProvider, instance *models.ServiceInstanceDetails) error {
+	finalizer, ok := provider.(broker.FieldFinalizer)
+	if !ok || len(finalizer.FinalizationFields()) == 0 {
+		return provider.UpdateInstanceDetails(ctx, instance)
+	}
+
+	fields := finalizer.FinalizationFields()
+	values, err := finalizer.FetchInstanceFields(ctx, *instance, fields)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]interface{}{}
+	if err := instance.GetOtherDetails(&details); err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if value, ok := values[field]; ok {
+			details[field] = value
+		}
+	}
+
+	return instance.SetOtherDetails(details)
+}
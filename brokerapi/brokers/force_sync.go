@@ -0,0 +1,123 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const (
+	forceSyncProp        = "request.force_sync"
+	forceSyncTimeoutProp = "request.force_sync_timeout"
+)
+
+// forceSyncPollInterval is the time between polls of an operation the broker
+// waits for.
+var forceSyncPollInterval = time.Second
+
+func init() {
+	viper.BindEnv(forceSyncProp, "FORCE_SYNC")
+	viper.SetDefault(forceSyncProp, false)
+
+	viper.BindEnv(forceSyncTimeoutProp, "FORCE_SYNC_TIMEOUT")
+	viper.SetDefault(forceSyncTimeoutProp, "50s")
+}
+
+// checkForceSync returns whether the provider's operation, if asynchronous,
+// should be waited for before responding, because the operator forced
+// synchronous behavior. Providers that can only operate asynchronously can't
+// be used in this mode.
+func checkForceSync(serviceName string, provider broker.ServiceProvider, async bool) (bool, error) {
+	if !async || !viper.GetBool(forceSyncProp) {
+		return false, nil
+	}
+
+	if asyncOnly, ok := provider.(broker.AsyncOnlyProvider); ok && asyncOnly.AsyncOnly() {
+		return false, brokerapi.NewFailureResponse(
+			fmt.Errorf("service %q only supports asynchronous operations, but the broker is configured to force synchronous operations with %s", serviceName, forceSyncProp),
+			http.StatusUnprocessableEntity,
+			"async-only",
+		)
+	}
+
+	return true, nil
+}
+
+// waitForOperation polls the instance's asynchronous operation until it
+// completes or the configured timeout elapses, and finalizes it the same way
+// LastOperation does. It returns false if the operation is still running
+// after the timeout.
+func (broker *ServiceBroker) waitForOperation(ctx context.Context, provider broker.ServiceProvider, instanceID, operationType string) (bool, error) {
+	logger := broker.Logger.Session("force-sync", lager.Data{"instance_id": instanceID, "operation_type": operationType})
+
+	timeout := time.After(viper.GetDuration(forceSyncTimeoutProp))
+	for {
+		instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+		if err != nil {
+			return false, fmt.Errorf("Error getting instance details from database: %s", err)
+		}
+
+		done, err := provider.PollInstance(ctx, *instance)
+		if err != nil {
+			broker.finishOperation(ctx, instanceID, brokerapi.Failed, err.Error())
+			return false, err
+		}
+
+		if done {
+			err := broker.updateStateOnOperationCompletion(ctx, provider, operationType, instanceID)
+			switch err.(type) {
+			case nil:
+				broker.finishOperation(ctx, instanceID, brokerapi.Succeeded, "")
+				return true, nil
+			case *readinessPendingError:
+				// keep waiting for the instance to become ready
+			case *readinessFailedError:
+				broker.finishOperation(ctx, instanceID, brokerapi.Failed, err.Error())
+				return false, err
+			default:
+				return false, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-timeout:
+			logger.Info("timed-out")
+			return false, nil
+		case <-time.After(forceSyncPollInterval):
+		}
+	}
+}
+
+// errForceSyncTimeout is returned when a client that doesn't accept
+// asynchronous operations made a request whose operation didn't complete
+// within the configured timeout.
+func errForceSyncTimeout() error {
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("the operation didn't complete within %s, it continues in the background", viper.GetDuration(forceSyncTimeoutProp)),
+		http.StatusGatewayTimeout,
+		"force-sync-timeout",
+	)
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// forceParameters sets the parameters the operator forced for the plan in the
+// request's parameters, replacing the user's values, and logs the user values
+// that were replaced.
+func forceParameters(logger lager.Logger, svc *broker.ServiceDefinition, plan broker.ServicePlan, instanceID string, rawParameters json.RawMessage) (json.RawMessage, error) {
+	forced, overridden, err := svc.ApplyForcedParameters(plan, rawParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(overridden) > 0 {
+		logger.Info("forced-parameters-override-user-values", lager.Data{
+			"instance_id": instanceID,
+			"plan_id":     plan.ID,
+			"parameters":  overridden,
+		})
+	}
+
+	return forced, nil
+}
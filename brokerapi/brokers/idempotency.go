@@ -0,0 +1,166 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	idempotencyKeyTTLProp    = "request.idempotency_key_ttl"
+	defaultIdempotencyKeyTTL = 10 * time.Minute
+)
+
+func init() {
+	viper.BindEnv(idempotencyKeyTTLProp, "IDEMPOTENCY_KEY_TTL")
+	viper.SetDefault(idempotencyKeyTTLProp, defaultIdempotencyKeyTTL)
+}
+
+// ErrDuplicateRequestInProgress is returned for a retry of a provision request
+// that is still running.
+var ErrDuplicateRequestInProgress = brokerapi.NewFailureResponseBuilder(
+	errors.New("a request with the same identity is still in progress"), http.StatusUnprocessableEntity, "duplicate-request-in-progress",
+).WithErrorKey("ConcurrencyError").Build()
+
+type requestIdentityKey struct{}
+
+// AddRequestIdentityToContext is a middleware storing the
+// X-Broker-API-Request-Identity header in the request context, so the broker
+// can recognize retried requests.
+func AddRequestIdentityToContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if identity := req.Header.Get("X-Broker-API-Request-Identity"); identity != "" {
+			req = req.WithContext(WithRequestIdentity(req.Context(), identity))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithRequestIdentity returns a copy of the context holding the identity of
+// the request.
+func WithRequestIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, requestIdentityKey{}, identity)
+}
+
+func requestIdentity(ctx context.Context) string {
+	identity, _ := ctx.Value(requestIdentityKey{}).(string)
+	return identity
+}
+
+// idempotencyKeyTTL is how long the keys of succeeded requests are kept.
+func idempotencyKeyTTL() time.Duration {
+	ttl := viper.GetDuration(idempotencyKeyTTLProp)
+	if ttl <= 0 {
+		return defaultIdempotencyKeyTTL
+	}
+
+	return ttl
+}
+
+// beginIdempotentProvision records the start of a provision request by its
+// identity. If a request with the same identity already succeeded, its
+// response is returned so the provision isn't repeated. Requests without an
+// identity aren't recorded. The key of a running request never expires, however
+// long the provision takes, so it can't run twice.
+func (broker *ServiceBroker) beginIdempotentProvision(ctx context.Context, instanceID string) (*brokerapi.ProvisionedServiceSpec, error) {
+	identity := requestIdentity(ctx)
+	if identity == "" {
+		return nil, nil
+	}
+
+	if err := db_service.DeleteExpiredIdempotencyKeys(ctx, time.Now()); err != nil {
+		broker.Logger.Error("deleting-expired-idempotency-keys", err)
+	}
+
+	previous, err := db_service.GetIdempotencyKeyByRequestIdentity(ctx, identity)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		// first time the request is seen
+	case err != nil:
+		return nil, err
+	case previous.ServiceInstanceId != instanceID:
+		return nil, brokerapi.NewFailureResponse(errors.New("the request identity was already used for another instance"), http.StatusUnprocessableEntity, "request-identity-reused")
+	case previous.State == string(brokerapi.Succeeded):
+		spec := brokerapi.ProvisionedServiceSpec{}
+		if err := json.Unmarshal([]byte(previous.Response), &spec); err != nil {
+			return nil, err
+		}
+		broker.Logger.Info("replaying-provision", lager.Data{"instance_id": instanceID, "request_identity": identity})
+		return &spec, nil
+	default:
+		return nil, ErrDuplicateRequestInProgress
+	}
+
+	record := models.IdempotencyKey{
+		RequestIdentity:   identity,
+		ServiceInstanceId: instanceID,
+		State:             string(brokerapi.InProgress),
+	}
+	if err := db_service.CreateIdempotencyKey(ctx, &record); err != nil {
+		// a concurrent request with the same identity recorded it first
+		return nil, ErrDuplicateRequestInProgress
+	}
+
+	return nil, nil
+}
+
+// finishIdempotentProvision records the response of a provision request so
+// retries get it too, until the key expires. Failed requests are forgotten so
+// they can be retried.
+func (broker *ServiceBroker) finishIdempotentProvision(ctx context.Context, spec brokerapi.ProvisionedServiceSpec, provisionErr error) {
+	identity := requestIdentity(ctx)
+	if identity == "" {
+		return
+	}
+
+	logger := broker.Logger.Session("finish-idempotent-provision", lager.Data{"request_identity": identity})
+	if provisionErr != nil {
+		if err := db_service.DeleteIdempotencyKey(ctx, identity); err != nil {
+			logger.Error("deleting-key", err)
+		}
+		return
+	}
+
+	record, err := db_service.GetIdempotencyKeyByRequestIdentity(ctx, identity)
+	if err != nil {
+		logger.Error("getting-key", err)
+		return
+	}
+
+	response, err := json.Marshal(spec)
+	if err != nil {
+		logger.Error("serializing-response", err)
+		return
+	}
+
+	expiresAt := time.Now().Add(idempotencyKeyTTL())
+	record.State = string(brokerapi.Succeeded)
+	record.Response = string(response)
+	record.ExpiresAt = &expiresAt
+	if err := db_service.SaveIdempotencyKey(ctx, record); err != nil {
+		logger.Error("saving-key", err)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAddRequestIdentityToContext(t *testing.T) {
+	cases := map[string]struct {
+		Header   string
+		Expected string
+	}{
+		"with identity":    {Header: "e26cea9a-3c88-4f0a-9bc1-0b8d6e2a7d47", Expected: "e26cea9a-3c88-4f0a-9bc1-0b8d6e2a7d47"},
+		"without identity": {Header: "", Expected: ""},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			var actual string
+			handler := AddRequestIdentityToContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actual = requestIdentity(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance", nil)
+			if tc.Header != "" {
+				req.Header.Set("X-Broker-API-Request-Identity", tc.Header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if actual != tc.Expected {
+				t.Errorf("expected identity %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
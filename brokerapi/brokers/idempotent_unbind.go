@@ -0,0 +1,56 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const idempotentUnbindProp = "request.idempotent_unbind"
+
+func init() {
+	viper.BindEnv(idempotentUnbindProp, "IDEMPOTENT_UNBIND")
+	viper.SetDefault(idempotentUnbindProp, false)
+}
+
+// unbindMissing handles unbinding a binding the broker has no record of. By
+// default this fails with ErrBindingDoesNotExist. If configured, the unbind
+// succeeds because the desired end state is already reached, after removing
+// any credentials left in the Credstore on a best-effort basis.
+func (broker *ServiceBroker) unbindMissing(serviceDefinition *broker.ServiceDefinition, instanceID, bindingID string) (brokerapi.UnbindSpec, error) {
+	if !viper.GetBool(idempotentUnbindProp) {
+		return brokerapi.UnbindSpec{}, brokerapi.ErrBindingDoesNotExist
+	}
+
+	logger := broker.Logger.Session("unbind-missing", lager.Data{"instance_id": instanceID, "binding_id": bindingID})
+
+	if broker.Credstore != nil {
+		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
+
+		if err := broker.Credstore.DeletePermission(credentialName); err != nil {
+			logger.Info("delete-permission-failed", lager.Data{"error": err.Error()})
+		}
+
+		if err := broker.Credstore.Delete(credentialName); err != nil {
+			logger.Info("delete-credentials-failed", lager.Data{"error": err.Error()})
+		}
+	}
+
+	logger.Info("binding-already-gone")
+	return brokerapi.UnbindSpec{}, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// InstanceOutputs returns the outputs the provider recorded for the instance
+// with the values of sensitive outputs masked. If the instance's service is no
+// longer in the catalog, every value is masked since none can be known to be
+// safe.
+func (broker *ServiceBroker) InstanceOutputs(ctx context.Context, instanceID string) (map[string]interface{}, error) {
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return nil, ErrInstanceNotFound
+	}
+
+	outputs := map[string]interface{}{}
+	if err := instance.GetOtherDetails(&outputs); err != nil {
+		return nil, err
+	}
+
+	definition, err := broker.registry.GetServiceById(instance.ServiceId)
+	if err != nil {
+		return maskAllOutputs(outputs), nil
+	}
+
+	return definition.MaskSensitiveOutputs(outputs), nil
+}
+
+func maskAllOutputs(outputs map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(outputs))
+	for name := range outputs {
+		masked[name] = broker.MaskedOutputValue
+	}
+	return masked
+}
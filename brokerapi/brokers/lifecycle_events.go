@@ -0,0 +1,130 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"errors"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
+)
+
+// operationEventTypes maps the types of instance operations to the types of
+// the events emitted once they complete.
+var operationEventTypes = map[string]string{
+	models.ProvisionOperationType:   events.InstanceProvisioned,
+	models.DeprovisionOperationType: events.InstanceDeprovisioned,
+	models.UpdateOperationType:      events.InstanceUpdated,
+}
+
+// eventQueueSize is how many events may wait for delivery before new ones
+// are dropped.
+const eventQueueSize = 256
+
+// eventQueue delivers events to a sink in the background, in the order they
+// were emitted, so a slow or unavailable receiver doesn't delay the requests
+// emitting them.
+type eventQueue struct {
+	sink   events.Sink
+	events chan events.Event
+	logger lager.Logger
+}
+
+// newEventQueue starts delivering the events queued for the sink. It returns
+// nil if there's no sink.
+func newEventQueue(sink events.Sink, logger lager.Logger) *eventQueue {
+	if sink == nil {
+		return nil
+	}
+
+	q := &eventQueue{
+		sink:   sink,
+		events: make(chan events.Event, eventQueueSize),
+		logger: logger.Session("event-queue"),
+	}
+	go q.run()
+
+	return q
+}
+
+func (q *eventQueue) run() {
+	for event := range q.events {
+		// delivery isn't tied to the request that emitted the event, which may
+		// be over by now
+		if err := q.sink.Send(context.Background(), event); err != nil {
+			q.logger.Error("sending-event", err, eventLogData(event))
+		}
+	}
+}
+
+// enqueue queues the event for delivery. Events are dropped and logged if the
+// queue is full.
+func (q *eventQueue) enqueue(event events.Event) {
+	select {
+	case q.events <- event:
+	default:
+		q.logger.Error("dropping-event", errors.New("the event queue is full"), eventLogData(event))
+	}
+}
+
+func eventLogData(event events.Event) lager.Data {
+	return lager.Data{"type": event.Type, "instance_id": event.Data.InstanceID, "binding_id": event.Data.BindingID}
+}
+
+// emitEvent queues the event for the configured sink, if any. Failing to
+// deliver it is logged but doesn't fail the operation.
+func (broker *ServiceBroker) emitEvent(ctx context.Context, eventType string, data events.Data) {
+	if broker.eventQueue == nil {
+		return
+	}
+
+	broker.eventQueue.enqueue(events.New(eventType, data))
+}
+
+// emitOperationEvent emits the event of a completed instance operation.
+func (broker *ServiceBroker) emitOperationEvent(ctx context.Context, instanceID, operationType, operationID string, state brokerapi.LastOperationState, errMessage string) {
+	eventType, ok := operationEventTypes[operationType]
+	if !ok {
+		return
+	}
+
+	broker.emitEvent(ctx, eventType, events.Data{
+		InstanceID:  instanceID,
+		OperationID: operationID,
+		Outcome:     string(state),
+		Error:       errMessage,
+	})
+}
+
+// runningOperation returns the operation of the instance that is still
+// running according to its history, if any.
+func runningOperation(ctx context.Context, instanceID string) *models.OperationHistory {
+	history, err := db_service.GetOperationHistoryByServiceInstanceId(ctx, instanceID)
+	if err != nil {
+		return nil
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].FinishedAt == nil {
+			return &history[i]
+		}
+	}
+
+	return nil
+}
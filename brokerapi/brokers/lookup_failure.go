@@ -0,0 +1,36 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// lookupFailure converts the registry's errors for unknown services and plans
+// to failure responses with the given status, so they aren't reported as
+// internal errors. Callers use 400 for IDs sent by the client and 404 for IDs
+// stored with an instance whose service or plan left the catalog. Other
+// errors are returned unchanged.
+func lookupFailure(err error, status int) error {
+	switch err.(type) {
+	case *broker.ServiceNotFoundError:
+		return brokerapi.NewFailureResponse(err, status, "service-not-found")
+	case *broker.PlanNotFoundError:
+		return brokerapi.NewFailureResponse(err, status, "plan-not-found")
+	default:
+		return err
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// checkMaintenanceInfo rejects update requests carrying a maintenance_info
+// that doesn't match the plan's, e.g. because the catalog changed since the
+// platform fetched it.
+func checkMaintenanceInfo(plan *broker.ServicePlan, details brokerapi.UpdateDetails) error {
+	if isEmptyMaintenanceInfo(details.MaintenanceInfo) {
+		return nil
+	}
+
+	if plan.MaintenanceInfo == nil {
+		return brokerapi.ErrMaintenanceInfoNilConflict
+	}
+
+	if !sameMaintenanceInfo(*plan.MaintenanceInfo, details.MaintenanceInfo) {
+		return brokerapi.ErrMaintenanceInfoConflict
+	}
+
+	return nil
+}
+
+// isUpgrade returns true if the update request only changes the instance's
+// maintenance_info, which platforms send to upgrade an instance to the
+// current version of its plan.
+func isUpgrade(instance models.ServiceInstanceDetails, previousPlanID string, details brokerapi.UpdateDetails) (bool, error) {
+	if isEmptyMaintenanceInfo(details.MaintenanceInfo) || details.PlanID != previousPlanID {
+		return false, nil
+	}
+
+	if params := details.GetRawParameters(); len(params) > 0 && string(params) != "{}" {
+		return false, nil
+	}
+
+	var current brokerapi.MaintenanceInfo
+	if err := instance.GetMaintenanceInfo(&current); err != nil {
+		return false, err
+	}
+
+	return !sameMaintenanceInfo(current, details.MaintenanceInfo), nil
+}
+
+// upgradeContext marks the context of an update as an upgrade so providers
+// can tell it apart from parameter changes.
+func upgradeContext(ctx context.Context) context.Context {
+	return broker.WithUpgrade(ctx)
+}
+
+// setMaintenanceInfo records the plan's maintenance_info on the instance.
+func setMaintenanceInfo(instance *models.ServiceInstanceDetails, plan *broker.ServicePlan) error {
+	if plan.MaintenanceInfo == nil {
+		return instance.SetMaintenanceInfo(nil)
+	}
+
+	return instance.SetMaintenanceInfo(plan.MaintenanceInfo)
+}
+
+func isEmptyMaintenanceInfo(info brokerapi.MaintenanceInfo) bool {
+	return len(info.Public) == 0 && info.Private == ""
+}
+
+func sameMaintenanceInfo(a, b brokerapi.MaintenanceInfo) bool {
+	if len(a.Public) == 0 && len(b.Public) == 0 {
+		return a.Private == b.Private
+	}
+
+	return a.Private == b.Private && reflect.DeepEqual(a.Public, b.Public)
+}
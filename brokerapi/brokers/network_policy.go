@@ -0,0 +1,95 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// networkPolicyManager returns the provider's NetworkPolicyManager if the plan
+// creates network policies for its bindings.
+func networkPolicyManager(plan *broker.ServicePlan, provider broker.ServiceProvider) (broker.NetworkPolicyManager, error) {
+	if !plan.NetworkPolicy {
+		return nil, nil
+	}
+
+	manager, ok := provider.(broker.NetworkPolicyManager)
+	if !ok {
+		return nil, fmt.Errorf("plan %q creates network policies but its service doesn't support them", plan.Name)
+	}
+	return manager, nil
+}
+
+// ensureNetworkPolicy returns the ID of the network policy allowing the app
+// to reach the instance, and whether it was created by this call. Bindings
+// of the same app share one policy. Service keys have no app, so they get
+// none.
+func ensureNetworkPolicy(ctx context.Context, manager broker.NetworkPolicyManager, instance models.ServiceInstanceDetails, appGUID string) (string, bool, error) {
+	if manager == nil || appGUID == "" {
+		return "", false, nil
+	}
+
+	bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instance.ID)
+	if err != nil {
+		return "", false, fmt.Errorf("Error listing the bindings of the instance: %s", err)
+	}
+	for _, binding := range bindings {
+		if binding.AppGuid == appGUID && binding.NetworkPolicyId != "" {
+			return binding.NetworkPolicyId, false, nil
+		}
+	}
+
+	policyID, err := manager.CreateNetworkPolicy(ctx, instance, appGUID)
+	if err != nil {
+		return "", false, fmt.Errorf("Error creating the network policy for app %q: %s", appGUID, err)
+	}
+	return policyID, true, nil
+}
+
+// releaseNetworkPolicy deletes the network policy of the binding unless other
+// bindings of the instance still use it. Policies are released even if the
+// plan stopped creating them since the binding was created.
+func releaseNetworkPolicy(ctx context.Context, logger lager.Logger, provider broker.ServiceProvider, instance models.ServiceInstanceDetails, binding models.ServiceBindingCredentials) error {
+	if binding.NetworkPolicyId == "" {
+		return nil
+	}
+
+	manager, ok := provider.(broker.NetworkPolicyManager)
+	if !ok {
+		logger.Info("network-policy-left", lager.Data{"instance_id": instance.ID, "policy_id": binding.NetworkPolicyId})
+		return nil
+	}
+
+	bindings, err := db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instance.ID)
+	if err != nil {
+		return fmt.Errorf("Error listing the bindings of the instance: %s", err)
+	}
+	for _, other := range bindings {
+		if other.BindingId != binding.BindingId && other.NetworkPolicyId == binding.NetworkPolicyId {
+			return nil
+		}
+	}
+
+	if err := manager.DeleteNetworkPolicy(ctx, instance, binding.NetworkPolicyId); err != nil {
+		return fmt.Errorf("Error deleting network policy %q: %s", binding.NetworkPolicyId, err)
+	}
+	return nil
+}
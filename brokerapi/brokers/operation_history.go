@@ -0,0 +1,79 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// OperationHistory returns the operations run on the instance, oldest first.
+func (broker *ServiceBroker) OperationHistory(ctx context.Context, instanceID string) ([]models.OperationHistory, error) {
+	return db_service.GetOperationHistoryByServiceInstanceId(ctx, instanceID)
+}
+
+// recordOperation adds an operation started at startedAt, just before the
+// provider was called, to the history of the instance. Failed and synchronous operations are recorded as finished
+// and their lifecycle event is emitted, asynchronous ones are finished by
+// finishOperation once they complete.
+// Failing to record the history is logged but doesn't fail the operation.
+func (broker *ServiceBroker) recordOperation(ctx context.Context, instanceID, operationType, operationID string, startedAt time.Time, async bool, opErr error) {
+	now := time.Now()
+	record := models.OperationHistory{
+		ServiceInstanceId: instanceID,
+		OperationType:     operationType,
+		OperationId:       operationID,
+		State:             string(brokerapi.InProgress),
+		StartedAt:         startedAt,
+	}
+
+	switch {
+	case opErr != nil:
+		record.State = string(brokerapi.Failed)
+		record.Error = opErr.Error()
+		record.FinishedAt = &now
+	case !async:
+		record.State = string(brokerapi.Succeeded)
+		record.FinishedAt = &now
+	}
+
+	if err := db_service.CreateOperationHistory(ctx, &record); err != nil {
+		broker.Logger.Error("recording-operation-history", err, lager.Data{"instance_id": instanceID})
+	}
+
+	if record.FinishedAt != nil {
+		broker.emitOperationEvent(ctx, instanceID, operationType, operationID, brokerapi.LastOperationState(record.State), record.Error)
+	}
+}
+
+// finishOperation sets the final state of the instance's asynchronous
+// operation in its history, emits its lifecycle event and frees the
+// concurrency slot it held, if any.
+func (broker *ServiceBroker) finishOperation(ctx context.Context, instanceID string, state brokerapi.LastOperationState, errMessage string) {
+	broker.serviceConcurrency.Finish(instanceID)
+	running := runningOperation(ctx, instanceID)
+	if err := db_service.FinishOperationHistory(ctx, instanceID, string(state), errMessage); err != nil {
+		broker.Logger.Error("finishing-operation-history", err, lager.Data{"instance_id": instanceID})
+	}
+
+	if running != nil {
+		broker.emitOperationEvent(ctx, instanceID, running.OperationType, running.OperationId, state, errMessage)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// operationProgress keeps the latest progress providers reported for the
+// operations they're polling, keyed by pollKey. It's kept in memory: the
+// progress is only a hint to users and is reported again by the next poll.
+type operationProgress struct {
+	mu      sync.Mutex
+	entries map[string]broker.OperationProgress
+}
+
+func newOperationProgress() *operationProgress {
+	return &operationProgress{entries: make(map[string]broker.OperationProgress)}
+}
+
+// reporting returns a copy of the context recording the progress reported
+// while polling the operation with the given key.
+func (p *operationProgress) reporting(ctx context.Context, key string) context.Context {
+	return broker.WithProgressReporter(ctx, func(progress broker.OperationProgress) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.entries[key] = progress
+	})
+}
+
+// describe returns the description of the latest progress of the operation,
+// or "" if none was reported.
+func (p *operationProgress) describe(key string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	progress, ok := p.entries[key]
+	if !ok {
+		return ""
+	}
+
+	return progress.String()
+}
+
+// forget drops the progress of a finished operation.
+func (p *operationProgress) forget(key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.entries, key)
+}
+
+// joinDescriptions joins the non-empty descriptions of an operation.
+func joinDescriptions(descriptions ...string) string {
+	var parts []string
+	for _, description := range descriptions {
+		if description != "" {
+			parts = append(parts, description)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
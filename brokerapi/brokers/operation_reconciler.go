@@ -0,0 +1,132 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/spf13/viper"
+)
+
+const (
+	reconcileOperationsProp         = "operations.reconcile"
+	reconcileOperationsIntervalProp = "operations.reconcile_interval"
+
+	defaultReconcileOperationsInterval = time.Minute
+)
+
+func init() {
+	viper.BindEnv(reconcileOperationsProp, "OPERATIONS_RECONCILE")
+	viper.SetDefault(reconcileOperationsProp, true)
+
+	viper.BindEnv(reconcileOperationsIntervalProp, "OPERATIONS_RECONCILE_INTERVAL")
+	viper.SetDefault(reconcileOperationsIntervalProp, defaultReconcileOperationsInterval)
+}
+
+// StartOperationReconciler reconciles the pending asynchronous operations
+// once right away, e.g. those left running when the broker restarted, and
+// then every operations.reconcile_interval until the context is done, so
+// operations no client polls, like those of service keys, still complete.
+// An interval of 0 only reconciles them on startup.
+// The returned channel is closed once the reconciler stopped, so callers can
+// wait for a running pass to finish before closing the database.
+func (broker *ServiceBroker) StartOperationReconciler(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if !viper.GetBool(reconcileOperationsProp) {
+		close(done)
+		return done
+	}
+
+	interval := viper.GetDuration(reconcileOperationsIntervalProp)
+	go func() {
+		defer close(done)
+
+		broker.ReconcileOperations(ctx)
+		if interval <= 0 {
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				broker.ReconcileOperations(ctx)
+			}
+		}
+	}()
+
+	return done
+}
+
+// ReconcileOperations polls the operation of every instance with a pending
+// asynchronous operation the same way LastOperation does for platforms, so
+// completed operations are finalized and failed ones recorded. Operations whose
+// completion was already recorded, e.g. failed provisions, are skipped, as
+// are those of instances without operation history.
+func (broker *ServiceBroker) ReconcileOperations(ctx context.Context) {
+	logger := broker.Logger.Session("reconcile-operations")
+
+	instances, err := db_service.GetServiceInstanceDetailsWithOperationId(ctx)
+	if err != nil {
+		logger.Error("listing-pending-operations", err)
+		return
+	}
+
+	for _, instance := range instances {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if !operationPending(ctx, instance.ID) {
+			continue
+		}
+
+		data := lager.Data{"instance_id": instance.ID, "operation_type": instance.OperationType}
+		operation, err := broker.LastOperation(ctx, instance.ID, brokerapi.PollDetails{
+			ServiceID:     instance.ServiceId,
+			PlanID:        instance.PlanId,
+			OperationData: instance.OperationId,
+		})
+		if err != nil {
+			logger.Error("polling-operation", err, data)
+			continue
+		}
+
+		if operation.State != brokerapi.InProgress {
+			data["state"] = operation.State
+			logger.Info("operation-finished", data)
+		}
+	}
+}
+
+// operationPending returns true if the history of the instance shows its
+// last operation is still running. Instances without history, e.g. provisioned
+// before it was kept, aren't owned by the reconciler: their operation can't be
+// marked finished, so they'd be polled forever, and are left to the platform.
+func operationPending(ctx context.Context, instanceID string) bool {
+	history, err := db_service.GetOperationHistoryByServiceInstanceId(ctx, instanceID)
+	if err != nil || len(history) == 0 {
+		return false
+	}
+
+	return history[len(history)-1].FinishedAt == nil
+}
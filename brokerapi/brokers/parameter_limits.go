@@ -0,0 +1,61 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+const (
+	provisionParamsMaxBytesProp = "request.max_parameter_bytes.provision"
+	updateParamsMaxBytesProp    = "request.max_parameter_bytes.update"
+	bindParamsMaxBytesProp      = "request.max_parameter_bytes.bind"
+
+	// defaultParamsMaxBytes leaves room for the parameters to be stored in a
+	// MySQL TEXT column (64KiB).
+	defaultParamsMaxBytes = 32 * 1024
+)
+
+func init() {
+	viper.BindEnv(provisionParamsMaxBytesProp, "PROVISION_PARAMS_MAX_BYTES")
+	viper.SetDefault(provisionParamsMaxBytesProp, defaultParamsMaxBytes)
+
+	viper.BindEnv(updateParamsMaxBytesProp, "UPDATE_PARAMS_MAX_BYTES")
+	viper.SetDefault(updateParamsMaxBytesProp, defaultParamsMaxBytes)
+
+	viper.BindEnv(bindParamsMaxBytesProp, "BIND_PARAMS_MAX_BYTES")
+	viper.SetDefault(bindParamsMaxBytesProp, defaultParamsMaxBytes)
+}
+
+// checkParametersSize returns a 400 error if the raw parameters are larger
+// than the limit configured in the given property. A limit of 0 or less
+// disables the check.
+func checkParametersSize(rawParameters json.RawMessage, maxBytesProp string) error {
+	maxBytes := viper.GetInt(maxBytesProp)
+	if maxBytes <= 0 || len(rawParameters) <= maxBytes {
+		return nil
+	}
+
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("parameters are %d bytes, the maximum allowed size is %d bytes", len(rawParameters), maxBytes),
+		http.StatusBadRequest,
+		"parameters-too-large",
+	)
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// pendingProvision tells a provider returning a partially provisioned
+// instance apart from a failed provision.
+func pendingProvision(logger lager.Logger, instanceID string, err error) (bool, error) {
+	if !broker.IsPendingProvision(err) {
+		return false, err
+	}
+
+	logger.Info("provision-pending", lager.Data{"instance_id": instanceID, "reason": err.Error()})
+	return true, nil
+}
+
+// rollBackPendingProvision deprovisions a partially provisioned instance if
+// the platform doesn't accept asynchronous provisions, so it can't poll for
+// the remaining work and nothing is left behind when the request fails.
+func rollBackPendingProvision(ctx context.Context, logger lager.Logger, provider broker.ServiceProvider, instance models.ServiceInstanceDetails) error {
+	details := brokerapi.DeprovisionDetails{ServiceID: instance.ServiceId, PlanID: instance.PlanId}
+	if _, err := provider.Deprovision(ctx, instance, details); err != nil {
+		logger.Error("rolling-back-pending-provision", err, lager.Data{"instance_id": instance.ID})
+		return fmt.Errorf("the instance needs an asynchronous provision to finish setting up. WARNING: rolling it back failed, contact your operator for cleanup: %s", err)
+	}
+
+	return brokerapi.ErrAsyncRequired
+}
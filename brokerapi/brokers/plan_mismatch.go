@@ -0,0 +1,57 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const rejectDeprovisionPlanMismatchProp = "request.reject_deprovision_plan_mismatch"
+
+func init() {
+	viper.BindEnv(rejectDeprovisionPlanMismatchProp, "REJECT_DEPROVISION_PLAN_MISMATCH")
+	viper.SetDefault(rejectDeprovisionPlanMismatchProp, false)
+}
+
+// checkDeprovisionPlan logs deprovision requests whose plan ID doesn't match
+// the plan the instance is on, which indicates the client has stale
+// information. If configured, the requests are rejected with a 400.
+func (broker *ServiceBroker) checkDeprovisionPlan(instance *models.ServiceInstanceDetails, details brokerapi.DeprovisionDetails) error {
+	if details.PlanID == "" || details.PlanID == instance.PlanId {
+		return nil
+	}
+
+	broker.Logger.Info("deprovision-plan-mismatch", lager.Data{
+		"instance_id":     instance.ID,
+		"plan_id":         instance.PlanId,
+		"request_plan_id": details.PlanID,
+	})
+
+	if !viper.GetBool(rejectDeprovisionPlanMismatchProp) {
+		return nil
+	}
+
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("plan ID %q doesn't match the plan %q of instance %q", details.PlanID, instance.PlanId, instance.ID),
+		http.StatusBadRequest,
+		"plan-mismatch",
+	)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// checkPlanPrerequisites rejects provisioning a plan that requires instances
+// of other services unless each of them has an instance in the space the new
+// instance is provisioned in.
+func (broker *ServiceBroker) checkPlanPrerequisites(ctx context.Context, plan *broker.ServicePlan, details brokerapi.ProvisionDetails) error {
+	if len(plan.RequiresInstanceOf) == 0 {
+		return nil
+	}
+
+	if details.SpaceGUID == "" {
+		return prerequisiteFailure(fmt.Errorf("plan %q requires instances of %v in the same space, but the request doesn't say which space the instance is provisioned in", plan.Name, plan.RequiresInstanceOf))
+	}
+
+	for _, required := range plan.RequiresInstanceOf {
+		requiredService, ok := broker.registry[required]
+		if !ok {
+			return prerequisiteFailure(fmt.Errorf("plan %q requires an instance of service %q, which this broker doesn't offer", plan.Name, required))
+		}
+
+		exists, err := db_service.ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx, details.SpaceGUID, requiredService.Id)
+		if err != nil {
+			return fmt.Errorf("Database error checking for instances of %q: %s", required, err)
+		}
+		if !exists {
+			return prerequisiteFailure(fmt.Errorf("plan %q requires an instance of service %q in space %q, create one first", plan.Name, required, details.SpaceGUID))
+		}
+	}
+
+	return nil
+}
+
+func prerequisiteFailure(err error) error {
+	return brokerapi.NewFailureResponse(err, http.StatusUnprocessableEntity, "missing-prerequisite")
+}
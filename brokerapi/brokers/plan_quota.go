@@ -0,0 +1,52 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// checkPlanInstanceQuota rejects provisioning a plan once the foundation has
+// as many instances of it as the operator allows, regardless of the
+// organizations they belong to. It's independent of any other limit on the
+// request, so every limit that applies is enforced. Concurrent provisions may
+// briefly exceed the quota.
+func checkPlanInstanceQuota(ctx context.Context, svc *broker.ServiceDefinition, plan *broker.ServicePlan) error {
+	quota := svc.PlanInstanceQuota(*plan)
+	if quota <= 0 {
+		return nil
+	}
+
+	count, err := db_service.CountServiceInstancesByPlan(ctx, plan.ID)
+	if err != nil {
+		return fmt.Errorf("Database error counting the instances of plan %q: %s", plan.Name, err)
+	}
+
+	if count >= quota {
+		return brokerapi.NewFailureResponse(
+			fmt.Errorf("plan %q reached its quota of %d instances across the foundation, delete an instance or choose another plan", plan.Name, quota),
+			http.StatusBadRequest,
+			"plan-quota-exceeded",
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	pollCacheTTLProp = "request.poll_cache_ttl"
+	pollWorkersProp  = "request.poll_workers"
+
+	defaultPollWorkers = 25
+)
+
+func init() {
+	viper.BindEnv(pollCacheTTLProp, "POLL_CACHE_TTL")
+	viper.SetDefault(pollCacheTTLProp, "0s")
+
+	viper.BindEnv(pollWorkersProp, "POLL_WORKERS")
+	viper.SetDefault(pollWorkersProp, defaultPollWorkers)
+}
+
+// pollCall is a PollInstance call that concurrent polls of the same
+// operation wait for rather than calling the provider themselves.
+type pollCall struct {
+	wg   sync.WaitGroup
+	done bool
+	err  error
+}
+
+// pollCache shares PollInstance results between LastOperation requests.
+// Concurrent polls of the same operation share one provider call, at most
+// the configured number of provider calls run at once, and in progress
+// results are kept for the configured TTL. Results of completed or failed
+// polls are never cached, so completions are acted on as soon as they are
+// seen, and entries are keyed by the operation so a new operation never sees
+// the result of a previous one.
+type pollCache struct {
+	mu       sync.Mutex
+	inFlight map[string]*pollCall
+	expires  map[string]time.Time
+	workers  chan struct{}
+}
+
+func newPollCache() *pollCache {
+	workers := viper.GetInt(pollWorkersProp)
+	if workers <= 0 {
+		workers = defaultPollWorkers
+	}
+
+	return &pollCache{
+		inFlight: make(map[string]*pollCall),
+		expires:  make(map[string]time.Time),
+		workers:  make(chan struct{}, workers),
+	}
+}
+
+// pollKey identifies the current operation of an instance.
+func pollKey(instance models.ServiceInstanceDetails) string {
+	return instance.ID + "/" + instance.OperationType + "/" + instance.OperationId
+}
+
+// Poll returns whether the operation identified by key is done, calling poll
+// unless an in progress result younger than the TTL is cached or another
+// request is already polling the operation. If ctx ends while waiting for a
+// worker, the operation is reported in progress and nothing is cached: the
+// provider wasn't asked, so the operation must not be failed.
+func (c *pollCache) Poll(ctx context.Context, key string, poll func() (bool, error)) (bool, error) {
+	c.mu.Lock()
+	if expires, ok := c.expires[key]; ok {
+		if time.Now().Before(expires) {
+			c.mu.Unlock()
+			return false, nil
+		}
+		delete(c.expires, key)
+	}
+
+	if call, ok := c.inFlight[key]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.done, call.err
+	}
+
+	call := &pollCall{}
+	call.wg.Add(1)
+	c.inFlight[key] = call
+	c.mu.Unlock()
+
+	select {
+	case c.workers <- struct{}{}:
+		call.done, call.err = poll()
+		<-c.workers
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.inFlight, key)
+		c.mu.Unlock()
+		call.wg.Done()
+		return false, nil
+	}
+
+	c.mu.Lock()
+	delete(c.inFlight, key)
+	if ttl := viper.GetDuration(pollCacheTTLProp); ttl > 0 && call.err == nil && !call.done {
+		c.expires[key] = time.Now().Add(ttl)
+	}
+	c.mu.Unlock()
+	call.wg.Done()
+
+	return call.done, call.err
+}
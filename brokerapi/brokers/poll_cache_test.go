@@ -0,0 +1,197 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestPollCache_Poll(t *testing.T) {
+	defer viper.Reset()
+
+	t.Run("in progress results are cached", func(t *testing.T) {
+		viper.Set(pollCacheTTLProp, "1h")
+		cache := newPollCache()
+
+		var calls int32
+		poll := func() (bool, error) {
+			atomic.AddInt32(&calls, 1)
+			return false, nil
+		}
+
+		for i := 0; i < 3; i++ {
+			if done, err := cache.Poll(context.Background(), "instance/provision/op", poll); done || err != nil {
+				t.Fatalf("expected in progress, got done=%v err=%v", done, err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 provider call, got %d", calls)
+		}
+
+		cache.Poll(context.Background(), "instance/update/op-2", poll)
+		if calls != 2 {
+			t.Errorf("expected a new operation not to use the cached result, got %d calls", calls)
+		}
+	})
+
+	t.Run("completed and failed results aren't cached", func(t *testing.T) {
+		viper.Set(pollCacheTTLProp, "1h")
+		cache := newPollCache()
+
+		var calls int32
+		results := []struct {
+			done bool
+			err  error
+		}{{true, nil}, {false, errors.New("quota exceeded")}, {true, nil}}
+		poll := func() (bool, error) {
+			result := results[atomic.AddInt32(&calls, 1)-1]
+			return result.done, result.err
+		}
+
+		for _, expected := range results {
+			done, err := cache.Poll(context.Background(), "instance/provision/op", poll)
+			if done != expected.done || err != expected.err {
+				t.Errorf("expected done=%v err=%v, got done=%v err=%v", expected.done, expected.err, done, err)
+			}
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		viper.Reset()
+		cache := newPollCache()
+
+		var calls int32
+		poll := func() (bool, error) {
+			atomic.AddInt32(&calls, 1)
+			return false, nil
+		}
+
+		cache.Poll(context.Background(), "instance/provision/op", poll)
+		cache.Poll(context.Background(), "instance/provision/op", poll)
+		if calls != 2 {
+			t.Errorf("expected every poll to call the provider, got %d calls", calls)
+		}
+	})
+
+	t.Run("concurrent polls share a call", func(t *testing.T) {
+		viper.Reset()
+		cache := newPollCache()
+
+		var calls int32
+		release := make(chan struct{})
+		poll := func() (bool, error) {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return true, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if done, _ := cache.Poll(context.Background(), "instance/provision/op", poll); !done {
+					t.Error("expected the shared result to be done")
+				}
+			}()
+		}
+
+		// give the polls time to join the in-flight call
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if calls != 1 {
+			t.Errorf("expected 1 provider call, got %d", calls)
+		}
+	})
+
+	t.Run("waiters giving up see the operation in progress", func(t *testing.T) {
+		viper.Reset()
+		viper.Set(pollWorkersProp, 1)
+		viper.Set(pollCacheTTLProp, "1h")
+		cache := newPollCache()
+
+		release := make(chan struct{})
+		go cache.Poll(context.Background(), "busy", func() (bool, error) {
+			<-release
+			return true, nil
+		})
+		defer close(release)
+
+		// give the busy poll time to take the only worker
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var calls int32
+		poll := func() (bool, error) {
+			atomic.AddInt32(&calls, 1)
+			return true, nil
+		}
+		if done, err := cache.Poll(ctx, "instance/provision/op", poll); done || err != nil {
+			t.Fatalf("expected in progress, got done=%v err=%v", done, err)
+		}
+
+		release <- struct{}{}
+		if done, err := cache.Poll(context.Background(), "instance/provision/op", poll); !done || err != nil {
+			t.Errorf("expected the next poll to call the provider, got done=%v err=%v", done, err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 provider call, got %d", calls)
+		}
+	})
+
+	t.Run("provider calls are bounded", func(t *testing.T) {
+		viper.Reset()
+		viper.Set(pollWorkersProp, 2)
+		cache := newPollCache()
+
+		var running, maxRunning int32
+		poll := func() (bool, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return true, nil
+		}
+
+		var wg sync.WaitGroup
+		for _, key := range []string{"a", "b", "c", "d", "e"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				cache.Poll(context.Background(), key, poll)
+			}(key)
+		}
+		wg.Wait()
+
+		if maxRunning > 2 {
+			t.Errorf("expected at most 2 concurrent provider calls, got %d", maxRunning)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// currentPlanID returns the plan the instance is on before an update. The
+// stored plan is authoritative, the previous_values sent by the platform are
+// only used if the record lost it. Divergences between both are logged as
+// they mean the platform and the broker disagree about the instance.
+func currentPlanID(logger lager.Logger, instance models.ServiceInstanceDetails, details brokerapi.UpdateDetails) string {
+	previous := details.PreviousValues.PlanID
+
+	switch {
+	case instance.PlanId == "":
+		return previous
+	case previous != "" && previous != instance.PlanId:
+		logger.Info("previous-plan-mismatch", lager.Data{
+			"instance_id":   instance.ID,
+			"stored_plan":   instance.PlanId,
+			"previous_plan": previous,
+		})
+	}
+
+	return instance.PlanId
+}
+
+// targetPlanID returns the plan the instance is updated to. Requests that
+// don't change the plan may omit it.
+func targetPlanID(currentPlanID string, details brokerapi.UpdateDetails) string {
+	if details.PlanID == "" {
+		return currentPlanID
+	}
+	return details.PlanID
+}
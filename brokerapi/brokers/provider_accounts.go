@@ -0,0 +1,34 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// selectProviderAccount returns the provider account a new instance in the
+// organization and space is created with, see broker.SelectProviderAccount.
+func selectProviderAccount(logger lager.Logger, instanceID, orgGUID, spaceGUID string) string {
+	account := broker.SelectProviderAccount(orgGUID, spaceGUID)
+	if account != "" {
+		logger.Info("selected-provider-account", lager.Data{
+			"instance_id": instanceID,
+			"account":     account,
+		})
+	}
+
+	return account
+}
@@ -0,0 +1,44 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// provisionTimedOut returns an error if the in progress provision of the
+// instance has run longer than the provision timeout of its plan. Plans
+// without a timeout never time out.
+func provisionTimedOut(instance models.ServiceInstanceDetails, definition *broker.ServiceDefinition) error {
+	if instance.OperationType != models.ProvisionOperationType {
+		return nil
+	}
+
+	plan, err := definition.GetPlanById(instance.PlanId)
+	if err != nil {
+		return nil
+	}
+
+	timeout := plan.GetProvisionTimeout()
+	if timeout <= 0 || time.Since(instance.CreatedAt) <= timeout {
+		return nil
+	}
+
+	return fmt.Errorf("provisioning didn't complete within the provision timeout of plan %q (%s)", plan.Name, timeout)
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// verifyProvision lets providers implementing broker.ProvisionVerifier check
+// a synchronously provisioned instance before it's recorded. If verification
+// fails, the resource is deprovisioned so no unusable instance is left behind.
+func verifyProvision(ctx context.Context, logger lager.Logger, provider broker.ServiceProvider, instance models.ServiceInstanceDetails) error {
+	verifier, ok := provider.(broker.ProvisionVerifier)
+	if !ok {
+		return nil
+	}
+
+	verifyErr := verifier.VerifyProvision(ctx, instance)
+	if verifyErr == nil {
+		return nil
+	}
+
+	logger.Error("provision-verification-failed", verifyErr, lager.Data{"instance_id": instance.ID})
+	details := brokerapi.DeprovisionDetails{ServiceID: instance.ServiceId, PlanID: instance.PlanId}
+	if _, err := provider.Deprovision(ctx, instance, details); err != nil {
+		logger.Error("rolling-back-unverified-provision", err, lager.Data{"instance_id": instance.ID})
+		return fmt.Errorf("provisioned instance failed verification: %s. WARNING: rolling it back failed, contact your operator for cleanup: %s", verifyErr, err)
+	}
+
+	return fmt.Errorf("provisioned instance failed verification and was rolled back: %s", verifyErr)
+}
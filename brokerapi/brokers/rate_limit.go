@@ -0,0 +1,195 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	orgRateLimitPerSecondProp = "request.org_rate_limit.per_second"
+	orgRateLimitBurstProp     = "request.org_rate_limit.burst"
+	orgRateLimitOverridesProp = "request.org_rate_limit.orgs"
+
+	defaultOrgRateLimitBurst = 10
+
+	// orgRateLimitSweepInterval is how often buckets of organizations that
+	// stopped sending requests are evicted.
+	orgRateLimitSweepInterval = time.Minute
+)
+
+func init() {
+	viper.BindEnv(orgRateLimitPerSecondProp, "ORG_RATE_LIMIT_PER_SECOND")
+	viper.SetDefault(orgRateLimitPerSecondProp, 0)
+
+	viper.BindEnv(orgRateLimitBurstProp, "ORG_RATE_LIMIT_BURST")
+	viper.SetDefault(orgRateLimitBurstProp, defaultOrgRateLimitBurst)
+
+	viper.BindEnv(orgRateLimitOverridesProp, "ORG_RATE_LIMITS")
+}
+
+// orgRateLimit is the rate requests of an organization are allowed at.
+type orgRateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// orgRateLimitFor returns the configured limit of the organization. The
+// global limit applies to organizations without an override.
+func orgRateLimitFor(orgGUID string) orgRateLimit {
+	limit := orgRateLimit{
+		PerSecond: viper.GetFloat64(orgRateLimitPerSecondProp),
+		Burst:     viper.GetInt(orgRateLimitBurstProp),
+	}
+
+	override := cast.ToStringMap(viper.GetStringMap(orgRateLimitOverridesProp)[orgGUID])
+	if v, ok := override["per_second"]; ok {
+		limit.PerSecond = cast.ToFloat64(v)
+	}
+	if v, ok := override["burst"]; ok {
+		limit.Burst = cast.ToInt(v)
+	}
+
+	return limit
+}
+
+// ValidateOrgRateLimits checks every enabled limit, global or per
+// organization, allows a burst of at least one request. A bucket that can't
+// hold a token would reject every request of the organization.
+func ValidateOrgRateLimits() error {
+	if limit := orgRateLimitFor(""); limit.PerSecond > 0 && limit.Burst < 1 {
+		return fmt.Errorf("%s must be at least 1 when %s is set, got %d", orgRateLimitBurstProp, orgRateLimitPerSecondProp, limit.Burst)
+	}
+
+	var orgs []string
+	for org := range viper.GetStringMap(orgRateLimitOverridesProp) {
+		orgs = append(orgs, org)
+	}
+	sort.Strings(orgs)
+
+	for _, org := range orgs {
+		if limit := orgRateLimitFor(org); limit.PerSecond > 0 && limit.Burst < 1 {
+			return fmt.Errorf("%s: organization %q must have a burst of at least 1, got %d", orgRateLimitOverridesProp, org, limit.Burst)
+		}
+	}
+
+	return nil
+}
+
+// tokenBucket holds up to burst tokens, refilled at perSecond tokens a second.
+type tokenBucket struct {
+	limit  orgRateLimit
+	tokens float64
+	last   time.Time
+}
+
+// take removes a token from the bucket. If the bucket is empty, it returns
+// false and how long until the next token is available.
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.PerSecond)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := (1 - b.tokens) / b.limit.PerSecond
+	return false, time.Duration(math.Ceil(wait)) * time.Second
+}
+
+// full returns whether the bucket refilled completely since it was last used,
+// so it's no different from a new one.
+func (b *tokenBucket) full(now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*b.limit.PerSecond >= float64(b.limit.Burst)
+}
+
+// orgRateLimiter limits the rate of requests per organization so a single
+// tenant can't exhaust cloud API quotas shared by all of them.
+type orgRateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newOrgRateLimiter() *orgRateLimiter {
+	return &orgRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow returns a 429 error if the organization has exceeded its rate limit,
+// and sets the Retry-After header of the response to when the next request
+// will be allowed. A rate of 0 or less disables the limit.
+func (l *orgRateLimiter) Allow(ctx context.Context, orgGUID string) error {
+	limit := orgRateLimitFor(orgGUID)
+	if limit.PerSecond <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.evictFull(now)
+
+	bucket, ok := l.buckets[orgGUID]
+	if !ok || bucket.limit != limit {
+		bucket = &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[orgGUID] = bucket
+	}
+
+	if ok, retryAfter := bucket.take(now); !ok {
+		broker.SetResponseHeader(ctx, "Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		return brokerapi.NewFailureResponse(
+			fmt.Errorf("too many requests for organization %q, retry after %s", orgGUID, retryAfter),
+			http.StatusTooManyRequests,
+			"rate-limited",
+		)
+	}
+
+	return nil
+}
+
+// evictFull drops the buckets that refilled completely, at most once per
+// sweep interval, so organizations that stopped sending requests don't hold
+// memory forever. Must be called with the lock held.
+func (l *orgRateLimiter) evictFull(now time.Time) {
+	if now.Sub(l.lastSweep) < orgRateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for org, bucket := range l.buckets {
+		if bucket.full(now) {
+			delete(l.buckets, org)
+		}
+	}
+}
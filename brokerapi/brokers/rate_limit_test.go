@@ -0,0 +1,156 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+func TestOrgRateLimiter_Allow(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(orgRateLimitPerSecondProp, 1)
+	viper.Set(orgRateLimitBurstProp, 2)
+	viper.Set(orgRateLimitOverridesProp, `{"big-org": {"per_second": 10, "burst": 5}, "unlimited-org": {"per_second": 0}}`)
+
+	now := time.Unix(0, 0)
+	limiter := newOrgRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	allowed := func(org string, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if limiter.Allow(context.Background(), org) == nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := allowed("small-org", 5); got != 2 {
+		t.Errorf("expected the global burst of 2 to be allowed, got %d", got)
+	}
+
+	if got := allowed("big-org", 10); got != 5 {
+		t.Errorf("expected the override burst of 5 to be allowed, got %d", got)
+	}
+
+	if got := allowed("unlimited-org", 100); got != 100 {
+		t.Errorf("expected a rate of 0 to disable the limit, got %d", got)
+	}
+
+	headers := broker.NewResponseHeaders()
+	err := limiter.Allow(broker.WithResponseHeaders(context.Background(), headers), "small-org")
+	failure, ok := err.(*brokerapi.FailureResponse)
+	if !ok {
+		t.Fatalf("expected a failure response, got %v", err)
+	}
+	if code := failure.ValidatedStatusCode(nil); code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+	if expected := `too many requests for organization "small-org", retry after 1s`; err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err.Error())
+	}
+	response := http.Header{}
+	headers.ApplyTo(response)
+	if retryAfter := response.Get("Retry-After"); retryAfter != "1" {
+		t.Errorf("expected Retry-After 1, got %q", retryAfter)
+	}
+
+	now = now.Add(time.Second)
+	if got := allowed("small-org", 5); got != 1 {
+		t.Errorf("expected 1 request to be allowed after refilling for a second, got %d", got)
+	}
+}
+
+func TestOrgRateLimiter_evictsFullBuckets(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(orgRateLimitPerSecondProp, 1)
+	viper.Set(orgRateLimitBurstProp, 120)
+
+	now := time.Unix(0, 0)
+	limiter := newOrgRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	for _, org := range []string{"idle-org", "busy-org"} {
+		for i := 0; i < 100; i++ {
+			limiter.Allow(context.Background(), org)
+		}
+	}
+
+	now = now.Add(time.Minute)
+	for i := 0; i < 100; i++ {
+		limiter.Allow(context.Background(), "busy-org")
+	}
+
+	now = now.Add(time.Minute)
+	limiter.Allow(context.Background(), "other-org")
+
+	if _, ok := limiter.buckets["idle-org"]; ok {
+		t.Error("expected the bucket of an organization that stopped sending requests to be evicted")
+	}
+	if _, ok := limiter.buckets["busy-org"]; !ok {
+		t.Error("expected the bucket of an organization that isn't refilled yet to be kept")
+	}
+}
+
+func TestValidateOrgRateLimits(t *testing.T) {
+	cases := map[string]struct {
+		PerSecond     float64
+		Burst         int
+		Overrides     string
+		ExpectedError string
+	}{
+		"disabled":             {PerSecond: 0, Burst: 0},
+		"valid":                {PerSecond: 1, Burst: 1, Overrides: `{"big-org": {"per_second": 10, "burst": 5}}`},
+		"global burst of zero": {PerSecond: 1, Burst: 0, ExpectedError: "request.org_rate_limit.burst must be at least 1 when request.org_rate_limit.per_second is set, got 0"},
+		"override burst of zero": {
+			PerSecond:     1,
+			Burst:         10,
+			Overrides:     `{"big-org": {"per_second": 10, "burst": 0}}`,
+			ExpectedError: `request.org_rate_limit.orgs: organization "big-org" must have a burst of at least 1, got 0`,
+		},
+		"override enabling a limit without burst": {
+			PerSecond:     0,
+			Burst:         0,
+			Overrides:     `{"big-org": {"per_second": 10}}`,
+			ExpectedError: `request.org_rate_limit.orgs: organization "big-org" must have a burst of at least 1, got 0`,
+		},
+		"disabled override": {PerSecond: 1, Burst: 10, Overrides: `{"unlimited-org": {"per_second": 0, "burst": 0}}`},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set(orgRateLimitPerSecondProp, tc.PerSecond)
+			viper.Set(orgRateLimitBurstProp, tc.Burst)
+			viper.Set(orgRateLimitOverridesProp, tc.Overrides)
+
+			err := ValidateOrgRateLimits()
+			switch {
+			case tc.ExpectedError == "" && err != nil:
+				t.Errorf("expected no error, got %v", err)
+			case tc.ExpectedError != "" && (err == nil || err.Error() != tc.ExpectedError):
+				t.Errorf("expected error %q, got %v", tc.ExpectedError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// readinessPendingError is returned while a provisioned instance doesn't pass
+// the readiness probe of its plan yet, so the provision stays in progress.
+type readinessPendingError struct {
+	err error
+}
+
+func (e *readinessPendingError) Error() string {
+	return fmt.Sprintf("waiting for the instance to become ready: %v", e.err)
+}
+
+// readinessFailedError is returned if a provisioned instance didn't pass the
+// readiness probe of its plan within the probe's timeout.
+type readinessFailedError struct {
+	err     error
+	timeout time.Duration
+}
+
+func (e *readinessFailedError) Error() string {
+	return fmt.Sprintf("the instance didn't become ready within %s: %v", e.timeout, e.err)
+}
+
+// checkReadiness runs the readiness probe of the instance's plan, if it has
+// one, against the outputs of the provision.
+func (broker *ServiceBroker) checkReadiness(ctx context.Context, instance models.ServiceInstanceDetails) error {
+	definition, err := broker.registry.GetServiceById(instance.ServiceId)
+	if err != nil {
+		return err
+	}
+
+	plan, err := definition.GetPlanById(instance.PlanId)
+	if err != nil || plan.ReadinessProbe == nil {
+		return nil
+	}
+
+	outputs := map[string]interface{}{}
+	if err := instance.GetOtherDetails(&outputs); err != nil {
+		return err
+	}
+
+	probeErr := plan.ReadinessProbe.Check(ctx, outputs)
+	switch {
+	case probeErr == nil:
+		return nil
+	case time.Since(instance.CreatedAt) > plan.ReadinessProbe.GetTimeout():
+		return &readinessFailedError{err: probeErr, timeout: plan.ReadinessProbe.GetTimeout()}
+	default:
+		return &readinessPendingError{err: probeErr}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ErrOperationInProgress is returned when reconciling an instance whose
+// operation the cloud still reports as running.
+var ErrOperationInProgress = brokerapi.NewFailureResponse(errors.New("the operation on this instance is still in progress"), http.StatusConflict, "operation-in-progress")
+
+// Reconcile re-runs the finalization of an instance's last operation. It is
+// used to recover instances left "in progress" because finalization failed
+// after the cloud operation had already succeeded.
+func (broker *ServiceBroker) Reconcile(ctx context.Context, instanceID string) (brokerapi.LastOperation, error) {
+	broker.Logger.Info("Reconciling", lager.Data{"instance_id": instanceID})
+
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return brokerapi.LastOperation{}, ErrInstanceNotFound
+	case err != nil:
+		return brokerapi.LastOperation{}, fmt.Errorf("Error getting instance details from database: %s", err)
+	}
+
+	if instance.OperationType == models.ClearOperationType {
+		return brokerapi.LastOperation{State: brokerapi.Succeeded, Description: "no pending operation"}, nil
+	}
+
+	_, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId, instance.ProviderAccount)
+	if err != nil {
+		return brokerapi.LastOperation{}, err
+	}
+
+	done, err := serviceProvider.PollInstance(ctx, *instance)
+	if err != nil {
+		return brokerapi.LastOperation{}, fmt.Errorf("Error checking the state of the operation: %s", err)
+	}
+
+	if !done {
+		return brokerapi.LastOperation{}, ErrOperationInProgress
+	}
+
+	err = broker.updateStateOnOperationCompletion(ctx, serviceProvider, instance.OperationType, instanceID)
+	switch err.(type) {
+	case nil:
+	case *readinessPendingError:
+		return brokerapi.LastOperation{}, ErrOperationInProgress
+	default:
+		return brokerapi.LastOperation{}, err
+	}
+	broker.finishOperation(ctx, instanceID, brokerapi.Succeeded, "")
+
+	return brokerapi.LastOperation{State: brokerapi.Succeeded}, nil
+}
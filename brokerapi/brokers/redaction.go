@@ -0,0 +1,96 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// redactedValue replaces the values of sensitive parameters in the logs.
+const redactedValue = "[REDACTED]"
+
+// redactParameters returns the parameters of the service's request with the
+// values of sensitive keys masked so they can be logged. Keys are sensitive if
+// they contain one of the configured log.redacted_keys, ignoring case, or the
+// service marks them sensitive. Parameters that aren't valid JSON are masked
+// entirely.
+func (broker *ServiceBroker) redactParameters(serviceID string, rawParameters json.RawMessage) json.RawMessage {
+	if len(rawParameters) == 0 {
+		return rawParameters
+	}
+
+	var params interface{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return json.RawMessage(`"` + redactedValue + `"`)
+	}
+
+	sensitive := make(map[string]bool)
+	if defn, err := broker.registry.GetServiceById(serviceID); err == nil {
+		for _, name := range defn.SensitiveParameters() {
+			sensitive[name] = true
+		}
+	}
+
+	redacted, err := json.Marshal(redactValue(params, utils.RedactedKeys(), sensitive))
+	if err != nil {
+		return json.RawMessage(`"` + redactedValue + `"`)
+	}
+
+	return redacted
+}
+
+// loggableProvisionDetails returns the details with sensitive parameters
+// masked, see redactParameters.
+func (broker *ServiceBroker) loggableProvisionDetails(details brokerapi.ProvisionDetails) brokerapi.ProvisionDetails {
+	details.RawParameters = broker.redactParameters(details.ServiceID, details.RawParameters)
+	return details
+}
+
+// loggableUpdateDetails returns the details with sensitive parameters masked,
+// see redactParameters.
+func (broker *ServiceBroker) loggableUpdateDetails(details brokerapi.UpdateDetails) brokerapi.UpdateDetails {
+	details.RawParameters = broker.redactParameters(details.ServiceID, details.RawParameters)
+	return details
+}
+
+// loggableBindDetails returns the details with sensitive parameters masked,
+// see redactParameters.
+func (broker *ServiceBroker) loggableBindDetails(details brokerapi.BindDetails) brokerapi.BindDetails {
+	details.RawParameters = broker.redactParameters(details.ServiceID, details.RawParameters)
+	return details
+}
+
+func redactValue(value interface{}, redactedKeys []string, sensitive map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			if sensitive[key] || utils.IsRedactedKey(key, redactedKeys) {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(elem, redactedKeys, sensitive)
+			}
+		}
+
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = redactValue(elem, redactedKeys, sensitive)
+		}
+	}
+
+	return value
+}
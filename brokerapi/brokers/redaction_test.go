@@ -0,0 +1,90 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+func TestServiceBroker_redactParameters(t *testing.T) {
+	serviceBroker := &ServiceBroker{
+		registry: broker.BrokerRegistry{
+			"my-service": &broker.ServiceDefinition{
+				Id: "service-id",
+				ProvisionInputVariables: []broker.BrokerVariable{
+					{FieldName: "connection_string", Sensitive: true},
+					{FieldName: "name"},
+				},
+				BindInputVariables: []broker.BrokerVariable{
+					{FieldName: "license", Constraints: map[string]interface{}{validation.KeySensitive: true}},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		ServiceID    string
+		RedactedKeys string
+		Raw          string
+		Expected     string
+	}{
+		"empty": {
+			ServiceID: "service-id",
+			Raw:       ``,
+			Expected:  ``,
+		},
+		"default keys": {
+			ServiceID: "unknown-service",
+			Raw:       `{"name":"db","admin_Password":"hunter2","users":[{"api_token":"abc","role":"reader"}]}`,
+			Expected:  `{"admin_Password":"[REDACTED]","name":"db","users":[{"api_token":"[REDACTED]","role":"reader"}]}`,
+		},
+		"schema sensitive fields": {
+			ServiceID: "service-id",
+			Raw:       `{"name":"db","connection_string":"postgres://u:p@host","license":{"key":"abc"}}`,
+			Expected:  `{"connection_string":"[REDACTED]","license":"[REDACTED]","name":"db"}`,
+		},
+		"configured keys": {
+			ServiceID:    "unknown-service",
+			RedactedKeys: "name, ",
+			Raw:          `{"name":"db","password":"hunter2"}`,
+			Expected:     `{"name":"[REDACTED]","password":"hunter2"}`,
+		},
+		"invalid json": {
+			ServiceID: "service-id",
+			Raw:       `{"password":"hunter2"`,
+			Expected:  `"[REDACTED]"`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			if tc.RedactedKeys != "" {
+				viper.Set(utils.RedactedKeysProp, tc.RedactedKeys)
+			}
+
+			actual := serviceBroker.redactParameters(tc.ServiceID, json.RawMessage(tc.Raw))
+			if string(actual) != tc.Expected {
+				t.Errorf("expected parameters %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
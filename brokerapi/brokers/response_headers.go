@@ -0,0 +1,70 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const responseHeadersProp = "api.response_headers"
+
+func init() {
+	viper.BindEnv(responseHeadersProp, "RESPONSE_HEADERS")
+}
+
+// AddResponseHeaders is a middleware adding the configured headers and those
+// set by providers with broker.SetResponseHeader to the responses. Headers the
+// wrapped handler sets take precedence over provider headers, which take
+// precedence over configured ones.
+func AddResponseHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		headers := broker.NewResponseHeaders()
+		for key, value := range viper.GetStringMapString(responseHeadersProp) {
+			headers.Set(key, value)
+		}
+
+		operationHeaders := broker.NewResponseHeaders()
+		req = req.WithContext(broker.WithResponseHeaders(req.Context(), operationHeaders))
+
+		next.ServeHTTP(&headerWriter{ResponseWriter: w, headers: []*broker.ResponseHeaders{operationHeaders, headers}}, req)
+	})
+}
+
+// headerWriter adds headers to a response right before it's written.
+type headerWriter struct {
+	http.ResponseWriter
+	headers     []*broker.ResponseHeaders
+	wroteHeader bool
+}
+
+func (w *headerWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		for _, h := range w.headers {
+			h.ApplyTo(w.Header())
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *headerWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
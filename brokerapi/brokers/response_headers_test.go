@@ -0,0 +1,92 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+func TestAddResponseHeaders(t *testing.T) {
+	cases := map[string]struct {
+		Configured string
+		Handler    func(w http.ResponseWriter, r *http.Request)
+		Expected   map[string]string
+	}{
+		"configured headers": {
+			Configured: `{"Cache-Control": "no-store", "X-Frame-Options": "DENY"}`,
+			Handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) },
+			Expected:   map[string]string{"Cache-Control": "no-store", "X-Frame-Options": "DENY"},
+		},
+		"added on implicit write": {
+			Configured: `{"Cache-Control": "no-store"}`,
+			Handler:    func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("{}")) },
+			Expected:   map[string]string{"Cache-Control": "no-store"},
+		},
+		"handler headers are kept": {
+			Configured: `{"Content-Type": "text/plain"}`,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+			},
+			Expected: map[string]string{"Content-Type": "application/json"},
+		},
+		"operation headers": {
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				broker.SetResponseHeader(r.Context(), "Retry-After", "30")
+				w.WriteHeader(http.StatusAccepted)
+			},
+			Expected: map[string]string{"Retry-After": "30"},
+		},
+		"operation headers take precedence over configured ones": {
+			Configured: `{"Retry-After": "10"}`,
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				broker.SetResponseHeader(r.Context(), "Retry-After", "30")
+				w.WriteHeader(http.StatusAccepted)
+			},
+			Expected: map[string]string{"Retry-After": "30"},
+		},
+		"operation headers set after the response are ignored": {
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				broker.SetResponseHeader(r.Context(), "Retry-After", "30")
+			},
+			Expected: map[string]string{"Retry-After": ""},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(responseHeadersProp, tc.Configured)
+			defer viper.Reset()
+
+			recorder := httptest.NewRecorder()
+			AddResponseHeaders(http.HandlerFunc(tc.Handler)).ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/v2/service_instances/instance", nil))
+
+			actual := map[string]string{}
+			for key := range tc.Expected {
+				actual[key] = recorder.Header().Get(key)
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected headers %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
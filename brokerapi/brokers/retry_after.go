@@ -0,0 +1,103 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const (
+	retryAfterMinProp = "request.retry_after_min"
+	retryAfterMaxProp = "request.retry_after_max"
+
+	defaultRetryAfterMin = 5 * time.Second
+	defaultRetryAfterMax = 10 * time.Minute
+)
+
+func init() {
+	viper.BindEnv(retryAfterMinProp, "RETRY_AFTER_MIN")
+	viper.SetDefault(retryAfterMinProp, defaultRetryAfterMin)
+
+	viper.BindEnv(retryAfterMaxProp, "RETRY_AFTER_MAX")
+	viper.SetDefault(retryAfterMaxProp, defaultRetryAfterMax)
+}
+
+// setRetryAfter sets the Retry-After header of a poll response for the
+// instance's in progress operation. The provider's suggestion is used if it
+// has one, the plan's poll interval otherwise, clamped to the configured
+// bounds. No header is set if neither suggests an interval.
+func setRetryAfter(ctx context.Context, provider broker.ServiceProvider, instance models.ServiceInstanceDetails, definition *broker.ServiceDefinition, logger lager.Logger) {
+	interval := suggestedRetryAfter(ctx, provider, instance, logger)
+	if interval <= 0 {
+		if plan, err := definition.GetPlanById(instance.PlanId); err == nil {
+			interval = plan.GetPollInterval()
+		}
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	seconds := math.Ceil(clampRetryAfter(interval).Seconds())
+	broker.SetResponseHeader(ctx, "Retry-After", strconv.Itoa(int(seconds)))
+}
+
+// suggestedRetryAfter returns the provider's suggested poll interval for the
+// instance's running operation, 0 if it can't suggest one.
+func suggestedRetryAfter(ctx context.Context, provider broker.ServiceProvider, instance models.ServiceInstanceDetails, logger lager.Logger) time.Duration {
+	suggester, ok := provider.(broker.RetryAfterSuggester)
+	if !ok {
+		return 0
+	}
+
+	interval, err := suggester.SuggestRetryAfter(ctx, instance)
+	if err != nil {
+		logger.Error("suggesting-retry-after", err, lager.Data{"instance_id": instance.ID})
+		return 0
+	}
+
+	return interval
+}
+
+// clampRetryAfter limits the interval to the configured bounds. Bounds that
+// aren't positive durations are replaced by the defaults.
+func clampRetryAfter(interval time.Duration) time.Duration {
+	lower := viper.GetDuration(retryAfterMinProp)
+	if lower <= 0 {
+		lower = defaultRetryAfterMin
+	}
+
+	upper := viper.GetDuration(retryAfterMaxProp)
+	if upper <= 0 {
+		upper = defaultRetryAfterMax
+	}
+
+	switch {
+	case interval < lower:
+		return lower
+	case interval > upper:
+		return upper
+	default:
+		return interval
+	}
+}
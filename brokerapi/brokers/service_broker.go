@@ -19,8 +19,10 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"code.cloudfoundry.org/lager"
+	"github.com/jinzhu/gorm"
 	"github.com/pivotal-cf/brokerapi"
 	"google.golang.org/api/googleapi"
 
@@ -29,15 +31,17 @@ import (
 	"github.com/pivotal/cloud-service-broker/db_service"
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/pivotal/cloud-service-broker/pkg/events"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
 )
 
 var (
 	invalidUserInputMsg        = "User supplied paramaters must be in the form of a valid JSON map."
 	ErrInvalidUserInput        = brokerapi.NewFailureResponse(errors.New(invalidUserInputMsg), http.StatusBadRequest, "parsing-user-request")
 	ErrGetInstancesUnsupported = brokerapi.NewFailureResponse(errors.New("the service_instances endpoint is unsupported"), http.StatusBadRequest, "unsupported")
-	ErrGetBindingsUnsupported  = brokerapi.NewFailureResponse(errors.New("the service_bindings endpoint is unsupported"), http.StatusBadRequest, "unsupported")
 	ErrNonUpdatableParameter   = brokerapi.NewFailureResponse(errors.New("attempt to update parameter that may result in service instance re-creation and data loss"), http.StatusBadRequest, "prohibited")
+	ErrInstanceNotFound        = brokerapi.NewFailureResponse(errors.New("instance does not exist"), http.StatusNotFound, "instance-not-found")
 )
 
 const credhubClientIdentifier = "csb"
@@ -48,15 +52,45 @@ type ServiceBroker struct {
 	Credstore credstore.CredStore
 
 	Logger lager.Logger
+
+	orgRateLimiter     *orgRateLimiter
+	serviceConcurrency *serviceConcurrencyLimiter
+	pollCache          *pollCache
+	bindingCache       *bindingCache
+	progress           *operationProgress
+	enrichment         *catalogEnrichment
+	health             *serviceHealth
+
+	credstoreRetries *credstoreRetryQueue
+	eventQueue       *eventQueue
 }
 
 // New creates a ServiceBroker.
 // Exactly one of ServiceBroker or error will be nil when returned.
 func New(cfg *BrokerConfig, logger lager.Logger) (*ServiceBroker, error) {
+	if cfg.Store != nil {
+		db_service.SetStore(cfg.Store)
+	}
+
+	eventSink := cfg.EventSink
+	if eventSink == nil {
+		eventSink = events.NewSinkFromEnv()
+	}
+
 	return &ServiceBroker{
-		registry:  cfg.Registry,
-		Credstore: cfg.Credstore,
-		Logger:    logger,
+		registry:           cfg.Registry,
+		Credstore:          cfg.Credstore,
+		Logger:             logger,
+		orgRateLimiter:     newOrgRateLimiter(),
+		serviceConcurrency: newServiceConcurrencyLimiter(),
+		pollCache:          newPollCache(),
+		bindingCache:       newBindingCache(),
+		progress:           newOperationProgress(),
+		enrichment:         newCatalogEnrichment(),
+		health:             newServiceHealth(),
+
+		credstoreRetries: newCredstoreRetryQueue(cfg.Credstore, logger),
+		eventQueue:       newEventQueue(eventSink, logger),
 	}, nil
 }
 
@@ -70,36 +104,105 @@ func (broker *ServiceBroker) Services(ctx context.Context) ([]brokerapi.Service,
 		return nil, err
 	}
 
-	for _, service := range enabledServices {
+	entries, err := enrichCatalogEntries(ctx, broker.Logger, broker.enrichment, enabledServices)
+	if err != nil {
+		return svcs, err
+	}
+
+	sortCatalog(broker.Logger, entries)
+	for _, entry := range entries {
+		svcs = append(svcs, entry.ToPlain())
+	}
+
+	return svcs, nil
+}
+
+// Catalog lists the enabled services of the broker's catalog with their
+// typed plans, for tools embedding the broker. Unlike Services, the plans keep
+// the broker specific details like service properties, roles and networks.
+// The result is a copy, modifying it doesn't affect the broker.
+func (broker *ServiceBroker) Catalog(ctx context.Context) ([]broker.Service, error) {
+	enabledServices, err := broker.registry.GetEnabledServices()
+	if err != nil {
+		return nil, err
+	}
+
+	svcs, err := copyCatalogEntries(enabledServices)
+	if err != nil {
+		return nil, err
+	}
+
+	sortCatalog(broker.Logger, svcs)
+	return svcs, nil
+}
+
+func enrichCatalogEntries(ctx context.Context, logger lager.Logger, enrichment *catalogEnrichment, services []*broker.ServiceDefinition) ([]broker.Service, error) {
+	var entries []broker.Service
+	for _, service := range services {
 		entry, err := service.CatalogEntry()
 		if err != nil {
-			return svcs, err
+			return nil, err
 		}
-		svcs = append(svcs, entry.ToPlain())
+		entries = append(entries, *enrichment.Enrich(ctx, logger, service, entry))
+	}
+
+	return entries, nil
+}
+
+func copyCatalogEntries(services []*broker.ServiceDefinition) ([]broker.Service, error) {
+	svcs := []broker.Service{}
+	for _, service := range services {
+		entry, err := service.CatalogEntry()
+		if err != nil {
+			return nil, err
+		}
+		svcs = append(svcs, entry.DeepCopy())
 	}
 
 	return svcs, nil
 }
 
-func (broker *ServiceBroker) getDefinitionAndProvider(serviceId string) (*broker.ServiceDefinition, broker.ServiceProvider, error) {
+// getDefinitionAndProvider looks up the service and creates its provider for
+// the provider account, the empty account uses the broker's credentials.
+func (broker *ServiceBroker) getDefinitionAndProvider(serviceId, account string) (*broker.ServiceDefinition, broker.ServiceProvider, error) {
 	defn, err := broker.registry.GetServiceById(serviceId)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	providerBuilder := defn.ProviderBuilder(broker.Logger)
-	return defn, providerBuilder, nil
+	provider, err := defn.AccountProvider(broker.Logger, account)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return defn, provider, nil
 }
 
 // Provision creates a new instance of a service.
 // It is bound to the `PUT /v2/service_instances/:instance_id` endpoint and can be called using the `cf create-service` command.
-func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, clientSupportsAsync bool) (brokerapi.ProvisionedServiceSpec, error) {
+func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, clientSupportsAsync bool) (spec brokerapi.ProvisionedServiceSpec, err error) {
 	broker.Logger.Info("Provisioning", lager.Data{
 		"instanceId":         instanceID,
 		"accepts_incomplete": clientSupportsAsync,
-		"details":            details,
+		"details":            broker.loggableProvisionDetails(details),
 	})
 
+	// retries of a request that already succeeded get its response
+	replay, err := broker.beginIdempotentProvision(ctx, instanceID)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	if replay != nil {
+		return *replay, nil
+	}
+	defer func() {
+		broker.finishIdempotentProvision(ctx, spec, err)
+	}()
+
+	if err := broker.orgRateLimiter.Allow(ctx, details.OrganizationGUID); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
 	// make sure that instance hasn't already been provisioned
 	exists, err := db_service.ExistsServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
@@ -109,38 +212,109 @@ func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, d
 		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrInstanceAlreadyExists
 	}
 
-	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(details.ServiceID)
-	if err != nil {
+	if err := checkUniqueInstanceName(ctx, details); err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
+	// the resources are created in the cloud account mapped to the space or
+	// organization, and every later operation uses the same account
+	account := selectProviderAccount(broker.Logger, instanceID, details.OrganizationGUID, details.SpaceGUID)
+	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(details.ServiceID, account)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, lookupFailure(err, http.StatusBadRequest)
+	}
+
 	// verify the service exists and the plan exists
 	plan, err := brokerService.GetPlanById(details.PlanID)
 	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, lookupFailure(err, http.StatusBadRequest)
+	}
+
+	if err := broker.checkPlanPrerequisites(ctx, plan, details); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	if err := checkPlanInstanceQuota(ctx, brokerService, plan); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	if err := broker.checkServiceHealth(ctx, brokerService); err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
 	// verify async provisioning is allowed if it is required
 	shouldProvisionAsync := serviceHelper.ProvisionsAsync()
-	if shouldProvisionAsync && !clientSupportsAsync {
+	forceSync, err := checkForceSync(brokerService.Name, serviceHelper, shouldProvisionAsync)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	if shouldProvisionAsync && !clientSupportsAsync && !forceSync {
 		return brokerapi.ProvisionedServiceSpec{}, brokerapi.ErrAsyncRequired
 	}
 
+	if err := checkParametersSize(details.GetRawParameters(), provisionParamsMaxBytesProp); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
 	// Give the user a better error message if they give us a bad request
-	if !isValidOrEmptyJSON(details.GetRawParameters()) {
-		return brokerapi.ProvisionedServiceSpec{}, ErrInvalidUserInput
+	if err := validateUserParameters(details.GetRawParameters()); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	// values are validated and stored in their canonical form
+	if details.RawParameters, err = brokerService.NormalizeParameters(details.GetRawParameters()); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	// operator forced values take precedence and are stored like user values
+	if details.RawParameters, err = forceParameters(broker.Logger, brokerService, *plan, instanceID, details.GetRawParameters()); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	callback, err := completionCallback(details.GetRawParameters())
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
 	// validate parameters meet the service's schema and merge the user vars with
 	// the plan's
-	vars, err := brokerService.ProvisionVariables(instanceID, details, *plan)
+	vars, err := brokerService.ProvisionVariables(instanceID, details, *plan, broker.Credstore)
+	if err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	warnDeprecatedParameters(ctx, broker.Logger, brokerService, instanceID, details.GetRawParameters())
+
+	// the stored fields are encoded before the provider creates resources, so
+	// failing to encode them can't leave resources the broker doesn't know of
+	rendered := renderProvisionParameters(instanceID, details)
+	encoded := models.ServiceInstanceDetails{}
+	if err := encoded.SetAvailabilityZones(instanceAvailabilityZones(rendered, *plan)); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	if err := encoded.SetMetadata(instanceMetadata(rendered.GetRawParameters())); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	if err := encoded.SetGeneratedParameters(brokerService.GeneratedParameters(vars)); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+	if err := setMaintenanceInfo(&encoded, plan); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, err
+	}
+
+	release, err := broker.serviceConcurrency.Acquire(ctx, brokerService.Id)
 	if err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
 	// get instance details
-	instanceDetails, err := serviceHelper.Provision(ctx, vars)
+	startedAt := time.Now()
+	providerCtx, span := tracing.StartSpan(ctx, "provider.Provision")
+	instanceDetails, err := serviceHelper.Provision(providerCtx, vars)
+	pending, err := pendingProvision(broker.Logger, instanceID, err)
+	tracing.End(span, err)
+	broker.serviceConcurrency.ReleaseAfter(instanceID, release, err == nil && (shouldProvisionAsync || pending))
 	if err != nil {
+		broker.recordOperation(ctx, instanceID, models.ProvisionOperationType, "", startedAt, false, err)
 		return brokerapi.ProvisionedServiceSpec{}, err
 	}
 
@@ -150,6 +324,39 @@ func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, d
 	instanceDetails.PlanId = details.PlanID
 	instanceDetails.SpaceGuid = details.SpaceGUID
 	instanceDetails.OrganizationGuid = details.OrganizationGUID
+	instanceDetails.ProviderAccount = account
+	instanceDetails.InstanceName = contextInstanceName(details.GetRawContext())
+	instanceDetails.ResourceName = brokerService.ResourceName(instanceID)
+	instanceDetails.ResourcePrefix = resourcePrefix(rendered)
+	network := instanceNetwork(rendered, *plan)
+	instanceDetails.Network = network.Network
+	instanceDetails.Subnet = network.Subnet
+	instanceDetails.AvailabilityZones = encoded.AvailabilityZones
+	instanceDetails.Metadata = encoded.Metadata
+	if enabled := deletionProtection(rendered.GetRawParameters()); enabled != nil {
+		instanceDetails.DeletionProtection = *enabled
+	}
+	instanceDetails.CompletionCallback = callback
+	instanceDetails.GeneratedParameters = encoded.GeneratedParameters
+	instanceDetails.MaintenanceInfo = encoded.MaintenanceInfo
+
+	// partially provisioned instances are finished asynchronously
+	if pending {
+		if !clientSupportsAsync {
+			err := rollBackPendingProvision(ctx, broker.Logger, serviceHelper, instanceDetails)
+			broker.recordOperation(ctx, instanceID, models.ProvisionOperationType, "", startedAt, false, err)
+			return brokerapi.ProvisionedServiceSpec{}, err
+		}
+		shouldProvisionAsync = true
+		instanceDetails.OperationType = models.ProvisionOperationType
+	}
+
+	if !shouldProvisionAsync {
+		if err := verifyProvision(ctx, broker.Logger, serviceHelper, instanceDetails); err != nil {
+			broker.recordOperation(ctx, instanceID, models.ProvisionOperationType, "", startedAt, false, err)
+			return brokerapi.ProvisionedServiceSpec{}, err
+		}
+	}
 
 	err = db_service.CreateServiceInstanceDetails(ctx, &instanceDetails)
 	if err != nil {
@@ -157,15 +364,32 @@ func (broker *ServiceBroker) Provision(ctx context.Context, instanceID string, d
 	}
 
 	// save provision request details
-	pr := models.ProvisionRequestDetails{
-		ServiceInstanceId: instanceID,
-		RequestDetails:    string(details.RawParameters),
+	pr := models.ProvisionRequestDetails{ServiceInstanceId: instanceID}
+	if err = pr.SetRequestDetails(details.RawParameters); err != nil {
+		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error encrypting provision request details: %s", err)
 	}
 	if err = db_service.CreateProvisionRequestDetails(ctx, &pr); err != nil {
 		return brokerapi.ProvisionedServiceSpec{}, fmt.Errorf("Error saving provision request details to database: %s. Services relying on async provisioning will not be able to complete provisioning", err)
 	}
 
-	return brokerapi.ProvisionedServiceSpec{IsAsync: shouldProvisionAsync, DashboardURL: "", OperationData: instanceDetails.OperationId}, nil
+	broker.recordOperation(ctx, instanceID, models.ProvisionOperationType, instanceDetails.OperationId, startedAt, shouldProvisionAsync, nil)
+
+	if forceSync {
+		done, err := broker.waitForOperation(ctx, serviceHelper, instanceID, models.ProvisionOperationType)
+		switch {
+		case err != nil:
+			return brokerapi.ProvisionedServiceSpec{}, err
+		case done:
+			if provisioned, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID); err == nil {
+				instanceDetails = *provisioned
+			}
+			return brokerapi.ProvisionedServiceSpec{DashboardURL: dashboardURL(broker.Logger, brokerService, *plan, details, instanceDetails)}, nil
+		case !clientSupportsAsync:
+			return brokerapi.ProvisionedServiceSpec{}, errForceSyncTimeout()
+		}
+	}
+
+	return brokerapi.ProvisionedServiceSpec{IsAsync: shouldProvisionAsync, DashboardURL: dashboardURL(broker.Logger, brokerService, *plan, details, instanceDetails), OperationData: instanceDetails.OperationId}, nil
 }
 
 // Deprovision destroys an existing instance of a service.
@@ -178,24 +402,55 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		"details":            details,
 	})
 
-	// make sure that instance actually exists
+	// make sure that instance actually exists, OSB expects 410 Gone if it
+	// doesn't so platforms treat the deprovision as successful
 	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
-	if err != nil {
+	switch {
+	case err == gorm.ErrRecordNotFound:
 		return response, brokerapi.ErrInstanceDoesNotExist
+	case err != nil:
+		return response, fmt.Errorf("Database error checking for existing instance: %s", err)
+	}
+
+	if err := broker.checkDeprovisionPlan(instance, details); err != nil {
+		return response, err
+	}
+
+	if err := checkDeletionProtection(ctx, instance); err != nil {
+		return response, err
+	}
+
+	if err := broker.checkDeprovisionBindings(ctx, instance); err != nil {
+		return response, err
 	}
 
-	_, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId, instance.ProviderAccount)
+	if err != nil {
+		return response, lookupFailure(err, http.StatusNotFound)
+	}
+
+	forceSync, err := checkForceSync(serviceDefinition.Name, serviceProvider, serviceProvider.DeprovisionsAsync())
 	if err != nil {
 		return response, err
 	}
 
 	// if async deprovisioning isn't allowed but this service needs it, throw an error
-	if serviceProvider.DeprovisionsAsync() && !clientSupportsAsync {
+	if serviceProvider.DeprovisionsAsync() && !clientSupportsAsync && !forceSync {
 		return response, brokerapi.ErrAsyncRequired
 	}
 
-	operationId, err := serviceProvider.Deprovision(ctx, *instance, details)
+	ctx, err = deprovisionContext(ctx, serviceDefinition, instance)
+	if err != nil {
+		return response, err
+	}
+	ctx = failedProvisionContext(ctx, broker.Logger, *instance)
+
+	startedAt := time.Now()
+	providerCtx, span := tracing.StartSpan(ctx, "provider.Deprovision")
+	operationId, err := serviceProvider.Deprovision(providerCtx, *instance, details)
+	tracing.End(span, err)
 	if err != nil {
+		broker.recordOperation(ctx, instanceID, models.DeprovisionOperationType, "", startedAt, false, err)
 		return response, err
 	}
 
@@ -206,6 +461,9 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		if err := db_service.DeleteServiceInstanceDetailsById(ctx, instanceID); err != nil {
 			return response, fmt.Errorf("Error deleting instance details from database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup", err)
 		}
+		broker.cleanupBindings(ctx, instanceID)
+
+		broker.recordOperation(ctx, instanceID, models.DeprovisionOperationType, "", startedAt, false, nil)
 		return response, nil
 	} else {
 		response.IsAsync = true
@@ -216,6 +474,21 @@ func (broker *ServiceBroker) Deprovision(ctx context.Context, instanceID string,
 		if err := db_service.SaveServiceInstanceDetails(ctx, instance); err != nil {
 			return response, fmt.Errorf("Error saving instance details to database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup.", err)
 		}
+
+		broker.recordOperation(ctx, instanceID, models.DeprovisionOperationType, *operationId, startedAt, true, nil)
+
+		if forceSync {
+			done, err := broker.waitForOperation(ctx, serviceProvider, instanceID, models.DeprovisionOperationType)
+			switch {
+			case err != nil:
+				return brokerapi.DeprovisionServiceSpec{}, err
+			case done:
+				return brokerapi.DeprovisionServiceSpec{}, nil
+			case !clientSupportsAsync:
+				return brokerapi.DeprovisionServiceSpec{}, errForceSyncTimeout()
+			}
+		}
+
 		return response, nil
 	}
 }
@@ -226,7 +499,7 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 	broker.Logger.Info("Binding", lager.Data{
 		"instance_id": instanceID,
 		"binding_id":  bindingID,
-		"details":     details,
+		"details":     broker.loggableBindDetails(details),
 	})
 
 	// check for existing binding
@@ -244,20 +517,40 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, fmt.Errorf("Error retrieving service instance details: %s", err)
 	}
 
-	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instanceRecord.ServiceId)
+	if err := broker.orgRateLimiter.Allow(ctx, instanceRecord.OrganizationGuid); err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	if err := checkNoOperationInProgress(*instanceRecord); err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instanceRecord.ServiceId, instanceRecord.ProviderAccount)
 	if err != nil {
+		return brokerapi.Binding{}, lookupFailure(err, http.StatusNotFound)
+	}
+
+	if err := checkBindSpace(details, *instanceRecord, serviceDefinition); err != nil {
 		return brokerapi.Binding{}, err
 	}
 
 	// verify the service exists and the plan exists
 	plan, err := serviceDefinition.GetPlanById(details.PlanID)
 	if err != nil {
+		return brokerapi.Binding{}, lookupFailure(err, http.StatusBadRequest)
+	}
+
+	if !plan.IsBindable(serviceDefinition.Bindable) {
+		return brokerapi.Binding{}, errPlanNotBindable(serviceDefinition.Name, plan.Name)
+	}
+
+	if err := checkParametersSize(details.GetRawParameters(), bindParamsMaxBytesProp); err != nil {
 		return brokerapi.Binding{}, err
 	}
 
 	// Give the user a better error message if they give us a bad request
-	if !isValidOrEmptyJSON(details.GetRawParameters()) {
-		return brokerapi.Binding{}, ErrInvalidUserInput
+	if err := validateUserParameters(details.GetRawParameters()); err != nil {
+		return brokerapi.Binding{}, err
 	}
 
 	// validate parameters meet the service's schema and merge the plan's vars with
@@ -267,9 +560,39 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		return brokerapi.Binding{}, err
 	}
 
+	name := bindingName(serviceDefinition, details)
+	if err := checkBindingName(ctx, instanceID, name); err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	credentialKeys, err := serviceDefinition.CredentialKeyMapping()
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	policyManager, err := networkPolicyManager(plan, serviceProvider)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
+	appGUID, appName := bindingApp(details)
+	spaceGUID, organizationGUID := bindingOrigin(details)
+	policyID, createdPolicy, err := ensureNetworkPolicy(ctx, policyManager, *instanceRecord, appGUID)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+
 	// create binding
-	credsDetails, err := serviceProvider.Bind(ctx, vars)
+	providerCtx, span := tracing.StartSpan(ctx, "provider.Bind")
+	credsDetails, err := bindWithRetries(providerCtx, broker.Logger, serviceProvider, vars, bindingID)
+	tracing.End(span, err)
 	if err != nil {
+		if createdPolicy {
+			if err := policyManager.DeleteNetworkPolicy(ctx, *instanceRecord, policyID); err != nil {
+				broker.Logger.Error("deleting-network-policy", err, lager.Data{"instance_id": instanceID, "policy_id": policyID})
+			}
+		}
+		broker.emitEvent(ctx, events.InstanceBound, events.Data{InstanceID: instanceID, BindingID: bindingID, Outcome: events.OutcomeFailed, Error: err.Error()})
 		return brokerapi.Binding{}, err
 	}
 
@@ -284,36 +607,69 @@ func (broker *ServiceBroker) Bind(ctx context.Context, instanceID, bindingID str
 		BindingId:         bindingID,
 		ServiceId:         details.ServiceID,
 		OtherDetails:      string(serializedCreds),
+		Role:              bindRole(serviceDefinition, details, plan),
+		CredentialFormat:  credentialFormat(details),
+		AppGuid:           appGUID,
+		AppName:           appName,
+		NetworkPolicyId:   policyID,
+		SpaceGuid:         spaceGUID,
+		OrganizationGuid:  organizationGUID,
+		Name:              name,
 	}
 
 	if err := db_service.CreateServiceBindingCredentials(ctx, &newCreds); err != nil {
 		return brokerapi.Binding{}, fmt.Errorf("Error saving credentials to database: %s. WARNING: these credentials cannot be unbound through cf. Please contact your operator for cleanup",
 			err)
 	}
+	broker.bindingCache.Invalidate(instanceID, bindingID)
+
+	if err := saveBindRequest(ctx, instanceID, bindingID, details.RawParameters); err != nil {
+		return brokerapi.Binding{}, err
+	}
 
-	binding, err := serviceProvider.BuildInstanceCredentials(ctx, newCreds, *instanceRecord)
+	providerCtx, span = tracing.StartSpan(ctx, "provider.BuildInstanceCredentials")
+	binding, err := serviceProvider.BuildInstanceCredentials(providerCtx, newCreds, *instanceRecord)
+	tracing.End(span, err)
+	if err != nil {
+		return brokerapi.Binding{}, err
+	}
+	binding.Credentials, err = bindingCredentials(binding.Credentials, credentialKeys, newCreds.CredentialFormat)
 	if err != nil {
 		return brokerapi.Binding{}, err
 	}
 
-	if broker.Credstore != nil {
-		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
+	if binding.SyslogDrainURL != "" {
+		if !serviceDefinition.RequiresPermission(brokerapi.PermissionSyslogDrain) {
+			return brokerapi.Binding{}, fmt.Errorf("Bind failure: the binding returned a syslog_drain_url but service %q does not require %q", serviceDefinition.Name, brokerapi.PermissionSyslogDrain)
+		}
 
-		_, err := broker.Credstore.Put(credentialName, binding.Credentials)
-		if err != nil {
-			return brokerapi.Binding{}, fmt.Errorf("Bind failure: unable to put credentials in Credstore: %v", err)
+		newCreds.SyslogDrainURL = binding.SyslogDrainURL
+		if err := db_service.SaveServiceBindingCredentials(ctx, &newCreds); err != nil {
+			return brokerapi.Binding{}, fmt.Errorf("Error saving syslog drain URL to database: %s", err)
 		}
+	}
 
-		_, err = broker.Credstore.AddPermission(credentialName, "mtls-app:"+details.AppGUID, []string{"read"})
-		if err != nil {
-			return brokerapi.Binding{}, fmt.Errorf("Bind failure: Unable to add Credstore permissions to app: %v", err)
+	if binding.RouteServiceURL != "" {
+		if !serviceDefinition.RequiresPermission(brokerapi.PermissionRouteForwarding) {
+			return brokerapi.Binding{}, fmt.Errorf("Bind failure: the binding returned a route_service_url but service %q does not require %q", serviceDefinition.Name, brokerapi.PermissionRouteForwarding)
 		}
 
-		binding.Credentials = map[string]interface{}{
-			"credhub-ref": credentialName,
+		newCreds.RouteServiceURL = binding.RouteServiceURL
+		if err := db_service.SaveServiceBindingCredentials(ctx, &newCreds); err != nil {
+			return brokerapi.Binding{}, fmt.Errorf("Error saving route service URL to database: %s", err)
+		}
+	}
+
+	if broker.Credstore != nil {
+		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
+
+		binding.Credentials, err = broker.putBindingCredentials(ctx, credentialName, appGUID, binding.Credentials)
+		if err != nil {
+			return brokerapi.Binding{}, err
 		}
 	}
 
+	broker.emitEvent(ctx, events.InstanceBound, events.Data{InstanceID: instanceID, BindingID: bindingID, Outcome: events.OutcomeSucceeded})
 	return *binding, nil
 }
 
@@ -328,20 +684,127 @@ func getCredentialName(serviceName, bindingID string) string {
 // GetBinding fetches an existing service binding.
 // GET /v2/service_instances/{instance_id}/service_bindings/{binding_id}
 //
-// NOTE: This functionality is not implemented.
+// The credentials are rebuilt from the stored records the same way Bind built
+// them, including every endpoint of a broker.CredentialSet. If a Credstore is
+// configured, only the reference to the stored credentials is returned. The
+// parameters the binding was created with are returned with sensitive values
+// masked.
 func (broker *ServiceBroker) GetBinding(ctx context.Context, instanceID, bindingID string) (brokerapi.GetBindingSpec, error) {
 	broker.Logger.Info("GetBinding", lager.Data{
 		"instance_id": instanceID,
 		"binding_id":  bindingID,
 	})
 
-	return brokerapi.GetBindingSpec{}, ErrGetBindingsUnsupported
+	bindRecord, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return brokerapi.GetBindingSpec{}, brokerapi.ErrBindingDoesNotExist
+	case err != nil:
+		return brokerapi.GetBindingSpec{}, fmt.Errorf("Database error retrieving the binding: %s", err)
+	}
+
+	instanceRecord, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return brokerapi.GetBindingSpec{}, fmt.Errorf("Error retrieving service instance details: %s", err)
+	}
+
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instanceRecord.ServiceId, instanceRecord.ProviderAccount)
+	if err != nil {
+		return brokerapi.GetBindingSpec{}, lookupFailure(err, http.StatusNotFound)
+	}
+
+	credentialKeys, err := serviceDefinition.CredentialKeyMapping()
+	if err != nil {
+		return brokerapi.GetBindingSpec{}, err
+	}
+
+	binding, cached := broker.bindingCache.Get(*bindRecord, *instanceRecord)
+	if !cached {
+		providerCtx, span := tracing.StartSpan(ctx, "provider.BuildInstanceCredentials")
+		binding, err = serviceProvider.BuildInstanceCredentials(providerCtx, *bindRecord, *instanceRecord)
+		tracing.End(span, err)
+		if err != nil {
+			return brokerapi.GetBindingSpec{}, err
+		}
+		broker.bindingCache.Put(*bindRecord, *instanceRecord, *binding)
+	}
+	binding.Credentials, err = bindingCredentials(binding.Credentials, credentialKeys, bindRecord.CredentialFormat)
+	if err != nil {
+		return brokerapi.GetBindingSpec{}, err
+	}
+
+	if broker.Credstore != nil {
+		binding.Credentials = map[string]interface{}{
+			"credhub-ref": getCredentialName(broker.getServiceName(serviceDefinition), bindingID),
+		}
+	}
+
+	parameters, err := broker.bindingParameters(ctx, instanceRecord.ServiceId, bindingID)
+	if err != nil {
+		return brokerapi.GetBindingSpec{}, err
+	}
+
+	return brokerapi.GetBindingSpec{
+		Credentials:     binding.Credentials,
+		SyslogDrainURL:  bindRecord.SyslogDrainURL,
+		RouteServiceURL: bindRecord.RouteServiceURL,
+		Parameters:      parameters,
+	}, nil
+}
+
+// InstanceBindings returns the bindings of the instance, oldest first, so
+// operators can audit which applications hold credentials for it.
+func (broker *ServiceBroker) InstanceBindings(ctx context.Context, instanceID string) ([]models.ServiceBindingCredentials, error) {
+	exists, err := db_service.ExistsServiceInstanceDetailsById(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrInstanceNotFound
+	}
+
+	return db_service.GetServiceBindingCredentialsByServiceInstanceId(ctx, instanceID)
+}
+
+// InstanceMetadata returns the operator supplied metadata of the instance.
+func (broker *ServiceBroker) InstanceMetadata(ctx context.Context, instanceID string) (map[string]string, error) {
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return nil, ErrInstanceNotFound
+	case err != nil:
+		return nil, err
+	}
+
+	return instance.GetMetadata()
+}
+
+// SetInstanceMetadata replaces the operator supplied metadata of the instance.
+func (broker *ServiceBroker) SetInstanceMetadata(ctx context.Context, instanceID string, metadata map[string]string) error {
+	if err := validateInstanceMetadata(metadata); err != nil {
+		return err
+	}
+
+	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return ErrInstanceNotFound
+	case err != nil:
+		return err
+	}
+
+	if err := instance.SetMetadata(metadata); err != nil {
+		return err
+	}
+
+	return db_service.SaveServiceInstanceDetails(ctx, instance)
 }
 
 // GetInstance fetches information about a service instance
 // GET /v2/service_instances/{instance_id}
 //
-// NOTE: This functionality is not implemented.
+// NOTE: This functionality is not implemented. Once it is, the response should
+// include the instance metadata.
 func (broker *ServiceBroker) GetInstance(ctx context.Context, instanceID string) (brokerapi.GetInstanceDetailsSpec, error) {
 	broker.Logger.Info("GetInstance", lager.Data{
 		"instance_id": instanceID,
@@ -375,15 +838,18 @@ func (broker *ServiceBroker) Unbind(ctx context.Context, instanceID, bindingID s
 		"details":     details,
 	})
 
-	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(details.ServiceID)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(details.ServiceID, "")
 	if err != nil {
-		return brokerapi.UnbindSpec{}, err
+		return brokerapi.UnbindSpec{}, lookupFailure(err, http.StatusBadRequest)
 	}
 
 	// validate existence of binding
 	existingBinding, err := db_service.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, instanceID, bindingID)
-	if err != nil {
-		return brokerapi.UnbindSpec{}, brokerapi.ErrBindingDoesNotExist
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return broker.unbindMissing(serviceDefinition, instanceID, bindingID)
+	case err != nil:
+		return brokerapi.UnbindSpec{}, fmt.Errorf("Database error checking for existing binding: %s", err)
 	}
 
 	// get existing service instance details
@@ -392,13 +858,17 @@ func (broker *ServiceBroker) Unbind(ctx context.Context, instanceID, bindingID s
 		return brokerapi.UnbindSpec{}, fmt.Errorf("Error retrieving service instance details: %s", err)
 	}
 
-	if broker.Credstore != nil {
-		credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
-
-		err = broker.Credstore.DeletePermission(credentialName)
-		if err != nil {
-			broker.Logger.Error(fmt.Sprintf("fail to delete permissions on the key %s", credentialName), err)
+	// the binding's resources live in the account the instance was created in
+	if instance.ProviderAccount != "" {
+		if serviceProvider, err = serviceDefinition.AccountProvider(broker.Logger, instance.ProviderAccount); err != nil {
+			return brokerapi.UnbindSpec{}, err
 		}
+	}
+
+	// credentials still waiting to be written to the Credstore were never stored
+	credentialName := getCredentialName(broker.getServiceName(serviceDefinition), bindingID)
+	if broker.Credstore != nil && !broker.credstoreRetries.cancel(credentialName) {
+		broker.deleteCredstorePermission(broker.Logger, credentialName, *existingBinding)
 
 		err := broker.Credstore.Delete(credentialName)
 		if err != nil {
@@ -407,7 +877,15 @@ func (broker *ServiceBroker) Unbind(ctx context.Context, instanceID, bindingID s
 	}
 
 	// remove binding from service provider
-	if err := serviceProvider.Unbind(ctx, *instance, *existingBinding); err != nil {
+	providerCtx, span := tracing.StartSpan(ctx, "provider.Unbind")
+	err = serviceProvider.Unbind(providerCtx, *instance, *existingBinding)
+	tracing.End(span, err)
+	if err != nil {
+		broker.emitEvent(ctx, events.InstanceUnbound, events.Data{InstanceID: instanceID, BindingID: bindingID, Outcome: events.OutcomeFailed, Error: err.Error()})
+		return brokerapi.UnbindSpec{}, err
+	}
+
+	if err := releaseNetworkPolicy(ctx, broker.Logger, serviceProvider, *instance, *existingBinding); err != nil {
 		return brokerapi.UnbindSpec{}, err
 	}
 
@@ -415,6 +893,9 @@ func (broker *ServiceBroker) Unbind(ctx context.Context, instanceID, bindingID s
 	if err := db_service.DeleteServiceBindingCredentials(ctx, existingBinding); err != nil {
 		return brokerapi.UnbindSpec{}, fmt.Errorf("Error soft-deleting credentials from database: %s. WARNING: these credentials will remain visible in cf. Contact your operator for cleanup", err)
 	}
+	broker.bindingCache.Invalidate(instanceID, bindingID)
+	deleteBindRequest(ctx, broker.Logger, bindingID)
+	broker.emitEvent(ctx, events.InstanceUnbound, events.Data{InstanceID: instanceID, BindingID: bindingID, Outcome: events.OutcomeSucceeded})
 
 	return brokerapi.UnbindSpec{}, nil
 }
@@ -432,45 +913,113 @@ func (broker *ServiceBroker) LastOperation(ctx context.Context, instanceID strin
 
 	instance, err := db_service.GetServiceInstanceDetailsById(ctx, instanceID)
 	if err != nil {
-		return brokerapi.LastOperation{}, brokerapi.ErrInstanceDoesNotExist
+		return brokerapi.LastOperation{}, missingInstanceLastOperationError(err)
 	}
 
-	_, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId)
+	serviceDefinition, serviceProvider, err := broker.getDefinitionAndProvider(instance.ServiceId, instance.ProviderAccount)
 	if err != nil {
-		return brokerapi.LastOperation{}, err
+		return brokerapi.LastOperation{}, lookupFailure(err, http.StatusNotFound)
 	}
 
+	// synchronous services are only polled while finishing a partial provision
 	isAsyncService := serviceProvider.ProvisionsAsync() || serviceProvider.DeprovisionsAsync()
-	if !isAsyncService {
+	if !isAsyncService && instance.OperationType == models.ClearOperationType {
 		return brokerapi.LastOperation{}, brokerapi.ErrAsyncRequired
 	}
 
 	lastOperationType := instance.OperationType
 
-	done, err := serviceProvider.PollInstance(ctx, *instance)
+	key := pollKey(*instance)
+	done, err := broker.pollCache.Poll(ctx, key, func() (bool, error) {
+		providerCtx, span := tracing.StartSpan(broker.progress.reporting(ctx, key), "provider.PollInstance")
+		done, err := serviceProvider.PollInstance(providerCtx, *instance)
+		tracing.End(span, err)
+		return done, err
+	})
 
 	if err != nil {
+		// the request ended before the provider answered, the operation itself
+		// may still be running
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return brokerapi.LastOperation{State: brokerapi.InProgress, Description: "the operation's state couldn't be checked, try again"}, nil
+		}
+
 		// this is a retryable error
 		if gerr, ok := err.(*googleapi.Error); ok {
 			if gerr.Code == 503 {
+				setRetryAfter(ctx, serviceProvider, *instance, serviceDefinition, broker.Logger)
 				return brokerapi.LastOperation{State: brokerapi.InProgress, Description: err.Error()}, nil
 			}
 		}
 
 		// This is not a retryable error. Return fail
+		broker.progress.forget(key)
+		broker.finishOperation(ctx, instanceID, brokerapi.Failed, err.Error())
+		notifyCompletionCallback(broker.Logger, *instance, lastOperationType, brokerapi.Failed, err.Error())
 		return brokerapi.LastOperation{State: brokerapi.Failed, Description: err.Error()}, nil
 	}
 
 	if !done {
-		return brokerapi.LastOperation{State: brokerapi.InProgress}, nil
+		if err := provisionTimedOut(*instance, serviceDefinition); err != nil {
+			broker.progress.forget(key)
+			broker.finishOperation(ctx, instanceID, brokerapi.Failed, err.Error())
+			notifyCompletionCallback(broker.Logger, *instance, lastOperationType, brokerapi.Failed, err.Error())
+			return brokerapi.LastOperation{State: brokerapi.Failed, Description: err.Error()}, nil
+		}
+
+		setRetryAfter(ctx, serviceProvider, *instance, serviceDefinition, broker.Logger)
+		description := joinDescriptions(broker.progress.describe(key), describeOperation(ctx, serviceProvider, *instance, broker.Logger))
+		return brokerapi.LastOperation{State: brokerapi.InProgress, Description: description}, nil
 	}
+	broker.progress.forget(key)
 
 	// the instance may have been invalidated, so we pass its primary key rather than the
 	// instance directly.
 	updateErr := broker.updateStateOnOperationCompletion(ctx, serviceProvider, lastOperationType, instanceID)
+	switch updateErr.(type) {
+	case nil:
+		broker.finishOperation(ctx, instanceID, brokerapi.Succeeded, "")
+		notifyCompletionCallback(broker.Logger, *instance, lastOperationType, brokerapi.Succeeded, "")
+	case *readinessPendingError:
+		return brokerapi.LastOperation{State: brokerapi.InProgress, Description: updateErr.Error()}, nil
+	case *readinessFailedError:
+		broker.finishOperation(ctx, instanceID, brokerapi.Failed, updateErr.Error())
+		notifyCompletionCallback(broker.Logger, *instance, lastOperationType, brokerapi.Failed, updateErr.Error())
+		return brokerapi.LastOperation{State: brokerapi.Failed, Description: updateErr.Error()}, nil
+	}
 	return brokerapi.LastOperation{State: brokerapi.Succeeded}, updateErr
 }
 
+// describeOperation returns the provider's description of the progress of the
+// instance's running operation, if it can describe it.
+func describeOperation(ctx context.Context, serviceProvider broker.ServiceProvider, instance models.ServiceInstanceDetails, logger lager.Logger) string {
+	describer, ok := serviceProvider.(broker.OperationDescriber)
+	if !ok {
+		return ""
+	}
+
+	description, err := describer.DescribeOperation(ctx, instance)
+	if err != nil {
+		logger.Error("describing-operation", err, lager.Data{"instance_id": instance.ID})
+		return ""
+	}
+
+	return description
+}
+
+// missingInstanceLastOperationError converts the error from looking up the
+// instance being polled into the OSB response. Instances the broker has no
+// record of are reported as gone, however long ago they were deleted, so the
+// platform treats a delete as successful if it polls after the record was
+// removed, e.g. because the deprovision was completed in the background.
+func missingInstanceLastOperationError(lookupErr error) error {
+	if lookupErr != gorm.ErrRecordNotFound {
+		return fmt.Errorf("Error getting instance details from database: %s", lookupErr)
+	}
+
+	return brokerapi.ErrInstanceDoesNotExist
+}
+
 // updateStateOnOperationCompletion handles updating/cleaning-up resources that need to be changed
 // once lastOperation finishes successfully.
 func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Context, service broker.ServiceProvider, lastOperationType, instanceID string) error {
@@ -478,6 +1027,7 @@ func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Contex
 		if err := db_service.DeleteServiceInstanceDetailsById(ctx, instanceID); err != nil {
 			return fmt.Errorf("Error deleting instance details from database: %s. WARNING: this instance will remain visible in cf. Contact your operator for cleanup", err)
 		}
+		broker.cleanupBindings(ctx, instanceID)
 
 		return nil
 	}
@@ -489,16 +1039,26 @@ func (broker *ServiceBroker) updateStateOnOperationCompletion(ctx context.Contex
 		return fmt.Errorf("Error getting instance details from database %v", err)
 	}
 
-	if err := service.UpdateInstanceDetails(ctx, details); err != nil {
+	if err := finalizeInstanceDetails(ctx, service, details); err != nil {
 		return fmt.Errorf("Error getting new instance details from GCP: %v", err)
 	}
 
+	if lastOperationType == models.ProvisionOperationType {
+		if err := broker.checkReadiness(ctx, *details); err != nil {
+			return err
+		}
+	}
+
 	details.OperationId = ""
 	details.OperationType = models.ClearOperationType
 	if err := db_service.SaveServiceInstanceDetails(ctx, details); err != nil {
 		return fmt.Errorf("Error saving instance details to database %v", err)
 	}
 
+	if lastOperationType == models.UpdateOperationType {
+		broker.refreshBindings(ctx, instanceID)
+	}
+
 	return nil
 }
 
@@ -508,7 +1068,7 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 	broker.Logger.Info("Updating", lager.Data{
 		"instance_id":        instanceID,
 		"accepts_incomplete": asyncAllowed,
-		"details":            details,
+		"details":            broker.loggableUpdateDetails(details),
 	})
 
 	// make sure that instance actually exists
@@ -517,76 +1077,288 @@ func (broker *ServiceBroker) Update(ctx context.Context, instanceID string, deta
 		return response, brokerapi.ErrInstanceDoesNotExist
 	}
 
-	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(instance.ServiceId)
-	if err != nil {
+	if err := broker.orgRateLimiter.Allow(ctx, instance.OrganizationGuid); err != nil {
 		return response, err
 	}
 
+	brokerService, serviceHelper, err := broker.getDefinitionAndProvider(instance.ServiceId, instance.ProviderAccount)
+	if err != nil {
+		return response, lookupFailure(err, http.StatusNotFound)
+	}
+
 	// verify the service exists and the plan exists
+	previousPlanID := currentPlanID(broker.Logger, *instance, details)
+	details.PlanID = targetPlanID(previousPlanID, details)
 	plan, err := brokerService.GetPlanById(details.PlanID)
+	if err != nil {
+		return response, lookupFailure(err, http.StatusBadRequest)
+	}
+
+	if err := checkMaintenanceInfo(plan, details); err != nil {
+		return response, err
+	}
+
+	upgrade, err := isUpgrade(*instance, previousPlanID, details)
 	if err != nil {
 		return response, err
 	}
 
+	if plan.ID != previousPlanID {
+		broker.Logger.Info("update-changes-plan", lager.Data{
+			"instance_id": instanceID,
+			"from_plan":   previousPlanID,
+			"to_plan":     plan.ID,
+		})
+	}
+
 	// verify async provisioning is allowed if it is required
 	shouldProvisionAsync := serviceHelper.ProvisionsAsync()
 	if shouldProvisionAsync && !asyncAllowed {
 		return response, brokerapi.ErrAsyncRequired
 	}
 
+	if err := checkParametersSize(details.GetRawParameters(), updateParamsMaxBytesProp); err != nil {
+		return response, err
+	}
+
 	// Give the user a better error message if they give us a bad request
-	if !isValidOrEmptyJSON(details.GetRawParameters()) {
-		return response, ErrInvalidUserInput
+	if err := validateUserParameters(details.GetRawParameters()); err != nil {
+		return response, err
 	}
 
-	allowUpdate, err := brokerService.AllowedUpdate(details); 
+	// values are validated and stored in their canonical form
+	if details.RawParameters, err = brokerService.NormalizeParameters(details.GetRawParameters()); err != nil {
+		return response, err
+	}
 
+	callback, err := completionCallback(details.GetRawParameters())
 	if err != nil {
 		return response, err
 	}
 
-	if !allowUpdate {
-		return response, ErrNonUpdatableParameter
+	// upgrades don't change parameters so none can be prohibited
+	if upgrade {
+		broker.Logger.Info("update-upgrades-instance", lager.Data{
+			"instance_id":      instanceID,
+			"maintenance_info": details.MaintenanceInfo,
+		})
+		ctx = upgradeContext(ctx)
+	} else {
+		classification, err := brokerService.ClassifyUpdate(details)
+		if err != nil {
+			return response, err
+		}
+
+		if len(classification.Prohibited) > 0 {
+			return response, ErrNonUpdatableParameter
+		}
+
+		if len(classification.Recreate) > 0 {
+			broker.Logger.Info("update-recreates-resources", lager.Data{
+				"instance_id": instanceID,
+				"parameters":  classification.Recreate,
+			})
+		}
+	}
+
+	// forced after classifying so forcing a value that can't be updated doesn't
+	// prohibit the update
+	if details.RawParameters, err = forceParameters(broker.Logger, brokerService, *plan, instanceID, details.GetRawParameters()); err != nil {
+		return response, err
 	}
-	
+
 	// validate parameters meet the service's schema and merge the user vars with
 	// the plan's
-	vars, err := brokerService.UpdateVariables(instanceID, details, *plan)
+	vars, err := brokerService.UpdateVariables(*instance, details, *plan, broker.Credstore)
+	if err != nil {
+		return response, err
+	}
+
+	if err := validateUpdate(ctx, serviceHelper, *instance, vars); err != nil {
+		return response, err
+	}
+	warnDeprecatedParameters(ctx, broker.Logger, brokerService, instanceID, details.GetRawParameters())
+
+	release, err := broker.serviceConcurrency.Acquire(ctx, brokerService.Id)
 	if err != nil {
 		return response, err
 	}
 
 	// get instance details
-	newInstanceDetails, err := serviceHelper.Update(ctx, vars)
+	startedAt := time.Now()
+	providerCtx, span := tracing.StartSpan(ctx, "provider.Update")
+	newInstanceDetails, err := serviceHelper.Update(providerCtx, vars)
+	tracing.End(span, err)
+	broker.serviceConcurrency.ReleaseAfter(instanceID, release, err == nil && shouldProvisionAsync)
 	if err != nil {
+		broker.recordOperation(ctx, instanceID, models.UpdateOperationType, "", startedAt, false, err)
 		return brokerapi.UpdateServiceSpec{}, err
 	}
 
 	// save instance details
 
-	instance.PlanId = newInstanceDetails.PlanId
+	instance.PlanId = plan.ID
+	if newInstanceDetails.PlanId != "" {
+		instance.PlanId = newInstanceDetails.PlanId
+	}
+	if metadata := instanceMetadata(details.GetRawParameters()); metadata != nil {
+		logRemovedInstanceMetadata(broker.Logger, *instance, metadata)
+		if err := instance.SetMetadata(metadata); err != nil {
+			return brokerapi.UpdateServiceSpec{}, err
+		}
+	}
+	if enabled := deletionProtection(details.GetRawParameters()); enabled != nil {
+		instance.DeletionProtection = *enabled
+	}
+	if callback != "" {
+		instance.CompletionCallback = callback
+	}
+	if name := contextInstanceName(details.RawContext); name != "" {
+		instance.InstanceName = name
+	}
+	if err := instance.SetGeneratedParameters(brokerService.GeneratedParameters(vars)); err != nil {
+		return brokerapi.UpdateServiceSpec{}, err
+	}
+	if err := setMaintenanceInfo(instance, plan); err != nil {
+		return brokerapi.UpdateServiceSpec{}, err
+	}
 
 	err = db_service.SaveServiceInstanceDetails(ctx, instance)
 	if err != nil {
 		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error saving instance details to database: %s. WARNING: this instance cannot be deprovisioned through cf. Contact your operator for cleanup", err)
 	}
+	broker.bindingCache.InvalidateInstance(instanceID)
 
 	// save provision request details
-	pr := models.ProvisionRequestDetails{
-		ServiceInstanceId: instanceID,
-		RequestDetails:    string(details.RawParameters),
+	pr := models.ProvisionRequestDetails{ServiceInstanceId: instanceID}
+	if err = pr.SetRequestDetails(details.RawParameters); err != nil {
+		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error encrypting provision request details: %s", err)
 	}
 	if err = db_service.SaveProvisionRequestDetails(ctx, &pr); err != nil {
 		return brokerapi.UpdateServiceSpec{}, fmt.Errorf("Error saving provision request details to database: %s. Services relying on async provisioning will not be able to complete provisioning", err)
 	}
 
+	broker.recordOperation(ctx, instanceID, models.UpdateOperationType, newInstanceDetails.OperationId, startedAt, shouldProvisionAsync, nil)
+
+	if !shouldProvisionAsync {
+		broker.refreshBindings(ctx, instanceID)
+	}
+
+	dashboardInstance := *instance
+	if newInstanceDetails.OtherDetails != "" {
+		dashboardInstance.OtherDetails = newInstanceDetails.OtherDetails
+	}
+
 	response.IsAsync = shouldProvisionAsync
-	response.DashboardURL = ""
+	response.DashboardURL = dashboardURL(broker.Logger, brokerService, *plan, updateDashboardDetails(*instance, details), dashboardInstance)
 	response.OperationData = newInstanceDetails.OperationId
 
 	return response, nil
 }
 
-func isValidOrEmptyJSON(msg json.RawMessage) bool {
-	return msg == nil || len(msg) == 0 || json.Valid(msg)
+// renderProvisionParameters renders the parameter templates of an already
+// validated provision request.
+func renderProvisionParameters(instanceID string, details brokerapi.ProvisionDetails) brokerapi.ProvisionDetails {
+	details.RawParameters, _ = broker.RenderParameterTemplates(details.GetRawParameters(), broker.ProvisionTemplateVariables(instanceID, details))
+	return details
+}
+
+// resourcePrefix returns the resource prefix of an already validated provision
+// request.
+func resourcePrefix(details brokerapi.ProvisionDetails) string {
+	prefix, _ := broker.ResourcePrefix(details.GetRawParameters())
+	return prefix
+}
+
+// instanceMetadata returns the instance metadata of already validated request
+// parameters, nil if the request doesn't set it.
+func instanceMetadata(rawParameters json.RawMessage) map[string]string {
+	metadata, _ := broker.InstanceMetadata(rawParameters)
+	return metadata
+}
+
+// logRemovedInstanceMetadata logs the metadata keys an update drops from the
+// instance.
+func logRemovedInstanceMetadata(logger lager.Logger, instance models.ServiceInstanceDetails, metadata map[string]string) {
+	previous, err := instance.GetMetadata()
+	if err != nil {
+		return
+	}
+
+	if removed := broker.RemovedInstanceMetadata(previous, metadata); len(removed) > 0 {
+		logger.Info("update-removes-instance-metadata", lager.Data{
+			"instance_id": instance.ID,
+			"keys":        removed,
+		})
+	}
+}
+
+// validateInstanceMetadata checks metadata supplied by an operator.
+func validateInstanceMetadata(metadata map[string]string) error {
+	return broker.ValidateInstanceMetadata(metadata)
+}
+
+// instanceNetwork returns the network of an already validated provision
+// request.
+func instanceNetwork(details brokerapi.ProvisionDetails, plan broker.ServicePlan) broker.InstanceNetwork {
+	network, _ := broker.ResolveNetwork(details.GetRawParameters(), plan)
+	return network
+}
+
+// instanceAvailabilityZones returns the zones of an already validated
+// provision request.
+func instanceAvailabilityZones(details brokerapi.ProvisionDetails, plan broker.ServicePlan) []string {
+	zones, _ := broker.ResolveAvailabilityZones(details.GetRawParameters(), plan)
+	return zones
+}
+
+// bindingCredentials renames the credential keys of a binding as configured by
+// the operator and converts them to the binding's credential format.
+func bindingCredentials(credentials interface{}, mapping map[string]string, format string) (interface{}, error) {
+	return broker.FormatCredentials(broker.MapCredentialKeys(credentials, mapping), format)
+}
+
+// credentialFormat returns the credential format of an already validated bind
+// request, empty for the default.
+func credentialFormat(details brokerapi.BindDetails) string {
+	format, _ := broker.CredentialFormat(details.GetRawParameters())
+	if format == broker.CredentialFormatJSON {
+		return ""
+	}
+	return format
+}
+
+// bindingApp returns the GUID and name of the application a bind request is
+// for. Both are empty for service keys, which aren't bound to an app.
+func bindingApp(details brokerapi.BindDetails) (appGUID, appName string) {
+	appGUID = details.AppGUID
+	if details.BindResource != nil && details.BindResource.AppGuid != "" {
+		appGUID = details.BindResource.AppGuid
+	}
+
+	var bindContext struct {
+		AppName string `json:"app_name"`
+	}
+	json.Unmarshal(details.GetRawContext(), &bindContext) // explicitly ignore parse errors
+
+	return appGUID, bindContext.AppName
+}
+
+// bindingName returns the name of an already validated bind request, if the
+// user supplied one.
+func bindingName(service *broker.ServiceDefinition, details brokerapi.BindDetails) string {
+	name, _ := service.BindingName(details.GetRawParameters())
+	return name
+}
+
+// bindRole returns the role of an already validated bind request, if the plan
+// declares roles.
+func bindRole(service *broker.ServiceDefinition, details brokerapi.BindDetails, plan *broker.ServicePlan) string {
+	if len(plan.Roles) == 0 {
+		return ""
+	}
+
+	params, _ := service.BindParameters(details.GetRawParameters())
+	role, _ := broker.BindRole(params)
+	return role
 }
@@ -0,0 +1,140 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	serviceConcurrencyLimitProp         = "request.service_concurrency.limit"
+	serviceConcurrencyOverridesProp     = "request.service_concurrency.services"
+	serviceConcurrencyQueueTimeoutProp  = "request.service_concurrency.queue_timeout"
+	serviceConcurrencyHoldUntilDoneProp = "request.service_concurrency.hold_until_complete"
+)
+
+func init() {
+	viper.BindEnv(serviceConcurrencyLimitProp, "SERVICE_CONCURRENCY_LIMIT")
+	viper.SetDefault(serviceConcurrencyLimitProp, 0)
+
+	viper.BindEnv(serviceConcurrencyOverridesProp, "SERVICE_CONCURRENCY_LIMITS")
+
+	viper.BindEnv(serviceConcurrencyQueueTimeoutProp, "SERVICE_CONCURRENCY_QUEUE_TIMEOUT")
+	viper.SetDefault(serviceConcurrencyQueueTimeoutProp, "10s")
+
+	viper.BindEnv(serviceConcurrencyHoldUntilDoneProp, "SERVICE_CONCURRENCY_HOLD_UNTIL_COMPLETE")
+	viper.SetDefault(serviceConcurrencyHoldUntilDoneProp, false)
+}
+
+// serviceConcurrencyLimitFor returns the number of provisions and updates of
+// the service allowed to run at once. The global limit applies to services
+// without an override.
+func serviceConcurrencyLimitFor(serviceID string) int {
+	overrides := viper.GetStringMap(serviceConcurrencyOverridesProp)
+	if v, ok := overrides[serviceID]; ok {
+		return cast.ToInt(v)
+	}
+
+	return viper.GetInt(serviceConcurrencyLimitProp)
+}
+
+// serviceConcurrencyLimiter caps the provisions and updates of each service
+// running at once so a burst of requests doesn't overwhelm its cloud API.
+type serviceConcurrencyLimiter struct {
+	mu         sync.Mutex
+	semaphores map[string]chan struct{}
+	held       map[string]func()
+}
+
+func newServiceConcurrencyLimiter() *serviceConcurrencyLimiter {
+	return &serviceConcurrencyLimiter{
+		semaphores: make(map[string]chan struct{}),
+		held:       make(map[string]func()),
+	}
+}
+
+// Acquire takes a slot of the service, waiting up to the queue timeout for
+// one to free up, and returns the function releasing it. It returns a 429
+// error if the service stays saturated. A limit of 0 or less disables it.
+func (l *serviceConcurrencyLimiter) Acquire(ctx context.Context, serviceID string) (func(), error) {
+	limit := serviceConcurrencyLimitFor(serviceID)
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	semaphore, ok := l.semaphores[serviceID]
+	if !ok || cap(semaphore) != limit {
+		// requests holding a slot of a previous limit release it there
+		semaphore = make(chan struct{}, limit)
+		l.semaphores[serviceID] = semaphore
+	}
+	l.mu.Unlock()
+
+	timer := time.NewTimer(viper.GetDuration(serviceConcurrencyQueueTimeoutProp))
+	defer timer.Stop()
+
+	select {
+	case semaphore <- struct{}{}:
+		var once sync.Once
+		return func() { once.Do(func() { <-semaphore }) }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, brokerapi.NewFailureResponse(
+			fmt.Errorf("too many operations of service %q are in progress, retry later", serviceID),
+			http.StatusTooManyRequests,
+			"concurrency-limited",
+		)
+	}
+}
+
+// ReleaseAfter releases the slot once the provider call returned. If
+// configured, slots of asynchronous operations are instead held until Finish
+// is called for the instance.
+func (l *serviceConcurrencyLimiter) ReleaseAfter(instanceID string, release func(), async bool) {
+	if !async || !viper.GetBool(serviceConcurrencyHoldUntilDoneProp) {
+		release()
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if previous, ok := l.held[instanceID]; ok {
+		previous()
+	}
+	l.held[instanceID] = release
+}
+
+// Finish releases the slot held by the instance's asynchronous operation, if
+// any.
+func (l *serviceConcurrencyLimiter) Finish(instanceID string) {
+	l.mu.Lock()
+	release, ok := l.held[instanceID]
+	delete(l.held, instanceID)
+	l.mu.Unlock()
+
+	if ok {
+		release()
+	}
+}
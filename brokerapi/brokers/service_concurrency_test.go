@@ -0,0 +1,103 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceConcurrencyLimiter_Acquire(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(serviceConcurrencyLimitProp, 1)
+	viper.Set(serviceConcurrencyOverridesProp, `{"big-service": 2, "unlimited-service": 0}`)
+	viper.Set(serviceConcurrencyQueueTimeoutProp, "10ms")
+
+	limiter := newServiceConcurrencyLimiter()
+	acquired := func(service string, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if _, err := limiter.Acquire(context.Background(), service); err == nil {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := acquired("small-service", 3); got != 1 {
+		t.Errorf("expected the global limit of 1 to be acquired, got %d", got)
+	}
+
+	if got := acquired("big-service", 3); got != 2 {
+		t.Errorf("expected the override limit of 2 to be acquired, got %d", got)
+	}
+
+	if got := acquired("unlimited-service", 10); got != 10 {
+		t.Errorf("expected a limit of 0 to disable the limit, got %d", got)
+	}
+
+	_, err := limiter.Acquire(context.Background(), "small-service")
+	failure, ok := err.(*brokerapi.FailureResponse)
+	if !ok {
+		t.Fatalf("expected a failure response, got %v", err)
+	}
+	if code := failure.ValidatedStatusCode(nil); code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+}
+
+func TestServiceConcurrencyLimiter_ReleaseAfter(t *testing.T) {
+	cases := map[string]struct {
+		Async         bool
+		HoldUntilDone bool
+		ExpectHeld    bool
+	}{
+		"sync":                        {Async: false, HoldUntilDone: true, ExpectHeld: false},
+		"async":                       {Async: true, HoldUntilDone: false, ExpectHeld: false},
+		"async held until completion": {Async: true, HoldUntilDone: true, ExpectHeld: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set(serviceConcurrencyLimitProp, 1)
+			viper.Set(serviceConcurrencyQueueTimeoutProp, "10ms")
+			viper.Set(serviceConcurrencyHoldUntilDoneProp, tc.HoldUntilDone)
+
+			limiter := newServiceConcurrencyLimiter()
+			release, err := limiter.Acquire(context.Background(), "service")
+			if err != nil {
+				t.Fatal(err)
+			}
+			limiter.ReleaseAfter("instance", release, tc.Async)
+
+			_, err = limiter.Acquire(context.Background(), "service")
+			if held := err != nil; held != tc.ExpectHeld {
+				t.Fatalf("expected the slot to be held: %t, got error: %v", tc.ExpectHeld, err)
+			}
+
+			if tc.ExpectHeld {
+				limiter.Finish("instance")
+				if _, err := limiter.Acquire(context.Background(), "service"); err != nil {
+					t.Errorf("expected the slot to be released when the operation finished, got %v", err)
+				}
+			}
+		})
+	}
+}
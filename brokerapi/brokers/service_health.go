@@ -0,0 +1,136 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/spf13/viper"
+)
+
+const (
+	serviceHealthIntervalProp = "api.service_health_interval"
+
+	// serviceHealthTimeout bounds how long a provider may take to check its
+	// backend, so a hanging cloud API doesn't stall the readiness endpoint.
+	serviceHealthTimeout = 5 * time.Second
+)
+
+func init() {
+	viper.BindEnv(serviceHealthIntervalProp, "SERVICE_HEALTH_INTERVAL")
+	viper.SetDefault(serviceHealthIntervalProp, "30s")
+}
+
+type healthResult struct {
+	err     error
+	checked time.Time
+}
+
+// serviceHealth runs the health checks of providers implementing
+// broker.HealthChecker at most once per configured interval, and keeps the
+// last result of each service so provisions can be rejected while its
+// backend is unhealthy.
+type serviceHealth struct {
+	mu      sync.Mutex
+	results map[string]healthResult
+	running map[string]bool
+}
+
+func newServiceHealth() *serviceHealth {
+	return &serviceHealth{results: make(map[string]healthResult), running: make(map[string]bool)}
+}
+
+// CheckAll checks the services whose provider implements
+// broker.HealthChecker and returns the results by service name.
+func (h *serviceHealth) CheckAll(ctx context.Context, logger lager.Logger, services []*broker.ServiceDefinition) map[string]error {
+	results := make(map[string]error)
+	for _, service := range services {
+		if _, ok := service.ProviderBuilder(logger).(broker.HealthChecker); ok {
+			results[service.Name] = h.Check(ctx, logger, service)
+		}
+	}
+
+	return results
+}
+
+// Check returns the health of the service's backend, checking it again if
+// the last result is older than the interval. Services whose provider
+// doesn't check its backend are reported as healthy. While a check is
+// running, other callers get the previous result.
+func (h *serviceHealth) Check(ctx context.Context, logger lager.Logger, service *broker.ServiceDefinition) error {
+	checker, ok := service.ProviderBuilder(logger).(broker.HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	h.mu.Lock()
+	last := h.results[service.Id]
+	if h.running[service.Id] || time.Since(last.checked) < viper.GetDuration(serviceHealthIntervalProp) {
+		h.mu.Unlock()
+		return last.err
+	}
+	h.running[service.Id] = true
+	h.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, serviceHealthTimeout)
+	defer cancel()
+
+	err := checker.HealthCheck(ctx)
+	if err != nil {
+		logger.Error("service-health-check", err, lager.Data{"service": service.Name})
+	} else if last.err != nil {
+		logger.Info("service-health-recovered", lager.Data{"service": service.Name})
+	}
+
+	h.mu.Lock()
+	h.results[service.Id] = healthResult{err: err, checked: time.Now()}
+	delete(h.running, service.Id)
+	h.mu.Unlock()
+
+	return err
+}
+
+// ServiceHealth checks the backend of each enabled service whose provider
+// implements broker.HealthChecker and returns the results by service name.
+func (broker *ServiceBroker) ServiceHealth(ctx context.Context) map[string]error {
+	enabledServices, err := broker.registry.GetEnabledServices()
+	if err != nil {
+		broker.Logger.Error("service-health", err)
+		return map[string]error{}
+	}
+
+	return broker.health.CheckAll(ctx, broker.Logger, enabledServices)
+}
+
+// checkServiceHealth rejects provisions of a service whose backend is
+// unhealthy. Like the readiness endpoint, it reuses recent check results.
+func (broker *ServiceBroker) checkServiceHealth(ctx context.Context, service *broker.ServiceDefinition) error {
+	if err := broker.health.Check(ctx, broker.Logger, service); err != nil {
+		return brokerapi.NewFailureResponse(
+			fmt.Errorf("the backend of service %q is unavailable, try again later: %v", service.Name, err),
+			http.StatusServiceUnavailable,
+			"service-unavailable",
+		)
+	}
+
+	return nil
+}
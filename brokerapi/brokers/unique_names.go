@@ -0,0 +1,73 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/spf13/viper"
+)
+
+const uniqueInstanceNamesProp = "request.unique_instance_names"
+
+func init() {
+	viper.BindEnv(uniqueInstanceNamesProp, "UNIQUE_INSTANCE_NAMES")
+	viper.SetDefault(uniqueInstanceNamesProp, false)
+}
+
+// checkUniqueInstanceName rejects provisioning an instance with the name of
+// another instance in the same space, if enabled. Requests that don't say
+// which name or space the instance gets are allowed.
+func checkUniqueInstanceName(ctx context.Context, details brokerapi.ProvisionDetails) error {
+	if !viper.GetBool(uniqueInstanceNamesProp) {
+		return nil
+	}
+
+	name := contextInstanceName(details.GetRawContext())
+	if name == "" || details.SpaceGUID == "" {
+		return nil
+	}
+
+	exists, err := db_service.ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx, details.SpaceGUID, name)
+	if err != nil {
+		return fmt.Errorf("Database error checking for instances named %q: %s", name, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	return brokerapi.NewFailureResponse(
+		fmt.Errorf("an instance named %q already exists in space %q", name, details.SpaceGUID),
+		http.StatusConflict,
+		"instance-name-taken",
+	)
+}
+
+// contextInstanceName returns the name the user gave the instance in the
+// context of a provision or update request. The platform sends the current
+// name on every update, so renames are picked up.
+func contextInstanceName(rawContext json.RawMessage) string {
+	var requestContext struct {
+		InstanceName string `json:"instance_name"`
+	}
+	json.Unmarshal(rawContext, &requestContext) // explicitly ignore parse errors
+
+	return requestContext.InstanceName
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+// validateUpdate asks providers implementing broker.UpdateValidator whether
+// the update is compatible with the instance's current state. Rejections
+// are returned as 422 Unprocessable Entity unless the provider already chose
+// a response.
+func validateUpdate(ctx context.Context, provider broker.ServiceProvider, instance models.ServiceInstanceDetails, vars *varcontext.VarContext) error {
+	validator, ok := provider.(broker.UpdateValidator)
+	if !ok {
+		return nil
+	}
+
+	err := validator.ValidateUpdate(ctx, instance, vars)
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := err.(*brokerapi.FailureResponse); ok {
+		return err
+	}
+
+	return brokerapi.NewFailureResponse(err, http.StatusUnprocessableEntity, "update-incompatible")
+}
@@ -0,0 +1,61 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// validateUserParameters checks the user supplied parameters are either
+// absent or a JSON object, so users get a better error message than the
+// variable parsing would give them.
+func validateUserParameters(msg json.RawMessage) error {
+	if len(msg) == 0 {
+		return nil
+	}
+
+	if !json.Valid(msg) {
+		return ErrInvalidUserInput
+	}
+
+	if kind := jsonKind(msg); kind != "an object" {
+		return brokerapi.NewFailureResponse(fmt.Errorf("User supplied parameters must be a JSON object, got %s.", kind), http.StatusBadRequest, "parsing-user-request")
+	}
+
+	return nil
+}
+
+// jsonKind describes the type of a valid JSON value.
+func jsonKind(msg json.RawMessage) string {
+	switch bytes.TrimSpace(msg)[0] {
+	case '{':
+		return "an object"
+	case '[':
+		return "an array"
+	case '"':
+		return "a string"
+	case 'n':
+		return "null"
+	case 't', 'f':
+		return "a boolean"
+	default:
+		return "a number"
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package brokers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestValidateUserParameters(t *testing.T) {
+	cases := map[string]struct {
+		Raw             json.RawMessage
+		ExpectedMessage string
+	}{
+		"nil":             {Raw: nil},
+		"empty":           {Raw: json.RawMessage(``)},
+		"object":          {Raw: json.RawMessage(`{"name":"db"}`)},
+		"padded object":   {Raw: json.RawMessage(" \n{}\t")},
+		"invalid json":    {Raw: json.RawMessage(`{invalid json`), ExpectedMessage: invalidUserInputMsg},
+		"whitespace only": {Raw: json.RawMessage(" \n\t"), ExpectedMessage: invalidUserInputMsg},
+		"null":            {Raw: json.RawMessage(`null`), ExpectedMessage: "User supplied parameters must be a JSON object, got null."},
+		"array":           {Raw: json.RawMessage(`[]`), ExpectedMessage: "User supplied parameters must be a JSON object, got an array."},
+		"number":          {Raw: json.RawMessage(`123`), ExpectedMessage: "User supplied parameters must be a JSON object, got a number."},
+		"string":          {Raw: json.RawMessage(`"db"`), ExpectedMessage: "User supplied parameters must be a JSON object, got a string."},
+		"padded boolean":  {Raw: json.RawMessage(` true`), ExpectedMessage: "User supplied parameters must be a JSON object, got a boolean."},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := validateUserParameters(tc.Raw)
+			if tc.ExpectedMessage == "" {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			failure, ok := err.(*brokerapi.FailureResponse)
+			if !ok {
+				t.Fatalf("expected a failure response, got %v", err)
+			}
+
+			if failure.Error() != tc.ExpectedMessage {
+				t.Errorf("expected message %q, got %q", tc.ExpectedMessage, failure.Error())
+			}
+
+			if code := failure.ValidatedStatusCode(nil); code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, code)
+			}
+		})
+	}
+}
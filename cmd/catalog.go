@@ -0,0 +1,80 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+// catalogValidationSummary is the machine-readable output of catalog validate.
+type catalogValidationSummary struct {
+	Valid    bool                    `json:"valid"`
+	Services int                     `json:"services"`
+	Problems []broker.CatalogProblem `json:"problems"`
+}
+
+func init() {
+	catalogCmd := &cobra.Command{
+		Use:   "catalog",
+		Short: "Inspect the service catalog",
+		Long: `Inspect the service catalog the broker would serve using the
+brokerpaks and configuration of the current environment.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+	rootCmd.AddCommand(catalogCmd)
+
+	catalogCmd.AddCommand(&cobra.Command{
+		Use:   "validate",
+		Short: "Validate the service catalog",
+		Long: `Loads the brokerpaks the same way the broker does on startup and checks
+every service's catalog entry for schema errors, violations of the OSB catalog
+JSON Schema, duplicate IDs, missing required fields and non-free plans without
+costs.
+
+A JSON summary is printed to stdout and the command exits with a non-zero
+status if any problem was found, so it can be run in CI without a live broker.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("catalog-validate")
+			cfg, err := brokers.NewBrokerConfigFromEnv(logger)
+			if err != nil {
+				log.Fatalf("Error loading catalog: %v", err)
+			}
+
+			problems := cfg.Registry.ValidateCatalog()
+			if problems == nil {
+				problems = []broker.CatalogProblem{}
+			}
+
+			utils.PrettyPrintOrExit(catalogValidationSummary{
+				Valid:    len(problems) == 0,
+				Services: len(cfg.Registry),
+				Problems: problems,
+			})
+
+			if len(problems) > 0 {
+				os.Exit(1)
+			}
+		},
+	})
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "reencrypt",
+		Short: "Re-encrypt instance parameters with the active encryption key",
+		Long: `Rewrites the instance parameters stored in the database that aren't encrypted
+with the key named by DB_ENCRYPTION_ACTIVE_KEY, including those stored before
+encryption was enabled.
+
+To rotate the key, add the new key to DB_ENCRYPTION_KEYS, make it the active
+key and restart the brokers, then run this command. The brokers keep serving
+requests meanwhile. Once it finishes, the old key can be removed from
+DB_ENCRYPTION_KEYS.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("reencrypt")
+			db_service.New(logger)
+
+			result, err := db_service.ReencryptParameters(context.Background())
+			if err != nil {
+				log.Fatalf("Error re-encrypting instance parameters: %v", err)
+			}
+
+			log.Printf("Re-encrypted %d instances, %d provision requests and %d bind requests", result.Instances, result.ProvisionRequests, result.BindRequests)
+		},
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+)
+
+// idMigrationSummary is the machine-readable output of migrate-ids.
+type idMigrationSummary struct {
+	DryRun  bool                           `json:"dry_run"`
+	Changes []db_service.IDMigrationChange `json:"changes"`
+}
+
+func init() {
+	var mappingFile string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "migrate-ids",
+		Short: "Migrate instances to renamed service and plan IDs",
+		Long: `Rewrites the service and plan IDs stored for existing instances and
+bindings after a brokerpak changed them, so the instances can be managed again.
+
+The mapping file is JSON of the form:
+
+	{"services": {"<old id>": "<new id>"}, "plans": {"<old id>": "<new id>"}}
+
+Every new ID must exist in the catalog of the current environment. All rows are
+updated in a single transaction. Use --dry-run to only report the number of
+affected rows.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("migrate-ids")
+
+			contents, err := ioutil.ReadFile(mappingFile)
+			if err != nil {
+				log.Fatalf("Error reading mapping: %v", err)
+			}
+
+			var mapping db_service.IDMapping
+			if err := json.Unmarshal(contents, &mapping); err != nil {
+				log.Fatalf("Error parsing mapping: %v", err)
+			}
+
+			cfg, err := brokers.NewBrokerConfigFromEnv(logger)
+			if err != nil {
+				log.Fatalf("Error loading catalog: %v", err)
+			}
+
+			if err := validateIDMapping(cfg.Registry, mapping); err != nil {
+				log.Fatalf("Invalid mapping: %v", err)
+			}
+
+			db_service.New(logger)
+			changes, err := db_service.MigrateIds(context.Background(), mapping, dryRun)
+			if err != nil {
+				log.Fatalf("Error migrating IDs, no changes were made: %v", err)
+			}
+
+			utils.PrettyPrintOrExit(idMigrationSummary{DryRun: dryRun, Changes: changes})
+		},
+	}
+
+	cmd.Flags().StringVarP(&mappingFile, "mapping", "m", "", "path to a JSON file mapping old IDs to new IDs")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "report the affected rows without changing them")
+	cmd.MarkFlagRequired("mapping")
+
+	rootCmd.AddCommand(cmd)
+}
+
+// validateIDMapping ensures every new ID is part of the registry.
+func validateIDMapping(registry broker.BrokerRegistry, mapping db_service.IDMapping) error {
+	for _, newId := range mapping.Services {
+		if _, err := registry.GetServiceById(newId); err != nil {
+			return err
+		}
+	}
+
+	for _, newId := range mapping.Plans {
+		if _, _, err := registry.GetPlanById(newId); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
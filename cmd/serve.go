@@ -17,18 +17,27 @@ package cmd
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal/cloud-service-broker/brokerapi/brokers"
 	"github.com/pivotal/cloud-service-broker/db_service"
 	"github.com/pivotal/cloud-service-broker/pkg/broker"
 	"github.com/pivotal/cloud-service-broker/pkg/brokerpak"
+	"github.com/pivotal/cloud-service-broker/pkg/credstore"
 	"github.com/pivotal/cloud-service-broker/pkg/server"
-	"github.com/pivotal/cloud-service-broker/pkg/toggles"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
 	"github.com/pivotal/cloud-service-broker/utils"
 	"github.com/gorilla/mux"
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/brokerapi/auth"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -37,10 +46,9 @@ const (
 	apiUserProp     = "api.user"
 	apiPasswordProp = "api.password"
 	apiPortProp     = "api.port"
-)
 
-var cfCompatibilityToggle = toggles.Features.Toggle("enable-cf-sharing", false, `Set all services to have the Sharable flag so they can be shared
-	across spaces in PCF.`)
+	drainTimeoutProp = "api.drain_timeout_secs"
+)
 
 func init() {
 	rootCmd.AddCommand(&cobra.Command{
@@ -64,32 +72,52 @@ func init() {
 	viper.BindEnv(apiUserProp, "SECURITY_USER_NAME")
 	viper.BindEnv(apiPasswordProp, "SECURITY_USER_PASSWORD")
 	viper.BindEnv(apiPortProp, "PORT")
+
+	viper.BindEnv(drainTimeoutProp, "DRAIN_TIMEOUT_SECS")
+	viper.SetDefault(drainTimeoutProp, 10)
 }
 
 func serve() {
 	logger := utils.NewLogger("cloud-service-broker")
 	db := db_service.New(logger)
 
+	shutdownTracing, err := tracing.Setup(logger)
+	if err != nil {
+		logger.Fatal("Error initializing tracing", err)
+	}
+
 	// init broker
 	cfg, err := brokers.NewBrokerConfigFromEnv(logger)
 	if err != nil {
 		logger.Fatal("Error initializing service broker config: %s", err)
 	}
-	var serviceBroker brokerapi.ServiceBroker
-	serviceBroker, err = brokers.New(cfg, logger)
+	if problems := cfg.Registry.ValidateCatalogSchema(); len(problems) > 0 {
+		for _, problem := range problems {
+			logger.Error("invalid-catalog", errors.New(problem.Message), lager.Data{
+				"service_id":   problem.ServiceId,
+				"service_name": problem.ServiceName,
+				"plan_id":      problem.PlanId,
+				"pointer":      problem.Pointer,
+			})
+		}
+		logger.Fatal("Error validating service catalog", fmt.Errorf("%d problem(s) found against the OSB catalog schema", len(problems)))
+	}
+	csb, err := brokers.New(cfg, logger)
 	if err != nil {
 		logger.Fatal("Error initializing service broker: %s", err)
 	}
+	var serviceBroker brokerapi.ServiceBroker = csb
 
 	credentials := brokerapi.BrokerCredentials{
 		Username: viper.GetString(apiUserProp),
 		Password: viper.GetString(apiPasswordProp),
 	}
 
-	if cfCompatibilityToggle.IsActive() {
+	if brokers.CfSharingToggle.IsActive() {
 		logger.Info("Enabling Cloud Foundry service sharing")
 		serviceBroker = server.NewCfSharingWrapper(serviceBroker)
 	}
+	serviceBroker = tracing.NewBrokerWrapper(serviceBroker)
 
 	services, err := serviceBroker.Services(context.Background())
 	if err != nil {
@@ -97,9 +125,35 @@ func serve() {
 	}
 	logger.Info("service catalog", lager.Data{"catalog": services})
 
-	brokerAPI := brokerapi.New(serviceBroker, logger, credentials)
+	brokerAPI := tracing.ExtractTraceContext(brokers.AddResponseHeaders(brokers.AddRequestIdentityToContext(brokers.AddDeletionProtectionOverrideToContext(brokers.AddForceDeprovisionToContext(brokers.AddDeprovisionParametersToContext(brokerapi.New(serviceBroker, logger, credentials)))))))
+
+	var reloader server.CredStoreReloader
+	if r, ok := cfg.Credstore.(credstore.Reloader); ok {
+		reloader = r
+		go reloadCredStoreOnSIGHUP(reloader, logger)
+	}
+
+	// operations left running by a previous broker process resume right away
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	reconcilerDone := csb.StartOperationReconciler(backgroundCtx)
+	purgerDone := csb.StartBindingPurger(backgroundCtx)
+
+	startServer(cfg.Registry, db.DB(), brokerAPI, csb, csb, reloader, credentials)
+
+	// wait for running background passes so they don't use a closed database
+	stopBackground()
+	<-reconcilerDone
+	<-purgerDone
+
+	// Only close the database once in-flight requests have drained so
+	// provisions aren't cut off between the cloud and the DB write.
+	if err := db.Close(); err != nil {
+		logger.Error("closing database", err)
+	}
 
-	startServer(cfg.Registry, db.DB(), brokerAPI)
+	if err := shutdownTracing(context.Background()); err != nil {
+		logger.Error("flushing traces", err)
+	}
 }
 
 func serveDocs() {
@@ -110,10 +164,10 @@ func serveDocs() {
 		logger.Error("loading brokerpaks", err)
 	}
 
-	startServer(registry, nil, nil)
+	startServer(registry, nil, nil, nil, nil, nil, brokerapi.BrokerCredentials{})
 }
 
-func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Handler) {
+func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Handler, instanceAdmin server.InstanceAdmin, services server.ServiceHealthReporter, reloader server.CredStoreReloader, credentials brokerapi.BrokerCredentials) {
 	logger := utils.NewLogger("cloud-service-broker")
 
 	router := mux.NewRouter()
@@ -123,11 +177,55 @@ func startServer(registry broker.BrokerRegistry, db *sql.DB, brokerapi http.Hand
 		router.PathPrefix("/v2").Handler(brokerapi)
 	}
 
+	adminAuth := auth.NewWrapper(credentials.Username, credentials.Password).Wrap
+	if instanceAdmin != nil {
+		server.AddAdminHandler(router, instanceAdmin, adminAuth, logger)
+	}
+
+	if reloader != nil {
+		server.AddCredStoreReloadHandler(router, reloader, adminAuth, logger)
+	}
+
 	server.AddDocsHandler(router, registry)
 	router.HandleFunc("/examples", server.NewExampleHandler(registry))
-	server.AddHealthHandler(router, db)
+	server.AddHealthHandler(router, db, services)
+	server.AddMetricsHandler(router, db, brokers.DeprecatedParameterUses)
 
 	port := viper.GetString(apiPortProp)
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		logger.Fatal("Error listening", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+
 	logger.Info("Serving", lager.Data{"port": port})
-	http.ListenAndServe(":"+port, router)
+	drainTimeout := time.Duration(viper.GetInt(drainTimeoutProp)) * time.Second
+	if err := server.ServeAndDrain(&http.Server{Handler: router}, listener, stop, drainTimeout, logger); err != nil {
+		logger.Error("serving", err)
+	}
+}
+
+// reloadCredStoreOnSIGHUP reloads the credstore auth material, e.g. a rotated
+// mTLS certificate, every time the process receives SIGHUP.
+func reloadCredStoreOnSIGHUP(reloader server.CredStoreReloader, logger lager.Logger) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	for range hup {
+		if viper.ConfigFileUsed() != "" {
+			if err := viper.ReadInConfig(); err != nil {
+				logger.Error("reloading config file", err)
+				continue
+			}
+		}
+
+		if err := reloader.Reload(); err != nil {
+			logger.Error("reloading credstore", err)
+			continue
+		}
+
+		logger.Info("reloaded credstore")
+	}
 }
@@ -0,0 +1,132 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+
+	"github.com/pivotal/cloud-service-broker/db_service"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	stateArchiveKeyProp        = "state_archive.key"
+	stateArchiveSourceKeysProp = "state_archive.source_encryption_keys"
+)
+
+func init() {
+	viper.BindEnv(stateArchiveKeyProp, "STATE_ARCHIVE_KEY")
+	viper.BindEnv(stateArchiveSourceKeysProp, "STATE_ARCHIVE_SOURCE_ENCRYPTION_KEYS")
+
+	var output string
+	exportCmd := &cobra.Command{
+		Use:   "export-state",
+		Short: "Export the instances and bindings of the broker",
+		Long: `Writes a consistent snapshot of the service instances, bindings, provision
+requests and Terraform deployments of the broker to an archive, to back it up
+or migrate it to another database.
+
+The archive contains credentials, so it is encrypted and authenticated with
+the key in the STATE_ARCHIVE_KEY environment variable. Use a long random key
+and keep it as safe as the database credentials.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("export-state")
+			db_service.New(logger)
+
+			state, err := db_service.ExportState(context.Background())
+			if err != nil {
+				log.Fatalf("Error reading the broker state: %v", err)
+			}
+
+			archive, err := db_service.SealState(state, viper.GetString(stateArchiveKeyProp))
+			if err != nil {
+				log.Fatalf("Error encrypting the broker state: %v", err)
+			}
+
+			if err := ioutil.WriteFile(output, archive, 0600); err != nil {
+				log.Fatalf("Error writing the archive: %v", err)
+			}
+
+			log.Printf("Exported %d instances and %d bindings", len(state.Instances), len(state.Bindings))
+		},
+	}
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "path to write the archive to")
+	exportCmd.MarkFlagRequired("output")
+	rootCmd.AddCommand(exportCmd)
+
+	var input string
+	importCmd := &cobra.Command{
+		Use:   "import-state",
+		Short: "Import the instances and bindings exported from a broker",
+		Long: `Restores an archive written by export-state, decrypted with the key in the
+STATE_ARCHIVE_KEY environment variable, into the database of the current
+environment.
+
+Instance parameters encrypted by the exporting broker are decrypted with the
+keys in the STATE_ARCHIVE_SOURCE_ENCRYPTION_KEYS environment variable, in the
+same format as DB_ENCRYPTION_KEYS, and re-encrypted with the active key of
+the current environment. Without source keys, the current keys are used.
+
+All records are imported in a single transaction. If any instance, binding or
+Terraform deployment of the archive already exists in the database, nothing
+is imported and the conflicts are reported.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := utils.NewLogger("import-state")
+
+			archive, err := ioutil.ReadFile(input)
+			if err != nil {
+				log.Fatalf("Error reading the archive: %v", err)
+			}
+
+			state, err := db_service.OpenState(archive, viper.GetString(stateArchiveKeyProp))
+			if err != nil {
+				log.Fatalf("Error decrypting the archive: %v", err)
+			}
+
+			source, err := sourceKeyRing()
+			if err != nil {
+				log.Fatalf("Error reading the source encryption keys: %v", err)
+			}
+
+			db_service.New(logger)
+			if err := db_service.ImportState(context.Background(), state, source); err != nil {
+				log.Fatalf("Error importing the broker state, no changes were made: %v", err)
+			}
+
+			log.Printf("Imported %d instances and %d bindings", len(state.Instances), len(state.Bindings))
+		},
+	}
+	importCmd.Flags().StringVarP(&input, "input", "i", "", "path of the archive to import")
+	importCmd.MarkFlagRequired("input")
+	rootCmd.AddCommand(importCmd)
+}
+
+// sourceKeyRing returns the key ring the exporting broker encrypted instance
+// parameters with, nil if it isn't configured.
+func sourceKeyRing() (*models.KeyRing, error) {
+	keys := viper.GetStringMapString(stateArchiveSourceKeysProp)
+	for id := range keys {
+		// the ring is only used to decrypt, so any key can be the active one
+		return models.NewKeyRing(keys, id)
+	}
+	return nil, nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// GetServiceBindingCredentialsByServiceInstanceId gets the bindings of the
+// instance, oldest first.
+func GetServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) (_ []models.ServiceBindingCredentials, err error) {
+	ctx, span := startSpan(ctx, "GetServiceBindingCredentialsByServiceInstanceId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceBindingCredentialsByServiceInstanceId(ctx, serviceInstanceId)
+}
+func (ds *SqlDatastore) GetServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.ServiceBindingCredentials, error) {
+	var bindings []models.ServiceBindingCredentials
+	if err := ds.db.Where("service_instance_id = ?", serviceInstanceId).Order("id asc").Find(&bindings).Error; err != nil {
+		return nil, err
+	}
+
+	return bindings, nil
+}
+
+// CountServiceBindingCredentialsByServiceInstanceId counts the bindings of the
+// instance.
+func CountServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "CountServiceBindingCredentialsByServiceInstanceId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CountServiceBindingCredentialsByServiceInstanceId(ctx, serviceInstanceId)
+}
+func (ds *SqlDatastore) CountServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) (int, error) {
+	var count int
+	if err := ds.db.Model(&models.ServiceBindingCredentials{}).Where("service_instance_id = ?", serviceInstanceId).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
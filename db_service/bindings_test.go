@@ -0,0 +1,102 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_GetServiceBindingCredentialsByServiceInstanceId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testCtx := context.Background()
+
+	for _, record := range []models.ServiceBindingCredentials{
+		{ServiceInstanceId: "instance", BindingId: "app-binding", AppGuid: "app-guid", AppName: "my-app"},
+		{ServiceInstanceId: "other", BindingId: "other-binding"},
+		{ServiceInstanceId: "instance", BindingId: "service-key"},
+	} {
+		if err := ds.CreateServiceBindingCredentials(testCtx, &record); err != nil {
+			t.Fatalf("Expected to be able to create the item %#v, got error: %s", record, err)
+		}
+	}
+
+	bindings, err := ds.GetServiceBindingCredentialsByServiceInstanceId(testCtx, "instance")
+	if err != nil {
+		t.Fatalf("Expected to be able to get the bindings, got error: %s", err)
+	}
+
+	if len(bindings) != 2 {
+		t.Fatalf("Expected 2 bindings, got %d", len(bindings))
+	}
+
+	if bindings[0].BindingId != "app-binding" || bindings[0].AppGuid != "app-guid" || bindings[0].AppName != "my-app" {
+		t.Errorf("Expected the app binding to be first, got %#v", bindings[0])
+	}
+
+	if bindings[1].BindingId != "service-key" || bindings[1].AppGuid != "" || bindings[1].AppName != "" {
+		t.Errorf("Expected the service key to have no app, got %#v", bindings[1])
+	}
+
+	if none, err := ds.GetServiceBindingCredentialsByServiceInstanceId(testCtx, "missing"); err != nil || len(none) != 0 {
+		t.Errorf("Expected no bindings for unknown instances, got %v, %v", none, err)
+	}
+}
+
+func TestSqlDatastore_CountServiceBindingCredentialsByServiceInstanceId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testCtx := context.Background()
+
+	for _, record := range []models.ServiceBindingCredentials{
+		{ServiceInstanceId: "instance", BindingId: "first"},
+		{ServiceInstanceId: "other", BindingId: "other-binding"},
+		{ServiceInstanceId: "instance", BindingId: "second"},
+	} {
+		if err := ds.CreateServiceBindingCredentials(testCtx, &record); err != nil {
+			t.Fatalf("Expected to be able to create the item %#v, got error: %s", record, err)
+		}
+	}
+
+	cases := map[string]struct {
+		InstanceId string
+		Expected   int
+	}{
+		"bound instance":   {InstanceId: "instance", Expected: 2},
+		"other instance":   {InstanceId: "other", Expected: 1},
+		"unknown instance": {InstanceId: "missing", Expected: 0},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			count, err := ds.CountServiceBindingCredentialsByServiceInstanceId(testCtx, tc.InstanceId)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if count != tc.Expected {
+				t.Errorf("Expected %d bindings, got %d", tc.Expected, count)
+			}
+		})
+	}
+
+	if err := ds.DeleteServiceBindingCredentialsByBindingId(testCtx, "first"); err != nil {
+		t.Fatalf("Expected to be able to delete the item, got error: %s", err)
+	}
+
+	if count, err := ds.CountServiceBindingCredentialsByServiceInstanceId(testCtx, "instance"); err != nil || count != 1 {
+		t.Errorf("Expected deleted bindings not to be counted, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+const brokerIdField = "BrokerId"
+
+// scopeToBroker registers callbacks on the database so brokers sharing it
+// only see their own records. Records created through db are assigned
+// brokerId, and queries, updates and deletes of models with a BrokerId field
+// are limited to the records with brokerId. See DB_BROKER_ID.
+func scopeToBroker(db *gorm.DB, brokerId string) {
+	assign := func(scope *gorm.Scope) {
+		if hasBrokerId(scope) {
+			scope.SetColumn(brokerIdField, brokerId)
+		}
+	}
+
+	filter := func(scope *gorm.Scope) {
+		if hasBrokerId(scope) {
+			scope.Search.Where(scope.QuotedTableName()+".broker_id = ?", brokerId)
+		}
+	}
+
+	callback := db.Callback()
+	callback.Create().Before("gorm:create").Register("broker:assign_broker_id", assign)
+	callback.Update().Before("gorm:update").Register("broker:assign_broker_id", assign)
+	callback.Update().Before("gorm:update").Register("broker:filter_broker_id", filter)
+	callback.Delete().Before("gorm:delete").Register("broker:filter_broker_id", filter)
+	callback.Query().Before("gorm:query").Register("broker:filter_broker_id", filter)
+	callback.RowQuery().Before("gorm:row_query").Register("broker:filter_broker_id", filter)
+}
+
+// hasBrokerId returns true if the model of the scope records its broker.
+func hasBrokerId(scope *gorm.Scope) bool {
+	for _, field := range scope.GetModelStruct().StructFields {
+		if field.Name == brokerIdField {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestScopeToBroker(t *testing.T) {
+	shared := newInMemoryDatastore(t)
+	defer shared.db.Close()
+
+	newBroker := func(brokerId string) *SqlDatastore {
+		db, err := gorm.Open("sqlite3", shared.db.DB())
+		if err != nil {
+			t.Fatalf("Error opening test database %s", err)
+		}
+		scopeToBroker(db, brokerId)
+		return &SqlDatastore{db: db}
+	}
+
+	ctx := context.Background()
+	alpha := newBroker("alpha")
+	beta := newBroker("beta")
+
+	_, instance := createServiceInstanceDetailsInstance()
+	instance.InstanceName = "my-db"
+	if err := alpha.CreateServiceInstanceDetails(ctx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item, got error: %s", err)
+	}
+
+	t.Run("assigns-broker-id", func(t *testing.T) {
+		var record models.ServiceInstanceDetails
+		if err := shared.db.Where("id = ?", instance.ID).First(&record).Error; err != nil {
+			t.Fatal(err)
+		}
+		if record.BrokerId != "alpha" {
+			t.Errorf("Expected broker ID alpha, got %q", record.BrokerId)
+		}
+	})
+
+	t.Run("owner-sees-record", func(t *testing.T) {
+		exists, err := alpha.ExistsServiceInstanceDetailsById(ctx, instance.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Error("Expected the owner to see the instance")
+		}
+
+		taken, err := alpha.ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx, instance.SpaceGuid, "my-db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !taken {
+			t.Error("Expected the owner to count the instance")
+		}
+	})
+
+	t.Run("other-broker-does-not-see-record", func(t *testing.T) {
+		exists, err := beta.ExistsServiceInstanceDetailsById(ctx, instance.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Error("Expected another broker not to see the instance")
+		}
+
+		taken, err := beta.ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx, instance.SpaceGuid, "my-db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if taken {
+			t.Error("Expected another broker not to count the instance")
+		}
+	})
+
+	t.Run("other-broker-cannot-delete-record", func(t *testing.T) {
+		if err := beta.DeleteServiceInstanceDetailsById(ctx, instance.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		exists, err := alpha.ExistsServiceInstanceDetailsById(ctx, instance.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Error("Expected the instance to survive a delete by another broker")
+		}
+	})
+
+	t.Run("other-broker-cannot-update-record", func(t *testing.T) {
+		if err := beta.db.Model(&models.ServiceInstanceDetails{}).Where("id = ?", instance.ID).Updates(map[string]interface{}{"location": "elsewhere"}).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		record, err := alpha.GetServiceInstanceDetailsById(ctx, instance.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if record.Location != instance.Location {
+			t.Errorf("Expected location %q, got %q", instance.Location, record.Location)
+		}
+	})
+}
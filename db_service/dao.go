@@ -24,42 +24,81 @@ import (
 )
 
 // CreateServiceInstanceDetails creates a new record in the database and assigns it a primary key.
-func CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().CreateServiceInstanceDetails(ctx, object) }
+func CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) (err error) {
+	ctx, span := startSpan(ctx, "CreateServiceInstanceDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateServiceInstanceDetails(ctx, object)
+}
 func (ds *SqlDatastore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ds.instances.invalidate(object.ID)
+	defer ds.instances.invalidate(object.ID)
 	return ds.db.Create(object).Error
 }
 
 // SaveServiceInstanceDetails updates an existing record in the database.
-func SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error { return defaultDatastore().SaveServiceInstanceDetails(ctx, object) }
+func SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) (err error) {
+	ctx, span := startSpan(ctx, "SaveServiceInstanceDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().SaveServiceInstanceDetails(ctx, object)
+}
 func (ds *SqlDatastore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ds.instances.invalidate(object.ID)
+	defer ds.instances.invalidate(object.ID)
 	return ds.db.Save(object).Error
 }
 // DeleteServiceInstanceDetailsById soft-deletes the record by its key (id).
-func DeleteServiceInstanceDetailsById(ctx context.Context, id string) error { return defaultDatastore().DeleteServiceInstanceDetailsById(ctx, id) }
+func DeleteServiceInstanceDetailsById(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceInstanceDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceInstanceDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
+	ds.instances.invalidate(id)
+	defer ds.instances.invalidate(id)
 	return ds.db.Where("id = ?", id).Delete(&models.ServiceInstanceDetails{}).Error
 }
 
 
 
 // DeleteServiceInstanceDetails soft-deletes the record.
-func DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error { return defaultDatastore().DeleteServiceInstanceDetails(ctx, record) }
+func DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceInstanceDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceInstanceDetails(ctx, record)
+}
 func (ds *SqlDatastore) DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error {
+	ds.instances.invalidate(record.ID)
+	defer ds.instances.invalidate(record.ID)
 	return ds.db.Delete(record).Error
 }
 // GetServiceInstanceDetailsById gets an instance of ServiceInstanceDetails by its key (id).
-func GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) { return defaultDatastore().GetServiceInstanceDetailsById(ctx, id) }
+func GetServiceInstanceDetailsById(ctx context.Context, id string) (_ *models.ServiceInstanceDetails, err error) {
+	ctx, span := startSpan(ctx, "GetServiceInstanceDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceInstanceDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	cached, version, ok := ds.instances.get(id)
+	if ok {
+		return cached, nil
+	}
+
 	record := models.ServiceInstanceDetails{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
 		return nil, err
 	}
 
+	ds.instances.put(&record, version)
+
 	return &record, nil
 }
 
 // ExistsServiceInstanceDetailsById checks to see if an instance of ServiceInstanceDetails exists by its key (id).
-func ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) { return defaultDatastore().ExistsServiceInstanceDetailsById(ctx, id) }
+func ExistsServiceInstanceDetailsById(ctx context.Context, id string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceInstanceDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceInstanceDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
 	return recordToExists(ds.GetServiceInstanceDetailsById(ctx, id))
 }
@@ -67,30 +106,50 @@ func (ds *SqlDatastore) ExistsServiceInstanceDetailsById(ctx context.Context, id
 
 
 // CreateServiceBindingCredentials creates a new record in the database and assigns it a primary key.
-func CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error { return defaultDatastore().CreateServiceBindingCredentials(ctx, object) }
+func CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) (err error) {
+	ctx, span := startSpan(ctx, "CreateServiceBindingCredentials")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateServiceBindingCredentials(ctx, object)
+}
 func (ds *SqlDatastore) CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
 	return ds.db.Create(object).Error
 }
 
 // SaveServiceBindingCredentials updates an existing record in the database.
-func SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error { return defaultDatastore().SaveServiceBindingCredentials(ctx, object) }
+func SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) (err error) {
+	ctx, span := startSpan(ctx, "SaveServiceBindingCredentials")
+	defer func() { endSpan(span, err) }()
+	return currentStore().SaveServiceBindingCredentials(ctx, object)
+}
 func (ds *SqlDatastore) SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
 	return ds.db.Save(object).Error
 }
 // DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId soft-deletes the record by its key (serviceInstanceId, bindingId).
-func DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error { return defaultDatastore().DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
+func DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+}
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error {
 	return ds.db.Where("service_instance_id = ? AND binding_id = ?", serviceInstanceId, bindingId).Delete(&models.ServiceBindingCredentials{}).Error
 }
 
 // DeleteServiceBindingCredentialsByBindingId soft-deletes the record by its key (bindingId).
-func DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error { return defaultDatastore().DeleteServiceBindingCredentialsByBindingId(ctx, bindingId) }
+func DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceBindingCredentialsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceBindingCredentialsByBindingId(ctx, bindingId)
+}
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error {
 	return ds.db.Where("binding_id = ?", bindingId).Delete(&models.ServiceBindingCredentials{}).Error
 }
 
 // DeleteServiceBindingCredentialsById soft-deletes the record by its key (id).
-func DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error { return defaultDatastore().DeleteServiceBindingCredentialsById(ctx, id) }
+func DeleteServiceBindingCredentialsById(ctx context.Context, id uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceBindingCredentialsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceBindingCredentialsById(ctx, id)
+}
 func (ds *SqlDatastore) DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error {
 	return ds.db.Where("id = ?", id).Delete(&models.ServiceBindingCredentials{}).Error
 }
@@ -98,12 +157,20 @@ func (ds *SqlDatastore) DeleteServiceBindingCredentialsById(ctx context.Context,
 
 
 // DeleteServiceBindingCredentials soft-deletes the record.
-func DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error { return defaultDatastore().DeleteServiceBindingCredentials(ctx, record) }
+func DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) (err error) {
+	ctx, span := startSpan(ctx, "DeleteServiceBindingCredentials")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteServiceBindingCredentials(ctx, record)
+}
 func (ds *SqlDatastore) DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error {
 	return ds.db.Delete(record).Error
 }
 // GetServiceBindingCredentialsByServiceInstanceIdAndBindingId gets an instance of ServiceBindingCredentials by its key (serviceInstanceId, bindingId).
-func GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
+func GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (_ *models.ServiceBindingCredentials, err error) {
+	ctx, span := startSpan(ctx, "GetServiceBindingCredentialsByServiceInstanceIdAndBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+}
 func (ds *SqlDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) {
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("service_instance_id = ? AND binding_id = ?", serviceInstanceId, bindingId).First(&record).Error; err != nil {
@@ -114,13 +181,21 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsByServiceInstanceIdAndBindin
 }
 
 // ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId checks to see if an instance of ServiceBindingCredentials exists by its key (serviceInstanceId, bindingId).
-func ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId) }
+func ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+}
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) {
 	return recordToExists(ds.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId))
 }
 
 // GetServiceBindingCredentialsByBindingId gets an instance of ServiceBindingCredentials by its key (bindingId).
-func GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsByBindingId(ctx, bindingId) }
+func GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (_ *models.ServiceBindingCredentials, err error) {
+	ctx, span := startSpan(ctx, "GetServiceBindingCredentialsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceBindingCredentialsByBindingId(ctx, bindingId)
+}
 func (ds *SqlDatastore) GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error) {
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("binding_id = ?", bindingId).First(&record).Error; err != nil {
@@ -131,13 +206,21 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsByBindingId(ctx context.Cont
 }
 
 // ExistsServiceBindingCredentialsByBindingId checks to see if an instance of ServiceBindingCredentials exists by its key (bindingId).
-func ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsByBindingId(ctx, bindingId) }
+func ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceBindingCredentialsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceBindingCredentialsByBindingId(ctx, bindingId)
+}
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error) {
 	return recordToExists(ds.GetServiceBindingCredentialsByBindingId(ctx, bindingId))
 }
 
 // GetServiceBindingCredentialsById gets an instance of ServiceBindingCredentials by its key (id).
-func GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error) { return defaultDatastore().GetServiceBindingCredentialsById(ctx, id) }
+func GetServiceBindingCredentialsById(ctx context.Context, id uint) (_ *models.ServiceBindingCredentials, err error) {
+	ctx, span := startSpan(ctx, "GetServiceBindingCredentialsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceBindingCredentialsById(ctx, id)
+}
 func (ds *SqlDatastore) GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error) {
 	record := models.ServiceBindingCredentials{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
@@ -148,7 +231,11 @@ func (ds *SqlDatastore) GetServiceBindingCredentialsById(ctx context.Context, id
 }
 
 // ExistsServiceBindingCredentialsById checks to see if an instance of ServiceBindingCredentials exists by its key (id).
-func ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsServiceBindingCredentialsById(ctx, id) }
+func ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceBindingCredentialsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceBindingCredentialsById(ctx, id)
+}
 func (ds *SqlDatastore) ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error) {
 	return recordToExists(ds.GetServiceBindingCredentialsById(ctx, id))
 }
@@ -156,18 +243,30 @@ func (ds *SqlDatastore) ExistsServiceBindingCredentialsById(ctx context.Context,
 
 
 // CreateProvisionRequestDetails creates a new record in the database and assigns it a primary key.
-func CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error { return defaultDatastore().CreateProvisionRequestDetails(ctx, object) }
+func CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "CreateProvisionRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateProvisionRequestDetails(ctx, object)
+}
 func (ds *SqlDatastore) CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
 	return ds.db.Create(object).Error
 }
 
 // SaveProvisionRequestDetails updates an existing record in the database.
-func SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error { return defaultDatastore().SaveProvisionRequestDetails(ctx, object) }
+func SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "SaveProvisionRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().SaveProvisionRequestDetails(ctx, object)
+}
 func (ds *SqlDatastore) SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
 	return ds.db.Save(object).Error
 }
 // DeleteProvisionRequestDetailsById soft-deletes the record by its key (id).
-func DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error { return defaultDatastore().DeleteProvisionRequestDetailsById(ctx, id) }
+func DeleteProvisionRequestDetailsById(ctx context.Context, id uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteProvisionRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteProvisionRequestDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error {
 	return ds.db.Where("id = ?", id).Delete(&models.ProvisionRequestDetails{}).Error
 }
@@ -175,12 +274,20 @@ func (ds *SqlDatastore) DeleteProvisionRequestDetailsById(ctx context.Context, i
 
 
 // DeleteProvisionRequestDetails soft-deletes the record.
-func DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error { return defaultDatastore().DeleteProvisionRequestDetails(ctx, record) }
+func DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "DeleteProvisionRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteProvisionRequestDetails(ctx, record)
+}
 func (ds *SqlDatastore) DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error {
 	return ds.db.Delete(record).Error
 }
 // GetProvisionRequestDetailsById gets an instance of ProvisionRequestDetails by its key (id).
-func GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error) { return defaultDatastore().GetProvisionRequestDetailsById(ctx, id) }
+func GetProvisionRequestDetailsById(ctx context.Context, id uint) (_ *models.ProvisionRequestDetails, err error) {
+	ctx, span := startSpan(ctx, "GetProvisionRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetProvisionRequestDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error) {
 	record := models.ProvisionRequestDetails{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
@@ -191,26 +298,144 @@ func (ds *SqlDatastore) GetProvisionRequestDetailsById(ctx context.Context, id u
 }
 
 // ExistsProvisionRequestDetailsById checks to see if an instance of ProvisionRequestDetails exists by its key (id).
-func ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) { return defaultDatastore().ExistsProvisionRequestDetailsById(ctx, id) }
+func ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsProvisionRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsProvisionRequestDetailsById(ctx, id)
+}
 func (ds *SqlDatastore) ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) {
 	return recordToExists(ds.GetProvisionRequestDetailsById(ctx, id))
 }
 
 
 
+// CreateBindRequestDetails creates a new record in the database and assigns it a primary key.
+func CreateBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "CreateBindRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateBindRequestDetails(ctx, object)
+}
+func (ds *SqlDatastore) CreateBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error {
+	return ds.db.Create(object).Error
+}
+
+// SaveBindRequestDetails updates an existing record in the database.
+func SaveBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "SaveBindRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().SaveBindRequestDetails(ctx, object)
+}
+func (ds *SqlDatastore) SaveBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error {
+	return ds.db.Save(object).Error
+}
+// DeleteBindRequestDetailsByBindingId soft-deletes the record by its key (bindingId).
+func DeleteBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBindRequestDetailsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteBindRequestDetailsByBindingId(ctx, bindingId)
+}
+func (ds *SqlDatastore) DeleteBindRequestDetailsByBindingId(ctx context.Context, bindingId string) error {
+	return ds.db.Where("binding_id = ?", bindingId).Delete(&models.BindRequestDetails{}).Error
+}
+
+// DeleteBindRequestDetailsById soft-deletes the record by its key (id).
+func DeleteBindRequestDetailsById(ctx context.Context, id uint) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBindRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteBindRequestDetailsById(ctx, id)
+}
+func (ds *SqlDatastore) DeleteBindRequestDetailsById(ctx context.Context, id uint) error {
+	return ds.db.Where("id = ?", id).Delete(&models.BindRequestDetails{}).Error
+}
+
+
+
+// DeleteBindRequestDetails soft-deletes the record.
+func DeleteBindRequestDetails(ctx context.Context, record *models.BindRequestDetails) (err error) {
+	ctx, span := startSpan(ctx, "DeleteBindRequestDetails")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteBindRequestDetails(ctx, record)
+}
+func (ds *SqlDatastore) DeleteBindRequestDetails(ctx context.Context, record *models.BindRequestDetails) error {
+	return ds.db.Delete(record).Error
+}
+// GetBindRequestDetailsByBindingId gets an instance of BindRequestDetails by its key (bindingId).
+func GetBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (_ *models.BindRequestDetails, err error) {
+	ctx, span := startSpan(ctx, "GetBindRequestDetailsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetBindRequestDetailsByBindingId(ctx, bindingId)
+}
+func (ds *SqlDatastore) GetBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (*models.BindRequestDetails, error) {
+	record := models.BindRequestDetails{}
+	if err := ds.db.Where("binding_id = ?", bindingId).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// ExistsBindRequestDetailsByBindingId checks to see if an instance of BindRequestDetails exists by its key (bindingId).
+func ExistsBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsBindRequestDetailsByBindingId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsBindRequestDetailsByBindingId(ctx, bindingId)
+}
+func (ds *SqlDatastore) ExistsBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (bool, error) {
+	return recordToExists(ds.GetBindRequestDetailsByBindingId(ctx, bindingId))
+}
+
+// GetBindRequestDetailsById gets an instance of BindRequestDetails by its key (id).
+func GetBindRequestDetailsById(ctx context.Context, id uint) (_ *models.BindRequestDetails, err error) {
+	ctx, span := startSpan(ctx, "GetBindRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetBindRequestDetailsById(ctx, id)
+}
+func (ds *SqlDatastore) GetBindRequestDetailsById(ctx context.Context, id uint) (*models.BindRequestDetails, error) {
+	record := models.BindRequestDetails{}
+	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// ExistsBindRequestDetailsById checks to see if an instance of BindRequestDetails exists by its key (id).
+func ExistsBindRequestDetailsById(ctx context.Context, id uint) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsBindRequestDetailsById")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsBindRequestDetailsById(ctx, id)
+}
+func (ds *SqlDatastore) ExistsBindRequestDetailsById(ctx context.Context, id uint) (bool, error) {
+	return recordToExists(ds.GetBindRequestDetailsById(ctx, id))
+}
+
+
+
 // CreateTerraformDeployment creates a new record in the database and assigns it a primary key.
-func CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error { return defaultDatastore().CreateTerraformDeployment(ctx, object) }
+func CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) (err error) {
+	ctx, span := startSpan(ctx, "CreateTerraformDeployment")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().CreateTerraformDeployment(ctx, object)
+}
 func (ds *SqlDatastore) CreateTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
 	return ds.db.Create(object).Error
 }
 
 // SaveTerraformDeployment updates an existing record in the database.
-func SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error { return defaultDatastore().SaveTerraformDeployment(ctx, object) }
+func SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) (err error) {
+	ctx, span := startSpan(ctx, "SaveTerraformDeployment")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().SaveTerraformDeployment(ctx, object)
+}
 func (ds *SqlDatastore) SaveTerraformDeployment(ctx context.Context, object *models.TerraformDeployment) error {
 	return ds.db.Save(object).Error
 }
 // DeleteTerraformDeploymentById soft-deletes the record by its key (id).
-func DeleteTerraformDeploymentById(ctx context.Context, id string) error { return defaultDatastore().DeleteTerraformDeploymentById(ctx, id) }
+func DeleteTerraformDeploymentById(ctx context.Context, id string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteTerraformDeploymentById")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().DeleteTerraformDeploymentById(ctx, id)
+}
 func (ds *SqlDatastore) DeleteTerraformDeploymentById(ctx context.Context, id string) error {
 	return ds.db.Where("id = ?", id).Delete(&models.TerraformDeployment{}).Error
 }
@@ -218,12 +443,20 @@ func (ds *SqlDatastore) DeleteTerraformDeploymentById(ctx context.Context, id st
 
 
 // DeleteTerraformDeployment soft-deletes the record.
-func DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) error { return defaultDatastore().DeleteTerraformDeployment(ctx, record) }
+func DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) (err error) {
+	ctx, span := startSpan(ctx, "DeleteTerraformDeployment")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().DeleteTerraformDeployment(ctx, record)
+}
 func (ds *SqlDatastore) DeleteTerraformDeployment(ctx context.Context, record *models.TerraformDeployment) error {
 	return ds.db.Delete(record).Error
 }
 // GetTerraformDeploymentById gets an instance of TerraformDeployment by its key (id).
-func GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error) { return defaultDatastore().GetTerraformDeploymentById(ctx, id) }
+func GetTerraformDeploymentById(ctx context.Context, id string) (_ *models.TerraformDeployment, err error) {
+	ctx, span := startSpan(ctx, "GetTerraformDeploymentById")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().GetTerraformDeploymentById(ctx, id)
+}
 func (ds *SqlDatastore) GetTerraformDeploymentById(ctx context.Context, id string) (*models.TerraformDeployment, error) {
 	record := models.TerraformDeployment{}
 	if err := ds.db.Where("id = ?", id).First(&record).Error; err != nil {
@@ -234,7 +467,11 @@ func (ds *SqlDatastore) GetTerraformDeploymentById(ctx context.Context, id strin
 }
 
 // ExistsTerraformDeploymentById checks to see if an instance of TerraformDeployment exists by its key (id).
-func ExistsTerraformDeploymentById(ctx context.Context, id string) (bool, error) { return defaultDatastore().ExistsTerraformDeploymentById(ctx, id) }
+func ExistsTerraformDeploymentById(ctx context.Context, id string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsTerraformDeploymentById")
+	defer func() { endSpan(span, err) }()
+	return defaultDatastore().ExistsTerraformDeploymentById(ctx, id)
+}
 func (ds *SqlDatastore) ExistsTerraformDeploymentById(ctx context.Context, id string) (bool, error) {
 	return recordToExists(ds.GetTerraformDeploymentById(ctx, id))
 }
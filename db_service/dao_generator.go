@@ -33,6 +33,8 @@ func main() {
 			Type:            "ServiceInstanceDetails",
 			PrimaryKeyType:  "string",
 			PrimaryKeyField: "id",
+			Cache:           "instances",
+			Stored:          true,
 			ExampleFields: map[string]interface{}{
 				"Name":             "Hello",
 				"Location":         "loc",
@@ -48,6 +50,7 @@ func main() {
 			Type:            "ServiceBindingCredentials",
 			PrimaryKeyType:  "uint",
 			PrimaryKeyField: "id",
+			Stored:          true,
 			Keys: []fieldList{
 				{
 					{Type: "string", Column: "service_instance_id"},
@@ -68,11 +71,28 @@ func main() {
 			Type:            "ProvisionRequestDetails",
 			PrimaryKeyType:  "uint",
 			PrimaryKeyField: "id",
+			Stored:          true,
 			ExampleFields: map[string]interface{}{
 				"ServiceInstanceId": "2222-2222-2222",
 				"RequestDetails":    `{"some":["json","blob","here"]}`,
 			},
 		},
+		{
+			Type:            "BindRequestDetails",
+			PrimaryKeyType:  "uint",
+			PrimaryKeyField: "id",
+			Stored:          true,
+			Keys: []fieldList{
+				{
+					{Type: "string", Column: "binding_id"},
+				},
+			},
+			ExampleFields: map[string]interface{}{
+				"ServiceInstanceId": "2222-2222-2222",
+				"BindingId":         "0000-0000-0000",
+				"RequestDetails":    `{"some":["json","blob","here"]}`,
+			},
+		},
 		{
 			Type:            "TerraformDeployment",
 			PrimaryKeyType:  "string",
@@ -136,6 +156,14 @@ type crudModel struct {
 	PrimaryKeyField string
 	ExampleFields   map[string]interface{}
 	Keys            []fieldList
+
+	// Cache is the SqlDatastore field caching records by their primary key,
+	// if any. Cached models MUST NOT have other keys.
+	Cache string
+
+	// Stored is set for models persisted through the pluggable Store rather
+	// than always in the database.
+	Stored bool
 }
 
 type fieldList []crudField
@@ -280,16 +308,35 @@ import (
 {{- range .Models}}
 
 {{- $type := .Type}}
+{{- $cache := .Cache}}
+{{- $store := "defaultDatastore()"}}
+{{- if .Stored}}{{$store = "currentStore()"}}{{end}}
 
 // {{funcName "Create" .Type}} creates a new record in the database and assigns it a primary key.
-func {{funcName "Create" .Type}}(ctx context.Context, object *models.{{.Type}}) error { return defaultDatastore().{{funcName "Create" .Type}}(ctx, object) }
+func {{funcName "Create" .Type}}(ctx context.Context, object *models.{{.Type}}) (err error) {
+	ctx, span := startSpan(ctx, "{{funcName "Create" .Type}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{funcName "Create" .Type}}(ctx, object)
+}
 func (ds *SqlDatastore) Create{{.Type}}(ctx context.Context, object *models.{{.Type}}) error {
+{{- if $cache}}
+	ds.{{$cache}}.invalidate(object.ID)
+	defer ds.{{$cache}}.invalidate(object.ID)
+{{- end}}
 	return ds.db.Create(object).Error
 }
 
 // {{funcName "Save" .Type}} updates an existing record in the database.
-func {{funcName "Save" .Type}}(ctx context.Context, object *models.{{.Type}}) error { return defaultDatastore().{{funcName "Save" .Type}}(ctx, object) }
+func {{funcName "Save" .Type}}(ctx context.Context, object *models.{{.Type}}) (err error) {
+	ctx, span := startSpan(ctx, "{{funcName "Save" .Type}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{funcName "Save" .Type}}(ctx, object)
+}
 func (ds *SqlDatastore) {{funcName "Save" .Type}}(ctx context.Context, object *models.{{.Type}}) error {
+{{- if $cache}}
+	ds.{{$cache}}.invalidate(object.ID)
+	defer ds.{{$cache}}.invalidate(object.ID)
+{{- end}}
 	return ds.db.Save(object).Error
 }
 
@@ -297,16 +344,32 @@ func (ds *SqlDatastore) {{funcName "Save" .Type}}(ctx context.Context, object *m
 {{ range $idx, $key := .Keys -}}
 {{ $fn := (print "Delete" $type $key.FuncName) -}}
 // {{$fn}} soft-deletes the record by its key ({{$key.CallParams}}).
-func {{$fn}}(ctx context.Context, {{ $key.Args }}) error { return defaultDatastore().{{$fn}}(ctx, {{$key.CallParams}}) }
+func {{$fn}}(ctx context.Context, {{ $key.Args }}) (err error) {
+	ctx, span := startSpan(ctx, "{{$fn}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{$fn}}(ctx, {{$key.CallParams}})
+}
 func (ds *SqlDatastore) {{$fn}}(ctx context.Context, {{ $key.Args }}) error {
+{{- if $cache}}
+	ds.{{$cache}}.invalidate({{$key.CallParams}})
+	defer ds.{{$cache}}.invalidate({{$key.CallParams}})
+{{- end}}
 	return ds.db.{{ $key.WhereClause }}.Delete(&models.{{$type}}{}).Error
 }
 
 {{ end }}
 
 // Delete{{.Type}} soft-deletes the record.
-func {{funcName "Delete" .Type}}(ctx context.Context, record *models.{{.Type}}) error { return defaultDatastore().{{funcName "Delete" .Type}}(ctx, record) }
+func {{funcName "Delete" .Type}}(ctx context.Context, record *models.{{.Type}}) (err error) {
+	ctx, span := startSpan(ctx, "{{funcName "Delete" .Type}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{funcName "Delete" .Type}}(ctx, record)
+}
 func (ds *SqlDatastore) {{funcName "Delete" .Type}}(ctx context.Context, record *models.{{.Type}}) error {
+{{- if $cache}}
+	ds.{{$cache}}.invalidate(record.ID)
+	defer ds.{{$cache}}.invalidate(record.ID)
+{{- end}}
 	return ds.db.Delete(record).Error
 }
 
@@ -315,19 +378,37 @@ func (ds *SqlDatastore) {{funcName "Delete" .Type}}(ctx context.Context, record
 
 {{ $getFn := (print "Get" $type $key.FuncName) -}}
 // {{$getFn}} gets an instance of {{$type}} by its key ({{$key.CallParams}}).
-func {{$getFn}}(ctx context.Context, {{ $key.Args }}) (*models.{{$type}}, error) { return defaultDatastore().{{$getFn}}(ctx, {{$key.CallParams}}) }
+func {{$getFn}}(ctx context.Context, {{ $key.Args }}) (_ *models.{{$type}}, err error) {
+	ctx, span := startSpan(ctx, "{{$getFn}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{$getFn}}(ctx, {{$key.CallParams}})
+}
 func (ds *SqlDatastore) {{$getFn}}(ctx context.Context, {{ $key.Args }}) (*models.{{$type}}, error) {
+{{- if $cache}}
+	cached, version, ok := ds.{{$cache}}.get({{$key.CallParams}})
+	if ok {
+		return cached, nil
+	}
+{{end}}
 	record := models.{{$type}}{}
 	if err := ds.db.{{ $key.WhereClause }}.First(&record).Error; err != nil {
 		return nil, err
 	}
+{{- if $cache}}
+
+	ds.{{$cache}}.put(&record, version)
+{{- end}}
 
 	return &record, nil
 }
 
 {{ $existsFn := (print "Exists" $type $key.FuncName) -}}
 // {{$existsFn}} checks to see if an instance of {{$type}} exists by its key ({{$key.CallParams}}).
-func {{$existsFn}}(ctx context.Context, {{ $key.Args }}) (bool, error) { return defaultDatastore().{{$existsFn}}(ctx, {{$key.CallParams}}) }
+func {{$existsFn}}(ctx context.Context, {{ $key.Args }}) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "{{$existsFn}}")
+	defer func() { endSpan(span, err) }()
+	return {{$store}}.{{$existsFn}}(ctx, {{$key.CallParams}})
+}
 func (ds *SqlDatastore) {{$existsFn}}(ctx context.Context, {{ $key.Args }}) (bool, error) {
 	return recordToExists(ds.{{$getFn}}(ctx, {{ $key.CallParams }}))
 }
@@ -34,6 +34,7 @@ func newInMemoryDatastore(t *testing.T) *SqlDatastore {
 	testDb.CreateTable(models.ServiceInstanceDetails{})
 	testDb.CreateTable(models.ServiceBindingCredentials{})
 	testDb.CreateTable(models.ProvisionRequestDetails{})
+	testDb.CreateTable(models.BindRequestDetails{})
 	testDb.CreateTable(models.TerraformDeployment{})
 	
 	return &SqlDatastore{db: testDb}
@@ -602,6 +603,204 @@ func TestSqlDatastore_ExistsProvisionRequestDetailsById(t *testing.T) {
 }
 
 
+func createBindRequestDetailsInstance() (uint, models.BindRequestDetails) {
+	testPk := uint(42)
+
+	instance := models.BindRequestDetails{}
+	instance.ID = testPk
+	instance.BindingId = "0000-0000-0000"
+	instance.RequestDetails = "{\"some\":[\"json\",\"blob\",\"here\"]}"
+	instance.ServiceInstanceId = "2222-2222-2222"
+
+
+	return testPk, instance
+}
+
+func ensureBindRequestDetailsFieldsMatch(t *testing.T, expected, actual *models.BindRequestDetails) {
+
+	if expected.BindingId != actual.BindingId {
+		t.Errorf("Expected field BindingId to be %#v, got %#v", expected.BindingId, actual.BindingId)
+	}
+
+	if expected.RequestDetails != actual.RequestDetails {
+		t.Errorf("Expected field RequestDetails to be %#v, got %#v", expected.RequestDetails, actual.RequestDetails)
+	}
+
+	if expected.ServiceInstanceId != actual.ServiceInstanceId {
+		t.Errorf("Expected field ServiceInstanceId to be %#v, got %#v", expected.ServiceInstanceId, actual.ServiceInstanceId)
+	}
+
+}
+
+func TestSqlDatastore_BindRequestDetailsDAO(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testPk, instance := createBindRequestDetailsInstance()
+	testCtx := context.Background()
+
+	// on startup, there should be no objects to find or delete
+	exists, err := ds.ExistsBindRequestDetailsById(testCtx, testPk)
+	ensureExistance(t, false, exists, err)
+
+	if _, err := ds.GetBindRequestDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing PK got %v", err)
+	}
+
+	// Should be able to create the item
+	beforeCreation := time.Now()
+	if err := ds.CreateBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+	afterCreation := time.Now()
+
+	// after creation we should be able to get the item
+	ret, err := ds.GetBindRequestDetailsById(testCtx, testPk)
+	if err != nil {
+		t.Errorf("Expected no error trying to get saved item, got: %v", err)
+	}
+
+	if ret.CreatedAt.Before(beforeCreation) || ret.CreatedAt.After(afterCreation) {
+		t.Errorf("Expected creation time to be between  %v and %v got %v", beforeCreation, afterCreation, ret.CreatedAt)
+	}
+
+	if !ret.UpdatedAt.Equal(ret.CreatedAt) {
+		t.Errorf("Expected initial update time to equal creation time, but got update: %v, create: %v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensureBindRequestDetailsFieldsMatch(t, &instance, ret)
+
+	// we should be able to update the item and it will have a new updated time
+	if err := ds.SaveBindRequestDetails(testCtx, ret); err != nil {
+		t.Errorf("Expected no error trying to get update %#v , got: %v", ret, err)
+	}
+
+	if !ret.UpdatedAt.After(ret.CreatedAt) {
+		t.Errorf("Expected update time to be after create time after update, got update: %#v create: %#v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// after deleting the item we should not be able to get it
+	if err := ds.DeleteBindRequestDetailsById(testCtx, testPk); err != nil {
+		t.Errorf("Expected no error when deleting by pk got: %v", err)
+	}
+
+	if _, err := ds.GetBindRequestDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected ErrRecordNotFound after delete but got %v", err)
+	}
+}
+func TestSqlDatastore_GetBindRequestDetailsByBindingId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createBindRequestDetailsInstance()
+	testCtx := context.Background()
+
+	if _, err := ds.GetBindRequestDetailsByBindingId(testCtx, instance.BindingId); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
+	}
+
+	beforeCreation := time.Now()
+	if err := ds.CreateBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+	afterCreation := time.Now()
+
+	// after creation we should be able to get the item
+	ret, err := ds.GetBindRequestDetailsByBindingId(testCtx, instance.BindingId)
+	if err != nil {
+		t.Errorf("Expected no error trying to get saved item, got: %v", err)
+	}
+
+	if ret.CreatedAt.Before(beforeCreation) || ret.CreatedAt.After(afterCreation) {
+		t.Errorf("Expected creation time to be between  %v and %v got %v", beforeCreation, afterCreation, ret.CreatedAt)
+	}
+
+	if !ret.UpdatedAt.Equal(ret.CreatedAt) {
+		t.Errorf("Expected initial update time to equal creation time, but got update: %v, create: %v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensureBindRequestDetailsFieldsMatch(t, &instance, ret)
+}
+
+func TestSqlDatastore_ExistsBindRequestDetailsByBindingId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createBindRequestDetailsInstance()
+	testCtx := context.Background()
+
+	exists, err := ds.ExistsBindRequestDetailsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, false, exists, err)
+
+	if err := ds.CreateBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	exists, err = ds.ExistsBindRequestDetailsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, true, exists, err)
+
+	if err := ds.DeleteBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected no error when deleting by pk got: %v", err)
+	}
+
+	// we should be able to see that it was soft-deleted
+	exists, err = ds.ExistsBindRequestDetailsByBindingId(testCtx, instance.BindingId)
+	ensureExistance(t, false, exists, err)
+}
+func TestSqlDatastore_GetBindRequestDetailsById(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createBindRequestDetailsInstance()
+	testCtx := context.Background()
+
+	if _, err := ds.GetBindRequestDetailsById(testCtx, instance.ID); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get non-existing record got %v", err)
+	}
+
+	beforeCreation := time.Now()
+	if err := ds.CreateBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+	afterCreation := time.Now()
+
+	// after creation we should be able to get the item
+	ret, err := ds.GetBindRequestDetailsById(testCtx, instance.ID)
+	if err != nil {
+		t.Errorf("Expected no error trying to get saved item, got: %v", err)
+	}
+
+	if ret.CreatedAt.Before(beforeCreation) || ret.CreatedAt.After(afterCreation) {
+		t.Errorf("Expected creation time to be between  %v and %v got %v", beforeCreation, afterCreation, ret.CreatedAt)
+	}
+
+	if !ret.UpdatedAt.Equal(ret.CreatedAt) {
+		t.Errorf("Expected initial update time to equal creation time, but got update: %v, create: %v", ret.UpdatedAt, ret.CreatedAt)
+	}
+
+	// Ensure non-gorm fields were deserialized correctly
+	ensureBindRequestDetailsFieldsMatch(t, &instance, ret)
+}
+
+func TestSqlDatastore_ExistsBindRequestDetailsById(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	_, instance := createBindRequestDetailsInstance()
+	testCtx := context.Background()
+
+	exists, err := ds.ExistsBindRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, false, exists, err)
+
+	if err := ds.CreateBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	exists, err = ds.ExistsBindRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, true, exists, err)
+
+	if err := ds.DeleteBindRequestDetails(testCtx, &instance); err != nil {
+		t.Errorf("Expected no error when deleting by pk got: %v", err)
+	}
+
+	// we should be able to see that it was soft-deleted
+	exists, err = ds.ExistsBindRequestDetailsById(testCtx, instance.ID)
+	ensureExistance(t, false, exists, err)
+}
+
+
 func createTerraformDeploymentInstance() (string, models.TerraformDeployment) {
 	testPk := string(42)
 
@@ -22,6 +22,7 @@ import (
 
 	"code.cloudfoundry.org/lager"
 	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
 
 	_ "github.com/jinzhu/gorm/dialects/sqlite"
 )
@@ -29,6 +30,10 @@ import (
 var DbConnection *gorm.DB
 var once sync.Once
 
+// defaultInstanceCache caches the instance records of the default datastore
+// if the operator sized it, see DB_INSTANCE_CACHE_SIZE.
+var defaultInstanceCache *instanceCache
+
 // Instantiates the db connection and runs migrations
 func New(logger lager.Logger) *gorm.DB {
 	once.Do(func() {
@@ -36,6 +41,11 @@ func New(logger lager.Logger) *gorm.DB {
 		if err := RunMigrations(DbConnection); err != nil {
 			panic(fmt.Sprintf("Error migrating database: %s", err.Error()))
 		}
+		scopeToBroker(DbConnection, viper.GetString(dbBrokerIdProp))
+		if err := ConfigureEncryption(); err != nil {
+			panic(fmt.Sprintf("Error configuring encryption: %s", err.Error()))
+		}
+		defaultInstanceCache = newInstanceCache(viper.GetInt(dbInstanceCacheSizeProp))
 	})
 	return DbConnection
 }
@@ -44,9 +54,12 @@ func New(logger lager.Logger) *gorm.DB {
 // instantiated in New(). In the future, all accesses of DbConnection will be
 // done through SqlDatastore and it will become the globally shared instance.
 func defaultDatastore() *SqlDatastore {
-	return &SqlDatastore{db: DbConnection}
+	return &SqlDatastore{db: DbConnection, instances: defaultInstanceCache}
 }
 
 type SqlDatastore struct {
 	db *gorm.DB
+
+	// instances caches instance records read by ID, it may be nil.
+	instances *instanceCache
 }
@@ -0,0 +1,50 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// GetDeletedServiceBindingCredentials gets the soft-deleted bindings that
+// were deleted at or after the given time, most recently deleted first.
+// Soft-deleted bindings are kept for auditing until they're purged.
+func GetDeletedServiceBindingCredentials(ctx context.Context, since time.Time) (_ []models.ServiceBindingCredentials, err error) {
+	ctx, span := startSpan(ctx, "GetDeletedServiceBindingCredentials")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetDeletedServiceBindingCredentials(ctx, since)
+}
+func (ds *SqlDatastore) GetDeletedServiceBindingCredentials(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error) {
+	var bindings []models.ServiceBindingCredentials
+	if err := ds.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at >= ?", since).Order("deleted_at desc, id desc").Find(&bindings).Error; err != nil {
+		return nil, err
+	}
+
+	return bindings, nil
+}
+
+// PurgeDeletedServiceBindingCredentials permanently removes the bindings that
+// were soft-deleted before the given time. Live bindings are never removed.
+func PurgeDeletedServiceBindingCredentials(ctx context.Context, before time.Time) (err error) {
+	ctx, span := startSpan(ctx, "PurgeDeletedServiceBindingCredentials")
+	defer func() { endSpan(span, err) }()
+	return currentStore().PurgeDeletedServiceBindingCredentials(ctx, before)
+}
+func (ds *SqlDatastore) PurgeDeletedServiceBindingCredentials(ctx context.Context, before time.Time) error {
+	return ds.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&models.ServiceBindingCredentials{}).Error
+}
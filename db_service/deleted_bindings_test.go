@@ -0,0 +1,100 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestDeletedServiceBindingCredentials(t *testing.T) {
+	stores := map[string]Store{
+		"sql":    newInMemoryDatastore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	for tn, store := range stores {
+		t.Run(tn, func(t *testing.T) {
+			testCtx := context.Background()
+			for _, bindingID := range []string{"live", "unbound-first", "unbound-second"} {
+				binding := models.ServiceBindingCredentials{ServiceInstanceId: "instance", BindingId: bindingID}
+				if err := store.CreateServiceBindingCredentials(testCtx, &binding); err != nil {
+					t.Fatalf("Expected to be able to create the binding %q, got error: %s", bindingID, err)
+				}
+			}
+
+			start := time.Now()
+			for _, bindingID := range []string{"unbound-first", "unbound-second"} {
+				if err := store.DeleteServiceBindingCredentialsByBindingId(testCtx, bindingID); err != nil {
+					t.Fatalf("Expected to be able to delete the binding %q, got error: %s", bindingID, err)
+				}
+			}
+
+			deleted, err := store.GetDeletedServiceBindingCredentials(testCtx, start.Add(-time.Minute))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if ids := bindingIDs(deleted); !reflect.DeepEqual(ids, []string{"unbound-second", "unbound-first"}) {
+				t.Errorf("Expected the unbound bindings newest first, got %v", ids)
+			}
+
+			deleted, err = store.GetDeletedServiceBindingCredentials(testCtx, time.Now().Add(time.Minute))
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(deleted) != 0 {
+				t.Errorf("Expected no bindings unbound after the window start, got %v", bindingIDs(deleted))
+			}
+
+			// binding the same ID again while the old row is kept
+			rebound := models.ServiceBindingCredentials{ServiceInstanceId: "instance", BindingId: "unbound-first"}
+			if err := store.CreateServiceBindingCredentials(testCtx, &rebound); err != nil {
+				t.Fatalf("Expected to be able to bind the unbound ID again, got error: %s", err)
+			}
+
+			if err := store.PurgeDeletedServiceBindingCredentials(testCtx, time.Now().Add(time.Minute)); err != nil {
+				t.Fatalf("Expected no error purging, got: %v", err)
+			}
+
+			deleted, err = store.GetDeletedServiceBindingCredentials(testCtx, time.Time{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(deleted) != 0 {
+				t.Errorf("Expected the unbound bindings to be purged, got %v", bindingIDs(deleted))
+			}
+
+			live, err := store.GetServiceBindingCredentialsByServiceInstanceId(testCtx, "instance")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if ids := bindingIDs(live); !reflect.DeepEqual(ids, []string{"live", "unbound-first"}) {
+				t.Errorf("Expected the live bindings to be kept, got %v", ids)
+			}
+		})
+	}
+}
+
+func bindingIDs(bindings []models.ServiceBindingCredentials) []string {
+	ids := []string{}
+	for _, binding := range bindings {
+		ids = append(ids, binding.BindingId)
+	}
+	return ids
+}
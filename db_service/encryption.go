@@ -0,0 +1,143 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	dbEncryptionKeysProp      = "db.encryption.keys"
+	dbEncryptionActiveKeyProp = "db.encryption.active_key"
+)
+
+func init() {
+	viper.BindEnv(dbEncryptionKeysProp, "DB_ENCRYPTION_KEYS")
+	viper.BindEnv(dbEncryptionActiveKeyProp, "DB_ENCRYPTION_ACTIVE_KEY")
+}
+
+// ConfigureEncryption sets up the key ring instance parameters are encrypted
+// with from the configured keys. Without keys, parameters are stored in plain
+// text.
+func ConfigureEncryption() error {
+	keys := viper.GetStringMapString(dbEncryptionKeysProp)
+	if len(keys) == 0 {
+		models.SetKeyRing(nil)
+		return nil
+	}
+
+	ring, err := models.NewKeyRing(keys, viper.GetString(dbEncryptionActiveKeyProp))
+	if err != nil {
+		return err
+	}
+
+	models.SetKeyRing(ring)
+	return nil
+}
+
+// ReencryptionResult counts the records re-encrypted with the active key.
+type ReencryptionResult struct {
+	Instances         int64 `json:"instances"`
+	ProvisionRequests int64 `json:"provision_requests"`
+	BindRequests      int64 `json:"bind_requests"`
+}
+
+// ReencryptParameters rewrites the instance parameters that aren't encrypted
+// with the active key, including those stored in plain text, so the old keys
+// can be removed. It's safe to run while the broker serves requests: a record
+// is only rewritten if it didn't change since it was read, and records
+// written concurrently already use the active key.
+func ReencryptParameters(ctx context.Context) (ReencryptionResult, error) {
+	return defaultDatastore().ReencryptParameters(ctx)
+}
+func (ds *SqlDatastore) ReencryptParameters(ctx context.Context) (ReencryptionResult, error) {
+	var result ReencryptionResult
+
+	ring := models.CurrentKeyRing()
+	if ring == nil {
+		return result, errors.New("encryption isn't configured, set DB_ENCRYPTION_KEYS and DB_ENCRYPTION_ACTIVE_KEY")
+	}
+
+	var instances []models.ServiceInstanceDetails
+	if err := ds.db.Unscoped().Select("id, generated_parameters").Where("generated_parameters <> ?", "").Find(&instances).Error; err != nil {
+		return result, err
+	}
+
+	for _, instance := range instances {
+		ds.instances.invalidate(instance.ID)
+		rows, err := ds.reencryptColumn(ring, &models.ServiceInstanceDetails{}, "generated_parameters", instance.ID, instance.GeneratedParameters)
+		if err != nil {
+			return result, fmt.Errorf("re-encrypting the generated parameters of instance %q: %v", instance.ID, err)
+		}
+		ds.instances.invalidate(instance.ID)
+		result.Instances += rows
+	}
+
+	var requests []models.ProvisionRequestDetails
+	if err := ds.db.Unscoped().Select("id, request_details").Where("request_details <> ?", "").Find(&requests).Error; err != nil {
+		return result, err
+	}
+
+	for _, request := range requests {
+		rows, err := ds.reencryptColumn(ring, &models.ProvisionRequestDetails{}, "request_details", request.ID, request.RequestDetails)
+		if err != nil {
+			return result, fmt.Errorf("re-encrypting provision request %d: %v", request.ID, err)
+		}
+		result.ProvisionRequests += rows
+	}
+
+	var bindRequests []models.BindRequestDetails
+	if err := ds.db.Unscoped().Select("id, request_details").Where("request_details <> ?", "").Find(&bindRequests).Error; err != nil {
+		return result, err
+	}
+
+	for _, request := range bindRequests {
+		rows, err := ds.reencryptColumn(ring, &models.BindRequestDetails{}, "request_details", request.ID, request.RequestDetails)
+		if err != nil {
+			return result, fmt.Errorf("re-encrypting bind request %d: %v", request.ID, err)
+		}
+		result.BindRequests += rows
+	}
+
+	return result, nil
+}
+
+// reencryptColumn rewrites the value of the column with the active key unless
+// it already uses it or changed since it was read.
+func (ds *SqlDatastore) reencryptColumn(ring *models.KeyRing, model interface{}, column string, id interface{}, value string) (int64, error) {
+	if !ring.NeedsRotation(value) {
+		return 0, nil
+	}
+
+	plaintext, err := ring.Decrypt(value)
+	if err != nil {
+		return 0, err
+	}
+
+	encrypted, err := ring.Encrypt(plaintext)
+	if err != nil {
+		return 0, err
+	}
+
+	update := ds.db.Unscoped().Model(model).
+		Where(fmt.Sprintf("id = ? AND %s = ?", column), id, value).
+		UpdateColumn(column, encrypted)
+	return update.RowsAffected, update.Error
+}
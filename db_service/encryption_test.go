@@ -0,0 +1,220 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+func TestKeyRing(t *testing.T) {
+	old, err := models.NewKeyRing(map[string]string{"k1": "old-secret"}, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := models.NewKeyRing(map[string]string{"k1": "old-secret", "k2": "new-secret"}, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := old.Encrypt(`{"password":"hunter2"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(encrypted, "encrypted:k1:") || strings.Contains(encrypted, "hunter2") {
+		t.Errorf("expected the value to be encrypted with k1, got %q", encrypted)
+	}
+
+	if plaintext, err := rotated.Decrypt(encrypted); err != nil || plaintext != `{"password":"hunter2"}` {
+		t.Errorf("expected the rotated ring to decrypt old values, got %q, %v", plaintext, err)
+	}
+
+	if !rotated.NeedsRotation(encrypted) || old.NeedsRotation(encrypted) {
+		t.Error("expected only values encrypted with an inactive key to need rotation")
+	}
+
+	if !rotated.NeedsRotation(`{"plain":"text"}`) || rotated.NeedsRotation("") {
+		t.Error("expected plain text values to need rotation and empty ones not to")
+	}
+
+	if plaintext, err := rotated.Decrypt(`{"plain":"text"}`); err != nil || plaintext != `{"plain":"text"}` {
+		t.Errorf("expected plain text to be returned as is, got %q, %v", plaintext, err)
+	}
+
+	newOnly, err := models.NewKeyRing(map[string]string{"k2": "new-secret"}, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newOnly.Decrypt(encrypted); err == nil || !strings.Contains(err.Error(), `unknown key "k1"`) {
+		t.Errorf("expected an unknown key error, got %v", err)
+	}
+
+	wrongSecret, err := models.NewKeyRing(map[string]string{"k1": "other-secret"}, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := wrongSecret.Decrypt(encrypted); err == nil {
+		t.Error("expected decrypting with a different secret to fail")
+	}
+
+	var unconfigured *models.KeyRing
+	if _, err := unconfigured.Decrypt(encrypted); err == nil {
+		t.Error("expected decrypting without a key ring to fail")
+	}
+
+	if _, err := models.NewKeyRing(map[string]string{"k1": "secret"}, "k2"); err == nil {
+		t.Error("expected an unknown active key to fail")
+	}
+
+	if _, err := models.NewKeyRing(map[string]string{"k:1": "secret"}, "k:1"); err == nil {
+		t.Error("expected a key ID with a colon to fail")
+	}
+}
+
+func TestConfigureEncryption(t *testing.T) {
+	defer viper.Reset()
+	defer models.SetKeyRing(nil)
+
+	viper.Set(dbEncryptionKeysProp, `{"k1":"secret","k2":"other"}`)
+	viper.Set(dbEncryptionActiveKeyProp, "k2")
+	if err := ConfigureEncryption(); err != nil {
+		t.Fatal(err)
+	}
+
+	if ring := models.CurrentKeyRing(); ring == nil || ring.ActiveKeyID() != "k2" {
+		t.Errorf("expected a key ring with k2 active, got %v", ring)
+	}
+
+	viper.Set(dbEncryptionActiveKeyProp, "k3")
+	if err := ConfigureEncryption(); err == nil {
+		t.Error("expected an unknown active key to fail")
+	}
+
+	viper.Set(dbEncryptionKeysProp, "")
+	if err := ConfigureEncryption(); err != nil || models.CurrentKeyRing() != nil {
+		t.Errorf("expected no keys to disable encryption, got %v", err)
+	}
+}
+
+func TestSqlDatastore_ReencryptParameters(t *testing.T) {
+	defer models.SetKeyRing(nil)
+
+	ds := newInMemoryDatastore(t)
+	testCtx := context.Background()
+	generated := map[string]string{"admin_password": "hunter2"}
+
+	if _, err := ds.ReencryptParameters(testCtx); err == nil {
+		t.Error("expected re-encrypting without encryption to fail")
+	}
+
+	// written before encryption was enabled
+	_, plain := createServiceInstanceDetailsInstance()
+	plain.ID = "plain"
+	if err := plain.SetGeneratedParameters(generated); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.CreateServiceInstanceDetails(testCtx, &plain); err != nil {
+		t.Fatal(err)
+	}
+
+	old, err := models.NewKeyRing(map[string]string{"k1": "old-secret"}, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models.SetKeyRing(old)
+
+	_, instance := createServiceInstanceDetailsInstance()
+	instance.ID = "encrypted"
+	if err := instance.SetGeneratedParameters(generated); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatal(err)
+	}
+
+	_, request := createProvisionRequestDetailsInstance()
+	request.ID = 0
+	if err := request.SetRequestDetails(json.RawMessage(`{"size":"large"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.CreateProvisionRequestDetails(testCtx, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := models.NewKeyRing(map[string]string{"k1": "old-secret", "k2": "new-secret"}, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models.SetKeyRing(rotated)
+
+	result, err := ds.ReencryptParameters(testCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (ReencryptionResult{Instances: 2, ProvisionRequests: 1}); result != expected {
+		t.Errorf("expected %v, got %v", expected, result)
+	}
+
+	// only the new key remains
+	newOnly, err := models.NewKeyRing(map[string]string{"k2": "new-secret"}, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models.SetKeyRing(newOnly)
+
+	for _, id := range []string{"plain", "encrypted"} {
+		record, err := ds.GetServiceInstanceDetailsById(testCtx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(record.GeneratedParameters, "encrypted:k2:") {
+			t.Errorf("expected instance %q to be encrypted with k2, got %q", id, record.GeneratedParameters)
+		}
+
+		actual, err := record.GetGeneratedParameters()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, generated) {
+			t.Errorf("expected instance %q to keep its parameters, got %v", id, actual)
+		}
+	}
+
+	stored, err := ds.GetProvisionRequestDetailsById(testCtx, request.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details, err := stored.GetRequestDetails(); err != nil || string(details) != `{"size":"large"}` {
+		t.Errorf("expected the request details to be re-encrypted, got %s, %v", details, err)
+	}
+
+	result, err = ds.ReencryptParameters(testCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (ReencryptionResult{}) {
+		t.Errorf("expected nothing left to re-encrypt, got %v", result)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// IDMapping maps service and plan IDs that were renamed in a brokerpak from
+// their old to their new value.
+type IDMapping struct {
+	Services map[string]string `json:"services"`
+	Plans    map[string]string `json:"plans"`
+}
+
+// IDMigrationChange reports how many rows of a table column use an old ID.
+type IDMigrationChange struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	OldId  string `json:"old_id"`
+	NewId  string `json:"new_id"`
+	Rows   int64  `json:"rows"`
+}
+
+// MigrateIds rewrites the service and plan IDs of instances and bindings
+// according to the mapping in a single transaction. If dryRun is true, the
+// affected rows are counted but nothing is changed.
+func MigrateIds(ctx context.Context, mapping IDMapping, dryRun bool) ([]IDMigrationChange, error) {
+	return defaultDatastore().MigrateIds(ctx, mapping, dryRun)
+}
+func (ds *SqlDatastore) MigrateIds(ctx context.Context, mapping IDMapping, dryRun bool) ([]IDMigrationChange, error) {
+	targets := []struct {
+		model   interface{}
+		table   string
+		column  string
+		mapping map[string]string
+	}{
+		{&models.ServiceInstanceDetails{}, "service_instance_details", "service_id", mapping.Services},
+		{&models.ServiceInstanceDetails{}, "service_instance_details", "plan_id", mapping.Plans},
+		{&models.ServiceBindingCredentials{}, "service_binding_credentials", "service_id", mapping.Services},
+	}
+
+	tx := ds.db.Begin()
+
+	var changes []IDMigrationChange
+	for _, target := range targets {
+		for _, oldId := range sortedKeys(target.mapping) {
+			change := IDMigrationChange{
+				Table:  target.table,
+				Column: target.column,
+				OldId:  oldId,
+				NewId:  target.mapping[oldId],
+			}
+
+			var err error
+			if change.Rows, err = migrateColumn(tx.Model(target.model), target.column, oldId, change.NewId, dryRun); err != nil {
+				tx.Rollback()
+				return nil, fmt.Errorf("updating %s.%s from %q to %q: %v", change.Table, change.Column, oldId, change.NewId, err)
+			}
+
+			changes = append(changes, change)
+		}
+	}
+
+	if dryRun {
+		return changes, tx.Rollback().Error
+	}
+
+	ds.instances.purge()
+	defer ds.instances.purge()
+	return changes, tx.Commit().Error
+}
+
+func migrateColumn(scope *gorm.DB, column, oldId, newId string, dryRun bool) (int64, error) {
+	scope = scope.Where(fmt.Sprintf("%s = ?", column), oldId)
+
+	if dryRun {
+		var count int64
+		err := scope.Count(&count).Error
+		return count, err
+	}
+
+	result := scope.Update(column, newId)
+	return result.RowsAffected, result.Error
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSqlDatastore_MigrateIds(t *testing.T) {
+	mapping := IDMapping{
+		Services: map[string]string{"123-456-7890": "new-service"},
+		Plans:    map[string]string{"planid": "new-plan", "unused-plan": "other-plan"},
+	}
+
+	expectedChanges := []IDMigrationChange{
+		{Table: "service_instance_details", Column: "service_id", OldId: "123-456-7890", NewId: "new-service", Rows: 1},
+		{Table: "service_instance_details", Column: "plan_id", OldId: "planid", NewId: "new-plan", Rows: 1},
+		{Table: "service_instance_details", Column: "plan_id", OldId: "unused-plan", NewId: "other-plan", Rows: 0},
+		{Table: "service_binding_credentials", Column: "service_id", OldId: "123-456-7890", NewId: "new-service", Rows: 1},
+	}
+
+	cases := map[string]struct {
+		DryRun            bool
+		ExpectedServiceId string
+		ExpectedPlanId    string
+	}{
+		"dry run": {
+			DryRun:            true,
+			ExpectedServiceId: "123-456-7890",
+			ExpectedPlanId:    "planid",
+		},
+		"migrate": {
+			DryRun:            false,
+			ExpectedServiceId: "new-service",
+			ExpectedPlanId:    "new-plan",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			ds := newInMemoryDatastore(t)
+			testCtx := context.Background()
+
+			instancePk, instance := createServiceInstanceDetailsInstance()
+			if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+				t.Fatal(err)
+			}
+
+			bindingPk, binding := createServiceBindingCredentialsInstance()
+			binding.ServiceId = instance.ServiceId
+			if err := ds.CreateServiceBindingCredentials(testCtx, &binding); err != nil {
+				t.Fatal(err)
+			}
+
+			changes, err := ds.MigrateIds(testCtx, mapping, tc.DryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(changes, expectedChanges) {
+				t.Errorf("Expected changes %v, got %v", expectedChanges, changes)
+			}
+
+			gotInstance, err := ds.GetServiceInstanceDetailsById(testCtx, instancePk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotInstance.ServiceId != tc.ExpectedServiceId || gotInstance.PlanId != tc.ExpectedPlanId {
+				t.Errorf("Expected instance service/plan %s/%s, got %s/%s", tc.ExpectedServiceId, tc.ExpectedPlanId, gotInstance.ServiceId, gotInstance.PlanId)
+			}
+
+			gotBinding, err := ds.GetServiceBindingCredentialsById(testCtx, bindingPk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotBinding.ServiceId != tc.ExpectedServiceId {
+				t.Errorf("Expected binding service %s, got %s", tc.ExpectedServiceId, gotBinding.ServiceId)
+			}
+		})
+	}
+}
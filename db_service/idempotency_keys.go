@@ -0,0 +1,81 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// CreateIdempotencyKey records a request that just started. It fails if the
+// key already exists, which guards against concurrent duplicates.
+func CreateIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) (err error) {
+	ctx, span := startSpan(ctx, "CreateIdempotencyKey")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateIdempotencyKey(ctx, object)
+}
+func (ds *SqlDatastore) CreateIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error {
+	return ds.db.Create(object).Error
+}
+
+// SaveIdempotencyKey updates a recorded request, e.g. with its response.
+func SaveIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) (err error) {
+	ctx, span := startSpan(ctx, "SaveIdempotencyKey")
+	defer func() { endSpan(span, err) }()
+	return currentStore().SaveIdempotencyKey(ctx, object)
+}
+func (ds *SqlDatastore) SaveIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error {
+	return ds.db.Save(object).Error
+}
+
+// GetIdempotencyKeyByRequestIdentity gets the request recorded with the
+// identity.
+func GetIdempotencyKeyByRequestIdentity(ctx context.Context, requestIdentity string) (_ *models.IdempotencyKey, err error) {
+	ctx, span := startSpan(ctx, "GetIdempotencyKeyByRequestIdentity")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetIdempotencyKeyByRequestIdentity(ctx, requestIdentity)
+}
+func (ds *SqlDatastore) GetIdempotencyKeyByRequestIdentity(ctx context.Context, requestIdentity string) (*models.IdempotencyKey, error) {
+	record := models.IdempotencyKey{}
+	if err := ds.db.Where("request_identity = ?", requestIdentity).First(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// DeleteIdempotencyKey removes the request recorded with the identity so it
+// can be retried.
+func DeleteIdempotencyKey(ctx context.Context, requestIdentity string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteIdempotencyKey")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteIdempotencyKey(ctx, requestIdentity)
+}
+func (ds *SqlDatastore) DeleteIdempotencyKey(ctx context.Context, requestIdentity string) error {
+	return ds.db.Unscoped().Where("request_identity = ?", requestIdentity).Delete(&models.IdempotencyKey{}).Error
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys that expired before the given
+// time. Keys of running requests never expire.
+func DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (err error) {
+	ctx, span := startSpan(ctx, "DeleteExpiredIdempotencyKeys")
+	defer func() { endSpan(span, err) }()
+	return currentStore().DeleteExpiredIdempotencyKeys(ctx, now)
+}
+func (ds *SqlDatastore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	return ds.db.Unscoped().Where("expires_at < ?", now).Delete(&models.IdempotencyKey{}).Error
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_IdempotencyKeys(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.IdempotencyKey{})
+	testCtx := context.Background()
+
+	running := models.IdempotencyKey{RequestIdentity: "running", ServiceInstanceId: "instance", State: "in progress"}
+	if err := ds.CreateIdempotencyKey(testCtx, &running); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", running, err)
+	}
+
+	duplicate := models.IdempotencyKey{RequestIdentity: "running", ServiceInstanceId: "instance"}
+	if err := ds.CreateIdempotencyKey(testCtx, &duplicate); err == nil {
+		t.Errorf("Expected creating a duplicate key to fail")
+	}
+
+	expiresAt := time.Now().Add(-time.Minute)
+	expired := models.IdempotencyKey{RequestIdentity: "expired", ServiceInstanceId: "instance", State: "succeeded", ExpiresAt: &expiresAt}
+	if err := ds.CreateIdempotencyKey(testCtx, &expired); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", expired, err)
+	}
+
+	if err := ds.DeleteExpiredIdempotencyKeys(testCtx, time.Now()); err != nil {
+		t.Fatalf("Expected to be able to delete expired keys, got error: %s", err)
+	}
+
+	if _, err := ds.GetIdempotencyKeyByRequestIdentity(testCtx, "expired"); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected the expired key to be deleted, got %v", err)
+	}
+
+	record, err := ds.GetIdempotencyKeyByRequestIdentity(testCtx, "running")
+	if err != nil {
+		t.Fatalf("Expected running keys to never expire, got error: %s", err)
+	}
+
+	record.State = "succeeded"
+	record.Response = `{"async":true}`
+	if err := ds.SaveIdempotencyKey(testCtx, record); err != nil {
+		t.Fatalf("Expected to be able to save the key, got error: %s", err)
+	}
+
+	if saved, err := ds.GetIdempotencyKeyByRequestIdentity(testCtx, "running"); err != nil || saved.Response != `{"async":true}` {
+		t.Errorf("Expected the response to be saved, got %#v, %v", saved, err)
+	}
+
+	if err := ds.DeleteIdempotencyKey(testCtx, "running"); err != nil {
+		t.Fatalf("Expected to be able to delete the key, got error: %s", err)
+	}
+
+	retry := models.IdempotencyKey{RequestIdentity: "running", ServiceInstanceId: "instance"}
+	if err := ds.CreateIdempotencyKey(testCtx, &retry); err != nil {
+		t.Errorf("Expected deleted keys to be reusable, got error: %s", err)
+	}
+}
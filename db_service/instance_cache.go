@@ -0,0 +1,135 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// instanceCache is a least recently used cache of instance records, shared by
+// the default datastore to take load off the database while platforms poll
+// LastOperation. A nil cache caches nothing.
+//
+// Every write to an instance invalidates its entry before and after writing,
+// so the old record isn't served while the write is in progress. Writes also
+// bump the cache's version, so a lookup that read the database before a
+// concurrent write finished never stores the record it read.
+type instanceCache struct {
+	mu       sync.Mutex
+	capacity int
+	version  uint64
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type instanceCacheEntry struct {
+	id     string
+	record models.ServiceInstanceDetails
+}
+
+// newInstanceCache creates a cache holding up to capacity records, or nil if
+// the capacity isn't positive.
+func newInstanceCache(capacity int) *instanceCache {
+	if capacity <= 0 {
+		return nil
+	}
+
+	return &instanceCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// get returns a copy of the cached record and true, or the current version
+// and false if the record isn't cached. The version must be passed to put.
+func (c *instanceCache) get(id string) (*models.ServiceInstanceDetails, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return nil, c.version, false
+	}
+
+	c.order.MoveToFront(elem)
+	record := elem.Value.(*instanceCacheEntry).record
+	return &record, c.version, true
+}
+
+// put caches a copy of the record read from the database, unless the cache
+// was written to since get returned the version.
+func (c *instanceCache) put(record *models.ServiceInstanceDetails, version uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if version != c.version {
+		return
+	}
+
+	if elem, ok := c.entries[record.ID]; ok {
+		elem.Value.(*instanceCacheEntry).record = *record
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[record.ID] = c.order.PushFront(&instanceCacheEntry{id: record.ID, record: *record})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*instanceCacheEntry).id)
+	}
+}
+
+// invalidate drops the record with the given ID.
+func (c *instanceCache) invalidate(id string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	if elem, ok := c.entries[id]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, id)
+	}
+}
+
+// purge drops all records, e.g. after bulk updates.
+func (c *instanceCache) purge() {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+}
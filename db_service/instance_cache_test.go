@@ -0,0 +1,220 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestInstanceCache(t *testing.T) {
+	record := func(id, name string) *models.ServiceInstanceDetails {
+		return &models.ServiceInstanceDetails{ID: id, Name: name}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		cache := newInstanceCache(0)
+		if cache != nil {
+			t.Fatalf("Expected no cache for a zero capacity, got %v", cache)
+		}
+
+		cache.put(record("a", "a"), 0)
+		if _, _, ok := cache.get("a"); ok {
+			t.Errorf("Expected a nil cache to cache nothing")
+		}
+	})
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		cache := newInstanceCache(2)
+		for _, id := range []string{"a", "b"} {
+			_, version, _ := cache.get(id)
+			cache.put(record(id, id), version)
+		}
+
+		cache.get("a")
+		_, version, _ := cache.get("c")
+		cache.put(record("c", "c"), version)
+
+		for id, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+			if _, _, ok := cache.get(id); ok != expected {
+				t.Errorf("Expected %q to be cached: %v, got %v", id, expected, ok)
+			}
+		}
+	})
+
+	t.Run("returns copies", func(t *testing.T) {
+		cache := newInstanceCache(1)
+		_, version, _ := cache.get("a")
+		cache.put(record("a", "original"), version)
+
+		cached, _, _ := cache.get("a")
+		cached.Name = "changed"
+
+		if cached, _, _ := cache.get("a"); cached.Name != "original" {
+			t.Errorf("Expected the cached record to be unchanged, got %q", cached.Name)
+		}
+	})
+
+	t.Run("ignores reads racing writes", func(t *testing.T) {
+		cache := newInstanceCache(1)
+		_, version, _ := cache.get("a")
+		cache.invalidate("a")
+		cache.put(record("a", "stale"), version)
+
+		if _, _, ok := cache.get("a"); ok {
+			t.Errorf("Expected a record read before a write not to be cached")
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		cache := newInstanceCache(1)
+		_, version, _ := cache.get("a")
+		cache.put(record("a", "a"), version)
+		cache.purge()
+
+		if _, _, ok := cache.get("a"); ok {
+			t.Errorf("Expected purged records not to be cached")
+		}
+	})
+}
+
+func TestSqlDatastore_CachedServiceInstanceDetails(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.instances = newInstanceCache(10)
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != nil {
+		t.Fatalf("Expected no error trying to get the item, got: %v", err)
+	}
+	if _, _, ok := ds.instances.get(testPk); !ok {
+		t.Fatalf("Expected the item to be cached")
+	}
+
+	instance.OperationType = models.UpdateOperationType
+	if err := ds.SaveServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to save the item, got error: %s", err)
+	}
+
+	ret, err := ds.GetServiceInstanceDetailsById(testCtx, testPk)
+	if err != nil {
+		t.Fatalf("Expected no error trying to get the item, got: %v", err)
+	}
+	if ret.OperationType != models.UpdateOperationType {
+		t.Errorf("Expected the saved item to be returned, got operation type %q", ret.OperationType)
+	}
+
+	if err := ds.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
+		t.Fatalf("Expected to be able to delete the item, got error: %s", err)
+	}
+
+	if _, err := ds.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get a deleted item, got %v", err)
+	}
+}
+
+func TestInstanceCache_concurrentAccess(t *testing.T) {
+	cache := newInstanceCache(4)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := fmt.Sprintf("instance-%d", i%6)
+			for j := 0; j < 100; j++ {
+				if _, version, ok := cache.get(id); !ok {
+					cache.put(&models.ServiceInstanceDetails{ID: id}, version)
+				}
+				if j%10 == 0 {
+					cache.invalidate(id)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if cache.order.Len() > 4 || len(cache.entries) != cache.order.Len() {
+		t.Errorf("Expected at most 4 consistent entries, got %d in the list and %d in the index", cache.order.Len(), len(cache.entries))
+	}
+}
+
+func TestSqlDatastore_CachedServiceInstanceDetails_concurrentReadWrite(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	// every connection to an in-memory database opens a separate database
+	ds.db.DB().SetMaxOpenConns(1)
+	ds.instances = newInstanceCache(10)
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+
+	instance.OperationId = "0"
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	var saved int64
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				expected := atomic.LoadInt64(&saved)
+				ret, err := ds.GetServiceInstanceDetailsById(testCtx, testPk)
+				if err != nil {
+					t.Errorf("Expected no error trying to get the item, got: %v", err)
+					return
+				}
+
+				var got int64
+				fmt.Sscan(ret.OperationId, &got)
+				if got < expected {
+					t.Errorf("Expected a read after save %d to return it, got save %d", expected, got)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := int64(1); i <= 200; i++ {
+		instance.OperationId = fmt.Sprint(i)
+		if err := ds.SaveServiceInstanceDetails(testCtx, &instance); err != nil {
+			t.Fatalf("Expected to be able to save the item, got error: %s", err)
+		}
+		atomic.StoreInt64(&saved, i)
+	}
+
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName checks to see if an
+// instance with the given user chosen name exists in the space.
+func ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx context.Context, spaceGuid, instanceName string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx, spaceGuid, instanceName)
+}
+func (ds *SqlDatastore) ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx context.Context, spaceGuid, instanceName string) (bool, error) {
+	var count int
+	if err := ds.db.Model(&models.ServiceInstanceDetails{}).Where("space_guid = ? AND instance_name = ?", spaceGuid, instanceName).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
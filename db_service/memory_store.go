@@ -0,0 +1,625 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// MemoryStore is a Store keeping its records in memory. The records are lost
+// when the broker stops, so it's meant for tests and throwaway brokers.
+//
+// Records are copied in and out so callers can't modify the stored state
+// without saving it.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	instances       map[string]models.ServiceInstanceDetails
+	bindings        map[uint]models.ServiceBindingCredentials
+	deletedBindings map[uint]models.ServiceBindingCredentials
+	provisions      map[uint]models.ProvisionRequestDetails
+	bindRequests    map[uint]models.BindRequestDetails
+	history         map[uint]models.OperationHistory
+	idempotencyKeys map[string]models.IdempotencyKey
+
+	lastId uint
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		instances:       make(map[string]models.ServiceInstanceDetails),
+		bindings:        make(map[uint]models.ServiceBindingCredentials),
+		deletedBindings: make(map[uint]models.ServiceBindingCredentials),
+		provisions:      make(map[uint]models.ProvisionRequestDetails),
+		bindRequests:    make(map[uint]models.BindRequestDetails),
+		history:         make(map[uint]models.OperationHistory),
+		idempotencyKeys: make(map[string]models.IdempotencyKey),
+	}
+}
+
+// nextModel stamps a new gorm.Model with an ID and its timestamps, keeping
+// IDs set by the caller.
+func (ms *MemoryStore) nextModel(model *gorm.Model) {
+	if model.ID == 0 {
+		ms.lastId++
+		model.ID = ms.lastId
+	} else if model.ID > ms.lastId {
+		ms.lastId = model.ID
+	}
+
+	now := time.Now()
+	if model.CreatedAt.IsZero() {
+		model.CreatedAt = now
+	}
+	model.UpdatedAt = now
+}
+
+func alreadyExists(kind string, key interface{}) error {
+	return fmt.Errorf("%s %v already exists", kind, key)
+}
+
+func (ms *MemoryStore) CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.instances[object.ID]; ok {
+		return alreadyExists("service instance", object.ID)
+	}
+
+	now := time.Now()
+	object.CreatedAt = now
+	object.UpdatedAt = now
+	ms.instances[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	now := time.Now()
+	if object.CreatedAt.IsZero() {
+		object.CreatedAt = now
+	}
+	object.UpdatedAt = now
+	ms.instances[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) DeleteServiceInstanceDetailsById(ctx context.Context, id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.instances, id)
+	return nil
+}
+
+func (ms *MemoryStore) DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.instances, record.ID)
+	return nil
+}
+
+func (ms *MemoryStore) GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.instances[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.instances[id]
+	return ok, nil
+}
+
+func (ms *MemoryStore) ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx context.Context, spaceGuid, instanceName string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, record := range ms.instances {
+		if record.SpaceGuid == spaceGuid && record.InstanceName == instanceName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (ms *MemoryStore) ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx context.Context, spaceGuid, serviceId string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, record := range ms.instances {
+		if record.SpaceGuid == spaceGuid && record.ServiceId == serviceId {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (ms *MemoryStore) CountServiceInstancesByPlan(ctx context.Context, planId string) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	count := 0
+	for _, record := range ms.instances {
+		if record.PlanId == planId {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (ms *MemoryStore) GetServiceInstanceDetailsWithOperationId(ctx context.Context) ([]models.ServiceInstanceDetails, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var instances []models.ServiceInstanceDetails
+	for _, record := range ms.instances {
+		if record.OperationId != "" {
+			instances = append(instances, record)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+func (ms *MemoryStore) CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.bindings[object.ID]; ok && object.ID != 0 {
+		return alreadyExists("service binding", object.ID)
+	}
+
+	ms.nextModel(&object.Model)
+	ms.bindings[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.nextModel(&object.Model)
+	ms.bindings[object.ID] = *object
+	return nil
+}
+
+// findBinding returns the oldest binding matching the predicate.
+func (ms *MemoryStore) findBinding(match func(models.ServiceBindingCredentials) bool) (models.ServiceBindingCredentials, bool) {
+	var found models.ServiceBindingCredentials
+	ok := false
+	for _, record := range ms.bindings {
+		if match(record) && (!ok || record.ID < found.ID) {
+			found = record
+			ok = true
+		}
+	}
+
+	return found, ok
+}
+
+// deleteBinding soft-deletes the binding, keeping it until it's purged.
+func (ms *MemoryStore) deleteBinding(id uint) {
+	record, ok := ms.bindings[id]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	record.DeletedAt = &now
+	ms.deletedBindings[id] = record
+	delete(ms.bindings, id)
+}
+
+func (ms *MemoryStore) deleteBindings(match func(models.ServiceBindingCredentials) bool) {
+	for id, record := range ms.bindings {
+		if match(record) {
+			ms.deleteBinding(id)
+		}
+	}
+}
+
+func (ms *MemoryStore) DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deleteBindings(func(record models.ServiceBindingCredentials) bool {
+		return record.ServiceInstanceId == serviceInstanceId && record.BindingId == bindingId
+	})
+	return nil
+}
+
+func (ms *MemoryStore) DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deleteBindings(func(record models.ServiceBindingCredentials) bool {
+		return record.BindingId == bindingId
+	})
+	return nil
+}
+
+func (ms *MemoryStore) DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deleteBinding(id)
+	return nil
+}
+
+func (ms *MemoryStore) DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.deleteBinding(record.ID)
+	return nil
+}
+
+func (ms *MemoryStore) GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.findBinding(func(record models.ServiceBindingCredentials) bool {
+		return record.ServiceInstanceId == serviceInstanceId && record.BindingId == bindingId
+	})
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error) {
+	_, err := ms.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx, serviceInstanceId, bindingId)
+	return err == nil, nil
+}
+
+func (ms *MemoryStore) GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.findBinding(func(record models.ServiceBindingCredentials) bool {
+		return record.BindingId == bindingId
+	})
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error) {
+	_, err := ms.GetServiceBindingCredentialsByBindingId(ctx, bindingId)
+	return err == nil, nil
+}
+
+func (ms *MemoryStore) GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.bindings[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.bindings[id]
+	return ok, nil
+}
+
+func (ms *MemoryStore) GetServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.ServiceBindingCredentials, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var bindings []models.ServiceBindingCredentials
+	for _, record := range ms.bindings {
+		if record.ServiceInstanceId == serviceInstanceId {
+			bindings = append(bindings, record)
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool { return bindings[i].ID < bindings[j].ID })
+	return bindings, nil
+}
+
+func (ms *MemoryStore) CountServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) (int, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	count := 0
+	for _, record := range ms.bindings {
+		if record.ServiceInstanceId == serviceInstanceId {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (ms *MemoryStore) GetDeletedServiceBindingCredentials(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var bindings []models.ServiceBindingCredentials
+	for _, record := range ms.deletedBindings {
+		if !record.DeletedAt.Before(since) {
+			bindings = append(bindings, record)
+		}
+	}
+
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].DeletedAt.Equal(*bindings[j].DeletedAt) {
+			return bindings[i].ID > bindings[j].ID
+		}
+		return bindings[i].DeletedAt.After(*bindings[j].DeletedAt)
+	})
+	return bindings, nil
+}
+
+func (ms *MemoryStore) PurgeDeletedServiceBindingCredentials(ctx context.Context, before time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for id, record := range ms.deletedBindings {
+		if record.DeletedAt.Before(before) {
+			delete(ms.deletedBindings, id)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.provisions[object.ID]; ok && object.ID != 0 {
+		return alreadyExists("provision request", object.ID)
+	}
+
+	ms.nextModel(&object.Model)
+	ms.provisions[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.nextModel(&object.Model)
+	ms.provisions[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.provisions, id)
+	return nil
+}
+
+func (ms *MemoryStore) DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.provisions, record.ID)
+	return nil
+}
+
+func (ms *MemoryStore) GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.provisions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.provisions[id]
+	return ok, nil
+}
+
+func (ms *MemoryStore) CreateBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.bindRequests[object.ID]; ok && object.ID != 0 {
+		return alreadyExists("bind request", object.ID)
+	}
+
+	ms.nextModel(&object.Model)
+	ms.bindRequests[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) SaveBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.nextModel(&object.Model)
+	ms.bindRequests[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) DeleteBindRequestDetailsByBindingId(ctx context.Context, bindingId string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for id, record := range ms.bindRequests {
+		if record.BindingId == bindingId {
+			delete(ms.bindRequests, id)
+		}
+	}
+
+	return nil
+}
+
+func (ms *MemoryStore) DeleteBindRequestDetailsById(ctx context.Context, id uint) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.bindRequests, id)
+	return nil
+}
+
+func (ms *MemoryStore) DeleteBindRequestDetails(ctx context.Context, record *models.BindRequestDetails) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.bindRequests, record.ID)
+	return nil
+}
+
+func (ms *MemoryStore) GetBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (*models.BindRequestDetails, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var found *models.BindRequestDetails
+	for _, record := range ms.bindRequests {
+		if record.BindingId == bindingId && (found == nil || record.ID < found.ID) {
+			record := record
+			found = &record
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return found, nil
+}
+
+func (ms *MemoryStore) ExistsBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (bool, error) {
+	_, err := ms.GetBindRequestDetailsByBindingId(ctx, bindingId)
+	return err == nil, nil
+}
+
+func (ms *MemoryStore) GetBindRequestDetailsById(ctx context.Context, id uint) (*models.BindRequestDetails, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.bindRequests[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) ExistsBindRequestDetailsById(ctx context.Context, id uint) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	_, ok := ms.bindRequests[id]
+	return ok, nil
+}
+
+func (ms *MemoryStore) CreateOperationHistory(ctx context.Context, object *models.OperationHistory) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.nextModel(&object.Model)
+	ms.history[object.ID] = *object
+	return nil
+}
+
+func (ms *MemoryStore) FinishOperationHistory(ctx context.Context, serviceInstanceId, state, errMessage string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var latest *models.OperationHistory
+	for _, record := range ms.history {
+		if record.ServiceInstanceId != serviceInstanceId || record.FinishedAt != nil {
+			continue
+		}
+		if latest == nil || record.ID > latest.ID {
+			record := record
+			latest = &record
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+
+	now := time.Now()
+	latest.State = state
+	latest.FinishedAt = &now
+	latest.Error = errMessage
+	ms.nextModel(&latest.Model)
+	ms.history[latest.ID] = *latest
+	return nil
+}
+
+func (ms *MemoryStore) GetOperationHistoryByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.OperationHistory, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	var history []models.OperationHistory
+	for _, record := range ms.history {
+		if record.ServiceInstanceId == serviceInstanceId {
+			history = append(history, record)
+		}
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].ID < history[j].ID })
+	return history, nil
+}
+
+func (ms *MemoryStore) CreateIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if _, ok := ms.idempotencyKeys[object.RequestIdentity]; ok {
+		return alreadyExists("idempotency key", object.RequestIdentity)
+	}
+
+	ms.nextModel(&object.Model)
+	ms.idempotencyKeys[object.RequestIdentity] = *object
+	return nil
+}
+
+func (ms *MemoryStore) SaveIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.nextModel(&object.Model)
+	ms.idempotencyKeys[object.RequestIdentity] = *object
+	return nil
+}
+
+func (ms *MemoryStore) GetIdempotencyKeyByRequestIdentity(ctx context.Context, requestIdentity string) (*models.IdempotencyKey, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	record, ok := ms.idempotencyKeys[requestIdentity]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &record, nil
+}
+
+func (ms *MemoryStore) DeleteIdempotencyKey(ctx context.Context, requestIdentity string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.idempotencyKeys, requestIdentity)
+	return nil
+}
+
+func (ms *MemoryStore) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for requestIdentity, record := range ms.idempotencyKeys {
+		if record.ExpiresAt != nil && record.ExpiresAt.Before(now) {
+			delete(ms.idempotencyKeys, requestIdentity)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestMemoryStore_ServiceInstanceDetails(t *testing.T) {
+	ms := NewMemoryStore()
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+
+	if _, err := ms.GetServiceInstanceDetailsById(testCtx, testPk); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get a missing record, got %v", err)
+	}
+
+	if err := ms.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+	if err := ms.CreateServiceInstanceDetails(testCtx, &instance); err == nil {
+		t.Errorf("Expected an error creating the item twice")
+	}
+
+	ret, err := ms.GetServiceInstanceDetailsById(testCtx, testPk)
+	if err != nil {
+		t.Fatalf("Expected no error trying to get the item, got: %v", err)
+	}
+	ensureServiceInstanceDetailsFieldsMatch(t, &instance, ret)
+
+	// returned records are copies
+	ret.Name = "changed"
+	if ret, _ := ms.GetServiceInstanceDetailsById(testCtx, testPk); ret.Name == "changed" {
+		t.Errorf("Expected changes to a returned record not to be stored")
+	}
+
+	if exists, _ := ms.ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(testCtx, instance.SpaceGuid, instance.ServiceId); !exists {
+		t.Errorf("Expected the instance to exist in its space")
+	}
+
+	if count, _ := ms.CountServiceInstancesByPlan(testCtx, instance.PlanId); count != 1 {
+		t.Errorf("Expected the instance to be counted for its plan, got %d", count)
+	}
+
+	if err := ms.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
+		t.Fatalf("Expected to be able to delete the item, got error: %s", err)
+	}
+	if exists, _ := ms.ExistsServiceInstanceDetailsById(testCtx, testPk); exists {
+		t.Errorf("Expected the deleted item not to exist")
+	}
+	if count, _ := ms.CountServiceInstancesByPlan(testCtx, instance.PlanId); count != 0 {
+		t.Errorf("Expected the deleted item not to be counted, got %d", count)
+	}
+}
+
+func TestMemoryStore_ServiceBindingCredentials(t *testing.T) {
+	ms := NewMemoryStore()
+	testCtx := context.Background()
+
+	for _, bindingId := range []string{"binding-1", "binding-2"} {
+		binding := models.ServiceBindingCredentials{ServiceInstanceId: "instance", BindingId: bindingId}
+		if err := ms.CreateServiceBindingCredentials(testCtx, &binding); err != nil {
+			t.Fatalf("Expected to be able to create the binding, got error: %s", err)
+		}
+		if binding.ID == 0 {
+			t.Errorf("Expected the binding to get an ID")
+		}
+	}
+
+	bindings, err := ms.GetServiceBindingCredentialsByServiceInstanceId(testCtx, "instance")
+	if err != nil {
+		t.Fatalf("Expected no error getting the bindings, got: %v", err)
+	}
+	if len(bindings) != 2 || bindings[0].BindingId != "binding-1" || bindings[1].BindingId != "binding-2" {
+		t.Errorf("Expected the bindings oldest first, got %v", bindings)
+	}
+	if count, _ := ms.CountServiceBindingCredentialsByServiceInstanceId(testCtx, "instance"); count != 2 {
+		t.Errorf("Expected 2 bindings to be counted, got %d", count)
+	}
+
+	if err := ms.DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, "instance", "binding-1"); err != nil {
+		t.Fatalf("Expected to be able to delete the binding, got error: %s", err)
+	}
+	if _, err := ms.GetServiceBindingCredentialsByBindingId(testCtx, "binding-1"); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected an ErrRecordNotFound trying to get a deleted binding, got %v", err)
+	}
+	if exists, _ := ms.ExistsServiceBindingCredentialsByBindingId(testCtx, "binding-2"); !exists {
+		t.Errorf("Expected the other binding to exist")
+	}
+}
+
+func TestMemoryStore_IdempotencyKeys(t *testing.T) {
+	ms := NewMemoryStore()
+	testCtx := context.Background()
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+
+	keys := []models.IdempotencyKey{
+		{RequestIdentity: "expired", ExpiresAt: &expired},
+		{RequestIdentity: "never-expires"},
+	}
+	for _, key := range keys {
+		key := key
+		if err := ms.CreateIdempotencyKey(testCtx, &key); err != nil {
+			t.Fatalf("Expected to be able to create the key, got error: %s", err)
+		}
+	}
+	if err := ms.CreateIdempotencyKey(testCtx, &models.IdempotencyKey{RequestIdentity: "expired"}); err == nil {
+		t.Errorf("Expected an error creating a duplicate key")
+	}
+
+	if err := ms.DeleteExpiredIdempotencyKeys(testCtx, now); err != nil {
+		t.Fatalf("Expected no error deleting expired keys, got: %v", err)
+	}
+	if _, err := ms.GetIdempotencyKeyByRequestIdentity(testCtx, "expired"); err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected the expired key to be deleted, got %v", err)
+	}
+	if _, err := ms.GetIdempotencyKeyByRequestIdentity(testCtx, "never-expires"); err != nil {
+		t.Errorf("Expected the key without expiry to be kept, got %v", err)
+	}
+}
+
+func TestSetStore(t *testing.T) {
+	ms := NewMemoryStore()
+	SetStore(ms)
+	defer SetStore(nil)
+
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+	if err := CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item, got error: %s", err)
+	}
+
+	if exists, _ := ms.ExistsServiceInstanceDetailsById(testCtx, testPk); !exists {
+		t.Errorf("Expected the package functions to use the store")
+	}
+}
@@ -20,9 +20,10 @@ import (
 
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
 )
 
-const numMigrations = 7
+const numMigrations = 30
 
 // runs schema migrations on the provided service broker database to get it up to date
 func RunMigrations(db *gorm.DB) error {
@@ -80,6 +81,118 @@ func RunMigrations(db *gorm.DB) error {
 		}
 	}
 
+	migrations[7] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV3{})
+	}
+
+	migrations[8] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV2{})
+	}
+
+	migrations[9] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV3{})
+	}
+
+	migrations[10] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV4{})
+	}
+
+	migrations[11] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.OperationHistoryV1{})
+	}
+
+	migrations[12] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV4{})
+	}
+
+	migrations[13] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.IdempotencyKeyV1{})
+	}
+
+	migrations[14] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV5{})
+	}
+
+	migrations[15] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV5{})
+	}
+
+	migrations[16] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV6{})
+	}
+
+	migrations[17] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV7{})
+	}
+
+	migrations[18] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV6{})
+	}
+
+	migrations[19] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV8{})
+	}
+
+	migrations[20] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV7{})
+	}
+
+	migrations[21] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV9{})
+	}
+
+	migrations[22] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV10{})
+	}
+
+	migrations[23] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV8{})
+	}
+
+	migrations[24] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV11{})
+	}
+
+	migrations[25] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.BindRequestDetailsV1{})
+	}
+
+	migrations[26] = func() error { // v5.0.0
+		tables := []interface{}{
+			&models.ServiceInstanceDetailsV12{},
+			&models.ServiceBindingCredentialsV9{},
+			&models.ProvisionRequestDetailsV3{},
+			&models.BindRequestDetailsV2{},
+			&models.TerraformDeploymentV2{},
+			&models.OperationHistoryV2{},
+			&models.IdempotencyKeyV2{},
+		}
+		if err := autoMigrateTables(db, tables...); err != nil {
+			return err
+		}
+
+		// existing records belong to the broker doing the upgrade
+		brokerId := viper.GetString(dbBrokerIdProp)
+		for _, table := range tables {
+			if err := db.Unscoped().Model(table).Where("broker_id IS NULL").UpdateColumn("broker_id", brokerId).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	migrations[27] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV13{})
+	}
+
+	migrations[28] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceInstanceDetailsV14{})
+	}
+
+	migrations[29] = func() error { // v5.0.0
+		return autoMigrateTables(db, &models.ServiceBindingCredentialsV10{})
+	}
+
 	var lastMigrationNumber = -1
 
 	// if we've run any migrations before, we should have a migrations table, so find the last one we ran
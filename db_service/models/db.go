@@ -31,10 +31,10 @@ const (
 
 // ServiceBindingCredentials holds credentials returned to the users after
 // binding to a service.
-type ServiceBindingCredentials ServiceBindingCredentialsV1
+type ServiceBindingCredentials ServiceBindingCredentialsV10
 
 // ServiceInstanceDetails holds information about provisioned services.
-type ServiceInstanceDetails ServiceInstanceDetailsV2
+type ServiceInstanceDetails ServiceInstanceDetailsV14
 
 // SetOtherDetails marshals the value passed in into a JSON string and sets
 // OtherDetails to it if marshalling was successful.
@@ -58,9 +58,187 @@ func (si ServiceInstanceDetails) GetOtherDetails(v interface{}) error {
 	return json.Unmarshal([]byte(si.OtherDetails), v)
 }
 
+// GetResourceName returns the name the resources of the instance are created
+// with. Instances provisioned before names were derived use their ID.
+func (si ServiceInstanceDetails) GetResourceName() string {
+	if si.ResourceName == "" {
+		return si.ID
+	}
+	return si.ResourceName
+}
+
+// SetMetadata marshals the metadata into a JSON string and sets Metadata to
+// it. Empty metadata clears the field.
+func (si *ServiceInstanceDetails) SetMetadata(metadata map[string]string) error {
+	if len(metadata) == 0 {
+		si.Metadata = ""
+		return nil
+	}
+
+	out, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	si.Metadata = string(out)
+	return nil
+}
+
+// GetMetadata returns the unmarshalled Metadata field. An empty Metadata field
+// results in an empty map.
+func (si ServiceInstanceDetails) GetMetadata() (map[string]string, error) {
+	metadata := map[string]string{}
+	if si.Metadata == "" {
+		return metadata, nil
+	}
+
+	if err := json.Unmarshal([]byte(si.Metadata), &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// SetGeneratedParameters marshals the generated parameters into a JSON string
+// and sets GeneratedParameters to it, encrypted with the active key if
+// encryption is configured. Empty parameters clear the field.
+func (si *ServiceInstanceDetails) SetGeneratedParameters(params map[string]string) error {
+	if len(params) == 0 {
+		si.GeneratedParameters = ""
+		return nil
+	}
+
+	out, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	si.GeneratedParameters, err = encryptParameters(string(out))
+	return err
+}
+
+// GetGeneratedParameters returns the unmarshalled GeneratedParameters field.
+// An empty field results in an empty map.
+func (si ServiceInstanceDetails) GetGeneratedParameters() (map[string]string, error) {
+	params := map[string]string{}
+	if si.GeneratedParameters == "" {
+		return params, nil
+	}
+
+	plaintext, err := decryptParameters(si.GeneratedParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(plaintext), &params); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// SetAvailabilityZones marshals the zones into a JSON array and sets
+// AvailabilityZones to it. No zones clear the field.
+func (si *ServiceInstanceDetails) SetAvailabilityZones(zones []string) error {
+	if len(zones) == 0 {
+		si.AvailabilityZones = ""
+		return nil
+	}
+
+	out, err := json.Marshal(zones)
+	if err != nil {
+		return err
+	}
+
+	si.AvailabilityZones = string(out)
+	return nil
+}
+
+// GetAvailabilityZones returns the unmarshalled AvailabilityZones field. An
+// empty field results in no zones.
+func (si ServiceInstanceDetails) GetAvailabilityZones() ([]string, error) {
+	if si.AvailabilityZones == "" {
+		return nil, nil
+	}
+
+	var zones []string
+	if err := json.Unmarshal([]byte(si.AvailabilityZones), &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// SetMaintenanceInfo marshals the maintenance info into a JSON string and
+// sets MaintenanceInfo to it. A nil value clears the field.
+func (si *ServiceInstanceDetails) SetMaintenanceInfo(info interface{}) error {
+	if info == nil {
+		si.MaintenanceInfo = ""
+		return nil
+	}
+
+	out, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	si.MaintenanceInfo = string(out)
+	return nil
+}
+
+// GetMaintenanceInfo unmarshals the MaintenanceInfo field into v. An empty
+// field leaves v unchanged.
+func (si ServiceInstanceDetails) GetMaintenanceInfo(v interface{}) error {
+	if si.MaintenanceInfo == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(si.MaintenanceInfo), v)
+}
+
 // ProvisionRequestDetails holds user-defined properties passed to a call
 // to provision a service.
-type ProvisionRequestDetails ProvisionRequestDetailsV1
+type ProvisionRequestDetails ProvisionRequestDetailsV3
+
+// SetRequestDetails sets RequestDetails to the request parameters, encrypted
+// with the active key if encryption is configured.
+func (pr *ProvisionRequestDetails) SetRequestDetails(params json.RawMessage) (err error) {
+	pr.RequestDetails, err = encryptParameters(string(params))
+	return err
+}
+
+// GetRequestDetails returns the decrypted request parameters.
+func (pr ProvisionRequestDetails) GetRequestDetails() (json.RawMessage, error) {
+	plaintext, err := decryptParameters(pr.RequestDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(plaintext), nil
+}
+
+// BindRequestDetails holds user-defined properties passed to a call to bind
+// a service instance.
+type BindRequestDetails BindRequestDetailsV2
+
+// TableName returns the table name of the bind requests.
+func (BindRequestDetails) TableName() string {
+	return BindRequestDetailsV1{}.TableName()
+}
+
+// SetRequestDetails sets RequestDetails to the bind parameters, encrypted
+// with the active key if encryption is configured.
+func (br *BindRequestDetails) SetRequestDetails(params json.RawMessage) (err error) {
+	br.RequestDetails, err = encryptParameters(string(params))
+	return err
+}
+
+// GetRequestDetails returns the decrypted bind parameters.
+func (br BindRequestDetails) GetRequestDetails() (json.RawMessage, error) {
+	plaintext, err := decryptParameters(br.RequestDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(plaintext), nil
+}
 
 // Migration represents the mgirations table. It holds a monotonically
 // increasing number that gets incremented with every database schema revision.
@@ -72,4 +250,21 @@ type CloudOperation CloudOperationV1
 
 // TerraformDeployment holds Terraform state and plan information for resources
 // that use that execution system.
-type TerraformDeployment TerraformDeploymentV1
+type TerraformDeployment TerraformDeploymentV2
+
+// OperationHistory records an operation on a service instance.
+type OperationHistory OperationHistoryV2
+
+// TableName returns the table name of the history, gorm would pluralize it
+// otherwise.
+func (OperationHistory) TableName() string {
+	return OperationHistoryV1{}.TableName()
+}
+
+// IdempotencyKey records the outcome of a request by its request identity.
+type IdempotencyKey IdempotencyKeyV2
+
+// TableName returns the table name of the keys.
+func (IdempotencyKey) TableName() string {
+	return IdempotencyKeyV1{}.TableName()
+}
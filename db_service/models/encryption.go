@@ -0,0 +1,194 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// encryptedPrefix marks values encrypted by a KeyRing, it's followed by the ID
+// of the key and the base64 encoded nonce and ciphertext separated by colons.
+const encryptedPrefix = "encrypted:"
+
+// KeyRing encrypts the instance parameters stored in the database. It holds
+// several keys so the key can be rotated without downtime: values are always
+// encrypted with the active key, and decrypted with the key whose ID is stored
+// alongside the ciphertext.
+type KeyRing struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+// NewKeyRing creates a key ring from the keys by ID, the key with the active
+// ID is used for all writes.
+func NewKeyRing(keys map[string]string, activeID string) (*KeyRing, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("the active encryption key %q isn't one of the keys %v", activeID, sortedKeyIDs(keys))
+	}
+
+	ring := &KeyRing{activeID: activeID, keys: make(map[string]cipher.AEAD)}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key ID %q, IDs must be non-empty and not contain colons", id)
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("the encryption key %q is empty", id)
+		}
+
+		sum := sha256.Sum256([]byte(key))
+		block, err := aes.NewCipher(sum[:])
+		if err != nil {
+			return nil, err
+		}
+
+		if ring.keys[id], err = cipher.NewGCM(block); err != nil {
+			return nil, err
+		}
+	}
+
+	return ring, nil
+}
+
+// ActiveKeyID returns the ID of the key new values are encrypted with.
+func (r *KeyRing) ActiveKeyID() string {
+	return r.activeID
+}
+
+// Encrypt encrypts the value with the active key. Empty values are kept
+// empty.
+func (r *KeyRing) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	aead := r.keys[r.activeID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(r.activeID))
+	return encryptedPrefix + r.activeID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value encrypted with any of the keys of the ring. Values
+// that aren't encrypted, e.g. written before encryption was enabled, are
+// returned as they are.
+func (r *KeyRing) Decrypt(value string) (string, error) {
+	keyID, encoded, ok := splitEncrypted(value)
+	if !ok {
+		return value, nil
+	}
+
+	if r == nil {
+		return "", fmt.Errorf("the value is encrypted with the key %q but encryption isn't configured", keyID)
+	}
+
+	aead, ok := r.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("the value is encrypted with the unknown key %q", keyID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("the encrypted value is malformed")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("the value can't be decrypted with the key %q, it was modified or the key changed", keyID)
+	}
+
+	return string(plaintext), nil
+}
+
+// NeedsRotation returns true if the value isn't encrypted with the active key.
+func (r *KeyRing) NeedsRotation(value string) bool {
+	if value == "" {
+		return false
+	}
+
+	keyID, _, ok := splitEncrypted(value)
+	return !ok || keyID != r.activeID
+}
+
+func splitEncrypted(value string) (keyID, encoded string, ok bool) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func sortedKeyIDs(keys map[string]string) []string {
+	var ids []string
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+var (
+	keyRingMu sync.RWMutex
+	keyRing   *KeyRing
+)
+
+// SetKeyRing sets the key ring instance parameters are encrypted with, nil
+// stores new values in plain text.
+func SetKeyRing(ring *KeyRing) {
+	keyRingMu.Lock()
+	defer keyRingMu.Unlock()
+	keyRing = ring
+}
+
+// CurrentKeyRing returns the key ring set by SetKeyRing.
+func CurrentKeyRing() *KeyRing {
+	keyRingMu.RLock()
+	defer keyRingMu.RUnlock()
+	return keyRing
+}
+
+// encryptParameters encrypts the value with the current key ring, if any.
+func encryptParameters(plaintext string) (string, error) {
+	ring := CurrentKeyRing()
+	if ring == nil {
+		return plaintext, nil
+	}
+
+	return ring.Encrypt(plaintext)
+}
+
+// decryptParameters decrypts a value written by encryptParameters.
+func decryptParameters(value string) (string, error) {
+	return CurrentKeyRing().Decrypt(value)
+}
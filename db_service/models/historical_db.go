@@ -46,6 +46,255 @@ func (ServiceBindingCredentialsV1) TableName() string {
 	return "service_binding_credentials"
 }
 
+// ServiceBindingCredentialsV2 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV2 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV2) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV3 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV3 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV3) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV4 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV4 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV4) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV5 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV5 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV5) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV6 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV6 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+
+	// NetworkPolicyId identifies the network policy allowing the bound
+	// application to reach the instance, if the plan creates them. Bindings
+	// of the same application share the policy.
+	NetworkPolicyId string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV6) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV7 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV7 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// RouteServiceURL holds the URL the platform should proxy the requests
+	// to the bound route through, if the service is a route service.
+	RouteServiceURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+
+	// NetworkPolicyId identifies the network policy allowing the bound
+	// application to reach the instance, if the plan creates them. Bindings
+	// of the same application share the policy.
+	NetworkPolicyId string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV7) TableName() string {
+	return "service_binding_credentials"
+}
+
+// ServiceBindingCredentialsV8 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV8 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// RouteServiceURL holds the URL the platform should proxy the requests
+	// to the bound route through, if the service is a route service.
+	RouteServiceURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+
+	// NetworkPolicyId identifies the network policy allowing the bound
+	// application to reach the instance, if the plan creates them. Bindings
+	// of the same application share the policy.
+	NetworkPolicyId string
+
+	// SpaceGuid and OrganizationGuid identify where the binding was requested
+	// from, which differs from the instance's space if the instance is shared.
+	// Both are empty if the platform didn't say.
+	SpaceGuid        string
+	OrganizationGuid string
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV8) TableName() string {
+	return "service_binding_credentials"
+}
+
 // ServiceInstanceDetailsV1 holds information about provisioned services.
 type ServiceInstanceDetailsV1 struct {
 	ID        string `gorm:"primary_key;type:varchar(255);not null"`
@@ -53,26 +302,832 @@ type ServiceInstanceDetailsV1 struct {
 	UpdatedAt time.Time
 	DeletedAt *time.Time
 
-	Name         string
-	Location     string
-	Url          string
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV1) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV2 holds information about provisioned services.
+type ServiceInstanceDetailsV2 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV2) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV3 holds information about provisioned services.
+type ServiceInstanceDetailsV3 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV3) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV4 holds information about provisioned services.
+type ServiceInstanceDetailsV4 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV4) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV5 holds information about provisioned services.
+type ServiceInstanceDetailsV5 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV5) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV6 holds information about provisioned services.
+type ServiceInstanceDetailsV6 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV6) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV7 holds information about provisioned services.
+type ServiceInstanceDetailsV7 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV7) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV8 holds information about provisioned services.
+type ServiceInstanceDetailsV8 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV8) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV9 holds information about provisioned services.
+type ServiceInstanceDetailsV9 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV9) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV10 holds information about provisioned services.
+type ServiceInstanceDetailsV10 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+
+	// DeletionProtection is set if the instance must not be deprovisioned
+	// unless the request explicitly overrides it.
+	DeletionProtection bool
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV10) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceInstanceDetailsV11 holds information about provisioned services.
+type ServiceInstanceDetailsV11 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+
+	// DeletionProtection is set if the instance must not be deprovisioned
+	// unless the request explicitly overrides it.
+	DeletionProtection bool
+
+	// MaintenanceInfo holds the JSON encoded maintenance_info of the plan
+	// the instance was last provisioned, updated or upgraded with.
+	MaintenanceInfo string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV11) TableName() string {
+	return "service_instance_details"
+}
+
+// ProvisionRequestDetailsV1 holds user-defined properties passed to a call
+// to provision a service.
+type ProvisionRequestDetailsV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string
+	// is a json.Marshal of models.ProvisionDetails
+	RequestDetails string
+}
+
+// TableName returns a consistent table name (`provision_request_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ProvisionRequestDetailsV1) TableName() string {
+	return "provision_request_details"
+}
+
+// ProvisionRequestDetailsV2 holds user-defined properties passed to a call
+// to provision a service.
+type ProvisionRequestDetailsV2 struct {
+	gorm.Model
+
+	ServiceInstanceId string
+
+	// is a json.Marshal of models.ProvisionDetails
+	RequestDetails string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`provision_request_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ProvisionRequestDetailsV2) TableName() string {
+	return "provision_request_details"
+}
+
+// MigrationV1 represents the mgirations table. It holds a monotonically
+// increasing number that gets incremented with every database schema revision.
+type MigrationV1 struct {
+	gorm.Model
+
+	MigrationId int `gorm:"type:int(10)"`
+}
+
+// TableName returns a consistent table name (`migrations`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (MigrationV1) TableName() string {
+	return "migrations"
+}
+
+// CloudOperationV1 holds information about the status of Google Cloud
+// long-running operations.
+// As-of version 4.1.0, this table is no longer necessary.
+type CloudOperationV1 struct {
+	gorm.Model
+
+	Name          string
+	Status        string
+	OperationType string
+	ErrorMessage  string `gorm:"type:text"`
+	InsertTime    string
+	StartTime     string
+	TargetId      string
+	TargetLink    string
+
+	ServiceId         string
+	ServiceInstanceId string
+}
+
+// TableName returns a consistent table name (`cloud_operations`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (CloudOperationV1) TableName() string {
+	return "cloud_operations"
+}
+
+// PlanDetailsV1 is a table that was deprecated in favor of using Environment
+// variables. It only remains for ORM migrations and the ability for existing
+// users to export their plans.
+type PlanDetailsV1 struct {
+	ID        string `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	ServiceId string
+	Name      string
+	Features  string `sql:"type:text"`
+}
+
+// TableName returns a consistent table name (`plan_details`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (PlanDetailsV1) TableName() string {
+	return "plan_details"
+}
+
+// TerraformDeploymentV1 describes the state of a Terraform resource deployment.
+type TerraformDeploymentV1 struct {
+	ID        string `gorm:"primary_key",sql:"type:varchar(1024)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+
+	// Workspace contains a JSON serialized version of the Terraform workspace.
+	Workspace string `sql:"type:text"`
+
+	// LastOperationType describes the last operation being performed on the resource.
+	LastOperationType string
+
+	// LastOperationState holds one of the following strings "in progress", "succeeded", "failed".
+	// These mirror the OSB API.
+	LastOperationState string
+
+	// LastOperationMessage is a description that can be passed back to the user.
+	LastOperationMessage string `sql:"type:text"`
+}
+
+// TableName returns a consistent table name (`tf_deployment`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (TerraformDeploymentV1) TableName() string {
+	return "terraform_deployments"
+}
+
+// OperationHistoryV1 records an operation on a service instance, so failures
+// can be investigated after the instance moved on to other operations.
+type OperationHistoryV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string `gorm:"index"`
+
+	// OperationType is one of the OSB operation types, e.g. "provision".
+	OperationType string
+
+	// OperationId is the ID of asynchronous operations, empty otherwise.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// State holds one of the following strings "in progress", "succeeded",
+	// "failed". These mirror the OSB API.
+	State string
+
+	StartedAt  time.Time
+	FinishedAt *time.Time
+
+	// Error holds the description of failed operations.
+	Error string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`operation_history`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (OperationHistoryV1) TableName() string {
+	return "operation_history"
+}
+
+// IdempotencyKeyV1 records the outcome of a request by its request identity,
+// so retries of the request get the same outcome instead of repeating it.
+type IdempotencyKeyV1 struct {
+	gorm.Model
+
+	// RequestIdentity is the X-Broker-API-Request-Identity of the request.
+	RequestIdentity string `gorm:"type:varchar(255);unique_index"`
+
+	ServiceInstanceId string
+
+	// State holds "in progress" while the request is running and "succeeded"
+	// once the response is recorded.
+	State string
+
+	// Response holds the JSON encoded response of succeeded requests.
+	Response string `gorm:"type:text"`
+
+	// ExpiresAt is when the key can be removed, it is unset while the request
+	// is running.
+	ExpiresAt *time.Time `gorm:"index"`
+}
+
+// TableName returns a consistent table name (`idempotency_keys`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (IdempotencyKeyV1) TableName() string {
+	return "idempotency_keys"
+}
+
+// BindRequestDetailsV1 holds user-defined properties passed to a call to bind
+// a service instance.
+type BindRequestDetailsV1 struct {
+	gorm.Model
+
+	ServiceInstanceId string
+	BindingId         string `gorm:"index"`
+
+	// RequestDetails holds the JSON encoded bind parameters.
+	RequestDetails string `gorm:"type:text"`
+}
+
+// TableName returns a consistent table name (`bind_request_details`) for gorm
+// so multiple structs from different versions of the database all operate on
+// the same table.
+func (BindRequestDetailsV1) TableName() string {
+	return "bind_request_details"
+}
+
+// ServiceBindingCredentialsV9 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV9 struct {
+	gorm.Model
+
 	OtherDetails string `gorm:"type:text"`
 
-	ServiceId        string
-	PlanId           string
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// RouteServiceURL holds the URL the platform should proxy the requests
+	// to the bound route through, if the service is a route service.
+	RouteServiceURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+
+	// NetworkPolicyId identifies the network policy allowing the bound
+	// application to reach the instance, if the plan creates them. Bindings
+	// of the same application share the policy.
+	NetworkPolicyId string
+
+	// SpaceGuid and OrganizationGuid identify where the binding was requested
+	// from, which differs from the instance's space if the instance is shared.
+	// Both are empty if the platform didn't say.
 	SpaceGuid        string
 	OrganizationGuid string
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
-// TableName returns a consistent table name (`service_instance_details`) for
+// TableName returns a consistent table name (`service_binding_credentials`) for
 // gorm so multiple structs from different versions of the database all operate
 // on the same table.
-func (ServiceInstanceDetailsV1) TableName() string {
-	return "service_instance_details"
+func (ServiceBindingCredentialsV9) TableName() string {
+	return "service_binding_credentials"
 }
 
-// ServiceInstanceDetailsV2 holds information about provisioned services.
-type ServiceInstanceDetailsV2 struct {
+// ServiceInstanceDetailsV12 holds information about provisioned services.
+type ServiceInstanceDetailsV12 struct {
 	ID        string `gorm:"primary_key;type:varchar(255);not null"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -98,136 +1153,422 @@ type ServiceInstanceDetailsV2 struct {
 	// The OperationId will be cleared after a successful operation.
 	// This string MAY be sent to users and MUST NOT leak confidential information.
 	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+
+	// DeletionProtection is set if the instance must not be deprovisioned
+	// unless the request explicitly overrides it.
+	DeletionProtection bool
+
+	// MaintenanceInfo holds the JSON encoded maintenance_info of the plan
+	// the instance was last provisioned, updated or upgraded with.
+	MaintenanceInfo string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
 // TableName returns a consistent table name (`service_instance_details`) for
 // gorm so multiple structs from different versions of the database all operate
 // on the same table.
-func (ServiceInstanceDetailsV2) TableName() string {
+func (ServiceInstanceDetailsV12) TableName() string {
 	return "service_instance_details"
 }
 
-// ProvisionRequestDetailsV1 holds user-defined properties passed to a call
+// ProvisionRequestDetailsV3 holds user-defined properties passed to a call
 // to provision a service.
-type ProvisionRequestDetailsV1 struct {
+type ProvisionRequestDetailsV3 struct {
 	gorm.Model
 
 	ServiceInstanceId string
+
 	// is a json.Marshal of models.ProvisionDetails
-	RequestDetails string
+	RequestDetails string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
 // TableName returns a consistent table name (`provision_request_details`) for
 // gorm so multiple structs from different versions of the database all operate
 // on the same table.
-func (ProvisionRequestDetailsV1) TableName() string {
+func (ProvisionRequestDetailsV3) TableName() string {
 	return "provision_request_details"
 }
 
-// ProvisionRequestDetailsV2 holds user-defined properties passed to a call
-// to provision a service.
-type ProvisionRequestDetailsV2 struct {
-	gorm.Model
+// TerraformDeploymentV2 describes the state of a Terraform resource deployment.
+type TerraformDeploymentV2 struct {
+	ID        string `gorm:"primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
 
-	ServiceInstanceId string
+	// Workspace contains a JSON serialized version of the Terraform workspace.
+	Workspace string `sql:"type:text"`
 
-	// is a json.Marshal of models.ProvisionDetails
-	RequestDetails string `gorm:"type:text"`
+	// LastOperationType describes the last operation being performed on the resource.
+	LastOperationType string
+
+	// LastOperationState holds one of the following strings "in progress", "succeeded", "failed".
+	// These mirror the OSB API.
+	LastOperationState string
+
+	// LastOperationMessage is a description that can be passed back to the user.
+	LastOperationMessage string `sql:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
-// TableName returns a consistent table name (`provision_request_details`) for
-// gorm so multiple structs from different versions of the database all operate
-// on the same table.
-func (ProvisionRequestDetailsV2) TableName() string {
-	return "provision_request_details"
+// TableName returns a consistent table name (`tf_deployment`) for gorm so
+// multiple structs from different versions of the database all operate on the
+// same table.
+func (TerraformDeploymentV2) TableName() string {
+	return "terraform_deployments"
 }
 
-// MigrationV1 represents the mgirations table. It holds a monotonically
-// increasing number that gets incremented with every database schema revision.
-type MigrationV1 struct {
+// OperationHistoryV2 records an operation on a service instance, so failures
+// can be investigated after the instance moved on to other operations.
+type OperationHistoryV2 struct {
 	gorm.Model
 
-	MigrationId int `gorm:"type:int(10)"`
+	ServiceInstanceId string `gorm:"index"`
+
+	// OperationType is one of the OSB operation types, e.g. "provision".
+	OperationType string
+
+	// OperationId is the ID of asynchronous operations, empty otherwise.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// State holds one of the following strings "in progress", "succeeded",
+	// "failed". These mirror the OSB API.
+	State string
+
+	StartedAt  time.Time
+	FinishedAt *time.Time
+
+	// Error holds the description of failed operations.
+	Error string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
-// TableName returns a consistent table name (`migrations`) for gorm so
+// TableName returns a consistent table name (`operation_history`) for gorm so
 // multiple structs from different versions of the database all operate on the
 // same table.
-func (MigrationV1) TableName() string {
-	return "migrations"
+func (OperationHistoryV2) TableName() string {
+	return "operation_history"
 }
 
-// CloudOperationV1 holds information about the status of Google Cloud
-// long-running operations.
-// As-of version 4.1.0, this table is no longer necessary.
-type CloudOperationV1 struct {
+// IdempotencyKeyV2 records the outcome of a request by its request identity,
+// so retries of the request get the same outcome instead of repeating it.
+type IdempotencyKeyV2 struct {
 	gorm.Model
 
-	Name          string
-	Status        string
-	OperationType string
-	ErrorMessage  string `gorm:"type:text"`
-	InsertTime    string
-	StartTime     string
-	TargetId      string
-	TargetLink    string
+	// RequestIdentity is the X-Broker-API-Request-Identity of the request.
+	RequestIdentity string `gorm:"type:varchar(255);unique_index"`
 
-	ServiceId         string
 	ServiceInstanceId string
+
+	// State holds "in progress" while the request is running and "succeeded"
+	// once the response is recorded.
+	State string
+
+	// Response holds the JSON encoded response of succeeded requests.
+	Response string `gorm:"type:text"`
+
+	// ExpiresAt is when the key can be removed, it is unset while the request
+	// is running.
+	ExpiresAt *time.Time `gorm:"index"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
 }
 
-// TableName returns a consistent table name (`cloud_operations`) for gorm so
+// TableName returns a consistent table name (`idempotency_keys`) for gorm so
 // multiple structs from different versions of the database all operate on the
 // same table.
-func (CloudOperationV1) TableName() string {
-	return "cloud_operations"
+func (IdempotencyKeyV2) TableName() string {
+	return "idempotency_keys"
 }
 
-// PlanDetailsV1 is a table that was deprecated in favor of using Environment
-// variables. It only remains for ORM migrations and the ability for existing
-// users to export their plans.
-type PlanDetailsV1 struct {
-	ID        string `gorm:"primary_key"`
+// BindRequestDetailsV2 holds user-defined properties passed to a call to bind
+// a service instance.
+type BindRequestDetailsV2 struct {
+	gorm.Model
+
+	ServiceInstanceId string
+	BindingId         string `gorm:"index"`
+
+	// RequestDetails holds the JSON encoded bind parameters.
+	RequestDetails string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
+}
+
+// TableName returns a consistent table name (`bind_request_details`) for gorm
+// so multiple structs from different versions of the database all operate on
+// the same table.
+func (BindRequestDetailsV2) TableName() string {
+	return "bind_request_details"
+}
+
+// ServiceInstanceDetailsV13 holds information about provisioned services.
+type ServiceInstanceDetailsV13 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time
 
-	ServiceId string
-	Name      string
-	Features  string `sql:"type:text"`
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
+
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+
+	// DeletionProtection is set if the instance must not be deprovisioned
+	// unless the request explicitly overrides it.
+	DeletionProtection bool
+
+	// MaintenanceInfo holds the JSON encoded maintenance_info of the plan
+	// the instance was last provisioned, updated or upgraded with.
+	MaintenanceInfo string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
+
+	// CompletionCallback is the URL the final state of the instance's
+	// asynchronous operations is posted to, if the user supplied one.
+	CompletionCallback string `gorm:"type:text"`
 }
 
-// TableName returns a consistent table name (`plan_details`) for gorm so
-// multiple structs from different versions of the database all operate on the
-// same table.
-func (PlanDetailsV1) TableName() string {
-	return "plan_details"
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV13) TableName() string {
+	return "service_instance_details"
 }
 
-// TerraformDeploymentV1 describes the state of a Terraform resource deployment.
-type TerraformDeploymentV1 struct {
-	ID        string `gorm:"primary_key",sql:"type:varchar(1024)"`
+// ServiceInstanceDetailsV14 holds information about provisioned services.
+type ServiceInstanceDetailsV14 struct {
+	ID        string `gorm:"primary_key;type:varchar(255);not null"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt *time.Time
 
-	// Workspace contains a JSON serialized version of the Terraform workspace.
-	Workspace string `sql:"type:text"`
+	Name         string
+	Location     string
+	Url          string
+	OtherDetails string `gorm:"type:text"`
 
-	// LastOperationType describes the last operation being performed on the resource.
-	LastOperationType string
+	ServiceId        string
+	PlanId           string
+	SpaceGuid        string
+	OrganizationGuid string
 
-	// LastOperationState holds one of the following strings "in progress", "succeeded", "failed".
-	// These mirror the OSB API.
-	LastOperationState string
+	// OperationType holds a string corresponding to what kind of operation
+	// OperationId is referencing. The object is "locked" for editing if
+	// an operation is pending.
+	OperationType string
 
-	// LastOperationMessage is a description that can be passed back to the user.
-	LastOperationMessage string `sql:"type:text"`
+	// OperationId holds a string referencing an operation specific to a broker.
+	// Operations in GCP all have a unique ID.
+	// The OperationId will be cleared after a successful operation.
+	// This string MAY be sent to users and MUST NOT leak confidential information.
+	OperationId string `gorm:"type:varchar(1024)"`
+
+	// ResourcePrefix holds the user supplied prefix for the names of the
+	// resources backing the instance.
+	ResourcePrefix string
+
+	// Network and Subnet identify the existing network the instance was
+	// placed in, if any.
+	Network string `gorm:"type:varchar(1024)"`
+	Subnet  string `gorm:"type:varchar(1024)"`
+
+	// Metadata holds a JSON object of operator supplied key/value pairs, e.g.
+	// a cost center or owner. It is not passed to providers.
+	Metadata string `gorm:"type:text"`
+
+	// ResourceName is the provider-safe name derived from the instance ID
+	// when it was provisioned, used for its resources on every operation.
+	ResourceName string
+
+	// GeneratedParameters holds a JSON object of the provision parameters
+	// the broker generated, e.g. admin passwords, so updates reuse them.
+	GeneratedParameters string `gorm:"type:text"`
+
+	// AvailabilityZones holds a JSON array of the zones the instance spans,
+	// if the user or the plan chose any.
+	AvailabilityZones string `gorm:"type:text"`
+
+	// InstanceName is the name the user gave the instance on the platform,
+	// taken from the provision request's context, if any.
+	InstanceName string
+
+	// DeletionProtection is set if the instance must not be deprovisioned
+	// unless the request explicitly overrides it.
+	DeletionProtection bool
+
+	// MaintenanceInfo holds the JSON encoded maintenance_info of the plan
+	// the instance was last provisioned, updated or upgraded with.
+	MaintenanceInfo string `gorm:"type:text"`
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
+
+	// CompletionCallback is the URL the final state of the instance's
+	// asynchronous operations is posted to, if the user supplied one.
+	CompletionCallback string `gorm:"type:text"`
+
+	// ProviderAccount names the provider account profile the instance's
+	// resources were created with, empty for the broker's own credentials.
+	ProviderAccount string
 }
 
-// TableName returns a consistent table name (`tf_deployment`) for gorm so
-// multiple structs from different versions of the database all operate on the
-// same table.
-func (TerraformDeploymentV1) TableName() string {
-	return "terraform_deployments"
+// TableName returns a consistent table name (`service_instance_details`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceInstanceDetailsV14) TableName() string {
+	return "service_instance_details"
+}
+
+// ServiceBindingCredentialsV10 holds credentials returned to the users after
+// binding to a service.
+type ServiceBindingCredentialsV10 struct {
+	gorm.Model
+
+	OtherDetails string `gorm:"type:text"`
+
+	ServiceId         string
+	ServiceInstanceId string
+	BindingId         string
+
+	// SyslogDrainURL holds the URL the platform should forward the logs of the
+	// bound application to, if any.
+	SyslogDrainURL string `gorm:"type:text"`
+
+	// RouteServiceURL holds the URL the platform should proxy the requests
+	// to the bound route through, if the service is a route service.
+	RouteServiceURL string `gorm:"type:text"`
+
+	// Role is the plan role the credentials were scoped to, if any.
+	Role string
+
+	// AppGuid and AppName identify the application the binding was created
+	// for. Both are empty for service keys.
+	AppGuid string
+	AppName string
+
+	// CredentialFormat is the shape the credentials are returned in, empty
+	// for the default JSON. OtherDetails always holds the raw credentials.
+	CredentialFormat string
+
+	// NetworkPolicyId identifies the network policy allowing the bound
+	// application to reach the instance, if the plan creates them. Bindings
+	// of the same application share the policy.
+	NetworkPolicyId string
+
+	// SpaceGuid and OrganizationGuid identify where the binding was requested
+	// from, which differs from the instance's space if the instance is shared.
+	// Both are empty if the platform didn't say.
+	SpaceGuid        string
+	OrganizationGuid string
+
+	// Name is the user supplied name of the binding, used by database
+	// services as the username of its credentials. It is unique within the
+	// instance, empty if the user didn't supply one.
+	Name string
+
+	// BrokerId identifies the broker the record belongs to when several
+	// brokers share the database, empty for brokers without an ID.
+	BrokerId string `gorm:"index"`
+}
+
+// TableName returns a consistent table name (`service_binding_credentials`) for
+// gorm so multiple structs from different versions of the database all operate
+// on the same table.
+func (ServiceBindingCredentialsV10) TableName() string {
+	return "service_binding_credentials"
 }
@@ -0,0 +1,74 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// CreateOperationHistory records the start of an operation on an instance.
+func CreateOperationHistory(ctx context.Context, object *models.OperationHistory) (err error) {
+	ctx, span := startSpan(ctx, "CreateOperationHistory")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CreateOperationHistory(ctx, object)
+}
+func (ds *SqlDatastore) CreateOperationHistory(ctx context.Context, object *models.OperationHistory) error {
+	return ds.db.Create(object).Error
+}
+
+// FinishOperationHistory sets the final state and error of the most recent
+// unfinished operation of the instance. It does nothing if there is none, e.g.
+// because the operation was started before the history was recorded.
+func FinishOperationHistory(ctx context.Context, serviceInstanceId, state, errMessage string) (err error) {
+	ctx, span := startSpan(ctx, "FinishOperationHistory")
+	defer func() { endSpan(span, err) }()
+	return currentStore().FinishOperationHistory(ctx, serviceInstanceId, state, errMessage)
+}
+func (ds *SqlDatastore) FinishOperationHistory(ctx context.Context, serviceInstanceId, state, errMessage string) error {
+	record := models.OperationHistory{}
+	err := ds.db.Where("service_instance_id = ? AND finished_at IS NULL", serviceInstanceId).Order("id desc").First(&record).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		return nil
+	case err != nil:
+		return err
+	}
+
+	now := time.Now()
+	record.State = state
+	record.FinishedAt = &now
+	record.Error = errMessage
+	return ds.db.Save(&record).Error
+}
+
+// GetOperationHistoryByServiceInstanceId gets the operations of the instance,
+// oldest first.
+func GetOperationHistoryByServiceInstanceId(ctx context.Context, serviceInstanceId string) (_ []models.OperationHistory, err error) {
+	ctx, span := startSpan(ctx, "GetOperationHistoryByServiceInstanceId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetOperationHistoryByServiceInstanceId(ctx, serviceInstanceId)
+}
+func (ds *SqlDatastore) GetOperationHistoryByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.OperationHistory, error) {
+	var history []models.OperationHistory
+	if err := ds.db.Where("service_instance_id = ?", serviceInstanceId).Order("id asc").Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
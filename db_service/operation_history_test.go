@@ -0,0 +1,72 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_OperationHistory(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	ds.db.CreateTable(models.OperationHistory{})
+	testCtx := context.Background()
+
+	// finishing without a recorded operation is a no-op
+	if err := ds.FinishOperationHistory(testCtx, "instance", "succeeded", ""); err != nil {
+		t.Fatalf("Expected no error finishing an unknown operation, got: %v", err)
+	}
+
+	for _, opType := range []string{models.ProvisionOperationType, models.DeprovisionOperationType} {
+		record := models.OperationHistory{
+			ServiceInstanceId: "instance",
+			OperationType:     opType,
+			State:             "in progress",
+			StartedAt:         time.Now(),
+		}
+		if err := ds.CreateOperationHistory(testCtx, &record); err != nil {
+			t.Fatalf("Expected to be able to create the item %#v, got error: %s", record, err)
+		}
+	}
+
+	if err := ds.FinishOperationHistory(testCtx, "instance", "failed", "quota exceeded"); err != nil {
+		t.Fatalf("Expected to be able to finish the operation, got error: %s", err)
+	}
+
+	history, err := ds.GetOperationHistoryByServiceInstanceId(testCtx, "instance")
+	if err != nil {
+		t.Fatalf("Expected to be able to get the history, got error: %s", err)
+	}
+
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 operations, got %d", len(history))
+	}
+
+	if history[0].OperationType != models.ProvisionOperationType || history[0].FinishedAt != nil {
+		t.Errorf("Expected the provision to be first and unfinished, got %#v", history[0])
+	}
+
+	latest := history[1]
+	if latest.State != "failed" || latest.Error != "quota exceeded" || latest.FinishedAt == nil {
+		t.Errorf("Expected the deprovision to have failed, got %#v", latest)
+	}
+
+	if other, err := ds.GetOperationHistoryByServiceInstanceId(testCtx, "other"); err != nil || len(other) != 0 {
+		t.Errorf("Expected no history for other instances, got %v, %v", other, err)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// GetServiceInstanceDetailsWithOperationId gets the instances with the ID of
+// an asynchronous operation set, either still running or whose completion
+// hasn't been recorded yet.
+func GetServiceInstanceDetailsWithOperationId(ctx context.Context) (_ []models.ServiceInstanceDetails, err error) {
+	ctx, span := startSpan(ctx, "GetServiceInstanceDetailsWithOperationId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().GetServiceInstanceDetailsWithOperationId(ctx)
+}
+func (ds *SqlDatastore) GetServiceInstanceDetailsWithOperationId(ctx context.Context) ([]models.ServiceInstanceDetails, error) {
+	var instances []models.ServiceInstanceDetails
+	if err := ds.db.Where("operation_id IS NOT NULL AND operation_id <> ''").Order("id asc").Find(&instances).Error; err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
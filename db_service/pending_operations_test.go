@@ -0,0 +1,60 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestGetServiceInstanceDetailsWithOperationId(t *testing.T) {
+	stores := map[string]Store{
+		"sql":    newInMemoryDatastore(t),
+		"memory": NewMemoryStore(),
+	}
+
+	for tn, store := range stores {
+		t.Run(tn, func(t *testing.T) {
+			testCtx := context.Background()
+			for _, instance := range []models.ServiceInstanceDetails{
+				{ID: "idle", OperationType: models.ClearOperationType},
+				{ID: "provisioning", OperationType: models.ProvisionOperationType, OperationId: "provision-op"},
+				{ID: "deprovisioning", OperationType: models.DeprovisionOperationType, OperationId: "deprovision-op"},
+			} {
+				instance := instance
+				if err := store.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+					t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+				}
+			}
+
+			instances, err := store.GetServiceInstanceDetailsWithOperationId(testCtx)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			var ids []string
+			for _, instance := range instances {
+				ids = append(ids, instance.ID)
+			}
+
+			if expected := []string{"deprovisioning", "provisioning"}; !reflect.DeepEqual(ids, expected) {
+				t.Errorf("Expected instances %v, got %v", expected, ids)
+			}
+		})
+	}
+}
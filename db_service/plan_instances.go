@@ -0,0 +1,37 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// CountServiceInstancesByPlan counts the instances of the plan across all
+// organizations. Deleted instances aren't counted.
+func CountServiceInstancesByPlan(ctx context.Context, planId string) (_ int, err error) {
+	ctx, span := startSpan(ctx, "CountServiceInstancesByPlan")
+	defer func() { endSpan(span, err) }()
+	return currentStore().CountServiceInstancesByPlan(ctx, planId)
+}
+func (ds *SqlDatastore) CountServiceInstancesByPlan(ctx context.Context, planId string) (int, error) {
+	var count int
+	if err := ds.db.Model(&models.ServiceInstanceDetails{}).Where("plan_id = ?", planId).Count(&count).Error; err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
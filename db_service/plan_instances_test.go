@@ -0,0 +1,69 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSqlDatastore_CountServiceInstancesByPlan(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	other := instance
+	other.ID = "other-instance"
+	other.OrganizationGuid = "other-org"
+	if err := ds.CreateServiceInstanceDetails(testCtx, &other); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", other, err)
+	}
+
+	cases := map[string]struct {
+		PlanId   string
+		Expected int
+	}{
+		"plan across organizations": {PlanId: instance.PlanId, Expected: 2},
+		"other plan":                {PlanId: "other-plan", Expected: 0},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			count, err := ds.CountServiceInstancesByPlan(testCtx, tc.PlanId)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if count != tc.Expected {
+				t.Errorf("Expected count to be %d, got %d", tc.Expected, count)
+			}
+		})
+	}
+
+	if err := ds.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
+		t.Fatalf("Expected to be able to delete the item, got error: %s", err)
+	}
+
+	count, err := ds.CountServiceInstancesByPlan(testCtx, instance.PlanId)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected deleted instances not to be counted, got %d", count)
+	}
+}
@@ -17,6 +17,7 @@ package db_service
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"database/sql"
 	"errors"
 	"fmt"
 	"os"
@@ -39,6 +40,14 @@ const (
 	dbTypeProp     = "db.type"
 	dbPathProp     = "db.path"
 
+	dbMaxOpenConnsProp    = "db.max_open_conns"
+	dbMaxIdleConnsProp    = "db.max_idle_conns"
+	dbConnMaxLifetimeProp = "db.conn_max_lifetime"
+
+	dbInstanceCacheSizeProp = "db.instance_cache_size"
+
+	dbBrokerIdProp = "db.broker_id"
+
 	DbTypeMysql   = "mysql"
 	DbTypeSqlite3 = "sqlite3"
 )
@@ -61,6 +70,19 @@ func init() {
 	viper.SetDefault(dbTypeProp, DbTypeMysql)
 
 	viper.BindEnv(dbPathProp, "DB_PATH")
+
+	viper.BindEnv(dbMaxOpenConnsProp, "DB_MAX_OPEN_CONNS")
+	viper.SetDefault(dbMaxOpenConnsProp, 25)
+	viper.BindEnv(dbMaxIdleConnsProp, "DB_MAX_IDLE_CONNS")
+	viper.SetDefault(dbMaxIdleConnsProp, 10)
+	viper.BindEnv(dbConnMaxLifetimeProp, "DB_CONN_MAX_LIFETIME")
+	viper.SetDefault(dbConnMaxLifetimeProp, "5m")
+
+	viper.BindEnv(dbInstanceCacheSizeProp, "DB_INSTANCE_CACHE_SIZE")
+	viper.SetDefault(dbInstanceCacheSizeProp, 0)
+
+	viper.BindEnv(dbBrokerIdProp, "DB_BROKER_ID")
+	viper.SetDefault(dbBrokerIdProp, "")
 }
 
 // pulls db credentials from the environment, connects to the db, and returns the db connection
@@ -88,9 +110,31 @@ func SetupDb(logger lager.Logger) *gorm.DB {
 		os.Exit(1)
 	}
 
+	configureConnectionPool(db.DB(), logger)
+
 	return db
 }
 
+// configureConnectionPool limits the connections the broker opens to the
+// database, so polling traffic can't exhaust the connections the database
+// allows. Connections are recycled periodically so they don't outlive server
+// side timeouts.
+func configureConnectionPool(db *sql.DB, logger lager.Logger) {
+	maxOpen := viper.GetInt(dbMaxOpenConnsProp)
+	maxIdle := viper.GetInt(dbMaxIdleConnsProp)
+	maxLifetime := viper.GetDuration(dbConnMaxLifetimeProp)
+
+	logger.Info("Configuring connection pool", lager.Data{
+		"max_open_conns":    maxOpen,
+		"max_idle_conns":    maxIdle,
+		"conn_max_lifetime": maxLifetime.String(),
+	})
+
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(maxLifetime)
+}
+
 func setupSqlite3Db(logger lager.Logger) (*gorm.DB, error) {
 	dbPath := viper.GetString(dbPathProp)
 	if dbPath == "" {
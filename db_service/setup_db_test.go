@@ -0,0 +1,36 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+func TestConfigureConnectionPool(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	defer viper.Reset()
+	viper.Set(dbMaxOpenConnsProp, 7)
+	viper.Set(dbMaxIdleConnsProp, 3)
+	viper.Set(dbConnMaxLifetimeProp, "1m")
+
+	configureConnectionPool(ds.db.DB(), utils.NewLogger("db-test"))
+
+	if actual := ds.db.DB().Stats().MaxOpenConnections; actual != 7 {
+		t.Errorf("Expected 7 max open connections, got %d", actual)
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// ExistsServiceInstanceDetailsBySpaceGuidAndServiceId checks to see if an
+// instance of the given service exists in the space.
+func ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx context.Context, spaceGuid, serviceId string) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "ExistsServiceInstanceDetailsBySpaceGuidAndServiceId")
+	defer func() { endSpan(span, err) }()
+	return currentStore().ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx, spaceGuid, serviceId)
+}
+func (ds *SqlDatastore) ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx context.Context, spaceGuid, serviceId string) (bool, error) {
+	var count int
+	if err := ds.db.Model(&models.ServiceInstanceDetails{}).Where("space_guid = ? AND service_id = ?", spaceGuid, serviceId).Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
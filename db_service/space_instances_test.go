@@ -0,0 +1,64 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSqlDatastore_ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(t *testing.T) {
+	ds := newInMemoryDatastore(t)
+	testPk, instance := createServiceInstanceDetailsInstance()
+	testCtx := context.Background()
+
+	if err := ds.CreateServiceInstanceDetails(testCtx, &instance); err != nil {
+		t.Fatalf("Expected to be able to create the item %#v, got error: %s", instance, err)
+	}
+
+	cases := map[string]struct {
+		SpaceGuid string
+		ServiceId string
+		Expected  bool
+	}{
+		"same space and service": {SpaceGuid: instance.SpaceGuid, ServiceId: instance.ServiceId, Expected: true},
+		"other service":          {SpaceGuid: instance.SpaceGuid, ServiceId: "other-service", Expected: false},
+		"other space":            {SpaceGuid: "other-space", ServiceId: instance.ServiceId, Expected: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			exists, err := ds.ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(testCtx, tc.SpaceGuid, tc.ServiceId)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if exists != tc.Expected {
+				t.Errorf("Expected exists to be %v, got %v", tc.Expected, exists)
+			}
+		})
+	}
+
+	if err := ds.DeleteServiceInstanceDetailsById(testCtx, testPk); err != nil {
+		t.Fatalf("Expected to be able to delete the item, got error: %s", err)
+	}
+
+	exists, err := ds.ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(testCtx, instance.SpaceGuid, instance.ServiceId)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if exists {
+		t.Errorf("Expected deleted instances not to satisfy prerequisites")
+	}
+}
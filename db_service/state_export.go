@@ -0,0 +1,307 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// stateArchiveVersion prefixes state archives so incompatible formats
+	// are recognized.
+	stateArchiveVersion = "csb-state-v1\n"
+
+	// stateArchiveSaltSize is the size of the random salt the archive key is
+	// derived with, stored after the version.
+	stateArchiveSaltSize = 16
+)
+
+// BrokerState is a snapshot of the instances and bindings of a broker, used
+// to back it up or migrate it to another database.
+type BrokerState struct {
+	Instances            []models.ServiceInstanceDetails    `json:"instances"`
+	Bindings             []models.ServiceBindingCredentials `json:"bindings"`
+	ProvisionRequests    []models.ProvisionRequestDetails   `json:"provision_requests"`
+	BindRequests         []models.BindRequestDetails        `json:"bind_requests,omitempty"`
+	TerraformDeployments []models.TerraformDeployment       `json:"terraform_deployments"`
+}
+
+// ExportState reads the state of the broker in a single transaction so the
+// snapshot is consistent.
+func ExportState(ctx context.Context) (*BrokerState, error) {
+	return defaultDatastore().ExportState(ctx)
+}
+func (ds *SqlDatastore) ExportState(ctx context.Context) (*BrokerState, error) {
+	tx := ds.db.Begin()
+	defer tx.Rollback()
+
+	state := &BrokerState{}
+	for _, rows := range []interface{}{&state.Instances, &state.Bindings, &state.ProvisionRequests, &state.BindRequests, &state.TerraformDeployments} {
+		if err := tx.Find(rows).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return state, nil
+}
+
+// ImportState restores the state of a broker in a single transaction. If any
+// instance, binding or Terraform deployment already exists, nothing is
+// imported and the conflicts are reported. Encrypted parameters are decrypted
+// with the key ring of the exporting broker, or the current one if nil, and
+// re-encrypted with the active key.
+func ImportState(ctx context.Context, state *BrokerState, source *models.KeyRing) error {
+	return defaultDatastore().ImportState(ctx, state, source)
+}
+func (ds *SqlDatastore) ImportState(ctx context.Context, state *BrokerState, source *models.KeyRing) error {
+	ds.instances.purge()
+	defer ds.instances.purge()
+
+	state, err := reencryptState(state, source, models.CurrentKeyRing())
+	if err != nil {
+		return err
+	}
+
+	tx := ds.db.Begin()
+
+	if err := checkStateConflicts(tx, state); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// records keyed by an auto incremented ID get a new one, as the
+	// destination may already use theirs
+	for _, binding := range state.Bindings {
+		binding.ID = 0
+		if err := tx.Create(&binding).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("importing binding %q: %v", binding.BindingId, err)
+		}
+	}
+
+	for _, request := range state.ProvisionRequests {
+		request.ID = 0
+		if err := tx.Create(&request).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("importing provision request of instance %q: %v", request.ServiceInstanceId, err)
+		}
+	}
+
+	for _, request := range state.BindRequests {
+		request.ID = 0
+		if err := tx.Create(&request).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("importing bind request of binding %q: %v", request.BindingId, err)
+		}
+	}
+
+	for _, instance := range state.Instances {
+		if err := tx.Create(&instance).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("importing instance %q: %v", instance.ID, err)
+		}
+	}
+
+	for _, deployment := range state.TerraformDeployments {
+		if err := tx.Create(&deployment).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("importing Terraform deployment %q: %v", deployment.ID, err)
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// reencryptState returns a copy of the state with the encrypted parameters
+// moved from the source key ring to the destination one, in plain text if
+// it's nil.
+func reencryptState(state *BrokerState, source, destination *models.KeyRing) (*BrokerState, error) {
+	if source == nil {
+		source = destination
+	}
+
+	reencrypt := func(value string) (string, error) {
+		plaintext, err := source.Decrypt(value)
+		if err != nil || destination == nil {
+			return plaintext, err
+		}
+		return destination.Encrypt(plaintext)
+	}
+
+	reencrypted := &BrokerState{
+		Bindings:             state.Bindings,
+		TerraformDeployments: state.TerraformDeployments,
+	}
+
+	for _, instance := range state.Instances {
+		params, err := reencrypt(instance.GeneratedParameters)
+		if err != nil {
+			return nil, fmt.Errorf("re-encrypting the generated parameters of instance %q: %v", instance.ID, err)
+		}
+		instance.GeneratedParameters = params
+		reencrypted.Instances = append(reencrypted.Instances, instance)
+	}
+
+	for _, request := range state.ProvisionRequests {
+		details, err := reencrypt(request.RequestDetails)
+		if err != nil {
+			return nil, fmt.Errorf("re-encrypting the provision request of instance %q: %v", request.ServiceInstanceId, err)
+		}
+		request.RequestDetails = details
+		reencrypted.ProvisionRequests = append(reencrypted.ProvisionRequests, request)
+	}
+
+	for _, request := range state.BindRequests {
+		details, err := reencrypt(request.RequestDetails)
+		if err != nil {
+			return nil, fmt.Errorf("re-encrypting the bind request of binding %q: %v", request.BindingId, err)
+		}
+		request.RequestDetails = details
+		reencrypted.BindRequests = append(reencrypted.BindRequests, request)
+	}
+
+	return reencrypted, nil
+}
+
+func checkStateConflicts(tx *gorm.DB, state *BrokerState) error {
+	var conflicts []string
+
+	for _, instance := range state.Instances {
+		var count int
+		if err := tx.Model(&models.ServiceInstanceDetails{}).Where("id = ?", instance.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("instance %q", instance.ID))
+		}
+	}
+
+	for _, binding := range state.Bindings {
+		var count int
+		if err := tx.Model(&models.ServiceBindingCredentials{}).Where("service_instance_id = ? AND binding_id = ?", binding.ServiceInstanceId, binding.BindingId).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("binding %q", binding.BindingId))
+		}
+	}
+
+	for _, deployment := range state.TerraformDeployments {
+		var count int
+		if err := tx.Model(&models.TerraformDeployment{}).Where("id = ?", deployment.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("Terraform deployment %q", deployment.ID))
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("the database already contains %s", strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+// SealState serializes the state into an archive encrypted and authenticated
+// with the key, so it can't be read or tampered with without it.
+func SealState(state *BrokerState, key string) ([]byte, error) {
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, stateArchiveSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := stateCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(stateArchiveVersion), salt...)
+	archive := append(append([]byte{}, header...), nonce...)
+	return aead.Seal(archive, nonce, plaintext, header), nil
+}
+
+// OpenState decrypts an archive created by SealState with the same key.
+func OpenState(archive []byte, key string) (*BrokerState, error) {
+	if !strings.HasPrefix(string(archive), stateArchiveVersion) {
+		return nil, errors.New("not a broker state archive")
+	}
+	if len(archive) < len(stateArchiveVersion)+stateArchiveSaltSize {
+		return nil, errors.New("the state archive is truncated")
+	}
+	header, archive := archive[:len(stateArchiveVersion)+stateArchiveSaltSize], archive[len(stateArchiveVersion)+stateArchiveSaltSize:]
+
+	aead, err := stateCipher(key, header[len(stateArchiveVersion):])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(archive) < aead.NonceSize() {
+		return nil, errors.New("the state archive is truncated")
+	}
+	nonce, ciphertext := archive[:aead.NonceSize()], archive[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, errors.New("the state archive was modified or the key is wrong")
+	}
+
+	state := &BrokerState{}
+	if err := json.Unmarshal(plaintext, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// stateCipher derives the archive cipher from the key and the salt of the
+// archive with scrypt, so weak keys are expensive to brute force.
+func stateCipher(key string, salt []byte) (cipher.AEAD, error) {
+	if key == "" {
+		return nil, errors.New("a key is required to encrypt the state archive")
+	}
+
+	derived, err := scrypt.Key([]byte(key), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
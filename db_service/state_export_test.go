@@ -0,0 +1,153 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestSqlDatastore_ExportImportState(t *testing.T) {
+	testCtx := context.Background()
+
+	source := newInMemoryDatastore(t)
+	_, instance := createServiceInstanceDetailsInstance()
+	_, binding := createServiceBindingCredentialsInstance()
+	_, request := createProvisionRequestDetailsInstance()
+	_, deployment := createTerraformDeploymentInstance()
+	for _, record := range []interface{}{&instance, &binding, &request, &deployment} {
+		if err := source.db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	state, err := source.ExportState(testCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := SealState(state, "secret key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenState(archive, "other key"); err == nil {
+		t.Error("expected opening the archive with another key to fail")
+	}
+
+	tampered := append([]byte{}, archive...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := OpenState(tampered, "secret key"); err == nil {
+		t.Error("expected opening a modified archive to fail")
+	}
+
+	opened, err := OpenState(archive, "secret key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	destination := newInMemoryDatastore(t)
+	if err := destination.ImportState(testCtx, opened, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	gotInstance, err := destination.GetServiceInstanceDetailsById(testCtx, instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureServiceInstanceDetailsFieldsMatch(t, &instance, gotInstance)
+
+	gotBinding, err := destination.GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(testCtx, binding.ServiceInstanceId, binding.BindingId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureServiceBindingCredentialsFieldsMatch(t, &binding, gotBinding)
+
+	gotRequest, err := destination.GetProvisionRequestDetailsById(testCtx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureProvisionRequestDetailsFieldsMatch(t, &request, gotRequest)
+
+	gotDeployment, err := destination.GetTerraformDeploymentById(testCtx, deployment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ensureTerraformDeploymentFieldsMatch(t, &deployment, gotDeployment)
+
+	err = destination.ImportState(testCtx, opened, nil)
+	if err == nil || !strings.Contains(err.Error(), "the database already contains") {
+		t.Errorf("expected importing twice to conflict, got %v", err)
+	}
+}
+
+func TestSqlDatastore_ImportState_Reencrypts(t *testing.T) {
+	testCtx := context.Background()
+	defer models.SetKeyRing(nil)
+
+	sourceRing, err := models.NewKeyRing(map[string]string{"source": "source-secret"}, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	destinationRing, err := models.NewKeyRing(map[string]string{"destination": "destination-secret"}, "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models.SetKeyRing(sourceRing)
+	source := newInMemoryDatastore(t)
+	_, instance := createServiceInstanceDetailsInstance()
+	if err := instance.SetGeneratedParameters(map[string]string{"password": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := source.db.Create(&instance).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := source.ExportState(testCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models.SetKeyRing(destinationRing)
+	destination := newInMemoryDatastore(t)
+	if err := destination.ImportState(testCtx, state, nil); err == nil {
+		t.Error("expected importing without the source keys to fail")
+	}
+
+	if err := destination.ImportState(testCtx, state, sourceRing); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := destination.GetServiceInstanceDetailsById(testCtx, instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if destinationRing.NeedsRotation(got.GeneratedParameters) {
+		t.Errorf("expected the parameters to be encrypted with the destination key, got %q", got.GeneratedParameters)
+	}
+
+	params, err := got.GetGeneratedParameters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["password"] != "hunter2" {
+		t.Errorf("expected the parameters to survive the import, got %v", params)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+// Store persists the state the broker keeps about service instances, their
+// bindings, provision and bind requests, along with the history of their
+// operations and the idempotency keys of requests. SqlDatastore stores it in
+// the broker's database and MemoryStore keeps it in memory, e.g. for tests.
+//
+// Lookups of missing records MUST return gorm.ErrRecordNotFound, callers
+// check for it.
+type Store interface {
+	CreateServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	SaveServiceInstanceDetails(ctx context.Context, object *models.ServiceInstanceDetails) error
+	DeleteServiceInstanceDetailsById(ctx context.Context, id string) error
+	DeleteServiceInstanceDetails(ctx context.Context, record *models.ServiceInstanceDetails) error
+	GetServiceInstanceDetailsById(ctx context.Context, id string) (*models.ServiceInstanceDetails, error)
+	ExistsServiceInstanceDetailsById(ctx context.Context, id string) (bool, error)
+	ExistsServiceInstanceDetailsBySpaceGuidAndInstanceName(ctx context.Context, spaceGuid, instanceName string) (bool, error)
+	ExistsServiceInstanceDetailsBySpaceGuidAndServiceId(ctx context.Context, spaceGuid, serviceId string) (bool, error)
+	CountServiceInstancesByPlan(ctx context.Context, planId string) (int, error)
+	GetServiceInstanceDetailsWithOperationId(ctx context.Context) ([]models.ServiceInstanceDetails, error)
+
+	CreateServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	SaveServiceBindingCredentials(ctx context.Context, object *models.ServiceBindingCredentials) error
+	DeleteServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) error
+	DeleteServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) error
+	DeleteServiceBindingCredentialsById(ctx context.Context, id uint) error
+	DeleteServiceBindingCredentials(ctx context.Context, record *models.ServiceBindingCredentials) error
+	GetServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsByServiceInstanceIdAndBindingId(ctx context.Context, serviceInstanceId string, bindingId string) (bool, error)
+	GetServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsByBindingId(ctx context.Context, bindingId string) (bool, error)
+	GetServiceBindingCredentialsById(ctx context.Context, id uint) (*models.ServiceBindingCredentials, error)
+	ExistsServiceBindingCredentialsById(ctx context.Context, id uint) (bool, error)
+	GetServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.ServiceBindingCredentials, error)
+	CountServiceBindingCredentialsByServiceInstanceId(ctx context.Context, serviceInstanceId string) (int, error)
+	GetDeletedServiceBindingCredentials(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error)
+	PurgeDeletedServiceBindingCredentials(ctx context.Context, before time.Time) error
+
+	CreateProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	SaveProvisionRequestDetails(ctx context.Context, object *models.ProvisionRequestDetails) error
+	DeleteProvisionRequestDetailsById(ctx context.Context, id uint) error
+	DeleteProvisionRequestDetails(ctx context.Context, record *models.ProvisionRequestDetails) error
+	GetProvisionRequestDetailsById(ctx context.Context, id uint) (*models.ProvisionRequestDetails, error)
+	ExistsProvisionRequestDetailsById(ctx context.Context, id uint) (bool, error)
+
+	CreateBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error
+	SaveBindRequestDetails(ctx context.Context, object *models.BindRequestDetails) error
+	DeleteBindRequestDetailsByBindingId(ctx context.Context, bindingId string) error
+	DeleteBindRequestDetailsById(ctx context.Context, id uint) error
+	DeleteBindRequestDetails(ctx context.Context, record *models.BindRequestDetails) error
+	GetBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (*models.BindRequestDetails, error)
+	ExistsBindRequestDetailsByBindingId(ctx context.Context, bindingId string) (bool, error)
+	GetBindRequestDetailsById(ctx context.Context, id uint) (*models.BindRequestDetails, error)
+	ExistsBindRequestDetailsById(ctx context.Context, id uint) (bool, error)
+
+	CreateOperationHistory(ctx context.Context, object *models.OperationHistory) error
+	FinishOperationHistory(ctx context.Context, serviceInstanceId, state, errMessage string) error
+	GetOperationHistoryByServiceInstanceId(ctx context.Context, serviceInstanceId string) ([]models.OperationHistory, error)
+
+	CreateIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error
+	SaveIdempotencyKey(ctx context.Context, object *models.IdempotencyKey) error
+	GetIdempotencyKeyByRequestIdentity(ctx context.Context, requestIdentity string) (*models.IdempotencyKey, error)
+	DeleteIdempotencyKey(ctx context.Context, requestIdentity string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) error
+}
+
+var _ Store = (*SqlDatastore)(nil)
+
+var (
+	storeMu     sync.RWMutex
+	activeStore Store
+)
+
+// SetStore replaces the store the package level functions use. A nil store
+// restores the default, the database opened by New.
+func SetStore(store Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	activeStore = store
+}
+
+// currentStore returns the store set with SetStore, or the default datastore
+// if there is none.
+func currentStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	if activeStore != nil {
+		return activeStore
+	}
+
+	return defaultDatastore()
+}
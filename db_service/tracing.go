@@ -0,0 +1,38 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package db_service
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pivotal/cloud-service-broker/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span covering a database call.
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.StartSpan(ctx, "db."+operation)
+}
+
+// endSpan ends the span of a database call. Missing records are an expected
+// outcome rather than a failure.
+func endSpan(span trace.Span, err error) {
+	if gorm.IsRecordNotFoundError(err) {
+		err = nil
+	}
+
+	tracing.End(span, err)
+}
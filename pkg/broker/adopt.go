@@ -0,0 +1,59 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+// AdoptRequest identifies existing cloud resources an operator wants to
+// record as a provisioned instance, e.g. when migrating to this broker.
+type AdoptRequest struct {
+	ServiceID        string `json:"service_id"`
+	PlanID           string `json:"plan_id"`
+	OrganizationGUID string `json:"organization_guid"`
+	SpaceGUID        string `json:"space_guid"`
+	Name             string `json:"name"`
+
+	// Resources identifies the existing resources, e.g. by name or ID. It is
+	// stored as the details of the instance so the provider can look them up.
+	Resources map[string]interface{} `json:"resources"`
+
+	// Parameters are recorded as the parameters the instance was provisioned
+	// with.
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+}
+
+var _ validation.Validatable = (*AdoptRequest)(nil)
+
+// Validate implements validation.Validatable.
+func (ar *AdoptRequest) Validate() (errs *validation.FieldError) {
+	errs = errs.Also(
+		validation.ErrIfBlank(ar.ServiceID, "service_id"),
+		validation.ErrIfBlank(ar.PlanID, "plan_id"),
+	)
+
+	if len(ar.Resources) == 0 {
+		errs = errs.Also(validation.ErrMissingField("resources"))
+	}
+
+	if len(ar.Parameters) > 0 {
+		errs = errs.Also(validation.ErrIfNotJSON(ar.Parameters, "parameters"))
+	}
+
+	return errs
+}
@@ -0,0 +1,186 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+// AvailabilityZonesParameter is the user parameter holding the zones an
+// instance spans.
+const AvailabilityZonesParameter = "availability_zones"
+
+// PlanAvailabilityZones configures the zones instances of a plan may span.
+type PlanAvailabilityZones struct {
+	// Allowed lists the zones users may choose from. Any zone is allowed if
+	// it's empty.
+	Allowed []string `json:"allowed,omitempty" yaml:"allowed,omitempty"`
+
+	// Default is used when the user doesn't supply zones.
+	Default []string `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// MinZones and MaxZones bound the number of zones, e.g. multi-AZ plans
+	// require at least two. Zero means no bound.
+	MinZones int `json:"min_zones,omitempty" yaml:"min_zones,omitempty"`
+	MaxZones int `json:"max_zones,omitempty" yaml:"max_zones,omitempty"`
+}
+
+var _ validation.Validatable = (*PlanAvailabilityZones)(nil)
+
+// Validate implements validation.Validatable.
+func (pz *PlanAvailabilityZones) Validate() (errs *validation.FieldError) {
+	for i, zone := range pz.Allowed {
+		if zone == "" {
+			errs = errs.Also(validation.ErrInvalidArrayValue(zone, "allowed", i))
+		}
+	}
+
+	if pz.MinZones < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(pz.MinZones, "min_zones"))
+	}
+
+	if pz.MaxZones < 0 || (pz.MaxZones > 0 && pz.MaxZones < pz.MinZones) {
+		errs = errs.Also(validation.ErrInvalidValue(pz.MaxZones, "max_zones"))
+	}
+
+	if len(pz.Allowed) > 0 && pz.MinZones > len(pz.Allowed) {
+		errs = errs.Also(validation.ErrInvalidValue(pz.MinZones, "min_zones"))
+	}
+
+	if len(pz.Default) > 0 {
+		if err := pz.check(pz.Default); err != nil {
+			errs = errs.Also(&validation.FieldError{Message: err.Error(), Paths: []string{"default"}})
+		}
+	}
+
+	return errs
+}
+
+// check returns an error if the zones aren't allowed or their number is out
+// of bounds.
+func (pz *PlanAvailabilityZones) check(zones []string) error {
+	if len(pz.Allowed) > 0 {
+		allowed := utils.NewStringSet(pz.Allowed...)
+		for _, zone := range zones {
+			if !allowed.Contains(zone) {
+				return fmt.Errorf("availability zone %q is not allowed, valid zones are: %s", zone, strings.Join(pz.Allowed, ", "))
+			}
+		}
+	}
+
+	if len(zones) < pz.MinZones {
+		return fmt.Errorf("at least %d availability zones are required, got %d", pz.MinZones, len(zones))
+	}
+
+	if pz.MaxZones > 0 && len(zones) > pz.MaxZones {
+		return fmt.Errorf("at most %d availability zones are allowed, got %d", pz.MaxZones, len(zones))
+	}
+
+	return nil
+}
+
+func errInvalidAvailabilityZones(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-availability-zones")
+}
+
+// ResolveAvailabilityZones extracts the zones from the raw provision
+// parameters, falling back to the plan's defaults, and validates them against
+// the plan.
+func ResolveAvailabilityZones(rawParameters json.RawMessage, plan ServicePlan) ([]string, error) {
+	params := map[string]interface{}{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	zones, err := availabilityZonesParameter(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if plan.AvailabilityZones == nil {
+		return zones, nil
+	}
+
+	if len(zones) == 0 {
+		zones = copyStrings(plan.AvailabilityZones.Default)
+	}
+
+	if err := plan.AvailabilityZones.check(zones); err != nil {
+		return nil, errInvalidAvailabilityZones("invalid %s for plan %q: %v", AvailabilityZonesParameter, plan.Name, err)
+	}
+
+	return zones, nil
+}
+
+func availabilityZonesParameter(params map[string]interface{}) ([]string, error) {
+	value, ok := params[AvailabilityZonesParameter]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, errInvalidAvailabilityZones("%s must be an array of strings", AvailabilityZonesParameter)
+	}
+
+	var zones []string
+	seen := utils.NewStringSet()
+	for _, item := range list {
+		zone, ok := item.(string)
+		if !ok || zone == "" {
+			return nil, errInvalidAvailabilityZones("%s must be an array of strings", AvailabilityZonesParameter)
+		}
+
+		if seen.Contains(zone) {
+			return nil, errInvalidAvailabilityZones("%s must not contain %q more than once", AvailabilityZonesParameter, zone)
+		}
+
+		seen.Add(zone)
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+// availabilityZonesVariable converts the zones to the list type of the
+// expression language.
+func availabilityZonesVariable(zones []string) []interface{} {
+	list := make([]interface{}, len(zones))
+	for i, zone := range zones {
+		list[i] = zone
+	}
+
+	return list
+}
+
+// availabilityZonesVariables returns the variables to merge into a request
+// context so templates see the zones of the instance, if it has any.
+func availabilityZonesVariables(zones interface{}) map[string]interface{} {
+	list, ok := zones.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil
+	}
+
+	return map[string]interface{}{AvailabilityZonesParameter: list}
+}
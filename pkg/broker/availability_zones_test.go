@@ -0,0 +1,200 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestPlanAvailabilityZones_Validate(t *testing.T) {
+	cases := map[string]struct {
+		Zones    PlanAvailabilityZones
+		Expected string
+	}{
+		"empty": {},
+		"good": {
+			Zones: PlanAvailabilityZones{Allowed: []string{"a", "b"}, Default: []string{"a", "b"}, MinZones: 2, MaxZones: 2},
+		},
+		"blank zone": {
+			Zones:    PlanAvailabilityZones{Allowed: []string{"a", ""}},
+			Expected: "invalid value: : allowed[1]",
+		},
+		"max below min": {
+			Zones:    PlanAvailabilityZones{MinZones: 3, MaxZones: 2},
+			Expected: "invalid value: 2: max_zones",
+		},
+		"min above allowed": {
+			Zones:    PlanAvailabilityZones{Allowed: []string{"a"}, MinZones: 2},
+			Expected: "invalid value: 2: min_zones",
+		},
+		"default not allowed": {
+			Zones:    PlanAvailabilityZones{Allowed: []string{"a"}, Default: []string{"b"}},
+			Expected: `availability zone "b" is not allowed, valid zones are: a: default`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := ""
+			if err := tc.Zones.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected: %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestResolveAvailabilityZones(t *testing.T) {
+	plan := ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{Name: "multi-az"},
+		AvailabilityZones: &PlanAvailabilityZones{
+			Allowed:  []string{"zone-a", "zone-b", "zone-c"},
+			Default:  []string{"zone-a", "zone-b"},
+			MinZones: 2,
+			MaxZones: 3,
+		},
+	}
+
+	cases := map[string]struct {
+		Plan          ServicePlan
+		Raw           string
+		Expected      []string
+		ExpectedError error
+	}{
+		"no plan zones and no params": {
+			Plan: ServicePlan{},
+			Raw:  `{}`,
+		},
+		"no plan zones": {
+			Plan:     ServicePlan{},
+			Raw:      `{"availability_zones":["us-east-1a"]}`,
+			Expected: []string{"us-east-1a"},
+		},
+		"plan default": {
+			Plan:     plan,
+			Raw:      ``,
+			Expected: []string{"zone-a", "zone-b"},
+		},
+		"user zones": {
+			Plan:     plan,
+			Raw:      `{"availability_zones":["zone-c","zone-b"]}`,
+			Expected: []string{"zone-c", "zone-b"},
+		},
+		"not allowed": {
+			Plan:          plan,
+			Raw:           `{"availability_zones":["zone-a","zone-d"]}`,
+			ExpectedError: errors.New(`invalid availability_zones for plan "multi-az": availability zone "zone-d" is not allowed, valid zones are: zone-a, zone-b, zone-c`),
+		},
+		"too few": {
+			Plan:          plan,
+			Raw:           `{"availability_zones":["zone-a"]}`,
+			ExpectedError: errors.New(`invalid availability_zones for plan "multi-az": at least 2 availability zones are required, got 1`),
+		},
+		"too many": {
+			Plan: ServicePlan{
+				ServicePlan:       brokerapi.ServicePlan{Name: "single-az"},
+				AvailabilityZones: &PlanAvailabilityZones{MaxZones: 1},
+			},
+			Raw:           `{"availability_zones":["zone-a","zone-b"]}`,
+			ExpectedError: errors.New(`invalid availability_zones for plan "single-az": at most 1 availability zones are allowed, got 2`),
+		},
+		"duplicate": {
+			Plan:          plan,
+			Raw:           `{"availability_zones":["zone-a","zone-a"]}`,
+			ExpectedError: errors.New(`availability_zones must not contain "zone-a" more than once`),
+		},
+		"not an array": {
+			Plan:          ServicePlan{},
+			Raw:           `{"availability_zones":"zone-a"}`,
+			ExpectedError: errors.New("availability_zones must be an array of strings"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := ResolveAvailabilityZones(json.RawMessage(tc.Raw), tc.Plan)
+			expectError(t, tc.ExpectedError, err)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected zones %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_AvailabilityZones(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+	}
+	plan := ServicePlan{
+		ServicePlan:       brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"},
+		AvailabilityZones: &PlanAvailabilityZones{Default: []string{"zone-a", "zone-b"}},
+	}
+	expected := map[string]interface{}{"availability_zones": []interface{}{"zone-a", "zone-b"}}
+
+	t.Run("provision", func(t *testing.T) {
+		vars, err := service.ProvisionVariables(testInstanceID, brokerapi.ProvisionDetails{}, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	instance := models.ServiceInstanceDetails{ID: testInstanceID}
+	if err := instance.SetAvailabilityZones([]string{"zone-a", "zone-b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		vars, err := service.UpdateVariables(instance, brokerapi.UpdateDetails{}, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		vars, err := service.BindVariables(instance, "binding-id", brokerapi.BindDetails{}, &plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("update prohibited", func(t *testing.T) {
+		details := brokerapi.UpdateDetails{RawParameters: json.RawMessage(`{"availability_zones":["zone-c"]}`)}
+		classification, err := service.ClassifyUpdate(details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(classification.Prohibited, []string{"availability_zones"}) {
+			t.Errorf("Expected availability_zones to be prohibited, got %v", classification.Prohibited)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext/interpolation"
+)
+
+// BindParameters returns the user supplied bind parameters on top of the
+// operator defaults with the defaults of the service's bind inputs applied, so
+// choices such as the role see the effective value. Defaults are also applied
+// to omitted properties of object parameters whose constraints declare them.
+// Template defaults are left to be evaluated with the other bind variables.
+func (svc *ServiceDefinition) BindParameters(rawParameters json.RawMessage) (json.RawMessage, error) {
+	user := map[string]interface{}{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &user); err != nil {
+			return nil, err
+		}
+	}
+
+	params := map[string]interface{}{}
+	for k, v := range svc.BindDefaultOverrides() {
+		params[k] = v
+	}
+	for k, v := range user {
+		params[k] = v
+	}
+
+	for _, v := range svc.BindInputVariables {
+		value, ok := params[v.FieldName]
+		if !ok && v.Default != nil && !isTemplateDefault(v.Default) {
+			value = v.Default
+			params[v.FieldName] = value
+		}
+
+		if object, ok := value.(map[string]interface{}); ok {
+			applyNestedDefaults(object, v.Constraints)
+		}
+	}
+
+	if len(params) == 0 {
+		return rawParameters, nil
+	}
+	return json.Marshal(params)
+}
+
+// applyNestedDefaults sets the defaults of the schema's properties the object
+// lacks, recursing into nested objects.
+func applyNestedDefaults(object map[string]interface{}, schema map[string]interface{}) {
+	properties, _ := schema[validation.KeyProperties].(map[string]interface{})
+	for name, raw := range properties {
+		property, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		value, ok := object[name]
+		if !ok {
+			if def, hasDefault := property[validation.KeyDefault]; hasDefault {
+				value = def
+				object[name] = value
+			}
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			applyNestedDefaults(nested, property)
+		}
+	}
+}
+
+func isTemplateDefault(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && interpolation.IsHILExpression(s)
+}
+
+func errInvalidBindParameters(err error) error {
+	return brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-bind-parameters")
+}
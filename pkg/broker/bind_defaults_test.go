@@ -0,0 +1,183 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestServiceDefinition_BindParameters(t *testing.T) {
+	service := ServiceDefinition{
+		Name: "bind-defaults",
+		BindInputVariables: []BrokerVariable{
+			{FieldName: "role", Type: JsonTypeString, Default: "reader"},
+			{FieldName: "name", Type: JsonTypeString, Default: "name-${request.binding_id}"},
+			{
+				FieldName: "access",
+				Type:      JsonTypeObject,
+				Default:   map[string]interface{}{},
+				Constraints: map[string]interface{}{
+					"properties": map[string]interface{}{
+						"ttl": map[string]interface{}{"type": "integer", "default": 3600},
+						"network": map[string]interface{}{
+							"type":    "object",
+							"default": map[string]interface{}{},
+							"properties": map[string]interface{}{
+								"public": map[string]interface{}{"type": "boolean", "default": false},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cases := map[string]struct {
+		Raw      string
+		Expected map[string]interface{}
+	}{
+		"no parameters": {
+			Raw: ``,
+			Expected: map[string]interface{}{
+				"role":   "reader",
+				"access": map[string]interface{}{"ttl": 3600.0, "network": map[string]interface{}{"public": false}},
+			},
+		},
+		"user values are kept": {
+			Raw: `{"role":"admin","access":{"ttl":60}}`,
+			Expected: map[string]interface{}{
+				"role":   "admin",
+				"access": map[string]interface{}{"ttl": 60.0, "network": map[string]interface{}{"public": false}},
+			},
+		},
+		"nested user values are kept": {
+			Raw: `{"access":{"network":{"public":true}}}`,
+			Expected: map[string]interface{}{
+				"role":   "reader",
+				"access": map[string]interface{}{"ttl": 3600.0, "network": map[string]interface{}{"public": true}},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			raw, err := service.BindParameters(json.RawMessage(tc.Raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := map[string]interface{}{}
+			if err := json.Unmarshal(raw, &actual); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected parameters %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_BindVariables_defaults(t *testing.T) {
+	service := ServiceDefinition{
+		Name: "bind-defaults",
+		BindInputVariables: []BrokerVariable{
+			{FieldName: "role", Type: JsonTypeString, Default: "reader"},
+			{
+				FieldName: "access",
+				Type:      JsonTypeObject,
+				Default:   map[string]interface{}{},
+				Constraints: map[string]interface{}{
+					"properties": map[string]interface{}{
+						"ttl": map[string]interface{}{"type": "integer", "default": 3600, "maximum": 86400},
+					},
+				},
+			},
+		},
+	}
+
+	plan := &ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{ID: "plan", Name: "standard"},
+		Roles:       []string{"admin", "reader"},
+	}
+	writerOnly := &ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{ID: "plan", Name: "writer-only"},
+		Roles:       []string{"writer"},
+	}
+
+	cases := map[string]struct {
+		Raw            string
+		Plan           *ServicePlan
+		ExpectedRole   string
+		ExpectedTTL    int
+		ExpectedError  error
+		ExpectedStatus int
+	}{
+		"defaults applied": {
+			Plan:         plan,
+			ExpectedRole: "reader",
+			ExpectedTTL:  3600,
+		},
+		"role given": {
+			Raw:          `{"role":"admin","access":{"ttl":60}}`,
+			Plan:         plan,
+			ExpectedRole: "admin",
+			ExpectedTTL:  60,
+		},
+		"default role not valid for plan": {
+			Plan:          writerOnly,
+			ExpectedError: errors.New(`role "reader" is not valid for plan "writer-only", valid roles are: writer`),
+		},
+		"nested value invalid": {
+			Raw:            `{"access":{"ttl":90000}}`,
+			Plan:           plan,
+			ExpectedError:  errors.New("1 error(s) occurred: access.ttl: Must be less than or equal to 86400"),
+			ExpectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			details := brokerapi.BindDetails{RawParameters: json.RawMessage(tc.Raw)}
+			vc, err := service.BindVariables(models.ServiceInstanceDetails{}, "binding-id", details, tc.Plan)
+			expectError(t, tc.ExpectedError, err)
+			if tc.ExpectedStatus != 0 {
+				failure, ok := err.(*brokerapi.FailureResponse)
+				if !ok || failure.ValidatedStatusCode(nil) != tc.ExpectedStatus {
+					t.Errorf("expected a failure response with status %d, got %#v", tc.ExpectedStatus, err)
+				}
+			}
+			if err != nil {
+				return
+			}
+
+			if role := vc.GetString("role"); role != tc.ExpectedRole {
+				t.Errorf("expected role %q, got %q", tc.ExpectedRole, role)
+			}
+
+			access := vc.ToMap()["access"].(map[string]interface{})
+			if ttl := access["ttl"]; ttl != float64(tc.ExpectedTTL) {
+				t.Errorf("expected ttl %d, got %v", tc.ExpectedTTL, ttl)
+			}
+		})
+	}
+}
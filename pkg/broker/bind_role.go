@@ -0,0 +1,74 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// BindRoleParameter is the user parameter selecting which of the plan's roles
+// the credentials of a binding are scoped to.
+const BindRoleParameter = "role"
+
+func errInvalidBindRole(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-role")
+}
+
+// BindRole extracts the role from the raw bind parameters. An empty string is
+// returned if no role was supplied.
+func BindRole(rawParameters json.RawMessage) (string, error) {
+	if len(rawParameters) == 0 {
+		return "", nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return "", err
+	}
+
+	value, ok := params[BindRoleParameter]
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	role, ok := value.(string)
+	if !ok {
+		return "", errInvalidBindRole("%s must be a string", BindRoleParameter)
+	}
+
+	return role, nil
+}
+
+// ValidateRole checks the role is one the plan allows. Plans that don't
+// declare roles leave the parameter to the service's own bind schema, and an
+// empty role leaves the choice of privileges to the service.
+func (sp *ServicePlan) ValidateRole(role string) error {
+	if role == "" || len(sp.Roles) == 0 {
+		return nil
+	}
+
+	for _, allowed := range sp.Roles {
+		if role == allowed {
+			return nil
+		}
+	}
+
+	return errInvalidBindRole("%s %q is not valid for plan %q, valid roles are: %s", BindRoleParameter, role, sp.Name, strings.Join(sp.Roles, ", "))
+}
@@ -0,0 +1,74 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestBindRole(t *testing.T) {
+	cases := map[string]struct {
+		Raw           string
+		ExpectedRole  string
+		ExpectedError error
+	}{
+		"empty":      {Raw: ``},
+		"missing":    {Raw: `{"other":"value"}`},
+		"present":    {Raw: `{"role":"reader"}`, ExpectedRole: "reader"},
+		"not string": {Raw: `{"role":42}`, ExpectedError: errors.New("role must be a string")},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			role, err := BindRole(json.RawMessage(tc.Raw))
+			expectError(t, tc.ExpectedError, err)
+			if role != tc.ExpectedRole {
+				t.Errorf("expected role %q, got %q", tc.ExpectedRole, role)
+			}
+		})
+	}
+}
+
+func TestServicePlan_ValidateRole(t *testing.T) {
+	plan := ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{Name: "standard"},
+		Roles:       []string{"admin", "reader", "writer"},
+	}
+
+	cases := map[string]struct {
+		Plan          ServicePlan
+		Role          string
+		ExpectedError error
+	}{
+		"empty role": {Plan: plan, Role: ""},
+		"allowed":    {Plan: plan, Role: "reader"},
+		"unknown": {
+			Plan:          plan,
+			Role:          "owner",
+			ExpectedError: errors.New(`role "owner" is not valid for plan "standard", valid roles are: admin, reader, writer`),
+		},
+		"plan without roles": {Plan: ServicePlan{}, Role: "owner"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			expectError(t, tc.ExpectedError, tc.Plan.ValidateRole(tc.Role))
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+const (
+	// BindingNameParameter is the user parameter naming a binding, database
+	// services use it as the username of the binding's credentials.
+	BindingNameParameter = "name"
+
+	// MaxBindingNameLength is the longest username MySQL accepts, PostgreSQL
+	// allows longer identifiers.
+	MaxBindingNameLength = 32
+)
+
+var (
+	// validBindingName matches unquoted identifiers valid in both MySQL and
+	// PostgreSQL.
+	validBindingName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+	// reservedBindingNames are the administrative users of the databases.
+	reservedBindingNames = []string{"root", "admin", "postgres", "mysql", "public", "rdsadmin", "cloudsqladmin"}
+)
+
+func errInvalidBindingName(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-binding-name")
+}
+
+// BindingName extracts the name of the binding from the raw bind parameters
+// and validates it's a database identifier: lower case letters, digits and
+// underscores not starting with a digit, at most MaxBindingNameLength long and
+// not a reserved user. An empty string is returned if no name was supplied or
+// the service declares its own name bind input, which is left to its schema.
+func (svc *ServiceDefinition) BindingName(rawParameters json.RawMessage) (string, error) {
+	if len(rawParameters) == 0 || svc.declaresBindingName() {
+		return "", nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return "", err
+	}
+
+	value, ok := params[BindingNameParameter]
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	name, ok := value.(string)
+	if !ok {
+		return "", errInvalidBindingName("%s must be a string", BindingNameParameter)
+	}
+
+	switch {
+	case len(name) > MaxBindingNameLength:
+		return "", errInvalidBindingName("%s must be at most %d characters long", BindingNameParameter, MaxBindingNameLength)
+	case !validBindingName.MatchString(name):
+		return "", errInvalidBindingName("%s must only contain lower case letters, digits and underscores and must not start with a digit", BindingNameParameter)
+	}
+
+	for _, reserved := range reservedBindingNames {
+		if name == reserved {
+			return "", errInvalidBindingName("%s %q is reserved, reserved names are: %s", BindingNameParameter, name, strings.Join(reservedBindingNames, ", "))
+		}
+	}
+
+	return name, nil
+}
+
+// declaresBindingName returns true if the service has a name bind input of
+// its own.
+func (svc *ServiceDefinition) declaresBindingName() bool {
+	for _, input := range svc.BindInputVariables {
+		if input.FieldName == BindingNameParameter {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withoutBindingName removes the binding name from the raw bind parameters so
+// it isn't passed to providers, unless the service declares its own name bind
+// input.
+func (svc *ServiceDefinition) withoutBindingName(rawParameters json.RawMessage) (json.RawMessage, error) {
+	if len(rawParameters) == 0 || svc.declaresBindingName() {
+		return rawParameters, nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	if _, ok := params[BindingNameParameter]; !ok {
+		return rawParameters, nil
+	}
+
+	delete(params, BindingNameParameter)
+	return json.Marshal(params)
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+func TestServiceDefinition_BindingName(t *testing.T) {
+	cases := map[string]struct {
+		Service       ServiceDefinition
+		Raw           string
+		ExpectedName  string
+		ExpectedError error
+	}{
+		"empty":      {Raw: ``},
+		"missing":    {Raw: `{"other":"value"}`},
+		"null":       {Raw: `{"name":null}`},
+		"valid":      {Raw: `{"name":"reporting_user"}`, ExpectedName: "reporting_user"},
+		"underscore": {Raw: `{"name":"_etl"}`, ExpectedName: "_etl"},
+		"max length": {Raw: `{"name":"abcdefghijklmnopqrstuvwxyz012345"}`, ExpectedName: "abcdefghijklmnopqrstuvwxyz012345"},
+		"not string": {Raw: `{"name":42}`, ExpectedError: errors.New("name must be a string")},
+		"too long": {
+			Raw:           `{"name":"abcdefghijklmnopqrstuvwxyz0123456"}`,
+			ExpectedError: errors.New("name must be at most 32 characters long"),
+		},
+		"upper case": {
+			Raw:           `{"name":"Reporting"}`,
+			ExpectedError: errors.New("name must only contain lower case letters, digits and underscores and must not start with a digit"),
+		},
+		"leading digit": {
+			Raw:           `{"name":"1user"}`,
+			ExpectedError: errors.New("name must only contain lower case letters, digits and underscores and must not start with a digit"),
+		},
+		"quote": {
+			Raw:           `{"name":"user\";drop"}`,
+			ExpectedError: errors.New("name must only contain lower case letters, digits and underscores and must not start with a digit"),
+		},
+		"reserved": {
+			Raw:           `{"name":"postgres"}`,
+			ExpectedError: errors.New(`name "postgres" is reserved, reserved names are: root, admin, postgres, mysql, public, rdsadmin, cloudsqladmin`),
+		},
+		"declared input": {
+			Service: ServiceDefinition{BindInputVariables: []BrokerVariable{{FieldName: "name", Type: JsonTypeString}}},
+			Raw:     `{"name":"Anything Goes"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			name, err := tc.Service.BindingName(json.RawMessage(tc.Raw))
+			expectError(t, tc.ExpectedError, err)
+			if name != tc.ExpectedName {
+				t.Errorf("expected name %q, got %q", tc.ExpectedName, name)
+			}
+			if err != nil {
+				if status := err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil); status != http.StatusBadRequest {
+					t.Errorf("expected status %d, got %d", http.StatusBadRequest, status)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_BindVariables_bindingName(t *testing.T) {
+	service := ServiceDefinition{
+		Name: "named-bindings",
+		BindInputVariables: []BrokerVariable{
+			{FieldName: "role", Type: JsonTypeString, Default: "reader"},
+		},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "username", Default: "${request.binding_name}", Overwrite: true},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "plan", Name: "standard"}}
+
+	cases := map[string]struct {
+		Raw           string
+		Expected      map[string]interface{}
+		ExpectedError error
+	}{
+		"named": {
+			Raw:      `{"name":"reporting"}`,
+			Expected: map[string]interface{}{"role": "reader", "username": "reporting"},
+		},
+		"unnamed": {
+			Raw:      `{"role":"writer"}`,
+			Expected: map[string]interface{}{"role": "writer", "username": ""},
+		},
+		"invalid": {
+			Raw:           `{"name":"admin"}`,
+			ExpectedError: errors.New(`name "admin" is reserved, reserved names are: root, admin, postgres, mysql, public, rdsadmin, cloudsqladmin`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			details := brokerapi.BindDetails{RawParameters: json.RawMessage(tc.Raw)}
+			vars, err := service.BindVariables(models.ServiceInstanceDetails{}, "binding-id", details, &plan)
+			expectError(t, tc.ExpectedError, err)
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(vars.ToMap(), tc.Expected) {
+				t.Errorf("Expected context: %v got %v", tc.Expected, vars.ToMap())
+			}
+		})
+	}
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal-cf/brokerapi"
 	"github.com/spf13/viper"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 func ExampleServiceDefinition_UserDefinedPlansProperty() {
@@ -286,7 +287,7 @@ func ExampleServiceDefinition_CatalogEntry() {
 		panic(err)
 	}
 
-	eq := reflect.DeepEqual(srvc.ToPlain().Plans[0].Schemas, service.createSchemas())
+	eq := reflect.DeepEqual(srvc.ToPlain().Plans[0].Schemas, service.createSchemas(service.Plans[0]))
 
 	fmt.Println("schema was generated?", eq)
 
@@ -457,7 +458,7 @@ func TestServiceDefinition_ProvisionVariables(t *testing.T) {
 
 			details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(tc.UserParams)}
 			plan := ServicePlan{ServiceProperties: tc.ServiceProperties, ProvisionOverrides: tc.ProvisionOverrides}
-			vars, err := service.ProvisionVariables("instance-id-here", details, plan)
+			vars, err := service.ProvisionVariables("instance-id-here", details, plan, nil)
 
 			expectError(t, tc.ExpectedError, err)
 
@@ -650,7 +651,7 @@ func TestServiceDefinition_createSchemas(t *testing.T) {
 		},
 	}
 
-	schemas := service.createSchemas()
+	schemas := service.createSchemas(service.Plans[0])
 	if schemas == nil {
 		t.Fatal("Schemas was nil, expected non-nil value")
 	}
@@ -684,6 +685,75 @@ func TestServiceDefinition_createSchemas(t *testing.T) {
 	}
 }
 
+func TestServiceDefinition_bindSchemas(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		Plans: []ServicePlan{
+			{ServicePlan: brokerapi.ServicePlan{ID: "open-plan", Name: "open"}},
+			{ServicePlan: brokerapi.ServicePlan{ID: "roles-plan", Name: "roles"}, Roles: []string{"reader", "writer"}},
+		},
+		BindInputVariables: []BrokerVariable{
+			{FieldName: "ttl", Type: JsonTypeInteger, Default: 60, Constraints: validation.NewConstraintBuilder().Minimum(1).Build()},
+		},
+		BindOutputVariables: []BrokerVariable{
+			{FieldName: "uri", Type: JsonTypeString, Details: "connection URI"},
+			{FieldName: SyslogDrainURLOutput, Type: JsonTypeString, Details: "drain"},
+			{FieldName: RouteServiceURLOutput, Type: JsonTypeString, Details: "route service"},
+		},
+	}
+
+	viper.Set("compatibility.enable-catalog-schemas", true)
+	defer viper.Reset()
+
+	entry, err := service.CatalogEntry()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("credentials", func(t *testing.T) {
+		expected := CreateJsonSchema(service.BindOutputVariables[:1])
+		actual := entry.Metadata.AdditionalMetadata[BindingCredentialsSchemaKey]
+		if !reflect.DeepEqual(expected, actual) {
+			t.Errorf("expected credentials schema %v, got %v", expected, actual)
+		}
+	})
+
+	// The advertised schema must accept exactly what BindVariables accepts.
+	cases := map[string]string{
+		"no params":      `{}`,
+		"valid ttl":      `{"ttl": 5}`,
+		"invalid ttl":    `{"ttl": 0}`,
+		"plan role":      `{"role": "reader"}`,
+		"unknown role":   `{"role": "admin"}`,
+		"role and ttl":   `{"role": "writer", "ttl": 10}`,
+		"non-string ttl": `{"ttl": "ten"}`,
+	}
+
+	for _, plan := range entry.Plans {
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(plan.Schemas.Binding.Create.Parameters))
+		if err != nil {
+			t.Fatalf("plan %s has an invalid bind schema: %v", plan.Name, err)
+		}
+
+		for tn, params := range cases {
+			t.Run(plan.Name+"/"+tn, func(t *testing.T) {
+				result, err := schema.Validate(gojsonschema.NewStringLoader(params))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				details := brokerapi.BindDetails{RawParameters: json.RawMessage(params)}
+				_, bindErr := service.BindVariables(models.ServiceInstanceDetails{}, "binding-id", details, &plan)
+
+				if result.Valid() != (bindErr == nil) {
+					t.Errorf("schema valid: %v, but BindVariables error: %v", result.Valid(), bindErr)
+				}
+			})
+		}
+	}
+}
+
 func expectError(t *testing.T, expected, actual error) {
 	t.Helper()
 	expectedErr := expected != nil
@@ -0,0 +1,132 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+// ServiceCapabilities describes how a service and its provider behave, so
+// operators can understand a brokerpak without reading its source.
+type ServiceCapabilities struct {
+	ServiceID   string `json:"service_id"`
+	ServiceName string `json:"service_name"`
+
+	ProvisionsAsync   bool `json:"provisions_async"`
+	DeprovisionsAsync bool `json:"deprovisions_async"`
+	Bindable          bool `json:"bindable"`
+	PlanUpdateable    bool `json:"plan_updateable"`
+
+	// The provision parameters by the way they can be updated.
+	UpdatableParameters  []string `json:"updatable_parameters"`
+	RecreateParameters   []string `json:"recreate_parameters"`
+	ProhibitedParameters []string `json:"prohibited_parameters"`
+
+	// Hooks lists which optional provider interfaces are implemented.
+	Hooks ProviderHooks `json:"hooks"`
+}
+
+// ProviderHooks reports the optional hooks of a service by name, see
+// serviceHooks.
+type ProviderHooks map[string]bool
+
+// serviceHooks are the optional interfaces a ServiceProvider, or for
+// instance_namer the ServiceDefinition, can implement. Every optional hook must
+// be listed so the capabilities don't misreport a service.
+var serviceHooks = []struct {
+	Name        string
+	Implemented func(svc *ServiceDefinition, provider ServiceProvider) bool
+}{
+	{"describe_operation", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(OperationDescriber)
+		return ok
+	}},
+	{"suggest_retry_after", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(RetryAfterSuggester)
+		return ok
+	}},
+	{"async_only", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		asyncOnly, ok := provider.(AsyncOnlyProvider)
+		return ok && asyncOnly.AsyncOnly()
+	}},
+	{"verify_provision", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(ProvisionVerifier)
+		return ok
+	}},
+	{"enrich_catalog", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(CatalogEnricher)
+		return ok
+	}},
+	{"network_policy", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(NetworkPolicyManager)
+		return ok
+	}},
+	{"health_check", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(HealthChecker)
+		return ok
+	}},
+	{"finalization_fields", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(FieldFinalizer)
+		return ok
+	}},
+	{"estimate_cost", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(CostEstimator)
+		return ok
+	}},
+	{"validate_update", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(UpdateValidator)
+		return ok
+	}},
+	{"adopt_instance", func(_ *ServiceDefinition, provider ServiceProvider) bool {
+		_, ok := provider.(InstanceAdopter)
+		return ok
+	}},
+	{"instance_namer", func(svc *ServiceDefinition, _ ServiceProvider) bool {
+		return svc.InstanceNamer != nil
+	}},
+}
+
+// Capabilities returns the capabilities of the service when backed by the
+// provider.
+func (svc *ServiceDefinition) Capabilities(provider ServiceProvider) ServiceCapabilities {
+	capabilities := ServiceCapabilities{
+		ServiceID:            svc.Id,
+		ServiceName:          svc.Name,
+		ProvisionsAsync:      provider.ProvisionsAsync(),
+		DeprovisionsAsync:    provider.DeprovisionsAsync(),
+		Bindable:             svc.Bindable,
+		PlanUpdateable:       svc.PlanUpdateable,
+		UpdatableParameters:  []string{},
+		RecreateParameters:   []string{},
+		ProhibitedParameters: []string{},
+		Hooks:                ProviderHooks{},
+	}
+
+	for _, hook := range serviceHooks {
+		capabilities.Hooks[hook.Name] = hook.Implemented(svc, provider)
+	}
+
+	for _, param := range svc.ProvisionInputVariables {
+		switch param.GetUpdateBehavior() {
+		case UpdateProhibited:
+			capabilities.ProhibitedParameters = append(capabilities.ProhibitedParameters, param.FieldName)
+		case UpdateRecreate:
+			capabilities.RecreateParameters = append(capabilities.RecreateParameters, param.FieldName)
+		default:
+			capabilities.UpdatableParameters = append(capabilities.UpdatableParameters, param.FieldName)
+		}
+	}
+
+	// the reserved parameters can never be updated, see ClassifyUpdate
+	capabilities.ProhibitedParameters = append(capabilities.ProhibitedParameters, svc.fixedParameters()...)
+
+	return capabilities
+}
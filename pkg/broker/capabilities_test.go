@@ -0,0 +1,138 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+type asyncDescribingProvider struct {
+	ServiceProvider
+}
+
+func (asyncDescribingProvider) ProvisionsAsync() bool   { return true }
+func (asyncDescribingProvider) DeprovisionsAsync() bool { return false }
+
+func (asyncDescribingProvider) DescribeOperation(ctx context.Context, instance models.ServiceInstanceDetails) (string, error) {
+	return "", nil
+}
+
+// allHooksProvider implements every optional ServiceProvider interface.
+type allHooksProvider struct {
+	asyncDescribingProvider
+}
+
+func (allHooksProvider) SuggestRetryAfter(ctx context.Context, instance models.ServiceInstanceDetails) (time.Duration, error) {
+	return 0, nil
+}
+func (allHooksProvider) AsyncOnly() bool { return true }
+func (allHooksProvider) VerifyProvision(ctx context.Context, instance models.ServiceInstanceDetails) error {
+	return nil
+}
+func (allHooksProvider) EnrichCatalog(ctx context.Context, entry *Service) error { return nil }
+func (allHooksProvider) CreateNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, appGUID string) (string, error) {
+	return "", nil
+}
+func (allHooksProvider) DeleteNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, policyID string) error {
+	return nil
+}
+func (allHooksProvider) HealthCheck(ctx context.Context) error { return nil }
+func (allHooksProvider) FinalizationFields() []string          { return nil }
+func (allHooksProvider) FetchInstanceFields(ctx context.Context, instance models.ServiceInstanceDetails, fields []string) (map[string]interface{}, error) {
+	return nil, nil
+}
+func (allHooksProvider) EstimateCost(ctx context.Context, vars *varcontext.VarContext) (*CostEstimate, error) {
+	return nil, nil
+}
+func (allHooksProvider) ValidateUpdate(ctx context.Context, instance models.ServiceInstanceDetails, vars *varcontext.VarContext) error {
+	return nil
+}
+func (allHooksProvider) AdoptInstance(ctx context.Context, instance models.ServiceInstanceDetails, resources map[string]interface{}) error {
+	return nil
+}
+
+func TestServiceDefinition_Capabilities(t *testing.T) {
+	service := ServiceDefinition{
+		Id:             "00000000-0000-0000-0000-000000000000",
+		Name:           "left-handed-smoke-sifter",
+		Bindable:       true,
+		PlanUpdateable: true,
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "tier"},
+			{FieldName: "region", ProhibitUpdate: true},
+			{FieldName: "disk_type", UpdateBehavior: UpdateRecreate},
+		},
+	}
+
+	expected := ServiceCapabilities{
+		ServiceID:            "00000000-0000-0000-0000-000000000000",
+		ServiceName:          "left-handed-smoke-sifter",
+		ProvisionsAsync:      true,
+		Bindable:             true,
+		PlanUpdateable:       true,
+		UpdatableParameters:  []string{"tier"},
+		RecreateParameters:   []string{"disk_type"},
+		ProhibitedParameters: []string{"region", "resource_prefix", "availability_zones"},
+		Hooks: ProviderHooks{
+			"describe_operation":  true,
+			"suggest_retry_after": false,
+			"async_only":          false,
+			"verify_provision":    false,
+			"enrich_catalog":      false,
+			"network_policy":      false,
+			"health_check":        false,
+			"finalization_fields": false,
+			"estimate_cost":       false,
+			"validate_update":     false,
+			"adopt_instance":      false,
+			"instance_namer":      false,
+		},
+	}
+
+	if actual := service.Capabilities(asyncDescribingProvider{}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected capabilities %+v, got %+v", expected, actual)
+	}
+
+	t.Run("network-plans", func(t *testing.T) {
+		service := service
+		service.Plans = []ServicePlan{{Network: &PlanNetwork{}}}
+
+		expected := []string{"region", "resource_prefix", "network", "subnet", "availability_zones"}
+		if actual := service.Capabilities(asyncDescribingProvider{}).ProhibitedParameters; !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Expected prohibited parameters %v, got %v", expected, actual)
+		}
+	})
+
+	t.Run("all-hooks", func(t *testing.T) {
+		service.InstanceNamer = &ResourceNaming{Prefix: "csb-"}
+		defer func() { service.InstanceNamer = nil }()
+
+		hooks := service.Capabilities(allHooksProvider{}).Hooks
+		for name, implemented := range hooks {
+			if !implemented {
+				t.Errorf("Expected hook %q to be reported", name)
+			}
+		}
+		if len(hooks) != len(expected.Hooks) {
+			t.Errorf("Expected %d hooks, got %d", len(expected.Hooks), len(hooks))
+		}
+	})
+}
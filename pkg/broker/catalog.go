@@ -16,6 +16,7 @@ package broker
 
 import (
 	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
 )
 
 // Service overrides the canonical Service Broker service type using a custom
@@ -24,6 +25,10 @@ type Service struct {
 	brokerapi.Service
 
 	Plans []ServicePlan `json:"plans"`
+
+	// DisplayOrder positions the service in the catalog when it's sorted by
+	// display order, 0 means unset.
+	DisplayOrder int `json:"-"`
 }
 
 // ToPlain converts this service to a plain PCF Service definition.
@@ -32,7 +37,7 @@ func (s Service) ToPlain() brokerapi.Service {
 	plainPlans := []brokerapi.ServicePlan{}
 
 	for _, plan := range s.Plans {
-		plainPlans = append(plainPlans, plan.ServicePlan)
+		plainPlans = append(plainPlans, plan.plainPlan())
 	}
 
 	plain.Plans = plainPlans
@@ -48,9 +53,106 @@ type ServicePlan struct {
 	ServiceProperties  map[string]interface{} `json:"service_properties"`
 	ProvisionOverrides map[string]interface{} `json:"provision_overrides,omitempty"`
 	BindOverrides      map[string]interface{} `json:"bind_overrides,omitempty"`
+
+	// Roles lists the values users may pass as the role bind parameter.
+	Roles []string `json:"roles,omitempty"`
+
+	// Network configures the network instances of the plan are placed in.
+	Network *PlanNetwork `json:"network,omitempty"`
+
+	// AvailabilityZones configures the zones instances of the plan may span.
+	AvailabilityZones *PlanAvailabilityZones `json:"availability_zones,omitempty"`
+
+	// ProvisionTimeout is the failsafe after which an in progress provision
+	// is reported as failed. EstimatedDuration is how long provisioning
+	// usually takes. Both are Go durations and are surfaced in the catalog.
+	ProvisionTimeout  string `json:"provision_timeout,omitempty"`
+	EstimatedDuration string `json:"estimated_duration,omitempty"`
+
+	// PollInterval is the Go duration the platform is asked to wait between
+	// polls of in progress operations, unless the provider suggests one.
+	PollInterval string `json:"poll_interval,omitempty"`
+
+	// ReadinessProbe keeps provisions in progress until the instance is
+	// usable.
+	ReadinessProbe *ReadinessProbe `json:"readiness_probe,omitempty"`
+
+	// NetworkPolicy makes bindings create a network policy allowing the bound
+	// application to reach the instance. The provider must implement
+	// NetworkPolicyManager.
+	NetworkPolicy bool `json:"network_policy,omitempty"`
+
+	// DisplayOrder positions the plan within its service when the catalog is
+	// sorted by display order, 0 means unset.
+	DisplayOrder int `json:"display_order,omitempty"`
+
+	// DeprovisionInputs are the parameters users may pass when deprovisioning
+	// instances of the plan, e.g. to skip a final snapshot.
+	DeprovisionInputs []BrokerVariable `json:"deprovision_inputs,omitempty"`
+
+	// RequiresInstanceOf lists the names of services an instance of each of
+	// which must exist in the space before the plan can be provisioned, e.g.
+	// a private link before a database.
+	RequiresInstanceOf []string `json:"requires_instance_of,omitempty"`
+
+	// DashboardUrlTemplate is the template of the dashboard URL returned for
+	// instances of the plan, it takes precedence over the service's.
+	DashboardUrlTemplate string `json:"dashboard_url_template,omitempty"`
 }
 
 // GetServiceProperties gets the plan settings variables as a string->interface map.
 func (sp *ServicePlan) GetServiceProperties() map[string]interface{} {
 	return sp.ServiceProperties
 }
+
+// IsBindable returns true if instances of the plan can be bound. Plans that
+// don't set bindable inherit it from their service.
+func (sp *ServicePlan) IsBindable(serviceBindable bool) bool {
+	if sp.Bindable == nil {
+		return serviceBindable
+	}
+
+	return *sp.Bindable
+}
+
+// PlanCost is a single price for a plan in one currency, e.g. 9.99 USD
+// MONTHLY.
+type PlanCost struct {
+	Amount   float64 `yaml:"amount" json:"amount"`
+	Currency string  `yaml:"currency" json:"currency"`
+	Unit     string  `yaml:"unit" json:"unit"`
+}
+
+var _ validation.Validatable = (*PlanCost)(nil)
+
+// Validate implements validation.Validatable.
+func (cost *PlanCost) Validate() (errs *validation.FieldError) {
+	if cost.Amount < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(cost.Amount, "amount"))
+	}
+
+	return errs.Also(
+		validation.ErrIfNotCurrencyCode(cost.Currency, "currency"),
+		validation.ErrIfBlank(cost.Unit, "unit"),
+	)
+}
+
+// ToServicePlanCosts converts the costs to their OSB form. Costs sharing a
+// unit are merged into a single entry with one amount per currency.
+func ToServicePlanCosts(costs []PlanCost) []brokerapi.ServicePlanCost {
+	var out []brokerapi.ServicePlanCost
+	byUnit := make(map[string]int)
+
+	for _, cost := range costs {
+		idx, ok := byUnit[cost.Unit]
+		if !ok {
+			idx = len(out)
+			byUnit[cost.Unit] = idx
+			out = append(out, brokerapi.ServicePlanCost{Amount: map[string]float64{}, Unit: cost.Unit})
+		}
+
+		out[idx].Amount[cost.Currency] = cost.Amount
+	}
+
+	return out
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import "github.com/pivotal-cf/brokerapi"
+
+// DeepCopy returns a copy of the service that shares no slices, maps or
+// pointers with the original, so callers may modify it freely.
+func (s Service) DeepCopy() Service {
+	out := s
+	out.Tags = copyStrings(s.Tags)
+	out.Requires = append([]brokerapi.RequiredPermission(nil), s.Requires...)
+
+	if s.Metadata != nil {
+		metadata := *s.Metadata
+		metadata.Shareable = copyBool(s.Metadata.Shareable)
+		metadata.AdditionalMetadata = copyMap(s.Metadata.AdditionalMetadata)
+		out.Metadata = &metadata
+	}
+
+	if s.DashboardClient != nil {
+		client := *s.DashboardClient
+		out.DashboardClient = &client
+	}
+
+	out.Plans = nil
+	for _, plan := range s.Plans {
+		out.Plans = append(out.Plans, plan.DeepCopy())
+	}
+
+	return out
+}
+
+// DeepCopy returns a copy of the plan that shares no slices, maps or pointers
+// with the original, so callers may modify it freely.
+func (sp ServicePlan) DeepCopy() ServicePlan {
+	out := sp
+	out.Free = copyBool(sp.Free)
+	out.Bindable = copyBool(sp.Bindable)
+	out.ServiceProperties = copyMap(sp.ServiceProperties)
+	out.ProvisionOverrides = copyMap(sp.ProvisionOverrides)
+	out.BindOverrides = copyMap(sp.BindOverrides)
+	out.Roles = copyStrings(sp.Roles)
+
+	if sp.Metadata != nil {
+		metadata := *sp.Metadata
+		metadata.Bullets = copyStrings(sp.Metadata.Bullets)
+		metadata.AdditionalMetadata = copyMap(sp.Metadata.AdditionalMetadata)
+		metadata.Costs = nil
+		for _, cost := range sp.Metadata.Costs {
+			amount := make(map[string]float64)
+			for currency, value := range cost.Amount {
+				amount[currency] = value
+			}
+			metadata.Costs = append(metadata.Costs, brokerapi.ServicePlanCost{Amount: amount, Unit: cost.Unit})
+		}
+		out.Metadata = &metadata
+	}
+
+	if sp.Schemas != nil {
+		schemas := *sp.Schemas
+		schemas.Instance.Create.Parameters = copyMap(sp.Schemas.Instance.Create.Parameters)
+		schemas.Instance.Update.Parameters = copyMap(sp.Schemas.Instance.Update.Parameters)
+		schemas.Binding.Create.Parameters = copyMap(sp.Schemas.Binding.Create.Parameters)
+		out.Schemas = &schemas
+	}
+
+	if sp.MaintenanceInfo != nil {
+		info := *sp.MaintenanceInfo
+		if sp.MaintenanceInfo.Public != nil {
+			info.Public = make(map[string]string)
+			for k, v := range sp.MaintenanceInfo.Public {
+				info.Public[k] = v
+			}
+		}
+		out.MaintenanceInfo = &info
+	}
+
+	if sp.Network != nil {
+		network := *sp.Network
+		out.Network = &network
+	}
+
+	if sp.AvailabilityZones != nil {
+		zones := *sp.AvailabilityZones
+		zones.Allowed = copyStrings(sp.AvailabilityZones.Allowed)
+		zones.Default = copyStrings(sp.AvailabilityZones.Default)
+		out.AvailabilityZones = &zones
+	}
+
+	return out
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+
+	v := *b
+	return &v
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+
+	return append([]string{}, s...)
+}
+
+// copyMap deep copies JSON-like maps, i.e. maps holding scalars, slices and
+// other maps.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	return copyValue(m).(map[string]interface{})
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			out[k] = copyValue(elem)
+		}
+		return out
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = copyValue(elem)
+		}
+		return out
+
+	case []string:
+		return copyStrings(v)
+
+	default:
+		return v
+	}
+}
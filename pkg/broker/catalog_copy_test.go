@@ -0,0 +1,82 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestService_DeepCopy(t *testing.T) {
+	newService := func() Service {
+		return Service{
+			Service: brokerapi.Service{
+				ID:       "service-id",
+				Tags:     []string{"beta"},
+				Requires: []brokerapi.RequiredPermission{brokerapi.PermissionSyslogDrain},
+				Metadata: &brokerapi.ServiceMetadata{
+					DisplayName:        "Service",
+					Shareable:          brokerapi.BindableValue(true),
+					AdditionalMetadata: map[string]interface{}{"nested": map[string]interface{}{"key": "value"}},
+				},
+			},
+			Plans: []ServicePlan{{
+				ServicePlan: brokerapi.ServicePlan{
+					ID:   "plan-id",
+					Free: brokerapi.FreeValue(false),
+					Metadata: &brokerapi.ServicePlanMetadata{
+						Bullets: []string{"fast"},
+						Costs:   []brokerapi.ServicePlanCost{{Amount: map[string]float64{"USD": 9.99}, Unit: "MONTHLY"}},
+					},
+					Schemas: &brokerapi.ServiceSchemas{
+						Instance: brokerapi.ServiceInstanceSchema{
+							Create: brokerapi.Schema{Parameters: map[string]interface{}{"required": []interface{}{"name"}}},
+						},
+					},
+				},
+				ServiceProperties: map[string]interface{}{"tier": 1, "zones": []interface{}{"a"}},
+				Roles:             []string{"reader"},
+				Network:           &PlanNetwork{Default: "vpc-default"},
+			}},
+		}
+	}
+
+	original := newService()
+	copied := original.DeepCopy()
+
+	if !reflect.DeepEqual(original, copied) {
+		t.Fatalf("expected the copy to equal the original, got %#v", copied)
+	}
+
+	copied.Tags[0] = "changed"
+	copied.Requires[0] = brokerapi.PermissionVolumeMount
+	*copied.Metadata.Shareable = false
+	copied.Metadata.AdditionalMetadata["nested"].(map[string]interface{})["key"] = "changed"
+
+	plan := copied.Plans[0]
+	*plan.Free = true
+	plan.Metadata.Bullets[0] = "changed"
+	plan.Metadata.Costs[0].Amount["USD"] = 0
+	plan.Schemas.Instance.Create.Parameters["required"].([]interface{})[0] = "changed"
+	plan.ServiceProperties["zones"].([]interface{})[0] = "changed"
+	plan.Roles[0] = "changed"
+	plan.Network.Default = "changed"
+
+	if !reflect.DeepEqual(original, newService()) {
+		t.Errorf("expected modifying the copy to leave the original unchanged, got %#v", original)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"sort"
+	"strings"
+)
+
+// CatalogOrder is how the services of the catalog and the plans of each
+// service are sorted.
+type CatalogOrder string
+
+const (
+	// CatalogOrderDefinition sorts services by name and keeps the plans in
+	// the order their service lists them, the default.
+	CatalogOrderDefinition CatalogOrder = "definition"
+	// CatalogOrderName sorts by name.
+	CatalogOrderName CatalogOrder = "name"
+	// CatalogOrderDisplayName sorts by display name, case insensitively.
+	// Entries without a display name use their name.
+	CatalogOrderDisplayName CatalogOrder = "display_name"
+	// CatalogOrderDisplayOrder sorts by the display_order of the entries,
+	// entries without one come last sorted by name. If two entries share a
+	// display_order, the whole list is sorted by name instead.
+	CatalogOrderDisplayOrder CatalogOrder = "display_order"
+)
+
+// SortCatalog sorts the services and the plans of each service in place.
+// Unknown orders are treated as CatalogOrderDefinition. It returns the lists that couldn't be sorted
+// by display_order because of duplicate values: "catalog" for the services,
+// and the service name for its plans.
+func SortCatalog(services []Service, order CatalogOrder) (fallbacks []string) {
+	serviceKeys := make([]catalogSortKey, len(services))
+	for i, svc := range services {
+		serviceKeys[i] = catalogSortKey{name: svc.Name, id: svc.ID, displayOrder: svc.DisplayOrder}
+		if svc.Metadata != nil {
+			serviceKeys[i].displayName = svc.Metadata.DisplayName
+		}
+
+		if !order.sortsPlans() {
+			continue
+		}
+
+		planKeys := make([]catalogSortKey, len(svc.Plans))
+		for j, plan := range svc.Plans {
+			planKeys[j] = catalogSortKey{name: plan.Name, id: plan.ID, displayOrder: plan.DisplayOrder}
+			if plan.Metadata != nil {
+				planKeys[j].displayName = plan.Metadata.DisplayName
+			}
+		}
+
+		if !sortByKeys(planKeys, order, func(i, j int) { svc.Plans[i], svc.Plans[j] = svc.Plans[j], svc.Plans[i] }) {
+			fallbacks = append(fallbacks, svc.Name)
+		}
+	}
+
+	if !sortByKeys(serviceKeys, order, func(i, j int) { services[i], services[j] = services[j], services[i] }) {
+		fallbacks = append([]string{"catalog"}, fallbacks...)
+	}
+
+	return fallbacks
+}
+
+func (order CatalogOrder) sortsPlans() bool {
+	switch order {
+	case CatalogOrderName, CatalogOrderDisplayName, CatalogOrderDisplayOrder:
+		return true
+	default:
+		return false
+	}
+}
+
+type catalogSortKey struct {
+	name         string
+	id           string
+	displayName  string
+	displayOrder int
+}
+
+// byName orders keys by name and then ID, so the order is deterministic even
+// if names collide.
+func (k catalogSortKey) byName(other catalogSortKey) bool {
+	if k.name != other.name {
+		return k.name < other.name
+	}
+
+	return k.id < other.id
+}
+
+func (k catalogSortKey) byDisplayName(other catalogSortKey) bool {
+	mine, theirs := strings.ToLower(k.sortName()), strings.ToLower(other.sortName())
+	if mine != theirs {
+		return mine < theirs
+	}
+
+	return k.byName(other)
+}
+
+func (k catalogSortKey) sortName() string {
+	if k.displayName != "" {
+		return k.displayName
+	}
+
+	return k.name
+}
+
+func (k catalogSortKey) byDisplayOrder(other catalogSortKey) bool {
+	switch {
+	case k.displayOrder > 0 && other.displayOrder > 0:
+		return k.displayOrder < other.displayOrder
+	case k.displayOrder > 0 || other.displayOrder > 0:
+		return k.displayOrder > 0
+	default:
+		return k.byName(other)
+	}
+}
+
+// sortByKeys sorts the keys with the given order, calling swap so the caller
+// can sort its own slice alongside. It returns false if the keys were sorted
+// by name because their display orders aren't unique.
+func sortByKeys(keys []catalogSortKey, order CatalogOrder, swap func(i, j int)) bool {
+	less := catalogSortKey.byName
+	ok := true
+
+	switch order {
+	case CatalogOrderDisplayName:
+		less = catalogSortKey.byDisplayName
+	case CatalogOrderDisplayOrder:
+		if ok = uniqueDisplayOrders(keys); ok {
+			less = catalogSortKey.byDisplayOrder
+		}
+	}
+
+	sort.Sort(&keySorter{keys: keys, less: less, swap: swap})
+	return ok
+}
+
+func uniqueDisplayOrders(keys []catalogSortKey) bool {
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if k.displayOrder <= 0 {
+			continue
+		}
+
+		if seen[k.displayOrder] {
+			return false
+		}
+		seen[k.displayOrder] = true
+	}
+
+	return true
+}
+
+type keySorter struct {
+	keys []catalogSortKey
+	less func(a, b catalogSortKey) bool
+	swap func(i, j int)
+}
+
+func (s *keySorter) Len() int           { return len(s.keys) }
+func (s *keySorter) Less(i, j int) bool { return s.less(s.keys[i], s.keys[j]) }
+func (s *keySorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestSortCatalog(t *testing.T) {
+	plan := func(name, displayName string, displayOrder int) ServicePlan {
+		return ServicePlan{
+			ServicePlan: brokerapi.ServicePlan{
+				ID:       name + "-id",
+				Name:     name,
+				Metadata: &brokerapi.ServicePlanMetadata{DisplayName: displayName},
+			},
+			DisplayOrder: displayOrder,
+		}
+	}
+
+	service := func(name, displayName string, displayOrder int, plans ...ServicePlan) Service {
+		return Service{
+			Service: brokerapi.Service{
+				ID:       name + "-id",
+				Name:     name,
+				Metadata: &brokerapi.ServiceMetadata{DisplayName: displayName},
+			},
+			Plans:        plans,
+			DisplayOrder: displayOrder,
+		}
+	}
+
+	names := func(services []Service) (out []string) {
+		for _, svc := range services {
+			out = append(out, svc.Name)
+			for _, plan := range svc.Plans {
+				out = append(out, svc.Name+"/"+plan.Name)
+			}
+		}
+		return
+	}
+
+	newCatalog := func() []Service {
+		return []Service{
+			service("mysql", "Zebra SQL", 2,
+				plan("small", "Tiny", 0),
+				plan("large", "Big", 1),
+				plan("medium", "medium", 1)),
+			service("redis", "", 0),
+			service("postgres", "alpha SQL", 1,
+				plan("small", "Small", 2),
+				plan("large", "Large", 1)),
+		}
+	}
+
+	cases := map[string]struct {
+		Order             CatalogOrder
+		ExpectedNames     []string
+		ExpectedFallbacks []string
+	}{
+		"name": {
+			Order:         CatalogOrderName,
+			ExpectedNames: []string{"mysql", "mysql/large", "mysql/medium", "mysql/small", "postgres", "postgres/large", "postgres/small", "redis"},
+		},
+		"definition": {
+			Order:         CatalogOrderDefinition,
+			ExpectedNames: []string{"mysql", "mysql/small", "mysql/large", "mysql/medium", "postgres", "postgres/small", "postgres/large", "redis"},
+		},
+		"unknown falls back to definition": {
+			Order:         "bogus",
+			ExpectedNames: []string{"mysql", "mysql/small", "mysql/large", "mysql/medium", "postgres", "postgres/small", "postgres/large", "redis"},
+		},
+		"display name": {
+			Order:         CatalogOrderDisplayName,
+			ExpectedNames: []string{"postgres", "postgres/large", "postgres/small", "redis", "mysql", "mysql/large", "mysql/medium", "mysql/small"},
+		},
+		"display order": {
+			Order:             CatalogOrderDisplayOrder,
+			ExpectedNames:     []string{"postgres", "postgres/large", "postgres/small", "mysql", "mysql/large", "mysql/medium", "mysql/small", "redis"},
+			ExpectedFallbacks: []string{"mysql"},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			catalog := newCatalog()
+			fallbacks := SortCatalog(catalog, tc.Order)
+
+			if actual := names(catalog); !reflect.DeepEqual(actual, tc.ExpectedNames) {
+				t.Errorf("Expected order %v, got %v", tc.ExpectedNames, actual)
+			}
+
+			if !reflect.DeepEqual(fallbacks, tc.ExpectedFallbacks) {
+				t.Errorf("Expected fallbacks %v, got %v", tc.ExpectedFallbacks, fallbacks)
+			}
+		})
+	}
+
+	t.Run("duplicate service display orders", func(t *testing.T) {
+		catalog := []Service{service("b", "", 1), service("a", "", 1)}
+		fallbacks := SortCatalog(catalog, CatalogOrderDisplayOrder)
+
+		if actual := names(catalog); !reflect.DeepEqual(actual, []string{"a", "b"}) {
+			t.Errorf("Expected services sorted by name, got %v", actual)
+		}
+
+		if !reflect.DeepEqual(fallbacks, []string{"catalog"}) {
+			t.Errorf("Expected the catalog to fall back, got %v", fallbacks)
+		}
+	})
+}
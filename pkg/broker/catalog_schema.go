@@ -0,0 +1,199 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// osbServiceSchema is the JSON Schema of a single service object of the OSB
+// catalog response, see
+// https://github.com/openservicebrokerapi/servicebroker/blob/v2.14/spec.md#catalog-management
+// Required string fields aren't given a minimum length so that empty values
+// keep being reported by the friendlier ValidateCatalog checks.
+const osbServiceSchema = `{
+	"$schema": "http://json-schema.org/draft-04/schema#",
+	"definitions": {
+		"parameters_schema": {
+			"type": "object",
+			"properties": {
+				"parameters": {"type": ["object", "null"]}
+			}
+		}
+	},
+	"type": "object",
+	"required": ["id", "name", "description", "bindable", "plans"],
+	"properties": {
+		"id": {"type": "string"},
+		"name": {"type": "string"},
+		"description": {"type": "string"},
+		"bindable": {"type": "boolean"},
+		"instances_retrievable": {"type": "boolean"},
+		"bindings_retrievable": {"type": "boolean"},
+		"plan_updateable": {"type": "boolean"},
+		"tags": {
+			"type": "array",
+			"items": {"type": "string", "minLength": 1}
+		},
+		"requires": {
+			"type": "array",
+			"uniqueItems": true,
+			"items": {"enum": ["syslog_drain", "route_forwarding", "volume_mount"]}
+		},
+		"metadata": {"type": "object"},
+		"dashboard_client": {
+			"type": "object",
+			"required": ["id", "secret"],
+			"properties": {
+				"id": {"type": "string", "minLength": 1},
+				"secret": {"type": "string", "minLength": 1},
+				"redirect_uri": {"type": "string"}
+			}
+		},
+		"plans": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["id", "name", "description"],
+				"properties": {
+					"id": {"type": "string"},
+					"name": {"type": "string"},
+					"description": {"type": "string"},
+					"free": {"type": "boolean"},
+					"bindable": {"type": "boolean"},
+					"metadata": {
+						"type": "object",
+						"properties": {
+							"bullets": {"type": "array", "items": {"type": "string"}},
+							"costs": {
+								"type": "array",
+								"items": {
+									"type": "object",
+									"required": ["amount", "unit"],
+									"properties": {
+										"amount": {
+											"type": "object",
+											"minProperties": 1,
+											"additionalProperties": {"type": "number"}
+										},
+										"unit": {"type": "string", "minLength": 1}
+									}
+								}
+							}
+						}
+					},
+					"schemas": {
+						"type": "object",
+						"properties": {
+							"service_instance": {
+								"type": "object",
+								"properties": {
+									"create": {"$ref": "#/definitions/parameters_schema"},
+									"update": {"$ref": "#/definitions/parameters_schema"}
+								}
+							},
+							"service_binding": {
+								"type": "object",
+								"properties": {
+									"create": {"$ref": "#/definitions/parameters_schema"}
+								}
+							}
+						}
+					},
+					"maintenance_info": {"type": "object"}
+				}
+			}
+		}
+	}
+}`
+
+var osbServiceSchemaLoader = gojsonschema.NewStringLoader(osbServiceSchema)
+
+// ValidateCatalogSchema converts the catalog entry of every registered
+// service to the plain OSB form served to platforms and validates it against
+// the OSB catalog JSON Schema. Each problem's Pointer is a JSON Pointer to the
+// offending value within the service's catalog entry.
+func (brokerRegistry BrokerRegistry) ValidateCatalogSchema() []CatalogProblem {
+	var problems []CatalogProblem
+
+	for _, svc := range brokerRegistry.GetAllServices() {
+		svcProblem := CatalogProblem{ServiceId: svc.Id, ServiceName: svc.Name}
+
+		entry, err := svc.CatalogEntry()
+		if err != nil {
+			svcProblem.Message = fmt.Sprintf("couldn't build catalog entry: %v", err)
+			problems = append(problems, svcProblem)
+			continue
+		}
+
+		plain := entry.ToPlain()
+		document, err := json.Marshal(plain)
+		if err != nil {
+			svcProblem.Message = fmt.Sprintf("couldn't serialize catalog entry: %v", err)
+			problems = append(problems, svcProblem)
+			continue
+		}
+
+		result, err := gojsonschema.Validate(osbServiceSchemaLoader, gojsonschema.NewBytesLoader(document))
+		if err != nil {
+			svcProblem.Message = fmt.Sprintf("couldn't validate catalog entry: %v", err)
+			problems = append(problems, svcProblem)
+			continue
+		}
+
+		for _, resultErr := range result.Errors() {
+			problem := svcProblem
+			problem.Pointer = schemaErrorPointer(resultErr)
+			problem.Message = fmt.Sprintf("does not match the OSB catalog schema: %s", resultErr.Description())
+
+			if planIndex, ok := pointerPlanIndex(problem.Pointer); ok && planIndex < len(plain.Plans) {
+				problem.PlanId = plain.Plans[planIndex].ID
+			}
+
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
+}
+
+// schemaErrorPointer converts the context of a validation error to a JSON
+// Pointer, e.g. /plans/0/metadata.
+func schemaErrorPointer(resultErr gojsonschema.ResultError) string {
+	path := strings.TrimPrefix(resultErr.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+// pointerPlanIndex returns the index of the plan a JSON Pointer into a
+// service's catalog entry points into, if any.
+func pointerPlanIndex(pointer string) (int, bool) {
+	parts := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	if len(parts) < 2 || parts[0] != "plans" {
+		return 0, false
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	return index, err == nil
+}
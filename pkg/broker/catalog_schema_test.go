@@ -0,0 +1,130 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestRegistry_ValidateCatalogSchema(t *testing.T) {
+	newService := func() *ServiceDefinition {
+		return &ServiceDefinition{
+			Id:          "b9e4332e-b42b-4680-bda5-ea1506797474",
+			Name:        "svc-a",
+			Description: "a test service",
+			Bindable:    true,
+			Plans: []ServicePlan{
+				{
+					ServicePlan: brokerapi.ServicePlan{
+						ID:          "e1d11f65-da66-46ad-977c-6d56513baf43",
+						Name:        "plan",
+						Description: "a test plan",
+						Free:        brokerapi.FreeValue(true),
+					},
+				},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		Service  *ServiceDefinition
+		Expected []CatalogProblem
+	}{
+		"valid": {
+			Service:  newService(),
+			Expected: nil,
+		},
+		"no plans": {
+			Service: func() *ServiceDefinition {
+				svc := newService()
+				svc.Plans = nil
+				return svc
+			}(),
+			Expected: []CatalogProblem{{
+				ServiceId:   "b9e4332e-b42b-4680-bda5-ea1506797474",
+				ServiceName: "svc-a",
+				Pointer:     "/plans",
+				Message:     "does not match the OSB catalog schema: Array must have at least 1 items",
+			}},
+		},
+		"unknown permission": {
+			Service: func() *ServiceDefinition {
+				svc := newService()
+				svc.Requires = []brokerapi.RequiredPermission{brokerapi.PermissionSyslogDrain, "log_forwarding"}
+				return svc
+			}(),
+			Expected: []CatalogProblem{{
+				ServiceId:   "b9e4332e-b42b-4680-bda5-ea1506797474",
+				ServiceName: "svc-a",
+				Pointer:     "/requires/1",
+				Message:     `does not match the OSB catalog schema: requires.1 must be one of the following: "syslog_drain", "route_forwarding", "volume_mount"`,
+			}},
+		},
+		"cost without unit": {
+			Service: func() *ServiceDefinition {
+				svc := newService()
+				svc.Plans[0].Metadata = &brokerapi.ServicePlanMetadata{
+					Costs: []brokerapi.ServicePlanCost{{Amount: map[string]float64{"USD": 1}}},
+				}
+				return svc
+			}(),
+			Expected: []CatalogProblem{{
+				ServiceId:   "b9e4332e-b42b-4680-bda5-ea1506797474",
+				ServiceName: "svc-a",
+				PlanId:      "e1d11f65-da66-46ad-977c-6d56513baf43",
+				Pointer:     "/plans/0/metadata/costs/0/unit",
+				Message:     "does not match the OSB catalog schema: String length must be greater than or equal to 1",
+			}},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			registry := BrokerRegistry{}
+			registry.Register(tc.Service)
+
+			actual := registry.ValidateCatalogSchema()
+			if !reflect.DeepEqual(tc.Expected, actual) {
+				t.Errorf("Expected problems %+v, got %+v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestPointerPlanIndex(t *testing.T) {
+	cases := map[string]struct {
+		Pointer       string
+		ExpectedIndex int
+		ExpectedOk    bool
+	}{
+		"root":          {Pointer: "/", ExpectedOk: false},
+		"plans":         {Pointer: "/plans", ExpectedOk: false},
+		"plan":          {Pointer: "/plans/2", ExpectedIndex: 2, ExpectedOk: true},
+		"plan field":    {Pointer: "/plans/1/metadata", ExpectedIndex: 1, ExpectedOk: true},
+		"service field": {Pointer: "/requires/0", ExpectedOk: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			index, ok := pointerPlanIndex(tc.Pointer)
+			if index != tc.ExpectedIndex || ok != tc.ExpectedOk {
+				t.Errorf("Expected (%d, %t), got (%d, %t)", tc.ExpectedIndex, tc.ExpectedOk, index, ok)
+			}
+		})
+	}
+}
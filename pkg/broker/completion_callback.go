@@ -0,0 +1,62 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// CompletionCallbackParameter is the user parameter holding the URL the
+// broker posts the final state of the instance's asynchronous operations to.
+const CompletionCallbackParameter = "completion_callback"
+
+// CompletionCallback extracts the completion callback URL from the raw
+// request parameters. Nil is returned if the request doesn't set it.
+func CompletionCallback(rawParameters json.RawMessage) (*url.URL, error) {
+	if len(rawParameters) == 0 {
+		return nil, nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	value, ok := params[CompletionCallbackParameter]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	raw, ok := value.(string)
+	if !ok {
+		return nil, invalidCompletionCallback("%s must be a string", CompletionCallbackParameter)
+	}
+
+	callback, err := url.Parse(raw)
+	if err != nil || (callback.Scheme != "https" && callback.Scheme != "http") || callback.Host == "" {
+		return nil, invalidCompletionCallback("%s must be an absolute http or https URL", CompletionCallbackParameter)
+	}
+
+	return callback, nil
+}
+
+func invalidCompletionCallback(format string, args ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, args...), http.StatusBadRequest, "invalid-completion-callback")
+}
@@ -0,0 +1,71 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestCompletionCallback(t *testing.T) {
+	cases := map[string]struct {
+		Raw           string
+		Expected      string
+		ExpectedError error
+	}{
+		"empty": {
+			Raw: ``,
+		},
+		"not set": {
+			Raw: `{"name":"db"}`,
+		},
+		"https": {
+			Raw:      `{"completion_callback":"https://ci.example.com/hooks/42?token=abc"}`,
+			Expected: "https://ci.example.com/hooks/42?token=abc",
+		},
+		"http": {
+			Raw:      `{"completion_callback":"http://ci.example.com:8080/hooks"}`,
+			Expected: "http://ci.example.com:8080/hooks",
+		},
+		"not a string": {
+			Raw:           `{"completion_callback":42}`,
+			ExpectedError: errors.New("completion_callback must be a string"),
+		},
+		"relative": {
+			Raw:           `{"completion_callback":"/hooks/42"}`,
+			ExpectedError: errors.New("completion_callback must be an absolute http or https URL"),
+		},
+		"other scheme": {
+			Raw:           `{"completion_callback":"ftp://ci.example.com/hooks"}`,
+			ExpectedError: errors.New("completion_callback must be an absolute http or https URL"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := CompletionCallback(json.RawMessage(tc.Raw))
+			expectError(t, tc.ExpectedError, err)
+
+			url := ""
+			if actual != nil {
+				url = actual.String()
+			}
+			if url != tc.Expected {
+				t.Errorf("Expected completion callback: %q got %q", tc.Expected, url)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// CostEstimateSourceProvider marks estimates computed by the provider
+	// from the parameters.
+	CostEstimateSourceProvider = "provider"
+
+	// CostEstimateSourcePlan marks estimates taken from the static costs of
+	// the plan, which don't depend on the parameters.
+	CostEstimateSourcePlan = "plan"
+)
+
+// CostEstimateRequest identifies the plan and parameters of an instance a
+// user wants to price before provisioning it.
+type CostEstimateRequest struct {
+	ServiceID        string          `json:"service_id"`
+	PlanID           string          `json:"plan_id"`
+	OrganizationGUID string          `json:"organization_guid"`
+	SpaceGUID        string          `json:"space_guid"`
+	Parameters       json.RawMessage `json:"parameters,omitempty"`
+}
+
+var _ validation.Validatable = (*CostEstimateRequest)(nil)
+
+// Validate implements validation.Validatable.
+func (cr *CostEstimateRequest) Validate() (errs *validation.FieldError) {
+	errs = errs.Also(
+		validation.ErrIfBlank(cr.ServiceID, "service_id"),
+		validation.ErrIfBlank(cr.PlanID, "plan_id"),
+	)
+
+	if len(cr.Parameters) > 0 {
+		errs = errs.Also(validation.ErrIfNotJSON(cr.Parameters, "parameters"))
+	}
+
+	return errs
+}
+
+// CostEstimate is the estimated price of an instance, e.g. 12.50 USD
+// MONTHLY, optionally broken down by the resources making it up.
+type CostEstimate struct {
+	Costs     []PlanCost `json:"costs"`
+	Breakdown []CostItem `json:"breakdown,omitempty"`
+
+	// Source is CostEstimateSourceProvider or CostEstimateSourcePlan.
+	Source string `json:"source"`
+}
+
+// CostItem is the price of one of the resources of an instance, e.g. its
+// storage.
+type CostItem struct {
+	Name  string     `json:"name"`
+	Costs []PlanCost `json:"costs"`
+}
+
+// PlanCostEstimate returns the static costs of the plan as an estimate. Plans
+// without costs are estimated free.
+func PlanCostEstimate(plan ServicePlan) *CostEstimate {
+	estimate := &CostEstimate{Costs: []PlanCost{}, Source: CostEstimateSourcePlan}
+	if plan.Metadata == nil {
+		return estimate
+	}
+
+	for _, cost := range plan.Metadata.Costs {
+		var currencies []string
+		for currency := range cost.Amount {
+			currencies = append(currencies, currency)
+		}
+		sort.Strings(currencies)
+
+		for _, currency := range currencies {
+			estimate.Costs = append(estimate.Costs, PlanCost{Amount: cost.Amount[currency], Currency: currency, Unit: cost.Unit})
+		}
+	}
+
+	return estimate
+}
@@ -0,0 +1,247 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+const (
+	// CredentialFormatParameter is the user parameter selecting the shape the
+	// credentials of a binding are returned in.
+	CredentialFormatParameter = "credential_format"
+
+	// CredentialFormatJSON returns the credentials as the service builds them.
+	CredentialFormatJSON = "json"
+
+	// CredentialFormatURI returns a single uri composed from the credentials.
+	CredentialFormatURI = "uri"
+
+	// CredentialFormatEnv returns a flat map of upper case keys to string
+	// values, usable as environment variables.
+	CredentialFormatEnv = "env"
+
+	// CredentialFormatFiles returns a files map of file names to contents,
+	// for workloads mounting the credentials as files.
+	CredentialFormatFiles = "files"
+
+	// MaxCredentialFilesBytes bounds the total size of the files of a
+	// binding, the size limit of a Kubernetes secret.
+	MaxCredentialFilesBytes = 1024 * 1024
+)
+
+var (
+	credentialFormats = []string{CredentialFormatJSON, CredentialFormatURI, CredentialFormatEnv, CredentialFormatFiles}
+
+	invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+	validFileName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9._-]{0,252}$`)
+)
+
+func errInvalidCredentialFormat(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-credential-format")
+}
+
+// CredentialFormat extracts the credential format from the raw bind
+// parameters and validates it. The default is json.
+func CredentialFormat(rawParameters json.RawMessage) (string, error) {
+	if len(rawParameters) == 0 {
+		return CredentialFormatJSON, nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return "", err
+	}
+
+	value, ok := params[CredentialFormatParameter]
+	if !ok || value == nil {
+		return CredentialFormatJSON, nil
+	}
+
+	for _, format := range credentialFormats {
+		if value == format {
+			return format, nil
+		}
+	}
+
+	return "", errInvalidCredentialFormat("%s must be one of: %s", CredentialFormatParameter, strings.Join(credentialFormats, ", "))
+}
+
+// withoutCredentialFormat removes the credential format from the raw bind
+// parameters so it isn't passed to providers.
+func withoutCredentialFormat(rawParameters json.RawMessage) (json.RawMessage, error) {
+	if len(rawParameters) == 0 {
+		return rawParameters, nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	if _, ok := params[CredentialFormatParameter]; !ok {
+		return rawParameters, nil
+	}
+
+	delete(params, CredentialFormatParameter)
+	return json.Marshal(params)
+}
+
+// FormatCredentials converts credentials into the given format. Credentials
+// that aren't an object are only returned as json.
+func FormatCredentials(credentials interface{}, format string) (interface{}, error) {
+	if format == "" || format == CredentialFormatJSON {
+		return credentials, nil
+	}
+
+	creds, ok := credentials.(map[string]interface{})
+	if !ok {
+		return nil, errInvalidCredentialFormat("the credentials of this service can only be returned as %s", CredentialFormatJSON)
+	}
+
+	switch format {
+	case CredentialFormatEnv:
+		env := map[string]interface{}{}
+		flattenEnv(env, "", creds)
+		return env, nil
+
+	case CredentialFormatURI:
+		uri, err := composeURI(creds)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"uri": uri}, nil
+
+	case CredentialFormatFiles:
+		files, err := credentialFiles(creds)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"files": files}, nil
+
+	default:
+		return nil, errInvalidCredentialFormat("unknown %s %q", CredentialFormatParameter, format)
+	}
+}
+
+// flattenEnv adds the credentials to env with nested keys joined by an
+// underscore, e.g. {"db": {"host": "h"}} becomes DB_HOST=h. Values that
+// aren't strings are JSON encoded.
+func flattenEnv(env map[string]interface{}, prefix string, creds map[string]interface{}) {
+	for k, v := range creds {
+		key := invalidEnvChars.ReplaceAllString(strings.ToUpper(k), "_")
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch value := v.(type) {
+		case map[string]interface{}:
+			flattenEnv(env, key, value)
+		case string:
+			env[key] = value
+		default:
+			encoded, _ := json.Marshal(value)
+			env[key] = string(encoded)
+		}
+	}
+}
+
+// credentialFiles returns a file per top level credential, named after its
+// key. String values are the file contents as-is, other values are JSON
+// encoded.
+func credentialFiles(creds map[string]interface{}) (map[string]interface{}, error) {
+	files := map[string]interface{}{}
+	size := 0
+	for _, name := range sortedCredentialKeys(creds) {
+		if !validFileName.MatchString(name) {
+			return nil, errInvalidCredentialFormat("credential %q can't be used as a file name, names must be at most 253 letters, digits, dots, dashes or underscores and not start with a dot or dash", name)
+		}
+
+		content, ok := creds[name].(string)
+		if !ok {
+			encoded, _ := json.Marshal(creds[name])
+			content = string(encoded)
+		}
+
+		size += len(content)
+		files[name] = content
+	}
+
+	if size > MaxCredentialFilesBytes {
+		return nil, errInvalidCredentialFormat("the credential files are %d bytes, the maximum allowed size is %d bytes", size, MaxCredentialFilesBytes)
+	}
+
+	return files, nil
+}
+
+// composeURI returns the uri in the credentials, or composes one from their
+// scheme, host, port, username, password and database name.
+func composeURI(creds map[string]interface{}) (string, error) {
+	if uri := firstString(creds, "uri", "url"); uri != "" {
+		return uri, nil
+	}
+
+	scheme := firstString(creds, "scheme", "protocol")
+	host := firstString(creds, "hostname", "host")
+	if scheme == "" || host == "" {
+		return "", errInvalidCredentialFormat("the credentials of this service have no uri and no scheme and host to compose one, available keys: %s", strings.Join(sortedCredentialKeys(creds), ", "))
+	}
+
+	if port, ok := creds["port"]; ok {
+		host = net.JoinHostPort(host, fmt.Sprint(port))
+	}
+
+	uri := url.URL{Scheme: scheme, Host: host}
+	if username := firstString(creds, "username", "user"); username != "" {
+		if password := firstString(creds, "password"); password != "" {
+			uri.User = url.UserPassword(username, password)
+		} else {
+			uri.User = url.User(username)
+		}
+	}
+	if name := firstString(creds, "name", "database"); name != "" {
+		uri.Path = "/" + name
+	}
+
+	return uri.String(), nil
+}
+
+func firstString(creds map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := creds[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func sortedCredentialKeys(creds map[string]interface{}) []string {
+	var keys []string
+	for k := range creds {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,108 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// CredentialKeysProperty returns the Viper property name of the object
+// operators can set to rename the credential keys of the service's bindings,
+// e.g. {"hostname": "host"} for apps expecting a host key in VCAP_SERVICES.
+func (svc *ServiceDefinition) CredentialKeysProperty() string {
+	return fmt.Sprintf("service.%s.bind.credential_keys", svc.Name)
+}
+
+// CredentialKeyMapping returns the operator-provided renames of the credential
+// keys. It fails if the mapping would make a binding lose a credential,
+// because two keys are renamed to the same name or a key is renamed to the
+// name of another output.
+func (svc *ServiceDefinition) CredentialKeyMapping() (map[string]string, error) {
+	mapping := viper.GetStringMapString(svc.CredentialKeysProperty())
+
+	outputs := make(map[string]bool)
+	for _, output := range svc.BindOutputVariables {
+		outputs[output.FieldName] = true
+	}
+
+	renamedFrom := make(map[string]string)
+	for _, from := range sortedMappingKeys(mapping) {
+		to := mapping[from]
+
+		switch {
+		case to == "":
+			return nil, fmt.Errorf("%s: credential %q can't be renamed to an empty key", svc.CredentialKeysProperty(), from)
+		case renamedFrom[to] != "":
+			return nil, fmt.Errorf("%s: credentials %q and %q are both renamed to %q", svc.CredentialKeysProperty(), renamedFrom[to], from, to)
+		case outputs[to] && to != from && mapping[to] == "":
+			return nil, fmt.Errorf("%s: credential %q can't be renamed to %q, it would replace the output of the same name", svc.CredentialKeysProperty(), from, to)
+		}
+
+		renamedFrom[to] = from
+	}
+
+	return mapping, nil
+}
+
+// MapCredentialKeys renames the keys of the credentials according to the
+// mapping. The endpoints of a CredentialSet are renamed individually.
+// Credentials that aren't an object are returned unchanged.
+func MapCredentialKeys(credentials interface{}, mapping map[string]string) interface{} {
+	creds, ok := credentials.(map[string]interface{})
+	if !ok || len(mapping) == 0 {
+		return credentials
+	}
+
+	if set, ok := ParseCredentialSet(creds); ok {
+		set.Primary = renameKeys(set.Primary, mapping)
+		set.ReadOnly = renameKeys(set.ReadOnly, mapping)
+
+		out := renameKeys(creds, mapping)
+		for k, v := range set.ToMap() {
+			out[k] = v
+		}
+		return out
+	}
+
+	return renameKeys(creds, mapping)
+}
+
+func renameKeys(in map[string]interface{}, mapping map[string]string) map[string]interface{} {
+	if in == nil {
+		return nil
+	}
+
+	out := make(map[string]interface{})
+	for k, v := range in {
+		if to, ok := mapping[k]; ok {
+			k = to
+		}
+		out[k] = v
+	}
+
+	return out
+}
+
+func sortedMappingKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
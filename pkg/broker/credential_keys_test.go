@@ -0,0 +1,118 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_CredentialKeyMapping(t *testing.T) {
+	service := ServiceDefinition{
+		Name: "left-handed-smoke-sifter",
+		BindOutputVariables: []BrokerVariable{
+			{FieldName: "hostname", Type: JsonTypeString, Required: true},
+			{FieldName: "username", Type: JsonTypeString, Required: true},
+			{FieldName: "password", Type: JsonTypeString, Required: true},
+		},
+	}
+
+	cases := map[string]struct {
+		Mapping       string
+		Expected      map[string]string
+		ExpectedError error
+	}{
+		"unset": {
+			Expected: map[string]string{},
+		},
+		"rename": {
+			Mapping:  `{"hostname":"host","username":"user"}`,
+			Expected: map[string]string{"hostname": "host", "username": "user"},
+		},
+		"swap": {
+			Mapping:  `{"hostname":"username","username":"hostname"}`,
+			Expected: map[string]string{"hostname": "username", "username": "hostname"},
+		},
+		"empty key": {
+			Mapping:       `{"hostname":""}`,
+			ExpectedError: errors.New(`service.left-handed-smoke-sifter.bind.credential_keys: credential "hostname" can't be renamed to an empty key`),
+		},
+		"duplicate target": {
+			Mapping:       `{"hostname":"host","username":"host"}`,
+			ExpectedError: errors.New(`service.left-handed-smoke-sifter.bind.credential_keys: credentials "hostname" and "username" are both renamed to "host"`),
+		},
+		"replaces output": {
+			Mapping:       `{"username":"password"}`,
+			ExpectedError: errors.New(`service.left-handed-smoke-sifter.bind.credential_keys: credential "username" can't be renamed to "password", it would replace the output of the same name`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			if tc.Mapping != "" {
+				viper.Set(service.CredentialKeysProperty(), tc.Mapping)
+			}
+
+			actual, err := service.CredentialKeyMapping()
+			expectError(t, tc.ExpectedError, err)
+			if tc.ExpectedError == nil && !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected mapping %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestMapCredentialKeys(t *testing.T) {
+	mapping := map[string]string{"hostname": "host", "username": "user"}
+
+	cases := map[string]struct {
+		Credentials interface{}
+		Expected    string
+	}{
+		"flat": {
+			Credentials: map[string]interface{}{"hostname": "db.example.com", "username": "admin", "password": "secret"},
+			Expected:    `{"host":"db.example.com","password":"secret","user":"admin"}`,
+		},
+		"credential set": {
+			Credentials: map[string]interface{}{
+				"primary":  map[string]interface{}{"hostname": "db.example.com"},
+				"readonly": map[string]interface{}{"hostname": "replica.example.com"},
+			},
+			Expected: `{"primary":{"host":"db.example.com"},"readonly":{"host":"replica.example.com"}}`,
+		},
+		"not an object": {
+			Credentials: "opaque",
+			Expected:    `"opaque"`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := json.Marshal(MapCredentialKeys(tc.Credentials, mapping))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(actual) != tc.Expected {
+				t.Errorf("expected credentials %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
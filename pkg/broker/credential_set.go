@@ -0,0 +1,78 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+const (
+	// PrimaryCredentials is the credentials key holding the read-write
+	// endpoint of a CredentialSet.
+	PrimaryCredentials = "primary"
+
+	// ReadOnlyCredentials is the credentials key holding the read-only
+	// endpoint, e.g. a read replica, of a CredentialSet.
+	ReadOnlyCredentials = "readonly"
+)
+
+// CredentialSet holds the credentials of a binding to an instance with
+// several endpoints, e.g. a database with read replicas. A ServiceProvider
+// returns it from Bind converted with ToMap. Bindings to instances with a
+// single endpoint keep returning flat credentials.
+type CredentialSet struct {
+	Primary  map[string]interface{}
+	ReadOnly map[string]interface{}
+}
+
+// ParseCredentialSet returns the set held by the credentials and true, or false
+// if the credentials are flat.
+func ParseCredentialSet(credentials map[string]interface{}) (CredentialSet, bool) {
+	primary, ok := credentials[PrimaryCredentials].(map[string]interface{})
+	if !ok {
+		return CredentialSet{}, false
+	}
+
+	readOnly, _ := credentials[ReadOnlyCredentials].(map[string]interface{})
+	return CredentialSet{Primary: primary, ReadOnly: readOnly}, true
+}
+
+// ToMap converts the set to credentials. The read-only endpoint is omitted if
+// there is none.
+func (cs CredentialSet) ToMap() map[string]interface{} {
+	out := map[string]interface{}{PrimaryCredentials: cs.Primary}
+	if cs.ReadOnly != nil {
+		out[ReadOnlyCredentials] = cs.ReadOnly
+	}
+
+	return out
+}
+
+// MergeDefaults returns a copy of the set where every endpoint also holds the
+// defaults it doesn't override, e.g. the details of the instance.
+func (cs CredentialSet) MergeDefaults(defaults map[string]interface{}) CredentialSet {
+	merge := func(endpoint map[string]interface{}) map[string]interface{} {
+		if endpoint == nil {
+			return nil
+		}
+
+		out := make(map[string]interface{})
+		for k, v := range defaults {
+			out[k] = v
+		}
+		for k, v := range endpoint {
+			out[k] = v
+		}
+		return out
+	}
+
+	return CredentialSet{Primary: merge(cs.Primary), ReadOnly: merge(cs.ReadOnly)}
+}
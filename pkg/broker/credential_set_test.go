@@ -0,0 +1,92 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseCredentialSet(t *testing.T) {
+	cases := map[string]struct {
+		Credentials string
+		Expected    CredentialSet
+		ExpectedOk  bool
+	}{
+		"flat": {
+			Credentials: `{"host":"db.example.com"}`,
+		},
+		"primary not an object": {
+			Credentials: `{"primary":"db.example.com"}`,
+		},
+		"primary only": {
+			Credentials: `{"primary":{"host":"db.example.com"}}`,
+			Expected:    CredentialSet{Primary: map[string]interface{}{"host": "db.example.com"}},
+			ExpectedOk:  true,
+		},
+		"primary and read-only": {
+			Credentials: `{"primary":{"host":"db.example.com"},"readonly":{"host":"replica.example.com"}}`,
+			Expected: CredentialSet{
+				Primary:  map[string]interface{}{"host": "db.example.com"},
+				ReadOnly: map[string]interface{}{"host": "replica.example.com"},
+			},
+			ExpectedOk: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			// round trip through JSON like the credentials stored in OtherDetails
+			var creds map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.Credentials), &creds); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, ok := ParseCredentialSet(creds)
+			if ok != tc.ExpectedOk {
+				t.Errorf("expected ok to be %v, got %v", tc.ExpectedOk, ok)
+			}
+
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected set %v, got %v", tc.Expected, actual)
+			}
+
+			if ok && !reflect.DeepEqual(actual.ToMap(), creds) {
+				t.Errorf("expected ToMap to reproduce %v, got %v", creds, actual.ToMap())
+			}
+		})
+	}
+}
+
+func TestCredentialSet_MergeDefaults(t *testing.T) {
+	set := CredentialSet{
+		Primary:  map[string]interface{}{"host": "db.example.com"},
+		ReadOnly: map[string]interface{}{"host": "replica.example.com"},
+	}
+	defaults := map[string]interface{}{"host": "instance.example.com", "name": "db"}
+
+	expected := CredentialSet{
+		Primary:  map[string]interface{}{"host": "db.example.com", "name": "db"},
+		ReadOnly: map[string]interface{}{"host": "replica.example.com", "name": "db"},
+	}
+	if actual := set.MergeDefaults(defaults); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected set %v, got %v", expected, actual)
+	}
+
+	if _, ok := set.Primary["name"]; ok {
+		t.Error("expected the original set to be unchanged")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// DashboardPlanPropertyPrefix prefixes the names of the plan properties
+	// dashboard URL templates can reference, e.g. {{plan.tier}}.
+	DashboardPlanPropertyPrefix = "plan."
+
+	// DashboardOutputPrefix prefixes the names of the provision outputs
+	// dashboard URL templates can reference, e.g. {{output.console_host}}.
+	DashboardOutputPrefix = "output."
+)
+
+// dashboardTemplateRegex matches placeholders like {{instanceID}} or
+// {{plan.tier}} in dashboard URL templates.
+var dashboardTemplateRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z][a-zA-Z0-9_.-]*)\s*\}\}`)
+
+// GetDashboardUrlTemplate returns the dashboard URL template of instances of
+// the plan: the plan's own template if it has one, the service's otherwise.
+func (svc *ServiceDefinition) GetDashboardUrlTemplate(plan ServicePlan) string {
+	if plan.DashboardUrlTemplate != "" {
+		return plan.DashboardUrlTemplate
+	}
+
+	return svc.DashboardUrlTemplate
+}
+
+// DashboardTemplateVariables returns the variables dashboard URL templates of
+// instances of the plan can reference: the instance metadata parameter
+// templates get, the plan's ID, name and properties and the scalar outputs
+// of the provision.
+func DashboardTemplateVariables(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan, outputs map[string]interface{}) map[string]string {
+	vars := ProvisionTemplateVariables(instanceId, details)
+	vars["planID"] = plan.ID
+	vars["planName"] = plan.Name
+
+	for name, value := range plan.ServiceProperties {
+		vars[DashboardPlanPropertyPrefix+name] = fmt.Sprint(value)
+	}
+
+	for name, value := range outputs {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}, nil:
+			continue
+		}
+		vars[DashboardOutputPrefix+name] = fmt.Sprint(value)
+	}
+
+	return vars
+}
+
+// RenderDashboardUrl replaces the placeholders of the dashboard URL template
+// with the given variables. Values are inserted as is. It fails if a
+// placeholder is unknown or the result isn't a URL.
+func RenderDashboardUrl(template string, vars map[string]string) (string, error) {
+	rendered, err := renderTemplate(dashboardTemplateRegex, template, vars)
+	if err != nil {
+		return "", err
+	}
+
+	if err := validation.ErrIfNotURL(rendered, "dashboard_url"); err != nil {
+		return "", fmt.Errorf("dashboard URL %q isn't a URL", rendered)
+	}
+
+	return rendered, nil
+}
+
+// ValidateDashboardUrlTemplate checks the template only references known
+// variables and renders to a URL, unless it starts with a placeholder like an
+// output holding the whole URL. Plan properties are checked against the
+// plan's, any property is allowed if the plan is nil. Outputs are only known
+// once instances are provisioned so any output is allowed.
+func ValidateDashboardUrlTemplate(template string, plan *ServicePlan, field string) *validation.FieldError {
+	if template == "" {
+		return nil
+	}
+
+	vars := DashboardTemplateVariables("instance-id", brokerapi.ProvisionDetails{}, ServicePlan{}, nil)
+	if plan != nil {
+		vars = DashboardTemplateVariables("instance-id", brokerapi.ProvisionDetails{}, *plan, nil)
+	}
+
+	for _, match := range dashboardTemplateRegex.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if strings.HasPrefix(name, DashboardOutputPrefix) || (plan == nil && strings.HasPrefix(name, DashboardPlanPropertyPrefix)) {
+			vars[name] = "value"
+		}
+	}
+
+	// placeholders are replaced by sample values so the result can be checked
+	for name := range vars {
+		vars[name] = "value"
+	}
+
+	rendered, err := renderTemplate(dashboardTemplateRegex, template, vars)
+	if err == nil && !strings.HasPrefix(template, "{{") && validation.ErrIfNotURL(rendered, field) != nil {
+		err = fmt.Errorf("%q isn't a URL", template)
+	}
+
+	if err != nil {
+		return &validation.FieldError{
+			Message: fmt.Sprintf("invalid dashboard URL template: %v", err),
+			Paths:   []string{field},
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestServiceDefinition_GetDashboardUrlTemplate(t *testing.T) {
+	svc := ServiceDefinition{DashboardUrlTemplate: "https://service.example.com"}
+
+	if actual := svc.GetDashboardUrlTemplate(ServicePlan{}); actual != "https://service.example.com" {
+		t.Errorf("expected plans without template to use the service's, got %q", actual)
+	}
+
+	plan := ServicePlan{DashboardUrlTemplate: "https://plan.example.com"}
+	if actual := svc.GetDashboardUrlTemplate(plan); actual != "https://plan.example.com" {
+		t.Errorf("expected the plan's template to take precedence, got %q", actual)
+	}
+}
+
+func TestRenderDashboardUrl(t *testing.T) {
+	plan := ServicePlan{
+		ServicePlan:       brokerapi.ServicePlan{ID: "plan-id", Name: "small"},
+		ServiceProperties: map[string]interface{}{"tier": "db-f1", "replicas": 2},
+	}
+	details := brokerapi.ProvisionDetails{RawContext: json.RawMessage(`{"space_guid":"space-guid"}`)}
+	outputs := map[string]interface{}{"host": "db.example.com", "ports": []interface{}{5432}}
+	vars := DashboardTemplateVariables(testInstanceID, details, plan, outputs)
+
+	cases := map[string]struct {
+		Template      string
+		Expected      string
+		ExpectedError string
+	}{
+		"instance metadata": {
+			Template: "https://console.example.com/{{spaceGUID}}/{{ instanceID }}",
+			Expected: "https://console.example.com/space-guid/" + testInstanceID,
+		},
+		"plan properties": {
+			Template: "https://console.example.com/{{planName}}?tier={{plan.tier}}&replicas={{plan.replicas}}",
+			Expected: "https://console.example.com/small?tier=db-f1&replicas=2",
+		},
+		"outputs": {
+			Template: "https://{{output.host}}/admin",
+			Expected: "https://db.example.com/admin",
+		},
+		"non scalar output": {
+			Template:      "https://console.example.com/{{output.ports}}",
+			ExpectedError: "unknown template variable(s) output.ports",
+		},
+		"not a URL": {
+			Template:      "{{plan.tier}}",
+			ExpectedError: `dashboard URL "db-f1" isn't a URL`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := RenderDashboardUrl(tc.Template, vars)
+			if tc.ExpectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.ExpectedError, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if actual != tc.Expected {
+				t.Errorf("expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestValidateDashboardUrlTemplate(t *testing.T) {
+	plan := ServicePlan{ServiceProperties: map[string]interface{}{"tier": "db-f1"}}
+
+	cases := map[string]struct {
+		Template string
+		Plan     *ServicePlan
+		Valid    bool
+	}{
+		"empty":                     {Template: "", Plan: &plan, Valid: true},
+		"plan property":             {Template: "https://console.example.com/{{plan.tier}}", Plan: &plan, Valid: true},
+		"unknown plan property":     {Template: "https://console.example.com/{{plan.size}}", Plan: &plan, Valid: false},
+		"any property without plan": {Template: "https://console.example.com/{{plan.size}}", Plan: nil, Valid: true},
+		"output":                    {Template: "https://{{output.host}}", Plan: &plan, Valid: true},
+		"output holding the URL":    {Template: "{{output.console_url}}", Plan: &plan, Valid: true},
+		"unknown variable":          {Template: "https://console.example.com/{{instanceGUID}}", Plan: &plan, Valid: false},
+		"not a URL":                 {Template: "console/{{instanceID}}", Plan: &plan, Valid: false},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := ValidateDashboardUrlTemplate(tc.Template, tc.Plan, "dashboard_url_template")
+			if tc.Valid && err != nil {
+				t.Errorf("expected template to be valid, got %v", err)
+			}
+			if !tc.Valid && err == nil {
+				t.Errorf("expected template to be invalid")
+			}
+		})
+	}
+}
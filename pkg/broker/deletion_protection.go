@@ -0,0 +1,56 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// DeletionProtectionParameter is the user parameter protecting an instance
+// from being deprovisioned unless the request explicitly overrides it.
+const DeletionProtectionParameter = "deletion_protection"
+
+// DeletionProtection extracts the deletion protection from the raw request
+// parameters. Nil is returned if the request doesn't set it.
+func DeletionProtection(rawParameters json.RawMessage) (*bool, error) {
+	if len(rawParameters) == 0 {
+		return nil, nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	value, ok := params[DeletionProtectionParameter]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	enabled, ok := value.(bool)
+	if !ok {
+		return nil, brokerapi.NewFailureResponse(
+			fmt.Errorf("%s must be a boolean", DeletionProtectionParameter),
+			http.StatusBadRequest,
+			"invalid-deletion-protection",
+		)
+	}
+
+	return &enabled, nil
+}
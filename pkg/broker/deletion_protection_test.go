@@ -0,0 +1,61 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDeletionProtection(t *testing.T) {
+	enabled, disabled := true, false
+
+	cases := map[string]struct {
+		Raw           string
+		Expected      *bool
+		ExpectedError error
+	}{
+		"empty": {
+			Raw: ``,
+		},
+		"not set": {
+			Raw: `{"name":"db"}`,
+		},
+		"enabled": {
+			Raw:      `{"deletion_protection":true}`,
+			Expected: &enabled,
+		},
+		"disabled": {
+			Raw:      `{"deletion_protection":false}`,
+			Expected: &disabled,
+		},
+		"not a boolean": {
+			Raw:           `{"deletion_protection":"true"}`,
+			ExpectedError: errors.New("deletion_protection must be a boolean"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := DeletionProtection(json.RawMessage(tc.Raw))
+			expectError(t, tc.ExpectedError, err)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected deletion protection: %v got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// DeprecatedParameters returns the names of the deprecated provision input
+// variables set in the raw request parameters, sorted.
+func (svc *ServiceDefinition) DeprecatedParameters(rawParameters json.RawMessage) []string {
+	if len(rawParameters) == 0 {
+		return nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil
+	}
+
+	var deprecated []string
+	for _, variable := range svc.ProvisionInputVariables {
+		if _, ok := params[variable.FieldName]; ok && variable.Deprecated {
+			deprecated = append(deprecated, variable.FieldName)
+		}
+	}
+
+	sort.Strings(deprecated)
+	return deprecated
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestServiceDefinition_DeprecatedParameters(t *testing.T) {
+	service := ServiceDefinition{
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "tier", Type: JsonTypeString},
+			{FieldName: "size", Type: JsonTypeString, Deprecated: true},
+			{FieldName: "legacy_name", Type: JsonTypeString, Deprecated: true},
+		},
+	}
+
+	cases := map[string]struct {
+		Raw      string
+		Expected []string
+	}{
+		"empty": {
+			Raw: ``,
+		},
+		"none deprecated": {
+			Raw: `{"tier":"gold"}`,
+		},
+		"deprecated": {
+			Raw:      `{"tier":"gold","size":"large","legacy_name":"db"}`,
+			Expected: []string{"legacy_name", "size"},
+		},
+		"invalid": {
+			Raw: `{`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := service.DeprecatedParameters(json.RawMessage(tc.Raw))
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected deprecated parameters: %v got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeprovisionSchemaMetadataKey is the plan metadata key the JSONSchema of the
+// deprovision parameters is surfaced under in the catalog.
+const DeprovisionSchemaMetadataKey = "deprovisionSchema"
+
+// DeprovisionSchema returns the JSONSchema of the plan's deprovision
+// parameters, nil if the plan doesn't accept any.
+func (sp *ServicePlan) DeprovisionSchema() map[string]interface{} {
+	if len(sp.DeprovisionInputs) == 0 {
+		return nil
+	}
+
+	return CreateJsonSchema(sp.DeprovisionInputs)
+}
+
+// DeprovisionParameters validates the raw deprovision parameters against the
+// plan's deprovision inputs and returns them with defaults applied. Parameters
+// the plan doesn't declare are rejected so users don't believe they had an
+// effect.
+func (sp *ServicePlan) DeprovisionParameters(rawParameters json.RawMessage) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	declared := make(map[string]bool)
+	for _, input := range sp.DeprovisionInputs {
+		declared[input.FieldName] = true
+	}
+
+	var unknown []string
+	for name := range params {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("the plan doesn't accept the deprovision parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	ApplyDefaults(params, sp.DeprovisionInputs)
+	if err := ValidateVariables(params, sp.DeprovisionInputs); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
+
+type deprovisionParametersKey struct{}
+
+// WithDeprovisionParameters returns a copy of the context carrying the
+// validated deprovision parameters.
+func WithDeprovisionParameters(ctx context.Context, params map[string]interface{}) context.Context {
+	return context.WithValue(ctx, deprovisionParametersKey{}, params)
+}
+
+// GetDeprovisionParameters lets a ServiceProvider read the parameters passed
+// to Deprovision, e.g. to skip a final snapshot. It returns an empty map if
+// the request has none.
+func GetDeprovisionParameters(ctx context.Context) map[string]interface{} {
+	if params, ok := ctx.Value(deprovisionParametersKey{}).(map[string]interface{}); ok {
+		return params
+	}
+
+	return map[string]interface{}{}
+}
@@ -0,0 +1,126 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestServicePlan_DeprovisionParameters(t *testing.T) {
+	plan := ServicePlan{
+		DeprovisionInputs: []BrokerVariable{
+			{FieldName: "skip_final_snapshot", Type: JsonTypeBoolean, Details: "Skip the final snapshot.", Default: false},
+			{FieldName: "retention_days", Type: JsonTypeInteger, Details: "Days to keep backups."},
+		},
+	}
+
+	cases := map[string]struct {
+		Plan        ServicePlan
+		Raw         string
+		Expected    map[string]interface{}
+		ExpectError bool
+	}{
+		"no parameters": {
+			Plan:     ServicePlan{},
+			Raw:      "",
+			Expected: map[string]interface{}{},
+		},
+		"defaults": {
+			Plan:     plan,
+			Raw:      "",
+			Expected: map[string]interface{}{"skip_final_snapshot": false},
+		},
+		"valid": {
+			Plan:     plan,
+			Raw:      `{"skip_final_snapshot":true,"retention_days":7}`,
+			Expected: map[string]interface{}{"skip_final_snapshot": true, "retention_days": float64(7)},
+		},
+		"wrong type": {
+			Plan:        plan,
+			Raw:         `{"skip_final_snapshot":"yes"}`,
+			ExpectError: true,
+		},
+		"undeclared": {
+			Plan:        ServicePlan{},
+			Raw:         `{"skip_final_snapshot":true}`,
+			ExpectError: true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := tc.Plan.DeprovisionParameters(json.RawMessage(tc.Raw))
+			if tc.ExpectError {
+				if err == nil {
+					t.Errorf("expected an error, got parameters %v", actual)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("expected parameters %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetDeprovisionParameters(t *testing.T) {
+	if params := GetDeprovisionParameters(context.Background()); len(params) != 0 {
+		t.Errorf("expected no parameters, got %v", params)
+	}
+
+	expected := map[string]interface{}{"skip_final_snapshot": true}
+	if params := GetDeprovisionParameters(WithDeprovisionParameters(context.Background(), expected)); !reflect.DeepEqual(params, expected) {
+		t.Errorf("expected parameters %v, got %v", expected, params)
+	}
+}
+
+func TestService_ToPlain_DeprovisionSchema(t *testing.T) {
+	service := Service{
+		Plans: []ServicePlan{
+			{
+				ServicePlan: brokerapi.ServicePlan{ID: "snapshotted"},
+				DeprovisionInputs: []BrokerVariable{
+					{FieldName: "skip_final_snapshot", Type: JsonTypeBoolean, Details: "Skip the final snapshot."},
+				},
+			},
+			{
+				ServicePlan: brokerapi.ServicePlan{ID: "plain"},
+			},
+		},
+	}
+
+	plain := service.ToPlain()
+
+	schema, ok := plain.Plans[0].Metadata.AdditionalMetadata[DeprovisionSchemaMetadataKey].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a deprovision schema in the metadata, got %v", plain.Plans[0].Metadata)
+	}
+	if _, ok := schema["properties"].(map[string]interface{})["skip_final_snapshot"]; !ok {
+		t.Errorf("expected the schema to describe skip_final_snapshot, got %v", schema)
+	}
+
+	if plain.Plans[1].Metadata != nil {
+		t.Errorf("expected plans without deprovision inputs to be unchanged, got %v", plain.Plans[1].Metadata)
+	}
+}
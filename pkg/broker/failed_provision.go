@@ -0,0 +1,34 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import "context"
+
+type failedProvisionKey struct{}
+
+// WithFailedProvision returns a copy of the context marking the instance being
+// deprovisioned as one whose provision failed, with the provision's error.
+func WithFailedProvision(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, failedProvisionKey{}, reason)
+}
+
+// GetFailedProvision lets a ServiceProvider's Deprovision know the instance
+// never finished provisioning and why. Providers should then tear down
+// whatever partial resources exist and treat resources that were never
+// created as already deleted rather than failing.
+func GetFailedProvision(ctx context.Context) (reason string, failed bool) {
+	reason, failed = ctx.Value(failedProvisionKey{}).(string)
+	return reason, failed
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGetFailedProvision(t *testing.T) {
+	if _, failed := GetFailedProvision(context.Background()); failed {
+		t.Error("expected the provision not to be marked as failed")
+	}
+
+	reason, failed := GetFailedProvision(WithFailedProvision(context.Background(), "quota exceeded"))
+	if !failed || reason != "quota exceeded" {
+		t.Errorf("expected a failed provision with reason %q, got %q (failed: %t)", "quota exceeded", reason, failed)
+	}
+}
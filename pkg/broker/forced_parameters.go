@@ -0,0 +1,153 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// ProvisionForcedParametersProperty returns the Viper property name for the
+// object operators set to force provision parameters of the service's plans
+// regardless of user input, keyed by plan name, e.g.
+// {"standard": {"encrypted": true}}.
+func (svc *ServiceDefinition) ProvisionForcedParametersProperty() string {
+	return fmt.Sprintf("service.%s.provision.forced", svc.Name)
+}
+
+// ForcedParameters returns the provision parameters the operator forced for
+// the plan, nil if there are none.
+func (svc *ServiceDefinition) ForcedParameters(plan ServicePlan) map[string]interface{} {
+	forced := cast.ToStringMap(viper.GetStringMap(svc.ProvisionForcedParametersProperty())[plan.Name])
+	if len(forced) == 0 {
+		return nil
+	}
+
+	return forced
+}
+
+// ApplyForcedParameters returns the raw request parameters with the plan's
+// forced parameters set, so they are stored and validated like any other
+// value, and the sorted names of the parameters whose user supplied value was
+// replaced. Parameters are returned as-is if the plan forces none.
+func (svc *ServiceDefinition) ApplyForcedParameters(plan ServicePlan, rawParameters json.RawMessage) (json.RawMessage, []string, error) {
+	forced := svc.ForcedParameters(plan)
+	if len(forced) == 0 {
+		return rawParameters, nil, nil
+	}
+
+	params := map[string]interface{}{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var overridden []string
+	for name, value := range forced {
+		if userValue, ok := params[name]; ok && !sameJSONValue(userValue, value) {
+			overridden = append(overridden, name)
+		}
+		params[name] = value
+	}
+	sort.Strings(overridden)
+
+	out, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, overridden, nil
+}
+
+// validateForcedParameters checks the forced parameters of every plan are
+// provision input variables of the service and hold valid values.
+func (svc *ServiceDefinition) validateForcedParameters(plans []ServicePlan) error {
+	configured := viper.GetStringMap(svc.ProvisionForcedParametersProperty())
+
+	planNames := make(map[string]bool)
+	for _, plan := range plans {
+		planNames[plan.Name] = true
+	}
+
+	var unknownPlans []string
+	for name := range configured {
+		if !planNames[name] {
+			unknownPlans = append(unknownPlans, name)
+		}
+	}
+	if len(unknownPlans) > 0 {
+		sort.Strings(unknownPlans)
+		return fmt.Errorf("%s: unknown plan(s) %s", svc.ProvisionForcedParametersProperty(), strings.Join(unknownPlans, ", "))
+	}
+
+	for _, plan := range plans {
+		forced := svc.ForcedParameters(plan)
+		if len(forced) == 0 {
+			continue
+		}
+
+		// the user's parameters are validated with the forced ones in place, so
+		// only the forced values themselves are checked here
+		var variables []BrokerVariable
+		for name := range forced {
+			variable := svc.provisionInputVariable(name)
+			if variable == nil {
+				return fmt.Errorf("%s: plan %q forces %q, which isn't a provision input variable", svc.ProvisionForcedParametersProperty(), plan.Name, name)
+			}
+			variable.Required = false
+			variables = append(variables, *variable)
+		}
+
+		if err := ValidateVariables(forced, variables); err != nil {
+			return fmt.Errorf("%s: plan %q: %v", svc.ProvisionForcedParametersProperty(), plan.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (svc *ServiceDefinition) provisionInputVariable(name string) *BrokerVariable {
+	for _, variable := range svc.ProvisionInputVariables {
+		if variable.FieldName == name {
+			return &variable
+		}
+	}
+
+	return nil
+}
+
+// sameJSONValue compares values as they'd be serialized, so e.g. the numbers
+// of a JSON request and of the configuration compare equal.
+func sameJSONValue(a, b interface{}) bool {
+	var normalized [2]interface{}
+	for i, value := range []interface{}{a, b} {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return false
+		}
+		if err := json.Unmarshal(raw, &normalized[i]); err != nil {
+			return false
+		}
+	}
+
+	return reflect.DeepEqual(normalized[0], normalized[1])
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func forcedParametersService() *ServiceDefinition {
+	return &ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "forced-service",
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "encrypted", Type: JsonTypeBoolean, Details: "Encrypt the storage."},
+			{FieldName: "size", Type: JsonTypeInteger, Details: "The size in GB.", Required: true},
+		},
+	}
+}
+
+func TestServiceDefinition_ApplyForcedParameters(t *testing.T) {
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "standard"}}
+
+	cases := map[string]struct {
+		Forced             string
+		Raw                string
+		Expected           string
+		ExpectedOverridden []string
+	}{
+		"nothing forced": {
+			Forced:   `{}`,
+			Raw:      `{"encrypted":false}`,
+			Expected: `{"encrypted":false}`,
+		},
+		"other plan forced": {
+			Forced:   `{"premium":{"encrypted":true}}`,
+			Raw:      `{"encrypted":false}`,
+			Expected: `{"encrypted":false}`,
+		},
+		"user value replaced": {
+			Forced:             `{"standard":{"encrypted":true}}`,
+			Raw:                `{"encrypted":false,"size":10}`,
+			Expected:           `{"encrypted":true,"size":10}`,
+			ExpectedOverridden: []string{"encrypted"},
+		},
+		"user value matches": {
+			Forced:   `{"standard":{"encrypted":true,"size":10}}`,
+			Raw:      `{"encrypted":true,"size":10.0}`,
+			Expected: `{"encrypted":true,"size":10}`,
+		},
+		"no user parameters": {
+			Forced:   `{"standard":{"encrypted":true}}`,
+			Raw:      ``,
+			Expected: `{"encrypted":true}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			svc := forcedParametersService()
+			defer viper.Reset()
+			viper.Set(svc.ProvisionForcedParametersProperty(), tc.Forced)
+
+			actual, overridden, err := svc.ApplyForcedParameters(plan, json.RawMessage(tc.Raw))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(actual) != tc.Expected {
+				t.Errorf("expected parameters %s, got %s", tc.Expected, actual)
+			}
+
+			if !reflect.DeepEqual(overridden, tc.ExpectedOverridden) {
+				t.Errorf("expected overridden parameters %v, got %v", tc.ExpectedOverridden, overridden)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_validateForcedParameters(t *testing.T) {
+	plans := []ServicePlan{{ServicePlan: brokerapi.ServicePlan{Name: "standard"}}}
+
+	cases := map[string]struct {
+		Forced        string
+		ExpectedError error
+	}{
+		"none": {
+			Forced: ``,
+		},
+		"valid": {
+			Forced: `{"standard":{"encrypted":true}}`,
+		},
+		"unknown plan": {
+			Forced:        `{"premium":{"encrypted":true}}`,
+			ExpectedError: errors.New("service.forced-service.provision.forced: unknown plan(s) premium"),
+		},
+		"undeclared variable": {
+			Forced:        `{"standard":{"tier":"gold"}}`,
+			ExpectedError: errors.New(`service.forced-service.provision.forced: plan "standard" forces "tier", which isn't a provision input variable`),
+		},
+		"invalid value": {
+			Forced:        `{"standard":{"encrypted":"yes"}}`,
+			ExpectedError: errors.New(`service.forced-service.provision.forced: plan "standard": 1 error(s) occurred: encrypted: Invalid type. Expected: boolean, given: string`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			svc := forcedParametersService()
+			defer viper.Reset()
+			viper.Set(svc.ProvisionForcedParametersProperty(), tc.Forced)
+
+			expectError(t, tc.ExpectedError, svc.validateForcedParameters(plans))
+		})
+	}
+}
+
+func TestServiceDefinition_ProvisionVariables_Forced(t *testing.T) {
+	svc := forcedParametersService()
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "standard"}}
+
+	defer viper.Reset()
+	viper.Set(svc.ProvisionForcedParametersProperty(), `{"standard":{"encrypted":true}}`)
+
+	details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"encrypted":false,"size":10}`)}
+	vars, err := svc.ProvisionVariables("instance-id", details, plan, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if encrypted := vars.GetBool("encrypted"); !encrypted {
+		t.Error("expected the forced value to take precedence over the user's")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+const (
+	// GenerateTypePassword values are made of random characters of the
+	// directive's charset.
+	GenerateTypePassword = "password"
+	// GenerateTypeHex values are hex encoded random bytes.
+	GenerateTypeHex = "hex"
+
+	defaultGenerateLength  = 32
+	defaultGenerateCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// GenerateDirective tells the broker to generate a random value for a
+// provision variable the user didn't supply, e.g. an admin password.
+type GenerateDirective struct {
+	// Type is either "password" (the default) or "hex".
+	Type string `yaml:"type,omitempty"`
+	// Length is the number of characters of the value, 32 by default.
+	Length int `yaml:"length,omitempty"`
+	// Charset holds the characters passwords are made of, letters and digits
+	// by default.
+	Charset string `yaml:"charset,omitempty"`
+}
+
+var _ validation.Validatable = (*GenerateDirective)(nil)
+
+// Validate implements validation.Validatable.
+func (gd *GenerateDirective) Validate() (errs *validation.FieldError) {
+	switch gd.Type {
+	case "", GenerateTypePassword:
+		if gd.Charset != "" && len([]rune(gd.Charset)) < 2 {
+			errs = errs.Also(validation.ErrInvalidValue(gd.Charset, "charset"))
+		}
+	case GenerateTypeHex:
+		if gd.Charset != "" {
+			errs = errs.Also(validation.ErrInvalidValue(gd.Charset, "charset"))
+		}
+	default:
+		errs = errs.Also(validation.ErrInvalidValue(gd.Type, "type"))
+	}
+
+	if gd.Length < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(gd.Length, "length"))
+	}
+
+	return errs
+}
+
+// Generate returns a new cryptographically random value.
+func (gd *GenerateDirective) Generate() (string, error) {
+	length := gd.Length
+	if length == 0 {
+		length = defaultGenerateLength
+	}
+
+	if gd.Type == GenerateTypeHex {
+		buf := make([]byte, (length+1)/2)
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(buf)[:length], nil
+	}
+
+	charset := []rune(gd.Charset)
+	if len(charset) == 0 {
+		charset = []rune(defaultGenerateCharset)
+	}
+
+	value := make([]rune, length)
+	max := big.NewInt(int64(len(charset)))
+	for i := range value {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		value[i] = charset[n.Int64()]
+	}
+
+	return string(value), nil
+}
+
+// generateParameters returns the values of the variables with a generate
+// directive the user didn't supply in the raw parameters. Values in existing,
+// generated for an earlier operation on the instance, are reused so updates
+// don't change them.
+func generateParameters(rawParameters json.RawMessage, variables []BrokerVariable, existing map[string]string) (map[string]string, error) {
+	params := map[string]json.RawMessage{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	generated := map[string]string{}
+	for _, variable := range variables {
+		if variable.Generate == nil {
+			continue
+		}
+
+		if _, supplied := params[variable.FieldName]; supplied {
+			continue
+		}
+
+		if value, ok := existing[variable.FieldName]; ok {
+			generated[variable.FieldName] = value
+			continue
+		}
+
+		value, err := variable.Generate.Generate()
+		if err != nil {
+			return nil, err
+		}
+		generated[variable.FieldName] = value
+	}
+
+	return generated, nil
+}
+
+// withGeneratedParameters adds values for the provision variables with a
+// generate directive the user didn't supply to the raw parameters, as if the
+// user had supplied them.
+func (svc *ServiceDefinition) withGeneratedParameters(rawParameters json.RawMessage, existing map[string]string) (json.RawMessage, error) {
+	generated, err := generateParameters(rawParameters, svc.ProvisionInputVariables, existing)
+	if err != nil {
+		return nil, err
+	}
+	if len(generated) == 0 {
+		return rawParameters, nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return nil, err
+		}
+	}
+
+	for k, v := range generated {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		params[k] = encoded
+	}
+
+	return json.Marshal(params)
+}
+
+// GeneratedParameters returns the values the variables with a generate
+// directive resolved to, so they can be stored with the instance and reused by
+// later updates. Values the user supplied are included too, otherwise an update
+// without them would replace them with generated ones.
+func (svc *ServiceDefinition) GeneratedParameters(vars *varcontext.VarContext) map[string]string {
+	resolved := vars.ToMap()
+	generated := map[string]string{}
+	for _, variable := range svc.ProvisionInputVariables {
+		if variable.Generate == nil {
+			continue
+		}
+
+		if value, ok := resolved[variable.FieldName].(string); ok {
+			generated[variable.FieldName] = value
+		}
+	}
+
+	return generated
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+func TestGenerateDirective_Generate(t *testing.T) {
+	cases := map[string]struct {
+		Directive GenerateDirective
+		Pattern   string
+	}{
+		"default":  {Directive: GenerateDirective{}, Pattern: `^[a-zA-Z0-9]{32}$`},
+		"length":   {Directive: GenerateDirective{Length: 12}, Pattern: `^[a-zA-Z0-9]{12}$`},
+		"charset":  {Directive: GenerateDirective{Charset: "ab!"}, Pattern: `^[ab!]{32}$`},
+		"hex":      {Directive: GenerateDirective{Type: GenerateTypeHex, Length: 7}, Pattern: `^[0-9a-f]{7}$`},
+		"password": {Directive: GenerateDirective{Type: GenerateTypePassword, Length: 5, Charset: "xy"}, Pattern: `^[xy]{5}$`},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			value, err := tc.Directive.Generate()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !regexp.MustCompile(tc.Pattern).MatchString(value) {
+				t.Errorf("Expected %q to match %q", value, tc.Pattern)
+			}
+		})
+	}
+}
+
+func TestGenerateDirective_Validate(t *testing.T) {
+	cases := map[string]struct {
+		Directive GenerateDirective
+		Expected  string
+	}{
+		"empty":         {Directive: GenerateDirective{}, Expected: ""},
+		"hex":           {Directive: GenerateDirective{Type: GenerateTypeHex, Length: 16}, Expected: ""},
+		"bad-type":      {Directive: GenerateDirective{Type: "uuid"}, Expected: "invalid value: uuid: type"},
+		"bad-length":    {Directive: GenerateDirective{Length: -1}, Expected: "invalid value: -1: length"},
+		"short-charset": {Directive: GenerateDirective{Charset: "a"}, Expected: "invalid value: a: charset"},
+		"hex-charset":   {Directive: GenerateDirective{Type: GenerateTypeHex, Charset: "ab"}, Expected: "invalid value: ab: charset"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := ""
+			if err := tc.Directive.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected: %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_GeneratedParameters(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "admin_password", Type: JsonTypeString, Details: "The admin password.", Generate: &GenerateDirective{Length: 20}},
+			{FieldName: "name", Type: JsonTypeString, Details: "The name."},
+		},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "password", Default: `${instance.generated_parameters["admin_password"]}`, Overwrite: true},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}
+
+	var generated map[string]string
+	t.Run("provision", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"name":"db"}`)}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		generated = service.GeneratedParameters(vars)
+		if len(generated["admin_password"]) != 20 {
+			t.Fatalf("Expected a generated password, got %v", generated)
+		}
+		if _, ok := generated["name"]; ok {
+			t.Errorf("Expected only generated variables, got %v", generated)
+		}
+	})
+
+	t.Run("user-supplied", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"admin_password":"hunter2"}`)}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := vars.GetString("admin_password"); actual != "hunter2" {
+			t.Errorf("Expected the user's value, got %q", actual)
+		}
+	})
+
+	instance := models.ServiceInstanceDetails{ID: testInstanceID}
+	if err := instance.SetGeneratedParameters(generated); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("update-reuses", func(t *testing.T) {
+		vars, err := service.UpdateVariables(instance, brokerapi.UpdateDetails{}, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := vars.GetString("admin_password"); actual != generated["admin_password"] {
+			t.Errorf("Expected the stored value %q, got %q", generated["admin_password"], actual)
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		vars, err := service.BindVariables(instance, "binding-id", brokerapi.BindDetails{}, &plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := vars.GetString("password"); actual != generated["admin_password"] {
+			t.Errorf("Expected the stored value %q, got %q", generated["admin_password"], actual)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// InstanceMetadataParameter is the user parameter holding operator supplied
+// key/value metadata for an instance, e.g. a cost center or owner.
+const InstanceMetadataParameter = "instance_metadata"
+
+func errInvalidInstanceMetadata(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-instance-metadata")
+}
+
+// InstanceMetadata extracts the instance metadata from the raw request
+// parameters. A nil map is returned if no metadata was supplied.
+func InstanceMetadata(rawParameters json.RawMessage) (map[string]string, error) {
+	if len(rawParameters) == 0 {
+		return nil, nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	value, ok := params[InstanceMetadataParameter]
+	if !ok || value == nil {
+		return nil, nil
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errInvalidInstanceMetadata("%s must be an object", InstanceMetadataParameter)
+	}
+
+	metadata := map[string]string{}
+	for k, v := range object {
+		str, ok := v.(string)
+		if !ok {
+			return nil, errInvalidInstanceMetadata("%s.%s must be a string", InstanceMetadataParameter, k)
+		}
+		metadata[k] = str
+	}
+
+	if err := ValidateInstanceMetadata(metadata); err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// ValidateInstanceMetadata checks the metadata keys are non-empty.
+func ValidateInstanceMetadata(metadata map[string]string) error {
+	for k := range metadata {
+		if k == "" {
+			return errInvalidInstanceMetadata("%s keys must not be empty", InstanceMetadataParameter)
+		}
+	}
+
+	return nil
+}
+
+// RemovedInstanceMetadata returns the sorted keys of the previous metadata
+// that the current metadata no longer has, so providers that patch tags rather
+// than replacing them can remove those tags from the resources.
+func RemovedInstanceMetadata(previous, current map[string]string) []string {
+	removed := []string{}
+	for k := range previous {
+		if _, ok := current[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	sort.Strings(removed)
+	return removed
+}
+
+// withoutBrokerParameters removes the parameters the broker consumes itself,
+// the instance metadata, deletion protection and completion callback, from
+// the raw request parameters so they aren't passed to providers.
+func withoutBrokerParameters(rawParameters json.RawMessage) (json.RawMessage, error) {
+	if len(rawParameters) == 0 {
+		return rawParameters, nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	_, hasMetadata := params[InstanceMetadataParameter]
+	_, hasProtection := params[DeletionProtectionParameter]
+	_, hasCallback := params[CompletionCallbackParameter]
+	if !hasMetadata && !hasProtection && !hasCallback {
+		return rawParameters, nil
+	}
+
+	delete(params, InstanceMetadataParameter)
+	delete(params, DeletionProtectionParameter)
+	delete(params, CompletionCallbackParameter)
+	return json.Marshal(params)
+}
+
+// metadataVariable converts metadata to a value usable in HIL templates, so
+// brokerpaks can reference it explicitly.
+func metadataVariable(metadata map[string]string) map[string]interface{} {
+	out := map[string]interface{}{}
+	for k, v := range metadata {
+		out[k] = v
+	}
+	return out
+}
+
+// metadataKeysVariable converts metadata keys to a list usable in HIL
+// templates.
+func metadataKeysVariable(keys []string) []interface{} {
+	list := make([]interface{}, len(keys))
+	for i, k := range keys {
+		list[i] = k
+	}
+	return list
+}
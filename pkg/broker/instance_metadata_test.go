@@ -0,0 +1,158 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+func TestInstanceMetadata(t *testing.T) {
+	cases := map[string]struct {
+		Raw           string
+		Expected      map[string]string
+		ExpectedError error
+	}{
+		"empty": {
+			Raw: ``,
+		},
+		"not set": {
+			Raw: `{"name":"db"}`,
+		},
+		"set": {
+			Raw:      `{"instance_metadata":{"owner":"team-a","cost_center":"42"}}`,
+			Expected: map[string]string{"owner": "team-a", "cost_center": "42"},
+		},
+		"not an object": {
+			Raw:           `{"instance_metadata":"owner=team-a"}`,
+			ExpectedError: errors.New("instance_metadata must be an object"),
+		},
+		"non-string value": {
+			Raw:           `{"instance_metadata":{"cost_center":42}}`,
+			ExpectedError: errors.New("instance_metadata.cost_center must be a string"),
+		},
+		"empty key": {
+			Raw:           `{"instance_metadata":{"":"team-a"}}`,
+			ExpectedError: errors.New("instance_metadata keys must not be empty"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := InstanceMetadata(json.RawMessage(tc.Raw))
+			expectError(t, tc.ExpectedError, err)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected metadata: %v got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_InstanceMetadata(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionComputedVariables: []varcontext.DefaultVariable{
+			{Name: "owner", Default: `${request.instance_metadata["owner"]}`, Overwrite: true},
+		},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "owner", Default: `${instance.metadata["owner"]}`, Overwrite: true},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}
+
+	t.Run("provision", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"name":"db","instance_metadata":{"owner":"team-a"}}`)}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"name": "db", "owner": "team-a"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID, Metadata: `{"owner":"team-a"}`}
+		details := brokerapi.UpdateDetails{RawParameters: json.RawMessage(`{"instance_metadata":{"owner":"team-b"}}`)}
+		vars, err := service.UpdateVariables(instance, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"owner": "team-b"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("update-removes-tags", func(t *testing.T) {
+		service := service
+		service.ProvisionComputedVariables = []varcontext.DefaultVariable{
+			{Name: "removed", Default: `${json.marshal(request.removed_instance_metadata)}`, Overwrite: true},
+		}
+		instance := models.ServiceInstanceDetails{ID: testInstanceID, Metadata: `{"owner":"team-a","cost_center":"42","env":"prod"}`}
+		details := brokerapi.UpdateDetails{RawParameters: json.RawMessage(`{"instance_metadata":{"owner":"team-a"}}`)}
+		vars, err := service.UpdateVariables(instance, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"removed": `["cost_center","env"]`}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID, Metadata: `{"owner":"team-a"}`}
+		vars, err := service.BindVariables(instance, "binding-id", brokerapi.BindDetails{}, &plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"owner": "team-a"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+}
+
+func TestRemovedInstanceMetadata(t *testing.T) {
+	cases := map[string]struct {
+		Previous map[string]string
+		Current  map[string]string
+		Expected []string
+	}{
+		"unchanged":     {Previous: map[string]string{"owner": "team-a"}, Current: map[string]string{"owner": "team-a"}, Expected: []string{}},
+		"value-changed": {Previous: map[string]string{"owner": "team-a"}, Current: map[string]string{"owner": "team-b"}, Expected: []string{}},
+		"added":         {Previous: map[string]string{}, Current: map[string]string{"owner": "team-a"}, Expected: []string{}},
+		"removed":       {Previous: map[string]string{"owner": "team-a", "env": "prod", "cost_center": "42"}, Current: map[string]string{"owner": "team-a"}, Expected: []string{"cost_center", "env"}},
+		"all-removed":   {Previous: map[string]string{"owner": "team-a"}, Current: map[string]string{}, Expected: []string{"owner"}},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := RemovedInstanceMetadata(tc.Previous, tc.Current)
+			if !reflect.DeepEqual(actual, tc.Expected) {
+				t.Errorf("Expected removed keys %v got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// resourceNameHashLength is the length of the hashes replacing or
+	// shortening instance IDs in resource names.
+	resourceNameHashLength = 16
+
+	// resourceNameSuffixLength is the length of the hash ending truncated
+	// names, so names of different instances stay distinct.
+	resourceNameSuffixLength = 8
+)
+
+var (
+	invalidResourceNameChars  = regexp.MustCompile(`[^a-z0-9-]+`)
+	resourceNamingPrefixRegex = regexp.MustCompile(`^[a-z][-a-z0-9]*$`)
+)
+
+// InstanceNamer maps OSB instance IDs to names that are safe to use for cloud
+// resources. The name of an instance is derived once, when it's provisioned,
+// and stored so later operations use the same name even if the namer changes.
+type InstanceNamer interface {
+	InstanceName(instanceID string) string
+}
+
+// ResourceNaming is a configurable InstanceNamer. The instance ID is lower
+// cased with characters other than letters, digits and hyphens replaced by
+// hyphens, or replaced by a hash, and prefixed.
+type ResourceNaming struct {
+	// Prefix is prepended to all names, e.g. "csb-".
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+
+	// MaxLength truncates longer names, ending them with a hash of the
+	// instance ID so they stay unique. 0 means no limit.
+	MaxLength int `json:"max_length,omitempty" yaml:"max_length,omitempty"`
+
+	// Hash replaces the instance ID with a hash of it.
+	Hash bool `json:"hash,omitempty" yaml:"hash,omitempty"`
+}
+
+var _ InstanceNamer = (*ResourceNaming)(nil)
+var _ validation.Validatable = (*ResourceNaming)(nil)
+
+// Validate implements validation.Validatable.
+func (rn *ResourceNaming) Validate() (errs *validation.FieldError) {
+	if rn.Prefix != "" {
+		errs = errs.Also(validation.ErrIfNotMatch(rn.Prefix, resourceNamingPrefixRegex, "prefix"))
+	}
+
+	if rn.MaxLength < 0 || (rn.MaxLength > 0 && rn.MaxLength < len(rn.Prefix)+resourceNameSuffixLength+2) {
+		errs = errs.Also(validation.ErrInvalidValue(rn.MaxLength, "max_length"))
+	}
+
+	return errs
+}
+
+// InstanceName implements InstanceNamer.
+func (rn *ResourceNaming) InstanceName(instanceID string) string {
+	sum := sha256.Sum256([]byte(instanceID))
+	hash := hex.EncodeToString(sum[:])
+
+	name := invalidResourceNameChars.ReplaceAllString(strings.ToLower(instanceID), "-")
+	if rn.Hash {
+		name = hash[:resourceNameHashLength]
+	}
+	name = rn.Prefix + name
+
+	if rn.MaxLength > 0 && len(name) > rn.MaxLength {
+		suffix := hash[:resourceNameSuffixLength]
+		name = strings.TrimRight(name[:rn.MaxLength-len(suffix)-1], "-") + "-" + suffix
+	}
+
+	return name
+}
+
+// ResourceName returns the name the resources of a new instance are created
+// with, the instance ID if the service has no InstanceNamer.
+func (svc *ServiceDefinition) ResourceName(instanceID string) string {
+	if svc.InstanceNamer == nil {
+		return instanceID
+	}
+	return svc.InstanceNamer.InstanceName(instanceID)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"testing"
+)
+
+func TestResourceNaming_InstanceName(t *testing.T) {
+	cases := map[string]struct {
+		Naming   ResourceNaming
+		ID       string
+		Expected string
+	}{
+		"default":   {Naming: ResourceNaming{}, ID: "My_Instance.1", Expected: "my-instance-1"},
+		"prefix":    {Naming: ResourceNaming{Prefix: "csb-"}, ID: "abc", Expected: "csb-abc"},
+		"hash":      {Naming: ResourceNaming{Prefix: "csb-", Hash: true}, ID: "abc", Expected: "csb-ba7816bf8f01cfea"},
+		"truncated": {Naming: ResourceNaming{Prefix: "csb-", MaxLength: 20}, ID: "0123456789abcdefghij", Expected: "csb-0123456-6bc14bdc"},
+		"short":     {Naming: ResourceNaming{MaxLength: 20}, ID: "abc", Expected: "abc"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := tc.Naming.InstanceName(tc.ID)
+			if actual != tc.Expected {
+				t.Errorf("Expected: %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestResourceNaming_Validate(t *testing.T) {
+	cases := map[string]struct {
+		Naming   ResourceNaming
+		Expected string
+	}{
+		"empty":           {Naming: ResourceNaming{}, Expected: ""},
+		"valid":           {Naming: ResourceNaming{Prefix: "csb-", MaxLength: 63}, Expected: ""},
+		"bad-prefix":      {Naming: ResourceNaming{Prefix: "CSB_"}, Expected: "field must match '^[a-z][-a-z0-9]*$': prefix"},
+		"short-maxlength": {Naming: ResourceNaming{Prefix: "csb-", MaxLength: 10}, Expected: "invalid value: 10: max_length"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := ""
+			if err := tc.Naming.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected: %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_ResourceName(t *testing.T) {
+	svc := ServiceDefinition{}
+	if name := svc.ResourceName("abc"); name != "abc" {
+		t.Errorf("Expected the instance ID without a namer, got %q", name)
+	}
+
+	svc.InstanceNamer = &ResourceNaming{Prefix: "csb-"}
+	if name := svc.ResourceName("abc"); name != "csb-abc" {
+		t.Errorf("Expected the namer's name, got %q", name)
+	}
+}
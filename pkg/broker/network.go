@@ -0,0 +1,198 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// NetworkParameter is the user parameter holding the ID of an existing
+	// network to place the instance in.
+	NetworkParameter = "network"
+
+	// SubnetParameter is the user parameter holding the ID of an existing
+	// subnet to place the instance in.
+	SubnetParameter = "subnet"
+
+	// maxNetworkIDLength is the size of the database columns holding the IDs.
+	maxNetworkIDLength = 1024
+)
+
+// networkIDRegex matches the network and subnet IDs of the supported clouds,
+// e.g. vpc-0a1b2c, projects/p/global/networks/n or Azure resource IDs.
+var networkIDRegex = regexp.MustCompile(`^[a-zA-Z0-9/][-a-zA-Z0-9_.:/]*$`)
+
+// PlanNetwork configures the network instances of a plan are placed in. Only
+// plans with a network accept the network and subnet parameters, for others
+// they're ordinary user parameters.
+type PlanNetwork struct {
+	// Default and DefaultSubnet are used when the user doesn't supply a
+	// network or subnet.
+	Default       string `json:"default,omitempty" yaml:"default,omitempty"`
+	DefaultSubnet string `json:"default_subnet,omitempty" yaml:"default_subnet,omitempty"`
+
+	// Pattern is a regular expression user supplied networks must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// pattern is Pattern compiled by Validate.
+	pattern *regexp.Regexp
+}
+
+var _ validation.Validatable = (*PlanNetwork)(nil)
+
+// Validate implements validation.Validatable. It compiles the pattern so
+// requests don't have to.
+func (pn *PlanNetwork) Validate() (errs *validation.FieldError) {
+	if pn.Default != "" {
+		errs = errs.Also(validation.ErrIfNotMatch(pn.Default, networkIDRegex, "default"))
+	}
+
+	if pn.DefaultSubnet != "" {
+		errs = errs.Also(validation.ErrIfNotMatch(pn.DefaultSubnet, networkIDRegex, "default_subnet"))
+	}
+
+	if pn.Pattern != "" {
+		pattern, err := regexp.Compile(pn.Pattern)
+		if err != nil {
+			errs = errs.Also(validation.ErrInvalidValue(pn.Pattern, "pattern"))
+		}
+		pn.pattern = pattern
+	}
+
+	return errs
+}
+
+// compiledPattern returns the compiled Pattern, compiling it if the network
+// wasn't validated.
+func (pn *PlanNetwork) compiledPattern() (*regexp.Regexp, error) {
+	if pn.pattern != nil {
+		return pn.pattern, nil
+	}
+
+	return regexp.Compile(pn.Pattern)
+}
+
+// ResolvesNetwork returns true if any plan of the service has a network, so
+// the network and subnet parameters are the broker's.
+func (svc *ServiceDefinition) ResolvesNetwork() bool {
+	for _, plan := range svc.Plans {
+		if plan.Network != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InstanceNetwork is the network an instance is placed in.
+type InstanceNetwork struct {
+	Network string
+	Subnet  string
+}
+
+func errInvalidNetwork(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-network")
+}
+
+// ResolveNetwork extracts the network and subnet from the raw provision
+// parameters, falling back to the plan's defaults, and validates them. Plans
+// without a network have none.
+func ResolveNetwork(rawParameters json.RawMessage, plan ServicePlan) (InstanceNetwork, error) {
+	if plan.Network == nil {
+		return InstanceNetwork{}, nil
+	}
+
+	params := map[string]interface{}{}
+	if len(rawParameters) > 0 {
+		if err := json.Unmarshal(rawParameters, &params); err != nil {
+			return InstanceNetwork{}, err
+		}
+	}
+
+	network, err := networkParameter(params, NetworkParameter)
+	if err != nil {
+		return InstanceNetwork{}, err
+	}
+
+	subnet, err := networkParameter(params, SubnetParameter)
+	if err != nil {
+		return InstanceNetwork{}, err
+	}
+
+	if network != "" && plan.Network.Pattern != "" {
+		pattern, err := plan.Network.compiledPattern()
+		if err != nil {
+			return InstanceNetwork{}, fmt.Errorf("invalid network pattern of plan %q: %v", plan.Name, err)
+		}
+
+		if !pattern.MatchString(network) {
+			return InstanceNetwork{}, errInvalidNetwork("%s %q is not allowed by plan %q, it must match %q", NetworkParameter, network, plan.Name, plan.Network.Pattern)
+		}
+	}
+
+	if network == "" {
+		network = plan.Network.Default
+	}
+
+	if subnet == "" {
+		subnet = plan.Network.DefaultSubnet
+	}
+
+	return InstanceNetwork{Network: network, Subnet: subnet}, nil
+}
+
+func networkParameter(params map[string]interface{}, name string) (string, error) {
+	value, ok := params[name]
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	id, ok := value.(string)
+	if !ok {
+		return "", errInvalidNetwork("%s must be a string", name)
+	}
+
+	if len(id) > maxNetworkIDLength || !networkIDRegex.MatchString(id) {
+		return "", errInvalidNetwork("%s %q is not a valid network ID, it must match %q and be at most %d characters", name, id, networkIDRegex.String(), maxNetworkIDLength)
+	}
+
+	return id, nil
+}
+
+// networkVariables returns the variables to merge into a request context so
+// templates see the network of the instance, if it has one.
+func networkVariables(network, subnet interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	if network != nil && network != "" {
+		out[NetworkParameter] = network
+	}
+
+	if subnet != nil && subnet != "" {
+		out[SubnetParameter] = subnet
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+)
+
+func TestResolveNetwork(t *testing.T) {
+	plan := ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{Name: "peered"},
+		Network: &PlanNetwork{
+			Default:       "vpc-default",
+			DefaultSubnet: "subnet-default",
+			Pattern:       `^vpc-[a-z0-9]+$`,
+		},
+	}
+
+	cases := map[string]struct {
+		Plan          ServicePlan
+		Raw           string
+		Expected      InstanceNetwork
+		ExpectedError error
+	}{
+		"no plan network and no params": {
+			Plan: ServicePlan{},
+			Raw:  `{}`,
+		},
+		"no plan network": {
+			Plan: ServicePlan{},
+			Raw:  `{"network":"projects/p/global/networks/n","subnet":"sub net"}`,
+		},
+		"plan network without defaults": {
+			Plan:     ServicePlan{Network: &PlanNetwork{}},
+			Raw:      `{"network":"projects/p/global/networks/n","subnet":"sub-1"}`,
+			Expected: InstanceNetwork{Network: "projects/p/global/networks/n", Subnet: "sub-1"},
+		},
+		"plan defaults": {
+			Plan:     plan,
+			Raw:      ``,
+			Expected: InstanceNetwork{Network: "vpc-default", Subnet: "subnet-default"},
+		},
+		"user network": {
+			Plan:     plan,
+			Raw:      `{"network":"vpc-0a1b2c"}`,
+			Expected: InstanceNetwork{Network: "vpc-0a1b2c", Subnet: "subnet-default"},
+		},
+		"not allowed by plan": {
+			Plan:          plan,
+			Raw:           `{"network":"projects/p/global/networks/n"}`,
+			ExpectedError: errors.New(`network "projects/p/global/networks/n" is not allowed by plan "peered", it must match "^vpc-[a-z0-9]+$"`),
+		},
+		"bad format": {
+			Plan:          plan,
+			Raw:           `{"subnet":"sub net"}`,
+			ExpectedError: errors.New(`subnet "sub net" is not a valid network ID, it must match "^[a-zA-Z0-9/][-a-zA-Z0-9_.:/]*$" and be at most 1024 characters`),
+		},
+		"not a string": {
+			Plan:          plan,
+			Raw:           `{"network":42}`,
+			ExpectedError: errors.New("network must be a string"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := ResolveNetwork(json.RawMessage(tc.Raw), tc.Plan)
+			expectError(t, tc.ExpectedError, err)
+			if actual != tc.Expected {
+				t.Errorf("expected network %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_Network(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+	}
+	plan := ServicePlan{
+		ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"},
+		Network:     &PlanNetwork{Default: "vpc-default"},
+	}
+
+	t.Run("provision", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"subnet":"subnet-a"}`)}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"network": "vpc-default", "subnet": "subnet-a"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	instance := models.ServiceInstanceDetails{ID: testInstanceID, Network: "vpc-a", Subnet: "subnet-a"}
+	expected := map[string]interface{}{"network": "vpc-a", "subnet": "subnet-a"}
+
+	t.Run("update", func(t *testing.T) {
+		vars, err := service.UpdateVariables(instance, brokerapi.UpdateDetails{}, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		details := brokerapi.BindDetails{RawParameters: json.RawMessage(`{"network":"vpc-other"}`)}
+		vars, err := service.BindVariables(instance, "binding-id", details, &plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("update prohibited", func(t *testing.T) {
+		service := service
+		service.Plans = []ServicePlan{plan}
+
+		details := brokerapi.UpdateDetails{RawParameters: json.RawMessage(`{"network":"vpc-b","subnet":"subnet-b"}`)}
+		classification, err := service.ClassifyUpdate(details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(classification.Prohibited, []string{"network", "subnet"}) {
+			t.Errorf("Expected network and subnet to be prohibited, got %v", classification.Prohibited)
+		}
+	})
+
+	t.Run("user parameters without plan network", func(t *testing.T) {
+		details := brokerapi.UpdateDetails{RawParameters: json.RawMessage(`{"network":"vpc-b","subnet":"subnet-b"}`)}
+		classification, err := service.ClassifyUpdate(details)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(classification.Prohibited) != 0 {
+			t.Errorf("Expected nothing to be prohibited, got %v", classification.Prohibited)
+		}
+	})
+}
+
+func TestPlanNetwork_Validate(t *testing.T) {
+	network := PlanNetwork{Pattern: `^vpc-(`}
+	if err := network.Validate(); err == nil {
+		t.Fatal("Expected an invalid pattern to be rejected")
+	}
+
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "peered"}, Network: &network}
+	_, err := ResolveNetwork(json.RawMessage(`{"network":"vpc-a"}`), plan)
+	expectError(t, errors.New("invalid network pattern of plan \"peered\": error parsing regexp: missing closing ): `^vpc-(`"), err)
+
+	network = PlanNetwork{Pattern: `^vpc-`}
+	if err := network.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if network.pattern == nil {
+		t.Error("Expected the pattern to be compiled")
+	}
+}
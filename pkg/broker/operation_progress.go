@@ -0,0 +1,75 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// OperationProgress is how far a running asynchronous operation made of
+// several phases, e.g. network, compute and configuration, has got.
+type OperationProgress struct {
+	// Phase is the 1-based index of the current phase out of Phases.
+	Phase  int
+	Phases int
+
+	// Name describes the current phase, e.g. "configuring".
+	Name string
+
+	// Percent is how much of the whole operation is complete, from 0 to 100.
+	Percent int
+}
+
+// String formats the progress for the platform, e.g.
+// "phase 2/3: configuring (60%)".
+func (p OperationProgress) String() string {
+	var parts []string
+	if p.Phase > 0 && p.Phases > 0 {
+		parts = append(parts, fmt.Sprintf("phase %d/%d", p.Phase, p.Phases))
+	}
+	if p.Name != "" {
+		parts = append(parts, p.Name)
+	}
+
+	percent := p.Percent
+	switch {
+	case percent < 0:
+		percent = 0
+	case percent > 100:
+		percent = 100
+	}
+
+	return fmt.Sprintf("%s (%d%%)", strings.Join(parts, ": "), percent)
+}
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a copy of the context passing the progress
+// reported by ReportProgress to report.
+func WithProgressReporter(ctx context.Context, report func(OperationProgress)) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, report)
+}
+
+// ReportProgress lets a ServiceProvider report the progress of the operation
+// it's polling in PollInstance. The latest progress is kept until the
+// operation finishes and shown to users while the platform polls it. It does
+// nothing if the context doesn't belong to a poll.
+func ReportProgress(ctx context.Context, progress OperationProgress) {
+	if report, ok := ctx.Value(progressReporterKey{}).(func(OperationProgress)); ok {
+		report(progress)
+	}
+}
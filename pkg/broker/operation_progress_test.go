@@ -0,0 +1,58 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"testing"
+)
+
+func TestOperationProgress_String(t *testing.T) {
+	cases := map[string]struct {
+		Progress OperationProgress
+		Expected string
+	}{
+		"full":       {Progress: OperationProgress{Phase: 2, Phases: 3, Name: "configuring", Percent: 60}, Expected: "phase 2/3: configuring (60%)"},
+		"no name":    {Progress: OperationProgress{Phase: 1, Phases: 3, Percent: 5}, Expected: "phase 1/3 (5%)"},
+		"no phases":  {Progress: OperationProgress{Name: "configuring", Percent: 60}, Expected: "configuring (60%)"},
+		"over 100":   {Progress: OperationProgress{Phase: 3, Phases: 3, Name: "done", Percent: 120}, Expected: "phase 3/3: done (100%)"},
+		"below zero": {Progress: OperationProgress{Phase: 1, Phases: 2, Name: "network", Percent: -1}, Expected: "phase 1/2: network (0%)"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := tc.Progress.String(); actual != tc.Expected {
+				t.Errorf("Expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestReportProgress(t *testing.T) {
+	// without a reporter nothing happens
+	ReportProgress(context.Background(), OperationProgress{Phase: 1, Phases: 2})
+
+	var reported []OperationProgress
+	ctx := WithProgressReporter(context.Background(), func(progress OperationProgress) {
+		reported = append(reported, progress)
+	})
+
+	ReportProgress(ctx, OperationProgress{Phase: 1, Phases: 2, Name: "network"})
+	ReportProgress(ctx, OperationProgress{Phase: 2, Phases: 2, Name: "compute"})
+
+	if len(reported) != 2 || reported[1].Name != "compute" {
+		t.Errorf("Expected both phases to be reported, got %v", reported)
+	}
+}
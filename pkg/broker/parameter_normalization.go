@@ -0,0 +1,113 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+// NormalizeDirective tells the broker to rewrite the value of a string
+// provision variable to its canonical form before validating it, so e.g.
+// "US-EAST-1 " is accepted as "us-east-1".
+type NormalizeDirective struct {
+	// Trim removes leading and trailing whitespace.
+	Trim bool `yaml:"trim,omitempty"`
+	// Lowercase converts the value to lower case.
+	Lowercase bool `yaml:"lowercase,omitempty"`
+	// Aliases maps alternative values to canonical ones. They are looked up
+	// after trimming and lowercasing.
+	Aliases map[string]string `yaml:"aliases,omitempty"`
+}
+
+var _ validation.Validatable = (*NormalizeDirective)(nil)
+
+// Validate implements validation.Validatable.
+func (nd *NormalizeDirective) Validate() (errs *validation.FieldError) {
+	for alias := range nd.Aliases {
+		// aliases that can't survive the other steps would never match
+		if alias == "" || nd.apply(alias) != alias {
+			errs = errs.Also(validation.ErrInvalidValue(alias, "aliases"))
+		}
+	}
+
+	return errs
+}
+
+// Normalize returns the canonical form of the value.
+func (nd *NormalizeDirective) Normalize(value string) string {
+	value = nd.apply(value)
+	if canonical, ok := nd.Aliases[value]; ok {
+		return canonical
+	}
+	return value
+}
+
+func (nd *NormalizeDirective) apply(value string) string {
+	if nd.Trim {
+		value = strings.TrimSpace(value)
+	}
+	if nd.Lowercase {
+		value = strings.ToLower(value)
+	}
+	return value
+}
+
+// NormalizeParameters returns the raw request parameters with the string
+// values of provision input variables with a normalize directive replaced by
+// their canonical form. Parameters are returned as-is if nothing changed.
+func (svc *ServiceDefinition) NormalizeParameters(rawParameters json.RawMessage) (json.RawMessage, error) {
+	if len(rawParameters) == 0 {
+		return rawParameters, nil
+	}
+
+	params := map[string]json.RawMessage{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	changed := false
+	for _, variable := range svc.ProvisionInputVariables {
+		raw, ok := params[variable.FieldName]
+		if !ok || variable.Normalize == nil {
+			continue
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			// non-string values are left for validation to reject
+			continue
+		}
+
+		normalized := variable.Normalize.Normalize(value)
+		if normalized == value {
+			continue
+		}
+
+		encoded, err := json.Marshal(normalized)
+		if err != nil {
+			return nil, err
+		}
+		params[variable.FieldName] = encoded
+		changed = true
+	}
+
+	if !changed {
+		return rawParameters, nil
+	}
+	return json.Marshal(params)
+}
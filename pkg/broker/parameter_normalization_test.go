@@ -0,0 +1,138 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeDirective_Normalize(t *testing.T) {
+	regions := NormalizeDirective{
+		Trim:      true,
+		Lowercase: true,
+		Aliases:   map[string]string{"virginia": "us-east-1"},
+	}
+
+	cases := map[string]struct {
+		Directive NormalizeDirective
+		Value     string
+		Expected  string
+	}{
+		"empty directive": {Directive: NormalizeDirective{}, Value: " US-EAST-1 ", Expected: " US-EAST-1 "},
+		"trim":            {Directive: NormalizeDirective{Trim: true}, Value: " US-EAST-1 ", Expected: "US-EAST-1"},
+		"lowercase":       {Directive: NormalizeDirective{Lowercase: true}, Value: " US-EAST-1 ", Expected: " us-east-1 "},
+		"canonical":       {Directive: regions, Value: "us-east-1", Expected: "us-east-1"},
+		"upper case":      {Directive: regions, Value: "US-EAST-1", Expected: "us-east-1"},
+		"whitespace":      {Directive: regions, Value: "us-east-1 ", Expected: "us-east-1"},
+		"alias":           {Directive: regions, Value: " Virginia", Expected: "us-east-1"},
+		"unknown":         {Directive: regions, Value: "Mars", Expected: "mars"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := tc.Directive.Normalize(tc.Value); actual != tc.Expected {
+				t.Errorf("Expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestNormalizeDirective_Validate(t *testing.T) {
+	cases := map[string]struct {
+		Directive NormalizeDirective
+		Expected  string
+	}{
+		"empty":            {Directive: NormalizeDirective{}, Expected: ""},
+		"aliases":          {Directive: NormalizeDirective{Trim: true, Lowercase: true, Aliases: map[string]string{"virginia": "us-east-1"}}, Expected: ""},
+		"blank-alias":      {Directive: NormalizeDirective{Aliases: map[string]string{"": "us-east-1"}}, Expected: "invalid value: : aliases"},
+		"upper-case-alias": {Directive: NormalizeDirective{Lowercase: true, Aliases: map[string]string{"Virginia": "us-east-1"}}, Expected: "invalid value: Virginia: aliases"},
+		"padded-alias":     {Directive: NormalizeDirective{Trim: true, Aliases: map[string]string{" virginia": "us-east-1"}}, Expected: "invalid value:  virginia: aliases"},
+		"case-kept-alias":  {Directive: NormalizeDirective{Aliases: map[string]string{"Virginia": "us-east-1"}}, Expected: ""},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual := ""
+			if err := tc.Directive.Validate(); err != nil {
+				actual = err.Error()
+			}
+			if actual != tc.Expected {
+				t.Errorf("Expected: %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_NormalizeParameters(t *testing.T) {
+	service := ServiceDefinition{
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "region", Type: JsonTypeString, Details: "region", Normalize: &NormalizeDirective{Trim: true, Lowercase: true}},
+			{FieldName: "name", Type: JsonTypeString, Details: "name"},
+		},
+	}
+
+	cases := map[string]struct {
+		Parameters string
+		Expected   map[string]interface{}
+		Unchanged  bool
+	}{
+		"no parameters": {
+			Parameters: "",
+			Unchanged:  true,
+		},
+		"normalized": {
+			Parameters: `{"region":"US-EAST-1 ","name":" My DB "}`,
+			Expected:   map[string]interface{}{"region": "us-east-1", "name": " My DB "},
+		},
+		"already canonical": {
+			Parameters: `{"region": "us-east-1"}`,
+			Unchanged:  true,
+		},
+		"not a string": {
+			Parameters: `{"region": 42}`,
+			Unchanged:  true,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := service.NormalizeParameters(json.RawMessage(tc.Parameters))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.Unchanged {
+				if string(actual) != tc.Parameters {
+					t.Errorf("Expected parameters to be unchanged, got %s", actual)
+				}
+				return
+			}
+
+			parsed := map[string]interface{}{}
+			if err := json.Unmarshal(actual, &parsed); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(parsed, tc.Expected) {
+				t.Errorf("Expected %v, got %v", tc.Expected, parsed)
+			}
+		})
+	}
+
+	if _, err := service.NormalizeParameters(json.RawMessage(`[]`)); err == nil {
+		t.Error("Expected an error for parameters that aren't an object")
+	}
+}
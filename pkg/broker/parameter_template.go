@@ -0,0 +1,142 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+// parameterTemplateRegex matches placeholders like {{spaceName}} in string
+// parameters. Only plain variable names are supported, there are no
+// functions or expressions a user could abuse.
+var parameterTemplateRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z]+)\s*\}\}`)
+
+// ProvisionTemplateVariables returns the instance metadata user supplied
+// provision parameters can reference. The GUIDs and names from the request
+// context take precedence over the deprecated top-level fields.
+func ProvisionTemplateVariables(instanceId string, details brokerapi.ProvisionDetails) map[string]string {
+	vars := map[string]string{
+		"instanceID":       instanceId,
+		"organizationGUID": details.OrganizationGUID,
+		"spaceGUID":        details.SpaceGUID,
+		"organizationName": "",
+		"spaceName":        "",
+		"instanceName":     "",
+	}
+
+	requestContext := map[string]interface{}{}
+	json.Unmarshal(details.GetRawContext(), &requestContext) // explicitly ignore parse errors
+
+	for contextKey, variable := range map[string]string{
+		"organization_guid": "organizationGUID",
+		"space_guid":        "spaceGUID",
+		"organization_name": "organizationName",
+		"space_name":        "spaceName",
+		"instance_name":     "instanceName",
+	} {
+		if value, ok := requestContext[contextKey].(string); ok {
+			vars[variable] = value
+		}
+	}
+
+	return vars
+}
+
+// RenderParameterTemplates replaces the placeholders in the string values of
+// the raw JSON parameters with the given variables. Placeholders are replaced
+// in a single pass, so values containing placeholders themselves aren't
+// expanded again. Unknown placeholders are rejected with a 400 error.
+func RenderParameterTemplates(rawParameters json.RawMessage, vars map[string]string) (json.RawMessage, error) {
+	if len(rawParameters) == 0 || !parameterTemplateRegex.Match(rawParameters) {
+		return rawParameters, nil
+	}
+
+	var params interface{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderStrings(params, func(value string) (string, error) {
+		return renderTemplate(parameterTemplateRegex, value, vars)
+	})
+	if err != nil {
+		return nil, brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-parameter-template")
+	}
+
+	return json.Marshal(rendered)
+}
+
+// renderStrings replaces the string values nested in the JSON value with the
+// result of render. Errors are prefixed with the path of the value.
+func renderStrings(value interface{}, render func(string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return render(v)
+
+	case map[string]interface{}:
+		for key, elem := range v {
+			rendered, err := renderStrings(elem, render)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			v[key] = rendered
+		}
+
+	case []interface{}:
+		for i, elem := range v {
+			rendered, err := renderStrings(elem, render)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			v[i] = rendered
+		}
+	}
+
+	return value, nil
+}
+
+func renderTemplate(pattern *regexp.Regexp, value string, vars map[string]string) (string, error) {
+	var unknown []string
+	rendered := pattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+		name := pattern.FindStringSubmatch(placeholder)[1]
+		replacement, ok := vars[name]
+		if !ok {
+			unknown = append(unknown, name)
+		}
+		return replacement
+	})
+
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown template variable(s) %s, valid variables are: %s", strings.Join(unknown, ", "), strings.Join(sortedVariableNames(vars), ", "))
+	}
+
+	return rendered, nil
+}
+
+func sortedVariableNames(vars map[string]string) []string {
+	var names []string
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
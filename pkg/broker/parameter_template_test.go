@@ -0,0 +1,135 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+func TestProvisionTemplateVariables(t *testing.T) {
+	details := brokerapi.ProvisionDetails{
+		OrganizationGUID: "deprecated-org",
+		SpaceGUID:        "deprecated-space",
+		RawContext:       json.RawMessage(`{"organization_guid":"org-guid","space_guid":"space-guid","organization_name":"my-org","space_name":"my-space","instance_name":42}`),
+	}
+
+	expected := map[string]string{
+		"instanceID":       testInstanceID,
+		"organizationGUID": "org-guid",
+		"spaceGUID":        "space-guid",
+		"organizationName": "my-org",
+		"spaceName":        "my-space",
+		"instanceName":     "",
+	}
+
+	if actual := ProvisionTemplateVariables(testInstanceID, details); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected variables %v, got %v", expected, actual)
+	}
+}
+
+func TestRenderParameterTemplates(t *testing.T) {
+	vars := map[string]string{
+		"spaceName":  "dev",
+		"instanceID": "{{spaceName}}",
+	}
+
+	cases := map[string]struct {
+		Raw           string
+		Expected      string
+		ExpectedError error
+	}{
+		"empty": {
+			Raw:      ``,
+			Expected: ``,
+		},
+		"no templates": {
+			Raw:      `{"name": "plain"}`,
+			Expected: `{"name": "plain"}`,
+		},
+		"nested values": {
+			Raw:      `{"name":"db-{{ spaceName }}","tags":["{{spaceName}}",1],"labels":{"space":"{{spaceName}}"}}`,
+			Expected: `{"labels":{"space":"dev"},"name":"db-dev","tags":["dev",1]}`,
+		},
+		"replacements aren't expanded again": {
+			Raw:      `{"name":"{{instanceID}}"}`,
+			Expected: `{"name":"{{spaceName}}"}`,
+		},
+		"keys aren't rendered": {
+			Raw:      `{"{{spaceName}}":"value"}`,
+			Expected: `{"{{spaceName}}":"value"}`,
+		},
+		"unknown variable": {
+			Raw:           `{"name":"{{password}}"}`,
+			ExpectedError: errors.New("name: unknown template variable(s) password, valid variables are: instanceID, spaceName"),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := RenderParameterTemplates(json.RawMessage(tc.Raw), vars)
+			expectError(t, tc.ExpectedError, err)
+			if string(actual) != tc.Expected {
+				t.Errorf("expected parameters %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_ParameterTemplates(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionInputVariables: []BrokerVariable{
+			{
+				FieldName: "name",
+				Type:      JsonTypeString,
+				Constraints: validation.NewConstraintBuilder().
+					Pattern("^[a-z-]+$").
+					Build(),
+			},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}
+
+	t.Run("rendered", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{
+			RawParameters: json.RawMessage(`{"name":"db-{{spaceName}}"}`),
+			RawContext:    json.RawMessage(`{"space_name":"dev"}`),
+		}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := vars.GetString("name"); actual != "db-dev" {
+			t.Errorf("expected name db-dev, got %q", actual)
+		}
+	})
+
+	t.Run("rendered output is validated", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{
+			RawParameters: json.RawMessage(`{"name":"db-{{spaceName}}"}`),
+			RawContext:    json.RawMessage(`{"space_name":"Dev Space"}`),
+		}
+		if _, err := service.ProvisionVariables(testInstanceID, details, plan, nil); err == nil {
+			t.Error("expected the rendered name to fail validation")
+		}
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import "fmt"
+
+// PendingProvisionError is returned by a ServiceProvider's Provision along
+// with the details of an instance that was created but isn't usable yet, e.g.
+// because it still has to be configured. The broker records the instance and
+// polls it with PollInstance until the remaining work is done, even if the
+// provider usually provisions synchronously.
+type PendingProvisionError struct {
+	// Reason describes the remaining work.
+	Reason string
+}
+
+func (e *PendingProvisionError) Error() string {
+	return fmt.Sprintf("the instance was created but is still being set up: %s", e.Reason)
+}
+
+// IsPendingProvision returns true if a provider's Provision returned a
+// partially provisioned instance rather than failing.
+func IsPendingProvision(err error) bool {
+	_, ok := err.(*PendingProvisionError)
+	return ok
+}
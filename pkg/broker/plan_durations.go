@@ -0,0 +1,99 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// ProvisionTimeoutMetadataKey is the plan metadata key the provision
+	// timeout is surfaced under in the catalog.
+	ProvisionTimeoutMetadataKey = "provisionTimeout"
+
+	// EstimatedDurationMetadataKey is the plan metadata key the estimated
+	// provisioning duration is surfaced under in the catalog.
+	EstimatedDurationMetadataKey = "estimatedDuration"
+)
+
+// ValidateDurations checks the plan's durations are positive Go durations,
+// e.g. 45m or 1h30m.
+func (sp *ServicePlan) ValidateDurations() (errs *validation.FieldError) {
+	errs = errs.Also(validatePlanDuration(sp.ProvisionTimeout, "provision_timeout"))
+	errs = errs.Also(validatePlanDuration(sp.EstimatedDuration, "estimated_duration"))
+	errs = errs.Also(validatePlanDuration(sp.PollInterval, "poll_interval"))
+	return errs
+}
+
+// GetProvisionTimeout returns the failsafe after which an in progress
+// provision of the plan is reported as failed, 0 if the plan has none.
+func (sp *ServicePlan) GetProvisionTimeout() time.Duration {
+	timeout, _ := time.ParseDuration(sp.ProvisionTimeout)
+	return timeout
+}
+
+// GetPollInterval returns how long the platform should wait between polls of
+// in progress operations of the plan, 0 if the plan doesn't say.
+func (sp *ServicePlan) GetPollInterval() time.Duration {
+	interval, _ := time.ParseDuration(sp.PollInterval)
+	return interval
+}
+
+// plainPlan returns the OSB plan with the durations and the deprovision
+// schema added to its metadata.
+func (sp ServicePlan) plainPlan() brokerapi.ServicePlan {
+	plain := sp.ServicePlan
+	deprovisionSchema := sp.DeprovisionSchema()
+	if sp.ProvisionTimeout == "" && sp.EstimatedDuration == "" && deprovisionSchema == nil {
+		return plain
+	}
+
+	metadata := brokerapi.ServicePlanMetadata{}
+	if plain.Metadata != nil {
+		metadata = *plain.Metadata
+	}
+
+	metadata.AdditionalMetadata = copyMap(metadata.AdditionalMetadata)
+	if metadata.AdditionalMetadata == nil {
+		metadata.AdditionalMetadata = map[string]interface{}{}
+	}
+	if sp.ProvisionTimeout != "" {
+		metadata.AdditionalMetadata[ProvisionTimeoutMetadataKey] = sp.ProvisionTimeout
+	}
+	if sp.EstimatedDuration != "" {
+		metadata.AdditionalMetadata[EstimatedDurationMetadataKey] = sp.EstimatedDuration
+	}
+	if deprovisionSchema != nil {
+		metadata.AdditionalMetadata[DeprovisionSchemaMetadataKey] = deprovisionSchema
+	}
+
+	plain.Metadata = &metadata
+	return plain
+}
+
+func validatePlanDuration(value, field string) *validation.FieldError {
+	if value == "" {
+		return nil
+	}
+
+	if duration, err := time.ParseDuration(value); err != nil || duration <= 0 {
+		return validation.ErrInvalidValue(value, field)
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+func TestServicePlan_ValidateDurations(t *testing.T) {
+	cases := map[string]struct {
+		Plan     ServicePlan
+		Expected string
+	}{
+		"unset": {
+			Plan: ServicePlan{},
+		},
+		"valid": {
+			Plan: ServicePlan{ProvisionTimeout: "2h", EstimatedDuration: "45m"},
+		},
+		"unparseable": {
+			Plan:     ServicePlan{ProvisionTimeout: "two hours"},
+			Expected: "invalid value: two hours: provision_timeout",
+		},
+		"negative": {
+			Plan:     ServicePlan{EstimatedDuration: "-5m"},
+			Expected: "invalid value: -5m: estimated_duration",
+		},
+		"poll interval": {
+			Plan:     ServicePlan{PollInterval: "0s"},
+			Expected: "invalid value: 0s: poll_interval",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := tc.Plan.ValidateDurations()
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected error %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServicePlan_GetProvisionTimeout(t *testing.T) {
+	plan := ServicePlan{ProvisionTimeout: "1h30m"}
+	if actual := plan.GetProvisionTimeout(); actual != 90*time.Minute {
+		t.Errorf("Expected timeout 1h30m0s, got %s", actual)
+	}
+
+	if actual := (&ServicePlan{}).GetProvisionTimeout(); actual != 0 {
+		t.Errorf("Expected no timeout, got %s", actual)
+	}
+}
+
+func TestService_ToPlain_Durations(t *testing.T) {
+	metadata := &brokerapi.ServicePlanMetadata{DisplayName: "Large"}
+	service := Service{
+		Plans: []ServicePlan{
+			{
+				ServicePlan:       brokerapi.ServicePlan{ID: "large", Metadata: metadata},
+				ProvisionTimeout:  "2h",
+				EstimatedDuration: "45m",
+			},
+			{
+				ServicePlan: brokerapi.ServicePlan{ID: "small"},
+			},
+		},
+	}
+
+	plain := service.ToPlain()
+
+	expected := &brokerapi.ServicePlanMetadata{
+		DisplayName: "Large",
+		AdditionalMetadata: map[string]interface{}{
+			ProvisionTimeoutMetadataKey:  "2h",
+			EstimatedDurationMetadataKey: "45m",
+		},
+	}
+	if !reflect.DeepEqual(plain.Plans[0].Metadata, expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, plain.Plans[0].Metadata)
+	}
+
+	if plain.Plans[1].Metadata != nil {
+		t.Errorf("Expected plans without durations to be unchanged, got %v", plain.Plans[1].Metadata)
+	}
+
+	if metadata.AdditionalMetadata != nil {
+		t.Errorf("Expected the service's plan metadata to be unchanged, got %v", metadata.AdditionalMetadata)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// PlanInstanceQuotaProperty returns the Viper property name for the object
+// operators set to cap the number of instances of the service's plans across
+// the whole foundation, keyed by plan name, e.g. {"premium": 10}.
+func (svc *ServiceDefinition) PlanInstanceQuotaProperty() string {
+	return fmt.Sprintf("service.%s.provision.instance_quota", svc.Name)
+}
+
+// PlanInstanceQuota returns the maximum number of instances of the plan, 0 if
+// the operator didn't cap it.
+func (svc *ServiceDefinition) PlanInstanceQuota(plan ServicePlan) int {
+	return cast.ToInt(viper.GetStringMap(svc.PlanInstanceQuotaProperty())[plan.Name])
+}
+
+// validatePlanInstanceQuota checks the quotas are set for known plans and are
+// positive integers.
+func (svc *ServiceDefinition) validatePlanInstanceQuota(plans []ServicePlan) error {
+	configured := viper.GetStringMap(svc.PlanInstanceQuotaProperty())
+
+	planNames := make(map[string]bool)
+	for _, plan := range plans {
+		planNames[plan.Name] = true
+	}
+
+	var unknownPlans []string
+	for name, value := range configured {
+		if !planNames[name] {
+			unknownPlans = append(unknownPlans, name)
+			continue
+		}
+
+		if quota, err := cast.ToIntE(value); err != nil || quota <= 0 {
+			return fmt.Errorf("%s: plan %q must have a positive integer quota, got %v", svc.PlanInstanceQuotaProperty(), name, value)
+		}
+	}
+	if len(unknownPlans) > 0 {
+		sort.Strings(unknownPlans)
+		return fmt.Errorf("%s: unknown plan(s) %s", svc.PlanInstanceQuotaProperty(), strings.Join(unknownPlans, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+func TestServiceDefinition_PlanInstanceQuota(t *testing.T) {
+	svc := ServiceDefinition{Name: "quota-service"}
+	standard := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "standard"}}
+	premium := ServicePlan{ServicePlan: brokerapi.ServicePlan{Name: "premium"}}
+
+	cases := map[string]struct {
+		Quota         string
+		ExpectedQuota int
+	}{
+		"none":        {Quota: ``, ExpectedQuota: 0},
+		"capped":      {Quota: `{"premium":10}`, ExpectedQuota: 10},
+		"other plans": {Quota: `{"standard":10}`, ExpectedQuota: 0},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set(svc.PlanInstanceQuotaProperty(), tc.Quota)
+
+			if quota := svc.PlanInstanceQuota(premium); quota != tc.ExpectedQuota {
+				t.Errorf("expected quota %d, got %d", tc.ExpectedQuota, quota)
+			}
+		})
+	}
+
+	t.Run("validation", func(t *testing.T) {
+		validationCases := map[string]struct {
+			Quota         string
+			ExpectedError error
+		}{
+			"none":  {Quota: ``},
+			"valid": {Quota: `{"standard":5,"premium":1}`},
+			"unknown plan": {
+				Quota:         `{"enterprise":5}`,
+				ExpectedError: errors.New("service.quota-service.provision.instance_quota: unknown plan(s) enterprise"),
+			},
+			"zero": {
+				Quota:         `{"premium":0}`,
+				ExpectedError: errors.New(`service.quota-service.provision.instance_quota: plan "premium" must have a positive integer quota, got 0`),
+			},
+			"not a number": {
+				Quota:         `{"premium":"ten"}`,
+				ExpectedError: errors.New(`service.quota-service.provision.instance_quota: plan "premium" must have a positive integer quota, got ten`),
+			},
+		}
+
+		for tn, tc := range validationCases {
+			t.Run(tn, func(t *testing.T) {
+				defer viper.Reset()
+				viper.Set(svc.PlanInstanceQuotaProperty(), tc.Quota)
+
+				expectError(t, tc.ExpectedError, svc.validatePlanInstanceQuota([]ServicePlan{standard, premium}))
+			})
+		}
+	})
+}
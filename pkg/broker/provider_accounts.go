@@ -0,0 +1,167 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	providerAccountsProp      = "provider.accounts"
+	providerSpaceAccountsProp = "provider.space_accounts"
+	providerOrgAccountsProp   = "provider.org_accounts"
+)
+
+func init() {
+	viper.BindEnv(providerAccountsProp, "PROVIDER_ACCOUNTS")
+	viper.BindEnv(providerSpaceAccountsProp, "PROVIDER_SPACE_ACCOUNTS")
+	viper.BindEnv(providerOrgAccountsProp, "PROVIDER_ORG_ACCOUNTS")
+}
+
+// ProviderAccountEnv returns the environment variables of the named provider
+// account profile, which replace the broker's own when the provider of an
+// instance created with the account runs, e.g. credentials of another cloud
+// account. The empty name stands for the broker's own credentials and has no
+// variables.
+func ProviderAccountEnv(account string) (map[string]string, error) {
+	if account == "" {
+		return nil, nil
+	}
+
+	accounts, err := providerAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	env, ok := accounts[account]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider account %q", account)
+	}
+
+	return env, nil
+}
+
+// providerAccounts returns the configured account profiles. JSON strings are
+// decoded directly because Viper lowercases the keys of nested maps, which
+// would change the names of the environment variables.
+func providerAccounts() (map[string]map[string]string, error) {
+	accounts := map[string]map[string]string{}
+	switch value := viper.Get(providerAccountsProp).(type) {
+	case nil:
+		return accounts, nil
+	case string:
+		if value == "" {
+			return accounts, nil
+		}
+		if err := json.Unmarshal([]byte(value), &accounts); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object of objects of strings: %v", providerAccountsProp, err)
+		}
+		return accounts, nil
+	default:
+		profiles, err := cast.ToStringMapE(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be an object: %v", providerAccountsProp, err)
+		}
+		for name, profile := range profiles {
+			if accounts[name], err = cast.ToStringMapStringE(profile); err != nil {
+				return nil, fmt.Errorf("provider account %q must be an object of environment variables: %v", name, err)
+			}
+		}
+		return accounts, nil
+	}
+}
+
+// SelectProviderAccount returns the provider account instances provisioned in
+// the space and organization are created with. Spaces mapped to an account
+// take precedence over their organization, the empty name is returned for
+// requests neither is mapped for.
+func SelectProviderAccount(orgGUID, spaceGUID string) string {
+	if account, ok := viper.GetStringMapString(providerSpaceAccountsProp)[spaceGUID]; ok && spaceGUID != "" {
+		return account
+	}
+
+	if account, ok := viper.GetStringMapString(providerOrgAccountsProp)[orgGUID]; ok && orgGUID != "" {
+		return account
+	}
+
+	return ""
+}
+
+// ValidateProviderAccounts checks every account profile has environment
+// variables and the space and organization mappings only reference known
+// profiles.
+func ValidateProviderAccounts() error {
+	accounts, err := providerAccounts()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(accounts))
+	for name := range accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(accounts[name]) == 0 {
+			return fmt.Errorf("provider account %q has no environment variables", name)
+		}
+	}
+
+	for _, prop := range []string{providerSpaceAccountsProp, providerOrgAccountsProp} {
+		mapping := viper.GetStringMapString(prop)
+		guids := make([]string, 0, len(mapping))
+		for guid := range mapping {
+			guids = append(guids, guid)
+		}
+		sort.Strings(guids)
+
+		for _, guid := range guids {
+			if _, ok := accounts[mapping[guid]]; !ok {
+				return fmt.Errorf("%s maps %q to unknown provider account %q", prop, guid, mapping[guid])
+			}
+		}
+	}
+
+	return nil
+}
+
+// AccountProvider creates the provider of the service that uses the named
+// provider account, see ProviderAccountEnv. The empty name creates the
+// provider with the broker's own credentials.
+func (svc *ServiceDefinition) AccountProvider(logger lager.Logger, account string) (ServiceProvider, error) {
+	if account == "" {
+		return svc.ProviderBuilder(logger), nil
+	}
+
+	if svc.AccountProviderBuilder == nil {
+		return nil, brokerapi.NewFailureResponse(fmt.Errorf("service %s doesn't support provider accounts", svc.Name), http.StatusUnprocessableEntity, "provider-account-unsupported")
+	}
+
+	env, err := ProviderAccountEnv(account)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.AccountProviderBuilder(logger, env), nil
+}
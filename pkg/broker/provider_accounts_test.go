@@ -0,0 +1,171 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+)
+
+func TestSelectProviderAccount(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(providerSpaceAccountsProp, map[string]string{"prod-space": "prod"})
+	viper.Set(providerOrgAccountsProp, map[string]string{"prod-org": "prod-shared", "other-org": "other"})
+
+	cases := map[string]struct {
+		OrgGUID   string
+		SpaceGUID string
+		Expected  string
+	}{
+		"space":             {OrgGUID: "other-org", SpaceGUID: "prod-space", Expected: "prod"},
+		"org":               {OrgGUID: "prod-org", SpaceGUID: "dev-space", Expected: "prod-shared"},
+		"unmapped":          {OrgGUID: "dev-org", SpaceGUID: "dev-space", Expected: ""},
+		"no context":        {Expected: ""},
+		"space without org": {SpaceGUID: "prod-space", Expected: "prod"},
+		"org without space": {OrgGUID: "other-org", Expected: "other"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := SelectProviderAccount(tc.OrgGUID, tc.SpaceGUID); actual != tc.Expected {
+				t.Errorf("Expected account %q got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestProviderAccountEnv(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(providerAccountsProp, `{"prod":{"GOOGLE_CREDENTIALS":"{}","GOOGLE_PROJECT":"prod-project"}}`)
+
+	env, err := ProviderAccountEnv("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"GOOGLE_CREDENTIALS": "{}", "GOOGLE_PROJECT": "prod-project"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("Expected env %v got %v", expected, env)
+	}
+
+	if env, err := ProviderAccountEnv(""); err != nil || env != nil {
+		t.Errorf("Expected no env for the broker's account, got %v, %v", env, err)
+	}
+
+	if _, err := ProviderAccountEnv("dev"); err == nil || err.Error() != `unknown provider account "dev"` {
+		t.Errorf("Expected an unknown account error, got %v", err)
+	}
+}
+
+func TestValidateProviderAccounts(t *testing.T) {
+	cases := map[string]struct {
+		Accounts      interface{}
+		SpaceAccounts map[string]string
+		OrgAccounts   map[string]string
+		ExpectedErr   string
+	}{
+		"not configured": {},
+		"valid": {
+			Accounts:      `{"prod":{"GOOGLE_PROJECT":"prod-project"}}`,
+			SpaceAccounts: map[string]string{"prod-space": "prod"},
+			OrgAccounts:   map[string]string{"prod-org": "prod"},
+		},
+		"invalid json": {
+			Accounts:    `{"prod":"prod-project"}`,
+			ExpectedErr: "provider.accounts must be a JSON object of objects of strings: ",
+		},
+		"empty account": {
+			Accounts:    `{"prod":{}}`,
+			ExpectedErr: `provider account "prod" has no environment variables`,
+		},
+		"unknown space account": {
+			Accounts:      `{"prod":{"GOOGLE_PROJECT":"prod-project"}}`,
+			SpaceAccounts: map[string]string{"prod-space": "staging"},
+			ExpectedErr:   `provider.space_accounts maps "prod-space" to unknown provider account "staging"`,
+		},
+		"unknown org account": {
+			OrgAccounts: map[string]string{"prod-org": "prod"},
+			ExpectedErr: `provider.org_accounts maps "prod-org" to unknown provider account "prod"`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			if tc.Accounts != nil {
+				viper.Set(providerAccountsProp, tc.Accounts)
+			}
+			viper.Set(providerSpaceAccountsProp, tc.SpaceAccounts)
+			viper.Set(providerOrgAccountsProp, tc.OrgAccounts)
+
+			err := ValidateProviderAccounts()
+			switch {
+			case tc.ExpectedErr == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.ExpectedErr != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.ExpectedErr)):
+				t.Errorf("Expected error %q, got %v", tc.ExpectedErr, err)
+			}
+		})
+	}
+}
+
+// accountProvider tells providers created for different accounts apart.
+type accountProvider struct {
+	ServiceProvider
+	account string
+}
+
+func TestServiceDefinition_AccountProvider(t *testing.T) {
+	defer viper.Reset()
+	viper.Set(providerAccountsProp, `{"prod":{"GOOGLE_PROJECT":"prod-project"}}`)
+
+	var accountEnv map[string]string
+	service := ServiceDefinition{
+		Name:            "left-handed-smoke-sifter",
+		ProviderBuilder: func(logger lager.Logger) ServiceProvider { return accountProvider{} },
+		AccountProviderBuilder: func(logger lager.Logger, env map[string]string) ServiceProvider {
+			accountEnv = env
+			return accountProvider{account: "prod"}
+		},
+	}
+	logger := utils.NewLogger("account-provider-test")
+
+	provider, err := service.AccountProvider(logger, "")
+	if err != nil || provider != (accountProvider{}) {
+		t.Errorf("Expected the default provider for the broker's account, got %v, %v", provider, err)
+	}
+
+	provider, err = service.AccountProvider(logger, "prod")
+	if err != nil || provider != (accountProvider{account: "prod"}) {
+		t.Errorf("Expected the account provider, got %v, %v", provider, err)
+	}
+	if expected := map[string]string{"GOOGLE_PROJECT": "prod-project"}; !reflect.DeepEqual(accountEnv, expected) {
+		t.Errorf("Expected the account env %v got %v", expected, accountEnv)
+	}
+
+	if _, err := service.AccountProvider(logger, "dev"); err == nil {
+		t.Error("Expected an error for an unknown account")
+	}
+
+	service.AccountProviderBuilder = nil
+	_, err = service.AccountProvider(logger, "prod")
+	if err == nil || err.Error() != "service left-handed-smoke-sifter doesn't support provider accounts" {
+		t.Errorf("Expected an unsupported error, got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+const (
+	// ReadinessProbeTCP probes an instance by connecting to a host:port.
+	ReadinessProbeTCP = "tcp"
+
+	// ReadinessProbeHTTP probes an instance by requesting a URL, any status
+	// below 400 counts as ready.
+	ReadinessProbeHTTP = "http"
+
+	defaultReadinessProbeTimeout = 30 * time.Minute
+	readinessProbeAttemptTimeout = 5 * time.Second
+)
+
+// ReadinessProbe checks a provisioned instance is usable, e.g. a database
+// accepts connections, before the provision is reported as succeeded.
+type ReadinessProbe struct {
+	// Type is either tcp or http.
+	Type string `json:"type" yaml:"type"`
+
+	// Output is the provision output holding the address to probe, a host:port
+	// for tcp probes and a URL for http probes.
+	Output string `json:"output" yaml:"output"`
+
+	// Timeout is a Go duration, measured from the start of the provision,
+	// after which a failing probe fails the provision. Defaults to 30m.
+	Timeout string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+}
+
+var _ validation.Validatable = (*ReadinessProbe)(nil)
+
+// Validate implements validation.Validatable.
+func (rp *ReadinessProbe) Validate() (errs *validation.FieldError) {
+	if rp.Type != ReadinessProbeTCP && rp.Type != ReadinessProbeHTTP {
+		errs = errs.Also(validation.ErrInvalidValue(rp.Type, "type"))
+	}
+
+	return errs.Also(
+		validation.ErrIfBlank(rp.Output, "output"),
+		validatePlanDuration(rp.Timeout, "timeout"),
+	)
+}
+
+// GetTimeout returns how long after the start of the provision the probe may
+// fail before the provision fails.
+func (rp *ReadinessProbe) GetTimeout() time.Duration {
+	if timeout, err := time.ParseDuration(rp.Timeout); err == nil && timeout > 0 {
+		return timeout
+	}
+	return defaultReadinessProbeTimeout
+}
+
+// Check probes the address in the provision outputs once.
+func (rp *ReadinessProbe) Check(ctx context.Context, outputs map[string]interface{}) error {
+	address, ok := outputs[rp.Output].(string)
+	if !ok || address == "" {
+		return fmt.Errorf("the provision output %q holding the address to probe is missing", rp.Output)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessProbeAttemptTimeout)
+	defer cancel()
+
+	switch rp.Type {
+	case ReadinessProbeTCP:
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case ReadinessProbeHTTP:
+		req, err := http.NewRequest(http.MethodGet, address, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s responded with %s", address, resp.Status)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown readiness probe type %q", rp.Type)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessProbe_Validate(t *testing.T) {
+	cases := map[string]struct {
+		Probe    ReadinessProbe
+		Expected string
+	}{
+		"tcp": {
+			Probe: ReadinessProbe{Type: "tcp", Output: "address", Timeout: "10m"},
+		},
+		"http": {
+			Probe: ReadinessProbe{Type: "http", Output: "uri"},
+		},
+		"bad type": {
+			Probe:    ReadinessProbe{Type: "udp", Output: "address"},
+			Expected: "invalid value: udp: type",
+		},
+		"missing output": {
+			Probe:    ReadinessProbe{Type: "tcp"},
+			Expected: "missing field(s): output",
+		},
+		"bad timeout": {
+			Probe:    ReadinessProbe{Type: "tcp", Output: "address", Timeout: "ten minutes"},
+			Expected: "invalid value: ten minutes: timeout",
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := tc.Probe.Validate()
+			actual := ""
+			if err != nil {
+				actual = err.Error()
+			}
+
+			if actual != tc.Expected {
+				t.Errorf("Expected error %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestReadinessProbe_GetTimeout(t *testing.T) {
+	if actual := (&ReadinessProbe{Timeout: "10m"}).GetTimeout(); actual != 10*time.Minute {
+		t.Errorf("expected timeout 10m, got %s", actual)
+	}
+
+	if actual := (&ReadinessProbe{}).GetTimeout(); actual != defaultReadinessProbeTimeout {
+		t.Errorf("expected default timeout, got %s", actual)
+	}
+}
+
+func TestReadinessProbe_Check(t *testing.T) {
+	ready := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ready.Close()
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddress := listener.Addr().String()
+	listener.Close()
+
+	cases := map[string]struct {
+		Probe       ReadinessProbe
+		Outputs     map[string]interface{}
+		ExpectReady bool
+	}{
+		"http ready": {
+			Probe:       ReadinessProbe{Type: "http", Output: "uri"},
+			Outputs:     map[string]interface{}{"uri": ready.URL},
+			ExpectReady: true,
+		},
+		"http unavailable": {
+			Probe:   ReadinessProbe{Type: "http", Output: "uri"},
+			Outputs: map[string]interface{}{"uri": unavailable.URL},
+		},
+		"tcp ready": {
+			Probe:       ReadinessProbe{Type: "tcp", Output: "address"},
+			Outputs:     map[string]interface{}{"address": ready.Listener.Addr().String()},
+			ExpectReady: true,
+		},
+		"tcp refused": {
+			Probe:   ReadinessProbe{Type: "tcp", Output: "address"},
+			Outputs: map[string]interface{}{"address": closedAddress},
+		},
+		"missing output": {
+			Probe:   ReadinessProbe{Type: "tcp", Output: "address"},
+			Outputs: map[string]interface{}{},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := tc.Probe.Check(context.Background(), tc.Outputs)
+			if tc.ExpectReady && err != nil {
+				t.Errorf("expected the instance to be ready, got %v", err)
+			}
+			if !tc.ExpectReady && err == nil {
+				t.Error("expected the probe to fail")
+			}
+		})
+	}
+}
@@ -19,8 +19,10 @@ import (
 	"log"
 	"sort"
 
+	"github.com/pivotal-cf/brokerapi"
 	"github.com/pivotal/cloud-service-broker/pkg/toggles"
 	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 var (
@@ -120,5 +122,230 @@ func (brokerRegistry BrokerRegistry) GetServiceById(id string) (*ServiceDefiniti
 		}
 	}
 
-	return nil, fmt.Errorf("Unknown service ID: %q", id)
+	return nil, &ServiceNotFoundError{ID: id}
+}
+
+// GetPlanById returns the plan with the given ID along with the service it
+// belongs to. An error is returned if no registered service has the plan.
+func (brokerRegistry BrokerRegistry) GetPlanById(id string) (*ServiceDefinition, *ServicePlan, error) {
+	for _, svc := range brokerRegistry.GetAllServices() {
+		if plan, err := svc.GetPlanById(id); err == nil {
+			return svc, plan, nil
+		}
+	}
+
+	return nil, nil, &PlanNotFoundError{ID: id}
+}
+
+// ServiceNotFoundError is returned when looking up a service ID that isn't
+// registered.
+type ServiceNotFoundError struct {
+	ID string
+}
+
+func (e *ServiceNotFoundError) Error() string {
+	return fmt.Sprintf("Unknown service ID: %q", e.ID)
+}
+
+// PlanNotFoundError is returned when looking up a plan ID that isn't part of
+// the catalog.
+type PlanNotFoundError struct {
+	ID string
+}
+
+func (e *PlanNotFoundError) Error() string {
+	return fmt.Sprintf("Plan ID %q could not be found", e.ID)
+}
+
+// CatalogProblem describes a single issue found while validating the catalog.
+type CatalogProblem struct {
+	ServiceId   string `json:"service_id,omitempty"`
+	ServiceName string `json:"service_name,omitempty"`
+	PlanId      string `json:"plan_id,omitempty"`
+	Pointer     string `json:"pointer,omitempty"`
+	Message     string `json:"message"`
+}
+
+// ValidateCatalog builds the catalog entry of every registered service and
+// checks it for problems that would otherwise only be found when a client
+// hits the broker. An empty result means the catalog is valid.
+func (brokerRegistry BrokerRegistry) ValidateCatalog() []CatalogProblem {
+	var problems []CatalogProblem
+	seenIds := make(map[string]string)
+
+	checkId := func(id, owner string, problem CatalogProblem) {
+		if id == "" {
+			return
+		}
+
+		if other, ok := seenIds[id]; ok {
+			problem.Message = fmt.Sprintf("duplicate ID %q, already used by %s", id, other)
+			problems = append(problems, problem)
+			return
+		}
+
+		seenIds[id] = owner
+	}
+
+	for _, svc := range brokerRegistry.GetAllServices() {
+		svcProblem := CatalogProblem{ServiceId: svc.Id, ServiceName: svc.Name}
+
+		if err := svc.Validate(); err != nil {
+			svcProblem.Message = err.Error()
+			problems = append(problems, svcProblem)
+		}
+
+		entry, err := svc.CatalogEntry()
+		if err != nil {
+			svcProblem.Message = fmt.Sprintf("couldn't build catalog entry: %v", err)
+			problems = append(problems, svcProblem)
+			continue
+		}
+
+		checkId(entry.ID, fmt.Sprintf("service %q", svc.Name), svcProblem)
+
+		if entry.Description == "" {
+			svcProblem.Message = "missing required field: description"
+			problems = append(problems, svcProblem)
+		}
+
+		if len(entry.Plans) == 0 {
+			svcProblem.Message = "service has no plans"
+			problems = append(problems, svcProblem)
+		}
+
+		for _, schema := range []map[string]interface{}{
+			CreateJsonSchema(svc.ProvisionInputVariables),
+			CreateJsonSchema(svc.BindInputVariables),
+		} {
+			if _, err := gojsonschema.NewSchema(gojsonschema.NewGoLoader(schema)); err != nil {
+				svcProblem.Message = fmt.Sprintf("invalid JSON schema: %v", err)
+				problems = append(problems, svcProblem)
+			}
+		}
+
+		if err := svc.validateForcedParameters(entry.Plans); err != nil {
+			svcProblem.Message = fmt.Sprintf("invalid forced parameters: %v", err)
+			problems = append(problems, svcProblem)
+		}
+
+		if err := svc.validatePlanInstanceQuota(entry.Plans); err != nil {
+			svcProblem.Message = fmt.Sprintf("invalid plan instance quota: %v", err)
+			problems = append(problems, svcProblem)
+		}
+
+		if _, err := svc.CredentialKeyMapping(); err != nil {
+			svcProblem.Message = fmt.Sprintf("invalid credential key mapping: %v", err)
+			problems = append(problems, svcProblem)
+		}
+
+		for _, output := range svc.BindOutputVariables {
+			if output.FieldName == SyslogDrainURLOutput && !svc.RequiresPermission(brokerapi.PermissionSyslogDrain) {
+				svcProblem.Message = fmt.Sprintf("bind output %q requires the service to declare %q", SyslogDrainURLOutput, brokerapi.PermissionSyslogDrain)
+				problems = append(problems, svcProblem)
+			}
+
+			if output.FieldName == RouteServiceURLOutput && !svc.RequiresPermission(brokerapi.PermissionRouteForwarding) {
+				svcProblem.Message = fmt.Sprintf("bind output %q requires the service to declare %q", RouteServiceURLOutput, brokerapi.PermissionRouteForwarding)
+				problems = append(problems, svcProblem)
+			}
+		}
+
+		for _, plan := range entry.Plans {
+			planProblem := svcProblem
+			planProblem.PlanId = plan.ID
+
+			checkId(plan.ID, fmt.Sprintf("plan %q of service %q", plan.Name, svc.Name), planProblem)
+
+			if plan.ID == "" {
+				planProblem.Message = "missing required field: id"
+				problems = append(problems, planProblem)
+			}
+
+			if plan.Name == "" {
+				planProblem.Message = "missing required field: name"
+				problems = append(problems, planProblem)
+			}
+
+			if plan.Description == "" {
+				planProblem.Message = "missing required field: description"
+				problems = append(problems, planProblem)
+			}
+
+			isFree := plan.Free != nil && *plan.Free
+			if !isFree && (plan.Metadata == nil || len(plan.Metadata.Costs) == 0) {
+				planProblem.Message = "plan is not free but has no costs"
+				problems = append(problems, planProblem)
+			}
+
+			if plan.Metadata != nil {
+				for _, cost := range plan.Metadata.Costs {
+					for currency, amount := range cost.Amount {
+						pc := PlanCost{Amount: amount, Currency: currency, Unit: cost.Unit}
+						if err := pc.Validate(); err != nil {
+							planProblem.Message = fmt.Sprintf("invalid cost: %v", err)
+							problems = append(problems, planProblem)
+						}
+					}
+				}
+			}
+
+			if plan.Network != nil {
+				if err := plan.Network.Validate(); err != nil {
+					planProblem.Message = fmt.Sprintf("invalid network: %v", err)
+					problems = append(problems, planProblem)
+				}
+			}
+
+			if plan.AvailabilityZones != nil {
+				if err := plan.AvailabilityZones.Validate(); err != nil {
+					planProblem.Message = fmt.Sprintf("invalid availability zones: %v", err)
+					problems = append(problems, planProblem)
+				}
+			}
+
+			if err := plan.ValidateDurations(); err != nil {
+				planProblem.Message = fmt.Sprintf("invalid duration: %v", err)
+				problems = append(problems, planProblem)
+			}
+
+			if plan.ReadinessProbe != nil {
+				if err := plan.ReadinessProbe.Validate(); err != nil {
+					planProblem.Message = fmt.Sprintf("invalid readiness probe: %v", err)
+					problems = append(problems, planProblem)
+				}
+			}
+
+			if err := ValidateDashboardUrlTemplate(svc.GetDashboardUrlTemplate(plan), &plan, "dashboard_url_template"); err != nil {
+				planProblem.Message = err.Error()
+				problems = append(problems, planProblem)
+			}
+
+			if err := svc.validateDashboardOutputs(svc.GetDashboardUrlTemplate(plan), "dashboard_url_template"); err != nil {
+				planProblem.Message = err.Error()
+				problems = append(problems, planProblem)
+			}
+
+			if plan.IsBindable(svc.Bindable) && !svc.Bindable {
+				planProblem.Message = "plan is bindable but its service isn't"
+				problems = append(problems, planProblem)
+			}
+
+			for _, required := range plan.RequiresInstanceOf {
+				if _, ok := brokerRegistry[required]; !ok {
+					planProblem.Message = fmt.Sprintf("requires an instance of unknown service %q", required)
+					problems = append(problems, planProblem)
+				}
+			}
+		}
+	}
+
+	// Problems building the entries were already reported above.
+	for _, problem := range brokerRegistry.ValidateCatalogSchema() {
+		if problem.Pointer != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	return problems
 }
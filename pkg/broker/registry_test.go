@@ -15,6 +15,7 @@
 package broker
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/pivotal-cf/brokerapi"
@@ -98,3 +99,177 @@ func TestRegistry_GetEnabledServices(t *testing.T) {
 		})
 	}
 }
+
+func TestRegistry_ValidateCatalog(t *testing.T) {
+	newService := func(name, serviceId, planId string) *ServiceDefinition {
+		return &ServiceDefinition{
+			Id:          serviceId,
+			Name:        name,
+			Description: "a test service",
+			Plans: []ServicePlan{
+				{
+					ServicePlan: brokerapi.ServicePlan{
+						ID:          planId,
+						Name:        "plan",
+						Description: "a test plan",
+						Free:        brokerapi.FreeValue(true),
+					},
+				},
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		Services         []*ServiceDefinition
+		ExpectedMessages []string
+	}{
+		"valid": {
+			Services: []*ServiceDefinition{
+				newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43"),
+			},
+			ExpectedMessages: nil,
+		},
+		"duplicate plan ids": {
+			Services: []*ServiceDefinition{
+				newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43"),
+				newService("svc-b", "a42c1182-d1a0-4d40-82c1-28220518b360", "e1d11f65-da66-46ad-977c-6d56513baf43"),
+			},
+			ExpectedMessages: []string{`duplicate ID "e1d11f65-da66-46ad-977c-6d56513baf43", already used by plan "plan" of service "svc-a"`},
+		},
+		"plan without costs": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Plans[0].Free = brokerapi.FreeValue(false)
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"plan is not free but has no costs"},
+		},
+		"invalid costs": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Plans[0].Free = brokerapi.FreeValue(false)
+				svc.Plans[0].Metadata = &brokerapi.ServicePlanMetadata{
+					Costs: []brokerapi.ServicePlanCost{{Amount: map[string]float64{"USD": -1}, Unit: "MONTHLY"}},
+				}
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"invalid cost: invalid value: -1: amount"},
+		},
+		"invalid durations": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Plans[0].ProvisionTimeout = "2 hours"
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"invalid duration: invalid value: 2 hours: provision_timeout"},
+		},
+		"invalid readiness probe": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Plans[0].ReadinessProbe = &ReadinessProbe{Type: "udp", Output: "uri"}
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"invalid readiness probe: invalid value: udp: type"},
+		},
+		"unknown prerequisite": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Plans[0].RequiresInstanceOf = []string{"private-link"}
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{`requires an instance of unknown service "private-link"`},
+		},
+		"invalid dashboard URL template": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.DashboardUrlTemplate = "https://console.example.com/{{plan.tier}}"
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"invalid dashboard URL template: unknown template variable(s) plan.tier, valid variables are: instanceID, instanceName, organizationGUID, organizationName, planID, planName, spaceGUID, spaceName: dashboard_url_template"},
+		},
+		"bindable plan of unbindable service": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Bindable = false
+				svc.Plans[0].Bindable = brokerapi.BindableValue(true)
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"plan is bindable but its service isn't"},
+		},
+		"missing descriptions": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.Description = ""
+				svc.Plans[0].Description = ""
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{"missing required field: description", "missing required field: description"},
+		},
+		"syslog drain without requires": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.BindOutputVariables = []BrokerVariable{{FieldName: "syslog_drain_url", Type: JsonTypeString, Details: "drain"}}
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{`bind output "syslog_drain_url" requires the service to declare "syslog_drain"`},
+		},
+		"route service without requires": {
+			Services: func() []*ServiceDefinition {
+				svc := newService("svc-a", "b9e4332e-b42b-4680-bda5-ea1506797474", "e1d11f65-da66-46ad-977c-6d56513baf43")
+				svc.BindOutputVariables = []BrokerVariable{{FieldName: "route_service_url", Type: JsonTypeString, Details: "route service"}}
+				return []*ServiceDefinition{svc}
+			}(),
+			ExpectedMessages: []string{`bind output "route_service_url" requires the service to declare "route_forwarding"`},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			registry := BrokerRegistry{}
+			for _, svc := range tc.Services {
+				registry.Register(svc)
+			}
+
+			var actual []string
+			for _, problem := range registry.ValidateCatalog() {
+				actual = append(actual, problem.Message)
+			}
+
+			if !reflect.DeepEqual(tc.ExpectedMessages, actual) {
+				t.Errorf("Expected problems %v, got %v", tc.ExpectedMessages, actual)
+			}
+		})
+	}
+}
+
+func TestRegistry_GetPlanById(t *testing.T) {
+	sd := ServiceDefinition{
+		Id:   "b9e4332e-b42b-4680-bda5-ea1506797474",
+		Name: "test-service",
+		Plans: []ServicePlan{
+			{
+				ServicePlan: brokerapi.ServicePlan{
+					ID:          "e1d11f65-da66-46ad-977c-6d56513baf43",
+					Name:        "plan",
+					Description: "a test plan",
+				},
+			},
+		},
+	}
+
+	registry := BrokerRegistry{}
+	registry.Register(&sd)
+
+	svc, plan, err := registry.GetPlanById("e1d11f65-da66-46ad-977c-6d56513baf43")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc.Id != sd.Id || plan.Name != "plan" {
+		t.Errorf("Expected plan %q of service %q, got %q of %q", "plan", sd.Id, plan.Name, svc.Id)
+	}
+
+	_, _, err = registry.GetPlanById("missing")
+	if _, ok := err.(*PlanNotFoundError); !ok || err.Error() != `Plan ID "missing" could not be found` {
+		t.Errorf("Expected unknown plan error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/pivotal-cf/brokerapi"
+)
+
+const (
+	// ResourcePrefixParameter is the user parameter holding a prefix for the
+	// names of the resources created for an instance.
+	ResourcePrefixParameter = "resource_prefix"
+
+	// maxResourceNameLength is the most restrictive name length limit of the
+	// supported clouds (e.g. GCP resource names and DNS labels).
+	maxResourceNameLength = 63
+)
+
+// resourcePrefixRegex matches names that are valid on all supported clouds:
+// lowercase letters, digits and hyphens, starting with a letter and not ending
+// with a hyphen.
+var resourcePrefixRegex = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+
+func errInvalidResourcePrefix(format string, a ...interface{}) error {
+	return brokerapi.NewFailureResponse(fmt.Errorf(format, a...), http.StatusBadRequest, "invalid-resource-prefix")
+}
+
+// ResourcePrefix extracts the resource prefix from the raw request parameters.
+// An empty string is returned if no prefix was supplied.
+func ResourcePrefix(rawParameters json.RawMessage) (string, error) {
+	if len(rawParameters) == 0 {
+		return "", nil
+	}
+
+	params := map[string]interface{}{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return "", err
+	}
+
+	value, ok := params[ResourcePrefixParameter]
+	if !ok || value == nil {
+		return "", nil
+	}
+
+	prefix, ok := value.(string)
+	if !ok {
+		return "", errInvalidResourcePrefix("%s must be a string", ResourcePrefixParameter)
+	}
+
+	return prefix, nil
+}
+
+// ValidateResourcePrefix checks the prefix against cloud naming rules and
+// ensures names of the form <prefix>-<instance id> fit in the length limits.
+func ValidateResourcePrefix(prefix, instanceID string) error {
+	if prefix == "" {
+		return nil
+	}
+
+	if !resourcePrefixRegex.MatchString(prefix) {
+		return errInvalidResourcePrefix("%s %q must start with a lowercase letter, contain only lowercase letters, digits and hyphens, and not end with a hyphen", ResourcePrefixParameter, prefix)
+	}
+
+	if maxLength := maxResourceNameLength - len(instanceID) - 1; len(prefix) > maxLength {
+		return errInvalidResourcePrefix("%s %q is too long, it may be at most %d characters", ResourcePrefixParameter, prefix, maxLength)
+	}
+
+	return nil
+}
+
+// resourcePrefixVariables returns the variables to merge into a request
+// context so templates see the prefix of the instance, if it has one.
+func resourcePrefixVariables(prefix interface{}) map[string]interface{} {
+	if prefix == nil || prefix == "" {
+		return nil
+	}
+
+	return map[string]interface{}{ResourcePrefixParameter: prefix}
+}
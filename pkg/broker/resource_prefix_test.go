@@ -0,0 +1,124 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
+)
+
+const testInstanceID = "00000000-0000-0000-0000-000000000000"
+
+func TestValidateResourcePrefix(t *testing.T) {
+	cases := map[string]struct {
+		Prefix        string
+		ExpectedError error
+	}{
+		"empty": {
+			Prefix: "",
+		},
+		"valid": {
+			Prefix: "tenant-a1",
+		},
+		"longest allowed": {
+			Prefix: strings.Repeat("a", 26),
+		},
+		"too long": {
+			Prefix:        strings.Repeat("a", 27),
+			ExpectedError: errors.New(`resource_prefix "aaaaaaaaaaaaaaaaaaaaaaaaaaa" is too long, it may be at most 26 characters`),
+		},
+		"uppercase": {
+			Prefix:        "Tenant",
+			ExpectedError: errors.New(`resource_prefix "Tenant" must start with a lowercase letter, contain only lowercase letters, digits and hyphens, and not end with a hyphen`),
+		},
+		"leading digit": {
+			Prefix:        "1tenant",
+			ExpectedError: errors.New(`resource_prefix "1tenant" must start with a lowercase letter, contain only lowercase letters, digits and hyphens, and not end with a hyphen`),
+		},
+		"trailing hyphen": {
+			Prefix:        "tenant-",
+			ExpectedError: errors.New(`resource_prefix "tenant-" must start with a lowercase letter, contain only lowercase letters, digits and hyphens, and not end with a hyphen`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			expectError(t, tc.ExpectedError, ValidateResourcePrefix(tc.Prefix, testInstanceID))
+		})
+	}
+}
+
+func TestServiceDefinition_ResourcePrefix(t *testing.T) {
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionComputedVariables: []varcontext.DefaultVariable{
+			{Name: "name", Default: "${request.resource_prefix}db", Overwrite: true},
+		},
+		BindComputedVariables: []varcontext.DefaultVariable{
+			{Name: "user", Default: "${instance.resource_prefix}user", Overwrite: true},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}
+
+	t.Run("provision", func(t *testing.T) {
+		details := brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"resource_prefix":"tenant-"}`)}
+		if _, err := service.ProvisionVariables(testInstanceID, details, plan, nil); err == nil {
+			t.Fatal("expected invalid prefix to be rejected")
+		}
+
+		details = brokerapi.ProvisionDetails{RawParameters: json.RawMessage(`{"resource_prefix":"tenant"}`)}
+		vars, err := service.ProvisionVariables(testInstanceID, details, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"resource_prefix": "tenant", "name": "tenantdb"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID, ResourcePrefix: "tenant"}
+		vars, err := service.UpdateVariables(instance, brokerapi.UpdateDetails{}, plan, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"resource_prefix": "tenant", "name": "tenantdb"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID, ResourcePrefix: "tenant"}
+		details := brokerapi.BindDetails{RawParameters: json.RawMessage(`{"resource_prefix":"other"}`)}
+		vars, err := service.BindVariables(instance, "binding-id", details, &plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]interface{}{"resource_prefix": "tenant", "user": "tenantuser"}
+		if !reflect.DeepEqual(vars.ToMap(), expected) {
+			t.Errorf("Expected context: %v got %v", expected, vars.ToMap())
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// ResponseHeaders collects the headers providers set on the response of an
+// OSB request.
+type ResponseHeaders struct {
+	mu     sync.Mutex
+	header http.Header
+	sent   bool
+}
+
+// NewResponseHeaders creates an empty collection of response headers.
+func NewResponseHeaders() *ResponseHeaders {
+	return &ResponseHeaders{header: make(http.Header)}
+}
+
+// Set sets a header. Headers set after the response was sent are ignored.
+func (h *ResponseHeaders) Set(key, value string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.sent {
+		h.header.Set(key, value)
+	}
+}
+
+// ApplyTo copies the collected headers to dst, skipping those dst already
+// has, and marks the response as sent.
+func (h *ResponseHeaders) ApplyTo(dst http.Header) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sent = true
+	for key, values := range h.header {
+		if dst.Get(key) == "" {
+			dst[key] = values
+		}
+	}
+}
+
+type responseHeadersKey struct{}
+
+// WithResponseHeaders returns a copy of the context collecting the response
+// headers of the request in headers.
+func WithResponseHeaders(ctx context.Context, headers *ResponseHeaders) context.Context {
+	return context.WithValue(ctx, responseHeadersKey{}, headers)
+}
+
+// SetResponseHeader sets an operation specific header, like Retry-After on an
+// asynchronous operation, on the response of the request the context belongs
+// to. Headers set by the broker API itself take precedence. It does nothing if
+// the context doesn't belong to a request.
+func SetResponseHeader(ctx context.Context, key, value string) {
+	if headers, ok := ctx.Value(responseHeadersKey{}).(*ResponseHeaders); ok {
+		headers.Set(key, value)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+// RetryableBindError is returned by a ServiceProvider's Bind when the binding
+// failed for a reason expected to go away on its own, e.g. IAM changes that
+// haven't propagated yet. The broker calls Bind again with the same variables
+// after a backoff, so before returning it the provider must remove whatever
+// the failed attempt created, or make Bind idempotent, to avoid duplicate
+// resources.
+type RetryableBindError struct {
+	// Err is the cause of the failure, returned to the platform if the
+	// broker gives up.
+	Err error
+}
+
+func (e *RetryableBindError) Error() string {
+	return e.Err.Error()
+}
+
+// IsRetryableBind returns true if a provider's Bind failed transiently and
+// can be retried.
+func IsRetryableBind(err error) bool {
+	_, ok := err.(*RetryableBindError)
+	return ok
+}
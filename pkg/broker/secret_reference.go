@@ -0,0 +1,101 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/spf13/viper"
+)
+
+const secretReferencePrefixProp = "credhub.secret_reference_prefix"
+
+func init() {
+	viper.BindEnv(secretReferencePrefixProp, "CH_SECRET_REFERENCE_PREFIX")
+}
+
+// secretReferenceRegex matches references like {{credhub-ref:/path/to/secret}}
+// in string parameters.
+var secretReferenceRegex = regexp.MustCompile(`\{\{\s*credhub-ref:([^{}\s]+)\s*\}\}`)
+
+// SecretResolver reads the value of a secret stored in the credstore.
+type SecretResolver interface {
+	GetValue(key string) (string, error)
+}
+
+// ResolveSecretReferences replaces the secret references in the string values
+// of the raw JSON parameters with the values of the secrets, so users don't
+// have to pass secrets inline. Only secrets under the operator configured
+// credhub.secret_reference_prefix can be referenced. Invalid references are
+// rejected with a 400 error.
+func ResolveSecretReferences(rawParameters json.RawMessage, resolver SecretResolver) (json.RawMessage, error) {
+	if len(rawParameters) == 0 || !secretReferenceRegex.Match(rawParameters) {
+		return rawParameters, nil
+	}
+
+	var params interface{}
+	if err := json.Unmarshal(rawParameters, &params); err != nil {
+		return nil, err
+	}
+
+	resolved, err := renderStrings(params, func(value string) (string, error) {
+		return resolveSecretReferences(value, resolver)
+	})
+	if err != nil {
+		return nil, brokerapi.NewFailureResponse(err, http.StatusBadRequest, "invalid-secret-reference")
+	}
+
+	return json.Marshal(resolved)
+}
+
+func resolveSecretReferences(value string, resolver SecretResolver) (string, error) {
+	var resolveErr error
+	resolved := secretReferenceRegex.ReplaceAllStringFunc(value, func(reference string) string {
+		path := secretReferenceRegex.FindStringSubmatch(reference)[1]
+		if resolveErr != nil {
+			return ""
+		}
+
+		if resolveErr = checkSecretReferencePath(path, resolver); resolveErr != nil {
+			return ""
+		}
+
+		secret, err := resolver.GetValue(path)
+		if err != nil {
+			resolveErr = fmt.Errorf("couldn't read secret %q: %v", path, err)
+		}
+		return secret
+	})
+
+	return resolved, resolveErr
+}
+
+func checkSecretReferencePath(path string, resolver SecretResolver) error {
+	prefix := strings.TrimSuffix(viper.GetString(secretReferencePrefixProp), "/")
+	switch {
+	case resolver == nil || prefix == "":
+		return errors.New("secret references aren't enabled on this broker")
+	case !strings.HasPrefix(path, prefix+"/") || strings.Contains(path, ".."):
+		return fmt.Errorf("secret %q can't be referenced, secrets must be under %s/", path, prefix)
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/spf13/viper"
+)
+
+type fakeSecretResolver map[string]string
+
+func (f fakeSecretResolver) GetValue(key string) (string, error) {
+	value, ok := f[key]
+	if !ok {
+		return "", fmt.Errorf("credential %s not found", key)
+	}
+	return value, nil
+}
+
+func TestResolveSecretReferences(t *testing.T) {
+	secrets := fakeSecretResolver{
+		"/shared/db-password": "s3cret",
+		"/shared/nested":      "{{credhub-ref:/shared/db-password}}",
+		"/private/admin":      "root",
+	}
+
+	cases := map[string]struct {
+		Raw           string
+		Prefix        string
+		Resolver      SecretResolver
+		Expected      string
+		ExpectedError error
+	}{
+		"no references": {
+			Raw:      `{"password":"plain"}`,
+			Expected: `{"password":"plain"}`,
+		},
+		"nested values": {
+			Raw:      `{"password":"{{credhub-ref:/shared/db-password}}","users":[{"password":"x-{{ credhub-ref:/shared/db-password }}"}]}`,
+			Prefix:   "/shared/",
+			Resolver: secrets,
+			Expected: `{"password":"s3cret","users":[{"password":"x-s3cret"}]}`,
+		},
+		"secrets aren't resolved again": {
+			Raw:      `{"password":"{{credhub-ref:/shared/nested}}"}`,
+			Prefix:   "/shared",
+			Resolver: secrets,
+			Expected: `{"password":"{{credhub-ref:/shared/db-password}}"}`,
+		},
+		"disabled without prefix": {
+			Raw:           `{"password":"{{credhub-ref:/shared/db-password}}"}`,
+			Resolver:      secrets,
+			ExpectedError: errors.New("password: secret references aren't enabled on this broker"),
+		},
+		"disabled without credstore": {
+			Raw:           `{"password":"{{credhub-ref:/shared/db-password}}"}`,
+			Prefix:        "/shared",
+			ExpectedError: errors.New("password: secret references aren't enabled on this broker"),
+		},
+		"outside of prefix": {
+			Raw:           `{"password":"{{credhub-ref:/private/admin}}"}`,
+			Prefix:        "/shared",
+			Resolver:      secrets,
+			ExpectedError: errors.New(`password: secret "/private/admin" can't be referenced, secrets must be under /shared/`),
+		},
+		"escaping prefix": {
+			Raw:           `{"password":"{{credhub-ref:/shared/../private/admin}}"}`,
+			Prefix:        "/shared",
+			Resolver:      secrets,
+			ExpectedError: errors.New(`password: secret "/shared/../private/admin" can't be referenced, secrets must be under /shared/`),
+		},
+		"missing secret": {
+			Raw:           `{"password":"{{credhub-ref:/shared/missing}}"}`,
+			Prefix:        "/shared",
+			Resolver:      secrets,
+			ExpectedError: errors.New(`password: couldn't read secret "/shared/missing": credential /shared/missing not found`),
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			viper.Set(secretReferencePrefixProp, tc.Prefix)
+			defer viper.Reset()
+
+			actual, err := ResolveSecretReferences(json.RawMessage(tc.Raw), tc.Resolver)
+			expectError(t, tc.ExpectedError, err)
+			if err != nil {
+				if status := err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil); status != 400 {
+					t.Errorf("expected status 400, got %d", status)
+				}
+			}
+			if string(actual) != tc.Expected {
+				t.Errorf("expected parameters %s, got %s", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestServiceDefinition_SecretReferences(t *testing.T) {
+	viper.Set(secretReferencePrefixProp, "/shared")
+	defer viper.Reset()
+
+	service := ServiceDefinition{
+		Id:   "00000000-0000-0000-0000-000000000000",
+		Name: "left-handed-smoke-sifter",
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "password", Type: JsonTypeString},
+		},
+	}
+	plan := ServicePlan{ServicePlan: brokerapi.ServicePlan{ID: "builtin-plan", Name: "Builtin!"}}
+
+	raw := json.RawMessage(`{"password":"{{credhub-ref:/shared/db-password}}"}`)
+	details := brokerapi.ProvisionDetails{RawParameters: raw}
+
+	vars, err := service.ProvisionVariables(testInstanceID, details, plan, fakeSecretResolver{"/shared/db-password": "s3cret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := vars.GetString("password"); actual != "s3cret" {
+		t.Errorf("expected the resolved password, got %q", actual)
+	}
+	if string(details.RawParameters) != string(raw) {
+		t.Errorf("expected the request parameters to keep the reference, got %s", details.RawParameters)
+	}
+
+	t.Run("update", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID}
+		details := brokerapi.UpdateDetails{RawParameters: raw}
+
+		vars, err := service.UpdateVariables(instance, details, plan, fakeSecretResolver{"/shared/db-password": "s3cret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := vars.GetString("password"); actual != "s3cret" {
+			t.Errorf("expected the resolved password, got %q", actual)
+		}
+		if string(details.RawParameters) != string(raw) {
+			t.Errorf("expected the request parameters to keep the reference, got %s", details.RawParameters)
+		}
+	})
+
+	t.Run("update-without-resolver", func(t *testing.T) {
+		instance := models.ServiceInstanceDetails{ID: testInstanceID}
+		details := brokerapi.UpdateDetails{RawParameters: raw}
+
+		_, err := service.UpdateVariables(instance, details, plan, nil)
+		if err == nil || err.(*brokerapi.FailureResponse).ValidatedStatusCode(nil) != 400 {
+			t.Errorf("expected a 400 error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"fmt"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+// MaskedOutputValue replaces the values of sensitive outputs wherever the
+// outputs of an instance are surfaced.
+const MaskedOutputValue = "[REDACTED]"
+
+// SensitiveOutputs returns the names of the outputs marked sensitive, with
+// sensitive or an x-sensitive constraint, whose values must only be passed
+// to applications through bindings.
+func (svc *ServiceDefinition) SensitiveOutputs() []string {
+	var names []string
+	for _, variable := range svc.BindOutputVariables {
+		if variable.IsSensitive() {
+			names = append(names, variable.FieldName)
+		}
+	}
+
+	return names
+}
+
+// MaskSensitiveOutputs returns a copy of the outputs of an instance with the
+// values of sensitive outputs replaced by MaskedOutputValue.
+func (svc *ServiceDefinition) MaskSensitiveOutputs(outputs map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(outputs))
+	for name, value := range outputs {
+		masked[name] = value
+	}
+
+	for _, name := range svc.SensitiveOutputs() {
+		if _, ok := masked[name]; ok {
+			masked[name] = MaskedOutputValue
+		}
+	}
+
+	return masked
+}
+
+// validateDashboardOutputs rejects dashboard URL templates referencing
+// sensitive outputs, which would expose them to every user of the platform.
+func (svc *ServiceDefinition) validateDashboardOutputs(template, field string) *validation.FieldError {
+	if template == "" {
+		return nil
+	}
+
+	sensitive := make(map[string]bool)
+	for _, name := range svc.SensitiveOutputs() {
+		sensitive[DashboardOutputPrefix+name] = true
+	}
+
+	var errs *validation.FieldError
+	for _, match := range dashboardTemplateRegex.FindAllStringSubmatch(template, -1) {
+		if sensitive[match[1]] {
+			errs = errs.Also(&validation.FieldError{
+				Message: fmt.Sprintf("invalid dashboard URL template: %q is a sensitive output", match[1]),
+				Paths:   []string{field},
+			})
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/pkg/validation"
+)
+
+func sensitiveOutputsService() ServiceDefinition {
+	return ServiceDefinition{
+		BindOutputVariables: []BrokerVariable{
+			{FieldName: "admin_password", Type: JsonTypeString, Details: "The admin password.", Sensitive: true},
+			{FieldName: "token", Type: JsonTypeString, Details: "A token.", Constraints: map[string]interface{}{validation.KeySensitive: true}},
+			{FieldName: "host", Type: JsonTypeString, Details: "The host."},
+		},
+	}
+}
+
+func TestServiceDefinition_SensitiveOutputs(t *testing.T) {
+	service := sensitiveOutputsService()
+
+	expected := []string{"admin_password", "token"}
+	if actual := service.SensitiveOutputs(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+}
+
+func TestServiceDefinition_MaskSensitiveOutputs(t *testing.T) {
+	service := sensitiveOutputsService()
+	outputs := map[string]interface{}{"admin_password": "s3cret", "host": "db.example.com", "port": 5432}
+
+	expected := map[string]interface{}{"admin_password": MaskedOutputValue, "host": "db.example.com", "port": 5432}
+	if actual := service.MaskSensitiveOutputs(outputs); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, got %v", expected, actual)
+	}
+
+	if outputs["admin_password"] != "s3cret" {
+		t.Error("Expected the outputs not to be modified")
+	}
+}
+
+func TestServiceDefinition_validateDashboardOutputs(t *testing.T) {
+	service := sensitiveOutputsService()
+
+	cases := map[string]struct {
+		Template string
+		Expected string
+	}{
+		"no template":      {Template: "", Expected: ""},
+		"public output":    {Template: "https://{{output.host}}/console", Expected: ""},
+		"sensitive output": {Template: "https://{{output.host}}/?token={{ output.token }}", Expected: `"output.token" is a sensitive output`},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			err := service.validateDashboardOutputs(tc.Template, "dashboard_url_template")
+			switch {
+			case tc.Expected == "" && err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			case tc.Expected != "" && (err == nil || !strings.Contains(err.Error(), tc.Expected)):
+				t.Errorf("Expected error containing %q, got %v", tc.Expected, err)
+			}
+		})
+	}
+}
@@ -30,7 +30,11 @@ import (
 	"github.com/spf13/viper"
 )
 
-var enableCatalogSchemas = toggles.Features.Toggle("enable-catalog-schemas", false, `Enable generating JSONSchema for the service catalog.`)
+var enableCatalogSchemas = toggles.Features.Toggle("enable-catalog-schemas", false, `Enable generating JSONSchema for the service catalog, including the credentials of bindings.`)
+
+// BindingCredentialsSchemaKey is the service metadata key holding the
+// JSONSchema of the credentials of its bindings.
+const BindingCredentialsSchemaKey = "bindingCredentialsSchema"
 
 // GlobalProvisionDefaults viper key for global provision defaults
 const GlobalProvisionDefaults = "provision.defaults"
@@ -50,6 +54,10 @@ type ServiceDefinition struct {
 	PlanUpdateable   bool
 	Plans            []ServicePlan
 
+	// DisplayOrder positions the service in the catalog when it's sorted by
+	// display order, 0 means unset.
+	DisplayOrder int
+
 	ProvisionInputVariables    []BrokerVariable
 	ProvisionComputedVariables []varcontext.DefaultVariable
 	BindInputVariables         []BrokerVariable
@@ -59,9 +67,27 @@ type ServiceDefinition struct {
 	Examples                   []ServiceExample
 	DefaultRoleWhitelist       []string
 
+	// DashboardUrlTemplate is the template of the dashboard URL returned for
+	// instances of plans that don't have their own, see
+	// DashboardTemplateVariables for the variables it can reference.
+	DashboardUrlTemplate string
+
+	// Requires holds the permissions the service needs from the platform, e.g.
+	// syslog_drain if its bindings return a syslog_drain_url.
+	Requires []brokerapi.RequiredPermission
+
+	// InstanceNamer derives the names of the resources of new instances from
+	// their ID. Without one, the instance ID is used.
+	InstanceNamer InstanceNamer
+
 	// ProviderBuilder creates a new provider given the project, auth, and logger.
 	ProviderBuilder func(plogger lager.Logger) ServiceProvider
 
+	// AccountProviderBuilder creates a new provider that runs with the
+	// environment variables of a provider account instead of the broker's,
+	// see AccountProvider. Services without one don't support accounts.
+	AccountProviderBuilder func(plogger lager.Logger, env map[string]string) ServiceProvider
+
 	// IsBuiltin is true if the service is built-in to the platform.
 	IsBuiltin bool
 }
@@ -111,6 +137,13 @@ func (sd *ServiceDefinition) Validate() (errs *validation.FieldError) {
 		errs = errs.Also(v.Validate().ViaFieldIndex("PlanVariables", i))
 	}
 
+	errs = errs.Also(ValidateDashboardUrlTemplate(sd.DashboardUrlTemplate, nil, "DashboardUrlTemplate"))
+	errs = errs.Also(sd.validateDashboardOutputs(sd.DashboardUrlTemplate, "DashboardUrlTemplate"))
+	for i, plan := range sd.Plans {
+		errs = errs.Also(ValidateDashboardUrlTemplate(plan.DashboardUrlTemplate, &plan, "DashboardUrlTemplate").ViaFieldIndex("Plans", i))
+		errs = errs.Also(sd.validateDashboardOutputs(plan.DashboardUrlTemplate, "DashboardUrlTemplate").ViaFieldIndex("Plans", i))
+	}
+
 	return errs
 }
 
@@ -154,6 +187,19 @@ func (svc *ServiceDefinition) BindDefaultOverrides() map[string]interface{} {
 	return viper.GetStringMap(svc.BindDefaultOverrideProperty())
 }
 
+// RefreshBindingsOnUpdateProperty returns the Viper property name of the
+// flag operators set to refresh the credentials of the service's bindings
+// after the instance was updated.
+func (svc *ServiceDefinition) RefreshBindingsOnUpdateProperty() string {
+	return fmt.Sprintf("service.%s.bind.refresh_on_update", svc.Name)
+}
+
+// RefreshBindingsOnUpdate returns true if the operator opted in to refreshing
+// the credentials of the service's bindings after updates.
+func (svc *ServiceDefinition) RefreshBindingsOnUpdate() bool {
+	return viper.GetBool(svc.RefreshBindingsOnUpdateProperty())
+}
+
 // TileUserDefinedPlansVariable returns the name of the user defined plans
 // variable for the broker tile.
 func (svc *ServiceDefinition) TileUserDefinedPlansVariable() string {
@@ -186,24 +232,46 @@ func (svc *ServiceDefinition) CatalogEntry() (*Service, error) {
 				SupportUrl:       svc.SupportUrl,
 			},
 			Tags:          svc.Tags,
+			Requires:      svc.Requires,
 			Bindable:      svc.Bindable,
 			PlanUpdatable: svc.PlanUpdateable,
+
+			BindingsRetrievable: svc.Bindable,
 		},
-		Plans: append(svc.Plans, userPlans...),
+		Plans:        append(append([]ServicePlan{}, svc.Plans...), userPlans...),
+		DisplayOrder: svc.DisplayOrder,
 	}
 
 	if enableCatalogSchemas.IsActive() {
 		for i, _ := range sd.Plans {
-			sd.Plans[i].Schemas = svc.createSchemas()
+			sd.Plans[i].Schemas = svc.createSchemas(sd.Plans[i])
+		}
+
+		if credentials := svc.bindCredentialsSchema(); credentials != nil {
+			if sd.Metadata.AdditionalMetadata == nil {
+				sd.Metadata.AdditionalMetadata = map[string]interface{}{}
+			}
+			sd.Metadata.AdditionalMetadata[BindingCredentialsSchemaKey] = credentials
 		}
 	}
 
 	return sd, nil
 }
 
+// RequiresPermission returns true if the service declares it needs the given
+// permission from the platform.
+func (svc *ServiceDefinition) RequiresPermission(permission brokerapi.RequiredPermission) bool {
+	for _, p := range svc.Requires {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
 // createSchemas creates JSONSchemas compatible with the OSB spec for provision and bind.
 // It leaves the instance update schema empty to indicate updates are not supported.
-func (svc *ServiceDefinition) createSchemas() *brokerapi.ServiceSchemas {
+func (svc *ServiceDefinition) createSchemas(plan ServicePlan) *brokerapi.ServiceSchemas {
 	return &brokerapi.ServiceSchemas{
 		Instance: brokerapi.ServiceInstanceSchema{
 			Create: brokerapi.Schema{
@@ -212,12 +280,66 @@ func (svc *ServiceDefinition) createSchemas() *brokerapi.ServiceSchemas {
 		},
 		Binding: brokerapi.ServiceBindingSchema{
 			Create: brokerapi.Schema{
-				Parameters: CreateJsonSchema(svc.BindInputVariables),
+				Parameters: svc.bindParametersSchema(plan),
 			},
 		},
 	}
 }
 
+// bindParametersSchema creates the JSONSchema of the bind parameters of the
+// plan. It matches what BindVariables enforces: the service's bind inputs,
+// with the role restricted to the plan's roles if it declares any.
+func (svc *ServiceDefinition) bindParametersSchema(plan ServicePlan) map[string]interface{} {
+	schema := CreateJsonSchema(svc.BindInputVariables)
+	if len(plan.Roles) == 0 {
+		return schema
+	}
+
+	properties := schema["properties"].(map[string]interface{})
+	role := map[string]interface{}{"type": JsonTypeString}
+	if existing, ok := properties[BindRoleParameter].(map[string]interface{}); ok {
+		role = make(map[string]interface{})
+		for k, v := range existing {
+			role[k] = v
+		}
+	}
+
+	var roles []interface{}
+	for _, r := range plan.Roles {
+		roles = append(roles, r)
+	}
+	role[validation.KeyEnum] = roles
+	properties[BindRoleParameter] = role
+
+	return schema
+}
+
+// bindCredentialsSchema creates the JSONSchema of the credentials returned to
+// applications, or nil if the service doesn't declare its bind outputs.
+// Outputs returned to the platform rather than the application are excluded.
+func (svc *ServiceDefinition) bindCredentialsSchema() map[string]interface{} {
+	// Invalid mappings fail binds, so the schema of the outputs is kept as-is.
+	mapping, _ := svc.CredentialKeyMapping()
+
+	var outputs []BrokerVariable
+	for _, output := range svc.BindOutputVariables {
+		if output.FieldName == SyslogDrainURLOutput || output.FieldName == RouteServiceURLOutput {
+			continue
+		}
+
+		if to, ok := mapping[output.FieldName]; ok {
+			output.FieldName = to
+		}
+		outputs = append(outputs, output)
+	}
+
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	return CreateJsonSchema(outputs)
+}
+
 // GetPlanById finds a plan in this service by its UUID.
 func (svc *ServiceDefinition) GetPlanById(planId string) (*ServicePlan, error) {
 	catalogEntry, err := svc.CatalogEntry()
@@ -231,7 +353,7 @@ func (svc *ServiceDefinition) GetPlanById(planId string) (*ServicePlan, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("Plan ID %q could not be found", planId)
+	return nil, &PlanNotFoundError{ID: planId}
 }
 
 // UserDefinedPlans extracts user defined plans from the environment, failing if
@@ -337,6 +459,21 @@ func (svc *ServiceDefinition) bindDefaults() []varcontext.DefaultVariable {
 	return out
 }
 
+// SensitiveParameters returns the names of the provision and bind parameters
+// marked sensitive, whose values must not be logged.
+func (svc *ServiceDefinition) SensitiveParameters() []string {
+	var names []string
+	for _, variables := range [][]BrokerVariable{svc.ProvisionInputVariables, svc.BindInputVariables} {
+		for _, variable := range variables {
+			if variable.IsSensitive() {
+				names = append(names, variable.FieldName)
+			}
+		}
+	}
+
+	return names
+}
+
 // ProvisionVariables gets the variable resolution context for a provision request.
 // Variables have a very specific resolution order, and this function populates the context to preserve that.
 // The variable resolution order is the following:
@@ -344,10 +481,11 @@ func (svc *ServiceDefinition) bindDefaults() []varcontext.DefaultVariable {
 // 1. Variables defined in your `computed_variables` JSON list.
 // 2. Variables defined by the selected service plan in its `service_properties` map.
 // 3. Variables overridden in the plan's `provision_overrides` map.
-// 4. User defined variables (in `provision_input_variables` or `bind_input_variables`)
-// 5. Operator default variables loaded from the environment.
-// 6. Global operator default variables loaded from the environemnt.
-// 7. Default variables (in `provision_input_variables` or `bind_input_variables`).
+// 4. Operator forced variables of the plan loaded from the environment.
+// 5. User defined variables (in `provision_input_variables` or `bind_input_variables`)
+// 6. Operator default variables loaded from the environment.
+// 7. Global operator default variables loaded from the environemnt.
+// 8. Default variables (in `provision_input_variables` or `bind_input_variables`).
 //
 // Loading into the map occurs slightly differently.
 // Default variables and computed_variables get executed by interpolation.
@@ -367,9 +505,13 @@ func (svc *ServiceDefinition) variables(constants map[string]interface{}, rawPar
 
 	builder := varcontext.Builder().
 		SetEvalConstants(constants).
-		MergeMap(ProvisionGlobalDefaults()).          // 6
-		MergeMap(svc.ProvisionDefaultOverrides()).    // 5
-		MergeJsonObject(rawParameters).               // 4
+		MergeMap(ProvisionGlobalDefaults()).          // 7
+		MergeMap(svc.ProvisionDefaultOverrides()).    // 6
+		MergeMap(resourcePrefixVariables(constants["request.resource_prefix"])).
+		MergeMap(networkVariables(constants["request.network"], constants["request.subnet"])).
+		MergeMap(availabilityZonesVariables(constants["request.availability_zones"])).
+		MergeJsonObject(rawParameters).               // 5
+		MergeMap(svc.ForcedParameters(plan)).         // 4
 		MergeMap(plan.ProvisionOverrides).            // 3
 		MergeDefaults(svc.provisionDefaults()).       // ?
 		MergeMap(plan.GetServiceProperties()).        // 2
@@ -378,25 +520,148 @@ func (svc *ServiceDefinition) variables(constants map[string]interface{}, rawPar
 	return buildAndValidate(builder, svc.ProvisionInputVariables)
 }
 
-func (svc *ServiceDefinition) ProvisionVariables(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan) (*varcontext.VarContext, error) {
+// ProvisionVariables gets the variable resolution context for a provision
+// request. Placeholders for instance metadata in the user supplied parameters,
+// see ProvisionTemplateVariables, are rendered before they are validated.
+// Secret references are resolved with secrets, see ResolveSecretReferences,
+// after the resource prefix and network were read, so neither is ever taken
+// from a secret.
+func (svc *ServiceDefinition) ProvisionVariables(instanceId string, details brokerapi.ProvisionDetails, plan ServicePlan, secrets SecretResolver) (*varcontext.VarContext, error) {
+	rendered, err := RenderParameterTemplates(details.GetRawParameters(), ProvisionTemplateVariables(instanceId, details))
+	if err != nil {
+		return nil, err
+	}
+	details.RawParameters = rendered
+
+	resourcePrefix, err := ResourcePrefix(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateResourcePrefix(resourcePrefix, instanceId); err != nil {
+		return nil, err
+	}
+
+	network, err := ResolveNetwork(details.GetRawParameters(), plan)
+	if err != nil {
+		return nil, err
+	}
+
+	zones, err := ResolveAvailabilityZones(details.GetRawParameters(), plan)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := InstanceMetadata(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := DeletionProtection(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
+	if _, err := CompletionCallback(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
 	// The namespaces of these values roughly align with the OSB spec.
 	constants := map[string]interface{}{
-		"request.plan_id":        details.PlanID,
-		"request.service_id":     details.ServiceID,
-		"request.instance_id":    instanceId,
-		"request.default_labels": utils.ExtractDefaultProvisionLabels(instanceId, details),
+		"request.plan_id":            details.PlanID,
+		"request.service_id":         details.ServiceID,
+		"request.instance_id":        instanceId,
+		"request.resource_name":      svc.ResourceName(instanceId),
+		"request.default_labels":     utils.ExtractDefaultProvisionLabels(instanceId, details),
+		"request.resource_prefix":    resourcePrefix,
+		"request.network":            network.Network,
+		"request.subnet":             network.Subnet,
+		"request.availability_zones": availabilityZonesVariable(zones),
+		"request.instance_metadata":  metadataVariable(metadata),
+	}
+
+	params, err := withoutBrokerParameters(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	params, err = ResolveSecretReferences(params, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err = svc.withGeneratedParameters(params, nil)
+	if err != nil {
+		return nil, err
 	}
-	return svc.variables(constants, details.GetRawParameters(), plan)
+
+	return svc.variables(constants, params, plan)
 }
 
-func (svc *ServiceDefinition) 	UpdateVariables(instanceId string, details brokerapi.UpdateDetails, plan ServicePlan) (*varcontext.VarContext, error) {
+// UpdateVariables gets the variable resolution context for an update request.
+// The resource prefix and network the instance was provisioned with are kept
+// so the existing resources aren't renamed or moved.
+func (svc *ServiceDefinition) UpdateVariables(instance models.ServiceInstanceDetails, details brokerapi.UpdateDetails, plan ServicePlan, secrets SecretResolver) (*varcontext.VarContext, error) {
+	previousMetadata, err := instance.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := InstanceMetadata(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		metadata = previousMetadata
+	}
+
+	if _, err := DeletionProtection(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
+	if _, err := CompletionCallback(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
+	zones, err := instance.GetAvailabilityZones()
+	if err != nil {
+		return nil, err
+	}
+
 	constants := map[string]interface{}{
-		"request.plan_id":        details.PlanID,
-		"request.service_id":     details.ServiceID,
-		"request.instance_id":    instanceId,
-		"request.default_labels": utils.ExtractDefaultUpdateLabels(instanceId, details),
+		"request.plan_id":            details.PlanID,
+		"request.service_id":         details.ServiceID,
+		"request.instance_id":        instance.ID,
+		"request.resource_name":      instance.GetResourceName(),
+		"request.default_labels":     utils.ExtractDefaultUpdateLabels(instance.ID, details),
+		"request.resource_prefix":    instance.ResourcePrefix,
+		"request.network":            instance.Network,
+		"request.subnet":             instance.Subnet,
+		"request.availability_zones": availabilityZonesVariable(zones),
+		"request.instance_metadata":  metadataVariable(metadata),
+		// tags dropped by the update, see RemovedInstanceMetadata
+		"request.removed_instance_metadata": metadataKeysVariable(RemovedInstanceMetadata(previousMetadata, metadata)),
+	}
+
+	params, err := withoutBrokerParameters(details.GetRawParameters())
+	if err != nil {
+		return nil, err
 	}
-	return svc.variables(constants, details.GetRawParameters(), plan)
+
+	params, err = ResolveSecretReferences(params, secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := instance.GetGeneratedParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	params, err = svc.withGeneratedParameters(params, generated)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.variables(constants, params, plan)
 }
 
 // BindVariables gets the variable resolution context for a bind request.
@@ -409,17 +674,67 @@ func (svc *ServiceDefinition) 	UpdateVariables(instanceId string, details broker
 // 4. Operator default variables loaded from the environment.
 // 5. Default variables (in `bind_input_variables`).
 //
+// Literal defaults, including those of object properties, are applied to the
+// user defined variables first, see BindParameters. Validation failures are
+// returned as 400 failure responses.
 func (svc *ServiceDefinition) BindVariables(instance models.ServiceInstanceDetails, bindingID string, details brokerapi.BindDetails, plan *ServicePlan) (*varcontext.VarContext, error) {
 	otherDetails := make(map[string]interface{})
 	if err := instance.GetOtherDetails(&otherDetails); err != nil {
 		return nil, err
 	}
 
+	instanceMetadata, err := instance.GetMetadata()
+	if err != nil {
+		return nil, err
+	}
+
+	generated, err := instance.GetGeneratedParameters()
+	if err != nil {
+		return nil, err
+	}
+
+	zones, err := instance.GetAvailabilityZones()
+	if err != nil {
+		return nil, err
+	}
+
 	appGuid := ""
 	if details.BindResource != nil {
 		appGuid = details.BindResource.AppGuid
 	}
 
+	if _, err := CredentialFormat(details.GetRawParameters()); err != nil {
+		return nil, err
+	}
+
+	bindingName, err := svc.BindingName(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := withoutCredentialFormat(details.GetRawParameters())
+	if err != nil {
+		return nil, err
+	}
+
+	if params, err = svc.withoutBindingName(params); err != nil {
+		return nil, err
+	}
+
+	params, err = svc.BindParameters(params)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := BindRole(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := plan.ValidateRole(role); err != nil {
+		return nil, err
+	}
+
 	// The namespaces of these values roughly align with the OSB spec.
 	constants := map[string]interface{}{
 		// specified in the URL
@@ -434,21 +749,42 @@ func (svc *ServiceDefinition) BindVariables(instance models.ServiceInstanceDetai
 		"request.service_id":      instance.ServiceId,
 		"request.app_guid":        appGuid,
 		"request.plan_properties": plan.GetServiceProperties(),
+		"request.role":            role,
+		"request.binding_name":    bindingName,
 
 		// specified by the existing instance
-		"instance.name":    instance.Name,
-		"instance.details": otherDetails,
+		"instance.name":                 instance.Name,
+		"instance.resource_name":        instance.GetResourceName(),
+		"instance.details":              otherDetails,
+		"instance.resource_prefix":      instance.ResourcePrefix,
+		"instance.network":              instance.Network,
+		"instance.subnet":               instance.Subnet,
+		"instance.availability_zones":   availabilityZonesVariable(zones),
+		"instance.metadata":             metadataVariable(instanceMetadata),
+		"instance.generated_parameters": metadataVariable(generated),
 	}
 
 	builder := varcontext.Builder().
 		SetEvalConstants(constants).
 		MergeMap(svc.BindDefaultOverrides()).
-		MergeJsonObject(details.GetRawParameters()).
+		MergeJsonObject(params).
 		MergeMap(plan.BindOverrides).
+		MergeMap(resourcePrefixVariables(instance.ResourcePrefix)).
+		MergeMap(networkVariables(instance.Network, instance.Subnet)).
+		MergeMap(availabilityZonesVariables(constants["instance.availability_zones"])).
 		MergeDefaults(svc.bindDefaults()).
 		MergeDefaults(svc.BindComputedVariables)
 
-	return buildAndValidate(builder, svc.BindInputVariables)
+	vc, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateVariables(vc.ToMap(), svc.BindInputVariables); err != nil {
+		return nil, errInvalidBindParameters(err)
+	}
+
+	return vc, nil
 }
 
 // buildAndValidate builds the varcontext and if it's valid validates the
@@ -467,21 +803,67 @@ func buildAndValidate(builder *varcontext.ContextBuilder, vars []BrokerVariable)
 	return vc, nil
 }
 
-func (svc *ServiceDefinition) AllowedUpdate(details brokerapi.UpdateDetails) (bool, error) {
+// UpdateClassification groups the parameters of an update request by the
+// effect changing them has on the instance.
+type UpdateClassification struct {
+	// Recreate holds the parameters that will cause the instance to be re-created.
+	Recreate []string
+	// Prohibited holds the parameters that may not be updated.
+	Prohibited []string
+}
+
+// ClassifyUpdate sorts the user supplied parameters of an update request by
+// their UpdateBehavior. Parameters that can be updated in place are omitted.
+func (svc *ServiceDefinition) ClassifyUpdate(details brokerapi.UpdateDetails) (UpdateClassification, error) {
+	classification := UpdateClassification{}
 	if details.GetRawParameters() == nil || len(details.GetRawParameters()) == 0 {
-		return true, nil
+		return classification, nil
 	}
 
 	out := map[string]interface{}{}
 	if err := json.Unmarshal(details.GetRawParameters(), &out); err != nil {
-		return false, err
+		return classification, err
 	}
+	// the resource prefix is baked into the names of existing resources and
+	// moving them to another network or other zones isn't supported
+	for _, fixed := range svc.fixedParameters() {
+		if _, ok := out[fixed]; ok {
+			classification.Prohibited = append(classification.Prohibited, fixed)
+		}
+	}
+
 	for _, param := range svc.ProvisionInputVariables {
-		if param.ProhibitUpdate {
-			if _, ok := out[param.FieldName]; ok {
-				return false, nil
-			} 
+		if _, ok := out[param.FieldName]; !ok {
+			continue
+		}
+
+		switch param.GetUpdateBehavior() {
+		case UpdateProhibited:
+			classification.Prohibited = append(classification.Prohibited, param.FieldName)
+		case UpdateRecreate:
+			classification.Recreate = append(classification.Recreate, param.FieldName)
 		}
 	}
-	return true, nil
+	return classification, nil
+}
+
+// fixedParameters returns the parameters handled by the broker that can never
+// be updated. The network parameters are only the broker's if the service
+// resolves networks.
+func (svc *ServiceDefinition) fixedParameters() []string {
+	if svc.ResolvesNetwork() {
+		return []string{ResourcePrefixParameter, NetworkParameter, SubnetParameter, AvailabilityZonesParameter}
+	}
+
+	return []string{ResourcePrefixParameter, AvailabilityZonesParameter}
+}
+
+// AllowedUpdate returns false if the update request contains parameters that
+// are prohibited from being updated.
+func (svc *ServiceDefinition) AllowedUpdate(details brokerapi.UpdateDetails) (bool, error) {
+	classification, err := svc.ClassifyUpdate(details)
+	if err != nil {
+		return false, err
+	}
+	return len(classification.Prohibited) == 0, nil
 }
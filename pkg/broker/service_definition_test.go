@@ -16,6 +16,7 @@ package broker
 
 import (
 	"encoding/json"
+	"reflect"
 	"testing"
 	"github.com/pivotal-cf/brokerapi"
 )
@@ -74,4 +75,54 @@ func TestServiceDefinition_CheckProhibitUpdate(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestServiceDefinition_ClassifyUpdate(t *testing.T) {
+	svcDef := ServiceDefinition{
+		ProvisionInputVariables: []BrokerVariable{
+			{FieldName: "legacy", ProhibitUpdate: true},
+			{FieldName: "prohibited", UpdateBehavior: UpdateProhibited},
+			{FieldName: "recreate", UpdateBehavior: UpdateRecreate},
+			{FieldName: "in_place", UpdateBehavior: UpdateInPlace},
+			{FieldName: "default"},
+		},
+	}
+
+	cases := map[string]struct {
+		rawParams string
+		expected  UpdateClassification
+	}{
+		"in place": {
+			rawParams: `{"in_place":1,"default":2}`,
+			expected:  UpdateClassification{},
+		},
+		"recreate": {
+			rawParams: `{"recreate":1,"in_place":2}`,
+			expected:  UpdateClassification{Recreate: []string{"recreate"}},
+		},
+		"resource prefix": {
+			rawParams: `{"resource_prefix":"tenant"}`,
+			expected:  UpdateClassification{Prohibited: []string{"resource_prefix"}},
+		},
+		"prohibited": {
+			rawParams: `{"legacy":1,"prohibited":2,"recreate":3}`,
+			expected: UpdateClassification{
+				Recreate:   []string{"recreate"},
+				Prohibited: []string{"legacy", "prohibited"},
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			actual, err := svcDef.ClassifyUpdate(brokerapi.UpdateDetails{
+				RawParameters: json.RawMessage(tc.rawParams),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(actual, tc.expected) {
+				t.Errorf("Expected classification %#v, got %#v", tc.expected, actual)
+			}
+		})
+	}
+}
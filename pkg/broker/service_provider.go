@@ -16,12 +16,23 @@ package broker
 
 import (
 	"context"
+	"time"
 
 	"github.com/pivotal/cloud-service-broker/db_service/models"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal-cf/brokerapi"
 )
 
+// SyslogDrainURLOutput is the name of the bind output holding the URL the
+// platform should forward the logs of the bound application to. Services
+// returning it must require brokerapi.PermissionSyslogDrain.
+const SyslogDrainURLOutput = "syslog_drain_url"
+
+// RouteServiceURLOutput is the name of the bind output holding the URL the
+// platform should proxy the requests to the bound route through. Services
+// returning it must require brokerapi.PermissionRouteForwarding.
+const RouteServiceURLOutput = "route_service_url"
+
 //go:generate counterfeiter . ServiceProvider
 
 // ServiceProvider performs the actual provisoning/deprovisioning part of a service broker request.
@@ -59,3 +70,105 @@ type ServiceProvider interface {
 	// Return a nil error if you choose not to implement this function.
 	UpdateInstanceDetails(ctx context.Context, instance *models.ServiceInstanceDetails) error
 }
+
+// OperationDescriber is optionally implemented by ServiceProviders that can
+// describe the progress of an instance's running asynchronous operation, e.g.
+// "removing firewall rules". The description is returned to the platform
+// while it polls the operation.
+type OperationDescriber interface {
+	DescribeOperation(ctx context.Context, instance models.ServiceInstanceDetails) (string, error)
+}
+
+// RetryAfterSuggester is optionally implemented by ServiceProviders that know
+// how long to wait before polling an instance's running asynchronous operation
+// again, e.g. from the kind of cloud operation. The suggestion takes
+// precedence over the plan's poll interval and is returned to the platform in
+// the Retry-After header.
+type RetryAfterSuggester interface {
+	SuggestRetryAfter(ctx context.Context, instance models.ServiceInstanceDetails) (time.Duration, error)
+}
+
+// AsyncOnlyProvider is optionally implemented by ServiceProviders whose
+// asynchronous operations can't be waited for within a request, e.g. because
+// they take hours. Such services fail when the operator forces synchronous
+// operations.
+type AsyncOnlyProvider interface {
+	AsyncOnly() bool
+}
+
+// ProvisionVerifier is optionally implemented by ServiceProviders that can
+// check a synchronously provisioned resource is actually usable, e.g.
+// reachable. Instances failing verification are rolled back and never
+// recorded.
+type ProvisionVerifier interface {
+	VerifyProvision(ctx context.Context, instance models.ServiceInstanceDetails) error
+}
+
+// CatalogEnricher is optionally implemented by ServiceProviders that augment
+// the catalog entry of their service with data only known by querying the
+// cloud, e.g. the available regions or instance sizes. The entry is a copy, so
+// changes only affect the served catalog.
+type CatalogEnricher interface {
+	EnrichCatalog(ctx context.Context, entry *Service) error
+}
+
+// NetworkPolicyManager is optionally implemented by ServiceProviders that can
+// allow the network of a bound application to reach an instance, e.g. with a
+// security group rule. It's used for the bindings of plans with NetworkPolicy
+// set.
+type NetworkPolicyManager interface {
+	// CreateNetworkPolicy allows the application to reach the instance and
+	// returns an ID identifying the policy.
+	CreateNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, appGUID string) (string, error)
+	// DeleteNetworkPolicy removes a policy created by CreateNetworkPolicy.
+	DeleteNetworkPolicy(ctx context.Context, instance models.ServiceInstanceDetails, policyID string) error
+}
+
+// HealthChecker is optionally implemented by ServiceProviders that can check
+// their backend, e.g. the cloud API, is reachable. The result is reported by
+// the readiness endpoint, and provisions of the service are rejected while
+// the backend is unhealthy.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// FieldFinalizer is optionally implemented by ServiceProviders whose full
+// refresh of an instance's details with UpdateInstanceDetails is expensive.
+// Once an asynchronous operation completes, only the fields it returns from
+// FinalizationFields are fetched and stored; the other details are kept. An
+// empty list falls back to UpdateInstanceDetails.
+type FieldFinalizer interface {
+	FinalizationFields() []string
+
+	// FetchInstanceFields returns the current values of the given fields of
+	// the instance's details.
+	FetchInstanceFields(ctx context.Context, instance models.ServiceInstanceDetails, fields []string) (map[string]interface{}, error)
+}
+
+// CostEstimator is optionally implemented by ServiceProviders that can price
+// an instance from its provision variables before it's provisioned, e.g. from
+// the requested disk size. Nothing may be created while estimating. Services
+// whose provider doesn't implement it are estimated with the static costs of
+// the plan.
+type CostEstimator interface {
+	EstimateCost(ctx context.Context, vars *varcontext.VarContext) (*CostEstimate, error)
+}
+
+// UpdateValidator is optionally implemented by ServiceProviders that can only
+// tell whether an update is valid from the instance's current state, e.g.
+// storage can't shrink below its current usage. ValidateUpdate is called once
+// the parameters passed the schema validation and before the provider's Update;
+// the update is rejected with a 422 Unprocessable Entity carrying the error's
+// message if it returns one.
+type UpdateValidator interface {
+	ValidateUpdate(ctx context.Context, instance models.ServiceInstanceDetails, vars *varcontext.VarContext) error
+}
+
+// InstanceAdopter is optionally implemented by ServiceProviders that can take
+// over existing cloud resources as an instance. AdoptInstance checks the
+// resources identified by resources exist and can be managed as the given
+// instance, e.g. by importing them into the provider's state. Adoption of
+// services whose provider doesn't implement it is rejected.
+type InstanceAdopter interface {
+	AdoptInstance(ctx context.Context, instance models.ServiceInstanceDetails, resources map[string]interface{}) error
+}
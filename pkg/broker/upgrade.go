@@ -0,0 +1,34 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package broker
+
+import "context"
+
+type upgradeKey struct{}
+
+// WithUpgrade returns a copy of the context marking the update as an upgrade
+// of the instance to the plan's current maintenance_info, with no changes to
+// its parameters or plan.
+func WithUpgrade(ctx context.Context) context.Context {
+	return context.WithValue(ctx, upgradeKey{}, true)
+}
+
+// IsUpgrade returns true if the context is of an update that only upgrades
+// the instance. Providers may use it to skip work that only parameter
+// changes require.
+func IsUpgrade(ctx context.Context) bool {
+	upgrade, _ := ctx.Value(upgradeKey{}).(bool)
+	return upgrade
+}
@@ -34,6 +34,7 @@ const (
 	JsonTypeNumeric JsonType = "number"
 	JsonTypeInteger JsonType = "integer"
 	JsonTypeBoolean JsonType = "boolean"
+	JsonTypeObject  JsonType = "object"
 )
 
 type JsonType string
@@ -58,6 +59,56 @@ type BrokerVariable struct {
 	// http://json-schema.org/latest/json-schema-validation.html
 	Constraints map[string]interface{} `yaml:"constraints,omitempty"`
 	ProhibitUpdate bool `yaml:"prohibit_update,omitempty"`
+	// UpdateBehavior classifies what happens to an instance when this variable
+	// changes. ProhibitUpdate is a shorthand for UpdateProhibited.
+	UpdateBehavior UpdateBehavior `yaml:"update_behavior,omitempty"`
+	// Sensitive variables, e.g. passwords, are masked in the broker's logs.
+	Sensitive bool `yaml:"sensitive,omitempty"`
+	// Generate makes the broker generate a random value for the variable if
+	// the user doesn't supply one. Only honored for provision variables.
+	Generate *GenerateDirective `yaml:"generate,omitempty"`
+	// Deprecated variables are still accepted, but users supplying them are
+	// warned. Only honored for provision variables.
+	Deprecated bool `yaml:"deprecated,omitempty"`
+	// Normalize makes the broker rewrite user supplied values to their
+	// canonical form before validating them. Only honored for provision
+	// variables.
+	Normalize *NormalizeDirective `yaml:"normalize,omitempty"`
+}
+
+// UpdateBehavior describes the effect of changing a provision parameter on an
+// existing service instance.
+type UpdateBehavior string
+
+const (
+	// UpdateInPlace parameters can be changed without disrupting the instance.
+	UpdateInPlace UpdateBehavior = "update_in_place"
+	// UpdateRecreate parameters can be changed, but the underlying resources
+	// will be destroyed and re-created which may result in data loss.
+	UpdateRecreate UpdateBehavior = "update_recreate"
+	// UpdateProhibited parameters can't be changed after the instance is created.
+	UpdateProhibited UpdateBehavior = "prohibited"
+)
+
+// GetUpdateBehavior returns the effective UpdateBehavior of the variable,
+// taking the legacy ProhibitUpdate flag into account.
+func (bv *BrokerVariable) GetUpdateBehavior() UpdateBehavior {
+	switch {
+	case bv.ProhibitUpdate:
+		return UpdateProhibited
+	case bv.UpdateBehavior == "":
+		return UpdateInPlace
+	default:
+		return bv.UpdateBehavior
+	}
+}
+
+// IsSensitive returns whether the variable is marked sensitive, either with
+// Sensitive or an x-sensitive constraint. Generated variables are secrets, so
+// they are always sensitive.
+func (bv *BrokerVariable) IsSensitive() bool {
+	sensitive, _ := bv.Constraints[validation.KeySensitive].(bool)
+	return bv.Sensitive || sensitive || bv.Generate != nil
 }
 
 var _ validation.Validatable = (*ServiceDefinition)(nil)
@@ -68,9 +119,45 @@ func (bv *BrokerVariable) Validate() (errs *validation.FieldError) {
 		validation.ErrIfBlank(bv.FieldName, "field_name"),
 		validation.ErrIfNotJSONSchemaType(string(bv.Type), "type"),
 		validation.ErrIfBlank(bv.Details, "details"),
+		bv.validateUpdateBehavior(),
+		bv.validateGenerate(),
+		bv.validateNormalize(),
 	)
 }
 
+func (bv *BrokerVariable) validateNormalize() *validation.FieldError {
+	if bv.Normalize == nil {
+		return nil
+	}
+
+	errs := bv.Normalize.Validate().ViaField("normalize")
+	if bv.Type != JsonTypeString {
+		errs = errs.Also(validation.ErrInvalidValue(bv.Type, "type"))
+	}
+	return errs
+}
+
+func (bv *BrokerVariable) validateGenerate() *validation.FieldError {
+	if bv.Generate == nil {
+		return nil
+	}
+
+	errs := bv.Generate.Validate().ViaField("generate")
+	if bv.Type != JsonTypeString {
+		errs = errs.Also(validation.ErrInvalidValue(bv.Type, "type"))
+	}
+	return errs
+}
+
+func (bv *BrokerVariable) validateUpdateBehavior() *validation.FieldError {
+	switch bv.UpdateBehavior {
+	case "", UpdateInPlace, UpdateRecreate, UpdateProhibited:
+		return nil
+	default:
+		return validation.ErrInvalidValue(bv.UpdateBehavior, "update_behavior")
+	}
+}
+
 // ToSchema converts the BrokerVariable into the value part of a JSON Schema.
 func (bv *BrokerVariable) ToSchema() map[string]interface{} {
 	schema := map[string]interface{}{}
@@ -117,8 +204,19 @@ func (bv *BrokerVariable) ToSchema() map[string]interface{} {
 		}
 	}
 
-	if bv.ProhibitUpdate {
-		schema[validation.KeyProhibitUpdate] = bv.ProhibitUpdate
+	if bv.IsSensitive() {
+		schema[validation.KeySensitive] = true
+	}
+
+	if bv.Deprecated {
+		schema[validation.KeyDeprecated] = true
+	}
+
+	switch bv.GetUpdateBehavior() {
+	case UpdateProhibited:
+		schema[validation.KeyProhibitUpdate] = true
+	case UpdateRecreate:
+		schema[validation.KeyUpdateBehavior] = UpdateRecreate
 	}
 
 	return schema
@@ -86,6 +86,30 @@ func TestBrokerVariable_ToSchema(t *testing.T) {
 				"prohibitUpdate": true,
 			},
 		},
+		"recreate update behavior is copied": {
+			BrokerVariable{UpdateBehavior: UpdateRecreate},
+			map[string]interface{}{
+				"updateBehavior": UpdateRecreate,
+			},
+		},
+		"sensitive is copied": {
+			BrokerVariable{Sensitive: true},
+			map[string]interface{}{
+				"x-sensitive": true,
+			},
+		},
+		"generated is sensitive": {
+			BrokerVariable{Generate: &GenerateDirective{}},
+			map[string]interface{}{
+				"x-sensitive": true,
+			},
+		},
+		"deprecated is copied": {
+			BrokerVariable{Deprecated: true},
+			map[string]interface{}{
+				"deprecated": true,
+			},
+		},
 	}
 
 	for tn, tc := range cases {
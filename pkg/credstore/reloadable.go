@@ -0,0 +1,146 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credstore
+
+import (
+	"sync"
+
+	"code.cloudfoundry.org/credhub-cli/credhub/permissions"
+)
+
+// Reloader is implemented by stores whose auth material can be reloaded
+// without restarting the broker.
+type Reloader interface {
+	Reload() error
+}
+
+// ReloadableCredStore is a CredStore whose underlying client can be replaced
+// at runtime, e.g. after its mTLS certificate or token was rotated.
+//
+// Calls that started before a reload complete with the old client. Calls
+// that fail after the client was replaced while they ran are retried once with
+// the new client, in case they failed because the old credentials were
+// revoked.
+type ReloadableCredStore struct {
+	factory func() (CredStore, error)
+
+	mu         sync.RWMutex
+	current    CredStore
+	generation int
+}
+
+var _ CredStore = (*ReloadableCredStore)(nil)
+var _ Reloader = (*ReloadableCredStore)(nil)
+
+// NewReloadableCredStore creates a store from the factory, which is called
+// again on every Reload.
+func NewReloadableCredStore(factory func() (CredStore, error)) (*ReloadableCredStore, error) {
+	store, err := factory()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReloadableCredStore{factory: factory, current: store}, nil
+}
+
+// Reload creates a new client from the factory and uses it for subsequent
+// calls. The old client is kept if creating the new one fails.
+func (r *ReloadableCredStore) Reload() error {
+	store, err := r.factory()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = store
+	r.generation++
+
+	return nil
+}
+
+func (r *ReloadableCredStore) snapshot() (CredStore, int) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.generation
+}
+
+// do runs the call and retries it once with the new client if the client was
+// reloaded while the call failed.
+func (r *ReloadableCredStore) do(call func(CredStore) error) error {
+	store, generation := r.snapshot()
+	err := call(store)
+	if err == nil {
+		return nil
+	}
+
+	if newStore, newGeneration := r.snapshot(); newGeneration != generation {
+		return call(newStore)
+	}
+
+	return err
+}
+
+func (r *ReloadableCredStore) Put(key string, credentials interface{}) (result interface{}, err error) {
+	err = r.do(func(c CredStore) error {
+		result, err = c.Put(key, credentials)
+		return err
+	})
+	return
+}
+
+func (r *ReloadableCredStore) PutValue(key string, credentials interface{}) (result interface{}, err error) {
+	err = r.do(func(c CredStore) error {
+		result, err = c.PutValue(key, credentials)
+		return err
+	})
+	return
+}
+
+func (r *ReloadableCredStore) Get(key string) (result interface{}, err error) {
+	err = r.do(func(c CredStore) error {
+		result, err = c.Get(key)
+		return err
+	})
+	return
+}
+
+func (r *ReloadableCredStore) GetValue(key string) (result string, err error) {
+	err = r.do(func(c CredStore) error {
+		result, err = c.GetValue(key)
+		return err
+	})
+	return
+}
+
+func (r *ReloadableCredStore) Delete(key string) error {
+	return r.do(func(c CredStore) error {
+		return c.Delete(key)
+	})
+}
+
+func (r *ReloadableCredStore) AddPermission(path string, actor string, ops []string) (result *permissions.Permission, err error) {
+	err = r.do(func(c CredStore) error {
+		result, err = c.AddPermission(path, actor, ops)
+		return err
+	})
+	return
+}
+
+func (r *ReloadableCredStore) DeletePermission(path string) error {
+	return r.do(func(c CredStore) error {
+		return c.DeletePermission(path)
+	})
+}
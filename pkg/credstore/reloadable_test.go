@@ -0,0 +1,91 @@
+// Copyright 2020 Pivotal Software, Inc.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package credstore_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal/cloud-service-broker/pkg/credstore"
+	"github.com/pivotal/cloud-service-broker/pkg/credstore/credstorefakes"
+)
+
+var _ = Describe("ReloadableCredStore", func() {
+	var (
+		clients    []*credstorefakes.FakeCredStore
+		factoryErr error
+		store      *credstore.ReloadableCredStore
+	)
+
+	BeforeEach(func() {
+		clients = nil
+		factoryErr = nil
+
+		var err error
+		store, err = credstore.NewReloadableCredStore(func() (credstore.CredStore, error) {
+			if factoryErr != nil {
+				return nil, factoryErr
+			}
+
+			client := &credstorefakes.FakeCredStore{}
+			clients = append(clients, client)
+			return client, nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("delegates to the current client", func() {
+		clients[0].GetValueReturns("value", nil)
+
+		Expect(store.GetValue("key")).To(Equal("value"))
+		Expect(clients[0].GetValueArgsForCall(0)).To(Equal("key"))
+	})
+
+	It("uses the new client after a reload", func() {
+		Expect(store.Reload()).To(Succeed())
+		Expect(store.Delete("key")).To(Succeed())
+
+		Expect(clients).To(HaveLen(2))
+		Expect(clients[0].DeleteCallCount()).To(Equal(0))
+		Expect(clients[1].DeleteCallCount()).To(Equal(1))
+	})
+
+	It("keeps the old client if the reload fails", func() {
+		factoryErr = errors.New("bad certificate")
+
+		Expect(store.Reload()).To(MatchError("bad certificate"))
+		Expect(store.Delete("key")).To(Succeed())
+		Expect(clients[0].DeleteCallCount()).To(Equal(1))
+	})
+
+	It("retries a call that failed while the client was reloaded", func() {
+		clients[0].DeleteStub = func(string) error {
+			Expect(store.Reload()).To(Succeed())
+			return errors.New("unauthorized")
+		}
+
+		Expect(store.Delete("key")).To(Succeed())
+		Expect(clients[1].DeleteCallCount()).To(Equal(1))
+	})
+
+	It("doesn't retry a call that failed without a reload", func() {
+		clients[0].DeleteReturns(errors.New("unauthorized"))
+
+		Expect(store.Delete("key")).To(MatchError("unauthorized"))
+		Expect(clients).To(HaveLen(1))
+		Expect(clients[0].DeleteCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,117 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events emits CloudEvents describing the lifecycle of the broker's
+// service instances and bindings.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	httpURLProp = "events.http.url"
+	sourceProp  = "events.source"
+
+	// SpecVersion is the version of the CloudEvents specification the events
+	// follow.
+	SpecVersion = "1.0"
+
+	// The types of the events, emitted once the operation completes.
+	InstanceProvisioned   = "com.broker.instance.provisioned"
+	InstanceDeprovisioned = "com.broker.instance.deprovisioned"
+	InstanceUpdated       = "com.broker.instance.updated"
+	InstanceBound         = "com.broker.instance.bound"
+	InstanceUnbound       = "com.broker.instance.unbound"
+
+	// OutcomeSucceeded and OutcomeFailed mirror the OSB operation states.
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+func init() {
+	viper.BindEnv(httpURLProp, "EVENTS_HTTP_URL")
+	viper.SetDefault(httpURLProp, "")
+
+	viper.BindEnv(sourceProp, "EVENTS_SOURCE")
+	viper.SetDefault(sourceProp, "cloud-service-broker")
+}
+
+// Event is a CloudEvent in its structured JSON form.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Data      `json:"data"`
+}
+
+// Data is the payload of the lifecycle events.
+type Data struct {
+	InstanceID  string `json:"instance_id"`
+	BindingID   string `json:"binding_id,omitempty"`
+	OperationID string `json:"operation_id,omitempty"`
+	Outcome     string `json:"outcome"`
+	Error       string `json:"error,omitempty"`
+}
+
+// New creates an event of the given type about the instance, or the binding
+// if the data has a binding ID, from the configured source.
+func New(eventType string, data Data) Event {
+	subject := data.InstanceID
+	if data.BindingID != "" {
+		subject = data.BindingID
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              newID(),
+		Source:          viper.GetString(sourceProp),
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+func newID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Sink delivers events, e.g. over HTTP or to a message bus.
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// NewSinkFromEnv returns the sink configured in the environment, or nil if
+// events aren't enabled.
+func NewSinkFromEnv() Sink {
+	url := viper.GetString(httpURLProp)
+	if url == "" {
+		return nil
+	}
+
+	return NewHTTPSink(url)
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestHTTPSink_Send(t *testing.T) {
+	viper.Set(sourceProp, "/brokers/test")
+	defer viper.Reset()
+
+	var received Event
+	var contentType string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+	}))
+	defer receiver.Close()
+
+	event := New(InstanceBound, Data{InstanceID: "instance", BindingID: "binding", Outcome: OutcomeSucceeded})
+	if err := NewHTTPSink(receiver.URL).Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "application/cloudevents+json" {
+		t.Errorf("expected a structured mode content type, got %q", contentType)
+	}
+
+	if received.SpecVersion != "1.0" || received.Type != InstanceBound || received.Source != "/brokers/test" || received.ID == "" {
+		t.Errorf("expected the CloudEvents attributes to be set, got %+v", received)
+	}
+
+	if received.Subject != "binding" || received.Data != event.Data {
+		t.Errorf("expected the binding to be the subject with the data, got %+v", received)
+	}
+}
+
+func TestHTTPSink_Send_rejected(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	err := NewHTTPSink(receiver.URL).Send(context.Background(), New(InstanceProvisioned, Data{InstanceID: "instance"}))
+	if err == nil {
+		t.Error("expected an error when the receiver rejects the event")
+	}
+}
+
+func TestNewSinkFromEnv(t *testing.T) {
+	defer viper.Reset()
+
+	if sink := NewSinkFromEnv(); sink != nil {
+		t.Errorf("expected no sink by default, got %v", sink)
+	}
+
+	viper.Set(httpURLProp, "http://localhost:8080/events")
+	if _, ok := NewSinkFromEnv().(*HTTPSink); !ok {
+		t.Error("expected an HTTP sink when a URL is configured")
+	}
+}
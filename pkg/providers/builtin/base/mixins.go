@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 
 	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
 	"github.com/pivotal/cloud-service-broker/pkg/varcontext"
 	"github.com/pivotal-cf/brokerapi"
 )
@@ -76,14 +77,49 @@ type MergedInstanceCredsMixin struct{}
 
 // BuildInstanceCredentials combines the bind credentials with the connection
 // information in the instance details to get a full set of connection details.
+// If the bind credentials are a broker.CredentialSet, the instance details are
+// merged into each of its endpoints instead.
 func (b *MergedInstanceCredsMixin) BuildInstanceCredentials(ctx context.Context, bindRecord models.ServiceBindingCredentials, instanceRecord models.ServiceInstanceDetails) (*brokerapi.Binding, error) {
-	vc, err := varcontext.Builder().
-		MergeJsonObject(json.RawMessage(instanceRecord.OtherDetails)).
-		MergeJsonObject(json.RawMessage(bindRecord.OtherDetails)).
-		Build()
+	instanceVc, err := varcontext.Builder().MergeJsonObject(json.RawMessage(instanceRecord.OtherDetails)).Build()
 	if err != nil {
 		return nil, err
 	}
 
-	return &brokerapi.Binding{Credentials: vc.ToMap()}, nil
+	bindVc, err := varcontext.Builder().MergeJsonObject(json.RawMessage(bindRecord.OtherDetails)).Build()
+	if err != nil {
+		return nil, err
+	}
+
+	var creds map[string]interface{}
+	if set, ok := broker.ParseCredentialSet(bindVc.ToMap()); ok {
+		creds = bindVc.ToMap()
+		for k, v := range set.MergeDefaults(instanceVc.ToMap()).ToMap() {
+			creds[k] = v
+		}
+	} else {
+		creds = instanceVc.ToMap()
+		for k, v := range bindVc.ToMap() {
+			creds[k] = v
+		}
+	}
+
+	binding := &brokerapi.Binding{Credentials: creds}
+
+	// The syslog drain and route service URLs are returned to the platform
+	// rather than the app.
+	if drainURL, ok := creds[broker.SyslogDrainURLOutput].(string); ok {
+		binding.SyslogDrainURL = drainURL
+		delete(creds, broker.SyslogDrainURLOutput)
+	}
+
+	if routeServiceURL, ok := creds[broker.RouteServiceURLOutput].(string); ok {
+		binding.RouteServiceURL = routeServiceURL
+		delete(creds, broker.RouteServiceURLOutput)
+	}
+
+	if bindRecord.Role != "" {
+		creds[broker.BindRoleParameter] = bindRecord.Role
+	}
+
+	return binding, nil
 }
@@ -45,6 +45,10 @@ type TfServiceDefinitionV1 struct {
 	BindSettings      TfServiceDefinitionV1Action `yaml:"bind"`
 	Examples          []broker.ServiceExample     `yaml:"examples"`
 	PlanUpdateable    bool						  `yaml:"plan_updateable"`
+	Requires          []brokerapi.RequiredPermission `yaml:"requires,omitempty"`
+	ResourceNaming    *broker.ResourceNaming        `yaml:"resource_naming,omitempty"`
+	DisplayOrder      int                           `yaml:"display_order,omitempty"`
+	DashboardUrlTemplate string                     `yaml:"dashboard_url_template,omitempty"`
 
 	// Internal SHOULD be set to true for Google maintained services.
 	Internal bool `yaml:"-"`
@@ -54,27 +58,78 @@ type TfServiceDefinitionV1 struct {
 // TfServiceDefinitionV1Plan represents a service plan in a human-friendly format
 // that can be converted into an OSB compatible plan.
 type TfServiceDefinitionV1Plan struct {
-	Name               string                 `yaml:"name"`
-	Id                 string                 `yaml:"id"`
-	Description        string                 `yaml:"description"`
-	DisplayName        string                 `yaml:"display_name"`
-	Bullets            []string               `yaml:"bullets,omitempty"`
-	Free               bool                   `yaml:"free,omitempty"`
-	Properties         map[string]interface{} `yaml:"properties"`
-	ProvisionOverrides map[string]interface{} `yaml:"provision_overrides,omitempty"`
-	BindOverrides      map[string]interface{} `yaml:"bind_overrides,omitempty"`
+	Name               string                        `yaml:"name"`
+	Id                 string                        `yaml:"id"`
+	Description        string                        `yaml:"description"`
+	DisplayName        string                        `yaml:"display_name"`
+	Bullets            []string                      `yaml:"bullets,omitempty"`
+	Free               bool                          `yaml:"free,omitempty"`
+	Bindable           *bool                         `yaml:"bindable,omitempty"`
+	Properties         map[string]interface{}        `yaml:"properties"`
+	ProvisionOverrides map[string]interface{}        `yaml:"provision_overrides,omitempty"`
+	BindOverrides      map[string]interface{}        `yaml:"bind_overrides,omitempty"`
+	Costs              []broker.PlanCost             `yaml:"costs,omitempty"`
+	Roles              []string                      `yaml:"roles,omitempty"`
+	Network            *broker.PlanNetwork           `yaml:"network,omitempty"`
+	AvailabilityZones  *broker.PlanAvailabilityZones `yaml:"availability_zones,omitempty"`
+	ProvisionTimeout   string                        `yaml:"provision_timeout,omitempty"`
+	EstimatedDuration  string                        `yaml:"estimated_duration,omitempty"`
+	PollInterval       string                        `yaml:"poll_interval,omitempty"`
+	ReadinessProbe     *broker.ReadinessProbe        `yaml:"readiness_probe,omitempty"`
+	NetworkPolicy      bool                          `yaml:"network_policy,omitempty"`
+	DisplayOrder       int                           `yaml:"display_order,omitempty"`
+	DeprovisionInputs  []broker.BrokerVariable       `yaml:"deprovision_inputs,omitempty"`
+	RequiresInstanceOf []string                      `yaml:"requires_instance_of,omitempty"`
+	MaintenanceInfo    *brokerapi.MaintenanceInfo    `yaml:"maintenance_info,omitempty"`
+	DashboardUrlTemplate string                      `yaml:"dashboard_url_template,omitempty"`
 }
 
 var _ validation.Validatable = (*TfServiceDefinitionV1Plan)(nil)
 
 // Validate implements validation.Validatable.
 func (plan *TfServiceDefinitionV1Plan) Validate() (errs *validation.FieldError) {
-	return errs.Also(
+	errs = errs.Also(
 		validation.ErrIfBlank(plan.Name, "name"),
 		validation.ErrIfNotUUID(plan.Id, "id"),
 		validation.ErrIfBlank(plan.Description, "description"),
 		validation.ErrIfBlank(plan.DisplayName, "display_name"),
 	)
+
+	for i, cost := range plan.Costs {
+		errs = errs.Also(cost.Validate().ViaFieldIndex("costs", i))
+	}
+
+	if plan.Network != nil {
+		errs = errs.Also(plan.Network.Validate().ViaField("network"))
+	}
+
+	if plan.AvailabilityZones != nil {
+		errs = errs.Also(plan.AvailabilityZones.Validate().ViaField("availability_zones"))
+	}
+
+	if plan.ReadinessProbe != nil {
+		errs = errs.Also(plan.ReadinessProbe.Validate().ViaField("readiness_probe"))
+	}
+
+	if plan.DisplayOrder < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(plan.DisplayOrder, "display_order"))
+	}
+
+	for i, input := range plan.DeprovisionInputs {
+		errs = errs.Also(input.Validate().ViaFieldIndex("deprovision_inputs", i))
+	}
+
+	for _, service := range plan.RequiresInstanceOf {
+		if service == "" {
+			errs = errs.Also(validation.ErrInvalidValue(service, "requires_instance_of"))
+		}
+	}
+
+	converted := plan.ToPlan()
+	errs = errs.Also(converted.ValidateDurations())
+	errs = errs.Also(broker.ValidateDashboardUrlTemplate(plan.DashboardUrlTemplate, &converted, "dashboard_url_template"))
+
+	return errs
 }
 
 // Converts this plan definition to a broker.ServicePlan.
@@ -84,10 +139,13 @@ func (plan *TfServiceDefinitionV1Plan) ToPlan() broker.ServicePlan {
 		Description: plan.Description,
 		Name:        plan.Name,
 		Free:        brokerapi.FreeValue(plan.Free),
+		Bindable:    plan.Bindable,
 		Metadata: &brokerapi.ServicePlanMetadata{
 			Bullets:     plan.Bullets,
 			DisplayName: plan.DisplayName,
+			Costs:       broker.ToServicePlanCosts(plan.Costs),
 		},
+		MaintenanceInfo: plan.MaintenanceInfo,
 	}
 
 	return broker.ServicePlan{
@@ -95,6 +153,18 @@ func (plan *TfServiceDefinitionV1Plan) ToPlan() broker.ServicePlan {
 		ServiceProperties:  plan.Properties,
 		ProvisionOverrides: plan.ProvisionOverrides,
 		BindOverrides:      plan.BindOverrides,
+		Roles:              plan.Roles,
+		Network:            plan.Network,
+		AvailabilityZones:  plan.AvailabilityZones,
+		ProvisionTimeout:   plan.ProvisionTimeout,
+		EstimatedDuration:  plan.EstimatedDuration,
+		PollInterval:       plan.PollInterval,
+		ReadinessProbe:     plan.ReadinessProbe,
+		NetworkPolicy:      plan.NetworkPolicy,
+		DisplayOrder:       plan.DisplayOrder,
+		DeprovisionInputs:  plan.DeprovisionInputs,
+		RequiresInstanceOf: plan.RequiresInstanceOf,
+		DashboardUrlTemplate: plan.DashboardUrlTemplate,
 	}
 }
 
@@ -282,6 +352,24 @@ func (tfb *TfServiceDefinitionV1) Validate() (errs *validation.FieldError) {
 		errs = errs.Also(v.Validate().ViaFieldIndex("plans", i))
 	}
 
+	for i, v := range tfb.Requires {
+		switch v {
+		case brokerapi.PermissionSyslogDrain, brokerapi.PermissionRouteForwarding, brokerapi.PermissionVolumeMount:
+		default:
+			errs = errs.Also(validation.ErrInvalidValue(v, validation.CurrentField).ViaFieldIndex("requires", i))
+		}
+	}
+
+	if tfb.ResourceNaming != nil {
+		errs = errs.Also(tfb.ResourceNaming.Validate().ViaField("resource_naming"))
+	}
+
+	if tfb.DisplayOrder < 0 {
+		errs = errs.Also(validation.ErrInvalidValue(tfb.DisplayOrder, "display_order"))
+	}
+
+	errs = errs.Also(broker.ValidateDashboardUrlTemplate(tfb.DashboardUrlTemplate, nil, "dashboard_url_template"))
+
 	errs = errs.Also(tfb.ProvisionSettings.Validate().ViaField("provision"))
 	errs = errs.Also(tfb.BindSettings.Validate().ViaField("bind"))
 
@@ -357,7 +445,7 @@ func (tfb *TfServiceDefinitionV1) ToService(executor wrapper.TerraformExecutor)
 	})
 
 	constDefn := *tfb
-	return &broker.ServiceDefinition{
+	svc := &broker.ServiceDefinition{
 		Id:               tfb.Id,
 		Name:             tfb.Name,
 		Description:      tfb.Description,
@@ -368,7 +456,10 @@ func (tfb *TfServiceDefinitionV1) ToService(executor wrapper.TerraformExecutor)
 		SupportUrl:       tfb.SupportUrl,
 		ImageUrl:         tfb.ImageUrl,
 		Tags:             tfb.Tags,
+		Requires:         tfb.Requires,
 		Plans:            rawPlans,
+		DisplayOrder:     tfb.DisplayOrder,
+		DashboardUrlTemplate: tfb.DashboardUrlTemplate,
 
 		ProvisionInputVariables: tfb.ProvisionSettings.UserInputs,
 		ProvisionComputedVariables: append(tfb.ProvisionSettings.Computed, varcontext.DefaultVariable{
@@ -386,7 +477,32 @@ func (tfb *TfServiceDefinitionV1) ToService(executor wrapper.TerraformExecutor)
 			jobRunner.Executor = executor
 			return NewTerraformProvider(jobRunner, logger, constDefn)
 		},
-	}, nil
+		AccountProviderBuilder: func(logger lager.Logger, accountEnv map[string]string) broker.ServiceProvider {
+			jobRunner := NewTfJobRunnerForProject(withAccountEnv(envVars, accountEnv))
+			jobRunner.Executor = executor
+			return NewTerraformProvider(jobRunner, logger, constDefn)
+		},
+	}
+
+	if tfb.ResourceNaming != nil {
+		svc.InstanceNamer = tfb.ResourceNaming
+	}
+
+	return svc, nil
+}
+
+// withAccountEnv returns the required environment variables with those of a
+// provider account taking precedence, so Terraform runs with the account's
+// credentials.
+func withAccountEnv(envVars, accountEnv map[string]string) map[string]string {
+	merged := make(map[string]string, len(envVars)+len(accountEnv))
+	for k, v := range envVars {
+		merged[k] = v
+	}
+	for k, v := range accountEnv {
+		merged[k] = v
+	}
+	return merged
 }
 
 // generateTfId creates a unique id for a given provision/bind combination that
@@ -15,6 +15,7 @@
 package tf
 
 import (
+    "encoding/json"
     "reflect"
     "os"
     "fmt"
@@ -611,6 +612,34 @@ func TestTfServiceDefinitionV1Plan_ToPlan(t *testing.T) {
                 },
                 ServiceProperties: map[string]interface{}{"domain": "example.com"}},
         },
+        "costs": {
+            Definition: TfServiceDefinitionV1Plan{
+                Id:          "00000000-0000-0000-0000-000000000001",
+                Name:        "example-email-plan",
+                DisplayName: "example.com email builder",
+                Description: "Builds emails for example.com.",
+                Costs: []broker.PlanCost{
+                    {Amount: 9.99, Currency: "USD", Unit: "MONTHLY"},
+                    {Amount: 8.99, Currency: "EUR", Unit: "MONTHLY"},
+                    {Amount: 0.01, Currency: "USD", Unit: "Email"},
+                },
+            },
+            Expected: broker.ServicePlan{
+                ServicePlan: brokerapi.ServicePlan{
+                    ID:          "00000000-0000-0000-0000-000000000001",
+                    Name:        "example-email-plan",
+                    Description: "Builds emails for example.com.",
+                    Free:        brokerapi.FreeValue(false),
+                    Metadata: &brokerapi.ServicePlanMetadata{
+                        DisplayName: "example.com email builder",
+                        Costs: []brokerapi.ServicePlanCost{
+                            {Amount: map[string]float64{"USD": 9.99, "EUR": 8.99}, Unit: "MONTHLY"},
+                            {Amount: map[string]float64{"USD": 0.01}, Unit: "Email"},
+                        },
+                    },
+                },
+            },
+        },
     }
 
     for tn, tc := range cases {
@@ -623,6 +652,102 @@ func TestTfServiceDefinitionV1Plan_ToPlan(t *testing.T) {
     }
 }
 
+func TestTfServiceDefinitionV1Plan_Validate(t *testing.T) {
+    plan := TfServiceDefinitionV1Plan{
+        Id:          "00000000-0000-0000-0000-000000000001",
+        Name:        "example-email-plan",
+        DisplayName: "example.com email builder",
+        Description: "Builds emails for example.com.",
+        Costs: []broker.PlanCost{
+            {Amount: 9.99, Currency: "USD", Unit: "MONTHLY"},
+            {Amount: -1, Currency: "dollars", Unit: ""},
+        },
+    }
+
+    err := plan.Validate()
+    if err == nil {
+        t.Fatal("expected invalid costs to fail validation")
+    }
+
+    expected := "field must be an ISO 4217 currency code: costs[1].currency\ninvalid value: -1: costs[1].amount\nmissing field(s): costs[1].unit"
+    if err.Error() != expected {
+        t.Errorf("expected error %q, got %q", expected, err.Error())
+    }
+}
+
+func TestTfServiceDefinitionV1Plan_ValidateDashboardUrlTemplate(t *testing.T) {
+    plan := TfServiceDefinitionV1Plan{
+        Id:                   "00000000-0000-0000-0000-000000000001",
+        Name:                 "example-email-plan",
+        DisplayName:          "example.com email builder",
+        Description:          "Builds emails for example.com.",
+        Properties:           map[string]interface{}{"domain": "example.com"},
+        DashboardUrlTemplate: "https://{{plan.domain}}/{{plan.region}}",
+    }
+
+    err := plan.Validate()
+    if err == nil {
+        t.Fatal("expected a template with unknown plan properties to fail validation")
+    }
+
+    if !strings.Contains(err.Error(), "unknown template variable(s) plan.region") {
+        t.Errorf("expected the unknown property to be reported, got %q", err.Error())
+    }
+
+    plan.DashboardUrlTemplate = "https://{{plan.domain}}/{{output.mailbox}}"
+    if err := plan.Validate(); err != nil {
+        t.Errorf("expected the template to be valid, got %v", err)
+    }
+
+    if actual := plan.ToPlan().DashboardUrlTemplate; actual != plan.DashboardUrlTemplate {
+        t.Errorf("expected the template to be converted, got %q", actual)
+    }
+}
+
+func TestTfServiceDefinitionV1_CatalogCosts(t *testing.T) {
+    definition := TfServiceDefinitionV1{
+        Version:     1,
+        Id:          "d34705c8-3edf-4ab8-93b3-d97f080da24c",
+        Name:        "my-service-name",
+        Description: "my-service-description",
+        DisplayName: "My Service Name",
+
+        ImageUrl:         "https://example.com/image.png",
+        SupportUrl:       "https://example.com/support",
+        DocumentationUrl: "https://example.com/docs",
+        Plans: []TfServiceDefinitionV1Plan{
+            {
+                Id:          "00000000-0000-0000-0000-000000000001",
+                Name:        "paid",
+                DisplayName: "Paid",
+                Description: "a paid plan",
+                Costs:       []broker.PlanCost{{Amount: 9.99, Currency: "USD", Unit: "MONTHLY"}},
+            },
+        },
+    }
+
+    service, err := definition.ToService(nil)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    entry, err := service.CatalogEntry()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    plain := entry.ToPlain()
+    actual, err := json.Marshal(plain.Plans[0].Metadata.Costs)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := `[{"amount":{"USD":9.99},"unit":"MONTHLY"}]`
+    if string(actual) != expected {
+        t.Errorf("expected metadata.costs %s, got %s", expected, actual)
+    }
+}
+
 func TestTfServiceDefinitionV1_ToService(t *testing.T) {
     definition := TfServiceDefinitionV1{
         Version:     1,
@@ -636,6 +761,7 @@ func TestTfServiceDefinitionV1_ToService(t *testing.T) {
         DocumentationUrl: "https://example.com/docs",
         Plans:            []TfServiceDefinitionV1Plan{},
         RequiredEnvVars: []string{"EXAMPLE_ENV_VAR"},
+        Requires:        []brokerapi.RequiredPermission{brokerapi.PermissionSyslogDrain},
 
         ProvisionSettings: TfServiceDefinitionV1Action{
             PlanInputs: []broker.BrokerVariable{
@@ -712,6 +838,7 @@ func TestTfServiceDefinitionV1_ToService(t *testing.T) {
         expectEqual("SupportUrl", definition.SupportUrl, service.SupportUrl)
         expectEqual("ImageUrl", definition.ImageUrl, service.ImageUrl)
         expectEqual("Tags", definition.Tags, service.Tags)
+        expectEqual("Requires", definition.Requires, service.Requires)
     })
 
     t.Run("vars", func(t *testing.T) {
@@ -748,3 +875,17 @@ func TestTfServiceDefinitionV1_ToService(t *testing.T) {
         }
     })
 }
+
+func TestWithAccountEnv(t *testing.T) {
+	envVars := map[string]string{"GOOGLE_CREDENTIALS": "broker", "GOOGLE_REGION": "us-central1"}
+	merged := withAccountEnv(envVars, map[string]string{"GOOGLE_CREDENTIALS": "prod", "GOOGLE_PROJECT": "prod-project"})
+
+	expected := map[string]string{"GOOGLE_CREDENTIALS": "prod", "GOOGLE_REGION": "us-central1", "GOOGLE_PROJECT": "prod-project"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected env %v got %v", expected, merged)
+	}
+
+	if envVars["GOOGLE_CREDENTIALS"] != "broker" {
+		t.Error("Expected the broker's env to be left unchanged")
+	}
+}
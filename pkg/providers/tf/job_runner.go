@@ -88,11 +88,18 @@ func (runner *TfJobRunner) StageJob(ctx context.Context, jobId string, workspace
 	return runner.operationFinished(nil, workspace, deployment)
 }
 
+// progressMessages describe running jobs by their operation type.
+var progressMessages = map[string]string{
+	models.ProvisionOperationType:   "creating resources",
+	models.UpdateOperationType:      "updating resources",
+	models.DeprovisionOperationType: "destroying resources",
+}
+
 func (runner *TfJobRunner) markJobStarted(ctx context.Context, deployment *models.TerraformDeployment, operationType string) error {
 	// update the deployment info
 	deployment.LastOperationType = operationType
 	deployment.LastOperationState = InProgress
-	deployment.LastOperationMessage = ""
+	deployment.LastOperationMessage = progressMessages[operationType]
 
 	if err := db_service.SaveTerraformDeployment(ctx, deployment); err != nil {
 		return err
@@ -296,6 +303,21 @@ func (runner *TfJobRunner) Status(ctx context.Context, id string) (isDone bool,
 	}
 }
 
+// Progress gets the description of the most recent job on the workspace while
+// it's running.
+func (runner *TfJobRunner) Progress(ctx context.Context, id string) (string, error) {
+	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	if deployment.LastOperationState != InProgress {
+		return "", nil
+	}
+
+	return deployment.LastOperationMessage, nil
+}
+
 // Outputs gets the output variables for the given module instance in the workspace.
 func (runner *TfJobRunner) Outputs(ctx context.Context, id, instanceName string) (map[string]interface{}, error) {
 	deployment, err := db_service.GetTerraformDeploymentById(ctx, id)
@@ -207,6 +207,11 @@ func (provider *terraformProvider) PollInstance(ctx context.Context, instance mo
 	return provider.jobRunner.Status(ctx, generateTfId(instance.ID, ""))
 }
 
+// DescribeOperation returns the progress of the backing job.
+func (provider *terraformProvider) DescribeOperation(ctx context.Context, instance models.ServiceInstanceDetails) (string, error) {
+	return provider.jobRunner.Progress(ctx, generateTfId(instance.ID, ""))
+}
+
 // ProvisionsAsync is always true for Terraformprovider.
 func (provider *terraformProvider) ProvisionsAsync() bool {
 	return true
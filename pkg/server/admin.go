@@ -0,0 +1,493 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+)
+
+// InstanceReconciler re-runs the finalization of an instance's last
+// operation.
+type InstanceReconciler interface {
+	Reconcile(ctx context.Context, instanceID string) (brokerapi.LastOperation, error)
+}
+
+// OperationHistorian returns the operations run on an instance.
+type OperationHistorian interface {
+	OperationHistory(ctx context.Context, instanceID string) ([]models.OperationHistory, error)
+}
+
+// BindingLister returns the bindings of an instance.
+type BindingLister interface {
+	InstanceBindings(ctx context.Context, instanceID string) ([]models.ServiceBindingCredentials, error)
+}
+
+// UnboundBindingLister returns the bindings unbound since the given time that
+// are kept for auditing.
+type UnboundBindingLister interface {
+	UnboundBindings(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error)
+}
+
+// MetadataStore reads and replaces the operator supplied metadata of an
+// instance.
+type MetadataStore interface {
+	InstanceMetadata(ctx context.Context, instanceID string) (map[string]string, error)
+	SetInstanceMetadata(ctx context.Context, instanceID string, metadata map[string]string) error
+}
+
+// OutputReader returns the outputs of an instance with sensitive values
+// masked.
+type OutputReader interface {
+	InstanceOutputs(ctx context.Context, instanceID string) (map[string]interface{}, error)
+}
+
+// DeletionProtectionStore reads and changes whether an instance is protected
+// from being deprovisioned.
+type DeletionProtectionStore interface {
+	DeletionProtection(ctx context.Context, instanceID string) (bool, error)
+	SetDeletionProtection(ctx context.Context, instanceID string, enabled bool) error
+}
+
+// CapabilityReporter reports how a service and its provider behave.
+type CapabilityReporter interface {
+	ServiceCapabilities(ctx context.Context, serviceID string) (broker.ServiceCapabilities, error)
+}
+
+// InstanceAdopter records existing cloud resources as an instance.
+type InstanceAdopter interface {
+	AdoptInstance(ctx context.Context, instanceID string, request broker.AdoptRequest) error
+}
+
+// InstanceCostEstimator prices an instance without provisioning it.
+type InstanceCostEstimator interface {
+	EstimateCost(ctx context.Context, instanceID string, request broker.CostEstimateRequest) (*broker.CostEstimate, error)
+}
+
+// BindingCredentialReissuer restores the Credstore entry of a binding.
+type BindingCredentialReissuer interface {
+	ReissueBindingCredentials(ctx context.Context, instanceID, bindingID string) error
+}
+
+// InstanceAdmin is the broker functionality used by the operator endpoints.
+type InstanceAdmin interface {
+	InstanceReconciler
+	OperationHistorian
+	BindingLister
+	UnboundBindingLister
+	MetadataStore
+	OutputReader
+	DeletionProtectionStore
+	CapabilityReporter
+	InstanceAdopter
+	InstanceCostEstimator
+	BindingCredentialReissuer
+}
+
+// AddAdminHandler adds the operator endpoints to the router. The middleware
+// is applied to every endpoint and should enforce authentication.
+func AddAdminHandler(router *mux.Router, instanceAdmin InstanceAdmin, middleware func(http.Handler) http.Handler, logger lager.Logger) {
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Handle("/instances/{instance_id}/reconcile", middleware(NewReconcileHandler(instanceAdmin, logger))).Methods(http.MethodPost)
+	admin.Handle("/instances/{instance_id}/operations", middleware(NewOperationHistoryHandler(instanceAdmin, logger))).Methods(http.MethodGet)
+	admin.Handle("/instances/{instance_id}/bindings", middleware(NewBindingListHandler(instanceAdmin, logger))).Methods(http.MethodGet)
+	admin.Handle("/instances/{instance_id}/bindings/{binding_id}/reissue", middleware(NewReissueBindingCredentialsHandler(instanceAdmin, logger))).Methods(http.MethodPost)
+	admin.Handle("/instances/{instance_id}/metadata", middleware(NewInstanceMetadataHandler(instanceAdmin, logger))).Methods(http.MethodGet, http.MethodPut)
+	admin.Handle("/instances/{instance_id}/outputs", middleware(NewInstanceOutputsHandler(instanceAdmin, logger))).Methods(http.MethodGet)
+	admin.Handle("/instances/{instance_id}/deletion_protection", middleware(NewDeletionProtectionHandler(instanceAdmin, logger))).Methods(http.MethodGet, http.MethodPut)
+	admin.Handle("/instances/{instance_id}/adopt", middleware(NewAdoptHandler(instanceAdmin, logger))).Methods(http.MethodPost)
+	admin.Handle("/instances/{instance_id}/cost-estimate", middleware(NewCostEstimateHandler(instanceAdmin, logger))).Methods(http.MethodPost)
+	admin.Handle("/bindings/unbound", middleware(NewUnboundBindingListHandler(instanceAdmin, logger))).Methods(http.MethodGet)
+	admin.Handle("/services/{service_id}/capabilities", middleware(NewServiceCapabilitiesHandler(instanceAdmin, logger))).Methods(http.MethodGet)
+}
+
+// CredStoreReloader reloads the auth material of the broker's credstore.
+type CredStoreReloader interface {
+	Reload() error
+}
+
+// AddCredStoreReloadHandler adds the operator endpoint reloading the
+// credstore to the router. The middleware should enforce authentication.
+func AddCredStoreReloadHandler(router *mux.Router, reloader CredStoreReloader, middleware func(http.Handler) http.Handler, logger lager.Logger) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.Session("reload-credstore")
+		if err := reloader.Reload(); err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		logger.Info("reloaded")
+		writeAdminJSON(w, http.StatusOK, struct{}{})
+	})
+
+	router.Handle("/admin/credstore/reload", middleware(handler)).Methods(http.MethodPost)
+}
+
+// NewReconcileHandler returns a handler that reconciles the instance in the
+// instance_id path variable and responds with the resulting operation state.
+func NewReconcileHandler(reconciler InstanceReconciler, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("reconcile", lager.Data{"instance_id": instanceID})
+
+		operation, err := reconciler.Reconcile(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, brokerapi.LastOperationResponse{
+			State:       operation.State,
+			Description: operation.Description,
+		})
+	})
+}
+
+// operationHistoryEntry is the JSON representation of an operation in the
+// history of an instance.
+type operationHistoryEntry struct {
+	Type        string     `json:"type"`
+	OperationID string     `json:"operation_id,omitempty"`
+	State       string     `json:"state"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// NewOperationHistoryHandler returns a handler that responds with the
+// operations run on the instance in the instance_id path variable, oldest
+// first.
+func NewOperationHistoryHandler(historian OperationHistorian, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("operation-history", lager.Data{"instance_id": instanceID})
+
+		history, err := historian.OperationHistory(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		entries := []operationHistoryEntry{}
+		for _, operation := range history {
+			entries = append(entries, operationHistoryEntry{
+				Type:        operation.OperationType,
+				OperationID: operation.OperationId,
+				State:       operation.State,
+				StartedAt:   operation.StartedAt,
+				FinishedAt:  operation.FinishedAt,
+				Error:       operation.Error,
+			})
+		}
+
+		writeAdminJSON(w, http.StatusOK, entries)
+	})
+}
+
+// bindingEntry is the JSON representation of a binding of an instance. It
+// never includes the credentials.
+type bindingEntry struct {
+	BindingID        string    `json:"binding_id"`
+	AppGUID          string    `json:"app_guid,omitempty"`
+	AppName          string    `json:"app_name,omitempty"`
+	SpaceGUID        string    `json:"space_guid,omitempty"`
+	OrganizationGUID string    `json:"organization_guid,omitempty"`
+	Role             string    `json:"role,omitempty"`
+	Name             string    `json:"name,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// NewBindingListHandler returns a handler that responds with the bindings of
+// the instance in the instance_id path variable, oldest first. Service keys
+// have no app_guid or app_name.
+func NewBindingListHandler(lister BindingLister, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("list-bindings", lager.Data{"instance_id": instanceID})
+
+		bindings, err := lister.InstanceBindings(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		entries := []bindingEntry{}
+		for _, binding := range bindings {
+			entries = append(entries, bindingEntry{
+				BindingID:        binding.BindingId,
+				AppGUID:          binding.AppGuid,
+				AppName:          binding.AppName,
+				SpaceGUID:        binding.SpaceGuid,
+				OrganizationGUID: binding.OrganizationGuid,
+				Role:             binding.Role,
+				Name:             binding.Name,
+				CreatedAt:        binding.CreatedAt,
+			})
+		}
+
+		writeAdminJSON(w, http.StatusOK, entries)
+	})
+}
+
+// unboundBindingEntry is the JSON representation of an unbound binding. It
+// never includes the credentials.
+type unboundBindingEntry struct {
+	InstanceID string `json:"instance_id"`
+	bindingEntry
+	UnboundAt time.Time `json:"unbound_at"`
+}
+
+// NewUnboundBindingListHandler returns a handler that responds with the
+// bindings unbound since the RFC 3339 time in the optional since query
+// parameter that weren't purged yet, most recently unbound first.
+func NewUnboundBindingListHandler(lister UnboundBindingLister, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := logger.Session("list-unbound-bindings")
+
+		since := time.Time{}
+		if value := r.URL.Query().Get("since"); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeAdminError(w, brokerapi.NewFailureResponse(fmt.Errorf("since must be an RFC 3339 time, got %q", value), http.StatusBadRequest, "invalid-since"), logger)
+				return
+			}
+			since = parsed
+		}
+
+		bindings, err := lister.UnboundBindings(r.Context(), since)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		entries := []unboundBindingEntry{}
+		for _, binding := range bindings {
+			entry := unboundBindingEntry{
+				InstanceID: binding.ServiceInstanceId,
+				bindingEntry: bindingEntry{
+					BindingID:        binding.BindingId,
+					AppGUID:          binding.AppGuid,
+					AppName:          binding.AppName,
+					SpaceGUID:        binding.SpaceGuid,
+					OrganizationGUID: binding.OrganizationGuid,
+					Role:             binding.Role,
+					Name:             binding.Name,
+					CreatedAt:        binding.CreatedAt,
+				},
+			}
+			if binding.DeletedAt != nil {
+				entry.UnboundAt = *binding.DeletedAt
+			}
+			entries = append(entries, entry)
+		}
+
+		writeAdminJSON(w, http.StatusOK, entries)
+	})
+}
+
+// NewReissueBindingCredentialsHandler returns a handler that puts the
+// credentials of the binding in the binding_id path variable back in the
+// Credstore.
+func NewReissueBindingCredentialsHandler(reissuer BindingCredentialReissuer, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		bindingID := mux.Vars(r)["binding_id"]
+		logger := logger.Session("reissue-binding-credentials", lager.Data{"instance_id": instanceID, "binding_id": bindingID})
+
+		if err := reissuer.ReissueBindingCredentials(r.Context(), instanceID, bindingID); err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, struct{}{})
+	})
+}
+
+// NewInstanceMetadataHandler returns a handler that responds with the metadata
+// of the instance in the instance_id path variable. PUT requests replace the
+// metadata with the JSON object of strings in the body first.
+func NewInstanceMetadataHandler(store MetadataStore, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("instance-metadata", lager.Data{"instance_id": instanceID, "method": r.Method})
+
+		if r.Method == http.MethodPut {
+			metadata := map[string]string{}
+			if err := json.NewDecoder(r.Body).Decode(&metadata); err != nil {
+				writeAdminError(w, brokerapi.NewFailureResponse(fmt.Errorf("metadata must be a JSON object of strings: %s", err), http.StatusBadRequest, "invalid-metadata"), logger)
+				return
+			}
+
+			if err := store.SetInstanceMetadata(r.Context(), instanceID, metadata); err != nil {
+				writeAdminError(w, err, logger)
+				return
+			}
+		}
+
+		metadata, err := store.InstanceMetadata(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, metadata)
+	})
+}
+
+// NewInstanceOutputsHandler returns a handler that responds with the outputs
+// of the instance in the instance_id path variable. Sensitive outputs are
+// masked by the reader.
+func NewInstanceOutputsHandler(reader OutputReader, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("instance-outputs", lager.Data{"instance_id": instanceID})
+
+		outputs, err := reader.InstanceOutputs(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, outputs)
+	})
+}
+
+// deletionProtectionBody is the body of the deletion protection endpoint.
+type deletionProtectionBody struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// NewDeletionProtectionHandler returns a handler that responds with whether
+// the instance in the instance_id path variable is protected from being
+// deprovisioned. PUT requests enable or disable the protection first.
+func NewDeletionProtectionHandler(store DeletionProtectionStore, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("deletion-protection", lager.Data{"instance_id": instanceID, "method": r.Method})
+
+		if r.Method == http.MethodPut {
+			body := deletionProtectionBody{}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Enabled == nil {
+				writeAdminError(w, brokerapi.NewFailureResponse(errors.New(`body must be a JSON object with a boolean "enabled"`), http.StatusBadRequest, "invalid-deletion-protection"), logger)
+				return
+			}
+
+			if err := store.SetDeletionProtection(r.Context(), instanceID, *body.Enabled); err != nil {
+				writeAdminError(w, err, logger)
+				return
+			}
+		}
+
+		enabled, err := store.DeletionProtection(r.Context(), instanceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, deletionProtectionBody{Enabled: &enabled})
+	})
+}
+
+// NewAdoptHandler returns a handler that records the existing resources
+// described by the broker.AdoptRequest in the body as the instance in the
+// instance_id path variable.
+func NewAdoptHandler(adopter InstanceAdopter, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("adopt", lager.Data{"instance_id": instanceID})
+
+		request := broker.AdoptRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeAdminError(w, brokerapi.NewFailureResponse(fmt.Errorf("invalid request body: %s", err), http.StatusBadRequest, "invalid-adopt-request"), logger)
+			return
+		}
+
+		if err := adopter.AdoptInstance(r.Context(), instanceID, request); err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusCreated, struct{}{})
+	})
+}
+
+// NewCostEstimateHandler returns a handler that responds with the estimated
+// cost of provisioning the instance in the instance_id path variable as
+// described by the broker.CostEstimateRequest in the body. Nothing is
+// provisioned.
+func NewCostEstimateHandler(estimator InstanceCostEstimator, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		instanceID := mux.Vars(r)["instance_id"]
+		logger := logger.Session("cost-estimate", lager.Data{"instance_id": instanceID})
+
+		request := broker.CostEstimateRequest{}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeAdminError(w, brokerapi.NewFailureResponse(fmt.Errorf("invalid request body: %s", err), http.StatusBadRequest, "invalid-cost-estimate-request"), logger)
+			return
+		}
+
+		estimate, err := estimator.EstimateCost(r.Context(), instanceID, request)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, estimate)
+	})
+}
+
+// NewServiceCapabilitiesHandler returns a handler that responds with the
+// capabilities of the service in the service_id path variable.
+func NewServiceCapabilitiesHandler(reporter CapabilityReporter, logger lager.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serviceID := mux.Vars(r)["service_id"]
+		logger := logger.Session("service-capabilities", lager.Data{"service_id": serviceID})
+
+		capabilities, err := reporter.ServiceCapabilities(r.Context(), serviceID)
+		if err != nil {
+			writeAdminError(w, err, logger)
+			return
+		}
+
+		writeAdminJSON(w, http.StatusOK, capabilities)
+	})
+}
+
+func writeAdminError(w http.ResponseWriter, err error, logger lager.Logger) {
+	logger.Error("failed", err)
+
+	if failure, ok := err.(*brokerapi.FailureResponse); ok {
+		writeAdminJSON(w, failure.ValidatedStatusCode(logger), failure.ErrorResponse())
+		return
+	}
+
+	writeAdminJSON(w, http.StatusInternalServerError, brokerapi.ErrorResponse{Description: err.Error()})
+}
+
+func writeAdminJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
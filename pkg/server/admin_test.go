@@ -0,0 +1,806 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal-cf/brokerapi/auth"
+	"github.com/pivotal/cloud-service-broker/db_service/models"
+	"github.com/pivotal/cloud-service-broker/pkg/broker"
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+type fakeInstanceAdmin struct {
+	instanceID   string
+	operation    brokerapi.LastOperation
+	history      []models.OperationHistory
+	bindings     []models.ServiceBindingCredentials
+	unbound      []models.ServiceBindingCredentials
+	since        time.Time
+	metadata     map[string]string
+	outputs      map[string]interface{}
+	protected    bool
+	capabilities broker.ServiceCapabilities
+	adopted      broker.AdoptRequest
+	estimated    broker.CostEstimateRequest
+	estimate     *broker.CostEstimate
+	bindingID    string
+	err          error
+}
+
+func (f *fakeInstanceAdmin) ReissueBindingCredentials(ctx context.Context, instanceID, bindingID string) error {
+	f.instanceID = instanceID
+	f.bindingID = bindingID
+	return f.err
+}
+
+func (f *fakeInstanceAdmin) EstimateCost(ctx context.Context, instanceID string, request broker.CostEstimateRequest) (*broker.CostEstimate, error) {
+	f.instanceID = instanceID
+	f.estimated = request
+	return f.estimate, f.err
+}
+
+func (f *fakeInstanceAdmin) AdoptInstance(ctx context.Context, instanceID string, request broker.AdoptRequest) error {
+	f.instanceID = instanceID
+	f.adopted = request
+	return f.err
+}
+
+func (f *fakeInstanceAdmin) ServiceCapabilities(ctx context.Context, serviceID string) (broker.ServiceCapabilities, error) {
+	f.instanceID = serviceID
+	return f.capabilities, f.err
+}
+
+func (f *fakeInstanceAdmin) InstanceMetadata(ctx context.Context, instanceID string) (map[string]string, error) {
+	f.instanceID = instanceID
+	return f.metadata, f.err
+}
+
+func (f *fakeInstanceAdmin) SetInstanceMetadata(ctx context.Context, instanceID string, metadata map[string]string) error {
+	f.instanceID = instanceID
+	f.metadata = metadata
+	return f.err
+}
+
+func (f *fakeInstanceAdmin) InstanceOutputs(ctx context.Context, instanceID string) (map[string]interface{}, error) {
+	f.instanceID = instanceID
+	return f.outputs, f.err
+}
+
+func (f *fakeInstanceAdmin) DeletionProtection(ctx context.Context, instanceID string) (bool, error) {
+	f.instanceID = instanceID
+	return f.protected, f.err
+}
+
+func (f *fakeInstanceAdmin) SetDeletionProtection(ctx context.Context, instanceID string, enabled bool) error {
+	f.instanceID = instanceID
+	f.protected = enabled
+	return f.err
+}
+
+func (f *fakeInstanceAdmin) InstanceBindings(ctx context.Context, instanceID string) ([]models.ServiceBindingCredentials, error) {
+	f.instanceID = instanceID
+	return f.bindings, f.err
+}
+
+func (f *fakeInstanceAdmin) UnboundBindings(ctx context.Context, since time.Time) ([]models.ServiceBindingCredentials, error) {
+	f.since = since
+	return f.unbound, f.err
+}
+
+func (f *fakeInstanceAdmin) OperationHistory(ctx context.Context, instanceID string) ([]models.OperationHistory, error) {
+	f.instanceID = instanceID
+	return f.history, f.err
+}
+
+func (f *fakeInstanceAdmin) Reconcile(ctx context.Context, instanceID string) (brokerapi.LastOperation, error) {
+	f.instanceID = instanceID
+	return f.operation, f.err
+}
+
+func TestAddAdminHandler(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Authorized     bool
+		Reconciler     fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"reconciled": {
+			Method:         http.MethodPost,
+			Authorized:     true,
+			Reconciler:     fakeInstanceAdmin{operation: brokerapi.LastOperation{State: brokerapi.Succeeded}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"state":"succeeded"}`,
+		},
+		"in progress": {
+			Method:         http.MethodPost,
+			Authorized:     true,
+			Reconciler:     fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("still running"), http.StatusConflict, "operation-in-progress")},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedBody:   `{"description":"still running"}`,
+		},
+		"unexpected error": {
+			Method:         http.MethodPost,
+			Authorized:     true,
+			Reconciler:     fakeInstanceAdmin{err: errors.New("db down")},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `{"description":"db down"}`,
+		},
+		"unauthorized": {
+			Method:         http.MethodPost,
+			Authorized:     false,
+			ExpectedStatus: http.StatusUnauthorized,
+		},
+		"wrong method": {
+			Method:         http.MethodGet,
+			Authorized:     true,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Reconciler, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(tc.Method, "/admin/instances/my-instance/reconcile", nil)
+			if tc.Authorized {
+				req.SetBasicAuth("user", "pass")
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK && tc.Reconciler.instanceID != "my-instance" {
+				t.Errorf("Expected instance my-instance to be reconciled, got %q", tc.Reconciler.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_OperationHistory(t *testing.T) {
+	started := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	finished := started.Add(time.Minute)
+
+	cases := map[string]struct {
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"history": {
+			Admin: fakeInstanceAdmin{history: []models.OperationHistory{
+				{OperationType: "provision", State: "succeeded", StartedAt: started, FinishedAt: &finished},
+				{OperationType: "deprovision", OperationId: "op-1", State: "failed", StartedAt: started, FinishedAt: &finished, Error: "quota exceeded"},
+				{OperationType: "deprovision", OperationId: "op-2", State: "in progress", StartedAt: finished},
+			}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: `[{"type":"provision","state":"succeeded","started_at":"2020-03-01T12:00:00Z","finished_at":"2020-03-01T12:01:00Z"},` +
+				`{"type":"deprovision","operation_id":"op-1","state":"failed","started_at":"2020-03-01T12:00:00Z","finished_at":"2020-03-01T12:01:00Z","error":"quota exceeded"},` +
+				`{"type":"deprovision","operation_id":"op-2","state":"in progress","started_at":"2020-03-01T12:01:00Z"}]`,
+		},
+		"no history": {
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[]`,
+		},
+		"unexpected error": {
+			Admin:          fakeInstanceAdmin{err: errors.New("db down")},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `{"description":"db down"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/instances/my-instance/operations", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.Admin.instanceID != "my-instance" {
+				t.Errorf("Expected history of instance my-instance, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_Bindings(t *testing.T) {
+	created := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	appBinding := models.ServiceBindingCredentials{BindingId: "app-binding", AppGuid: "app-guid", AppName: "my-app", SpaceGuid: "space-guid", OrganizationGuid: "org-guid", Role: "reader", Name: "reporting", OtherDetails: `{"password":"secret"}`}
+	appBinding.CreatedAt = created
+	serviceKey := models.ServiceBindingCredentials{BindingId: "service-key", OtherDetails: `{"password":"secret"}`}
+	serviceKey.CreatedAt = created
+
+	cases := map[string]struct {
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"bindings": {
+			Admin:          fakeInstanceAdmin{bindings: []models.ServiceBindingCredentials{appBinding, serviceKey}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: `[{"binding_id":"app-binding","app_guid":"app-guid","app_name":"my-app","space_guid":"space-guid","organization_guid":"org-guid","role":"reader","name":"reporting","created_at":"2020-03-01T12:00:00Z"},` +
+				`{"binding_id":"service-key","created_at":"2020-03-01T12:00:00Z"}]`,
+		},
+		"no bindings": {
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[]`,
+		},
+		"missing instance": {
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("instance does not exist"), http.StatusNotFound, "instance-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"instance does not exist"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/instances/my-instance/bindings", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.Admin.instanceID != "my-instance" {
+				t.Errorf("Expected bindings of instance my-instance, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_UnboundBindings(t *testing.T) {
+	created := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	unbound := time.Date(2020, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	appBinding := models.ServiceBindingCredentials{ServiceInstanceId: "my-instance", BindingId: "app-binding", AppGuid: "app-guid", AppName: "my-app", OtherDetails: `{"password":"secret"}`}
+	appBinding.CreatedAt = created
+	appBinding.DeletedAt = &unbound
+
+	cases := map[string]struct {
+		Admin          fakeInstanceAdmin
+		Query          string
+		ExpectedStatus int
+		ExpectedBody   string
+		ExpectedSince  time.Time
+	}{
+		"unbound bindings": {
+			Admin:          fakeInstanceAdmin{unbound: []models.ServiceBindingCredentials{appBinding}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[{"instance_id":"my-instance","binding_id":"app-binding","app_guid":"app-guid","app_name":"my-app","created_at":"2020-03-01T12:00:00Z","unbound_at":"2020-03-02T12:00:00Z"}]`,
+		},
+		"since": {
+			Query:          "?since=2020-03-02T00:00:00Z",
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `[]`,
+			ExpectedSince:  time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"invalid since": {
+			Query:          "?since=yesterday",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `{"description":"since must be an RFC 3339 time, got \"yesterday\""}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/bindings/unbound"+tc.Query, nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if !tc.Admin.since.Equal(tc.ExpectedSince) {
+				t.Errorf("Expected bindings unbound since %v, got %v", tc.ExpectedSince, tc.Admin.since)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_Metadata(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Body           string
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"get": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{metadata: map[string]string{"owner": "team-a"}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"owner":"team-a"}`,
+		},
+		"put": {
+			Method:         http.MethodPut,
+			Body:           `{"cost_center":"42"}`,
+			Admin:          fakeInstanceAdmin{metadata: map[string]string{"owner": "team-a"}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"cost_center":"42"}`,
+		},
+		"put non-string values": {
+			Method:         http.MethodPut,
+			Body:           `{"cost_center":42}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"missing instance": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("instance does not exist"), http.StatusNotFound, "instance-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"instance does not exist"}`,
+		},
+		"wrong method": {
+			Method:         http.MethodPost,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(tc.Method, "/admin/instances/my-instance/metadata", strings.NewReader(tc.Body))
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK && tc.Admin.instanceID != "my-instance" {
+				t.Errorf("Expected metadata of instance my-instance, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_Outputs(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"get": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{outputs: map[string]interface{}{"host": "db.example.com", "admin_password": broker.MaskedOutputValue}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"admin_password":"[REDACTED]","host":"db.example.com"}`,
+		},
+		"missing instance": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("instance does not exist"), http.StatusNotFound, "instance-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"instance does not exist"}`,
+		},
+		"wrong method": {
+			Method:         http.MethodPut,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(tc.Method, "/admin/instances/my-instance/outputs", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK && tc.Admin.instanceID != "my-instance" {
+				t.Errorf("Expected outputs of instance my-instance, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_DeletionProtection(t *testing.T) {
+	cases := map[string]struct {
+		Method         string
+		Body           string
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"get": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{protected: true},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"enabled":true}`,
+		},
+		"enable": {
+			Method:         http.MethodPut,
+			Body:           `{"enabled":true}`,
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"enabled":true}`,
+		},
+		"disable": {
+			Method:         http.MethodPut,
+			Body:           `{"enabled":false}`,
+			Admin:          fakeInstanceAdmin{protected: true},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"enabled":false}`,
+		},
+		"put without enabled": {
+			Method:         http.MethodPut,
+			Body:           `{}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"put non-boolean": {
+			Method:         http.MethodPut,
+			Body:           `{"enabled":"yes"}`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"missing instance": {
+			Method:         http.MethodGet,
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("instance does not exist"), http.StatusNotFound, "instance-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"instance does not exist"}`,
+		},
+		"wrong method": {
+			Method:         http.MethodPost,
+			ExpectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(tc.Method, "/admin/instances/my-instance/deletion_protection", strings.NewReader(tc.Body))
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK && tc.Admin.instanceID != "my-instance" {
+				t.Errorf("Expected deletion protection of instance my-instance, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_Capabilities(t *testing.T) {
+	cases := map[string]struct {
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"capabilities": {
+			Admin: fakeInstanceAdmin{capabilities: broker.ServiceCapabilities{
+				ServiceID:            "my-service",
+				ServiceName:          "csb-db",
+				ProvisionsAsync:      true,
+				DeprovisionsAsync:    true,
+				Bindable:             true,
+				UpdatableParameters:  []string{"tier"},
+				RecreateParameters:   []string{},
+				ProhibitedParameters: []string{"region"},
+				Hooks:                broker.ProviderHooks{"describe_operation": true, "estimate_cost": false},
+			}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: `{"service_id":"my-service","service_name":"csb-db","provisions_async":true,"deprovisions_async":true,"bindable":true,"plan_updateable":false,` +
+				`"updatable_parameters":["tier"],"recreate_parameters":[],"prohibited_parameters":["region"],"hooks":{"describe_operation":true,"estimate_cost":false}}`,
+		},
+		"missing service": {
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New(`Unknown service ID: "my-service"`), http.StatusNotFound, "service-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"Unknown service ID: \"my-service\""}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/services/my-service/capabilities", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.Admin.instanceID != "my-service" {
+				t.Errorf("Expected capabilities of service my-service, got %q", tc.Admin.instanceID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_Adopt(t *testing.T) {
+	cases := map[string]struct {
+		Body           string
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"adopted": {
+			Body:           `{"service_id":"my-service","plan_id":"my-plan","resources":{"bucket":"existing-bucket"}}`,
+			ExpectedStatus: http.StatusCreated,
+			ExpectedBody:   `{}`,
+		},
+		"invalid body": {
+			Body:           `{"service_id":`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"resources not found": {
+			Body:           `{"service_id":"my-service","plan_id":"my-plan","resources":{"bucket":"missing-bucket"}}`,
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("not found"), http.StatusUnprocessableEntity, "resources-not-found")},
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedBody:   `{"description":"not found"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/instances/my-instance/adopt", strings.NewReader(tc.Body))
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusCreated && tc.Admin.adopted.Resources["bucket"] != "existing-bucket" {
+				t.Errorf("Expected the request to be passed on, got %+v", tc.Admin.adopted)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_ReissueBindingCredentials(t *testing.T) {
+	cases := map[string]struct {
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"reissued": {
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{}`,
+		},
+		"binding not found": {
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("binding does not exist"), http.StatusNotFound, "binding-not-found")},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedBody:   `{"description":"binding does not exist"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/instances/my-instance/bindings/my-binding/reissue", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.Admin.instanceID != "my-instance" || tc.Admin.bindingID != "my-binding" {
+				t.Errorf("Expected the binding to be passed on, got %q %q", tc.Admin.instanceID, tc.Admin.bindingID)
+			}
+		})
+	}
+}
+
+func TestAddAdminHandler_CostEstimate(t *testing.T) {
+	cases := map[string]struct {
+		Body           string
+		Admin          fakeInstanceAdmin
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"estimated": {
+			Body: `{"service_id":"my-service","plan_id":"my-plan","parameters":{"disk_gb":100}}`,
+			Admin: fakeInstanceAdmin{estimate: &broker.CostEstimate{
+				Costs:  []broker.PlanCost{{Amount: 12.5, Currency: "USD", Unit: "MONTHLY"}},
+				Source: broker.CostEstimateSourceProvider,
+			}},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{"costs":[{"amount":12.5,"currency":"USD","unit":"MONTHLY"}],"source":"provider"}`,
+		},
+		"invalid body": {
+			Body:           `{"service_id":`,
+			ExpectedStatus: http.StatusBadRequest,
+		},
+		"invalid parameters": {
+			Body:           `{"service_id":"my-service","plan_id":"my-plan","parameters":{"disk_gb":"big"}}`,
+			Admin:          fakeInstanceAdmin{err: brokerapi.NewFailureResponse(errors.New("disk_gb: invalid type"), http.StatusBadRequest, "invalid-parameters")},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedBody:   `{"description":"disk_gb: invalid type"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddAdminHandler(router, &tc.Admin, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/instances/my-instance/cost-estimate", strings.NewReader(tc.Body))
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); tc.ExpectedBody != "" && body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.ExpectedStatus == http.StatusOK && (tc.Admin.instanceID != "my-instance" || tc.Admin.estimated.PlanID != "my-plan") {
+				t.Errorf("Expected the request to be passed on, got %+v", tc.Admin.estimated)
+			}
+		})
+	}
+}
+
+type fakeReloader struct {
+	calls int
+	err   error
+}
+
+func (f *fakeReloader) Reload() error {
+	f.calls++
+	return f.err
+}
+
+func TestAddCredStoreReloadHandler(t *testing.T) {
+	cases := map[string]struct {
+		Reloader       fakeReloader
+		ExpectedStatus int
+		ExpectedBody   string
+	}{
+		"reloaded": {
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody:   `{}`,
+		},
+		"invalid config": {
+			Reloader:       fakeReloader{err: errors.New("bad certificate")},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedBody:   `{"description":"bad certificate"}`,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			router := mux.NewRouter()
+			AddCredStoreReloadHandler(router, &tc.Reloader, auth.NewWrapper("user", "pass").Wrap, utils.NewLogger("admin-test"))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/credstore/reload", nil)
+			req.SetBasicAuth("user", "pass")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.ExpectedStatus {
+				t.Errorf("Expected response code: %d got: %d", tc.ExpectedStatus, w.Code)
+			}
+
+			if body := strings.TrimSpace(w.Body.String()); body != tc.ExpectedBody {
+				t.Errorf("Expected body: %s got: %s", tc.ExpectedBody, body)
+			}
+
+			if tc.Reloader.calls != 1 {
+				t.Errorf("Expected 1 reload, got %d", tc.Reloader.calls)
+			}
+		})
+	}
+}
@@ -15,16 +15,29 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/heptiolabs/healthcheck"
 )
 
+// ServiceHealthReporter reports the health of the backend of each service
+// that can check it, by service name.
+type ServiceHealthReporter interface {
+	ServiceHealth(ctx context.Context) map[string]error
+}
+
 // AddHealthHandler creates a new handler for health and liveness checks and
-// adds it to the /live and /ready endpoints.
-func AddHealthHandler(router *mux.Router, db *sql.DB) healthcheck.Handler {
+// adds it to the /live and /ready endpoints. If services is set, the full
+// readiness response also reports the health of each service's backend.
+// Unhealthy backends don't make the broker unready, so the other services
+// keep working.
+func AddHealthHandler(router *mux.Router, db *sql.DB, services ServiceHealthReporter) healthcheck.Handler {
 	health := healthcheck.NewHandler()
 
 	if db != nil {
@@ -32,7 +45,61 @@ func AddHealthHandler(router *mux.Router, db *sql.DB) healthcheck.Handler {
 	}
 
 	router.HandleFunc("/live", health.LiveEndpoint)
-	router.HandleFunc("/ready", health.ReadyEndpoint)
+	router.HandleFunc("/ready", readyEndpoint(health, services))
 
 	return health
 }
+
+// readyEndpoint adds the health of the services to the full response of the
+// readiness endpoint under the services key.
+func readyEndpoint(health healthcheck.Handler, services ServiceHealthReporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if services == nil || r.Method != http.MethodGet || r.URL.Query().Get("full") != "1" {
+			health.ReadyEndpoint(w, r)
+			return
+		}
+
+		recorder := &bufferedResponse{header: w.Header(), status: http.StatusOK}
+		health.ReadyEndpoint(recorder, r)
+
+		response := make(map[string]interface{})
+		if err := json.Unmarshal(recorder.body.Bytes(), &response); err != nil {
+			w.WriteHeader(recorder.status)
+			w.Write(recorder.body.Bytes())
+			return
+		}
+
+		serviceResults := make(map[string]string)
+		for name, err := range services.ServiceHealth(r.Context()) {
+			serviceResults[name] = "OK"
+			if err != nil {
+				serviceResults[name] = err.Error()
+			}
+		}
+		response["services"] = serviceResults
+
+		w.WriteHeader(recorder.status)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		encoder.Encode(response)
+	}
+}
+
+// bufferedResponse holds a response so it can be amended before it's sent.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	b.status = status
+}
@@ -15,6 +15,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -42,6 +43,7 @@ func TestNewHealthHandler(t *testing.T) {
 		ExpectedBody   string
 		LiveErr        error
 		ReadyErr       error
+		Services       ServiceHealthReporter
 	}{
 		"live endpoint full": {
 			Endpoint:       "/live?full=1",
@@ -81,12 +83,24 @@ func TestNewHealthHandler(t *testing.T) {
 			ExpectedBody:   `{"database":"OK","test-live":"OK","test-ready":"bad-value"}`,
 			ReadyErr:       errors.New("bad-value"),
 		},
+		"ready endpoint full with services": {
+			Endpoint:       "/ready?full=1",
+			ExpectedStatus: 200,
+			ExpectedBody:   `{"database":"OK","services":{"healthy":"OK","unhealthy":"api down"},"test-live":"OK","test-ready":"OK"}`,
+			Services:       fakeServiceHealth{"healthy": nil, "unhealthy": errors.New("api down")},
+		},
+		"ready endpoint minimal with services": {
+			Endpoint:       "/ready",
+			ExpectedStatus: 200,
+			ExpectedBody:   `{}`,
+			Services:       fakeServiceHealth{"unhealthy": errors.New("api down")},
+		},
 	}
 
 	for tn, tc := range cases {
 		t.Run(tn, func(t *testing.T) {
 			router := mux.NewRouter()
-			handler := AddHealthHandler(router, db.DB())
+			handler := AddHealthHandler(router, db.DB(), tc.Services)
 			handler.AddLivenessCheck("test-live", func() error {
 				return tc.LiveErr
 			})
@@ -113,3 +127,9 @@ func TestNewHealthHandler(t *testing.T) {
 		})
 	}
 }
+
+type fakeServiceHealth map[string]error
+
+func (f fakeServiceHealth) ServiceHealth(ctx context.Context) map[string]error {
+	return f
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/jinzhu/gorm"
+)
+
+func TestAddMetricsHandler(t *testing.T) {
+	db, err := gorm.Open("sqlite3", "metrics-test.db")
+	if err != nil {
+		t.Fatalf("couldn't create database: %v", err)
+	}
+	defer os.Remove("metrics-test.db")
+	defer db.Close()
+	db.DB().SetMaxOpenConns(7)
+
+	router := mux.NewRouter()
+	AddMetricsHandler(router, db.DB())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected response code: %d got: %d", http.StatusOK, w.Code)
+	}
+
+	for _, expected := range []string{
+		"csb_db_max_open_connections 7",
+		"csb_db_open_connections ",
+		"csb_db_in_use_connections ",
+		"csb_db_idle_connections ",
+		"csb_db_wait_count_total ",
+		"csb_db_wait_duration_seconds_total ",
+	} {
+		if !strings.Contains(w.Body.String(), expected) {
+			t.Errorf("Expected metrics to contain %q, got: %s", expected, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// ServeAndDrain serves HTTP requests on the listener until a value is
+// received on stop. It then stops accepting new connections and waits up to
+// drainTimeout for in-flight requests to finish before returning.
+//
+// Callers should only release resources used by the handlers, like the
+// database, after this function returns.
+func ServeAndDrain(srv *http.Server, listener net.Listener, stop <-chan os.Signal, drainTimeout time.Duration, logger lager.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case sig := <-stop:
+		logger.Info("draining", lager.Data{"signal": sig.String(), "timeout": drainTimeout.String()})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Error("drain-incomplete", err)
+		return err
+	}
+
+	logger.Info("drained")
+	return nil
+}
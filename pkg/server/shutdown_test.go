@@ -0,0 +1,99 @@
+// Copyright 2020 Pivotal Software, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pivotal/cloud-service-broker/utils"
+)
+
+func TestServeAndDrain(t *testing.T) {
+	cases := map[string]struct {
+		DrainTimeout time.Duration
+		ReleaseAfter time.Duration
+		ExpectedErr  error
+		ExpectedCode int
+	}{
+		"in-flight request finishes": {
+			DrainTimeout: 5 * time.Second,
+			ReleaseAfter: 100 * time.Millisecond,
+			ExpectedErr:  nil,
+			ExpectedCode: http.StatusOK,
+		},
+		"deadline exceeded": {
+			DrainTimeout: 50 * time.Millisecond,
+			ReleaseAfter: 500 * time.Millisecond,
+			ExpectedErr:  context.DeadlineExceeded,
+			ExpectedCode: http.StatusOK,
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.WriteHeader(http.StatusOK)
+			})}
+
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			stop := make(chan os.Signal, 1)
+			drained := make(chan error, 1)
+			go func() {
+				drained <- ServeAndDrain(srv, listener, stop, tc.DrainTimeout, utils.NewLogger("shutdown-test"))
+			}()
+
+			responses := make(chan int, 1)
+			go func() {
+				resp, err := http.Get("http://" + listener.Addr().String())
+				if err != nil {
+					responses <- 0
+					return
+				}
+				resp.Body.Close()
+				responses <- resp.StatusCode
+			}()
+
+			<-started
+			stop <- syscall.SIGTERM
+			time.AfterFunc(tc.ReleaseAfter, func() { close(release) })
+
+			if err := <-drained; err != tc.ExpectedErr {
+				t.Errorf("expected drain error %v, got %v", tc.ExpectedErr, err)
+			}
+
+			if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+				t.Error("expected new connections to be refused after draining")
+			}
+
+			if code := <-responses; code != tc.ExpectedCode {
+				t.Errorf("expected in-flight request to get %d, got %d", tc.ExpectedCode, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+
+	"github.com/pivotal-cf/brokerapi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// BrokerWrapper starts a span covering each OSB operation of the wrapped
+// broker. Spans created while handling the operation, e.g. around provider
+// and database calls, are its children.
+type BrokerWrapper struct {
+	wrapped brokerapi.ServiceBroker
+}
+
+var _ brokerapi.ServiceBroker = (*BrokerWrapper)(nil)
+
+// NewBrokerWrapper wraps the broker to trace its operations.
+func NewBrokerWrapper(wrapped brokerapi.ServiceBroker) brokerapi.ServiceBroker {
+	return &BrokerWrapper{wrapped: wrapped}
+}
+
+func startOperation(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx, span := StartSpan(ctx, "osb."+name, attributes...)
+	span.SetAttributes(attribute.String("osb.operation", name))
+	return ctx, span
+}
+
+// Services implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Services(ctx context.Context) (services []brokerapi.Service, err error) {
+	ctx, span := startOperation(ctx, "Services")
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Services(ctx)
+}
+
+// Provision implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Provision(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, asyncAllowed bool) (spec brokerapi.ProvisionedServiceSpec, err error) {
+	ctx, span := startOperation(ctx, "Provision",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.service_id", details.ServiceID),
+		attribute.String("osb.plan_id", details.PlanID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Provision(ctx, instanceID, details, asyncAllowed)
+}
+
+// Deprovision implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Deprovision(ctx context.Context, instanceID string, details brokerapi.DeprovisionDetails, asyncAllowed bool) (spec brokerapi.DeprovisionServiceSpec, err error) {
+	ctx, span := startOperation(ctx, "Deprovision",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.service_id", details.ServiceID),
+		attribute.String("osb.plan_id", details.PlanID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Deprovision(ctx, instanceID, details, asyncAllowed)
+}
+
+// GetInstance implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) GetInstance(ctx context.Context, instanceID string) (spec brokerapi.GetInstanceDetailsSpec, err error) {
+	ctx, span := startOperation(ctx, "GetInstance", attribute.String("osb.instance_id", instanceID))
+	defer func() { End(span, err) }()
+
+	return w.wrapped.GetInstance(ctx, instanceID)
+}
+
+// Update implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Update(ctx context.Context, instanceID string, details brokerapi.UpdateDetails, asyncAllowed bool) (spec brokerapi.UpdateServiceSpec, err error) {
+	ctx, span := startOperation(ctx, "Update",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.service_id", details.ServiceID),
+		attribute.String("osb.plan_id", details.PlanID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Update(ctx, instanceID, details, asyncAllowed)
+}
+
+// LastOperation implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) LastOperation(ctx context.Context, instanceID string, details brokerapi.PollDetails) (op brokerapi.LastOperation, err error) {
+	ctx, span := startOperation(ctx, "LastOperation",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.operation_data", details.OperationData),
+	)
+	defer func() {
+		span.SetAttributes(attribute.String("osb.state", string(op.State)))
+		End(span, err)
+	}()
+
+	return w.wrapped.LastOperation(ctx, instanceID, details)
+}
+
+// Bind implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Bind(ctx context.Context, instanceID, bindingID string, details brokerapi.BindDetails, asyncAllowed bool) (binding brokerapi.Binding, err error) {
+	ctx, span := startOperation(ctx, "Bind",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.binding_id", bindingID),
+		attribute.String("osb.service_id", details.ServiceID),
+		attribute.String("osb.plan_id", details.PlanID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Bind(ctx, instanceID, bindingID, details, asyncAllowed)
+}
+
+// Unbind implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) Unbind(ctx context.Context, instanceID, bindingID string, details brokerapi.UnbindDetails, asyncAllowed bool) (spec brokerapi.UnbindSpec, err error) {
+	ctx, span := startOperation(ctx, "Unbind",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.binding_id", bindingID),
+		attribute.String("osb.service_id", details.ServiceID),
+		attribute.String("osb.plan_id", details.PlanID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.Unbind(ctx, instanceID, bindingID, details, asyncAllowed)
+}
+
+// GetBinding implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) GetBinding(ctx context.Context, instanceID, bindingID string) (spec brokerapi.GetBindingSpec, err error) {
+	ctx, span := startOperation(ctx, "GetBinding",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.binding_id", bindingID),
+	)
+	defer func() { End(span, err) }()
+
+	return w.wrapped.GetBinding(ctx, instanceID, bindingID)
+}
+
+// LastBindingOperation implements brokerapi.ServiceBroker.
+func (w *BrokerWrapper) LastBindingOperation(ctx context.Context, instanceID, bindingID string, details brokerapi.PollDetails) (op brokerapi.LastOperation, err error) {
+	ctx, span := startOperation(ctx, "LastBindingOperation",
+		attribute.String("osb.instance_id", instanceID),
+		attribute.String("osb.binding_id", bindingID),
+		attribute.String("osb.operation_data", details.OperationData),
+	)
+	defer func() {
+		span.SetAttributes(attribute.String("osb.state", string(op.State)))
+		End(span, err)
+	}()
+
+	return w.wrapped.LastBindingOperation(ctx, instanceID, bindingID, details)
+}
@@ -0,0 +1,71 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pivotal-cf/brokerapi"
+	"github.com/pivotal/cloud-service-broker/pkg/server/fakes"
+	"go.opentelemetry.io/otel/codes"
+)
+
+func TestBrokerWrapper(t *testing.T) {
+	recorder := recordSpans(t)
+
+	fake := &fakes.FakeServiceBroker{}
+	fake.ProvisionStub = func(ctx context.Context, instanceID string, details brokerapi.ProvisionDetails, asyncAllowed bool) (brokerapi.ProvisionedServiceSpec, error) {
+		_, span := StartSpan(ctx, "provider.Provision")
+		End(span, nil)
+		return brokerapi.ProvisionedServiceSpec{}, errors.New("quota exceeded")
+	}
+
+	wrapper := NewBrokerWrapper(fake)
+	_, err := wrapper.Provision(context.Background(), "instance", brokerapi.ProvisionDetails{ServiceID: "service", PlanID: "plan"}, true)
+	if err == nil || err.Error() != "quota exceeded" {
+		t.Fatalf("expected the wrapped broker's error, got %v", err)
+	}
+	if fake.ProvisionCallCount() != 1 {
+		t.Fatalf("expected the wrapped broker to be called once, got %d", fake.ProvisionCallCount())
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	provider, operation := spans[0], spans[1]
+	if operation.Name() != "osb.Provision" {
+		t.Errorf("expected the operation span to be named osb.Provision, got %q", operation.Name())
+	}
+	if provider.Parent().SpanID() != operation.SpanContext().SpanID() {
+		t.Error("expected the provider span to be a child of the operation span")
+	}
+	if operation.Status().Code != codes.Error {
+		t.Errorf("expected the operation span to record the error, got %v", operation.Status())
+	}
+
+	attributes := make(map[string]string)
+	for _, kv := range operation.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	for key, expected := range map[string]string{"osb.instance_id": "instance", "osb.service_id": "service", "osb.plan_id": "plan"} {
+		if attributes[key] != expected {
+			t.Errorf("expected attribute %s to be %q, got %q", key, expected, attributes[key])
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOTLPExporter_ExportSpans(t *testing.T) {
+	var received otlpRequest
+	var apiKey string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("expected spans to be posted to /v1/traces, got %s", r.URL.Path)
+		}
+		apiKey = r.Header.Get("api-key")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+	}))
+	defer collector.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	ctx, parent := tracer.Start(context.Background(), "osb.Provision")
+	parent.SetAttributes(attribute.String("osb.instance_id", "instance"), attribute.Int("attempt", 2))
+	_, child := tracer.Start(ctx, "provider.Provision")
+	End(child, errors.New("quota exceeded"))
+	End(parent, nil)
+
+	exporter := NewOTLPExporter(collector.URL+"/", map[string]string{"api-key": "secret"})
+	if err := exporter.ExportSpans(context.Background(), recorder.Ended()); err != nil {
+		t.Fatal(err)
+	}
+
+	if apiKey != "secret" {
+		t.Errorf("expected the configured headers to be sent, got api-key %q", apiKey)
+	}
+
+	if len(received.ResourceSpans) != 1 || len(received.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("expected the spans to be grouped in one scope, got %+v", received)
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	child0, parent0 := spans[0], spans[1]
+	if child0.ParentSpanID != parent0.SpanID || child0.TraceID != parent0.TraceID {
+		t.Errorf("expected %q to be a child of %q", child0.Name, parent0.Name)
+	}
+	if child0.Status.Code != 2 || child0.Status.Message != "quota exceeded" {
+		t.Errorf("expected the error status, got %+v", child0.Status)
+	}
+	if len(child0.Events) != 1 || child0.Events[0].Name != "exception" {
+		t.Errorf("expected the error event, got %+v", child0.Events)
+	}
+	if parent0.Status.Code != 0 {
+		t.Errorf("expected an unset status, got %+v", parent0.Status)
+	}
+
+	attributes := make(map[string]map[string]interface{})
+	for _, kv := range parent0.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	if attributes["osb.instance_id"]["stringValue"] != "instance" || attributes["attempt"]["intValue"] != "2" {
+		t.Errorf("unexpected attributes %v", attributes)
+	}
+}
+
+func TestOTLPExporter_ExportSpans_CollectorError(t *testing.T) {
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer collector.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	_, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test").Start(context.Background(), "span")
+	span.End()
+
+	if err := NewOTLPExporter(collector.URL, nil).ExportSpans(context.Background(), recorder.Ended()); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tracing emits OpenTelemetry traces of the broker's operations.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	exporterProp     = "tracing.exporter"
+	otlpEndpointProp = "tracing.otlp.endpoint"
+	otlpHeadersProp  = "tracing.otlp.headers"
+
+	// ExporterOTLP exports spans to an OpenTelemetry collector with the
+	// OTLP/HTTP protocol.
+	ExporterOTLP = "otlp"
+
+	instrumentationName = "github.com/pivotal/cloud-service-broker"
+)
+
+func init() {
+	viper.BindEnv(exporterProp, "TRACING_EXPORTER")
+	viper.SetDefault(exporterProp, "")
+
+	viper.BindEnv(otlpEndpointProp, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	viper.SetDefault(otlpEndpointProp, "http://localhost:4318")
+
+	viper.BindEnv(otlpHeadersProp, "OTEL_EXPORTER_OTLP_HEADERS")
+	viper.SetDefault(otlpHeadersProp, "")
+}
+
+// Setup installs the configured exporter. Tracing is disabled unless an
+// exporter is configured, spans are then no-ops. The returned function
+// flushes the spans not exported yet and must be called before exiting.
+func Setup(logger lager.Logger) (shutdown func(context.Context) error, err error) {
+	exporter := viper.GetString(exporterProp)
+	switch exporter {
+	case "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, the only supported one is %q", exporter, ExporterOTLP)
+	}
+
+	headers, err := parseHeaders(viper.GetString(otlpHeadersProp))
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := viper.GetString(otlpEndpointProp)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(NewOTLPExporter(endpoint, headers)),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "cloud-service-broker"),
+			attribute.String("service.version", utils.Version),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	logger.Info("tracing-enabled", lager.Data{"exporter": exporter, "endpoint": endpoint})
+	return provider.Shutdown, nil
+}
+
+// StartSpan starts a span named after the operation, a child of the span in
+// the context if any.
+func StartSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends the span, recording the error as an event and marking the span as
+// failed if there's one.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// ExtractTraceContext is a middleware continuing the trace of the caller, the
+// traceparent header of the request, in the request context.
+func ExtractTraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// parseHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format, e.g.
+// api-key=secret,tenant=a.
+func parseHeaders(value string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid OTLP header %q, expected key=value", pair)
+		}
+
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+	}
+
+	return headers, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/pivotal/cloud-service-broker/utils"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installs a tracer provider recording the ended spans for the
+// duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	return recorder
+}
+
+func TestEnd(t *testing.T) {
+	recorder := recordSpans(t)
+
+	_, ok := StartSpan(context.Background(), "ok")
+	End(ok, nil)
+	_, failed := StartSpan(context.Background(), "failed")
+	End(failed, errors.New("boom"))
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	if spans[0].Status().Code != codes.Unset || len(spans[0].Events()) != 0 {
+		t.Errorf("expected the successful span to have no error, got %v %v", spans[0].Status(), spans[0].Events())
+	}
+
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != "boom" {
+		t.Errorf("expected the failed span to have an error status, got %v", spans[1].Status())
+	}
+	if len(spans[1].Events()) != 1 || spans[1].Events()[0].Name != "exception" {
+		t.Errorf("expected the error to be recorded as an event, got %v", spans[1].Events())
+	}
+}
+
+func TestStartSpan_Child(t *testing.T) {
+	recorder := recordSpans(t)
+
+	ctx, parent := StartSpan(context.Background(), "parent")
+	_, child := StartSpan(ctx, "child")
+	End(child, nil)
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("expected %q to be a child of %q", spans[0].Name(), spans[1].Name())
+	}
+}
+
+func TestExtractTraceContext(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(previous)
+
+	var actual trace.SpanContext
+	handler := ExtractTraceContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = trace.SpanContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v2/catalog", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if actual.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || actual.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the caller's trace context, got %v", actual)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	logger := utils.NewLogger("tracing-test")
+
+	cases := map[string]struct {
+		Exporter    string
+		Headers     string
+		ExpectError bool
+	}{
+		"disabled by default": {Exporter: ""},
+		"otlp":                {Exporter: "otlp", Headers: "api-key=secret"},
+		"unknown exporter":    {Exporter: "zipkin", ExpectError: true},
+		"invalid headers":     {Exporter: "otlp", Headers: "api-key", ExpectError: true},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			defer viper.Reset()
+			viper.Set(exporterProp, tc.Exporter)
+			viper.Set(otlpHeadersProp, tc.Headers)
+			previous := otel.GetTracerProvider()
+			defer otel.SetTracerProvider(previous)
+
+			shutdown, err := Setup(logger)
+			if tc.ExpectError {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("unexpected error shutting down: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	actual, err := parseHeaders("api-key=secret, tenant = a=b,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"api-key": "secret", "tenant": "a=b"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected headers %v, got %v", expected, actual)
+	}
+}
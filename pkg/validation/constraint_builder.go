@@ -36,7 +36,11 @@ const (
 	KeyMinProperties    = "minProperties"
 	KeyRequired         = "required"
 	KeyPropertyNames    = "propertyNames"
+	KeyProperties       = "properties"
 	KeyProhibitUpdate   = "prohibitUpdate"
+	KeyUpdateBehavior   = "updateBehavior"
+	KeySensitive        = "x-sensitive"
+	KeyDeprecated       = "deprecated"
 )
 
 //  NewConstraintBuilder creates a builder for JSON Schema compliant constraint
@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"net/url"
 	"regexp"
+	"strings"
 
 	"github.com/hashicorp/hcl2/hclparse"
+	"golang.org/x/text/currency"
 )
 
 var (
@@ -102,6 +104,19 @@ func ErrIfNotUUID(value string, field string) *FieldError {
 	}
 }
 
+// ErrIfNotCurrencyCode returns an error if the value is not an ISO 4217
+// currency code.
+func ErrIfNotCurrencyCode(value string, field string) *FieldError {
+	if _, err := currency.ParseISO(value); err != nil || value != strings.ToUpper(value) {
+		return &FieldError{
+			Message: "field must be an ISO 4217 currency code",
+			Paths:   []string{field},
+		}
+	}
+
+	return nil
+}
+
 // ErrIfNotURL returns an error if the value is not a valid URL.
 func ErrIfNotURL(value string, field string) *FieldError {
 	// Validaiton inspired by: github.com/go-playground/validator/baked_in.go
@@ -63,3 +63,11 @@ func ExampleErrIfNotJSON() {
 	// Output: Good is nil: true
 	// Bad: invalid JSON: my-field
 }
+
+func ExampleErrIfNotCurrencyCode() {
+	fmt.Println("Good is nil:", ErrIfNotCurrencyCode("USD", "my-field") == nil)
+	fmt.Println("Bad:", ErrIfNotCurrencyCode("dollars", "my-field"))
+
+	// Output: Good is nil: true
+	// Bad: field must be an ISO 4217 currency code: my-field
+}
@@ -0,0 +1,205 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+const (
+	logFormatProp = "log.format"
+
+	// LogFormatLager writes lager's own JSON format.
+	LogFormatLager = "lager"
+
+	// LogFormatECS writes JSON following the Elastic Common Schema.
+	LogFormatECS = "ecs"
+
+	ecsVersion         = "1.6.0"
+	redactedLogValue   = "[REDACTED]"
+	ecsDataFieldPrefix = "data."
+)
+
+func init() {
+	viper.BindEnv(logFormatProp, "LOG_FORMAT")
+	viper.SetDefault(logFormatProp, LogFormatLager)
+}
+
+// ecsFieldNames maps well known lager.Data keys to the fields they're written
+// to in the ECS format.
+var ecsFieldNames = map[string]string{
+	"error":            "error.message",
+	"session":          "log.session",
+	"instance_id":      "instance_id",
+	"correlation_id":   "correlation_id",
+	"request_identity": "correlation_id",
+}
+
+// ecsReservedFields are written by the sink itself, data keys that collide
+// with them are prefixed with "data.".
+var ecsReservedFields = map[string]bool{
+	"@timestamp":  true,
+	"log.level":   true,
+	"log.logger":  true,
+	"message":     true,
+	"ecs.version": true,
+}
+
+// newLogSink returns the constructor of the sinks of the configured
+// log.format.
+func newLogSink() func(io.Writer, lager.LogLevel) lager.Sink {
+	if strings.ToLower(viper.GetString(logFormatProp)) == LogFormatECS {
+		return NewECSSink
+	}
+
+	return lager.NewWriterSink
+}
+
+type ecsSink struct {
+	writer      io.Writer
+	minLogLevel lager.LogLevel
+	writeL      *sync.Mutex
+}
+
+// NewECSSink returns a sink writing one Elastic Common Schema compatible JSON
+// object per line. The lager.Data of each entry is flattened into dotted
+// top-level fields and the values of keys matching log.redacted_keys are
+// masked.
+func NewECSSink(writer io.Writer, minLogLevel lager.LogLevel) lager.Sink {
+	return &ecsSink{
+		writer:      writer,
+		minLogLevel: minLogLevel,
+		writeL:      new(sync.Mutex),
+	}
+}
+
+func (sink *ecsSink) Log(log lager.LogFormat) {
+	if log.LogLevel < sink.minLogLevel {
+		return
+	}
+
+	entry, err := json.Marshal(ecsEntry(log, RedactedKeys()))
+	if err != nil {
+		// like lager, keep the entry but drop the data that couldn't be encoded
+		log.Data = lager.Data{"log.error": err.Error()}
+		entry, _ = json.Marshal(ecsEntry(log, nil))
+	}
+
+	sink.writeL.Lock()
+	sink.writer.Write(entry)
+	sink.writer.Write([]byte("\n"))
+	sink.writeL.Unlock()
+}
+
+// ecsEntry converts a lager entry to the fields of its ECS document.
+func ecsEntry(log lager.LogFormat, redactedKeys []string) map[string]interface{} {
+	entry := map[string]interface{}{
+		"@timestamp":  ecsTimestamp(log.Timestamp),
+		"log.level":   log.LogLevel.String(),
+		"log.logger":  log.Source,
+		"message":     log.Message,
+		"ecs.version": ecsVersion,
+	}
+
+	for key, value := range log.Data {
+		if IsRedactedKey(key, redactedKeys) {
+			entry[ecsFieldName(key)] = redactedLogValue
+			continue
+		}
+
+		flattenLogValue(entry, ecsFieldName(key), normalizeLogValue(value), redactedKeys)
+	}
+
+	return entry
+}
+
+func ecsFieldName(key string) string {
+	if name, ok := ecsFieldNames[key]; ok {
+		return name
+	}
+
+	if ecsReservedFields[key] {
+		return ecsDataFieldPrefix + key
+	}
+
+	return key
+}
+
+// normalizeLogValue converts structs to the maps they'd be serialized as so
+// they can be flattened.
+func normalizeLogValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, int, int64, float64:
+		return value
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return value
+	}
+
+	return normalized
+}
+
+func flattenLogValue(entry map[string]interface{}, field string, value interface{}, redactedKeys []string) {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		entry[field] = value
+		return
+	}
+
+	for key, elem := range object {
+		if IsRedactedKey(key, redactedKeys) {
+			entry[field+"."+key] = redactedLogValue
+			continue
+		}
+
+		flattenLogValue(entry, field+"."+key, elem, redactedKeys)
+	}
+}
+
+// ecsTimestamp converts lager's timestamps, seconds since the epoch, to
+// RFC 3339.
+func ecsTimestamp(timestamp string) string {
+	parts := strings.SplitN(timestamp, ".", 2)
+	seconds, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return timestamp
+	}
+
+	var nanos int64
+	if len(parts) == 2 {
+		fraction := (parts[1] + "000000000")[:9]
+		if nanos, err = strconv.ParseInt(fraction, 10, 64); err != nil {
+			return timestamp
+		}
+	}
+
+	return time.Unix(seconds, nanos).UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,170 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/spf13/viper"
+)
+
+func TestNewECSSink(t *testing.T) {
+	type details struct {
+		PlanID        string                 `json:"plan_id"`
+		RawParameters map[string]interface{} `json:"parameters"`
+	}
+
+	cases := map[string]struct {
+		Log      func(logger lager.Logger)
+		Expected map[string]interface{}
+	}{
+		"info": {
+			Log: func(logger lager.Logger) {
+				logger.Info("provisioning", lager.Data{
+					"instance_id":      "instance",
+					"request_identity": "request",
+					"details":          details{PlanID: "plan", RawParameters: map[string]interface{}{"admin_password": "hunter2", "size": 2}},
+				})
+			},
+			Expected: map[string]interface{}{
+				"log.level":                         "info",
+				"log.logger":                        "test",
+				"message":                           "test.provisioning",
+				"ecs.version":                       "1.6.0",
+				"instance_id":                       "instance",
+				"correlation_id":                    "request",
+				"details.plan_id":                   "plan",
+				"details.parameters.admin_password": "[REDACTED]",
+				"details.parameters.size":           float64(2),
+			},
+		},
+		"error": {
+			Log: func(logger lager.Logger) {
+				logger.Error("binding", errors.New("quota exceeded"), lager.Data{"message": "shadowed", "token": "abc"})
+			},
+			Expected: map[string]interface{}{
+				"log.level":     "error",
+				"log.logger":    "test",
+				"message":       "test.binding",
+				"ecs.version":   "1.6.0",
+				"error.message": "quota exceeded",
+				"data.message":  "shadowed",
+				"token":         "[REDACTED]",
+			},
+		},
+		"session": {
+			Log: func(logger lager.Logger) {
+				logger.Session("reconcile").Debug("polling")
+			},
+			Expected: map[string]interface{}{
+				"log.level":   "debug",
+				"log.logger":  "test",
+				"message":     "test.reconcile.polling",
+				"ecs.version": "1.6.0",
+				"log.session": "1",
+			},
+		},
+		"unencodable data": {
+			Log: func(logger lager.Logger) {
+				logger.Info("measuring", lager.Data{"ratio": math.NaN()})
+			},
+			Expected: map[string]interface{}{
+				"log.level":   "info",
+				"log.logger":  "test",
+				"message":     "test.measuring",
+				"ecs.version": "1.6.0",
+				"log.error":   "json: unsupported value: NaN",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			logger := lager.NewLogger("test")
+			logger.RegisterSink(NewECSSink(buf, lager.DEBUG))
+
+			tc.Log(logger)
+
+			var actual map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+				t.Fatalf("expected a JSON object, got %q: %v", buf.String(), err)
+			}
+
+			if _, ok := actual["@timestamp"]; !ok {
+				t.Errorf("expected a timestamp, got %v", actual)
+			}
+			delete(actual, "@timestamp")
+
+			if !reflect.DeepEqual(tc.Expected, actual) {
+				t.Errorf("expected entry %v, got %v", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewECSSink_MinLogLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := lager.NewLogger("test")
+	logger.RegisterSink(NewECSSink(buf, lager.ERROR))
+
+	logger.Info("ignored")
+	if buf.Len() != 0 {
+		t.Errorf("expected entries below the minimum level to be dropped, got %q", buf.String())
+	}
+}
+
+func TestEcsTimestamp(t *testing.T) {
+	cases := map[string]struct {
+		Timestamp string
+		Expected  string
+	}{
+		"lager":        {Timestamp: "1602759600.123456789", Expected: "2020-10-15T11:00:00.123456789Z"},
+		"no fraction":  {Timestamp: "1602759600", Expected: "2020-10-15T11:00:00Z"},
+		"not a number": {Timestamp: "yesterday", Expected: "yesterday"},
+	}
+
+	for tn, tc := range cases {
+		t.Run(tn, func(t *testing.T) {
+			if actual := ecsTimestamp(tc.Timestamp); actual != tc.Expected {
+				t.Errorf("expected %q, got %q", tc.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestNewLogSink(t *testing.T) {
+	defer viper.Set(logFormatProp, LogFormatLager)
+
+	viper.Set(logFormatProp, LogFormatECS)
+	buf := &bytes.Buffer{}
+	newLogSink()(buf, lager.DEBUG).Log(lager.LogFormat{Timestamp: "1602759600", Message: "test.ecs"})
+	if !bytes.Contains(buf.Bytes(), []byte(`"ecs.version"`)) {
+		t.Errorf("expected an ECS entry, got %q", buf.String())
+	}
+
+	viper.Set(logFormatProp, LogFormatLager)
+	buf.Reset()
+	newLogSink()(buf, lager.DEBUG).Log(lager.LogFormat{Timestamp: "1602759600", Message: "test.lager"})
+	if !bytes.Contains(buf.Bytes(), []byte(`"log_level"`)) {
+		t.Errorf("expected a lager entry, got %q", buf.String())
+	}
+}
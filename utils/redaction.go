@@ -0,0 +1,63 @@
+// Copyright 2018 the Service Broker Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	// RedactedKeysProp is the comma separated list of keys whose values are
+	// masked in the logs.
+	RedactedKeysProp    = "log.redacted_keys"
+	defaultRedactedKeys = "password,secret,token,private_key,credential"
+)
+
+func init() {
+	viper.BindEnv(RedactedKeysProp, "LOG_REDACTED_KEYS")
+	viper.SetDefault(RedactedKeysProp, defaultRedactedKeys)
+}
+
+// RedactedKeys returns the configured log.redacted_keys, lower cased.
+func RedactedKeys() []string {
+	configured := defaultRedactedKeys
+	if viper.IsSet(RedactedKeysProp) {
+		configured = viper.GetString(RedactedKeysProp)
+	}
+
+	var keys []string
+	for _, key := range strings.Split(configured, ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// IsRedactedKey returns true if the key contains one of the redacted keys,
+// ignoring case.
+func IsRedactedKey(key string, redactedKeys []string) bool {
+	key = strings.ToLower(key)
+	for _, redacted := range redactedKeys {
+		if strings.Contains(key, redacted) {
+			return true
+		}
+	}
+
+	return false
+}
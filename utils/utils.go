@@ -226,12 +226,13 @@ func SingleLineErrorFormatter(es []error) string {
 }
 
 // NewLogger creates a new lager.Logger with the given name that has correct
-// writing settings.
+// writing settings. Entries are written in the configured log.format.
 func NewLogger(name string) lager.Logger {
 	logger := lager.NewLogger(name)
 
-	logger.RegisterSink(lager.NewWriterSink(os.Stderr, lager.ERROR))
-	logger.RegisterSink(lager.NewWriterSink(os.Stdout, lager.DEBUG))
+	newSink := newLogSink()
+	logger.RegisterSink(newSink(os.Stderr, lager.ERROR))
+	logger.RegisterSink(newSink(os.Stdout, lager.DEBUG))
 
 	return logger
 }